@@ -2,15 +2,19 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 	"valhalla/internal/config"
+	"valhalla/internal/discovery/providers"
 	"valhalla/internal/logger"
+	"valhalla/internal/secrets"
 )
 
 // AuthOptions holds options for the auth command
@@ -18,7 +22,9 @@ type AuthOptions struct {
 	Provider string
 	Server   string
 	Username string
+	Profile  string // named profile under providers.<provider>.profiles; empty for the flat keys
 	Save     bool
+	Keyring  bool
 	Test     bool
 }
 
@@ -33,6 +39,9 @@ func NewAuthCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
 
 Supports interactive credential entry with secure password prompts.
 Credentials can be saved to configuration file or set as environment variables.
+With --keyring, passwords and token secrets go to the OS keyring (macOS
+Keychain, Windows Credential Manager or a Linux Secret Service) and the
+configuration file only refers to them.
 
 Examples:
   # Configure VMware credentials interactively
@@ -42,7 +51,16 @@ Examples:
   valhalla auth proxmox --server proxmox.example.com --username admin@pam
   
   # Test existing credentials
-  valhalla auth vmware --test`,
+  valhalla auth vmware --test
+
+  # Save verified credentials to a project config file instead of ~/.valhalla.yaml
+  valhalla --config ./proj.yaml auth vmware --save
+
+  # Keep the password in the OS keyring instead of the config file
+  valhalla auth vmware --server vcenter.example.com --keyring
+
+  # Save a second vCenter as the dr profile
+  valhalla auth vmware --profile dr --server vcenter-dr.example.com --save`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				opts.Provider = args[0]
@@ -54,7 +72,9 @@ Examples:
 	// Add flags
 	cmd.Flags().StringVarP(&opts.Server, "server", "s", "", "Server hostname or IP address")
 	cmd.Flags().StringVarP(&opts.Username, "username", "u", "", "Username for authentication")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Named profile to configure or test, saved under providers.<provider>.profiles (defaults to the flat provider keys)")
 	cmd.Flags().BoolVar(&opts.Save, "save", false, "Save credentials to configuration file")
+	cmd.Flags().BoolVar(&opts.Keyring, "keyring", false, "Save the password in the OS keyring, leaving a reference in the configuration file (implies --save)")
 	cmd.Flags().BoolVar(&opts.Test, "test", false, "Test existing credentials")
 
 	// Add subcommands for each provider
@@ -70,6 +90,14 @@ func runAuth(log *logger.Logger, cfg *config.Config, opts *AuthOptions) error {
 	if opts.Provider == "" {
 		return fmt.Errorf("provider required (vmware, proxmox, nutanix)")
 	}
+	if !config.IsDefaultProfile(opts.Profile) {
+		if strings.EqualFold(opts.Profile, config.AllProfiles) {
+			return fmt.Errorf("--profile all only applies to discover; name the profile to configure")
+		}
+		if err := config.ValidateProfileName(opts.Profile); err != nil {
+			return err
+		}
+	}
 
 	switch strings.ToLower(opts.Provider) {
 	case "vmware", "vsphere":
@@ -96,13 +124,15 @@ Examples:
   valhalla auth vmware --server vcenter.example.com --username administrator@vsphere.local
   valhalla auth vmware --test`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return authVMware(log, cfg, opts)
+			return runAuth(log, cfg, opts)
 		},
 	}
 
 	cmd.Flags().StringVarP(&opts.Server, "server", "s", "", "vCenter server hostname or IP")
 	cmd.Flags().StringVarP(&opts.Username, "username", "u", "", "vCenter username")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Named profile to configure or test, saved under providers.<provider>.profiles (defaults to the flat provider keys)")
 	cmd.Flags().BoolVar(&opts.Save, "save", false, "Save credentials to config file")
+	cmd.Flags().BoolVar(&opts.Keyring, "keyring", false, "Save the password in the OS keyring, leaving a reference in the config file (implies --save)")
 	cmd.Flags().BoolVar(&opts.Test, "test", false, "Test existing credentials")
 
 	return cmd
@@ -123,13 +153,15 @@ Examples:
   valhalla auth proxmox --server proxmox.example.com --username root@pam
   valhalla auth proxmox --test`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return authProxmox(log, cfg, opts)
+			return runAuth(log, cfg, opts)
 		},
 	}
 
 	cmd.Flags().StringVarP(&opts.Server, "server", "s", "", "Proxmox server hostname or IP")
 	cmd.Flags().StringVarP(&opts.Username, "username", "u", "", "Proxmox username (e.g., root@pam)")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Named profile to configure or test, saved under providers.<provider>.profiles (defaults to the flat provider keys)")
 	cmd.Flags().BoolVar(&opts.Save, "save", false, "Save credentials to config file")
+	cmd.Flags().BoolVar(&opts.Keyring, "keyring", false, "Save the password in the OS keyring, leaving a reference in the config file (implies --save)")
 	cmd.Flags().BoolVar(&opts.Test, "test", false, "Test existing credentials")
 
 	return cmd
@@ -148,13 +180,15 @@ Examples:
   valhalla auth nutanix --server prism.example.com --username admin
   valhalla auth nutanix --test`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return authNutanix(log, cfg, opts)
+			return runAuth(log, cfg, opts)
 		},
 	}
 
 	cmd.Flags().StringVarP(&opts.Server, "server", "s", "", "Nutanix Prism server hostname or IP")
 	cmd.Flags().StringVarP(&opts.Username, "username", "u", "", "Nutanix username")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Named profile to configure or test, saved under providers.<provider>.profiles (defaults to the flat provider keys)")
 	cmd.Flags().BoolVar(&opts.Save, "save", false, "Save credentials to config file")
+	cmd.Flags().BoolVar(&opts.Keyring, "keyring", false, "Save the password in the OS keyring, leaving a reference in the config file (implies --save)")
 	cmd.Flags().BoolVar(&opts.Test, "test", false, "Test existing credentials")
 
 	return cmd
@@ -165,11 +199,13 @@ func authVMware(log *logger.Logger, cfg *config.Config, opts *AuthOptions) error
 	log.Info("Configuring VMware vCenter authentication")
 
 	if opts.Test {
-		return testVMwareCredentials(log, cfg)
+		return testVMwareCredentials(log, cfg, opts.Profile)
 	}
 
 	// Get current config
-	vmwareConfig := cfg.GetVMwareConfig()
+	// Current settings are only prompt defaults, so a keyring error about
+	// the password doesn't matter here
+	vmwareConfig, _ := cfg.GetVMwareProfile(opts.Profile)
 
 	// Prompt for server if not provided
 	if opts.Server == "" {
@@ -231,11 +267,15 @@ func authVMware(log *logger.Logger, cfg *config.Config, opts *AuthOptions) error
 	log.Info("VMware credentials verified successfully")
 
 	// Save credentials if requested
-	if opts.Save {
-		return saveVMwareCredentials(cfg, testConfig, log)
+	if opts.Save || opts.Keyring {
+		return saveVMwareCredentials(cfg, opts.Profile, testConfig, opts.Keyring, log)
 	}
 
 	// Show environment variable instructions
+	if !config.IsDefaultProfile(opts.Profile) {
+		showProfileSaveHint(opts.Profile)
+		return nil
+	}
 	showVMwareEnvInstructions(testConfig)
 	return nil
 }
@@ -245,10 +285,10 @@ func authProxmox(log *logger.Logger, cfg *config.Config, opts *AuthOptions) erro
 	log.Info("Configuring Proxmox authentication")
 
 	if opts.Test {
-		return testProxmoxCredentials(log, cfg)
+		return testProxmoxCredentials(log, cfg, opts.Profile)
 	}
 
-	proxmoxConfig := cfg.GetProxmoxConfig()
+	proxmoxConfig, _ := cfg.GetProxmoxProfile(opts.Profile)
 
 	// Get server
 	if opts.Server == "" {
@@ -328,10 +368,14 @@ func authProxmox(log *logger.Logger, cfg *config.Config, opts *AuthOptions) erro
 
 	log.Info("Proxmox credentials verified successfully")
 
-	if opts.Save {
-		return saveProxmoxCredentials(cfg, testConfig, log)
+	if opts.Save || opts.Keyring {
+		return saveProxmoxCredentials(cfg, opts.Profile, testConfig, opts.Keyring, log)
 	}
 
+	if !config.IsDefaultProfile(opts.Profile) {
+		showProfileSaveHint(opts.Profile)
+		return nil
+	}
 	showProxmoxEnvInstructions(testConfig)
 	return nil
 }
@@ -341,10 +385,10 @@ func authNutanix(log *logger.Logger, cfg *config.Config, opts *AuthOptions) erro
 	log.Info("Configuring Nutanix authentication")
 
 	if opts.Test {
-		return testNutanixCredentials(log, cfg)
+		return testNutanixCredentials(log, cfg, opts.Profile)
 	}
 
-	nutanixConfig := cfg.GetNutanixConfig()
+	nutanixConfig, _ := cfg.GetNutanixProfile(opts.Profile)
 
 	// Get server
 	if opts.Server == "" {
@@ -405,44 +449,97 @@ func authNutanix(log *logger.Logger, cfg *config.Config, opts *AuthOptions) erro
 
 	log.Info("Nutanix credentials verified successfully")
 
-	if opts.Save {
-		return saveNutanixCredentials(cfg, testConfig, log)
+	if opts.Save || opts.Keyring {
+		return saveNutanixCredentials(cfg, opts.Profile, testConfig, opts.Keyring, log)
 	}
 
+	if !config.IsDefaultProfile(opts.Profile) {
+		showProfileSaveHint(opts.Profile)
+		return nil
+	}
 	showNutanixEnvInstructions(testConfig)
 	return nil
 }
 
-// Test connection functions (placeholder implementations)
+// authTestTimeout bounds a credential test so a wrong host name fails fast
+const authTestTimeout = 10 * time.Second
+
 func testVMwareConnection(log *logger.Logger, cfg config.VMwareConfig) error {
-	// TODO: Implement actual VMware connection test
 	log.Info("Testing VMware connection", "server", cfg.Server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), authTestTimeout)
+	defer cancel()
+
+	// The datacenter is not part of the credentials being tested
+	cfg.Datacenter = ""
+
+	provider := providers.NewVMwareProvider(log.WithProvider("vmware"))
+	if err := provider.ConnectVMware(ctx, cfg); err != nil {
+		return err
+	}
+	defer provider.Disconnect()
+
+	version := provider.ConnectionInfo().ProviderVersion()
+	log.Info("Connected to vCenter", "server", cfg.Server, "version", version.Version, "build", version.Build)
+	fmt.Printf("Connected to %s: %s\n", cfg.Server, version.Product)
 	return nil
 }
 
 func testProxmoxConnection(log *logger.Logger, cfg config.ProxmoxConfig) error {
-	// TODO: Implement actual Proxmox connection test
 	log.Info("Testing Proxmox connection", "server", cfg.Server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), authTestTimeout)
+	defer cancel()
+
+	// Connecting logs in or presents the API token, then reads /version
+	provider := providers.NewProxmoxProvider(log.WithProvider("proxmox"))
+	if err := provider.ConnectProxmox(ctx, cfg); err != nil {
+		return err
+	}
+	defer provider.Disconnect()
+
+	version := provider.ConnectionInfo().Version
+	log.Info("Connected to Proxmox", "server", cfg.Server, "version", version)
+	fmt.Printf("Connected to %s: Proxmox VE %s\n", cfg.Server, version)
 	return nil
 }
 
 func testNutanixConnection(log *logger.Logger, cfg config.NutanixConfig) error {
-	// TODO: Implement actual Nutanix connection test
-	log.Info("Testing Nutanix connection", "server", cfg.Server)
+	log.Info("Testing Nutanix connection", "server", cfg.Server, "port", cfg.Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), authTestTimeout)
+	defer cancel()
+
+	// Connecting lists clusters, which checks the credentials and the response
+	provider := providers.NewNutanixProvider(log.WithProvider("nutanix"))
+	if err := provider.ConnectNutanix(ctx, cfg); err != nil {
+		return err
+	}
+	defer provider.Disconnect()
+
+	version := provider.ConnectionInfo().Version
+	log.Info("Connected to Nutanix Prism", "server", cfg.Server, "version", version)
+	fmt.Printf("Connected to %s: Nutanix AOS %s\n", cfg.Server, version)
 	return nil
 }
 
 // Test existing credentials functions
-func testVMwareCredentials(log *logger.Logger, cfg *config.Config) error {
-	vmwareConfig := cfg.GetVMwareConfig()
+func testVMwareCredentials(log *logger.Logger, cfg *config.Config, profile string) error {
+	vmwareConfig, err := cfg.GetVMwareProfile(profile)
+	if err != nil {
+		return err
+	}
 	if vmwareConfig.Server == "" || vmwareConfig.Username == "" || vmwareConfig.Password == "" {
 		return fmt.Errorf("VMware credentials not configured")
 	}
 	return testVMwareConnection(log, vmwareConfig)
 }
 
-func testProxmoxCredentials(log *logger.Logger, cfg *config.Config) error {
-	proxmoxConfig := cfg.GetProxmoxConfig()
+func testProxmoxCredentials(log *logger.Logger, cfg *config.Config, profile string) error {
+	proxmoxConfig, err := cfg.GetProxmoxProfile(profile)
+	if err != nil {
+		return err
+	}
 	if proxmoxConfig.Server == "" || proxmoxConfig.Username == "" {
 		return fmt.Errorf("Proxmox credentials not configured")
 	}
@@ -452,8 +549,11 @@ func testProxmoxCredentials(log *logger.Logger, cfg *config.Config) error {
 	return testProxmoxConnection(log, proxmoxConfig)
 }
 
-func testNutanixCredentials(log *logger.Logger, cfg *config.Config) error {
-	nutanixConfig := cfg.GetNutanixConfig()
+func testNutanixCredentials(log *logger.Logger, cfg *config.Config, profile string) error {
+	nutanixConfig, err := cfg.GetNutanixProfile(profile)
+	if err != nil {
+		return err
+	}
 	if nutanixConfig.Server == "" || nutanixConfig.Username == "" || nutanixConfig.Password == "" {
 		return fmt.Errorf("Nutanix credentials not configured")
 	}
@@ -461,24 +561,94 @@ func testNutanixCredentials(log *logger.Logger, cfg *config.Config) error {
 }
 
 // Save credentials functions
-func saveVMwareCredentials(cfg *config.Config, vmwareConfig config.VMwareConfig, log *logger.Logger) error {
-	// TODO: Implement saving to config file
-	log.Info("Saving VMware credentials to config file")
-	return nil
+func saveVMwareCredentials(cfg *config.Config, profile string, vmwareConfig config.VMwareConfig, keyring bool, log *logger.Logger) error {
+	settings := map[string]interface{}{
+		"server":   vmwareConfig.Server,
+		"username": vmwareConfig.Username,
+		"password": vmwareConfig.Password,
+	}
+	if keyring {
+		keyringSettings(log, "vmware", vmwareConfig.Server, vmwareConfig.Username, "password", "VSPHERE_PASSWORD", settings)
+	}
+	return saveCredentials(cfg, "vmware", profile, settings, log)
 }
 
-func saveProxmoxCredentials(cfg *config.Config, proxmoxConfig config.ProxmoxConfig, log *logger.Logger) error {
-	// TODO: Implement saving to config file
-	log.Info("Saving Proxmox credentials to config file")
-	return nil
+func saveProxmoxCredentials(cfg *config.Config, profile string, proxmoxConfig config.ProxmoxConfig, keyring bool, log *logger.Logger) error {
+	settings := map[string]interface{}{
+		"server":   proxmoxConfig.Server,
+		"username": proxmoxConfig.Username,
+	}
+	// Clear the unused authentication method so the two never conflict
+	if proxmoxConfig.TokenID != "" {
+		settings["token_id"] = proxmoxConfig.TokenID
+		settings["secret"] = proxmoxConfig.Secret
+		settings["password"] = ""
+		if keyring {
+			keyringSettings(log, "proxmox", proxmoxConfig.Server, proxmoxConfig.TokenID, "secret", "PROXMOX_SECRET", settings)
+		}
+	} else {
+		settings["password"] = proxmoxConfig.Password
+		settings["token_id"] = ""
+		settings["secret"] = ""
+		if keyring {
+			keyringSettings(log, "proxmox", proxmoxConfig.Server, proxmoxConfig.Username, "password", "PROXMOX_PASSWORD", settings)
+		}
+	}
+	return saveCredentials(cfg, "proxmox", profile, settings, log)
+}
+
+func saveNutanixCredentials(cfg *config.Config, profile string, nutanixConfig config.NutanixConfig, keyring bool, log *logger.Logger) error {
+	settings := map[string]interface{}{
+		"server":   nutanixConfig.Server,
+		"username": nutanixConfig.Username,
+		"password": nutanixConfig.Password,
+		"port":     nutanixConfig.Port,
+	}
+	if keyring {
+		keyringSettings(log, "nutanix", nutanixConfig.Server, nutanixConfig.Username, "password", "NUTANIX_PASSWORD", settings)
+	}
+	return saveCredentials(cfg, "nutanix", profile, settings, log)
 }
 
-func saveNutanixCredentials(cfg *config.Config, nutanixConfig config.NutanixConfig, log *logger.Logger) error {
-	// TODO: Implement saving to config file
-	log.Info("Saving Nutanix credentials to config file")
+// keyringSettings moves the secret in settings[field] to the OS keyring,
+// under valhalla/<provider>/<server> for user, and saves a reference to it
+// instead. Without a keyring, such as on a headless Linux host, the secret
+// is left out of the config file rather than written in plaintext, and the
+// environment variable to provide it with is printed without its value.
+func keyringSettings(log *logger.Logger, provider, server, user, field, envVar string, settings map[string]interface{}) {
+	secret, _ := settings[field].(string)
+	key := secrets.Key(provider, server)
+	if err := secrets.Set(key, user, secret); err != nil {
+		delete(settings, field)
+		log.Warn("Not saving the secret, no OS keyring available", "provider", provider, "error", err)
+		fmt.Printf("No OS keyring available; provide the %s secret with:\nexport %s=\"<secret>\"\n", provider, envVar)
+		return
+	}
+	settings[field] = secrets.Reference(key)
+	log.Info("Stored secret in OS keyring", "provider", provider, "key", key)
+}
+
+// saveCredentials writes verified provider credentials to the config file,
+// under the profile's section. The insecure setting is left as configured:
+// the connection tests skip TLS verification, which is not a choice the
+// user made.
+func saveCredentials(cfg *config.Config, provider, profile string, settings map[string]interface{}, log *logger.Logger) error {
+	section := config.ProfileSection(provider, profile)
+	filename, err := cfg.SaveProviderSettings(section, settings)
+	if err != nil {
+		return fmt.Errorf("failed to save %s credentials: %w", provider, err)
+	}
+	log.Info("Saved credentials to config file", "provider", provider, "section", "providers."+section, "file", filename)
+	fmt.Printf("Credentials for %s saved to %s\n", strings.ReplaceAll(section, ".profiles.", " profile "), filename)
 	return nil
 }
 
+// showProfileSaveHint explains that a named profile has no environment
+// variables, which only configure the default profile
+func showProfileSaveHint(profile string) {
+	fmt.Printf("\nEnvironment variables only configure the default profile; rerun with --save or --keyring to keep the %s profile\n", profile)
+}
+
 // Environment variable instruction functions
 func showVMwareEnvInstructions(cfg config.VMwareConfig) {
 	fmt.Println("\nTo use these credentials, set the following environment variables:")
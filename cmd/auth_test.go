@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+)
+
+func TestSaveVMwareCredentialsRoundTrip(t *testing.T) {
+	for _, env := range []string{"VSPHERE_SERVER", "VSPHERE_USER", "VSPHERE_PASSWORD"} {
+		t.Setenv(env, "")
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	filename := filepath.Join(t.TempDir(), "valhalla.yaml")
+	existing := "output:\n  format: json\nproviders:\n  proxmox:\n    server: pve.example.com\n"
+	if err := os.WriteFile(filename, []byte(existing), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.New()
+	if err := cfg.InitConfig(filename); err != nil {
+		t.Fatalf("InitConfig: %v", err)
+	}
+	saved := config.VMwareConfig{Server: "vcenter.example.com", Username: "administrator@vsphere.local", Password: `p"ss:word`}
+	if err := saveVMwareCredentials(cfg, "", saved, false, logger.NewWithOutput(io.Discard)); err != nil {
+		t.Fatalf("saveVMwareCredentials: %v", err)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("config file mode = %o, want 600", mode)
+	}
+
+	// Reload from scratch, as the next command would
+	viper.Reset()
+	reloaded := config.New()
+	if err := reloaded.InitConfig(filename); err != nil {
+		t.Fatalf("InitConfig after save: %v", err)
+	}
+
+	got, err := reloaded.GetVMwareConfig()
+	if err != nil {
+		t.Fatalf("GetVMwareConfig: %v", err)
+	}
+	if got.Server != saved.Server || got.Username != saved.Username || got.Password != saved.Password {
+		t.Errorf("reloaded %s / %s / %s, want %s / %s / %s",
+			got.Server, got.Username, got.Password, saved.Server, saved.Username, saved.Password)
+	}
+	if reloaded.Output.Format != "json" || reloaded.Providers.Proxmox.Server != "pve.example.com" {
+		t.Errorf("existing settings were not kept: output.format %q, proxmox server %q",
+			reloaded.Output.Format, reloaded.Providers.Proxmox.Server)
+	}
+}
+
+// loadConfig loads filename as the --config file, or the default file
+// search when it is empty, with provider environment variables cleared
+func loadConfig(t *testing.T, filename string) *config.Config {
+	t.Helper()
+
+	for _, env := range []string{
+		"VSPHERE_SERVER", "VSPHERE_USER", "VSPHERE_PASSWORD",
+		"PROXMOX_SERVER", "PROXMOX_USER", "PROXMOX_PASSWORD", "PROXMOX_TOKEN_ID", "PROXMOX_SECRET",
+		"NUTANIX_SERVER", "NUTANIX_USER", "NUTANIX_PASSWORD",
+	} {
+		t.Setenv(env, "")
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	cfg := config.New()
+	if err := cfg.InitConfig(filename); err != nil {
+		t.Fatalf("InitConfig(%q): %v", filename, err)
+	}
+	return cfg
+}
+
+// checkPrivate fails unless filename exists with mode 0600
+func checkPrivate(t *testing.T, filename string) {
+	t.Helper()
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("config file was not written: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("%s mode = %o, want 600", filename, mode)
+	}
+}
+
+func TestSaveCredentialsCreatesConfigFlagFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "proj.yaml")
+	log := logger.NewWithOutput(io.Discard)
+
+	saved := config.NutanixConfig{Server: "prism.example.com", Username: "admin", Password: "secret", Port: 9440}
+	if err := saveNutanixCredentials(loadConfig(t, filename), "", saved, false, log); err != nil {
+		t.Fatalf("saveNutanixCredentials: %v", err)
+	}
+	checkPrivate(t, filename)
+
+	got, err := loadConfig(t, filename).GetNutanixConfig()
+	if err != nil {
+		t.Fatalf("GetNutanixConfig: %v", err)
+	}
+	if got.Server != saved.Server || got.Username != saved.Username || got.Password != saved.Password || got.Port != saved.Port {
+		t.Errorf("reloaded %+v, want %+v", got, saved)
+	}
+}
+
+func TestSaveCredentialsDefaultsToHomeFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	// The default search also looks in the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	saved := config.VMwareConfig{Server: "vcenter.example.com", Username: "administrator@vsphere.local", Password: "secret"}
+	if err := saveVMwareCredentials(loadConfig(t, ""), "", saved, false, logger.NewWithOutput(io.Discard)); err != nil {
+		t.Fatalf("saveVMwareCredentials: %v", err)
+	}
+
+	filename := filepath.Join(home, ".valhalla.yaml")
+	checkPrivate(t, filename)
+	got, err := loadConfig(t, "").GetVMwareConfig()
+	if err != nil {
+		t.Fatalf("GetVMwareConfig: %v", err)
+	}
+	if got.Server != saved.Server || got.Password != saved.Password {
+		t.Errorf("reloaded %s / %s from ~/.valhalla.yaml, want %s / %s", got.Server, got.Password, saved.Server, saved.Password)
+	}
+}
+
+func TestSaveProxmoxCredentialsSwitchesMethod(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "valhalla.yaml")
+	log := logger.NewWithOutput(io.Discard)
+
+	password := config.ProxmoxConfig{Server: "pve.example.com", Username: "root@pam", Password: "secret"}
+	if err := saveProxmoxCredentials(loadConfig(t, filename), "", password, false, log); err != nil {
+		t.Fatalf("saving password: %v", err)
+	}
+	token := config.ProxmoxConfig{Server: "pve.example.com", Username: "root@pam", TokenID: "root@pam!valhalla", Secret: "uuid-secret"}
+	if err := saveProxmoxCredentials(loadConfig(t, filename), "", token, false, log); err != nil {
+		t.Fatalf("saving token: %v", err)
+	}
+
+	got, err := loadConfig(t, filename).GetProxmoxConfig()
+	if err != nil {
+		t.Fatalf("GetProxmoxConfig: %v", err)
+	}
+	if got.TokenID != token.TokenID || got.Secret != token.Secret || got.Password != "" {
+		t.Errorf("reloaded token %q, secret %q, password %q; want the token only", got.TokenID, got.Secret, got.Password)
+	}
+}
+
+func TestSaveCredentialsToProfile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "valhalla.yaml")
+	log := logger.NewWithOutput(io.Discard)
+
+	primary := config.VMwareConfig{Server: "vcenter.example.com", Username: "admin", Password: "one"}
+	if err := saveVMwareCredentials(loadConfig(t, filename), "", primary, false, log); err != nil {
+		t.Fatal(err)
+	}
+	dr := config.VMwareConfig{Server: "vcenter-dr.example.com", Username: "admin", Password: "two"}
+	if err := saveVMwareCredentials(loadConfig(t, filename), "dr", dr, false, log); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := loadConfig(t, filename)
+	got, err := cfg.GetVMwareProfile("dr")
+	if err != nil {
+		t.Fatalf("GetVMwareProfile: %v", err)
+	}
+	if got.Server != dr.Server || got.Password != dr.Password {
+		t.Errorf("dr profile %s / %s, want %s / %s", got.Server, got.Password, dr.Server, dr.Password)
+	}
+	if def, err := cfg.GetVMwareConfig(); err != nil || def.Server != primary.Server {
+		t.Errorf("default profile server %q (%v), want %q", def.Server, err, primary.Server)
+	}
+}
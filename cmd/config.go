@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+)
+
+// ConfigContextsOptions holds options for the config contexts command
+type ConfigContextsOptions struct {
+	Format string
+}
+
+// NewConfigCmd creates the config command
+func NewConfigCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the loaded configuration",
+	}
+
+	cmd.AddCommand(newConfigContextsCmd(log, cfg))
+
+	return cmd
+}
+
+// newConfigContextsCmd creates the config contexts subcommand
+func newConfigContextsCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &ConfigContextsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "contexts",
+		Short: "List the configured provider profiles",
+		Long: `List every configured provider profile with its endpoint, account and where
+its credentials come from. Secrets are never shown or read from the keyring.
+
+The flat providers.<provider> keys, together with the provider's environment
+variables, are the default profile; further endpoints are named profiles
+under providers.<provider>.profiles:
+
+  providers:
+    vmware:
+      server: vcenter.example.com
+      username: administrator@vsphere.local
+      profiles:
+        dr:
+          server: vcenter-dr.example.com
+          username: administrator@vsphere.local
+
+Named profiles take the settings they leave out, other than the server and
+credentials, from the flat keys. Select one with --profile on discover and
+auth, or every profile of a provider with --profile all on discover.
+
+Examples:
+  valhalla config contexts
+  valhalla config contexts --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConfigContexts(log, cfg, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", "table", "Output format (table, json)")
+
+	return cmd
+}
+
+// runConfigContexts executes the config contexts command
+func runConfigContexts(log *logger.Logger, cfg *config.Config, opts *ConfigContextsOptions) error {
+	contexts := cfg.Contexts()
+
+	switch strings.ToLower(opts.Format) {
+	case "json":
+		if contexts == nil {
+			contexts = []config.ProfileContext{}
+		}
+		data, err := json.MarshalIndent(contexts, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		if len(contexts) == 0 {
+			fmt.Println("No provider profiles configured; run valhalla auth <provider> --save to add one")
+			return nil
+		}
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Provider", "Profile", "Server", "Username", "Credentials"})
+		table.SetBorder(true)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		for _, context := range contexts {
+			credentials := context.Credentials
+			if context.Error != "" {
+				credentials = "invalid: " + context.Error
+			}
+			table.Append([]string{context.Provider, context.Profile, context.Server, context.Username, credentials})
+		}
+		table.Render()
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.Format)
+	}
+
+	if file := cfg.GetConfigFile(); file != "" {
+		log.Debug("Listed provider profiles", "config_file", file, "profiles", len(contexts))
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/discovery"
+	"valhalla/internal/logger"
+	"valhalla/internal/output"
+)
+
+// ConvertOptions holds options for the convert command
+type ConvertOptions struct {
+	OutputFormat string
+	OutputFile   string
+	Provider     string
+	Fields       []string
+	Where        []string
+	Encrypt      bool
+	YAMLDocs     bool
+	MaxFieldSize int
+	Summary      bool
+
+	// VMs to leave out, as with discover
+	Filters config.FilterConfig
+}
+
+// NewConvertCmd creates the convert command
+func NewConvertCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &ConvertOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "convert <discovery-file>",
+		Short: "Convert discovery results to another output format",
+		Long: `Re-render saved discovery results in another output format without
+discovering again.
+
+The input may be any file written by discover or import: a JSON list, JSON
+objects one per line, YAML (a list or one document per infrastructure), or
+CSV, each optionally encrypted with --encrypt. Encrypted input is decrypted
+with the passphrase in VALHALLA_PASSPHRASE. The output formats, --fields
+projection and VM exclusions are those of discover.
+
+--where keeps only what matches a condition on a --fields path. A condition
+on a resource list such as virtual_machines.cpus>=4 keeps the matching
+resources of that list; any other, such as provider=vmware, keeps or drops
+whole infrastructures. "=" and "!=" ignore case and accept * patterns;
+">", ">=", "<" and "<=" compare numbers numerically. Repeated conditions
+must all match.
+
+Examples:
+  # The same data as a spreadsheet
+  valhalla convert discovery.json --format csv -o inventory.csv
+
+  # An HTML report of the powered on VMs only
+  valhalla convert discovery.yaml -f html -o report.html --exclude-power-state poweredOff
+
+  # Only VM names and disk sizes, as YAML
+  valhalla convert discovery.json -f yaml --fields virtual_machines.name,virtual_machines.disks.size
+
+  # A Markdown report of the VMs with 8 or more vCPUs
+  valhalla convert discovery.json -f markdown --where "virtual_machines.cpus>=8"
+
+  # The topology of one vCenter as a Graphviz graph
+  valhalla convert discovery.json -f dot --where server=vcenter.example.com -o topo.dot
+
+  # Decrypt, or encrypt, with the passphrase in VALHALLA_PASSPHRASE
+  valhalla convert discovery.json.enc -f csv -o inventory.csv
+  valhalla convert discovery.json -f json -o discovery.json.enc --encrypt`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Encrypt && opts.OutputFile == "" {
+				return fmt.Errorf("--encrypt needs --output-file")
+			}
+			return runConvert(log, cfg, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "table", "Output format ("+strings.Join(output.Formats, ", ")+")")
+	cmd.Flags().StringVarP(&opts.OutputFile, "output-file", "o", "", "Output file path (default: stdout)")
+	cmd.Flags().StringVarP(&opts.Provider, "provider", "p", "", "Only convert infrastructures of this provider (vmware, proxmox, nutanix)")
+	cmd.Flags().StringSliceVar(&opts.Fields, "fields", []string{}, "Only include these dotted field paths in json/yaml output, e.g. virtual_machines.name,networks.* (provider, server and discovery_time are always kept)")
+	cmd.Flags().StringArrayVar(&opts.Where, "where", []string{}, "Only convert what matches this condition, e.g. virtual_machines.power_state=poweredOn or virtual_machines.cpus>=4 (repeatable)")
+	cmd.Flags().BoolVar(&opts.Encrypt, "encrypt", false, "Encrypt the output file with the passphrase in "+output.PassphraseEnv)
+	cmd.Flags().BoolVar(&opts.YAMLDocs, "yaml-docs", false, "Emit one YAML document per infrastructure (yaml format only)")
+	cmd.Flags().IntVar(&opts.MaxFieldSize, "max-field-size", 0, "Truncate annotation and metadata values over this many bytes (JSON is only truncated when set)")
+	cmd.Flags().BoolVar(&opts.Summary, "summary", false, "Also print resource counts to stderr")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeNames, "exclude-name", []string{}, "Leave out VMs whose name matches these patterns, e.g. tmp-*")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeFolders, "exclude-folder", []string{}, "Leave out VMs in these folders or their subfolders (patterns allowed)")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludePowerStates, "exclude-power-state", []string{}, "Leave out VMs in these power states, e.g. poweredOff or stopped")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeTags, "exclude-tag", []string{}, "Leave out VMs with a tag matching these patterns, e.g. env:sandbox")
+
+	return cmd
+}
+
+// runConvert reads discovery results and writes them in another format
+func runConvert(log *logger.Logger, cfg *config.Config, inputFile string, opts *ConvertOptions) error {
+	// Reject bad options before reading a possibly large file
+	if err := output.ValidateFormat(opts.OutputFormat); err != nil {
+		return err
+	}
+	if _, err := output.ParseFieldPaths(opts.Fields); err != nil {
+		return err
+	}
+	conditions, err := output.ParseConditions(opts.Where)
+	if err != nil {
+		return err
+	}
+	if err := opts.Filters.Validate(); err != nil {
+		return err
+	}
+	if opts.Encrypt {
+		if _, err := output.Passphrase(); err != nil {
+			return err
+		}
+	}
+
+	infrastructures, err := output.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read discovery results: %w", err)
+	}
+	if opts.Provider != "" {
+		infrastructures = filterByProvider(infrastructures, opts.Provider)
+	}
+	if n := discovery.ApplyFilters(infrastructures, opts.Filters); n > 0 {
+		log.Info("Excluded VMs by filters", "count", n)
+	}
+	if len(conditions) > 0 {
+		before := getTotalResourceCount(infrastructures)
+		infrastructures = output.Where(infrastructures, conditions)
+		log.Info("Applied --where conditions",
+			"conditions", len(conditions),
+			"infrastructures", len(infrastructures),
+			"excluded_resources", before-getTotalResourceCount(infrastructures))
+	}
+
+	// Output settings default as they do for discover
+	if cfg.Output.YAMLDocuments {
+		opts.YAMLDocs = true
+	}
+	if opts.MaxFieldSize == 0 && !strings.EqualFold(opts.OutputFormat, "json") {
+		opts.MaxFieldSize = cfg.Output.MaxFieldSize
+	}
+
+	log.Info("Converting discovery results",
+		"file", inputFile,
+		"format", opts.OutputFormat,
+		"infrastructures", len(infrastructures),
+		"total_resources", getTotalResourceCount(infrastructures))
+
+	return outputResults(log, &DiscoverOptions{
+		OutputFormat: opts.OutputFormat,
+		OutputFile:   opts.OutputFile,
+		Fields:       opts.Fields,
+		YAMLDocs:     opts.YAMLDocs,
+		MaxFieldSize: opts.MaxFieldSize,
+		Summary:      opts.Summary,
+		Encrypt:      opts.Encrypt,
+	}, infrastructures)
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/output"
+)
+
+// convertFixture returns two infrastructures with VMs, a network and a
+// datastore, which every input format can carry
+func convertFixture() []*models.Infrastructure {
+	discovered := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []*models.Infrastructure{
+		{
+			Provider: "vmware", Server: "vcenter.example.com", DiscoveryTime: discovered,
+			VirtualMachines: []models.VirtualMachine{
+				{ID: "vm-1", Name: "web-01", PowerState: "poweredOn", CPUs: 2, Memory: 4096,
+					Disks:        []models.Disk{{Name: "Hard disk 1", Size: 40, Datastore: "ds1"}},
+					NetworkCards: []models.NetworkCard{{Name: "Network adapter 1", Network: "VM Network"}}},
+				{ID: "vm-2", Name: "db-01", PowerState: "poweredOff", CPUs: 8, Memory: 16384},
+			},
+			Networks: []models.Network{{ID: "network-1", Name: "VM Network", Type: "Network"}},
+			Storage:  []models.Storage{{ID: "datastore-1", Name: "ds1", Type: "VMFS", Capacity: 1024, FreeSpace: 512, Accessible: true}},
+		},
+		{
+			Provider: "proxmox", Server: "pve.example.com", DiscoveryTime: discovered,
+			VirtualMachines: []models.VirtualMachine{{ID: "100", Name: "app-01", PowerState: "running", CPUs: 4, Memory: 2048}},
+		},
+	}
+}
+
+// convertInputs writes the fixture in every input layout convert reads,
+// each also encrypted, and returns the files by layout name
+func convertInputs(t *testing.T) map[string]string {
+	t.Helper()
+
+	infrastructures := convertFixture()
+	format := func(name string, docs bool) []byte {
+		data, err := output.NewFormatter(name).WithYAMLDocuments(docs).Format(infrastructures)
+		if err != nil {
+			t.Fatalf("Format %s: %v", name, err)
+		}
+		return data
+	}
+	var ndjson bytes.Buffer
+	for _, infra := range infrastructures {
+		line, err := json.Marshal(infra)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ndjson.Write(append(line, '\n'))
+	}
+
+	plain := map[string]struct {
+		file string
+		data []byte
+	}{
+		"json":      {"discovery.json", format("json", false)},
+		"ndjson":    {"discovery.ndjson", ndjson.Bytes()},
+		"yaml":      {"discovery.yaml", format("yaml", false)},
+		"yaml-docs": {"discovery-docs.yaml", format("yaml", true)},
+		"csv":       {"discovery.csv", format("csv", false)},
+	}
+
+	dir := t.TempDir()
+	passphrase, err := output.Passphrase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := make(map[string]string)
+	for name, input := range plain {
+		encrypted, err := output.Encrypt(input.data, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for layout, content := range map[string][]byte{name: input.data, name + " encrypted": encrypted} {
+			file := filepath.Join(dir, input.file)
+			if layout != name {
+				file += output.EncryptedSuffix
+			}
+			if err := os.WriteFile(file, content, 0600); err != nil {
+				t.Fatal(err)
+			}
+			inputs[layout] = file
+		}
+	}
+	return inputs
+}
+
+// vmNames returns the sorted names of the VMs in infrastructures
+func vmNames(infrastructures []*models.Infrastructure) []string {
+	var names []string
+	for _, infra := range infrastructures {
+		for _, vm := range infra.VirtualMachines {
+			names = append(names, vm.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestConvertFormatMatrix(t *testing.T) {
+	t.Setenv(output.PassphraseEnv, "correct horse battery staple")
+	inputs := convertInputs(t)
+	log := logger.NewWithOutput(io.Discard)
+	want := []string{"app-01", "db-01", "web-01"}
+
+	for layout, input := range inputs {
+		for _, format := range output.Formats {
+			t.Run(layout+" to "+format, func(t *testing.T) {
+				out := filepath.Join(t.TempDir(), "out."+format)
+				if err := runConvert(log, &config.Config{}, input, &ConvertOptions{OutputFormat: format, OutputFile: out}); err != nil {
+					t.Fatalf("runConvert: %v", err)
+				}
+				data, err := os.ReadFile(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				switch format {
+				case "json", "yaml", "csv":
+					// Machine-readable output reads back to the same VMs
+					got, err := output.ReadFile(out)
+					if err != nil {
+						t.Fatalf("reading %s output back: %v", format, err)
+					}
+					if names := vmNames(got); strings.Join(names, ",") != strings.Join(want, ",") {
+						t.Errorf("%s output holds VMs %v, want %v", format, names, want)
+					}
+				case "summary":
+					if !bytes.Contains(data, []byte("Grand Total: 5")) {
+						t.Errorf("summary does not count every resource:\n%s", data)
+					}
+				default:
+					for _, name := range want {
+						if !bytes.Contains(data, []byte(name)) {
+							t.Errorf("%s output does not mention %s:\n%s", format, name, data)
+						}
+					}
+					if !bytes.Contains(data, []byte("vcenter.example.com")) {
+						t.Errorf("%s output does not mention the vCenter:\n%s", format, data)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestConvertEncryptedOutput(t *testing.T) {
+	t.Setenv(output.PassphraseEnv, "correct horse battery staple")
+	inputs := convertInputs(t)
+	log := logger.NewWithOutput(io.Discard)
+
+	out := filepath.Join(t.TempDir(), "discovery.json.enc")
+	opts := &ConvertOptions{OutputFormat: "json", OutputFile: out, Encrypt: true}
+	if err := runConvert(log, &config.Config{}, inputs["yaml"], opts); err != nil {
+		t.Fatalf("runConvert: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !output.IsEncrypted(data) || bytes.Contains(data, []byte("web-01")) {
+		t.Fatal("output is not encrypted")
+	}
+	if info, err := os.Stat(out); err == nil && info.Mode().Perm() != 0600 {
+		t.Errorf("encrypted output mode = %v, want 0600", info.Mode().Perm())
+	}
+	got, err := output.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading encrypted output back: %v", err)
+	}
+	if len(vmNames(got)) != 3 {
+		t.Errorf("encrypted output holds VMs %v", vmNames(got))
+	}
+
+	t.Setenv(output.PassphraseEnv, "")
+	if err := runConvert(log, &config.Config{}, inputs["json encrypted"], &ConvertOptions{OutputFormat: "table", OutputFile: out}); err == nil {
+		t.Error("encrypted input converted without a passphrase")
+	}
+}
+
+func TestConvertWhereAndFields(t *testing.T) {
+	t.Setenv(output.PassphraseEnv, "correct horse battery staple")
+	inputs := convertInputs(t)
+	log := logger.NewWithOutput(io.Discard)
+
+	tests := []struct {
+		name  string
+		where []string
+		want  []string
+	}{
+		{name: "resource list", where: []string{"virtual_machines.cpus>=4"}, want: []string{"app-01", "db-01"}},
+		{name: "infrastructure", where: []string{"provider=vmware"}, want: []string{"db-01", "web-01"}},
+		{name: "both", where: []string{"provider=vmware", "virtual_machines.power_state!=poweredOff"}, want: []string{"web-01"}},
+		{name: "nested list", where: []string{"virtual_machines.disks.datastore=ds*"}, want: []string{"web-01"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "out.json")
+			opts := &ConvertOptions{OutputFormat: "json", OutputFile: out, Where: tt.where}
+			if err := runConvert(log, &config.Config{}, inputs["json encrypted"], opts); err != nil {
+				t.Fatalf("runConvert: %v", err)
+			}
+			got, err := output.ReadFile(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := vmNames(got); strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("--where %v kept VMs %v, want %v", tt.where, names, tt.want)
+			}
+		})
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out.yaml")
+		opts := &ConvertOptions{OutputFormat: "yaml", OutputFile: out, Fields: []string{"virtual_machines.name"}, Where: []string{"virtual_machines.name=web-*"}}
+		if err := runConvert(log, &config.Config{}, inputs["ndjson"], opts); err != nil {
+			t.Fatalf("runConvert: %v", err)
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(data, []byte("name: web-01")) || bytes.Contains(data, []byte("db-01")) || bytes.Contains(data, []byte("cpus")) {
+			t.Errorf("projected output:\n%s", data)
+		}
+	})
+
+	t.Run("bad condition", func(t *testing.T) {
+		opts := &ConvertOptions{OutputFormat: "json", Where: []string{"virtual_machines.nope=1"}}
+		if err := runConvert(log, &config.Config{}, inputs["json"], opts); err == nil {
+			t.Error("unknown --where field accepted")
+		}
+	})
+}
@@ -2,30 +2,63 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"valhalla/internal/config"
 	"valhalla/internal/discovery"
+	"valhalla/internal/enrich"
 	"valhalla/internal/logger"
 	"valhalla/internal/models"
 	"valhalla/internal/output"
+	"valhalla/internal/signing"
+	"valhalla/pkg/valhalla"
 )
 
 // DiscoverOptions holds options for the discover command
 type DiscoverOptions struct {
 	Providers    []string
+	Profile      string // named provider profile, or all; empty for the default
 	OutputFormat string
 	OutputFile   string
 	Datacenter   string
 	Cluster      string
 	Node         string
-	Concurrent   int
+	Concurrent   int // API requests at once within each provider; 0 uses the configured concurrency
+	MaxProviders int // providers discovered at once; 0 uses discover.max_providers
 	Timeout      time.Duration
 	DryRun       bool
+	WithAlarms   bool
+	WithPermissions bool
+	WithContentLibraries bool
+	YAMLDocs     bool
+	MaxFieldSize int
+	AllNetworks  bool
+	IncludeSnapshots bool
+	IncludeTemplates bool
+	DeepStorageScan bool
+	VerifyNetwork   bool
+	Fields       []string
+	DumpRaw      string
+	Summary      bool
+	CacheTTL     time.Duration
+	NoCache      bool
+	ClearCache   bool
+	SignKey      string // private key signing the output file
+	Encrypt      bool   // encrypt the output file with VALHALLA_PASSPHRASE
+
+	// Exclusions given on the command line, merged over discover.default_filters
+	Filters          config.FilterConfig
+	NoDefaultFilters bool
+
+	// VMware VMs to discover; the others are never listed
+	VMs config.VMSelection
 }
 
 // NewDiscoverCmd creates the discover command
@@ -46,27 +79,111 @@ Examples:
   
   # Discover all supported providers
   valhalla discover --provider vmware,proxmox,nutanix
+
+  # Discover the providers listed under discover.providers in the config file
+  valhalla discover
+
+  # Discover the vCenter configured as providers.vmware.profiles.dr
+  valhalla discover --provider vmware --profile dr
+
+  # Discover every configured vCenter, one infrastructure per endpoint
+  valhalla discover --provider vmware --profile all
   
   # Save results to file
-  valhalla discover --provider vmware --output-file infrastructure.json`,
+  valhalla discover --provider vmware --output-file infrastructure.json
+
+  # Export a spreadsheet and print resource counts alongside it
+  valhalla discover --provider vmware --format csv -o inventory.csv --summary
+
+  # Write a self-contained HTML report to share
+  valhalla discover --provider vmware -f html -o report.html
+
+  # Draw the topology with Graphviz
+  valhalla discover --provider vmware -f dot -o topo.dot && dot -Tpng topo.dot -o topo.png
+
+  # Only the powered on VMs of the Prod folder whose names contain web or api
+  valhalla discover --provider vmware --power-state poweredOn --folder Prod --name web --name api
+
+  # List templates among the VMs as well as in the templates section
+  valhalla discover --provider vmware --include-templates
+
+  # Leave out powered off VMs and anything in a Sandbox folder
+  valhalla discover --provider vmware --exclude-power-state poweredOff --exclude-folder "*/Sandbox"
+
+  # Reuse results discovered in the last 10 minutes instead of reconnecting
+  valhalla discover --provider vmware --cache-ttl 10m
+
+  # Remove cached discovery results
+  valhalla discover --clear-cache
+
+  # Check reverse DNS and SSH/RDP reachability of each VM's IP
+  valhalla discover --provider vmware --verify-network
+
+  # Sign the results, provenance included, with a key from valhalla sign keygen
+  valhalla discover --provider vmware --format json -o discovery.json --sign-key valhalla.key
+
+  # Encrypt the results with the passphrase in VALHALLA_PASSPHRASE
+  valhalla discover --provider vmware --format json -o discovery.json.enc --encrypt
+
+  # Keep only VM names and disk sizes
+  valhalla discover --provider vmware --format json --fields virtual_machines.name,virtual_machines.disks.size`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("cache-ttl") {
+				opts.CacheTTL = cfg.Discover.CacheTTL
+			}
+			if !cmd.Flags().Changed("sign-key") {
+				opts.SignKey = cfg.Signing.KeyFile
+			} else if opts.OutputFile == "" {
+				return fmt.Errorf("--sign-key needs --output-file; output written to stdout can't be signed")
+			}
+			if opts.Encrypt && opts.OutputFile == "" {
+				return fmt.Errorf("--encrypt needs --output-file")
+			}
 			return runDiscover(log, cfg, opts)
 		},
 	}
 
 	// Add flags
-	cmd.Flags().StringSliceVarP(&opts.Providers, "provider", "p", []string{}, "Providers to discover (vmware, proxmox, nutanix)")
-	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "table", "Output format (table, json, yaml)")
+	cmd.Flags().StringSliceVarP(&opts.Providers, "provider", "p", []string{}, "Providers to discover (vmware, proxmox, nutanix); defaults to discover.providers from config")
+	cmd.Flags().StringVar(&opts.Profile, "profile", "", "Provider profile to discover, from providers.<provider>.profiles, or all for every configured profile (defaults to the flat provider keys)")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "table", "Output format ("+strings.Join(output.Formats, ", ")+")")
 	cmd.Flags().StringVarP(&opts.OutputFile, "output-file", "o", "", "Output file path")
+	cmd.Flags().BoolVar(&opts.Summary, "summary", false, "Also print a discovery summary to stderr")
+	cmd.Flags().StringVar(&opts.SignKey, "sign-key", "", "Ed25519 private key (PEM) to sign the output file with, writing <output-file>.sig (defaults to signing.key_file)")
+	cmd.Flags().BoolVar(&opts.Encrypt, "encrypt", false, "Encrypt the output file with the passphrase in "+output.PassphraseEnv)
 	cmd.Flags().StringVar(&opts.Datacenter, "datacenter", "", "VMware datacenter to discover")
 	cmd.Flags().StringVar(&opts.Cluster, "cluster", "", "Cluster to discover")
 	cmd.Flags().StringVar(&opts.Node, "node", "", "Proxmox node to discover")
-	cmd.Flags().IntVar(&opts.Concurrent, "concurrent", 10, "Number of concurrent discovery operations")
+	cmd.Flags().IntVar(&opts.Concurrent, "concurrent", 0, "Concurrent API requests within each provider (defaults to each provider's concurrency from config, 10 unless set)")
+	cmd.Flags().IntVar(&opts.MaxProviders, "max-providers", 0, "Number of providers discovered at once (defaults to discover.max_providers; 0 discovers all at once)")
 	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Minute, "Discovery timeout")
+	cmd.Flags().DurationVar(&opts.CacheTTL, "cache-ttl", 0, "Reuse discovery results cached under the output directory for this long, e.g. 10m (0 disables; defaults to discover.cache_ttl)")
+	cmd.Flags().BoolVar(&opts.NoCache, "no-cache", false, "Connect to every provider even when fresh cached results exist")
+	cmd.Flags().BoolVar(&opts.ClearCache, "clear-cache", false, "Remove all cached discovery results and exit")
 	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Perform a dry run without making API calls")
-
-	// Mark required flags
-	cmd.MarkFlagRequired("provider")
+	cmd.Flags().IntVar(&opts.MaxFieldSize, "max-field-size", 0, "Truncate annotation and metadata values over this many bytes (JSON is only truncated when set)")
+	cmd.Flags().BoolVar(&opts.YAMLDocs, "yaml-docs", false, "Emit one YAML document per infrastructure (yaml format only)")
+	cmd.Flags().BoolVar(&opts.AllNetworks, "all-networks", false, "List all datacenter networks and datastores even when --cluster is set (VMware)")
+	cmd.Flags().StringSliceVar(&opts.Fields, "fields", []string{}, "Only include these dotted field paths in json/yaml output, e.g. virtual_machines.name,networks.* (provider, server and discovery_time are always kept)")
+	cmd.Flags().BoolVar(&opts.IncludeSnapshots, "include-snapshots", false, "List each VM's snapshot tree with names, dates and sizes, not just the count (VMware)")
+	cmd.Flags().BoolVar(&opts.IncludeTemplates, "include-templates", false, "Also list templates among the VMs, marked config.template; they stay in the templates section too and are counted once (VMware, Proxmox)")
+	cmd.Flags().BoolVar(&opts.WithAlarms, "with-alarms", false, "Include triggered alarms in discovery results (VMware)")
+	cmd.Flags().BoolVar(&opts.WithPermissions, "with-permissions", false, "Include permissions and their principals' type, enabled state and group size (VMware)")
+	cmd.Flags().BoolVar(&opts.WithContentLibraries, "with-content-libraries", false, "Include content libraries and their items (VMware, vCenter 6.0 and later)")
+	cmd.Flags().BoolVar(&opts.DeepStorageScan, "deep-storage-scan", false, "List every ISO, template and volume on each storage, not just per-content totals (Proxmox)")
+	cmd.Flags().BoolVar(&opts.VerifyNetwork, "verify-network", false, "Check each VM's primary IP: reverse DNS, forward confirmation and TCP probes of network_check.ports (private addresses only unless network_check.probe_public)")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeNames, "exclude-name", []string{}, "Leave out VMs whose name matches these patterns, e.g. tmp-*")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeFolders, "exclude-folder", []string{}, "Leave out VMs in these folders or their subfolders (patterns allowed)")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludePowerStates, "exclude-power-state", []string{}, "Leave out VMs in these power states, e.g. poweredOff or stopped")
+	cmd.Flags().StringSliceVar(&opts.Filters.ExcludeTags, "exclude-tag", []string{}, "Leave out VMs with a tag matching these patterns, e.g. env:sandbox")
+	cmd.Flags().BoolVar(&opts.NoDefaultFilters, "no-default-filters", false, "Ignore discover.default_filters from the config file")
+	cmd.Flags().StringVar(&opts.VMs.PowerState, "power-state", "", "Only discover VMs in this power state: poweredOn, poweredOff or suspended (VMware)")
+	cmd.Flags().StringArrayVar(&opts.VMs.Names, "name", []string{}, "Only discover VMs whose name contains this, ignoring case; repeat for several (VMware)")
+	cmd.Flags().StringVar(&opts.VMs.Host, "host", "", "Only discover VMs running on this ESXi host (VMware)")
+	cmd.Flags().StringVar(&opts.VMs.ResourcePool, "resource-pool", "", "Only discover VMs in this resource pool or vApp (VMware)")
+	cmd.Flags().StringVar(&opts.VMs.Folder, "folder", "", "Only discover VMs in this folder or its subfolders, e.g. Prod/Web or /DC0/vm/Prod (VMware)")
+	cmd.Flags().StringVar(&opts.DumpRaw, "dump-raw", "", "Write raw API objects next to the converted models in this directory (requires --debug)")
+	cmd.Flags().MarkHidden("dump-raw")
 
 	return cmd
 }
@@ -76,6 +193,42 @@ func runDiscover(log *logger.Logger, cfg *config.Config, opts *DiscoverOptions)
 	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
 	defer cancel()
 
+	cacheDir := filepath.Join(cfg.Output.Directory, discovery.CacheDirName)
+	if opts.ClearCache {
+		removed, err := discovery.NewCache(cacheDir, 0).Clear()
+		if err != nil {
+			return err
+		}
+		log.Info("Cleared discovery cache", "directory", cacheDir, "entries", removed)
+		return nil
+	}
+
+	// Fall back to the configured provider list
+	if len(opts.Providers) == 0 {
+		opts.Providers = cfg.Discover.Providers
+	}
+	if len(opts.Providers) == 0 {
+		return fmt.Errorf("no providers specified: use --provider or set discover.providers in the config file")
+	}
+	if err := output.ValidateFormat(opts.OutputFormat); err != nil {
+		return err
+	}
+	if err := validateVMSelection(&opts.VMs); err != nil {
+		return err
+	}
+	// Catch an unusable signing key or a missing passphrase before a long
+	// discovery run
+	if opts.SignKey != "" && opts.OutputFile != "" {
+		if _, err := signing.LoadPrivateKey(opts.SignKey); err != nil {
+			return err
+		}
+	}
+	if opts.Encrypt {
+		if _, err := output.Passphrase(); err != nil {
+			return err
+		}
+	}
+
 	log.StartOperation("Infrastructure discovery", "providers", opts.Providers)
 
 	// Validate configuration
@@ -84,59 +237,169 @@ func runDiscover(log *logger.Logger, cfg *config.Config, opts *DiscoverOptions)
 	}
 
 	// Initialize discovery engine
+	if opts.MaxProviders == 0 {
+		opts.MaxProviders = cfg.Discover.MaxProviders
+	}
 	engine := discovery.NewEngine(log, cfg)
+	engine.SetMaxConcurrentProviders(opts.MaxProviders)
 
-	// Aggregate results from all providers
-	var allResults []*models.Infrastructure
+	// Raw dumps need a live connection, so they always bypass the cache
+	if opts.CacheTTL > 0 && !opts.NoCache && opts.DumpRaw == "" {
+		engine.SetCache(discovery.NewCache(cacheDir, opts.CacheTTL))
+	}
 
-	// Discover from each provider
 	for _, provider := range opts.Providers {
-		providerLog := log.WithProvider(provider)
-		
-		if opts.DryRun {
-			providerLog.Info("Dry run mode - skipping actual discovery")
-			continue
+		switch strings.ToLower(provider) {
+		case "vmware", "vsphere", "proxmox", "nutanix":
+		default:
+			return fmt.Errorf("unsupported provider: %s", provider)
+		}
+	}
+
+	// Check every provider's credentials before connecting to any of them,
+	// reporting all that are missing at once
+	var problems []string
+	for _, provider := range opts.Providers {
+		profiles, err := providerProfiles(cfg, provider, opts.Profile)
+		if err != nil {
+			return err
+		}
+		for _, profile := range profiles {
+			if err := engine.ValidateProfileConfig(provider, profile); err != nil {
+				problems = append(problems, fmt.Sprintf("  %s\n    fix: %s", err, authFixCommand(provider, err)))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("provider configuration incomplete:\n%s", strings.Join(problems, "\n"))
+	}
+
+	// Raw dumps contain full API objects and are only meant for debugging
+	if opts.DumpRaw != "" && !viper.GetBool("debug") {
+		return fmt.Errorf("--dump-raw requires --debug")
+	}
+
+	// Reject unknown --fields paths before spending time on discovery
+	if _, err := output.ParseFieldPaths(opts.Fields); err != nil {
+		return err
+	}
+
+	// Flags replace the configured default for the same filter
+	filters := opts.Filters
+	if !opts.NoDefaultFilters {
+		var applied []string
+		filters, applied = cfg.Discover.DefaultFilters.Merge(opts.Filters)
+		if len(applied) > 0 {
+			log.Info("Applying default discovery filters", "filters", strings.Join(applied, " "))
 		}
+	}
+	if err := filters.Validate(); err != nil {
+		return err
+	}
 
+	if opts.DryRun {
+		for _, provider := range opts.Providers {
+			log.WithProvider(provider).Info("Dry run mode - skipping actual discovery")
+		}
+		return nil
+	}
+
+	// Discover each provider concurrently under its own deadline
+	results := engine.RunProviders(ctx, opts.Providers, func(ctx context.Context, provider string) ([]*models.Infrastructure, error) {
+		providerLog := log.WithProvider(provider)
 		providerLog.StartOperation("Provider discovery")
 
+		var infrastructures []*models.Infrastructure
+		var err error
 		switch strings.ToLower(provider) {
 		case "vmware", "vsphere":
-			results, err := discoverVMware(ctx, engine, providerLog, cfg, opts)
-			if err != nil {
-				providerLog.FailOperation("VMware discovery", err)
-				return err
-			}
-			allResults = append(allResults, results...)
-
+			infrastructures, err = discoverVMware(ctx, providerLog, engine, cfg, opts)
 		case "proxmox":
-			results, err := discoverProxmox(ctx, engine, providerLog, cfg, opts)
-			if err != nil {
-				providerLog.FailOperation("Proxmox discovery", err)
-				return err
-			}
-			allResults = append(allResults, results...)
-
+			infrastructures, err = discoverProxmox(ctx, providerLog, engine, cfg, opts)
 		case "nutanix":
-			results, err := discoverNutanix(ctx, engine, providerLog, cfg, opts)
-			if err != nil {
-				providerLog.FailOperation("Nutanix discovery", err)
-				return err
-			}
-			allResults = append(allResults, results...)
-
-		default:
-			return fmt.Errorf("unsupported provider: %s", provider)
+			infrastructures, err = discoverNutanix(ctx, providerLog, engine, cfg, opts)
+		}
+		// Profiles that succeeded alongside a failed one are still
+		// reported, so their results are completed too
+		if n := discovery.ApplyFilters(infrastructures, filters); n > 0 {
+			providerLog.Info("Excluded VMs by discovery filters", "count", n)
+		}
+		recordProvenance(infrastructures, provider, cfg, opts, filters)
+		if err != nil {
+			providerLog.FailOperation("Provider discovery", err)
+			return infrastructures, err
 		}
 
 		providerLog.CompleteOperation("Provider discovery")
+		return infrastructures, nil
+	})
+
+	// Aggregate results from all providers
+	var allResults []*models.Infrastructure
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result.Provider)
+		}
+		allResults = append(allResults, result.Infrastructures...)
+	}
+
+	// Infer ownership when rules are configured
+	if len(cfg.Ownership.Rules) > 0 {
+		if _, err := applyOwnership(log, cfg.Ownership.Rules, allResults); err != nil {
+			return fmt.Errorf("invalid ownership rules: %w", err)
+		}
+	}
+
+	// Score how much manual work each VM needs to migrate
+	enrich.ScoreComplexity(allResults, cfg.Complexity)
+
+	// Flag VMs whose hardware version holds back newer features
+	if n := enrich.HardwareAdvisories(allResults, cfg.Hardware.MinVersion); n > 0 {
+		log.Info("Found VMs below the minimum hardware version", "count", n, "min_version", cfg.Hardware.MinVersion)
+	}
+
+	// Deep delta disk chains slow the VM down and make migration take longer
+	if n := enrich.DiskChainAdvisories(allResults); n > 0 {
+		log.Warn("Found disks with deep delta disk chains", "disks", n, "max_depth", enrich.MaxDiskChainDepth)
+	}
+
+	// Propose storage moves for datastores over the utilization threshold
+	if n := enrich.RebalanceStorage(allResults, cfg.Storage.RebalanceThreshold); n > 0 {
+		log.Info("Proposed storage moves for datastores over the utilization threshold", "moves", n, "threshold", cfg.Storage.RebalanceThreshold)
+	}
+
+	// Resolve and probe guest IPs; every lookup and probe has its own
+	// timeout, so the spent discovery deadline doesn't apply
+	if opts.VerifyNetwork {
+		stats := enrich.VerifyNetwork(context.Background(), allResults, cfg.NetworkCheck)
+		log.Info("Verified VM network addresses", "checked", stats.Checked, "dns_only", stats.Skipped)
+		if len(stats.Mismatched) > 0 {
+			log.Warn("VMs without matching reverse DNS", "count", len(stats.Mismatched), "vms", strings.Join(stats.Mismatched, ", "))
+		}
+		if len(stats.Unreachable) > 0 {
+			log.Warn("VMs with none of the probed ports reachable", "count", len(stats.Unreachable), "ports", cfg.NetworkCheck.Ports, "vms", strings.Join(stats.Unreachable, ", "))
+		}
 	}
 
 	// Output results
+	if cfg.Output.YAMLDocuments {
+		opts.YAMLDocs = true
+	}
+	// JSON keeps full values unless a limit was given explicitly
+	if opts.MaxFieldSize == 0 && !strings.EqualFold(opts.OutputFormat, "json") {
+		opts.MaxFieldSize = cfg.Output.MaxFieldSize
+	}
 	if err := outputResults(log, opts, allResults); err != nil {
 		return fmt.Errorf("failed to output results: %w", err)
 	}
 
+	logProviderSummary(log, results)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("discovery failed for providers: %s", strings.Join(failed, ", "))
+	}
+
 	log.CompleteOperation("Infrastructure discovery", 
 		"total_resources", getTotalResourceCount(allResults),
 		"providers", len(opts.Providers))
@@ -144,13 +407,79 @@ func runDiscover(log *logger.Logger, cfg *config.Config, opts *DiscoverOptions)
 	return nil
 }
 
+// logProviderSummary reports how long each provider took and whether it timed out
+func logProviderSummary(log *logger.Logger, results []discovery.ProviderRun) {
+	for _, result := range results {
+		status := "ok"
+		switch {
+		case result.TimedOut:
+			status = "timed out"
+		case !result.Success:
+			status = "failed"
+		}
+
+		args := []interface{}{
+			"provider", result.Provider,
+			"status", status,
+			"duration", result.Duration,
+			"resources", result.ResourceCount,
+		}
+		if result.Timeout != "" {
+			args = append(args, "timeout", result.Timeout)
+		}
+		for _, infra := range result.Infrastructures {
+			if version := output.ProviderVersion(infra); version != "" {
+				args = append(args, "version", version)
+				break
+			}
+		}
+		if !result.Success {
+			args = append(args, "error", result.Error)
+			log.Error("Provider discovery summary", args...)
+		} else {
+			log.Info("Provider discovery summary", args...)
+		}
+	}
+}
+
+// authFixCommand suggests the auth command that sets a provider's missing
+// settings; passwords are always prompted for rather than passed as flags
+func authFixCommand(provider string, err error) string {
+	provider = strings.ToLower(provider)
+	if provider == "vsphere" {
+		provider = "vmware"
+	}
+
+	command := "valhalla auth " + provider
+	var configErr *discovery.ConfigError
+	if errors.As(err, &configErr) {
+		if configErr.Profile != "" {
+			command += " --profile " + configErr.Profile
+		}
+		for _, setting := range configErr.Missing {
+			switch setting {
+			case "server":
+				command += " --server <server>"
+			case "username":
+				command += " --username <username>"
+			}
+		}
+	}
+	return command + " --save"
+}
+
 // discoverVMware discovers VMware infrastructure
-func discoverVMware(ctx context.Context, engine *discovery.Engine, log *logger.Logger, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
-	vmwareConfig := cfg.GetVMwareConfig()
-	
-	// Validate VMware configuration
-	if vmwareConfig.Server == "" {
-		return nil, fmt.Errorf("VMware server not configured")
+func discoverVMware(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	return discoverProfiles(log, cfg, "vmware", opts, func(log *logger.Logger, profile string) ([]*models.Infrastructure, error) {
+		return discoverVMwareProfile(ctx, log, engine, cfg, profile, opts)
+	})
+}
+
+// discoverVMwareProfile discovers the vCenter of one VMware profile
+func discoverVMwareProfile(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, profile string, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	vmwareConfig, err := cfg.GetVMwareProfile(profile)
+	if err != nil {
+		return nil, err
 	}
 
 	// Override datacenter if specified
@@ -160,60 +489,206 @@ func discoverVMware(ctx context.Context, engine *discovery.Engine, log *logger.L
 	if opts.Cluster != "" {
 		vmwareConfig.Cluster = opts.Cluster
 	}
+	if opts.WithAlarms {
+		vmwareConfig.WithAlarms = true
+	}
+	if opts.WithPermissions {
+		vmwareConfig.WithPermissions = true
+	}
+	if opts.WithContentLibraries {
+		vmwareConfig.WithContentLibraries = true
+	}
+	if opts.AllNetworks {
+		vmwareConfig.AllNetworks = true
+	}
+	if opts.IncludeSnapshots {
+		vmwareConfig.IncludeSnapshots = true
+	}
+	if opts.IncludeTemplates {
+		vmwareConfig.IncludeTemplates = true
+	}
+	if opts.DumpRaw != "" {
+		vmwareConfig.DumpRaw = opts.DumpRaw
+	}
+	if opts.Concurrent > 0 {
+		vmwareConfig.Discovery.Concurrency = opts.Concurrent
+	}
+	vmwareConfig.VMs = opts.VMs
 
-	log.Info("Connecting to VMware vCenter", "server", vmwareConfig.Server, "datacenter", vmwareConfig.Datacenter)
+	return engine.Cached(discovery.VMwareCacheKey(vmwareConfig), func() ([]*models.Infrastructure, error) {
+		log.Info("Connecting to VMware vCenter", "server", vmwareConfig.Server, "datacenter", vmwareConfig.Datacenter)
+		return discoverWith(ctx, valhalla.ProviderConfig{Provider: "vmware", VMware: vmwareConfig, Log: log})
+	})
+}
 
-	return engine.DiscoverVMware(ctx, vmwareConfig)
+// validateVMSelection checks the VM selection flags, normalizing the case of
+// the power state
+func validateVMSelection(vms *config.VMSelection) error {
+	if vms.PowerState == "" {
+		return nil
+	}
+	for _, state := range []string{"poweredOn", "poweredOff", "suspended"} {
+		if strings.EqualFold(vms.PowerState, state) {
+			vms.PowerState = state
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid --power-state %q: must be poweredOn, poweredOff or suspended", vms.PowerState)
 }
 
 // discoverProxmox discovers Proxmox infrastructure
-func discoverProxmox(ctx context.Context, engine *discovery.Engine, log *logger.Logger, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
-	proxmoxConfig := cfg.GetProxmoxConfig()
-	
-	// Validate Proxmox configuration
-	if proxmoxConfig.Server == "" {
-		return nil, fmt.Errorf("Proxmox server not configured")
+func discoverProxmox(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	return discoverProfiles(log, cfg, "proxmox", opts, func(log *logger.Logger, profile string) ([]*models.Infrastructure, error) {
+		return discoverProxmoxProfile(ctx, log, engine, cfg, profile, opts)
+	})
+}
+
+// discoverProxmoxProfile discovers the cluster of one Proxmox profile
+func discoverProxmoxProfile(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, profile string, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	proxmoxConfig, err := cfg.GetProxmoxProfile(profile)
+	if err != nil {
+		return nil, err
 	}
 
 	// Override node if specified
 	if opts.Node != "" {
 		proxmoxConfig.Node = opts.Node
 	}
+	if opts.DeepStorageScan {
+		proxmoxConfig.DeepStorageScan = true
+	}
+	if opts.IncludeTemplates {
+		proxmoxConfig.IncludeTemplates = true
+	}
+	if opts.Concurrent > 0 {
+		proxmoxConfig.Concurrency = opts.Concurrent
+	}
 
-	log.Info("Connecting to Proxmox", "server", proxmoxConfig.Server, "node", proxmoxConfig.Node)
-
-	return engine.DiscoverProxmox(ctx, proxmoxConfig)
+	return engine.Cached(discovery.ProxmoxCacheKey(proxmoxConfig), func() ([]*models.Infrastructure, error) {
+		log.Info("Connecting to Proxmox", "server", proxmoxConfig.Server, "node", proxmoxConfig.Node)
+		return discoverWith(ctx, valhalla.ProviderConfig{Provider: "proxmox", Proxmox: proxmoxConfig, Log: log})
+	})
 }
 
 // discoverNutanix discovers Nutanix infrastructure
-func discoverNutanix(ctx context.Context, engine *discovery.Engine, log *logger.Logger, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
-	nutanixConfig := cfg.GetNutanixConfig()
-	
-	// Validate Nutanix configuration
-	if nutanixConfig.Server == "" {
-		return nil, fmt.Errorf("Nutanix server not configured")
+func discoverNutanix(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	return discoverProfiles(log, cfg, "nutanix", opts, func(log *logger.Logger, profile string) ([]*models.Infrastructure, error) {
+		return discoverNutanixProfile(ctx, log, engine, cfg, profile, opts)
+	})
+}
+
+// discoverNutanixProfile discovers the Prism endpoint of one Nutanix profile
+func discoverNutanixProfile(ctx context.Context, log *logger.Logger, engine *discovery.Engine, cfg *config.Config, profile string, opts *DiscoverOptions) ([]*models.Infrastructure, error) {
+	nutanixConfig, err := cfg.GetNutanixProfile(profile)
+	if err != nil {
+		return nil, err
 	}
 
 	// Override cluster if specified
 	if opts.Cluster != "" {
 		nutanixConfig.Cluster = opts.Cluster
 	}
+	if opts.Concurrent > 0 {
+		nutanixConfig.Concurrency = opts.Concurrent
+	}
+
+	return engine.Cached(discovery.NutanixCacheKey(nutanixConfig), func() ([]*models.Infrastructure, error) {
+		log.Info("Connecting to Nutanix", "server", nutanixConfig.Server, "cluster", nutanixConfig.Cluster)
+		return discoverWith(ctx, valhalla.ProviderConfig{Provider: "nutanix", Nutanix: nutanixConfig, Log: log})
+	})
+}
 
-	log.Info("Connecting to Nutanix", "server", nutanixConfig.Server, "cluster", nutanixConfig.Cluster)
+// providerProfiles returns the profiles of provider that --profile selects
+func providerProfiles(cfg *config.Config, provider, profile string) ([]string, error) {
+	provider = strings.ToLower(provider)
+	if provider == "vsphere" {
+		provider = "vmware"
+	}
+	return cfg.SelectProfiles(provider, profile)
+}
 
-	return engine.DiscoverNutanix(ctx, nutanixConfig)
+// discoverProfiles runs discover for each profile of provider that
+// --profile selects, in turn, and marks the results with their profile in
+// the "profile" metadata when --profile is given. One failing profile
+// doesn't stop the others: the results of those that succeeded are
+// returned along with an error naming the profiles that failed.
+func discoverProfiles(log *logger.Logger, cfg *config.Config, provider string, opts *DiscoverOptions, discover func(log *logger.Logger, profile string) ([]*models.Infrastructure, error)) ([]*models.Infrastructure, error) {
+	profiles, err := providerProfiles(cfg, provider, opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Profile == "" {
+		return discover(log, profiles[0])
+	}
+
+	var infrastructures []*models.Infrastructure
+	var failed []string
+	for _, profile := range profiles {
+		profileLog := log.With("profile", profile)
+		results, err := discover(profileLog, profile)
+		if err != nil {
+			if len(profiles) == 1 {
+				return nil, err
+			}
+			profileLog.Error("Profile discovery failed", "error", err)
+			failed = append(failed, fmt.Sprintf("%s (%v)", profile, err))
+			continue
+		}
+		for _, infra := range results {
+			if infra.Metadata == nil {
+				infra.Metadata = make(map[string]interface{})
+			}
+			infra.Metadata["profile"] = profile
+		}
+		infrastructures = append(infrastructures, results...)
+	}
+	if len(failed) > 0 {
+		return infrastructures, fmt.Errorf("discovery failed for %s profiles: %s", provider, strings.Join(failed, ", "))
+	}
+	return infrastructures, nil
+}
+
+// discoverWith runs one provider's discovery through the public API
+func discoverWith(ctx context.Context, cfg valhalla.ProviderConfig) ([]*models.Infrastructure, error) {
+	infrastructure, err := valhalla.Discover(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Infrastructure{infrastructure}, nil
 }
 
 // outputResults outputs discovery results in the specified format
 func outputResults(log *logger.Logger, opts *DiscoverOptions, results []*models.Infrastructure) error {
 	// Create output formatter
-	formatter := output.NewFormatter(opts.OutputFormat)
+	formatter := output.NewFormatter(opts.OutputFormat).
+		WithYAMLDocuments(opts.YAMLDocs).
+		WithMaxFieldSize(opts.MaxFieldSize)
+
+	if len(opts.Fields) > 0 {
+		paths, err := output.ParseFieldPaths(opts.Fields)
+		if err != nil {
+			return err
+		}
+		formatter.WithFields(paths)
+	}
 
 	// Format results
 	formattedOutput, err := formatter.Format(results)
 	if err != nil {
 		return fmt.Errorf("failed to format output: %w", err)
 	}
+	if opts.Encrypt {
+		if opts.OutputFile == "" {
+			return fmt.Errorf("encrypted results must be written to a file")
+		}
+		passphrase, err := output.Passphrase()
+		if err != nil {
+			return err
+		}
+		if formattedOutput, err = output.Encrypt(formattedOutput, passphrase); err != nil {
+			return fmt.Errorf("failed to encrypt output: %w", err)
+		}
+	}
 
 	// Output to file or stdout
 	if opts.OutputFile != "" {
@@ -227,16 +702,41 @@ func outputResults(log *logger.Logger, opts *DiscoverOptions, results []*models.
 		}
 
 		// Write to file
-		if err := os.WriteFile(opts.OutputFile, formattedOutput, 0644); err != nil {
+		mode := os.FileMode(0644)
+		if opts.Encrypt {
+			mode = 0600
+		}
+		if err := os.WriteFile(opts.OutputFile, formattedOutput, mode); err != nil {
 			return fmt.Errorf("failed to write output file: %w", err)
 		}
 
 		log.Info("Results written to file", "file", opts.OutputFile)
+
+		// The signature covers the whole file, provenance block included
+		if opts.SignKey != "" {
+			key, err := signing.LoadPrivateKey(opts.SignKey)
+			if err != nil {
+				return err
+			}
+			sigPath, err := signing.SignFile(opts.OutputFile, key)
+			if err != nil {
+				return err
+			}
+			log.Info("Signed results", "signature", sigPath)
+		}
 	} else {
+		if opts.SignKey != "" {
+			log.Warn("Results written to stdout are not signed; use --output-file", "key", opts.SignKey)
+		}
 		// Write to stdout
 		fmt.Print(string(formattedOutput))
 	}
 
+	// The summary goes to stderr so it never mixes into piped output
+	if opts.Summary {
+		fmt.Fprint(os.Stderr, formatter.FormatSummary(results))
+	}
+
 	return nil
 }
 
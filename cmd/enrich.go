@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/enrich"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/output"
+)
+
+// EnrichOptions holds options for the enrich command
+type EnrichOptions struct {
+	RulesFile    string
+	OutputFile   string
+	OutputFormat string
+}
+
+// NewEnrichCmd creates the enrich command
+func NewEnrichCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &EnrichOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "enrich <discovery-file>",
+		Short: "Infer VM owners and environments in existing discovery results",
+		Long: `Infer the owner and environment of each VM from annotations, tags and
+folders, using ordered rules. The first rule that matches sets each field.
+
+Rules come from --rules or the ownership section of the config file:
+
+  rules:
+    - source: annotation   # annotation key, or a "owner: ..." line in the notes
+      key: owner
+    - source: tag          # tag written as Category:Value
+      key: Owner
+    - source: folder       # first capture group is the value
+      pattern: "^/?([^/]+)/"
+      field: environment
+    - source: default
+      value: unassigned
+
+The same rules are applied during discovery when set in the config file.
+
+Examples:
+  # Enrich a discovery file in place
+  valhalla enrich discovery.json --rules owners.yaml
+
+  # Write the enriched results to a new file
+  valhalla enrich discovery.json --rules owners.yaml -o enriched.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnrich(log, cfg, args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.RulesFile, "rules", "", "Ownership rules file (default: ownership.rules from the config)")
+	cmd.Flags().StringVarP(&opts.OutputFile, "output-file", "o", "", "Output file path (default: overwrite the input file)")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "json", "Output format (json, yaml)")
+
+	return cmd
+}
+
+// runEnrich applies ownership rules to an existing discovery file
+func runEnrich(log *logger.Logger, cfg *config.Config, inputFile string, opts *EnrichOptions) error {
+	log.StartOperation("Enrichment", "file", inputFile)
+
+	rules := cfg.Ownership.Rules
+	if opts.RulesFile != "" {
+		var err error
+		if rules, err = config.LoadOwnershipRules(opts.RulesFile); err != nil {
+			return err
+		}
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("no ownership rules: use --rules or set ownership.rules in the config file")
+	}
+
+	infrastructures, err := output.ReadFile(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read discovery results: %w", err)
+	}
+
+	stats, err := applyOwnership(log, rules, infrastructures)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, formatOwnershipStats(stats))
+
+	outputFile := opts.OutputFile
+	if outputFile == "" {
+		outputFile = inputFile
+	}
+	if err := outputResults(log, &DiscoverOptions{
+		OutputFormat: opts.OutputFormat,
+		OutputFile:   outputFile,
+	}, infrastructures); err != nil {
+		return err
+	}
+
+	log.CompleteOperation("Enrichment", "vms", stats.VMs, "owners", stats.Owners, "environments", stats.Environments)
+	return nil
+}
+
+// applyOwnership compiles the ownership rules, applies them and logs coverage
+func applyOwnership(log *logger.Logger, rules []config.OwnershipRule, infrastructures []*models.Infrastructure) (enrich.OwnershipStats, error) {
+	compiled, err := enrich.CompileOwnershipRules(rules)
+	if err != nil {
+		return enrich.OwnershipStats{}, err
+	}
+
+	stats := compiled.Apply(infrastructures)
+	for _, rule := range stats.Rules {
+		log.Debug("Ownership rule coverage", "rule", rule.Rule, "field", rule.Field, "matches", rule.Matches)
+	}
+	log.Info("Inferred VM ownership",
+		"vms", stats.VMs,
+		"with_owner", stats.Owners,
+		"with_environment", stats.Environments)
+
+	return stats, nil
+}
+
+// formatOwnershipStats renders per-rule match counts and overall coverage
+func formatOwnershipStats(stats enrich.OwnershipStats) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"#", "Rule", "Field", "Matches"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for i, rule := range stats.Rules {
+		table.Append([]string{fmt.Sprintf("%d", i+1), rule.Rule, rule.Field, fmt.Sprintf("%d", rule.Matches)})
+	}
+	table.Render()
+
+	output.WriteString(fmt.Sprintf("Owner coverage: %d/%d VMs, environment coverage: %d/%d VMs\n",
+		stats.Owners, stats.VMs, stats.Environments, stats.VMs))
+
+	return output.String()
+}
@@ -0,0 +1,18 @@
+package cmd
+
+// ExitCodeError is returned by commands that must end the process with a
+// specific exit code rather than the generic failure code
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+// Error returns the underlying error message
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *ExitCodeError) Unwrap() error {
+	return e.Err
+}
@@ -1,16 +1,26 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"valhalla/internal/config"
+	"valhalla/internal/enrich"
 	"valhalla/internal/generators"
 	"valhalla/internal/logger"
 	"valhalla/internal/models"
+	"valhalla/internal/output"
+	"valhalla/internal/signing"
+	"valhalla/pkg/valhalla"
 )
 
 // GenerateOptions holds options for the generate command
@@ -20,7 +30,50 @@ type GenerateOptions struct {
 	OutputDir    string
 	Provider     string
 	DryRun       bool
+	SummaryOnly  bool
 	Validate     bool
+	Flatten      bool
+	MaxFieldSize int
+	PreserveMAC  bool
+	SkipRDMVMs   bool
+	Stack        string
+	IncludeSecrets bool
+	AllowSecrets   bool
+
+	// Target virtual hardware version for older VMs; 0 keeps the discovered one
+	UpgradeHWVersion int
+
+	// Carry post_provision.* annotations into commented-out post-clone steps
+	EmitPostProvision bool
+	AllowLinkedClones bool
+	WithImports       bool
+	IncludeAllDataSources bool
+	CreateNetworks        bool
+
+	// Capacity of the cluster the VMs are generated for
+	TargetInfra    string
+	TargetCluster  string
+	StrictCapacity bool
+
+	// Resources that will not exist in the target environment
+	ExcludeDatastores []string
+	ExcludeNetworks   []string
+
+	// Skip VMs scoring above this migration complexity; negative for no limit
+	MaxComplexity int
+
+	// Pilot sampling
+	Sample          int
+	SamplePerGroup  int
+	GroupBy         string
+	Seed            int64
+	SeedSet         bool // --seed was given, so even 0 is used as is
+	ExcludeManifest string
+
+	// Ed25519 keys: the input must be signed by VerifyKey's private key,
+	// and provenance.json is signed with SignKey
+	VerifyKey string
+	SignKey   string
 }
 
 // NewGenerateCmd creates the generate command
@@ -38,6 +91,12 @@ Supports multiple output formats:
 - Ansible playbooks
 - Custom templates
 
+Hooks listed under generate.hooks in the config post-process each generated
+file before it is checked and written, e.g. to inject a company header. A
+hook runs a command with the file's content on stdin, in an empty temporary
+directory, or renders a Go template; its output replaces the content and a
+failing hook aborts generation. See examples/hooks.
+
 Examples:
   # Generate Terraform from discovery results
   valhalla generate --input discovery.json --format terraform --output-dir ./terraform
@@ -46,19 +105,81 @@ Examples:
   valhalla generate --input discovery.json --format pulumi-typescript --output-dir ./pulumi
   
   # Generate for specific provider only
-  valhalla generate --input discovery.json --provider vmware --format terraform`,
+  valhalla generate --input discovery.json --provider vmware --format terraform
+
+  # Adopt existing VMs: terraform plan shows no changes after the imports
+  valhalla generate --input discovery.json --format terraform --with-imports
+
+  # Recreate the port groups too, with their VLANs and security policies
+  valhalla generate --input discovery.json --format terraform --create-networks
+
+  # Check the VMs fit cluster "DR" of another environment, failing if not
+  valhalla generate --input discovery.json --target-infra dr.json --target-cluster DR --strict-capacity
+
+  # Generate a single main.tf instead of one file per section
+  valhalla generate --input discovery.json --format terraform --flatten
+
+  # Show which generate.hooks from the config would change each file
+  valhalla generate --input discovery.json --dry-run --summary-only
+
+  # Generate a reproducible 10 VM pilot, then everything else
+  valhalla generate --input discovery.json --sample 10 --seed 42 --output-dir ./pilot
+  valhalla generate --input discovery.json --exclude-manifest ./pilot/pilot-manifest.json
+
+  # Only generate from signed discovery results, and sign provenance.json
+  valhalla generate --input discovery.json --verify-key valhalla.pub --sign-key valhalla.key`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runGenerate(log, cfg, opts)
+			opts.SeedSet = cmd.Flags().Changed("seed")
+			if !cmd.Flags().Changed("verify-key") {
+				opts.VerifyKey = cfg.Signing.PublicKeyFile
+			}
+			if !cmd.Flags().Changed("sign-key") {
+				opts.SignKey = cfg.Signing.KeyFile
+			}
+			err := runGenerate(log, cfg, opts)
+			var exitErr *ExitCodeError
+			if errors.As(err, &exitErr) {
+				// Drift is an expected outcome, not a usage mistake
+				cmd.SilenceUsage = true
+			}
+			return err
 		},
 	}
 
 	// Add flags
-	cmd.Flags().StringVarP(&opts.InputFile, "input", "i", "", "Input file with discovery results (JSON)")
+	cmd.Flags().StringVarP(&opts.InputFile, "input", "i", "", "Input file with discovery results (JSON or YAML)")
 	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "terraform", "Output format (terraform, pulumi-python, pulumi-typescript, pulumi-go, pulumi-csharp, ansible)")
 	cmd.Flags().StringVarP(&opts.OutputDir, "output-dir", "o", "./output", "Output directory for generated files")
 	cmd.Flags().StringVarP(&opts.Provider, "provider", "p", "", "Filter by provider (vmware, proxmox, nutanix)")
-	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show what would be generated without creating files")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Show what would be generated without creating files; diffs against an existing output directory and exits with code 2 on differences")
+	cmd.Flags().BoolVar(&opts.SummaryOnly, "summary-only", false, "With --dry-run, list changed files instead of showing unified diffs")
 	cmd.Flags().BoolVar(&opts.Validate, "validate", true, "Validate generated templates")
+	cmd.Flags().IntVar(&opts.MaxFieldSize, "max-field-size", 0, "Annotations over this many bytes go to notes/ files instead of comments (default: output.max_field_size)")
+	cmd.Flags().BoolVar(&opts.Flatten, "flatten", false, "Merge all generated Terraform into a single main.tf")
+	cmd.Flags().BoolVar(&opts.PreserveMAC, "preserve-mac", false, "Set static MAC addresses from discovery on generated network interfaces")
+	cmd.Flags().BoolVar(&opts.EmitPostProvision, "emit-post-provision", false, "Carry post_provision.* annotations into commented-out post-clone blocks and POST_PROVISION.md (never run automatically)")
+	cmd.Flags().BoolVar(&opts.AllowLinkedClones, "allow-linked-clones", false, "Recreate linked clones of discovered templates as linked clones (Terraform clone block with linked_clone = true) instead of full copies")
+	cmd.Flags().BoolVar(&opts.WithImports, "with-imports", false, "Also emit Terraform import blocks (imports.tf, Terraform 1.5+) and import.sh for the discovered VMs, so existing VMs are adopted instead of recreated")
+	cmd.Flags().BoolVar(&opts.IncludeAllDataSources, "include-all-data-sources", false, "Emit Terraform data sources for every discovered network, datastore, host and resource pool, not just those the generated VMs use")
+	cmd.Flags().BoolVar(&opts.CreateNetworks, "create-networks", false, "Create the discovered port groups in Terraform (networks.tf) with their VLAN and security policy, instead of only looking them up")
+	cmd.Flags().StringVar(&opts.TargetInfra, "target-infra", "", "Discovery file of the target environment; checks the generated VMs fit its capacity within capacity.cpu_overcommit and capacity.memory_overcommit")
+	cmd.Flags().StringVar(&opts.TargetCluster, "target-cluster", "", "Cluster the VMs are generated for, in --target-infra or else the input file; checks its capacity as --target-infra does")
+	cmd.Flags().BoolVar(&opts.StrictCapacity, "strict-capacity", false, "Fail when the generated VMs exceed the target cluster's overcommit limits")
+	cmd.Flags().IntVar(&opts.UpgradeHWVersion, "upgrade-hw-version", 0, "Generate VMs on an older virtual hardware version at this version instead, e.g. 19 (0 keeps the discovered version)")
+	cmd.Flags().BoolVar(&opts.SkipRDMVMs, "skip-rdm-vms", false, "Leave VMs with raw device mapping disks out of generated code instead of generating them without those disks")
+	cmd.Flags().StringVar(&opts.Stack, "stack", "dev", "Pulumi stack name used for the generated Pulumi.<stack>.yaml")
+	cmd.Flags().BoolVar(&opts.IncludeSecrets, "include-secrets", false, "Also write plaintext credentials from the config into git-ignored variable files")
+	cmd.Flags().BoolVar(&opts.AllowSecrets, "allow-secrets", false, "Write generated files even if the secret scan finds credentials in them")
+	cmd.Flags().StringSliceVar(&opts.ExcludeDatastores, "exclude-datastore", []string{}, "Datastore name or ID to leave out of generated code (repeatable); VMs only on excluded datastores are skipped")
+	cmd.Flags().StringSliceVar(&opts.ExcludeNetworks, "exclude-network", []string{}, "Network to leave out of generated code (repeatable); VMs only on excluded networks are skipped")
+	cmd.Flags().IntVar(&opts.MaxComplexity, "max-complexity", -1, "Skip VMs whose migration complexity score is above this value (-1 for no limit)")
+	cmd.Flags().IntVar(&opts.Sample, "sample", 0, "Generate only a random sample of N VMs (pilot generation)")
+	cmd.Flags().IntVar(&opts.SamplePerGroup, "sample-per-group", 0, "Sample N VMs from each group (see --group-by)")
+	cmd.Flags().StringVar(&opts.GroupBy, "group-by", "folder", "Grouping for --sample-per-group (folder, tag)")
+	cmd.Flags().Int64Var(&opts.Seed, "seed", 0, "Random seed for sampling (default: time based, recorded in the manifest)")
+	cmd.Flags().StringVar(&opts.VerifyKey, "verify-key", "", "Ed25519 public key (PEM); refuse input whose <input>.sig was not made with its private key (defaults to signing.public_key_file)")
+	cmd.Flags().StringVar(&opts.SignKey, "sign-key", "", "Ed25519 private key (PEM) to sign provenance.json with, writing provenance.json.sig (defaults to signing.key_file)")
+	cmd.Flags().StringVar(&opts.ExcludeManifest, "exclude-manifest", "", "Skip VMs listed in a pilot manifest from a previous sampled run")
 
 	// Mark required flags
 	cmd.MarkFlagRequired("input")
@@ -70,9 +191,37 @@ Examples:
 func runGenerate(log *logger.Logger, cfg *config.Config, opts *GenerateOptions) error {
 	log.StartOperation("IaC generation", "format", opts.OutputFormat, "input", opts.InputFile)
 
+	// Reject bad hooks before reading a possibly large file
+	hooks := generateHooks(cfg.Generate)
+	if err := generators.PrepareHooks(hooks); err != nil {
+		return err
+	}
+
+	// Likewise an unusable signing key
+	var signKey ed25519.PrivateKey
+	if opts.SignKey != "" && !opts.DryRun {
+		key, err := signing.LoadPrivateKey(opts.SignKey)
+		if err != nil {
+			return err
+		}
+		signKey = key
+	}
+
+	// Only trust results signed by the expected key
+	if opts.VerifyKey != "" {
+		key, err := signing.LoadPublicKey(opts.VerifyKey)
+		if err != nil {
+			return err
+		}
+		if err := signing.VerifyFile(opts.InputFile, key); err != nil {
+			return fmt.Errorf("discovery results failed signature check: %w", err)
+		}
+		log.Info("Verified discovery results signature", "file", opts.InputFile, "key", opts.VerifyKey)
+	}
+
 	// Read discovery results
 	log.Info("Reading discovery results", "file", opts.InputFile)
-	infrastructures, err := readDiscoveryResults(opts.InputFile)
+	infrastructures, err := output.ReadFile(opts.InputFile)
 	if err != nil {
 		return fmt.Errorf("failed to read discovery results: %w", err)
 	}
@@ -89,18 +238,116 @@ func runGenerate(log *logger.Logger, cfg *config.Config, opts *GenerateOptions)
 		"providers", getProviderCounts(infrastructures),
 		"total_resources", len(infrastructures))
 
-	// Create generator
-	generator, err := generators.NewGenerator(opts.OutputFormat, log)
+	// Drop VMs already generated in a pilot run
+	if opts.ExcludeManifest != "" {
+		manifest, err := generators.ReadManifest(opts.ExcludeManifest)
+		if err != nil {
+			return err
+		}
+		infrastructures = generators.ExcludeManifest(infrastructures, manifest)
+		log.Info("Excluded VMs from manifest", "manifest", opts.ExcludeManifest, "excluded", len(manifest.VMs))
+	}
+
+	// Drop datastores and networks that will not exist in the target
+	if len(opts.ExcludeDatastores) > 0 || len(opts.ExcludeNetworks) > 0 {
+		var notes []generators.ExclusionNote
+		infrastructures, notes = generators.ExcludeResources(infrastructures, generators.ExcludeOptions{
+			Datastores: opts.ExcludeDatastores,
+			Networks:   opts.ExcludeNetworks,
+		})
+		for _, note := range notes {
+			if note.Skipped {
+				log.Warn("Skipping VM that only uses excluded resources", "provider", note.Provider, "vm", note.VM, "reason", note.Reason)
+			} else {
+				log.Warn("Removed excluded resources from VM", "provider", note.Provider, "vm", note.VM, "reason", note.Reason)
+			}
+		}
+	}
+
+	// Leave VMs needing manual work out of early waves. Scores are recomputed
+	// so the current weights apply to older discovery files too.
+	if opts.MaxComplexity >= 0 {
+		enrich.ScoreComplexity(infrastructures, cfg.Complexity)
+		var notes []generators.ExclusionNote
+		infrastructures, notes = generators.ExcludeComplex(infrastructures, opts.MaxComplexity)
+		for _, note := range notes {
+			log.Info("Skipping complex VM", "provider", note.Provider, "vm", note.VM, "reason", note.Reason)
+		}
+		log.Info("Filtered VMs by complexity", "max_complexity", opts.MaxComplexity, "skipped", len(notes))
+	}
+
+	// Sampling runs last so filtered-out VMs never count towards the sample
+	var manifest *generators.Manifest
+	if opts.Sample > 0 || opts.SamplePerGroup > 0 {
+		if !opts.SeedSet {
+			opts.Seed = time.Now().UnixNano()
+		}
+		infrastructures, manifest, err = generators.SampleVMs(infrastructures, generators.SampleOptions{
+			Count:    opts.Sample,
+			PerGroup: opts.SamplePerGroup,
+			GroupBy:  opts.GroupBy,
+			Seed:     opts.Seed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to sample VMs: %w", err)
+		}
+		log.Info("Sampled VMs for pilot generation",
+			"sampled", manifest.SampledVMs,
+			"total", manifest.TotalVMs,
+			"seed", manifest.Seed)
+	}
+
+	// Project the generated VMs onto the target cluster
+	var capacity map[string]*generators.CapacityCheck
+	if opts.TargetInfra != "" || opts.TargetCluster != "" {
+		capacity, err = checkCapacity(log, cfg.Capacity, opts, infrastructures)
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.MaxFieldSize == 0 {
+		opts.MaxFieldSize = cfg.Output.MaxFieldSize
+	}
+
+	var secrets map[string]string
+	if opts.IncludeSecrets {
+		log.Warn("Including plaintext credentials in generated files; keep them out of version control")
+		if secrets, err = credentialSecrets(cfg); err != nil {
+			return err
+		}
+	}
+
+	// Generated files are scanned for secrets with the configured rule settings
+	secretRules, err := validationRuleOverrides(cfg.Validation, &ValidationOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to create generator: %w", err)
+		return err
 	}
 
-	// Generate IaC templates
+	// Generate IaC templates through the same API embedders use
 	log.Info("Generating IaC templates")
-	results, err := generator.Generate(infrastructures, generators.GenerateOptions{
-		OutputDir: opts.OutputDir,
-		DryRun:    opts.DryRun,
-		Validate:  opts.Validate,
+	results, err := valhalla.GenerateAll(context.Background(), infrastructures, valhalla.Format(opts.OutputFormat), valhalla.Options{
+		OutputDir:             opts.OutputDir,
+		DryRun:                opts.DryRun,
+		Validate:              opts.Validate,
+		Flatten:               opts.Flatten,
+		MaxFieldSize:          opts.MaxFieldSize,
+		PreserveMAC:           opts.PreserveMAC,
+		SkipRDMVMs:            opts.SkipRDMVMs,
+		UpgradeHWVersion:      opts.UpgradeHWVersion,
+		EmitPostProvision:     opts.EmitPostProvision,
+		AllowLinkedClones:     opts.AllowLinkedClones,
+		WithImports:           opts.WithImports,
+		IncludeAllDataSources: opts.IncludeAllDataSources,
+		CreateNetworks:        opts.CreateNetworks,
+		Stack:                 opts.Stack,
+		IncludeSecrets:        opts.IncludeSecrets,
+		Secrets:               secrets,
+		AllowSecrets:          opts.AllowSecrets,
+		SecretRules:           secretRules,
+		Capacity:              capacity,
+		Hooks:                 hooks,
+		Log:                   log,
 	})
 	if err != nil {
 		log.FailOperation("IaC generation", err)
@@ -108,35 +355,216 @@ func runGenerate(log *logger.Logger, cfg *config.Config, opts *GenerateOptions)
 	}
 
 	// Output results
+	drift := false
 	if opts.DryRun {
-		log.Info("Dry run - showing what would be generated:")
+		if info, err := os.Stat(opts.OutputDir); err == nil && info.IsDir() {
+			log.Info("Dry run - comparing with existing output directory", "output_dir", opts.OutputDir)
+			diffs, err := valhalla.Diff(results, opts.OutputDir)
+			if err != nil {
+				return fmt.Errorf("failed to diff generated files: %w", err)
+			}
+			if len(hooks) > 0 {
+				log.Warn("Dry run compares files before hooks run, so files hooks change show as different")
+			}
+			drift = printDiffs(diffs, opts.SummaryOnly)
+		} else {
+			log.Info("Dry run - showing what would be generated:")
+			for _, result := range results {
+				fmt.Printf("Would create: %s (%d bytes)\n", result.Path, len(result.Content))
+			}
+		}
 		for _, result := range results {
-			fmt.Printf("Would create: %s (%d bytes)\n", result.Path, result.Size)
+			if names, ok := result.Metadata["hooks"].([]string); ok {
+				fmt.Printf("Would run hooks on %s: %s\n", result.Path, strings.Join(names, ", "))
+			}
 		}
 	} else {
 		log.Info("Generated IaC templates", "files", len(results), "output_dir", opts.OutputDir)
 		for _, result := range results {
-			log.Info("Created file", "path", result.Path, "size_bytes", result.Size)
+			log.Info("Created file", "path", result.Path, "size_bytes", len(result.Content))
+		}
+	}
+
+	// Trace the generated code back to the discovery runs it came from
+	provenancePath := filepath.Join(opts.OutputDir, generators.ProvenanceFile)
+	if opts.DryRun {
+		fmt.Printf("Would create: %s\n", provenancePath)
+	} else {
+		record := &generators.ProvenanceRecord{
+			GeneratedAt:     time.Now().UTC(),
+			ValhallaVersion: buildVersion,
+			Format:          opts.OutputFormat,
+			Sources:         generators.Sources(infrastructures),
+		}
+		if err := generators.WriteProvenance(record, provenancePath); err != nil {
+			return err
+		}
+		log.Info("Wrote provenance", "path", provenancePath)
+
+		if signKey != nil {
+			sigPath, err := signing.SignFile(provenancePath, signKey)
+			if err != nil {
+				return err
+			}
+			log.Info("Signed provenance", "signature", sigPath)
+		}
+	}
+
+	// Record the pilot selection so the remainder can be generated later
+	if manifest != nil {
+		manifestPath := filepath.Join(opts.OutputDir, "pilot-manifest.json")
+		if opts.DryRun {
+			fmt.Printf("Would create: %s (%d VMs)\n", manifestPath, manifest.SampledVMs)
+		} else {
+			if err := generators.WriteManifest(manifest, manifestPath); err != nil {
+				return err
+			}
+			log.Info("Wrote pilot manifest", "path", manifestPath)
+		}
+		log.CompleteOperation("IaC generation",
+			"files_generated", len(results),
+			"sampled_vms", manifest.SampledVMs,
+			"total_vms", manifest.TotalVMs)
+	} else {
+		log.CompleteOperation("IaC generation", "files_generated", len(results))
+	}
+
+	// Repeat capacity warnings at the end of the summary so they are not
+	// lost in the per-file output
+	keys := make([]string, 0, len(capacity))
+	for key := range capacity {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		check := capacity[key]
+		for _, reason := range check.Exceeded() {
+			log.Warn("Capacity warning", "target", check.Target, "vms", check.VMs, "reason", reason)
 		}
 	}
 
-	log.CompleteOperation("IaC generation", "files_generated", len(results))
+	if drift {
+		return &ExitCodeError{Code: 2, Err: fmt.Errorf("generated IaC differs from %s", opts.OutputDir)}
+	}
 	return nil
 }
 
-// readDiscoveryResults reads and parses discovery results from a JSON file
-func readDiscoveryResults(filename string) ([]*models.Infrastructure, error) {
-	data, err := os.ReadFile(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+// generateHooks converts the configured generate hooks for the generators
+func generateHooks(cfg config.GenerateConfig) []generators.Hook {
+	hooks := make([]generators.Hook, len(cfg.Hooks))
+	for i, hook := range cfg.Hooks {
+		hooks[i] = generators.Hook{
+			Name:     hook.Name,
+			Files:    hook.Files,
+			Command:  hook.Command,
+			Args:     hook.Args,
+			Template: hook.Template,
+			Timeout:  hook.Timeout,
+		}
+	}
+	return hooks
+}
+
+// checkCapacity checks each infrastructure's VMs against the capacity of
+// the target cluster, warning about any over the overcommit limits, or
+// failing with --strict-capacity
+func checkCapacity(log *logger.Logger, limits config.CapacityConfig, opts *GenerateOptions, infrastructures []*models.Infrastructure) (map[string]*generators.CapacityCheck, error) {
+	var targets []*models.Infrastructure
+	if opts.TargetInfra != "" {
+		var err error
+		if targets, err = output.ReadFile(opts.TargetInfra); err != nil {
+			return nil, fmt.Errorf("failed to read target infrastructure: %w", err)
+		}
+	}
+
+	checks := make(map[string]*generators.CapacityCheck)
+	var exceeded []string
+	for _, infra := range infrastructures {
+		// The target is the same provider in the target file, or the
+		// infrastructure itself
+		target := infra
+		if opts.TargetInfra != "" {
+			target = nil
+			for _, candidate := range targets {
+				if strings.EqualFold(candidate.Provider, infra.Provider) {
+					target = candidate
+					break
+				}
+			}
+			if target == nil {
+				log.Warn("No target infrastructure for provider, capacity not checked", "provider", infra.Provider, "target_infra", opts.TargetInfra)
+				continue
+			}
+		}
+
+		cluster := opts.TargetCluster
+		if cluster == "" {
+			cluster = target.Cluster
+		}
+		check, err := generators.CheckCapacity(infra, target, cluster, limits.CPUOvercommit, limits.MemoryOvercommit)
+		if err != nil {
+			log.Warn("Capacity not checked", "provider", infra.Provider, "error", err)
+			continue
+		}
+		checks[generators.CapacityKey(infra)] = check
+
+		log.Info("Projected target capacity",
+			"target", check.Target,
+			"vms", check.VMs,
+			"cpu_ratio", fmt.Sprintf("%.2f", check.CPURatio()),
+			"memory_ratio", fmt.Sprintf("%.2f", check.MemoryRatio()))
+		for _, reason := range check.Exceeded() {
+			log.Warn("Target cluster over capacity", "target", check.Target, "reason", reason)
+			exceeded = append(exceeded, check.Target+": "+reason)
+		}
 	}
 
-	var infrastructures []*models.Infrastructure
-	if err := json.Unmarshal(data, &infrastructures); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	if opts.StrictCapacity && len(exceeded) > 0 {
+		return nil, fmt.Errorf("target capacity exceeded (--strict-capacity): %s", strings.Join(exceeded, "; "))
+	}
+	return checks, nil
+}
+
+// printDiffs prints each changed file as a unified diff, or as a one-line
+// summary when summaryOnly is set, colorizing diffs on a terminal. It reports
+// whether any file would change.
+func printDiffs(diffs []generators.FileDiff, summaryOnly bool) bool {
+	color := term.IsTerminal(int(os.Stdout.Fd()))
+	changed := 0
+
+	for _, diff := range diffs {
+		if diff.Status == generators.DiffUnchanged {
+			continue
+		}
+		changed++
+
+		if summaryOnly || diff.Binary {
+			fmt.Printf("%-9s %s (%d -> %d bytes)\n", diff.Status, diff.Path, diff.OldSize, diff.NewSize)
+			continue
+		}
+
+		for _, line := range strings.SplitAfter(diff.Unified, "\n") {
+			if !color || line == "" {
+				fmt.Print(line)
+				continue
+			}
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+				fmt.Print("\x1b[1m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n")
+			case strings.HasPrefix(line, "@@"):
+				fmt.Print("\x1b[36m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n")
+			case strings.HasPrefix(line, "+"):
+				fmt.Print("\x1b[32m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n")
+			case strings.HasPrefix(line, "-"):
+				fmt.Print("\x1b[31m" + strings.TrimSuffix(line, "\n") + "\x1b[0m\n")
+			default:
+				fmt.Print(line)
+			}
+		}
 	}
 
-	return infrastructures, nil
+	fmt.Printf("%d of %d generated files would change\n", changed, len(diffs))
+	return changed > 0
 }
 
 // filterByProvider filters infrastructures by provider type
@@ -159,3 +587,29 @@ func getProviderCounts(infrastructures []*models.Infrastructure) map[string]int
 	}
 	return counts
 }
+
+// credentialSecrets returns configured provider credentials keyed by the
+// variable names generators use for them
+func credentialSecrets(cfg *config.Config) (map[string]string, error) {
+	vmware, err := cfg.GetVMwareConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxmox, err := cfg.GetProxmoxConfig()
+	if err != nil {
+		return nil, err
+	}
+	nutanix, err := cfg.GetNutanixConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"vsphere_username": vmware.Username,
+		"vsphere_password": vmware.Password,
+		"proxmox_username": proxmox.Username,
+		"proxmox_password": proxmox.Password,
+		"nutanix_username": nutanix.Username,
+		"nutanix_password": nutanix.Password,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/output"
+)
+
+func TestCheckCapacityStrict(t *testing.T) {
+	// Cluster DR: one 4-core host with 16 GiB
+	target := filepath.Join(t.TempDir(), "dr.json")
+	dr := `[{"provider":"vmware","server":"vcenter-dr","clusters":[{"name":"DR","hosts":["esx-dr-1"]}],
+		"hosts":[{"name":"esx-dr-1","type":"ESXi","cpu_cores":4,"memory":{"total":16384}}]}]`
+	if err := os.WriteFile(target, []byte(dr), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := []*models.Infrastructure{{
+		Provider: "vmware",
+		Server:   "vcenter",
+		VirtualMachines: []models.VirtualMachine{
+			{Name: "app-01", CPUs: 8, Memory: 8192},
+			{Name: "app-02", CPUs: 8, Memory: 8192},
+		},
+	}}
+	limits := config.CapacityConfig{CPUOvercommit: 4, MemoryOvercommit: 1}
+	log := logger.NewWithOutput(io.Discard)
+
+	opts := &GenerateOptions{TargetInfra: target, TargetCluster: "DR"}
+	checks, err := checkCapacity(log, limits, opts, source)
+	if err != nil {
+		t.Fatalf("checkCapacity: %v", err)
+	}
+	if len(checks) != 1 {
+		t.Fatalf("got %d checks, want 1", len(checks))
+	}
+
+	// 16 vCPUs on 4 cores is within 4:1, but 16 GiB of 16 GiB leaves memory
+	// at 1:1; one more GiB is over
+	source[0].VirtualMachines[1].Memory = 9216
+	if _, err := checkCapacity(log, limits, opts, source); err != nil {
+		t.Errorf("over capacity failed without --strict-capacity: %v", err)
+	}
+	opts.StrictCapacity = true
+	_, err = checkCapacity(log, limits, opts, source)
+	if err == nil || !strings.Contains(err.Error(), "memory overcommit") {
+		t.Errorf("checkCapacity = %v, want a memory overcommit error under --strict-capacity", err)
+	}
+}
+
+func TestGenerateHooksFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "valhalla.yaml")
+	configYAML := `generate:
+  hooks:
+    - name: header
+      files: ["*.tf"]
+      timeout: 5s
+      template: |
+        {{ comment .Path "Owned by the platform team" }}{{ .Content }}
+`
+	if err := os.WriteFile(configFile, []byte(configYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := loadConfig(t, configFile)
+	if hooks := generateHooks(cfg.Generate); len(hooks) != 1 || hooks[0].Timeout != 5*time.Second || hooks[0].Files[0] != "*.tf" {
+		t.Fatalf("configured hooks = %+v", hooks)
+	}
+
+	input := filepath.Join(dir, "discovery.json")
+	data, err := output.NewFormatter("json").Format(convertFixture())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "terraform")
+	opts := &GenerateOptions{InputFile: input, OutputFormat: "terraform", OutputDir: out, Provider: "vmware", MaxComplexity: -1}
+	if err := runGenerate(logger.NewWithOutput(io.Discard), cfg, opts); err != nil {
+		t.Fatalf("runGenerate: %v", err)
+	}
+	for _, name := range []string{"provider.tf", "virtual_machines.tf"} {
+		content, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(content), "# Owned by the platform team\n") {
+			t.Errorf("%s was written without the hook's header:\n%s", name, content)
+		}
+	}
+
+	// A broken hook fails before the input is read
+	cfg.Generate.Hooks[0].Template = "{{ .Content"
+	opts.InputFile = filepath.Join(dir, "missing.json")
+	if err := runGenerate(logger.NewWithOutput(io.Discard), cfg, opts); err == nil || !strings.Contains(err.Error(), "hook header has an invalid template") {
+		t.Errorf("runGenerate = %v, want the invalid hook reported", err)
+	}
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/output"
+)
+
+// ImportOptions holds options for the import command
+type ImportOptions struct {
+	FromCSV      string
+	OutputFile   string
+	OutputFormat string
+}
+
+// NewImportCmd creates the import command
+func NewImportCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &ImportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Build discovery results from a manual inventory",
+		Long: `Build discovery results from an inventory kept outside a hypervisor, such as a
+spreadsheet, so the generators can be used without a live connection.
+
+The CSV layout matches the one written by 'discover --format csv':
+
+  Provider,Server,Datacenter,Cluster,Node,Resource_Type,Name,State,CPUs,Memory_MB,OS,Host,Type,Capacity_GB,Free_GB,VLAN,Network
+
+Resource_Type is VM, Network or Storage. VM rows list their networks in the
+Network column separated by ';'. Columns are matched by header name, and only
+Provider, Server, Resource_Type and Name are required.
+
+Examples:
+  # Convert a spreadsheet export into discovery JSON
+  valhalla import --from-csv inventory.csv -o discovery.json
+
+  # Then generate Terraform from it
+  valhalla generate --input discovery.json --format terraform`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImport(log, cfg, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.FromCSV, "from-csv", "", "CSV inventory file to import")
+	cmd.Flags().StringVarP(&opts.OutputFile, "output-file", "o", "", "Output file path")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "json", "Output format (json, yaml)")
+
+	cmd.MarkFlagRequired("from-csv")
+
+	return cmd
+}
+
+// runImport executes the import process
+func runImport(log *logger.Logger, cfg *config.Config, opts *ImportOptions) error {
+	log.StartOperation("Import", "file", opts.FromCSV)
+
+	file, err := os.Open(opts.FromCSV)
+	if err != nil {
+		return fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	results, err := output.ParseCSV(file)
+	if err != nil {
+		return fmt.Errorf("failed to import CSV: %w", err)
+	}
+
+	log.Info("Import completed",
+		"infrastructures", len(results),
+		"total_resources", getTotalResourceCount(results))
+
+	return outputResults(log, &DiscoverOptions{
+		OutputFormat: opts.OutputFormat,
+		OutputFile:   opts.OutputFile,
+	}, results)
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/diff"
+	"valhalla/internal/discovery"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/output"
+)
+
+// PlanOptions holds options for the plan command
+type PlanOptions struct {
+	InputFile          string
+	Provider           string
+	OutputFormat       string
+	Datacenter         string
+	Cluster            string
+	Node               string
+	Timeout            time.Duration
+	FreeSpaceThreshold float64
+}
+
+// NewPlanCmd creates the plan command
+func NewPlanCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &PlanOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show what changed since a saved discovery",
+		Long: `Discover a provider again and compare the result with a saved discovery file,
+listing VMs added and removed, CPU, memory, disk and network changes of the
+VMs that remain, cluster EVC mode changes, and datastores added, removed,
+resized or whose free space moved by more than --free-space-threshold.
+
+EVC mode changes are high-impact, as they decide which clusters VMs can live
+migrate between, and are marked with ! in the table and "highlight": true in
+JSON.
+
+VMs are matched by UUID or provider ID, and by name when those don't match.
+The command exits with code 2 when there are differences, so it can gate a
+pipeline before IaC is generated again.
+
+Examples:
+  # What changed in vCenter since the last discovery
+  valhalla plan --input discovery.json --provider vmware
+
+  # Fail a pipeline step on drift, keeping a machine-readable record
+  valhalla plan --input discovery.json --provider proxmox --format json > drift.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPlan(log, cfg, opts)
+			var exitErr *ExitCodeError
+			if errors.As(err, &exitErr) {
+				// Drift is an expected outcome, not a usage mistake
+				cmd.SilenceUsage = true
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.InputFile, "input", "i", "", "Saved discovery results to compare against (JSON or YAML)")
+	cmd.Flags().StringVarP(&opts.Provider, "provider", "p", "", "Provider to discover again (vmware, proxmox, nutanix)")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "format", "f", "table", "Output format (table, json)")
+	cmd.Flags().StringVar(&opts.Datacenter, "datacenter", "", "VMware datacenter to discover")
+	cmd.Flags().StringVar(&opts.Cluster, "cluster", "", "Cluster to discover")
+	cmd.Flags().StringVar(&opts.Node, "node", "", "Proxmox node to discover")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Minute, "Discovery timeout")
+	cmd.Flags().Float64Var(&opts.FreeSpaceThreshold, "free-space-threshold", diff.DefaultOptions.FreeSpaceThreshold, "Report datastore free space changes over this many percent of capacity")
+
+	cmd.MarkFlagRequired("input")
+	cmd.MarkFlagRequired("provider")
+
+	return cmd
+}
+
+// runPlan discovers the provider and prints its differences from the input
+func runPlan(log *logger.Logger, cfg *config.Config, opts *PlanOptions) error {
+	format := strings.ToLower(opts.OutputFormat)
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported plan format: %s (supported: table, json)", opts.OutputFormat)
+	}
+
+	previous, err := output.ReadFile(opts.InputFile)
+	if err != nil {
+		return fmt.Errorf("failed to read discovery results: %w", err)
+	}
+	previous = filterByProvider(previous, providerName(opts.Provider))
+	if len(previous) == 0 {
+		log.Warn("Input has no results for the provider, everything discovered is new", "provider", opts.Provider, "input", opts.InputFile)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if err := cfg.Discover.DefaultFilters.Validate(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+	defer cancel()
+
+	discoverOpts := &DiscoverOptions{
+		Datacenter: opts.Datacenter,
+		Cluster:    opts.Cluster,
+		Node:       opts.Node,
+	}
+	engine := discovery.NewEngine(log, cfg)
+	providerLog := log.WithProvider(opts.Provider)
+
+	var current []*models.Infrastructure
+	switch strings.ToLower(opts.Provider) {
+	case "vmware", "vsphere":
+		current, err = discoverVMware(ctx, providerLog, engine, cfg, discoverOpts)
+	case "proxmox":
+		current, err = discoverProxmox(ctx, providerLog, engine, cfg, discoverOpts)
+	case "nutanix":
+		current, err = discoverNutanix(ctx, providerLog, engine, cfg, discoverOpts)
+	default:
+		return fmt.Errorf("unsupported provider: %s", opts.Provider)
+	}
+	if err != nil {
+		return fmt.Errorf("discovery failed: %w", err)
+	}
+	// The saved discovery was most likely taken with the configured default
+	// filters, so VMs they leave out would otherwise all show as added
+	discovery.ApplyFilters(current, cfg.Discover.DefaultFilters)
+
+	reports := diff.CompareAll(previous, current, diff.Options{FreeSpaceThreshold: opts.FreeSpaceThreshold})
+
+	if format == "json" {
+		data, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode plan: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(formatPlan(reports))
+	}
+
+	if diff.HasChanges(reports) {
+		return &ExitCodeError{Code: 2, Err: fmt.Errorf("%s differs from %s", opts.Provider, opts.InputFile)}
+	}
+	log.Info("No changes since the saved discovery", "input", opts.InputFile)
+	return nil
+}
+
+// providerName maps provider aliases to the name discovery records
+func providerName(provider string) string {
+	if strings.EqualFold(provider, "vsphere") {
+		return "vmware"
+	}
+	return provider
+}
+
+// formatPlan renders the changes of each infrastructure as a table
+func formatPlan(reports []*diff.Report) string {
+	var output strings.Builder
+
+	for _, report := range reports {
+		output.WriteString(fmt.Sprintf("\n=== %s (%s) ===\n", strings.ToUpper(report.Provider), report.Server))
+		if len(report.Changes) == 0 {
+			output.WriteString("No changes\n")
+			continue
+		}
+
+		counts := make(map[string]int)
+		table := tablewriter.NewWriter(&output)
+		table.SetHeader([]string{"Change", "Resource", "Name", "Field", "Before", "After"})
+		table.SetBorder(true)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		highlighted := 0
+		for _, change := range report.Changes {
+			counts[change.Kind]++
+			kind := change.Kind
+			if change.Highlight {
+				highlighted++
+				kind = "! " + kind
+			}
+			table.Append([]string{kind, change.Resource, change.Name, change.Field, change.Before, change.After})
+		}
+		table.Render()
+
+		output.WriteString(fmt.Sprintf("%d added, %d removed, %d changed\n",
+			counts[diff.Added], counts[diff.Removed], counts[diff.Changed]))
+		if highlighted > 0 {
+			output.WriteString(fmt.Sprintf("%d high-impact changes marked with !: clusters with a different EVC mode may no longer accept live migrations\n", highlighted))
+		}
+	}
+
+	return output.String()
+}
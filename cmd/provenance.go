@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// buildVersion and buildCommit identify the running binary in provenance
+// records
+var (
+	buildVersion = "dev"
+	buildCommit  = "none"
+)
+
+// SetBuildInfo records the version and commit the binary was built from
+func SetBuildInfo(version, commit string) {
+	buildVersion, buildCommit = version, commit
+}
+
+// recordProvenance adds a provenance block to each infrastructure saying
+// which build, machine and provider account produced it and with which
+// filters and detail settings. Only the account name is recorded, never a
+// password or token secret, and the config file only as a hash of its path.
+func recordProvenance(infrastructures []*models.Infrastructure, provider string, cfg *config.Config, opts *DiscoverOptions, filters config.FilterConfig) {
+	provenance := map[string]interface{}{
+		"valhalla_version": buildVersion,
+		"valhalla_commit":  buildCommit,
+		"os":               runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		provenance["hostname"] = hostname
+	}
+	if local, err := user.Current(); err == nil {
+		provenance["local_user"] = local.Username
+	}
+	if _, applied := filters.Merge(config.FilterConfig{}); len(applied) > 0 {
+		provenance["filters"] = applied
+	}
+	if detail := detailSettings(provider, opts); len(detail) > 0 {
+		provenance["detail"] = detail
+	}
+	if path := viper.ConfigFileUsed(); path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		sum := sha256.Sum256([]byte(path))
+		provenance["config_path_sha256"] = hex.EncodeToString(sum[:])
+	}
+
+	for _, infra := range infrastructures {
+		if infra.Metadata == nil {
+			infra.Metadata = make(map[string]interface{})
+		}
+		// Each profile may authenticate as a different account
+		record := make(map[string]interface{}, len(provenance)+1)
+		for key, value := range provenance {
+			record[key] = value
+		}
+		profile, _ := infra.Metadata["profile"].(string)
+		if account := providerAccount(provider, profile, cfg); account != "" {
+			record["provider_user"] = account
+		}
+		infra.Metadata["provenance"] = record
+	}
+}
+
+// providerAccount returns the account a provider profile's discovery
+// authenticated as; for Proxmox API tokens that is the token ID, which names
+// user and token. Keyring errors only concern the password, so they are
+// ignored.
+func providerAccount(provider, profile string, cfg *config.Config) string {
+	switch strings.ToLower(provider) {
+	case "vmware", "vsphere":
+		vmware, _ := cfg.GetVMwareProfile(profile)
+		return vmware.Username
+	case "proxmox":
+		proxmox, _ := cfg.GetProxmoxProfile(profile)
+		if proxmox.TokenID != "" {
+			return proxmox.TokenID
+		}
+		return proxmox.Username
+	case "nutanix":
+		nutanix, _ := cfg.GetNutanixProfile(profile)
+		return nutanix.Username
+	}
+	return ""
+}
+
+// detailSettings lists the discover options that changed what was
+// collected for provider
+func detailSettings(provider string, opts *DiscoverOptions) []string {
+	var detail []string
+	add := func(enabled bool, setting string) {
+		if enabled {
+			detail = append(detail, setting)
+		}
+	}
+
+	switch strings.ToLower(provider) {
+	case "vmware", "vsphere":
+		add(opts.Datacenter != "", "datacenter="+opts.Datacenter)
+		add(opts.Cluster != "", "cluster="+opts.Cluster)
+		add(opts.WithAlarms, "with_alarms")
+		add(opts.WithPermissions, "with_permissions")
+		add(opts.WithContentLibraries, "with_content_libraries")
+		add(opts.AllNetworks, "all_networks")
+		add(opts.IncludeSnapshots, "include_snapshots")
+	case "proxmox":
+		add(opts.Node != "", "node="+opts.Node)
+		add(opts.DeepStorageScan, "deep_storage_scan")
+	case "nutanix":
+		add(opts.Cluster != "", "cluster="+opts.Cluster)
+	}
+	add(len(opts.Fields) > 0, "fields="+strings.Join(opts.Fields, ","))
+	return detail
+}
@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/discovery/providers"
+	"valhalla/internal/logger"
+)
+
+// ProvidersOptions holds options for the providers command
+type ProvidersOptions struct {
+	Format string
+	Check  bool
+}
+
+// ProviderStatus describes a provider's capabilities and readiness
+type ProviderStatus struct {
+	Name           string                        `json:"name"`
+	Description    string                        `json:"description"`
+	Capabilities   map[providers.Capability]bool `json:"capabilities"`
+	RequiredConfig []ConfigKeyStatus             `json:"required_config"`
+	Configured     bool                          `json:"configured"`
+	Connection     *ConnectionCheck              `json:"connection,omitempty"`
+	Unavailable    []providers.Capability        `json:"unavailable_capabilities,omitempty"` // supported, but not by the detected version
+}
+
+// ConfigKeyStatus reports whether a required configuration key is set
+type ConfigKeyStatus struct {
+	Key       string `json:"key"`
+	Satisfied bool   `json:"satisfied"`
+}
+
+// ConnectionCheck holds the result of a provider connection test
+type ConnectionCheck struct {
+	Success bool                       `json:"success"`
+	Error   string                     `json:"error,omitempty"`
+	Version *providers.ProviderVersion `json:"version,omitempty"`
+}
+
+// NewProvidersCmd creates the providers command
+func NewProvidersCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &ProvidersOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "providers",
+		Short: "List supported providers and their capabilities",
+		Long: `List every supported provider with its capability matrix, the configuration
+keys it requires, and whether the loaded configuration satisfies them.
+
+Examples:
+  # Show the capability matrix
+  valhalla providers
+
+  # Also test connectivity to each configured provider
+  valhalla providers --check
+
+  # Emit JSON for documentation generation
+  valhalla providers --format json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runProviders(log, cfg, opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", "table", "Output format (table, json)")
+	cmd.Flags().BoolVar(&opts.Check, "check", false, "Test the connection to each configured provider")
+
+	return cmd
+}
+
+// runProviders executes the providers command
+func runProviders(log *logger.Logger, cfg *config.Config, opts *ProvidersOptions) error {
+	var statuses []ProviderStatus
+
+	for _, info := range providers.ListProviderInfo() {
+		status := ProviderStatus{
+			Name:         info.Name,
+			Description:  info.Description,
+			Capabilities: make(map[providers.Capability]bool),
+			Configured:   true,
+		}
+
+		for _, capability := range providers.AllCapabilities() {
+			status.Capabilities[capability] = info.Supports(capability)
+		}
+
+		for _, key := range info.RequiredConfig {
+			satisfied := cfg.HasValue(key)
+			if !satisfied {
+				status.Configured = false
+			}
+			status.RequiredConfig = append(status.RequiredConfig, ConfigKeyStatus{
+				Key:       key,
+				Satisfied: satisfied,
+			})
+		}
+
+		if opts.Check && status.Configured {
+			status.Connection = checkProviderConnection(log, cfg, info.Name)
+			if status.Connection.Version != nil {
+				status.Unavailable = providers.UnavailableCapabilities(info.Name, *status.Connection.Version)
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	switch strings.ToLower(opts.Format) {
+	case "json":
+		data, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format output: %w", err)
+		}
+		fmt.Println(string(data))
+	case "table":
+		fmt.Print(formatProviderStatuses(statuses))
+	default:
+		return fmt.Errorf("unsupported output format: %s", opts.Format)
+	}
+
+	return nil
+}
+
+// checkProviderConnection tests the connection to a provider using the loaded
+// config, recording the version the endpoint reports where the provider can
+// connect for real
+func checkProviderConnection(log *logger.Logger, cfg *config.Config, provider string) *ConnectionCheck {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var conn providers.Provider
+	var err error
+	switch provider {
+	case "vmware":
+		p := providers.NewVMwareProvider(log)
+		conn = p
+		var vmwareConfig config.VMwareConfig
+		if vmwareConfig, err = cfg.GetVMwareConfig(); err == nil {
+			err = p.ConnectVMware(ctx, vmwareConfig)
+		}
+	case "proxmox":
+		p := providers.NewProxmoxProvider(log)
+		conn = p
+		var proxmoxConfig config.ProxmoxConfig
+		if proxmoxConfig, err = cfg.GetProxmoxConfig(); err == nil {
+			err = p.ConnectProxmox(ctx, proxmoxConfig)
+		}
+	case "nutanix":
+		p := providers.NewNutanixProvider(log)
+		conn = p
+		var nutanixConfig config.NutanixConfig
+		if nutanixConfig, err = cfg.GetNutanixConfig(); err == nil {
+			err = p.ConnectNutanix(ctx, nutanixConfig)
+		}
+	default:
+		err = fmt.Errorf("no connection test for provider: %s", provider)
+	}
+
+	if err != nil {
+		return &ConnectionCheck{Success: false, Error: err.Error()}
+	}
+
+	check := &ConnectionCheck{Success: true}
+	if conn != nil {
+		version := conn.ConnectionInfo().ProviderVersion()
+		check.Version = &version
+		conn.Disconnect()
+	}
+	return check
+}
+
+// formatProviderStatuses renders the capability matrix and configuration status as tables
+func formatProviderStatuses(statuses []ProviderStatus) string {
+	var output strings.Builder
+
+	// Capability matrix
+	output.WriteString("Capabilities:\n")
+	header := []string{"Capability"}
+	for _, status := range statuses {
+		header = append(header, status.Name)
+	}
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader(header)
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	// Detected versions, only known after --check
+	versionRow := []string{"version"}
+	checked := false
+	for _, status := range statuses {
+		if status.Connection != nil && status.Connection.Version != nil {
+			versionRow = append(versionRow, status.Connection.Version.Version)
+			checked = true
+		} else {
+			versionRow = append(versionRow, "-")
+		}
+	}
+	if checked {
+		table.Append(versionRow)
+	}
+
+	for _, capability := range providers.AllCapabilities() {
+		row := []string{string(capability)}
+		for _, status := range statuses {
+			switch {
+			case !status.Capabilities[capability]:
+				row = append(row, "-")
+			case capabilityUnavailable(status, capability):
+				row = append(row, "no (version)")
+			default:
+				row = append(row, "yes")
+			}
+		}
+		table.Append(row)
+	}
+	table.Render()
+
+	// Configuration and connection status
+	output.WriteString("\nConfiguration:\n")
+	table = tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Provider", "Key", "Set", "Connection"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, status := range statuses {
+		connection := "not checked"
+		if status.Connection != nil {
+			if status.Connection.Success {
+				connection = "ok"
+			} else {
+				connection = "failed: " + status.Connection.Error
+			}
+		}
+
+		for i, key := range status.RequiredConfig {
+			set := "No"
+			if key.Satisfied {
+				set = "Yes"
+			}
+
+			// Only show provider name and connection on the first row
+			name, conn := "", ""
+			if i == 0 {
+				name, conn = status.Name, connection
+			}
+			table.Append([]string{name, key.Key, set, conn})
+		}
+	}
+	table.Render()
+
+	return output.String()
+}
+
+// capabilityUnavailable reports whether a supported capability is ruled out
+// by the provider's detected version
+func capabilityUnavailable(status ProviderStatus, capability providers.Capability) bool {
+	for _, unavailable := range status.Unavailable {
+		if unavailable == capability {
+			return true
+		}
+	}
+	return false
+}
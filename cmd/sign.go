@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/signing"
+)
+
+// SignKeygenOptions holds options for the sign keygen command
+type SignKeygenOptions struct {
+	Out   string
+	Force bool
+}
+
+// SignVerifyOptions holds options for the sign verify command
+type SignVerifyOptions struct {
+	Key string
+}
+
+// NewSignCmd creates the sign command
+func NewSignCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Manage signing keys and check signed outputs",
+		Long: `Discovery results and generate's provenance.json can be signed with an
+Ed25519 key, so audits can tell who produced a file and that it is unchanged.
+A signature covers the whole file, provenance block included, and is written
+next to it as <file>.sig.
+
+  signing:
+    key_file: /etc/valhalla/valhalla.key         # discover and generate sign with it
+    public_key_file: /etc/valhalla/valhalla.pub  # generate refuses input it didn't sign
+
+Examples:
+  valhalla sign keygen --out valhalla
+  valhalla discover --provider vmware --format json -o discovery.json --sign-key valhalla.key
+  valhalla sign verify --key valhalla.pub discovery.json`,
+	}
+
+	cmd.AddCommand(newSignKeygenCmd(log, cfg))
+	cmd.AddCommand(newSignVerifyCmd(log, cfg))
+
+	return cmd
+}
+
+// newSignKeygenCmd creates the sign keygen subcommand
+func newSignKeygenCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &SignKeygenOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "keygen",
+		Short: "Create an Ed25519 key pair",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSignKeygen(log, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Out, "out", "valhalla", "Path prefix of the key files: <out>.key (private) and <out>.pub")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Overwrite existing key files")
+
+	return cmd
+}
+
+// runSignKeygen executes the sign keygen command
+func runSignKeygen(log *logger.Logger, opts *SignKeygenOptions) error {
+	privatePath, publicPath := opts.Out+".key", opts.Out+".pub"
+	if !opts.Force {
+		for _, path := range []string{privatePath, publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				return fmt.Errorf("%s already exists; use --force to replace it", path)
+			}
+		}
+	}
+
+	private, public, err := signing.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(privatePath, private, 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(publicPath, public, 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	log.Info("Created signing key pair", "private", privatePath, "public", publicPath)
+	return nil
+}
+
+// newSignVerifyCmd creates the sign verify subcommand
+func newSignVerifyCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &SignVerifyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "verify FILE...",
+		Short: "Check files against their .sig signatures",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("key") {
+				opts.Key = cfg.Signing.PublicKeyFile
+			}
+			// A bad signature is an outcome, not a usage mistake
+			cmd.SilenceUsage = true
+			return runSignVerify(log, opts, args)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Key, "key", "", "Ed25519 public key (PEM) the files were signed for (defaults to signing.public_key_file)")
+
+	return cmd
+}
+
+// runSignVerify executes the sign verify command
+func runSignVerify(log *logger.Logger, opts *SignVerifyOptions, files []string) error {
+	if opts.Key == "" {
+		return fmt.Errorf("no public key: use --key or set signing.public_key_file in the config file")
+	}
+	key, err := signing.LoadPublicKey(opts.Key)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, file := range files {
+		if err := signing.VerifyFile(file, key); err != nil {
+			log.Error("Signature check failed", "file", file, "error", err)
+			failed++
+			continue
+		}
+		log.Info("Signature verified", "file", file)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed the signature check", failed, len(files))
+	}
+	return nil
+}
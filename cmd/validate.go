@@ -3,7 +3,9 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"valhalla/internal/config"
 	"valhalla/internal/logger"
@@ -17,6 +19,11 @@ type ValidationOptions struct {
 	Recursive bool
 	Fix       bool
 	Strict    bool
+	FailOn    string
+	Enable    []string
+	Disable   []string
+	Severity  []string
+	ListRules bool
 }
 
 // NewValidateCmd creates the validate command
@@ -42,11 +49,37 @@ Examples:
   valhalla validate --path discovery.json --format json
   
   # Validate recursively with fixes
-  valhalla validate --path ./output --recursive --fix`,
+  valhalla validate --path ./output --recursive --fix
+
+  # Fail CI on warnings as well as errors
+  valhalla validate --path ./terraform --fail-on warning
+
+  # Report hardcoded credentials as errors and skip the AMI check
+  valhalla validate --path ./terraform --severity terraform-hardcoded-credentials=error --disable terraform-hardcoded-ami
+
+  # List the rules with their default and strict severities
+  valhalla validate --list-rules
+
+Rules can also be configured under validation.rules in the config file:
+
+  validation:
+    rules:
+      terraform-hardcoded-ami:
+        enabled: false
+      json-escape:
+        severity: error`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.ListRules {
+				fmt.Print(formatValidationRules())
+				return nil
+			}
 			if len(args) > 0 {
 				opts.Path = args[0]
 			}
+			// Strict mode treats warnings as failures unless --fail-on is given
+			if opts.Strict && !cmd.Flags().Changed("fail-on") {
+				opts.FailOn = "warning"
+			}
 			return runValidation(log, cfg, opts)
 		},
 	}
@@ -56,7 +89,12 @@ Examples:
 	cmd.Flags().StringVarP(&opts.Format, "format", "f", "auto", "Format to validate (auto, terraform, pulumi, ansible, json)")
 	cmd.Flags().BoolVarP(&opts.Recursive, "recursive", "r", false, "Validate recursively")
 	cmd.Flags().BoolVar(&opts.Fix, "fix", false, "Attempt to fix validation issues")
-	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Use strict validation rules")
+	cmd.Flags().BoolVar(&opts.Strict, "strict", false, "Use each rule's strict severity, see --list-rules (implies --fail-on warning)")
+	cmd.Flags().StringVar(&opts.FailOn, "fail-on", "error", "Lowest issue severity that fails validation (error, warning, none)")
+	cmd.Flags().StringSliceVar(&opts.Enable, "enable", []string{}, "Enable validation rules disabled in the config file")
+	cmd.Flags().StringSliceVar(&opts.Disable, "disable", []string{}, "Disable validation rules")
+	cmd.Flags().StringSliceVar(&opts.Severity, "severity", []string{}, "Override a rule's severity as rule=severity (error, warning, info)")
+	cmd.Flags().BoolVar(&opts.ListRules, "list-rules", false, "List validation rules and exit")
 
 	return cmd
 }
@@ -65,6 +103,18 @@ Examples:
 func runValidation(log *logger.Logger, cfg *config.Config, opts *ValidationOptions) error {
 	log.StartOperation("Validation", "path", opts.Path, "format", opts.Format)
 
+	failOn := strings.ToLower(opts.FailOn)
+	switch failOn {
+	case "error", "warning", "none":
+	default:
+		return fmt.Errorf("invalid --fail-on value: %s (expected error, warning, or none)", opts.FailOn)
+	}
+
+	rules, err := validationRuleOverrides(cfg.Validation, opts)
+	if err != nil {
+		return err
+	}
+
 	// Check if path exists
 	if _, err := os.Stat(opts.Path); os.IsNotExist(err) {
 		return fmt.Errorf("path does not exist: %s", opts.Path)
@@ -88,6 +138,7 @@ func runValidation(log *logger.Logger, cfg *config.Config, opts *ValidationOptio
 			Recursive: opts.Recursive,
 			Fix:       opts.Fix,
 			Strict:    opts.Strict,
+			Rules:     rules,
 		})
 	} else {
 		// Validate single file
@@ -95,6 +146,7 @@ func runValidation(log *logger.Logger, cfg *config.Config, opts *ValidationOptio
 			Format: opts.Format,
 			Fix:    opts.Fix,
 			Strict: opts.Strict,
+			Rules:  rules,
 		})
 		if validationErr == nil {
 			results = []*validation.ValidationResult{result}
@@ -124,6 +176,8 @@ func runValidation(log *logger.Logger, cfg *config.Config, opts *ValidationOptio
 				case "warning":
 					totalWarnings++
 					log.Warn("Validation warning", "file", result.Path, "line", issue.Line, "message", issue.Message)
+				case "info":
+					log.Info("Validation note", "file", result.Path, "line", issue.Line, "message", issue.Message)
 				}
 				
 				if issue.Fixed {
@@ -151,10 +205,72 @@ func runValidation(log *logger.Logger, cfg *config.Config, opts *ValidationOptio
 
 	log.CompleteOperation("Validation", "files_validated", len(results), "issues_found", totalIssues)
 
-	// Return error if there were validation errors (not warnings)
-	if totalErrors > 0 {
-		return fmt.Errorf("validation failed with %d errors", totalErrors)
+	// Return error if issues reach the configured failure threshold
+	switch failOn {
+	case "error":
+		if totalErrors > 0 {
+			return fmt.Errorf("validation failed with %d errors", totalErrors)
+		}
+	case "warning":
+		if totalErrors > 0 || totalWarnings > 0 {
+			return fmt.Errorf("validation failed with %d errors and %d warnings", totalErrors, totalWarnings)
+		}
 	}
 
 	return nil
 }
+
+// validationRuleOverrides combines validation.rules from the config file with
+// the --enable, --disable and --severity flags, which take precedence, and
+// rejects unknown rule IDs
+func validationRuleOverrides(cfg config.ValidationConfig, opts *ValidationOptions) (validation.RuleOverrides, error) {
+	configured := make(validation.RuleOverrides, len(cfg.Rules))
+	for id, rule := range cfg.Rules {
+		configured[id] = validation.RuleOverride{Enabled: rule.Enabled, Severity: strings.ToLower(rule.Severity)}
+	}
+	if err := configured.Check(); err != nil {
+		return nil, fmt.Errorf("invalid validation.rules in config: %w", err)
+	}
+
+	flags := make(validation.RuleOverrides)
+	setEnabled := func(ids []string, enabled bool) {
+		for _, id := range ids {
+			override := flags[id]
+			override.Enabled = &enabled
+			flags[id] = override
+		}
+	}
+	setEnabled(opts.Enable, true)
+	setEnabled(opts.Disable, false)
+	for _, value := range opts.Severity {
+		id, severity, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --severity value: %s (expected rule=severity)", value)
+		}
+		override := flags[id]
+		override.Severity = strings.ToLower(severity)
+		flags[id] = override
+	}
+	if err := flags.Check(); err != nil {
+		return nil, err
+	}
+
+	return configured.Merge(flags), nil
+}
+
+// formatValidationRules renders the rule registry as a table
+func formatValidationRules() string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Rule", "Format", "Severity", "Strict", "Description"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, rule := range validation.Rules {
+		table.Append([]string{rule.ID, rule.Format, rule.Severity, rule.StrictSeverity, rule.Description})
+	}
+
+	table.Render()
+	return output.String()
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/output"
+)
+
+// WatchOptions holds options for the watch command
+type WatchOptions struct {
+	Discover   DiscoverOptions // used for every cycle
+	Interval   time.Duration
+	StatusAddr string // admin listener serving /status; empty disables it
+}
+
+// NewWatchCmd creates the watch command
+func NewWatchCmd(log *logger.Logger, cfg *config.Config) *cobra.Command {
+	opts := &WatchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Discover again on an interval, reloading the config file when it changes",
+		Long: `Run discovery every --interval and write the results to --output-file, until
+interrupted.
+
+The config file is watched while running. When it changes, it is parsed and
+validated, and the next cycle uses it; a cycle already running finishes with
+the configuration it started with. The changed keys are logged with
+passwords and secrets redacted. An invalid file is rejected and logged, and
+the active configuration kept, so rotated credentials take effect without a
+restart.
+
+GET /status on --status-addr reports the active configuration generation,
+when it was loaded, the latest rejected change and the latest cycle.
+
+Examples:
+  # Refresh the VMware inventory every 15 minutes
+  valhalla watch --provider vmware --config valhalla.yaml -o inventory.json
+
+  # Every hour, reporting status on all interfaces
+  valhalla watch --interval 1h --status-addr :9090 -o inventory.json
+  curl http://localhost:9090/status`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opts.Interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			reloader := config.NewReloader(cfg, log)
+			if err := reloader.Watch(ctx); err != nil {
+				log.Warn("Not watching the configuration for changes", "error", err)
+			}
+			return runWatch(ctx, log, &watchState{reloader: reloader}, opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&opts.Discover.Providers, "provider", "p", []string{}, "Providers to discover (vmware, proxmox, nutanix); defaults to discover.providers from config")
+	cmd.Flags().StringVar(&opts.Discover.Profile, "profile", "", "Provider profile to discover, or all for every configured profile (defaults to the flat provider keys)")
+	cmd.Flags().StringVarP(&opts.Discover.OutputFormat, "format", "f", "json", "Output format ("+strings.Join(output.Formats, ", ")+")")
+	cmd.Flags().StringVarP(&opts.Discover.OutputFile, "output-file", "o", "", "Output file, replaced after every cycle")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 15*time.Minute, "Time between the start of one discovery and the next")
+	cmd.Flags().DurationVar(&opts.Discover.Timeout, "timeout", 5*time.Minute, "Timeout of each discovery")
+	cmd.Flags().StringVar(&opts.StatusAddr, "status-addr", "127.0.0.1:8089", "Address serving GET /status (empty disables it)")
+	cmd.MarkFlagRequired("output-file")
+
+	return cmd
+}
+
+// watchCycle describes one discovery run of the watch command
+type watchCycle struct {
+	Started          time.Time `json:"started"`
+	Finished         time.Time `json:"finished"`
+	ConfigGeneration int       `json:"config_generation"`
+	Error            string    `json:"error,omitempty"`
+}
+
+// watchState tracks the discovery cycles of the watch command for /status
+type watchState struct {
+	reloader *config.Reloader
+
+	mu        sync.Mutex
+	cycles    int
+	lastCycle *watchCycle
+	nextCycle time.Time
+}
+
+// record stores a finished cycle and when the next one starts
+func (s *watchState) record(cycle watchCycle, next time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cycles++
+	s.lastCycle = &cycle
+	s.nextCycle = next
+}
+
+// ServeHTTP reports the configuration and the latest cycle as JSON
+func (s *watchState) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	status := struct {
+		Config    config.ReloadStatus `json:"config"`
+		Cycles    int                 `json:"cycles"`
+		LastCycle *watchCycle         `json:"last_cycle,omitempty"`
+		NextCycle *time.Time          `json:"next_cycle,omitempty"`
+	}{
+		Config:    s.reloader.Status(),
+		Cycles:    s.cycles,
+		LastCycle: s.lastCycle,
+	}
+	if !s.nextCycle.IsZero() {
+		next := s.nextCycle
+		status.NextCycle = &next
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// runWatch discovers every opts.Interval with the active configuration of
+// state's reloader until ctx is cancelled. A failed cycle is logged and
+// reported on /status; the next one still runs.
+func runWatch(ctx context.Context, log *logger.Logger, state *watchState, opts *WatchOptions) error {
+	reloader := state.reloader
+
+	if opts.StatusAddr != "" {
+		listener, err := net.Listen("tcp", opts.StatusAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", opts.StatusAddr, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/status", state)
+		server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("Status endpoint stopped", "error", err)
+			}
+		}()
+		defer server.Close()
+		log.Info("Serving status", "url", "http://"+listener.Addr().String()+"/status")
+	}
+
+	for {
+		cycle := watchCycle{Started: time.Now()}
+		err := reloader.Use(func(cfg *config.Config) error {
+			cycle.ConfigGeneration = reloader.Status().Generation
+
+			// runDiscover fills in defaults, so every cycle starts from the
+			// flags and takes the rest from the configuration it runs with
+			cycleOpts := opts.Discover
+			cycleOpts.Providers = append([]string(nil), opts.Discover.Providers...)
+			cycleOpts.SignKey = cfg.Signing.KeyFile
+			return runDiscover(log, cfg, &cycleOpts)
+		})
+		cycle.Finished = time.Now()
+		if err != nil {
+			cycle.Error = err.Error()
+			log.Error("Discovery cycle failed", "generation", cycle.ConfigGeneration, "error", err)
+		}
+
+		next := cycle.Started.Add(opts.Interval)
+		state.record(cycle, next)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+)
+
+func TestWatchReloadsConfigBetweenCycles(t *testing.T) {
+	for _, env := range []string{"VSPHERE_SERVER", "VSPHERE_USER", "VSPHERE_PASSWORD"} {
+		t.Setenv(env, "")
+	}
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "valhalla.yaml")
+	incomplete := "output:\n  directory: " + dir + "\ndiscover:\n  providers: [vmware]\nproviders:\n  vmware:\n    server: vcenter.example.com\n    username: svc-valhalla\n"
+	if err := os.WriteFile(filename, []byte(incomplete), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.New()
+	if err := cfg.InitConfig(filename); err != nil {
+		t.Fatalf("InitConfig: %v", err)
+	}
+
+	log := logger.NewWithOutput(io.Discard)
+	reloader := config.NewReloader(cfg, log)
+	state := &watchState{reloader: reloader}
+	opts := &WatchOptions{
+		Discover: DiscoverOptions{OutputFormat: "json", OutputFile: filepath.Join(dir, "inventory.json"), Timeout: time.Minute, DryRun: true},
+		Interval: 20 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := reloader.Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	done := make(chan error)
+	go func() { done <- runWatch(ctx, log, state, opts) }()
+
+	status := func() watchStatusJSON {
+		recorder := httptest.NewRecorder()
+		state.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+		var status watchStatusJSON
+		if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+			t.Fatalf("/status: %v\n%s", err, recorder.Body)
+		}
+		return status
+	}
+	waitFor := func(what string, done func(watchStatusJSON) bool) watchStatusJSON {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			s := status()
+			if done(s) {
+				return s
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %+v", what, s)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// Without a password every cycle fails its credential check
+	first := waitFor("a failed cycle", func(s watchStatusJSON) bool { return s.LastCycle != nil })
+	if !strings.Contains(first.LastCycle.Error, "provider configuration incomplete") || first.Config.Generation != 1 {
+		t.Errorf("first cycle: %+v", first)
+	}
+
+	// Rotated credentials apply from the next cycle on, without a restart
+	if err := os.WriteFile(filename, []byte(incomplete+"    password: Passw0rd\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := waitFor("a cycle with the new config", func(s watchStatusJSON) bool {
+		return s.LastCycle != nil && s.LastCycle.ConfigGeneration == 2
+	})
+	if reloaded.LastCycle.Error != "" || reloaded.Config.Generation != 2 || reloaded.Config.ConfigFile != filename || reloaded.Cycles < 2 {
+		t.Errorf("status after reload: %+v", reloaded)
+	}
+	if reloaded.NextCycle == nil || reloaded.NextCycle.Before(reloaded.LastCycle.Started) {
+		t.Errorf("next cycle = %v", reloaded.NextCycle)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("runWatch: %v", err)
+	}
+}
+
+func TestWatchStatusMethods(t *testing.T) {
+	state := &watchState{reloader: config.NewReloader(config.New(), logger.NewWithOutput(io.Discard))}
+
+	recorder := httptest.NewRecorder()
+	state.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /status = %d, want 405", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	state.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("GET /status = %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	// Before the first cycle there is no cycle to report
+	body := recorder.Body.String()
+	if !strings.Contains(body, `"generation":1`) || strings.Contains(body, "last_cycle") || strings.Contains(body, "next_cycle") {
+		t.Errorf("GET /status before the first cycle:\n%s", body)
+	}
+}
+
+// watchStatusJSON is the /status response as clients read it
+type watchStatusJSON struct {
+	Config    config.ReloadStatus `json:"config"`
+	Cycles    int                 `json:"cycles"`
+	LastCycle *watchCycle         `json:"last_cycle"`
+	NextCycle *time.Time          `json:"next_cycle"`
+}
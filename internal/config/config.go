@@ -1,22 +1,198 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"valhalla/internal/secrets"
 )
 
 // Config holds all configuration for Valhalla
 type Config struct {
 	Debug     bool          `mapstructure:"debug"`
 	LogFormat string        `mapstructure:"log_format"`
+	LogFile   string        `mapstructure:"log_file"` // rotated log file replacing stdout
 	Providers ProvidersConfig `mapstructure:"providers"`
 	Output    OutputConfig  `mapstructure:"output"`
+	Discover  DiscoverConfig `mapstructure:"discover"`
+	Ownership OwnershipConfig `mapstructure:"ownership"`
+	Complexity ComplexityConfig `mapstructure:"complexity"`
+	Hardware  HardwareConfig `mapstructure:"hardware"`
+	Storage   StorageConfig  `mapstructure:"storage"`
+	Validation ValidationConfig `mapstructure:"validation"`
+	NetworkCheck NetworkCheckConfig `mapstructure:"network_check"`
+	Capacity  CapacityConfig `mapstructure:"capacity"`
+	Generate  GenerateConfig `mapstructure:"generate"`
+	Signing   SigningConfig  `mapstructure:"signing"`
 }
 
-// ProvidersConfig holds provider-specific configurations
+// GenerateConfig holds settings of the generate command
+type GenerateConfig struct {
+	Hooks []HookConfig `mapstructure:"hooks"` // run on each generated file, in order
+}
+
+// HookConfig post-processes generated files. For each generated file
+// matching Files, the hook runs Command with the file's content on stdin,
+// or renders Template, and its output replaces the content.
+type HookConfig struct {
+	Name     string        `mapstructure:"name"`
+	Files    []string      `mapstructure:"files"`    // patterns of generated paths, e.g. *.tf; empty matches every file
+	Command  string        `mapstructure:"command"`  // executable to run
+	Args     []string      `mapstructure:"args"`
+	Template string        `mapstructure:"template"` // Go template rendered instead of running a command
+	Timeout  time.Duration `mapstructure:"timeout"`  // per file; 0 for 30s
+}
+
+// SigningConfig holds the Ed25519 keys outputs are signed and inputs
+// verified with; see internal/signing
+type SigningConfig struct {
+	KeyFile       string `mapstructure:"key_file"`        // PEM private key signing discover output and provenance.json
+	PublicKeyFile string `mapstructure:"public_key_file"` // PEM public key generate verifies its input with
+}
+
+// CapacityConfig holds the overcommit limits generate checks a target
+// cluster against
+type CapacityConfig struct {
+	CPUOvercommit    float64 `mapstructure:"cpu_overcommit"`    // vCPUs allowed per physical core
+	MemoryOvercommit float64 `mapstructure:"memory_overcommit"` // configured memory allowed per MiB of host memory
+}
+
+// NetworkCheckConfig holds the settings of discover --verify-network
+type NetworkCheckConfig struct {
+	Ports       []int         `mapstructure:"ports"`        // TCP ports probed on each VM's primary IP; empty only checks DNS
+	Timeout     time.Duration `mapstructure:"timeout"`      // per DNS lookup and per port probe
+	Concurrency int           `mapstructure:"concurrency"`  // VMs checked at once
+	ProbePublic bool          `mapstructure:"probe_public"` // also probe addresses outside private, loopback and link-local ranges
+}
+
+// ValidationConfig holds per-rule settings for the validate command
+type ValidationConfig struct {
+	Rules map[string]ValidationRuleConfig `mapstructure:"rules"` // by rule ID, see valhalla validate --list-rules
+}
+
+// ValidationRuleConfig enables or disables a validation rule or changes the
+// severity of its issues
+type ValidationRuleConfig struct {
+	Enabled  *bool  `mapstructure:"enabled"`
+	Severity string `mapstructure:"severity"` // error, warning or info
+}
+
+// StorageConfig holds the datastore utilization policy
+type StorageConfig struct {
+	RebalanceThreshold float64 `mapstructure:"rebalance_threshold"` // used percent above which moves are proposed; 0 disables
+}
+
+// HardwareConfig holds the VMware virtual hardware version policy
+type HardwareConfig struct {
+	MinVersion int `mapstructure:"min_version"` // VMs below this version, e.g. 14 for vmx-14, get an upgrade advisory
+}
+
+// ComplexityConfig holds the weights used to score how much manual work a
+// VM needs to migrate
+type ComplexityConfig struct {
+	Weights      map[string]int `mapstructure:"weights"`        // points per factor, e.g. rdm: 5
+	MaxNICs      int            `mapstructure:"max_nics"`       // more network cards than this adds many_nics
+	LargeDiskGiB int64          `mapstructure:"large_disk_gib"` // disks at least this large add large_disks
+}
+
+// OwnershipConfig holds the ordered rules used to infer VM ownership
+type OwnershipConfig struct {
+	Rules []OwnershipRule `mapstructure:"rules"`
+}
+
+// OwnershipRule derives a VM's owner or environment from one source.
+// Rules are evaluated in order and the first match for each field wins.
+type OwnershipRule struct {
+	Field   string `mapstructure:"field"`   // owner (default) or environment
+	Source  string `mapstructure:"source"`  // annotation, tag, folder or default
+	Key     string `mapstructure:"key"`     // annotation key or tag category
+	Pattern string `mapstructure:"pattern"` // folder regex; the first capture group is the value
+	Value   string `mapstructure:"value"`   // value for default rules
+}
+
+// DiscoverConfig holds defaults for the discover command
+type DiscoverConfig struct {
+	Providers       []string                 `mapstructure:"providers"`
+	Timeouts        map[string]time.Duration `mapstructure:"timeouts"`         // per-provider time budget, e.g. nutanix: 2m
+	TimeoutFraction float64                  `mapstructure:"timeout_fraction"` // share of --timeout each provider may use
+	DefaultFilters  FilterConfig             `mapstructure:"default_filters"`  // exclusions applied to every discovery
+	MaxProviders    int                      `mapstructure:"max_providers"`    // providers discovered at once; 0 discovers all at once
+
+	// Long-running phases log a heartbeat every HeartbeatInterval and are
+	// aborted with a warning after StallTimeout without an API response;
+	// 0 disables either
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	StallTimeout      time.Duration `mapstructure:"stall_timeout"`
+
+	// CacheTTL reuses results cached under the output directory for this
+	// long instead of connecting again; 0 disables the cache
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+}
+
+// FilterConfig excludes VMs from discovery results. Names, folders and tags
+// are shell-style patterns such as "tmp-*"; all values match regardless of
+// case. Excluding a folder also excludes its subfolders.
+type FilterConfig struct {
+	ExcludeNames       []string `mapstructure:"exclude_names"`
+	ExcludeFolders     []string `mapstructure:"exclude_folders"`
+	ExcludePowerStates []string `mapstructure:"exclude_power_states"` // e.g. poweredOff, stopped or OFF depending on the provider
+	ExcludeTags        []string `mapstructure:"exclude_tags"`
+}
+
+// IsEmpty reports whether the filters exclude nothing
+func (f FilterConfig) IsEmpty() bool {
+	return len(f.ExcludeNames) == 0 && len(f.ExcludeFolders) == 0 &&
+		len(f.ExcludePowerStates) == 0 && len(f.ExcludeTags) == 0
+}
+
+// Merge combines default filters with filters given on the command line.
+// A filter set on the command line replaces the default for that filter;
+// the others keep their defaults. It also returns the defaults that were
+// kept, as name=values strings for logging.
+func (f FilterConfig) Merge(cli FilterConfig) (FilterConfig, []string) {
+	var applied []string
+	pick := func(name string, defaults, override []string) []string {
+		if len(override) > 0 {
+			return override
+		}
+		if len(defaults) > 0 {
+			applied = append(applied, name+"="+strings.Join(defaults, ","))
+		}
+		return defaults
+	}
+
+	merged := FilterConfig{
+		ExcludeNames:       pick("exclude_names", f.ExcludeNames, cli.ExcludeNames),
+		ExcludeFolders:     pick("exclude_folders", f.ExcludeFolders, cli.ExcludeFolders),
+		ExcludePowerStates: pick("exclude_power_states", f.ExcludePowerStates, cli.ExcludePowerStates),
+		ExcludeTags:        pick("exclude_tags", f.ExcludeTags, cli.ExcludeTags),
+	}
+	return merged, applied
+}
+
+// Validate checks that every pattern is well formed
+func (f FilterConfig) Validate() error {
+	for _, patterns := range [][]string{f.ExcludeNames, f.ExcludeFolders, f.ExcludeTags} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ProvidersConfig holds provider-specific configurations. The flat keys of
+// each provider are its default profile; further endpoints are named
+// profiles under providers.<provider>.profiles, read with GetVMwareProfile
+// and its siblings.
 type ProvidersConfig struct {
 	VMware  VMwareConfig  `mapstructure:"vmware"`
 	Proxmox ProxmoxConfig `mapstructure:"proxmox"`
@@ -31,6 +207,44 @@ type VMwareConfig struct {
 	Insecure   bool   `mapstructure:"insecure"`
 	Datacenter string `mapstructure:"datacenter"`
 	Cluster    string `mapstructure:"cluster"`
+	WithAlarms bool   `mapstructure:"with_alarms"`
+	WithPermissions bool `mapstructure:"with_permissions"` // list permissions and resolve their principals
+	WithContentLibraries bool `mapstructure:"with_content_libraries"` // list content libraries and their items
+	LDAP       LDAPConfig `mapstructure:"ldap"` // directory behind the identity source, for principal details
+	AllNetworks bool  `mapstructure:"all_networks"`
+	IncludeSnapshots bool `mapstructure:"include_snapshots"` // list each VM's snapshot tree, not just the count
+	IncludeTemplates bool `mapstructure:"include_templates"` // also list templates among the VMs
+	Discovery  VMwareDiscoveryConfig `mapstructure:"discovery"`
+	DumpRaw    string `mapstructure:"dump_raw"` // directory for raw API objects, debug only
+	VMs        VMSelection `mapstructure:"-"` // set from discover flags
+}
+
+// LDAPConfig points permission discovery at the directory behind vCenter's
+// identity source, for accounts that cannot use the SSO admin API
+type LDAPConfig struct {
+	URL          string `mapstructure:"url"` // ldap://dc01.example.com or ldaps://dc01.example.com
+	BindDN       string `mapstructure:"bind_dn"`
+	BindPassword string `mapstructure:"bind_password"`
+	BaseDN       string `mapstructure:"base_dn"`
+	Insecure     bool   `mapstructure:"insecure"`
+}
+
+// VMSelection limits VMware discovery to the VMs matching every field set
+type VMSelection struct {
+	PowerState   string   // poweredOn, poweredOff or suspended
+	Names        []string // VM name contains any of these, ignoring case
+	Host         string
+	ResourcePool string
+	Folder       string // folder path below the datacenter's VM folder, including subfolders
+}
+
+// VMwareDiscoveryConfig tunes how VMware object properties are retrieved
+type VMwareDiscoveryConfig struct {
+	MaxRetries        int           `mapstructure:"max_retries"`         // retries per failed retrieval
+	Backoff           time.Duration `mapstructure:"backoff"`             // initial delay between retries, doubled each attempt
+	BatchSize         int           `mapstructure:"batch_size"`          // objects per property retrieval; 1 retrieves objects one at a time
+	PerObjectFallback bool          `mapstructure:"per_object_fallback"` // retry a failed batch one object at a time
+	Concurrency       int           `mapstructure:"concurrency"`         // batches retrieved at once
 }
 
 // ProxmoxConfig holds Proxmox configuration
@@ -42,6 +256,9 @@ type ProxmoxConfig struct {
 	Secret   string `mapstructure:"secret"`
 	Node     string `mapstructure:"node"`
 	Insecure bool   `mapstructure:"insecure"`
+	DeepStorageScan bool `mapstructure:"deep_storage_scan"` // list every storage volume, not just per-content totals
+	IncludeTemplates bool `mapstructure:"include_templates"` // also list templates among the VMs
+	Concurrency     int  `mapstructure:"concurrency"`       // guest configurations and storage listings read at once
 }
 
 // NutanixConfig holds Nutanix configuration
@@ -52,6 +269,7 @@ type NutanixConfig struct {
 	Port     int    `mapstructure:"port"`
 	Insecure bool   `mapstructure:"insecure"`
 	Cluster  string `mapstructure:"cluster"`
+	Concurrency int `mapstructure:"concurrency"` // category value listings read at once
 }
 
 // OutputConfig holds output configuration
@@ -59,6 +277,8 @@ type OutputConfig struct {
 	Format    string `mapstructure:"format"`
 	Directory string `mapstructure:"directory"`
 	Filename  string `mapstructure:"filename"`
+	YAMLDocuments bool `mapstructure:"yaml_documents"`
+	MaxFieldSize  int  `mapstructure:"max_field_size"`
 }
 
 // New creates a new Config instance
@@ -95,7 +315,10 @@ func (c *Config) InitConfig(cfgFile string) error {
 
 	// Read in config file
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+		_, notFound := err.(viper.ConfigFileNotFoundError)
+		// A --config file that doesn't exist yet is where auth --save
+		// creates it, so it is treated like a missing default file
+		if !notFound && !(cfgFile != "" && errors.Is(err, fs.ErrNotExist)) {
 			return fmt.Errorf("failed to read config file: %w", err)
 		}
 		// Config file not found; ignore error
@@ -116,24 +339,103 @@ func (c *Config) setDefaults() {
 	viper.SetDefault("output.format", "table")
 	viper.SetDefault("output.directory", "./output")
 	viper.SetDefault("output.filename", "infrastructure")
+	viper.SetDefault("output.yaml_documents", false)
+	viper.SetDefault("output.max_field_size", 4096)
+	viper.SetDefault("discover.providers", []string{})
+	viper.SetDefault("discover.timeout_fraction", 0)
+	viper.SetDefault("discover.max_providers", 0)
+	viper.SetDefault("discover.heartbeat_interval", "45s")
+	viper.SetDefault("discover.stall_timeout", "5m")
+	viper.SetDefault("complexity.max_nics", 4)
+	viper.SetDefault("complexity.large_disk_gib", 2048)
+	viper.SetDefault("complexity.weights.snapshots", 2)
+	viper.SetDefault("complexity.weights.rdm", 5)
+	viper.SetDefault("complexity.weights.passthrough", 5)
+	viper.SetDefault("complexity.weights.fault_tolerance", 4)
+	viper.SetDefault("complexity.weights.many_nics", 2)
+	viper.SetDefault("complexity.weights.independent_disks", 3)
+	viper.SetDefault("complexity.weights.large_disks", 3)
+	viper.SetDefault("complexity.weights.unknown_os", 2)
+	viper.SetDefault("complexity.weights.missing_tools", 1)
+	viper.SetDefault("hardware.min_version", 14)
+	viper.SetDefault("storage.rebalance_threshold", 80)
+	viper.SetDefault("signing.key_file", "")
+	viper.SetDefault("signing.public_key_file", "")
+	viper.SetDefault("capacity.cpu_overcommit", 4.0)
+	viper.SetDefault("capacity.memory_overcommit", 1.0)
+	viper.SetDefault("network_check.ports", []int{22, 3389})
+	viper.SetDefault("network_check.timeout", "2s")
+	viper.SetDefault("network_check.concurrency", 16)
+	viper.SetDefault("network_check.probe_public", false)
 	
+	// Credentials default to empty so VALHALLA_PROVIDERS_* environment
+	// variables reach them; viper only unmarshals keys it knows about
+	for _, key := range []string{
+		"providers.vmware.server", "providers.vmware.username", "providers.vmware.password",
+		"providers.proxmox.server", "providers.proxmox.username", "providers.proxmox.password",
+		"providers.proxmox.token_id", "providers.proxmox.secret",
+		"providers.nutanix.server", "providers.nutanix.username", "providers.nutanix.password",
+	} {
+		viper.SetDefault(key, "")
+	}
+
 	// VMware defaults
 	viper.SetDefault("providers.vmware.insecure", true)
 	viper.SetDefault("providers.vmware.datacenter", "")
 	viper.SetDefault("providers.vmware.cluster", "")
+	viper.SetDefault("providers.vmware.with_alarms", false)
+	viper.SetDefault("providers.vmware.with_permissions", false)
+	viper.SetDefault("providers.vmware.with_content_libraries", false)
+	viper.SetDefault("providers.vmware.all_networks", false)
+	viper.SetDefault("providers.vmware.include_snapshots", false)
+	viper.SetDefault("providers.vmware.discovery.max_retries", 0)
+	viper.SetDefault("providers.vmware.discovery.backoff", "1s")
+	viper.SetDefault("providers.vmware.discovery.batch_size", 500)
+	viper.SetDefault("providers.vmware.discovery.per_object_fallback", true)
+	viper.SetDefault("providers.vmware.discovery.concurrency", 10)
 	
 	// Proxmox defaults
 	viper.SetDefault("providers.proxmox.insecure", true)
 	viper.SetDefault("providers.proxmox.node", "")
+	viper.SetDefault("providers.proxmox.deep_storage_scan", false)
+	viper.SetDefault("providers.proxmox.concurrency", 10)
 	
 	// Nutanix defaults
 	viper.SetDefault("providers.nutanix.port", 9440)
 	viper.SetDefault("providers.nutanix.insecure", true)
 	viper.SetDefault("providers.nutanix.cluster", "")
+	viper.SetDefault("providers.nutanix.concurrency", 10)
+}
+
+// GetVMwareConfig returns VMware configuration with environment variable
+// overrides, reading the password from the OS keyring when the config
+// refers to it
+func (c *Config) GetVMwareConfig() (VMwareConfig, error) {
+	return resolveVMwareSecrets(c.vmwareConfig())
 }
 
-// GetVMwareConfig returns VMware configuration with environment variable overrides
-func (c *Config) GetVMwareConfig() VMwareConfig {
+// resolveVMwareSecrets reads the password from the OS keyring when cfg
+// refers to it
+func resolveVMwareSecrets(cfg VMwareConfig) (VMwareConfig, error) {
+	password, err := secrets.Resolve("vmware", cfg.Username, cfg.Password)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Password = password
+
+	if cfg.LDAP.BindPassword != "" {
+		password, err := secrets.Resolve("vmware", cfg.LDAP.BindDN, cfg.LDAP.BindPassword)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.LDAP.BindPassword = password
+	}
+	return cfg, nil
+}
+
+// vmwareConfig returns VMware configuration with environment variable
+// overrides, leaving keyring references as they are
+func (c *Config) vmwareConfig() VMwareConfig {
 	cfg := c.Providers.VMware
 	
 	// Override with environment variables
@@ -150,8 +452,33 @@ func (c *Config) GetVMwareConfig() VMwareConfig {
 	return cfg
 }
 
-// GetProxmoxConfig returns Proxmox configuration with environment variable overrides
-func (c *Config) GetProxmoxConfig() ProxmoxConfig {
+// GetProxmoxConfig returns Proxmox configuration with environment variable
+// overrides, reading the password or token secret from the OS keyring when
+// the config refers to it
+func (c *Config) GetProxmoxConfig() (ProxmoxConfig, error) {
+	return resolveProxmoxSecrets(c.proxmoxConfig())
+}
+
+// resolveProxmoxSecrets reads the password and token secret from the OS
+// keyring when cfg refers to them
+func resolveProxmoxSecrets(cfg ProxmoxConfig) (ProxmoxConfig, error) {
+	password, err := secrets.Resolve("proxmox", cfg.Username, cfg.Password)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Password = password
+	// Token secrets are stored for the token ID, which names the user
+	secret, err := secrets.Resolve("proxmox", cfg.TokenID, cfg.Secret)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Secret = secret
+	return cfg, nil
+}
+
+// proxmoxConfig returns Proxmox configuration with environment variable
+// overrides, leaving keyring references as they are
+func (c *Config) proxmoxConfig() ProxmoxConfig {
 	cfg := c.Providers.Proxmox
 	
 	// Override with environment variables
@@ -174,8 +501,27 @@ func (c *Config) GetProxmoxConfig() ProxmoxConfig {
 	return cfg
 }
 
-// GetNutanixConfig returns Nutanix configuration with environment variable overrides
-func (c *Config) GetNutanixConfig() NutanixConfig {
+// GetNutanixConfig returns Nutanix configuration with environment variable
+// overrides, reading the password from the OS keyring when the config
+// refers to it
+func (c *Config) GetNutanixConfig() (NutanixConfig, error) {
+	return resolveNutanixSecrets(c.nutanixConfig())
+}
+
+// resolveNutanixSecrets reads the password from the OS keyring when cfg
+// refers to it
+func resolveNutanixSecrets(cfg NutanixConfig) (NutanixConfig, error) {
+	password, err := secrets.Resolve("nutanix", cfg.Username, cfg.Password)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Password = password
+	return cfg, nil
+}
+
+// nutanixConfig returns Nutanix configuration with environment variable
+// overrides, leaving keyring references as they are
+func (c *Config) nutanixConfig() NutanixConfig {
 	cfg := c.Providers.Nutanix
 	
 	// Override with environment variables
@@ -192,6 +538,61 @@ func (c *Config) GetNutanixConfig() NutanixConfig {
 	return cfg
 }
 
+// HasValue reports whether a configuration key has a value, taking
+// environment variable overrides for provider credentials into account.
+// Keyring references count as values without being read.
+func (c *Config) HasValue(key string) bool {
+	return c.ProfileHasValue(DefaultProfile, key)
+}
+
+// ProfileHasValue reports whether a provider profile has a value for a
+// flat provider key such as providers.vmware.server, like HasValue. Other
+// keys don't depend on the profile. Unknown profiles have no values.
+func (c *Config) ProfileHasValue(profile, key string) bool {
+	switch key {
+	case "providers.vmware.server", "providers.vmware.username", "providers.vmware.password":
+		cfg, err := c.vmwareProfile(profile)
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "providers.vmware.server":
+			return cfg.Server != ""
+		case "providers.vmware.username":
+			return cfg.Username != ""
+		}
+		return cfg.Password != ""
+	case "providers.proxmox.server", "providers.proxmox.username", "providers.proxmox.password":
+		cfg, err := c.proxmoxProfile(profile)
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "providers.proxmox.server":
+			return cfg.Server != ""
+		case "providers.proxmox.username":
+			// A full user@realm!name token ID names the user itself
+			return cfg.Username != "" || strings.Contains(cfg.TokenID, "!")
+		}
+		// An API token is an accepted alternative to a password
+		return cfg.Password != "" || (cfg.TokenID != "" && cfg.Secret != "")
+	case "providers.nutanix.server", "providers.nutanix.username", "providers.nutanix.password":
+		cfg, err := c.nutanixProfile(profile)
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "providers.nutanix.server":
+			return cfg.Server != ""
+		case "providers.nutanix.username":
+			return cfg.Username != ""
+		}
+		return cfg.Password != ""
+	default:
+		return viper.IsSet(key)
+	}
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.Output.Directory != "" {
@@ -199,14 +600,84 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
-	
-	return nil
+
+	return c.ValidateProfiles()
+}
+
+// LoadOwnershipRules reads ownership rules from a standalone rules file laid
+// out like the ownership section of the config file
+func LoadOwnershipRules(filename string) ([]OwnershipRule, error) {
+	v := viper.New()
+	v.SetConfigFile(filename)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var rules OwnershipConfig
+	if err := v.Unmarshal(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	return rules.Rules, nil
 }
 
-// WriteConfigFile writes the current configuration to a file
+// WriteConfigFile writes the current configuration to a file. The file may
+// hold credentials, so it is only readable by its owner.
 func (c *Config) WriteConfigFile(filename string) error {
 	viper.SetConfigFile(filename)
-	return viper.WriteConfig()
+	viper.SetConfigPermissions(0600)
+	if err := viper.WriteConfig(); err != nil {
+		return err
+	}
+	// The permissions above only apply when the file is created
+	return os.Chmod(filename, 0600)
+}
+
+// SaveProviderSettings sets provider settings under providers.<provider>,
+// where provider may also be a profile's section from ProfileSection, and
+// writes the configuration to the config file in use, or to ~/.valhalla.yaml
+// when none was loaded. Settings already in the file are kept. It returns the
+// path written.
+func (c *Config) SaveProviderSettings(provider string, settings map[string]interface{}) (string, error) {
+	filename := c.GetConfigFile()
+	if filename == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		filename = filepath.Join(home, ".valhalla.yaml")
+	}
+
+	// Only the file's own contents are rewritten, so defaults and
+	// environment overrides are not persisted
+	file := viper.New()
+	file.SetConfigFile(filename)
+	if filepath.Ext(filename) == "" {
+		file.SetConfigType("yaml")
+	}
+	if _, err := os.Stat(filename); err == nil {
+		if err := file.ReadInConfig(); err != nil {
+			return "", fmt.Errorf("failed to read config file %s: %w", filename, err)
+		}
+	}
+
+	for key, value := range settings {
+		key = "providers." + provider + "." + key
+		file.Set(key, value)
+		viper.Set(key, value)
+	}
+	if err := viper.Unmarshal(c); err != nil {
+		return "", fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	file.SetConfigPermissions(0600)
+	if err := file.WriteConfig(); err != nil {
+		return "", fmt.Errorf("failed to write config file %s: %w", filename, err)
+	}
+	if err := os.Chmod(filename, 0600); err != nil {
+		return "", fmt.Errorf("failed to restrict config file %s: %w", filename, err)
+	}
+	return filename, nil
 }
 
 // GetConfigFile returns the path to the config file
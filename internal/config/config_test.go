@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterConfigMerge(t *testing.T) {
+	defaults := FilterConfig{
+		ExcludeNames:       []string{"tmp-*"},
+		ExcludePowerStates: []string{"poweredOff"},
+		ExcludeTags:        []string{"env:sandbox"},
+	}
+
+	tests := []struct {
+		name     string
+		defaults FilterConfig
+		cli      FilterConfig
+		want     FilterConfig
+		applied  []string
+	}{
+		{
+			name:     "defaults only",
+			defaults: defaults,
+			want:     defaults,
+			applied:  []string{"exclude_names=tmp-*", "exclude_power_states=poweredOff", "exclude_tags=env:sandbox"},
+		},
+		{
+			name: "flags only",
+			cli:  FilterConfig{ExcludeFolders: []string{"*/Sandbox"}},
+			want: FilterConfig{ExcludeFolders: []string{"*/Sandbox"}},
+		},
+		{
+			name:     "flag replaces the default for the same filter",
+			defaults: defaults,
+			cli:      FilterConfig{ExcludeNames: []string{"test-*", "scratch"}},
+			want: FilterConfig{
+				ExcludeNames:       []string{"test-*", "scratch"},
+				ExcludePowerStates: []string{"poweredOff"},
+				ExcludeTags:        []string{"env:sandbox"},
+			},
+			applied: []string{"exclude_power_states=poweredOff", "exclude_tags=env:sandbox"},
+		},
+		{
+			name:     "flags for other filters add to the defaults",
+			defaults: defaults,
+			cli:      FilterConfig{ExcludeFolders: []string{"Archive"}},
+			want: FilterConfig{
+				ExcludeNames:       []string{"tmp-*"},
+				ExcludeFolders:     []string{"Archive"},
+				ExcludePowerStates: []string{"poweredOff"},
+				ExcludeTags:        []string{"env:sandbox"},
+			},
+			applied: []string{"exclude_names=tmp-*", "exclude_power_states=poweredOff", "exclude_tags=env:sandbox"},
+		},
+		{
+			name:     "every flag set",
+			defaults: defaults,
+			cli: FilterConfig{
+				ExcludeNames:       []string{"a"},
+				ExcludeFolders:     []string{"b"},
+				ExcludePowerStates: []string{"suspended"},
+				ExcludeTags:        []string{"d"},
+			},
+			want: FilterConfig{
+				ExcludeNames:       []string{"a"},
+				ExcludeFolders:     []string{"b"},
+				ExcludePowerStates: []string{"suspended"},
+				ExcludeTags:        []string{"d"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, applied := tt.defaults.Merge(tt.cli)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(applied, tt.applied) {
+				t.Errorf("applied = %v, want %v", applied, tt.applied)
+			}
+		})
+	}
+}
+
+func TestFilterConfigValidate(t *testing.T) {
+	if err := (FilterConfig{ExcludeNames: []string{"tmp-*"}, ExcludeTags: []string{"env:[ab]*"}}).Validate(); err != nil {
+		t.Errorf("valid patterns: %v", err)
+	}
+	if err := (FilterConfig{ExcludeFolders: []string{"[unclosed"}}).Validate(); err == nil {
+		t.Error("malformed pattern was accepted")
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"valhalla/internal/secrets"
+)
+
+// DefaultProfile names the endpoint configured by a provider's flat keys,
+// e.g. providers.vmware.server, and its environment variables
+const DefaultProfile = "default"
+
+// AllProfiles selects every configured profile of a provider
+const AllProfiles = "all"
+
+// profileNamePattern matches the names allowed for named profiles. Viper
+// lowercases keys, and a dot would split the name into nested keys.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// IsDefaultProfile reports whether profile selects the flat keys
+func IsDefaultProfile(profile string) bool {
+	return profile == "" || strings.EqualFold(profile, DefaultProfile)
+}
+
+// ProfileSection returns the section under providers holding a profile's
+// settings: the provider itself for the default profile, otherwise
+// <provider>.profiles.<profile>
+func ProfileSection(provider, profile string) string {
+	if IsDefaultProfile(profile) {
+		return provider
+	}
+	return provider + ".profiles." + strings.ToLower(profile)
+}
+
+// ProfileNames returns the profiles configured for a provider: the default
+// profile first when its server is set, then the named profiles under
+// providers.<provider>.profiles in name order
+func (c *Config) ProfileNames(provider string) []string {
+	var names []string
+	if c.HasValue("providers." + provider + ".server") {
+		names = append(names, DefaultProfile)
+	}
+	return append(names, namedProfiles(provider)...)
+}
+
+// namedProfiles returns the names under providers.<provider>.profiles
+func namedProfiles(provider string) []string {
+	profiles := viper.GetStringMap("providers." + provider + ".profiles")
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SelectProfiles expands a --profile value into the profiles to use: the
+// default profile when it is empty, and every configured profile for all
+func (c *Config) SelectProfiles(provider, profile string) ([]string, error) {
+	profile = strings.ToLower(profile)
+	switch {
+	case IsDefaultProfile(profile):
+		return []string{DefaultProfile}, nil
+	case profile == AllProfiles:
+		names := c.ProfileNames(provider)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no %s profiles configured", provider)
+		}
+		return names, nil
+	case !viper.IsSet("providers." + ProfileSection(provider, profile)):
+		return nil, unknownProfile(provider, profile)
+	}
+	return []string{profile}, nil
+}
+
+// ValidateProfiles checks the names of the configured profiles. default and
+// all select profiles rather than name them, so they are reserved.
+func (c *Config) ValidateProfiles() error {
+	for _, provider := range []string{"vmware", "proxmox", "nutanix"} {
+		for _, name := range namedProfiles(provider) {
+			if name == DefaultProfile || name == AllProfiles {
+				return fmt.Errorf("providers.%s.profiles.%s: %q is reserved; the flat providers.%s keys are the default profile", provider, name, name, provider)
+			}
+			if err := ValidateProfileName(name); err != nil {
+				return fmt.Errorf("providers.%s.profiles.%s: %w", provider, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateProfileName checks that a named profile can be saved under
+// providers.<provider>.profiles
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(strings.ToLower(name)) {
+		return fmt.Errorf("invalid profile name %q: profile names may only contain letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// unknownProfile reports a profile that isn't configured, listing those that are
+func unknownProfile(provider, profile string) error {
+	names := namedProfiles(provider)
+	if len(names) == 0 {
+		return fmt.Errorf("unknown %s profile %q: no profiles configured under providers.%s.profiles", provider, profile, provider)
+	}
+	return fmt.Errorf("unknown %s profile %q (configured: %s)", provider, profile, strings.Join(names, ", "))
+}
+
+// decodeProfile decodes providers.<provider>.profiles.<profile> over cfg,
+// so settings the profile leaves out keep the values cfg has
+func decodeProfile(provider, profile string, cfg interface{}) error {
+	key := "providers." + ProfileSection(provider, profile)
+	if !viper.IsSet(key) {
+		return unknownProfile(provider, strings.ToLower(profile))
+	}
+	if err := viper.UnmarshalKey(key, cfg); err != nil {
+		return fmt.Errorf("failed to read %s profile %s: %w", provider, profile, err)
+	}
+	return nil
+}
+
+// GetVMwareProfile returns a VMware profile's configuration, reading
+// secrets from the OS keyring when the profile refers to them. The default
+// profile is GetVMwareConfig. Named profiles take the flat keys' settings
+// they leave out, except the server and credentials, and ignore the
+// environment variables.
+func (c *Config) GetVMwareProfile(profile string) (VMwareConfig, error) {
+	cfg, err := c.vmwareProfile(profile)
+	if err != nil {
+		return cfg, err
+	}
+	return resolveVMwareSecrets(cfg)
+}
+
+// vmwareProfile returns a VMware profile's configuration, leaving keyring
+// references as they are
+func (c *Config) vmwareProfile(profile string) (VMwareConfig, error) {
+	if IsDefaultProfile(profile) {
+		return c.vmwareConfig(), nil
+	}
+	cfg := c.Providers.VMware
+	cfg.Server, cfg.Username, cfg.Password = "", "", ""
+	err := decodeProfile("vmware", profile, &cfg)
+	return cfg, err
+}
+
+// GetProxmoxProfile returns a Proxmox profile's configuration like
+// GetVMwareProfile
+func (c *Config) GetProxmoxProfile(profile string) (ProxmoxConfig, error) {
+	cfg, err := c.proxmoxProfile(profile)
+	if err != nil {
+		return cfg, err
+	}
+	return resolveProxmoxSecrets(cfg)
+}
+
+// proxmoxProfile returns a Proxmox profile's configuration, leaving keyring
+// references as they are
+func (c *Config) proxmoxProfile(profile string) (ProxmoxConfig, error) {
+	if IsDefaultProfile(profile) {
+		return c.proxmoxConfig(), nil
+	}
+	cfg := c.Providers.Proxmox
+	cfg.Server, cfg.Username, cfg.Password, cfg.TokenID, cfg.Secret = "", "", "", "", ""
+	err := decodeProfile("proxmox", profile, &cfg)
+	return cfg, err
+}
+
+// GetNutanixProfile returns a Nutanix profile's configuration like
+// GetVMwareProfile
+func (c *Config) GetNutanixProfile(profile string) (NutanixConfig, error) {
+	cfg, err := c.nutanixProfile(profile)
+	if err != nil {
+		return cfg, err
+	}
+	return resolveNutanixSecrets(cfg)
+}
+
+// nutanixProfile returns a Nutanix profile's configuration, leaving keyring
+// references as they are
+func (c *Config) nutanixProfile(profile string) (NutanixConfig, error) {
+	if IsDefaultProfile(profile) {
+		return c.nutanixConfig(), nil
+	}
+	cfg := c.Providers.Nutanix
+	cfg.Server, cfg.Username, cfg.Password = "", "", ""
+	err := decodeProfile("nutanix", profile, &cfg)
+	return cfg, err
+}
+
+// ProfileContext describes a configured provider profile without reading
+// its secrets
+type ProfileContext struct {
+	Provider    string `json:"provider"`
+	Profile     string `json:"profile"`
+	Server      string `json:"server"`
+	Username    string `json:"username,omitempty"`
+	Credentials string `json:"credentials"` // password, keyring, api token, api token (keyring) or missing
+	Error       string `json:"error,omitempty"`
+}
+
+// Contexts lists the configured profiles of every provider, in provider
+// order and then as ProfileNames orders them
+func (c *Config) Contexts() []ProfileContext {
+	var contexts []ProfileContext
+	for _, provider := range []string{"vmware", "proxmox", "nutanix"} {
+		for _, profile := range c.ProfileNames(provider) {
+			context := ProfileContext{Provider: provider, Profile: profile}
+			var err error
+			switch provider {
+			case "vmware":
+				var cfg VMwareConfig
+				if cfg, err = c.vmwareProfile(profile); err == nil {
+					context.Server, context.Username = cfg.Server, cfg.Username
+					context.Credentials = credentialSource(cfg.Password)
+				}
+			case "proxmox":
+				var cfg ProxmoxConfig
+				if cfg, err = c.proxmoxProfile(profile); err == nil {
+					context.Server, context.Username = cfg.Server, cfg.Username
+					context.Credentials = credentialSource(cfg.Password)
+					if cfg.TokenID != "" && cfg.Secret != "" {
+						context.Username = cfg.TokenID
+						context.Credentials = "api token"
+						if _, ok := secrets.ParseReference(cfg.Secret); ok {
+							context.Credentials = "api token (keyring)"
+						}
+					}
+				}
+			case "nutanix":
+				var cfg NutanixConfig
+				if cfg, err = c.nutanixProfile(profile); err == nil {
+					context.Server, context.Username = cfg.Server, cfg.Username
+					if cfg.Port != 0 {
+						context.Server = fmt.Sprintf("%s:%d", cfg.Server, cfg.Port)
+					}
+					context.Credentials = credentialSource(cfg.Password)
+				}
+			}
+			if err != nil {
+				context.Error = err.Error()
+			}
+			contexts = append(contexts, context)
+		}
+	}
+	return contexts
+}
+
+// credentialSource says where a password comes from without revealing it
+func credentialSource(password string) string {
+	if password == "" {
+		return "missing"
+	}
+	if _, ok := secrets.ParseReference(password); ok {
+		return "keyring"
+	}
+	return "password"
+}
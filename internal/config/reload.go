@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"valhalla/internal/logger"
+)
+
+// reloadDelay is how long the config file must be quiet before it is
+// reloaded; editors and config management often write a file in steps
+const reloadDelay = 250 * time.Millisecond
+
+// Reloader holds the configuration of a long-running command and swaps in
+// a new one when the config file changes. A change is parsed and validated
+// first; an invalid file is rejected and the active configuration kept.
+type Reloader struct {
+	log *logger.Logger
+
+	// cycle is held for reading while a configuration is in use and for
+	// writing while the file is swapped in, as every setting, including
+	// named profiles, is read from viper
+	cycle   sync.RWMutex
+	current *Config
+	raw     []byte // contents of the active config file
+
+	mu         sync.Mutex
+	generation int
+	loadedAt   time.Time
+	lastError  string
+	rejectedAt time.Time
+}
+
+// ReloadStatus describes the active configuration for status reporting
+type ReloadStatus struct {
+	ConfigFile string     `json:"config_file,omitempty"`
+	Generation int        `json:"generation"`           // 1 for the configuration read at startup
+	LoadedAt   time.Time  `json:"loaded_at"`            // when the active configuration was loaded
+	LastError  string     `json:"last_error,omitempty"` // why the latest change was rejected
+	RejectedAt *time.Time `json:"rejected_at,omitempty"`
+}
+
+// NewReloader creates a reloader with cfg, read by InitConfig, as the first
+// configuration generation
+func NewReloader(cfg *Config, log *logger.Logger) *Reloader {
+	r := &Reloader{
+		log:        log.WithComponent("config"),
+		current:    cfg,
+		generation: 1,
+		loadedAt:   time.Now(),
+	}
+	if file := viper.ConfigFileUsed(); file != "" {
+		r.raw, _ = os.ReadFile(file)
+	}
+	return r
+}
+
+// Use runs fn with the active configuration. A reload waits until fn
+// returns, so one discovery cycle never mixes two configurations.
+func (r *Reloader) Use(fn func(cfg *Config) error) error {
+	r.cycle.RLock()
+	defer r.cycle.RUnlock()
+	return fn(r.current)
+}
+
+// Status returns the active configuration generation, when it was loaded
+// and why the latest change was rejected, if it was
+func (r *Reloader) Status() ReloadStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := ReloadStatus{
+		ConfigFile: viper.ConfigFileUsed(),
+		Generation: r.generation,
+		LoadedAt:   r.loadedAt,
+		LastError:  r.lastError,
+	}
+	if !r.rejectedAt.IsZero() {
+		rejectedAt := r.rejectedAt
+		status.RejectedAt = &rejectedAt
+	}
+	return status
+}
+
+// Reload re-reads and validates the config file and makes it the active
+// configuration, logging the keys that changed with secrets redacted. An
+// invalid file is rejected and the active configuration kept.
+func (r *Reloader) Reload() error {
+	data, err := os.ReadFile(viper.ConfigFileUsed())
+	if err != nil {
+		return r.reject(fmt.Errorf("failed to read config file: %w", err))
+	}
+	// A file caught halfway through being rewritten is usually empty
+	if len(bytes.TrimSpace(data)) == 0 {
+		return r.reject(fmt.Errorf("config file is empty"))
+	}
+
+	r.cycle.Lock()
+	if bytes.Equal(data, r.raw) {
+		r.cycle.Unlock()
+		return nil
+	}
+	before := viper.AllSettings()
+	next, err := parseConfig(data)
+	if err != nil {
+		// Put the active file's settings back
+		if restoreErr := viper.ReadConfig(bytes.NewReader(r.raw)); restoreErr != nil {
+			r.log.Error("Failed to restore active configuration", "error", restoreErr)
+		}
+		r.cycle.Unlock()
+		return r.reject(err)
+	}
+	changes := diffSettings(before, viper.AllSettings())
+	r.current = next
+	r.raw = data
+
+	// A cycle starting next sees the new generation
+	r.mu.Lock()
+	r.generation++
+	r.loadedAt = time.Now()
+	r.lastError = ""
+	r.rejectedAt = time.Time{}
+	generation := r.generation
+	r.mu.Unlock()
+	r.cycle.Unlock()
+
+	if len(changes) == 0 {
+		changes = []string{"none"}
+	}
+	r.log.Info("Configuration reloaded", "generation", generation, "changes", strings.Join(changes, "; "))
+	return nil
+}
+
+// reject records why a configuration change was not loaded
+func (r *Reloader) reject(err error) error {
+	r.mu.Lock()
+	r.lastError = err.Error()
+	r.rejectedAt = time.Now()
+	generation := r.generation
+	r.mu.Unlock()
+
+	r.log.Error("Rejected configuration change, keeping active configuration", "generation", generation, "error", err)
+	return err
+}
+
+// parseConfig loads config file contents into viper and returns the
+// validated configuration they describe
+func parseConfig(data []byte) (*Config, error) {
+	if err := viper.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	next := New()
+	if err := viper.Unmarshal(next); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if err := next.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	if err := next.Discover.DefaultFilters.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: discover.default_filters: %w", err)
+	}
+	return next, nil
+}
+
+// Watch reloads the configuration whenever the config file is written or
+// replaced, until ctx is cancelled. The containing directory is watched so
+// editors that save by renaming a new file into place are picked up.
+func (r *Reloader) Watch(ctx context.Context) error {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		return fmt.Errorf("no config file in use to watch")
+	}
+	file = filepath.Clean(file)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	r.log.Info("Watching configuration for changes", "file", file)
+
+	// Rejections are logged and reported by Status
+	reload := time.AfterFunc(time.Hour, func() { r.Reload() })
+	reload.Stop()
+
+	go func() {
+		defer watcher.Close()
+		defer reload.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != file || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+				reload.Reset(reloadDelay)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				r.log.Warn("Config watcher error", "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// diffSettings lists the dotted keys whose values differ between two sets
+// of viper settings, in key order. Secret values are redacted.
+func diffSettings(before, after map[string]interface{}) []string {
+	old := make(map[string]interface{})
+	flattenSettings("", before, old)
+	current := make(map[string]interface{})
+	flattenSettings("", after, current)
+
+	keys := make(map[string]bool, len(current))
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range current {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, key := range sorted {
+		oldValue, hadOld := old[key]
+		newValue, hasNew := current[key]
+		if hadOld == hasNew && reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		switch {
+		case isSecretKey(key):
+			changes = append(changes, key+" changed [redacted]")
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("%s added: %v", key, newValue))
+		case !hasNew:
+			changes = append(changes, key+" removed")
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, oldValue, newValue))
+		}
+	}
+	return changes
+}
+
+// flattenSettings stores nested settings in flat by their dotted keys
+func flattenSettings(prefix string, settings map[string]interface{}, flat map[string]interface{}) {
+	for key, value := range settings {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			flattenSettings(key, nested, flat)
+			continue
+		}
+		flat[key] = value
+	}
+}
+
+// isSecretKey reports whether a setting holds a password or secret, whose
+// value is never logged
+func isSecretKey(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	return strings.Contains(name, "password") || strings.Contains(name, "secret")
+}
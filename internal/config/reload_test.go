@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"valhalla/internal/logger"
+)
+
+// loadConfig writes content to a config file and loads it like the CLI
+// does, returning a reloader over it, the file and the reloader's log
+func loadConfig(t *testing.T, content string) (*Reloader, string, *bytes.Buffer) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	filename := filepath.Join(t.TempDir(), "valhalla.yaml")
+	writeConfig(t, filename, content)
+	cfg := New()
+	if err := cfg.InitConfig(filename); err != nil {
+		t.Fatalf("InitConfig: %v", err)
+	}
+
+	var logs bytes.Buffer
+	return NewReloader(cfg, logger.NewWithOutput(&logs)), filename, &logs
+}
+
+func writeConfig(t *testing.T, filename, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const reloadConfig = `output:
+  directory: %s
+providers:
+  vmware:
+    server: vcenter.example.com
+    username: svc-valhalla
+    password: OldPassw0rd
+`
+
+func TestReloaderReload(t *testing.T) {
+	dir := t.TempDir()
+	reloader, filename, logs := loadConfig(t, strings.Replace(reloadConfig, "%s", dir, 1))
+	first := reloader.Status()
+
+	rotated := strings.Replace(reloadConfig, "%s", dir, 1)
+	rotated = strings.Replace(rotated, "OldPassw0rd", "NewPassw0rd", 1)
+	rotated = strings.Replace(rotated, "vcenter.example.com", "vcenter2.example.com", 1)
+	writeConfig(t, filename, rotated)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	reloader.Use(func(cfg *Config) error {
+		if cfg.Providers.VMware.Password != "NewPassw0rd" || cfg.Providers.VMware.Server != "vcenter2.example.com" {
+			t.Errorf("active VMware config = %+v", cfg.Providers.VMware)
+		}
+		return nil
+	})
+	status := reloader.Status()
+	if status.Generation != 2 || !status.LoadedAt.After(first.LoadedAt) || status.ConfigFile != filename {
+		t.Errorf("status after reload = %+v, first %+v", status, first)
+	}
+
+	log := logs.String()
+	for _, want := range []string{
+		"providers.vmware.password changed [redacted]",
+		"providers.vmware.server: vcenter.example.com -> vcenter2.example.com",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("reload log lacks %q:\n%s", want, log)
+		}
+	}
+	if strings.Contains(log, "Passw0rd") {
+		t.Errorf("reload log reveals a password:\n%s", log)
+	}
+
+	// Rewriting the same contents is not a new generation
+	if err := reloader.Reload(); err != nil || reloader.Status().Generation != 2 {
+		t.Errorf("Reload of unchanged file: %v, generation %d", err, reloader.Status().Generation)
+	}
+}
+
+func TestReloaderRejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	valid := strings.Replace(reloadConfig, "%s", dir, 1)
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "empty", content: "\n", want: "config file is empty"},
+		{name: "unparsable", content: "providers: [vmware\n", want: "failed to parse config file"},
+		{name: "wrong type", content: valid + "discover:\n  cache_ttl: soon\n", want: "failed to unmarshal config"},
+		{name: "reserved profile", content: valid + "    profiles:\n      all:\n        server: other\n", want: "reserved"},
+		{name: "bad filter", content: valid + "discover:\n  default_filters:\n    exclude_names: ['[tmp']\n", want: "invalid filter pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reloader, filename, _ := loadConfig(t, valid)
+			active := reloader.Status()
+
+			writeConfig(t, filename, tt.content)
+			err := reloader.Reload()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Reload error = %v, want %q", err, tt.want)
+			}
+
+			status := reloader.Status()
+			if status.Generation != 1 || !status.LoadedAt.Equal(active.LoadedAt) || status.LastError != err.Error() || status.RejectedAt == nil {
+				t.Errorf("status after rejected change = %+v", status)
+			}
+			// Settings read from viper, like profiles, are still the active file's
+			reloader.Use(func(cfg *Config) error {
+				if cfg.Providers.VMware.Password != "OldPassw0rd" || viper.GetString("providers.vmware.password") != "OldPassw0rd" {
+					t.Errorf("active config changed: %+v, viper %q", cfg.Providers.VMware, viper.GetString("providers.vmware.password"))
+				}
+				if viper.IsSet("providers.vmware.profiles") {
+					t.Error("rejected profiles are still set in viper")
+				}
+				return nil
+			})
+
+			// Fixing the file loads it and clears the error
+			writeConfig(t, filename, strings.Replace(valid, "OldPassw0rd", "NewPassw0rd", 1))
+			if err := reloader.Reload(); err != nil {
+				t.Fatalf("Reload of fixed file: %v", err)
+			}
+			if status := reloader.Status(); status.Generation != 2 || status.LastError != "" || status.RejectedAt != nil {
+				t.Errorf("status after fix = %+v", status)
+			}
+		})
+	}
+}
+
+func TestReloaderWaitsForCycle(t *testing.T) {
+	dir := t.TempDir()
+	reloader, filename, _ := loadConfig(t, strings.Replace(reloadConfig, "%s", dir, 1))
+	writeConfig(t, filename, strings.Replace(strings.Replace(reloadConfig, "%s", dir, 1), "OldPassw0rd", "NewPassw0rd", 1))
+
+	reloaded := make(chan error)
+	reloader.Use(func(cfg *Config) error {
+		go func() { reloaded <- reloader.Reload() }()
+
+		// The cycle keeps its configuration, settings read from viper included
+		time.Sleep(100 * time.Millisecond)
+		if reloader.Status().Generation != 1 || viper.GetString("providers.vmware.password") != "OldPassw0rd" {
+			t.Error("configuration reloaded during a cycle")
+		}
+		return nil
+	})
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if reloader.Status().Generation != 2 {
+		t.Errorf("generation after the cycle = %d, want 2", reloader.Status().Generation)
+	}
+}
+
+func TestReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	valid := strings.Replace(reloadConfig, "%s", dir, 1)
+	reloader, filename, _ := loadConfig(t, valid)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := reloader.Watch(ctx); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	waitFor := func(what string, done func(ReloadStatus) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !done(reloader.Status()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %+v", what, reloader.Status())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// Written in place
+	writeConfig(t, filename, strings.Replace(valid, "OldPassw0rd", "Passw0rd2", 1))
+	waitFor("write", func(s ReloadStatus) bool { return s.Generation == 2 })
+
+	// Rejected
+	writeConfig(t, filename, "providers: [vmware\n")
+	waitFor("rejection", func(s ReloadStatus) bool { return s.LastError != "" })
+
+	// Renamed into place, as editors save
+	replacement := filepath.Join(dir, "valhalla.yaml.tmp")
+	writeConfig(t, replacement, strings.Replace(valid, "OldPassw0rd", "Passw0rd3", 1))
+	if err := os.Rename(replacement, filename); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("rename", func(s ReloadStatus) bool { return s.Generation == 3 })
+
+	reloader.Use(func(cfg *Config) error {
+		if cfg.Providers.VMware.Password != "Passw0rd3" {
+			t.Errorf("active password = %q", cfg.Providers.VMware.Password)
+		}
+		return nil
+	})
+}
+
+func TestDiffSettings(t *testing.T) {
+	before := map[string]interface{}{
+		"debug": false,
+		"providers": map[string]interface{}{
+			"proxmox": map[string]interface{}{"secret": "s1", "token_id": "root@pam!ci"},
+			"vmware": map[string]interface{}{
+				"ldap":     map[string]interface{}{"bind_password": "b1"},
+				"profiles": map[string]interface{}{"dr": map[string]interface{}{"server": "dr-vc"}},
+			},
+		},
+	}
+	after := map[string]interface{}{
+		"debug": false,
+		"providers": map[string]interface{}{
+			"proxmox": map[string]interface{}{"secret": "s2", "token_id": "root@pam!ci2"},
+			"vmware": map[string]interface{}{
+				"ldap":     map[string]interface{}{"bind_password": "b2"},
+				"profiles": map[string]interface{}{"lab": map[string]interface{}{"server": "lab-vc", "password": "p"}},
+			},
+		},
+	}
+
+	want := []string{
+		"providers.proxmox.secret changed [redacted]",
+		"providers.proxmox.token_id: root@pam!ci -> root@pam!ci2",
+		"providers.vmware.ldap.bind_password changed [redacted]",
+		"providers.vmware.profiles.dr.server removed",
+		"providers.vmware.profiles.lab.password changed [redacted]",
+		"providers.vmware.profiles.lab.server added: lab-vc",
+	}
+	if got := diffSettings(before, after); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diffSettings =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,324 @@
+// Package diff compares two discoveries of the same environment, such as a
+// saved discovery file and a fresh discovery, and reports what changed.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// Change kinds
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is one difference between two discoveries
+type Change struct {
+	Kind     string `json:"kind"`     // added, removed or changed
+	Resource string `json:"resource"` // vm, cluster or storage
+	Name     string `json:"name"`
+	Field    string `json:"field,omitempty"` // for changed resources: cpus, memory, disks, networks, host, state, evc_mode, capacity, free_space
+	Before   string `json:"before,omitempty"`
+	After    string `json:"after,omitempty"`
+
+	// Highlight marks high-impact changes, such as a cluster's EVC mode,
+	// which decides where its VMs can live migrate to and from
+	Highlight bool `json:"highlight,omitempty"`
+}
+
+// Report lists the changes of one infrastructure
+type Report struct {
+	Provider string   `json:"provider"`
+	Server   string   `json:"server"`
+	Changes  []Change `json:"changes"`
+}
+
+// Options tune what counts as a change
+type Options struct {
+	// FreeSpaceThreshold is how many percentage points of a datastore's
+	// capacity its free space must move by to be reported; free space
+	// changes all the time, so small moves are noise
+	FreeSpaceThreshold float64
+}
+
+// DefaultOptions are the options used by the plan command
+var DefaultOptions = Options{FreeSpaceThreshold: 5}
+
+// HasChanges reports whether any report has changes
+func HasChanges(reports []*Report) bool {
+	for _, report := range reports {
+		if len(report.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CompareAll pairs infrastructures by provider and server and compares each
+// pair. An infrastructure only in after is compared against an empty one,
+// so all its VMs are added, and likewise one only in before has all its VMs
+// removed.
+func CompareAll(before, after []*models.Infrastructure, opts Options) []*Report {
+	key := func(infra *models.Infrastructure) string {
+		return strings.ToLower(infra.Provider) + "|" + strings.ToLower(infra.Server)
+	}
+
+	previous := make(map[string]*models.Infrastructure, len(before))
+	for _, infra := range before {
+		previous[key(infra)] = infra
+	}
+
+	var reports []*Report
+	seen := make(map[string]bool)
+	for _, infra := range after {
+		k := key(infra)
+		seen[k] = true
+		old := previous[k]
+		if old == nil {
+			old = &models.Infrastructure{Provider: infra.Provider, Server: infra.Server}
+		}
+		reports = append(reports, Compare(old, infra, opts))
+	}
+	for _, infra := range before {
+		if !seen[key(infra)] {
+			reports = append(reports, Compare(infra, &models.Infrastructure{Provider: infra.Provider, Server: infra.Server}, opts))
+		}
+	}
+	return reports
+}
+
+// Compare reports the VM, cluster EVC mode and storage changes from before
+// to after. VMs are matched by instance UUID, BIOS UUID or provider ID,
+// falling back to their name when none of those match. Templates are left
+// out.
+func Compare(before, after *models.Infrastructure, opts Options) *Report {
+	report := &Report{Provider: after.Provider, Server: after.Server, Changes: []Change{}}
+	report.Changes = append(report.Changes, compareVMs(before.VirtualMachines, after.VirtualMachines)...)
+	report.Changes = append(report.Changes, compareClusters(before.Clusters, after.Clusters)...)
+	report.Changes = append(report.Changes, compareStorage(before.Storage, after.Storage, opts)...)
+	return report
+}
+
+// compareVMs matches VMs across the discoveries and compares each match
+func compareVMs(before, after []models.VirtualMachine) []Change {
+	before, after = withoutTemplates(before), withoutTemplates(after)
+
+	byID := make(map[string]int)
+	byName := make(map[string]int)
+	for i, vm := range before {
+		for _, id := range vmIDs(vm) {
+			byID[id] = i
+		}
+		byName[vm.Name] = i
+	}
+
+	// Identifiers are matched first, so a name fallback cannot take a VM
+	// that another one matches by identifier
+	match := make([]int, len(after))
+	matched := make(map[int]bool)
+	for j, vm := range after {
+		match[j] = -1
+		for _, id := range vmIDs(vm) {
+			if i, ok := byID[id]; ok && !matched[i] {
+				match[j] = i
+				matched[i] = true
+				break
+			}
+		}
+	}
+	for j, vm := range after {
+		if i, ok := byName[vm.Name]; ok && match[j] < 0 && !matched[i] {
+			match[j] = i
+			matched[i] = true
+		}
+	}
+
+	var changes []Change
+	for j, vm := range after {
+		if match[j] < 0 {
+			changes = append(changes, Change{Kind: Added, Resource: "vm", Name: vm.Name, After: vmSummary(vm)})
+			continue
+		}
+		changes = append(changes, compareVM(before[match[j]], vm)...)
+	}
+
+	for i, vm := range before {
+		if !matched[i] {
+			changes = append(changes, Change{Kind: Removed, Resource: "vm", Name: vm.Name, Before: vmSummary(vm)})
+		}
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// compareVM reports the changed settings of one VM
+func compareVM(before, after models.VirtualMachine) []Change {
+	var changes []Change
+	field := func(name, old, new string) {
+		if old != new {
+			changes = append(changes, Change{Kind: Changed, Resource: "vm", Name: after.Name, Field: name, Before: old, After: new})
+		}
+	}
+
+	field("name", before.Name, after.Name)
+	field("state", before.State, after.State)
+	field("cpus", strconv.Itoa(before.CPUs), strconv.Itoa(after.CPUs))
+	field("memory", strconv.FormatInt(before.Memory, 10), strconv.FormatInt(after.Memory, 10))
+	field("disks", diskSummary(before.Disks), diskSummary(after.Disks))
+	field("networks", networkSummary(before.NetworkCards), networkSummary(after.NetworkCards))
+	field("host", before.Host, after.Host)
+	return changes
+}
+
+// compareClusters reports EVC mode changes of the clusters in both
+// discoveries, matched by name. They are highlighted: VMs can only live
+// migrate to a cluster whose EVC mode is no newer than their source's.
+func compareClusters(before, after []models.Cluster) []Change {
+	previous := make(map[string]models.Cluster, len(before))
+	for _, cluster := range before {
+		previous[cluster.Name] = cluster
+	}
+
+	var changes []Change
+	for _, cluster := range after {
+		old, ok := previous[cluster.Name]
+		if !ok || old.EVCMode == cluster.EVCMode {
+			continue
+		}
+		changes = append(changes, Change{Kind: Changed, Resource: "cluster", Name: cluster.Name, Field: "evc_mode",
+			Before: evcSummary(old.EVCMode), After: evcSummary(cluster.EVCMode), Highlight: true})
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// evcSummary describes a cluster's EVC mode
+func evcSummary(mode string) string {
+	if mode == "" {
+		return "disabled"
+	}
+	return mode
+}
+
+// compareStorage reports datastores added, removed, resized, or whose free
+// space moved by more than the threshold
+func compareStorage(before, after []models.Storage, opts Options) []Change {
+	previous := make(map[string]models.Storage, len(before))
+	for _, store := range before {
+		previous[storageKey(store)] = store
+	}
+
+	var changes []Change
+	seen := make(map[string]bool)
+	for _, store := range after {
+		key := storageKey(store)
+		seen[key] = true
+		old, ok := previous[key]
+		if !ok {
+			changes = append(changes, Change{Kind: Added, Resource: "storage", Name: store.Name, After: fmt.Sprintf("%d GiB", store.Capacity)})
+			continue
+		}
+		if old.Capacity != store.Capacity {
+			changes = append(changes, Change{Kind: Changed, Resource: "storage", Name: store.Name, Field: "capacity",
+				Before: fmt.Sprintf("%d GiB", old.Capacity), After: fmt.Sprintf("%d GiB", store.Capacity)})
+		}
+		if store.Capacity > 0 {
+			moved := float64(store.FreeSpace-old.FreeSpace) / float64(store.Capacity) * 100
+			if moved < 0 {
+				moved = -moved
+			}
+			if moved > opts.FreeSpaceThreshold {
+				changes = append(changes, Change{Kind: Changed, Resource: "storage", Name: store.Name, Field: "free_space",
+					Before: fmt.Sprintf("%d GiB", old.FreeSpace), After: fmt.Sprintf("%d GiB", store.FreeSpace)})
+			}
+		}
+	}
+	for _, store := range before {
+		if !seen[storageKey(store)] {
+			changes = append(changes, Change{Kind: Removed, Resource: "storage", Name: store.Name, Before: fmt.Sprintf("%d GiB", store.Capacity)})
+		}
+	}
+
+	sortChanges(changes)
+	return changes
+}
+
+// storageKey identifies a datastore across discoveries. Local storage of
+// the same name exists on every Proxmox node, so the node is part of it.
+func storageKey(store models.Storage) string {
+	if node, ok := store.Metadata["node"].(string); ok && store.Local {
+		return store.Name + "@" + node
+	}
+	return store.Name
+}
+
+// vmIDs returns the identifiers a VM is matched by, most specific first
+func vmIDs(vm models.VirtualMachine) []string {
+	var ids []string
+	if vm.Config.InstanceUUID != "" {
+		ids = append(ids, "instance:"+vm.Config.InstanceUUID)
+	}
+	if vm.Config.UUID != "" {
+		ids = append(ids, "uuid:"+vm.Config.UUID)
+	}
+	if vm.ID != "" {
+		ids = append(ids, "id:"+vm.ID)
+	}
+	return ids
+}
+
+// withoutTemplates returns the VMs that are not templates
+func withoutTemplates(vms []models.VirtualMachine) []models.VirtualMachine {
+	var result []models.VirtualMachine
+	for _, vm := range vms {
+		if !vm.Config.Template {
+			result = append(result, vm)
+		}
+	}
+	return result
+}
+
+// vmSummary describes an added or removed VM
+func vmSummary(vm models.VirtualMachine) string {
+	return fmt.Sprintf("%d CPUs, %d MiB, %s", vm.CPUs, vm.Memory, diskSummary(vm.Disks))
+}
+
+// diskSummary describes a VM's disks by size and datastore, in disk order
+func diskSummary(disks []models.Disk) string {
+	if len(disks) == 0 {
+		return "no disks"
+	}
+	parts := make([]string, len(disks))
+	for i, disk := range disks {
+		parts[i] = fmt.Sprintf("%d GiB", disk.Size)
+		if disk.Datastore != "" {
+			parts[i] += " on " + disk.Datastore
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// networkSummary lists the networks a VM's NICs are on, in NIC order
+func networkSummary(nics []models.NetworkCard) string {
+	networks := make([]string, len(nics))
+	for i, nic := range nics {
+		networks[i] = nic.Network
+	}
+	return strings.Join(networks, ", ")
+}
+
+// sortChanges orders changes by resource name, keeping each resource's
+// fields in the order they were compared
+func sortChanges(changes []Change) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+}
@@ -0,0 +1,295 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// vm returns a small powered on VM with one disk and NIC
+func vm(name, id, uuid string) models.VirtualMachine {
+	return models.VirtualMachine{
+		ID: id, Name: name, State: "running", CPUs: 2, Memory: 4096, Host: "esx-1",
+		Config:       models.VMConfig{UUID: uuid},
+		Disks:        []models.Disk{{Size: 40, Datastore: "ds1"}},
+		NetworkCards: []models.NetworkCard{{Network: "VM Network"}},
+	}
+}
+
+// clone returns a VM sharing its BIOS UUID with the other clones
+func clone(name, instanceUUID string, cpus int) models.VirtualMachine {
+	v := vm(name, "", "bios-1")
+	v.Config.InstanceUUID = instanceUUID
+	v.CPUs = cpus
+	return v
+}
+
+// template returns a VM template
+func template(name string) models.VirtualMachine {
+	v := vm(name, "vm-3", "uuid-3")
+	v.Config.Template = true
+	return v
+}
+
+func TestCompareVMs(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []models.VirtualMachine
+		after  []models.VirtualMachine
+		want   []Change
+	}{
+		{
+			name:   "unchanged",
+			before: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+			after:  []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+		},
+		{
+			name:  "added",
+			after: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+			want:  []Change{{Kind: Added, Resource: "vm", Name: "web-01", After: "2 CPUs, 4096 MiB, 40 GiB on ds1"}},
+		},
+		{
+			name:   "removed",
+			before: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+			want:   []Change{{Kind: Removed, Resource: "vm", Name: "web-01", Before: "2 CPUs, 4096 MiB, 40 GiB on ds1"}},
+		},
+		{
+			name:   "renamed, matched by UUID",
+			before: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+			after:  []models.VirtualMachine{vm("web-01-old", "vm-9", "uuid-1")},
+			want:   []Change{{Kind: Changed, Resource: "vm", Name: "web-01-old", Field: "name", Before: "web-01", After: "web-01-old"}},
+		},
+		{
+			name:   "renamed, matched by ID",
+			before: []models.VirtualMachine{vm("app", "100", "")},
+			after:  []models.VirtualMachine{vm("app-01", "100", "")},
+			want:   []Change{{Kind: Changed, Resource: "vm", Name: "app-01", Field: "name", Before: "app", After: "app-01"}},
+		},
+		{
+			// Clones keep the BIOS UUID; the instance UUID tells them apart
+			name:   "instance UUID before BIOS UUID",
+			before: []models.VirtualMachine{clone("a", "inst-a", 2), clone("b", "inst-b", 2)},
+			after:  []models.VirtualMachine{clone("b", "inst-b", 4), clone("a", "inst-a", 2)},
+			want:   []Change{{Kind: Changed, Resource: "vm", Name: "b", Field: "cpus", Before: "2", After: "4"}},
+		},
+		{
+			name:   "recreated, matched by name",
+			before: []models.VirtualMachine{vm("db-01", "vm-2", "uuid-2")},
+			after:  []models.VirtualMachine{vm("db-01", "vm-7", "uuid-7")},
+		},
+		{
+			name:   "name fallback does not take a VM matched by ID",
+			before: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")},
+			after: []models.VirtualMachine{
+				vm("web-01", "vm-5", "uuid-5"),
+				vm("web-02", "vm-1", "uuid-1"),
+			},
+			want: []Change{
+				{Kind: Added, Resource: "vm", Name: "web-01", After: "2 CPUs, 4096 MiB, 40 GiB on ds1"},
+				{Kind: Changed, Resource: "vm", Name: "web-02", Field: "name", Before: "web-01", After: "web-02"},
+			},
+		},
+		{
+			name:   "templates left out",
+			before: []models.VirtualMachine{template("tpl")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareVMs(tt.before, tt.after)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareVMs =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVMFields(t *testing.T) {
+	tests := []struct {
+		field  string
+		change func(*models.VirtualMachine)
+		before string
+		after  string
+	}{
+		{"state", func(v *models.VirtualMachine) { v.State = "stopped" }, "running", "stopped"},
+		{"cpus", func(v *models.VirtualMachine) { v.CPUs = 8 }, "2", "8"},
+		{"memory", func(v *models.VirtualMachine) { v.Memory = 8192 }, "4096", "8192"},
+		{"disks", func(v *models.VirtualMachine) { v.Disks[0].Size = 80 }, "40 GiB on ds1", "80 GiB on ds1"},
+		{"disks", func(v *models.VirtualMachine) { v.Disks[0].Datastore = "ds2" }, "40 GiB on ds1", "40 GiB on ds2"},
+		{"disks", func(v *models.VirtualMachine) { v.Disks = nil }, "40 GiB on ds1", "no disks"},
+		{"networks", func(v *models.VirtualMachine) { v.NetworkCards[0].Network = "DMZ" }, "VM Network", "DMZ"},
+		{"host", func(v *models.VirtualMachine) { v.Host = "esx-2" }, "esx-1", "esx-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.after, func(t *testing.T) {
+			before := vm("web-01", "vm-1", "uuid-1")
+			after := vm("web-01", "vm-1", "uuid-1")
+			tt.change(&after)
+
+			want := []Change{{Kind: Changed, Resource: "vm", Name: "web-01", Field: tt.field, Before: tt.before, After: tt.after}}
+			if got := compareVMs([]models.VirtualMachine{before}, []models.VirtualMachine{after}); !reflect.DeepEqual(got, want) {
+				t.Errorf("compareVMs =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestCompareStorage(t *testing.T) {
+	store := func(name string, capacity, free int64) models.Storage {
+		return models.Storage{Name: name, Capacity: capacity, FreeSpace: free}
+	}
+	local := func(node string, free int64) models.Storage {
+		return models.Storage{Name: "local", Local: true, Capacity: 100, FreeSpace: free, Metadata: map[string]interface{}{"node": node}}
+	}
+
+	tests := []struct {
+		name   string
+		before []models.Storage
+		after  []models.Storage
+		want   []Change
+	}{
+		{
+			name:   "free space within threshold",
+			before: []models.Storage{store("ds1", 1000, 500)},
+			after:  []models.Storage{store("ds1", 1000, 460)},
+		},
+		{
+			name:   "free space drift",
+			before: []models.Storage{store("ds1", 1000, 500)},
+			after:  []models.Storage{store("ds1", 1000, 400)},
+			want:   []Change{{Kind: Changed, Resource: "storage", Name: "ds1", Field: "free_space", Before: "500 GiB", After: "400 GiB"}},
+		},
+		{
+			name:   "resized",
+			before: []models.Storage{store("ds1", 1000, 500)},
+			after:  []models.Storage{store("ds1", 2000, 1500)},
+			want: []Change{
+				{Kind: Changed, Resource: "storage", Name: "ds1", Field: "capacity", Before: "1000 GiB", After: "2000 GiB"},
+				{Kind: Changed, Resource: "storage", Name: "ds1", Field: "free_space", Before: "500 GiB", After: "1500 GiB"},
+			},
+		},
+		{
+			name:   "added and removed",
+			before: []models.Storage{store("old", 100, 50)},
+			after:  []models.Storage{store("new", 200, 200)},
+			want: []Change{
+				{Kind: Added, Resource: "storage", Name: "new", After: "200 GiB"},
+				{Kind: Removed, Resource: "storage", Name: "old", Before: "100 GiB"},
+			},
+		},
+		{
+			name:   "local storage keyed by node",
+			before: []models.Storage{local("pve1", 50), local("pve2", 50)},
+			after:  []models.Storage{local("pve2", 50), local("pve1", 20)},
+			want:   []Change{{Kind: Changed, Resource: "storage", Name: "local", Field: "free_space", Before: "50 GiB", After: "20 GiB"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareStorage(tt.before, tt.after, DefaultOptions)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareStorage =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareClusters(t *testing.T) {
+	cluster := func(name, evc string) models.Cluster {
+		return models.Cluster{ID: "domain-" + name, Name: name, EVCMode: evc}
+	}
+
+	tests := []struct {
+		name   string
+		before []models.Cluster
+		after  []models.Cluster
+		want   []Change
+	}{
+		{
+			name:   "unchanged",
+			before: []models.Cluster{cluster("prod", "intel-skylake"), cluster("dev", "")},
+			after:  []models.Cluster{cluster("dev", ""), cluster("prod", "intel-skylake")},
+		},
+		{
+			name:   "raised",
+			before: []models.Cluster{cluster("prod", "intel-broadwell")},
+			after:  []models.Cluster{cluster("prod", "intel-skylake")},
+			want:   []Change{{Kind: Changed, Resource: "cluster", Name: "prod", Field: "evc_mode", Before: "intel-broadwell", After: "intel-skylake", Highlight: true}},
+		},
+		{
+			name:   "enabled and disabled",
+			before: []models.Cluster{cluster("b", "amd-zen"), cluster("a", "")},
+			after:  []models.Cluster{cluster("a", "intel-merom"), cluster("b", "")},
+			want: []Change{
+				{Kind: Changed, Resource: "cluster", Name: "a", Field: "evc_mode", Before: "disabled", After: "intel-merom", Highlight: true},
+				{Kind: Changed, Resource: "cluster", Name: "b", Field: "evc_mode", Before: "amd-zen", After: "disabled", Highlight: true},
+			},
+		},
+		{
+			name:   "clusters in one discovery only",
+			before: []models.Cluster{cluster("old", "intel-skylake")},
+			after:  []models.Cluster{cluster("new", "intel-icelake")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareClusters(tt.before, tt.after)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareClusters =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+
+	// Compare reports them with the VM and storage changes
+	before := &models.Infrastructure{Provider: "vmware", Server: "vcenter", Clusters: []models.Cluster{cluster("prod", "intel-broadwell")}}
+	after := &models.Infrastructure{Provider: "vmware", Server: "vcenter", Clusters: []models.Cluster{cluster("prod", "intel-skylake")}}
+	if report := Compare(before, after, DefaultOptions); len(report.Changes) != 1 || !report.Changes[0].Highlight {
+		t.Errorf("Compare changes = %+v, want the highlighted EVC mode change", report.Changes)
+	}
+}
+
+func TestCompareAll(t *testing.T) {
+	before := []*models.Infrastructure{
+		{Provider: "vmware", Server: "vcenter", VirtualMachines: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")}},
+		{Provider: "proxmox", Server: "pve-old", VirtualMachines: []models.VirtualMachine{vm("app", "100", "")}},
+	}
+	after := []*models.Infrastructure{
+		{Provider: "VMware", Server: "VCENTER", VirtualMachines: []models.VirtualMachine{vm("web-01", "vm-1", "uuid-1")}},
+		{Provider: "nutanix", Server: "prism", VirtualMachines: []models.VirtualMachine{vm("db", "a1", "")}},
+	}
+
+	reports := CompareAll(before, after, DefaultOptions)
+	if len(reports) != 3 {
+		t.Fatalf("got %d reports, want 3", len(reports))
+	}
+	kinds := make(map[string]string)
+	for _, report := range reports {
+		for _, change := range report.Changes {
+			kinds[report.Server] += change.Kind
+		}
+	}
+	want := map[string]string{"prism": Added, "pve-old": Removed}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("changes by server = %v, want %v (servers match ignoring case)", kinds, want)
+	}
+	if !HasChanges(reports) {
+		t.Error("HasChanges = false")
+	}
+	if HasChanges(CompareAll(before[:1], after[:1], DefaultOptions)) {
+		t.Error("HasChanges = true for identical discoveries")
+	}
+}
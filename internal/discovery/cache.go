@@ -0,0 +1,199 @@
+package discovery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// CacheDirName is the directory under the output directory holding cached
+// discovery results
+const CacheDirName = ".cache"
+
+// CacheKey identifies the endpoint, account and scope a discovery ran against
+type CacheKey struct {
+	Provider   string `json:"provider"`
+	Server     string `json:"server"`
+	Datacenter string `json:"datacenter,omitempty"` // datacenter, node or cluster discovered
+
+	// User is a hash of the account discovery logs in as, since what an
+	// account can see depends on its permissions
+	User string `json:"user,omitempty"`
+
+	// Options lists other settings that change what discovery returns, so
+	// results discovered with them are not reused without them
+	Options []string `json:"options,omitempty"`
+}
+
+// file returns the cache file name for the key
+func (k CacheKey) file() string {
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{k.Provider, k.Server, k.Datacenter, k.User}, k.Options...), "\x00")))
+	return strings.ToLower(k.Provider) + "-" + hex.EncodeToString(sum[:8]) + ".json"
+}
+
+// cacheEntry is the on-disk form of cached results; CachedAt is checked
+// against the TTL on every read
+type cacheEntry struct {
+	CachedAt        time.Time                `json:"cached_at"`
+	Key             CacheKey                 `json:"key"`
+	Infrastructures []*models.Infrastructure `json:"infrastructures"`
+}
+
+// Cache keeps discovery results on disk so runs within TTL of each other
+// skip connecting to the provider
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewCache creates a cache in dir whose entries are reused for ttl
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+// Load returns the cached results for key and their age. Missing, expired
+// and unreadable entries are all misses.
+func (c *Cache) Load(key CacheKey) ([]*models.Infrastructure, time.Duration, bool) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key.file()))
+	if err != nil {
+		return nil, 0, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, 0, false
+	}
+	age := time.Since(entry.CachedAt)
+	if age < 0 || age > c.ttl || len(entry.Infrastructures) == 0 {
+		return nil, age, false
+	}
+	return entry.Infrastructures, age, true
+}
+
+// Store saves results for key, replacing any earlier entry
+func (c *Cache) Store(key CacheKey, infrastructures []*models.Infrastructure) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		CachedAt:        time.Now().UTC(),
+		Key:             key,
+		Infrastructures: infrastructures,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry: %w", err)
+	}
+
+	// Write then rename so a concurrent reader never sees half an entry
+	path := filepath.Join(c.dir, key.file())
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clear removes every cached entry and returns how many there were
+func (c *Cache) Clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+			return removed, fmt.Errorf("failed to remove cache entry: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// VMwareCacheKey returns the cache key for a vCenter discovery
+func VMwareCacheKey(cfg config.VMwareConfig) CacheKey {
+	key := CacheKey{Provider: "vmware", Server: cfg.Server, Datacenter: cfg.Datacenter, User: userHash(cfg.Username)}
+	if cfg.Cluster != "" {
+		key.Options = append(key.Options, "cluster="+cfg.Cluster)
+	}
+	if cfg.WithAlarms {
+		key.Options = append(key.Options, "with_alarms")
+	}
+	if cfg.WithPermissions {
+		key.Options = append(key.Options, "with_permissions")
+	}
+	if cfg.WithContentLibraries {
+		key.Options = append(key.Options, "with_content_libraries")
+	}
+	if cfg.AllNetworks {
+		key.Options = append(key.Options, "all_networks")
+	}
+	if cfg.IncludeSnapshots {
+		key.Options = append(key.Options, "include_snapshots")
+	}
+	if cfg.IncludeTemplates {
+		key.Options = append(key.Options, "include_templates")
+	}
+	// Narrowed discoveries must not be served for full ones
+	if cfg.VMs.PowerState != "" {
+		key.Options = append(key.Options, "power_state="+cfg.VMs.PowerState)
+	}
+	if len(cfg.VMs.Names) > 0 {
+		key.Options = append(key.Options, "names="+strings.Join(cfg.VMs.Names, ","))
+	}
+	if cfg.VMs.Host != "" {
+		key.Options = append(key.Options, "host="+cfg.VMs.Host)
+	}
+	if cfg.VMs.ResourcePool != "" {
+		key.Options = append(key.Options, "resource_pool="+cfg.VMs.ResourcePool)
+	}
+	if cfg.VMs.Folder != "" {
+		key.Options = append(key.Options, "folder="+cfg.VMs.Folder)
+	}
+	return key
+}
+
+// ProxmoxCacheKey returns the cache key for a Proxmox discovery
+func ProxmoxCacheKey(cfg config.ProxmoxConfig) CacheKey {
+	// An API token can have fewer privileges than its user
+	key := CacheKey{Provider: "proxmox", Server: cfg.Server, Datacenter: cfg.Node, User: userHash(cfg.Username, cfg.TokenID)}
+	if cfg.DeepStorageScan {
+		key.Options = append(key.Options, "deep_storage_scan")
+	}
+	if cfg.IncludeTemplates {
+		key.Options = append(key.Options, "include_templates")
+	}
+	return key
+}
+
+// NutanixCacheKey returns the cache key for a Prism discovery
+func NutanixCacheKey(cfg config.NutanixConfig) CacheKey {
+	return CacheKey{Provider: "nutanix", Server: fmt.Sprintf("%s:%d", cfg.Server, cfg.Port), Datacenter: cfg.Cluster, User: userHash(cfg.Username)}
+}
+
+// userHash identifies an account in a cache key without writing its name
+// to the cache
+func userHash(identity ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(identity, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,73 @@
+package discovery
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+func TestCacheKeysSeparateUsers(t *testing.T) {
+	tests := []struct {
+		name        string
+		admin, user CacheKey
+	}{
+		{
+			name:  "vmware",
+			admin: VMwareCacheKey(config.VMwareConfig{Server: "vc", Datacenter: "DC1", Username: "administrator@vsphere.local"}),
+			user:  VMwareCacheKey(config.VMwareConfig{Server: "vc", Datacenter: "DC1", Username: "readonly@vsphere.local"}),
+		},
+		{
+			name:  "proxmox user",
+			admin: ProxmoxCacheKey(config.ProxmoxConfig{Server: "pve", Username: "root@pam"}),
+			user:  ProxmoxCacheKey(config.ProxmoxConfig{Server: "pve", Username: "audit@pve"}),
+		},
+		{
+			name:  "proxmox token",
+			admin: ProxmoxCacheKey(config.ProxmoxConfig{Server: "pve", Username: "root@pam", TokenID: "full"}),
+			user:  ProxmoxCacheKey(config.ProxmoxConfig{Server: "pve", Username: "root@pam", TokenID: "audit"}),
+		},
+		{
+			name:  "nutanix",
+			admin: NutanixCacheKey(config.NutanixConfig{Server: "prism", Port: 9440, Username: "admin"}),
+			user:  NutanixCacheKey(config.NutanixConfig{Server: "prism", Port: 9440, Username: "viewer"}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewCache(t.TempDir(), time.Hour)
+			admin := []*models.Infrastructure{{Provider: tt.admin.Provider, VirtualMachines: []models.VirtualMachine{{ID: "vm-1"}, {ID: "vm-2"}}}}
+			if err := cache.Store(tt.admin, admin); err != nil {
+				t.Fatalf("Store: %v", err)
+			}
+
+			if _, _, ok := cache.Load(tt.user); ok {
+				t.Error("another user's discovery was served from the cache")
+			}
+			if _, _, ok := cache.Load(tt.admin); !ok {
+				t.Error("the same user's discovery was not served from the cache")
+			}
+		})
+	}
+}
+
+func TestCacheKeyHidesUser(t *testing.T) {
+	dir := t.TempDir()
+	key := VMwareCacheKey(config.VMwareConfig{Server: "vc", Username: "svc-valhalla@corp.example"})
+	if err := NewCache(dir, time.Hour).Store(key, []*models.Infrastructure{{Provider: "vmware"}}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, key.file()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "svc-valhalla") {
+		t.Errorf("cache entry names the user:\n%s", data)
+	}
+}
@@ -3,6 +3,7 @@ package discovery
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,6 +19,13 @@ type Engine struct {
 	config   *config.Config
 	providers map[string]providers.Provider
 	mu       sync.RWMutex
+
+	// maxProviders caps how many providers are discovered at once; 0 runs
+	// them all together
+	maxProviders int
+
+	// cache, when set, serves recent results instead of connecting
+	cache *Cache
 }
 
 // NewEngine creates a new discovery engine
@@ -33,111 +41,286 @@ func NewEngine(log *logger.Logger, cfg *config.Config) *Engine {
 func (e *Engine) DiscoverVMware(ctx context.Context, cfg config.VMwareConfig) ([]*models.Infrastructure, error) {
 	e.log.Info("Starting VMware discovery", "server", cfg.Server)
 
-	// Create VMware provider
 	provider := providers.NewVMwareProvider(e.log)
-	
-	// Connect to vCenter
-	if err := provider.ConnectVMware(ctx, cfg); err != nil {
-		return nil, fmt.Errorf("failed to connect to VMware: %w", err)
+	return e.discover(ctx, "VMware", provider, func() error {
+		return provider.ConnectVMware(ctx, cfg)
+	})
+}
+
+// DiscoverProxmox discovers Proxmox infrastructure
+func (e *Engine) DiscoverProxmox(ctx context.Context, cfg config.ProxmoxConfig) ([]*models.Infrastructure, error) {
+	e.log.Info("Starting Proxmox discovery", "server", cfg.Server, "node", cfg.Node)
+
+	provider := providers.NewProxmoxProvider(e.log)
+	return e.discover(ctx, "Proxmox", provider, func() error {
+		return provider.ConnectProxmox(ctx, cfg)
+	})
+}
+
+// DiscoverNutanix discovers Nutanix infrastructure
+func (e *Engine) DiscoverNutanix(ctx context.Context, cfg config.NutanixConfig) ([]*models.Infrastructure, error) {
+	e.log.Info("Starting Nutanix discovery", "server", cfg.Server)
+
+	provider := providers.NewNutanixProvider(e.log)
+	return e.discover(ctx, "Nutanix", provider, func() error {
+		return provider.ConnectNutanix(ctx, cfg)
+	})
+}
+
+// discover connects a provider, runs its discovery and disconnects. Every
+// provider's discovery, whether from the CLI, DiscoverAll or pkg/valhalla,
+// returns through here, so this is where resources are put in a stable order.
+func (e *Engine) discover(ctx context.Context, name string, provider providers.Provider, connect func() error) ([]*models.Infrastructure, error) {
+	if err := connect(); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", name, err)
 	}
 	defer provider.Disconnect()
 
-	// Perform discovery
 	infrastructure, err := provider.Discover(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("VMware discovery failed: %w", err)
+		return nil, fmt.Errorf("%s discovery failed: %w", name, err)
 	}
+	infrastructure.SortResources()
 
 	return []*models.Infrastructure{infrastructure}, nil
 }
 
-// DiscoverProxmox discovers Proxmox infrastructure
-func (e *Engine) DiscoverProxmox(ctx context.Context, cfg config.ProxmoxConfig) ([]*models.Infrastructure, error) {
-	e.log.Info("Starting Proxmox discovery", "server", cfg.Server)
+// ProviderRun holds one provider's discovery outcome along with its results
+type ProviderRun struct {
+	providers.DiscoveryResult
+	Infrastructures []*models.Infrastructure
+	Err             error
+}
 
-	// TODO: Implement Proxmox provider
-	e.log.Warn("Proxmox provider not yet implemented")
-	
-	// Create placeholder infrastructure
-	infrastructure := &models.Infrastructure{
-		Provider:      "proxmox",
-		Server:        cfg.Server,
-		Node:          cfg.Node,
-		DiscoveryTime: time.Now(),
-		Metadata:      make(map[string]interface{}),
+// ProviderDiscoverFunc discovers a single provider within the given context
+type ProviderDiscoverFunc func(ctx context.Context, provider string) ([]*models.Infrastructure, error)
+
+// ProviderTimeout returns the time budget for a provider. A per-provider
+// timeout from discover.timeouts wins; otherwise discover.timeout_fraction
+// of the remaining parent deadline is used. Zero means no extra limit.
+func (e *Engine) ProviderTimeout(ctx context.Context, provider string) time.Duration {
+	if timeout, ok := e.config.Discover.Timeouts[strings.ToLower(provider)]; ok && timeout > 0 {
+		return timeout
 	}
 
-	infrastructure.Metadata["status"] = "not_implemented"
-	infrastructure.Metadata["message"] = "Proxmox discovery will be implemented in next phase"
+	fraction := e.config.Discover.TimeoutFraction
+	if fraction > 0 && fraction < 1 {
+		if deadline, ok := ctx.Deadline(); ok {
+			return time.Duration(float64(time.Until(deadline)) * fraction)
+		}
+	}
 
-	return []*models.Infrastructure{infrastructure}, nil
+	return 0
 }
 
-// DiscoverNutanix discovers Nutanix infrastructure
-func (e *Engine) DiscoverNutanix(ctx context.Context, cfg config.NutanixConfig) ([]*models.Infrastructure, error) {
-	e.log.Info("Starting Nutanix discovery", "server", cfg.Server)
+// SetMaxConcurrentProviders caps how many providers RunProviders discovers
+// at once; 0 or less removes the cap
+func (e *Engine) SetMaxConcurrentProviders(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxProviders = n
+}
 
-	// TODO: Implement Nutanix provider
-	e.log.Warn("Nutanix provider not yet implemented")
-	
-	// Create placeholder infrastructure
-	infrastructure := &models.Infrastructure{
-		Provider:      "nutanix",
-		Server:        cfg.Server,
-		Cluster:       cfg.Cluster,
-		DiscoveryTime: time.Now(),
-		Metadata:      make(map[string]interface{}),
+// SetCache makes the engine reuse results from cache while they are fresh;
+// nil turns caching off
+func (e *Engine) SetCache(cache *Cache) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cache = cache
+}
+
+// Cached returns the results cached for key if still within the cache TTL.
+// Otherwise it runs discover and caches a complete result; results with
+// aborted phases are not cached so the next run retries them.
+func (e *Engine) Cached(key CacheKey, discover func() ([]*models.Infrastructure, error)) ([]*models.Infrastructure, error) {
+	e.mu.RLock()
+	cache := e.cache
+	e.mu.RUnlock()
+	if cache == nil {
+		return discover()
 	}
 
-	infrastructure.Metadata["status"] = "not_implemented"
-	infrastructure.Metadata["message"] = "Nutanix discovery will be implemented in next phase"
+	log := e.log.WithProvider(key.Provider)
+	if infrastructures, age, ok := cache.Load(key); ok {
+		log.Info("Using cached discovery results", "server", key.Server, "age", age.Round(time.Second).String())
+		return infrastructures, nil
+	}
 
-	return []*models.Infrastructure{infrastructure}, nil
+	infrastructures, err := discover()
+	if err != nil {
+		return nil, err
+	}
+	for _, infra := range infrastructures {
+		if _, partial := infra.Metadata["phase_warnings"]; partial {
+			return infrastructures, nil
+		}
+	}
+	if err := cache.Store(key, infrastructures); err != nil {
+		log.Warn("Failed to cache discovery results", "error", err)
+	}
+	return infrastructures, nil
+}
+
+// RunProviders runs discovery for each provider concurrently, up to the
+// configured cap, each under its own deadline so a hung endpoint only
+// exhausts its own budget. A provider's deadline starts once it gets a slot.
+// Results are returned in the order the providers were given.
+func (e *Engine) RunProviders(ctx context.Context, names []string, discover ProviderDiscoverFunc) []ProviderRun {
+	results := make([]ProviderRun, len(names))
+
+	e.mu.RLock()
+	limit := e.maxProviders
+	e.mu.RUnlock()
+	if limit <= 0 || limit > len(names) {
+		limit = len(names)
+	}
+	slots := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = e.runProvider(ctx, name, discover)
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runProvider discovers one provider under its sub-deadline. A provider that
+// ignores cancellation is abandoned once its deadline passes.
+func (e *Engine) runProvider(ctx context.Context, name string, discover ProviderDiscoverFunc) ProviderRun {
+	run := ProviderRun{}
+	run.Provider = name
+
+	providerCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout := e.ProviderTimeout(ctx, name); timeout > 0 {
+		run.Timeout = timeout.String()
+		providerCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	providerCtx = providers.WithWatchdog(providerCtx, providers.Watchdog{
+		Heartbeat: e.config.Discover.HeartbeatInterval,
+		Stall:     e.config.Discover.StallTimeout,
+	})
+
+	type outcome struct {
+		infrastructures []*models.Infrastructure
+		err             error
+	}
+	done := make(chan outcome, 1)
+
+	start := time.Now()
+	go func() {
+		infrastructures, err := discover(providerCtx, name)
+		done <- outcome{infrastructures, err}
+	}()
+
+	select {
+	case out := <-done:
+		run.Infrastructures = out.infrastructures
+		run.Err = out.err
+	case <-providerCtx.Done():
+		run.Err = providerCtx.Err()
+	}
+	duration := time.Since(start).Round(time.Millisecond)
+	run.Duration = duration.String()
+
+	if run.Err != nil && providerCtx.Err() == context.DeadlineExceeded {
+		run.TimedOut = true
+		run.Infrastructures = nil
+		run.Err = fmt.Errorf("%s discovery timed out after %s", name, duration)
+	}
+
+	run.Success = run.Err == nil
+	if run.Err != nil {
+		run.Error = run.Err.Error()
+	}
+	for _, infra := range run.Infrastructures {
+		run.ResourceCount += len(infra.VirtualMachines) + len(infra.Networks) + len(infra.Storage)
+	}
+
+	return run
 }
 
-// DiscoverAll discovers infrastructure from all configured providers
-func (e *Engine) DiscoverAll(ctx context.Context) ([]*models.Infrastructure, error) {
+// DiscoverAll discovers infrastructure from all configured providers,
+// leaving out the VMs that discover.default_filters exclude
+func (e *Engine) DiscoverAll(ctx context.Context) ([]*models.Infrastructure, []ProviderRun, error) {
 	e.log.Info("Starting multi-provider discovery")
 
-	var allResults []*models.Infrastructure
-	var errors []error
+	filters := e.config.Discover.DefaultFilters
+	if err := filters.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if !filters.IsEmpty() {
+		_, applied := filters.Merge(config.FilterConfig{})
+		e.log.Info("Applying default discovery filters", "filters", strings.Join(applied, " "))
+	}
 
-	// Discover VMware if configured
-	vmwareConfig := e.config.GetVMwareConfig()
-	if vmwareConfig.Server != "" {
-		results, err := e.DiscoverVMware(ctx, vmwareConfig)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("VMware discovery failed: %w", err))
-		} else {
-			allResults = append(allResults, results...)
-		}
+	var names []string
+	if e.config.HasValue("providers.vmware.server") {
+		names = append(names, "vmware")
+	}
+	if e.config.HasValue("providers.proxmox.server") {
+		names = append(names, "proxmox")
+	}
+	if e.config.HasValue("providers.nutanix.server") {
+		names = append(names, "nutanix")
 	}
 
-	// Discover Proxmox if configured
-	proxmoxConfig := e.config.GetProxmoxConfig()
-	if proxmoxConfig.Server != "" {
-		results, err := e.DiscoverProxmox(ctx, proxmoxConfig)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("Proxmox discovery failed: %w", err))
-		} else {
-			allResults = append(allResults, results...)
+	results := e.RunProviders(ctx, names, func(ctx context.Context, provider string) ([]*models.Infrastructure, error) {
+		var infrastructures []*models.Infrastructure
+		var err error
+		switch provider {
+		case "vmware":
+			var cfg config.VMwareConfig
+			if cfg, err = e.config.GetVMwareConfig(); err != nil {
+				break
+			}
+			infrastructures, err = e.Cached(VMwareCacheKey(cfg), func() ([]*models.Infrastructure, error) {
+				return e.DiscoverVMware(ctx, cfg)
+			})
+		case "proxmox":
+			var cfg config.ProxmoxConfig
+			if cfg, err = e.config.GetProxmoxConfig(); err != nil {
+				break
+			}
+			infrastructures, err = e.Cached(ProxmoxCacheKey(cfg), func() ([]*models.Infrastructure, error) {
+				return e.DiscoverProxmox(ctx, cfg)
+			})
+		default:
+			var cfg config.NutanixConfig
+			if cfg, err = e.config.GetNutanixConfig(); err != nil {
+				break
+			}
+			infrastructures, err = e.Cached(NutanixCacheKey(cfg), func() ([]*models.Infrastructure, error) {
+				return e.DiscoverNutanix(ctx, cfg)
+			})
 		}
-	}
+		if err == nil {
+			ApplyFilters(infrastructures, filters)
+		}
+		return infrastructures, err
+	})
 
-	// Discover Nutanix if configured
-	nutanixConfig := e.config.GetNutanixConfig()
-	if nutanixConfig.Server != "" {
-		results, err := e.DiscoverNutanix(ctx, nutanixConfig)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("Nutanix discovery failed: %w", err))
-		} else {
-			allResults = append(allResults, results...)
+	var allResults []*models.Infrastructure
+	var errors []error
+	for _, result := range results {
+		if result.Err != nil {
+			errors = append(errors, fmt.Errorf("%s discovery failed: %w", result.Provider, result.Err))
+			continue
 		}
+		allResults = append(allResults, result.Infrastructures...)
 	}
 
 	// Handle errors
 	if len(errors) > 0 && len(allResults) == 0 {
-		return nil, fmt.Errorf("all provider discoveries failed: %v", errors)
+		return nil, results, fmt.Errorf("all provider discoveries failed: %v", errors)
 	}
 
 	if len(errors) > 0 {
@@ -151,7 +334,7 @@ func (e *Engine) DiscoverAll(ctx context.Context) ([]*models.Infrastructure, err
 		"total_infrastructures", len(allResults),
 		"failed_providers", len(errors))
 
-	return allResults, nil
+	return allResults, results, nil
 }
 
 // RegisterProvider registers a custom provider
@@ -182,46 +365,74 @@ func (e *Engine) GetRegisteredProviders() []string {
 	return names
 }
 
-// ValidateProviderConfig validates provider configurations
-func (e *Engine) ValidateProviderConfig(provider string) error {
-	switch provider {
-	case "vmware", "vsphere":
-		cfg := e.config.GetVMwareConfig()
-		if cfg.Server == "" {
-			return fmt.Errorf("VMware server not configured")
-		}
-		if cfg.Username == "" {
-			return fmt.Errorf("VMware username not configured")
-		}
-		if cfg.Password == "" {
-			return fmt.Errorf("VMware password not configured")
-		}
-	case "proxmox":
-		cfg := e.config.GetProxmoxConfig()
-		if cfg.Server == "" {
-			return fmt.Errorf("Proxmox server not configured")
-		}
-		if cfg.Username == "" {
-			return fmt.Errorf("Proxmox username not configured")
-		}
-		if cfg.Password == "" && (cfg.TokenID == "" || cfg.Secret == "") {
-			return fmt.Errorf("Proxmox password or API token not configured")
-		}
-	case "nutanix":
-		cfg := e.config.GetNutanixConfig()
-		if cfg.Server == "" {
-			return fmt.Errorf("Nutanix server not configured")
+// ConfigError lists the required settings a provider is missing
+type ConfigError struct {
+	Provider string
+	Profile  string   // named profile, empty for the default one
+	Missing  []string // setting names, e.g. server, username, password
+}
+
+// providerDisplayNames are the product names used in messages
+var providerDisplayNames = map[string]string{
+	"vmware":  "VMware",
+	"proxmox": "Proxmox",
+	"nutanix": "Nutanix",
+}
+
+func (e *ConfigError) Error() string {
+	missing := make([]string, len(e.Missing))
+	for i, setting := range e.Missing {
+		missing[i] = setting
+		// An API token is an accepted alternative to a Proxmox password
+		if e.Provider == "proxmox" && setting == "password" {
+			missing[i] = "password or API token"
 		}
-		if cfg.Username == "" {
-			return fmt.Errorf("Nutanix username not configured")
+	}
+
+	list := strings.Join(missing, ", ")
+	if n := len(missing); n > 1 {
+		list = strings.Join(missing[:n-1], ", ") + " and " + missing[n-1]
+	}
+	if e.Profile != "" {
+		return fmt.Sprintf("%s %s not configured for profile %s", providerDisplayNames[e.Provider], list, e.Profile)
+	}
+	return fmt.Sprintf("%s %s not configured", providerDisplayNames[e.Provider], list)
+}
+
+// ValidateProviderConfig checks that every setting a provider requires is
+// set, in the config file or the environment, and returns a *ConfigError
+// listing all that are missing
+func (e *Engine) ValidateProviderConfig(provider string) error {
+	return e.ValidateProfileConfig(provider, config.DefaultProfile)
+}
+
+// ValidateProfileConfig checks a provider profile like
+// ValidateProviderConfig
+func (e *Engine) ValidateProfileConfig(provider, profile string) error {
+	provider = strings.ToLower(provider)
+	if provider == "vsphere" {
+		provider = "vmware"
+	}
+
+	info, ok := providers.GetProviderInfo(provider)
+	if !ok {
+		return fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	var missing []string
+	for _, key := range info.RequiredConfig {
+		if !e.config.ProfileHasValue(profile, key) {
+			missing = append(missing, key[strings.LastIndex(key, ".")+1:])
 		}
-		if cfg.Password == "" {
-			return fmt.Errorf("Nutanix password not configured")
+	}
+	if len(missing) > 0 {
+		configErr := &ConfigError{Provider: provider, Missing: missing}
+		if profile != config.DefaultProfile {
+			configErr.Profile = profile
 		}
-	default:
-		return fmt.Errorf("unsupported provider: %s", provider)
+		return configErr
 	}
-	
+
 	return nil
 }
 
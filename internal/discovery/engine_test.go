@@ -0,0 +1,74 @@
+package discovery
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"valhalla/internal/discovery/providers"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+)
+
+// staticProvider returns a fixed infrastructure from Discover
+type staticProvider struct {
+	infra *models.Infrastructure
+}
+
+func (p *staticProvider) Connect(ctx context.Context) error { return nil }
+func (p *staticProvider) Disconnect() error                 { return nil }
+func (p *staticProvider) GetName() string                   { return "static" }
+func (p *staticProvider) IsConnected() bool                 { return true }
+func (p *staticProvider) ConnectionInfo() providers.ConnectionInfo {
+	return providers.ConnectionInfo{}
+}
+func (p *staticProvider) Discover(ctx context.Context) (*models.Infrastructure, error) {
+	return p.infra, nil
+}
+
+func TestDiscoverOrderIsStable(t *testing.T) {
+	vms := []models.VirtualMachine{
+		{ID: "vm-3", Name: "web", Config: models.VMConfig{UUID: "b"}},
+		{ID: "vm-1", Name: "app"},
+		{ID: "vm-2", Name: "web", Config: models.VMConfig{UUID: "a"}},
+		{ID: "vm-4", Name: "db"},
+	}
+	networks := []models.Network{{ID: "net-2", Name: "prod"}, {ID: "net-1", Name: "prod"}, {ID: "net-3", Name: "dev"}}
+	storage := []models.Storage{{ID: "ds-2", Name: "ssd"}, {ID: "ds-1", Name: "hdd"}}
+
+	// Every rotation of the hypervisor's answer must give the same result
+	var first *models.Infrastructure
+	for shift := 0; shift < len(vms); shift++ {
+		infra := &models.Infrastructure{
+			VirtualMachines: append(append([]models.VirtualMachine{}, vms[shift:]...), vms[:shift]...),
+			Networks:        append(append([]models.Network{}, networks[shift%len(networks):]...), networks[:shift%len(networks)]...),
+			Storage:         append(append([]models.Storage{}, storage[shift%len(storage):]...), storage[:shift%len(storage)]...),
+		}
+
+		engine := NewEngine(logger.NewWithOutput(io.Discard), nil)
+		infrastructures, err := engine.discover(context.Background(), "static", &staticProvider{infra}, func() error { return nil })
+		if err != nil {
+			t.Fatalf("discover: %v", err)
+		}
+
+		if first == nil {
+			first = infrastructures[0]
+			continue
+		}
+		if !reflect.DeepEqual(infrastructures[0], first) {
+			t.Errorf("rotation %d gave a different order than rotation 0", shift)
+		}
+	}
+
+	var ids []string
+	for _, vm := range first.VirtualMachines {
+		ids = append(ids, vm.ID)
+	}
+	if want := []string{"vm-1", "vm-4", "vm-2", "vm-3"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("VM order = %v, want %v", ids, want)
+	}
+	if first.Networks[0].ID != "net-3" || first.Networks[1].ID != "net-1" {
+		t.Errorf("network order = %v, want dev, then prod by ID", first.Networks)
+	}
+}
@@ -0,0 +1,82 @@
+package discovery
+
+import (
+	"path"
+	"strings"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// ApplyFilters removes the VMs the filters exclude from each infrastructure
+// and returns how many were removed. Patterns are assumed to be valid.
+func ApplyFilters(infrastructures []*models.Infrastructure, filters config.FilterConfig) int {
+	if filters.IsEmpty() {
+		return 0
+	}
+
+	removed := 0
+	for _, infra := range infrastructures {
+		kept := infra.VirtualMachines[:0]
+		for _, vm := range infra.VirtualMachines {
+			if Excluded(vm, filters) {
+				removed++
+				continue
+			}
+			kept = append(kept, vm)
+		}
+		infra.VirtualMachines = kept
+	}
+	return removed
+}
+
+// Excluded reports whether any of the filters excludes vm
+func Excluded(vm models.VirtualMachine, filters config.FilterConfig) bool {
+	if matchesAny(filters.ExcludeNames, vm.Name) {
+		return true
+	}
+	for _, state := range filters.ExcludePowerStates {
+		if strings.EqualFold(state, vm.PowerState) || strings.EqualFold(state, vm.State) {
+			return true
+		}
+	}
+	for _, tag := range vm.Tags {
+		if matchesAny(filters.ExcludeTags, tag) {
+			return true
+		}
+	}
+
+	// A folder pattern also matches everything beneath the folder
+	for folder := strings.TrimSuffix(vm.Folder, "/"); folder != "" && folder != "/" && folder != "."; folder = path.Dir(folder) {
+		if matchesFolder(filters.ExcludeFolders, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFolder reports whether one of the patterns matches folder or its
+// trailing path elements, so "*/Sandbox" matches /DC0/vm/Lab/Sandbox
+func matchesFolder(patterns []string, folder string) bool {
+	for rest := folder; rest != ""; {
+		if matchesAny(patterns, rest) {
+			return true
+		}
+		i := strings.Index(strings.TrimPrefix(rest, "/"), "/")
+		if i < 0 {
+			break
+		}
+		rest = strings.TrimPrefix(rest, "/")[i+1:]
+	}
+	return false
+}
+
+// matchesAny reports whether value matches one of the patterns, ignoring case
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package discovery
+
+import (
+	"testing"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+func TestExcluded(t *testing.T) {
+	vm := models.VirtualMachine{
+		Name:       "Web-01",
+		PowerState: "poweredOff",
+		Tags:       []string{"env:prod", "team:web"},
+		Folder:     "/DC1/vm/Production/Web",
+	}
+
+	tests := []struct {
+		name    string
+		filters config.FilterConfig
+		want    bool
+	}{
+		{"no filters", config.FilterConfig{}, false},
+		{"name pattern ignores case", config.FilterConfig{ExcludeNames: []string{"web-*"}}, true},
+		{"name pattern", config.FilterConfig{ExcludeNames: []string{"db-*"}}, false},
+		{"power state", config.FilterConfig{ExcludePowerStates: []string{"POWEREDOFF"}}, true},
+		{"tag", config.FilterConfig{ExcludeTags: []string{"team:*"}}, true},
+		{"folder", config.FilterConfig{ExcludeFolders: []string{"/DC1/vm/Production/Web"}}, true},
+		{"folder by trailing path", config.FilterConfig{ExcludeFolders: []string{"Production/Web"}}, true},
+		{"parent folder", config.FilterConfig{ExcludeFolders: []string{"*/Production"}}, true},
+		{"other folder", config.FilterConfig{ExcludeFolders: []string{"*/Sandbox"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Excluded(vm, tt.filters); got != tt.want {
+				t.Errorf("Excluded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFiltersUsesMergedFilters(t *testing.T) {
+	defaults := config.FilterConfig{ExcludeNames: []string{"tmp-*"}, ExcludePowerStates: []string{"poweredOff"}}
+
+	// The flag replaces the name default but keeps the power-state default
+	filters, _ := defaults.Merge(config.FilterConfig{ExcludeNames: []string{"scratch"}})
+
+	infra := &models.Infrastructure{VirtualMachines: []models.VirtualMachine{
+		{Name: "tmp-build", PowerState: "poweredOn"},
+		{Name: "scratch", PowerState: "poweredOn"},
+		{Name: "db-01", PowerState: "poweredOff"},
+		{Name: "web-01", PowerState: "poweredOn"},
+	}}
+
+	if n := ApplyFilters([]*models.Infrastructure{infra}, filters); n != 2 {
+		t.Errorf("ApplyFilters removed %d VMs, want 2", n)
+	}
+	var names []string
+	for _, vm := range infra.VirtualMachines {
+		names = append(names, vm.Name)
+	}
+	if len(names) != 2 || names[0] != "tmp-build" || names[1] != "web-01" {
+		t.Errorf("kept %v, want [tmp-build web-01]", names)
+	}
+}
@@ -0,0 +1,198 @@
+package providers
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"valhalla/internal/models"
+)
+
+// Capability names a discovery feature a provider may support
+type Capability string
+
+const (
+	CapabilityVMs           Capability = "vms"
+	CapabilityNetworks      Capability = "networks"
+	CapabilityStorage       Capability = "storage"
+	CapabilityDatacenters   Capability = "datacenters"
+	CapabilityClusters      Capability = "clusters"
+	CapabilityHosts         Capability = "hosts"
+	CapabilityResourcePools Capability = "resource_pools"
+	CapabilityTemplates     Capability = "templates"
+	CapabilityTags          Capability = "tags"
+	CapabilitySnapshots     Capability = "snapshots"
+	CapabilityPermissions   Capability = "permissions"
+	CapabilityAlarms        Capability = "alarms"
+)
+
+// AllCapabilities returns every known capability in display order
+func AllCapabilities() []Capability {
+	return []Capability{
+		CapabilityVMs,
+		CapabilityNetworks,
+		CapabilityStorage,
+		CapabilityDatacenters,
+		CapabilityClusters,
+		CapabilityHosts,
+		CapabilityResourcePools,
+		CapabilityTemplates,
+		CapabilityTags,
+		CapabilitySnapshots,
+		CapabilityPermissions,
+		CapabilityAlarms,
+	}
+}
+
+// The discovery methods behind each capability. A provider's capabilities
+// are read off the methods its type has, so the table can't drift from the
+// code as features land.
+type (
+	vmDiscoverer interface {
+		DiscoverVMs(ctx context.Context, filters VMDiscoveryFilters) ([]models.VirtualMachine, error)
+	}
+	networkDiscoverer interface {
+		DiscoverNetworks(ctx context.Context) ([]models.Network, error)
+	}
+	storageDiscoverer interface {
+		DiscoverStorage(ctx context.Context) ([]models.Storage, error)
+	}
+	datacenterDiscoverer interface {
+		DiscoverDatacenters(ctx context.Context) ([]models.Datacenter, error)
+	}
+	// vCenter lists a datacenter's clusters, Prism the clusters it manages
+	datacenterClusterDiscoverer interface {
+		DiscoverClusters(ctx context.Context, datacenter string) ([]models.Cluster, error)
+	}
+	clusterDiscoverer interface {
+		DiscoverClusters(ctx context.Context) ([]models.Cluster, error)
+	}
+	hostDiscoverer interface {
+		DiscoverHosts(ctx context.Context, cluster string) ([]models.Host, error)
+	}
+	nodeDiscoverer interface {
+		DiscoverNodes(ctx context.Context) ([]models.Host, error)
+	}
+	resourcePoolDiscoverer interface {
+		DiscoverResourcePools(ctx context.Context) ([]models.ResourcePool, error)
+	}
+	templateDiscoverer interface {
+		DiscoverTemplates(ctx context.Context) ([]models.Template, error)
+	}
+	categoryDiscoverer interface {
+		DiscoverCategories(ctx context.Context) (map[string][]string, error)
+	}
+	permissionDiscoverer interface {
+		DiscoverPermissions(ctx context.Context) ([]models.Permission, error)
+	}
+)
+
+// featureProvider is implemented by providers with discovery that runs
+// inside another phase rather than behind a method of its own, such as
+// snapshots read along with the VMs
+type featureProvider interface {
+	Features() []Capability
+}
+
+// DeriveCapabilities returns the capabilities a provider implements, in
+// AllCapabilities order
+func DeriveCapabilities(provider Provider) []Capability {
+	has := make(map[Capability]bool)
+	implements := func(capability Capability, ok bool) {
+		if ok {
+			has[capability] = true
+		}
+	}
+
+	_, ok := provider.(vmDiscoverer)
+	implements(CapabilityVMs, ok)
+	_, ok = provider.(networkDiscoverer)
+	implements(CapabilityNetworks, ok)
+	_, ok = provider.(storageDiscoverer)
+	implements(CapabilityStorage, ok)
+	_, ok = provider.(datacenterDiscoverer)
+	implements(CapabilityDatacenters, ok)
+	_, ok = provider.(datacenterClusterDiscoverer)
+	implements(CapabilityClusters, ok)
+	_, ok = provider.(clusterDiscoverer)
+	implements(CapabilityClusters, ok)
+	_, ok = provider.(hostDiscoverer)
+	implements(CapabilityHosts, ok)
+	_, ok = provider.(nodeDiscoverer)
+	implements(CapabilityHosts, ok)
+	_, ok = provider.(resourcePoolDiscoverer)
+	implements(CapabilityResourcePools, ok)
+	_, ok = provider.(templateDiscoverer)
+	implements(CapabilityTemplates, ok)
+	_, ok = provider.(categoryDiscoverer)
+	implements(CapabilityTags, ok)
+	_, ok = provider.(permissionDiscoverer)
+	implements(CapabilityPermissions, ok)
+
+	if features, ok := provider.(featureProvider); ok {
+		for _, capability := range features.Features() {
+			has[capability] = true
+		}
+	}
+
+	var capabilities []Capability
+	for _, capability := range AllCapabilities() {
+		if has[capability] {
+			capabilities = append(capabilities, capability)
+		}
+	}
+	return capabilities
+}
+
+// ProviderInfo describes what a provider can discover and what it needs to connect
+type ProviderInfo struct {
+	Name           string       `json:"name"`
+	Description    string       `json:"description"`
+	Capabilities   []Capability `json:"capabilities"`
+	RequiredConfig []string     `json:"required_config"`
+}
+
+// Supports reports whether the provider implements a capability
+func (i ProviderInfo) Supports(capability Capability) bool {
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ProviderInfo)
+)
+
+// RegisterProviderInfo records a provider's capabilities, replacing any earlier registration
+func RegisterProviderInfo(info ProviderInfo) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[info.Name] = info
+}
+
+// GetProviderInfo returns the registered information for a provider
+func GetProviderInfo(name string) (ProviderInfo, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	info, exists := registry[name]
+	return info, exists
+}
+
+// ListProviderInfo returns all registered providers sorted by name
+func ListProviderInfo() []ProviderInfo {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	var infos []ProviderInfo
+	for _, info := range registry {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
@@ -0,0 +1,56 @@
+package providers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeriveCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider Provider
+		want     []Capability
+	}{
+		{
+			name:     "vmware",
+			provider: &vmwareProvider{},
+			want: []Capability{
+				CapabilityVMs, CapabilityNetworks, CapabilityStorage, CapabilityDatacenters,
+				CapabilityClusters, CapabilityHosts, CapabilityResourcePools, CapabilityTemplates,
+				CapabilityTags, CapabilitySnapshots, CapabilityPermissions, CapabilityAlarms,
+			},
+		},
+		{
+			name:     "proxmox",
+			provider: &proxmoxProvider{},
+			want: []Capability{
+				CapabilityVMs, CapabilityNetworks, CapabilityStorage, CapabilityHosts,
+				CapabilityResourcePools, CapabilityTemplates,
+			},
+		},
+		{
+			name:     "nutanix",
+			provider: &nutanixProvider{},
+			want: []Capability{
+				CapabilityVMs, CapabilityNetworks, CapabilityStorage, CapabilityClusters,
+				CapabilityHosts, CapabilityTags,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeriveCapabilities(tt.provider); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DeriveCapabilities() = %v, want %v", got, tt.want)
+			}
+
+			info, ok := GetProviderInfo(tt.name)
+			if !ok {
+				t.Fatalf("%s is not registered", tt.name)
+			}
+			if !reflect.DeepEqual(info.Capabilities, tt.want) {
+				t.Errorf("registered capabilities = %v, want %v", info.Capabilities, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultConcurrency bounds concurrent API requests within a provider when
+// the configuration leaves it unset
+const defaultConcurrency = 10
+
+// concurrencyLimit returns the configured number of concurrent requests, or
+// the default when it is not positive
+func concurrencyLimit(configured int) int {
+	if configured < 1 {
+		return defaultConcurrency
+	}
+	return configured
+}
+
+// forEachLimit calls fn for every index below n with at most limit calls
+// running at once, and waits for them to finish. No further calls are
+// started once ctx is done.
+func forEachLimit(ctx context.Context, n, limit int, fn func(i int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,75 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// describeConnectError turns a failure to reach server into an error that
+// says what went wrong and what to check. Errors it does not recognise are
+// returned unchanged.
+func describeConnectError(server string, err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalidCert      x509.CertificateInvalidError
+		recordHeader     tls.RecordHeaderError
+		dnsErr           *net.DNSError
+		netErr           net.Error
+		opErr            *net.OpError
+	)
+
+	switch {
+	case errors.As(err, &unknownAuthority), errors.As(err, &hostname), errors.As(err, &invalidCert):
+		return fmt.Errorf("TLS certificate of %s could not be verified (trust its CA or set insecure: true): %w", server, err)
+	case errors.As(err, &recordHeader):
+		return fmt.Errorf("%s did not answer with TLS; check the URL scheme and port: %w", server, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf("connection refused by %s; check the server address and port: %w", server, err)
+	case errors.As(err, &dnsErr):
+		return fmt.Errorf("cannot resolve %s; check the server name: %w", server, err)
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("timed out connecting to %s; check the server name and that it is reachable: %w", server, err)
+	case errors.As(err, &opErr):
+		return fmt.Errorf("cannot reach %s: %w", server, err)
+	}
+	return err
+}
+
+// APIError is a non-2xx API response
+type APIError struct {
+	Method     string
+	Path       string
+	Status     string
+	StatusCode int
+}
+
+// Error describes the response, calling out rejected credentials and
+// missing permissions
+func (e *APIError) Error() string {
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return fmt.Sprintf("%s %s: %s: invalid credentials", e.Method, e.Path, e.Status)
+	case http.StatusForbidden:
+		return fmt.Sprintf("%s %s: %s: the user lacks permission for this call", e.Method, e.Path, e.Status)
+	}
+	return fmt.Sprintf("%s %s: %s", e.Method, e.Path, e.Status)
+}
+
+// statusError describes a non-2xx API response
+func statusError(req *http.Request, resp *http.Response) error {
+	return &APIError{Method: req.Method, Path: req.URL.Path, Status: resp.Status, StatusCode: resp.StatusCode}
+}
+
+// isUnauthorized reports whether err is a 401 response, which mid-discovery
+// means the session or ticket expired
+func isUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized
+}
@@ -23,6 +23,10 @@ type Provider interface {
 	
 	// IsConnected returns true if the provider is connected
 	IsConnected() bool
+
+	// ConnectionInfo describes the current connection, including the
+	// version the endpoint reported
+	ConnectionInfo() ConnectionInfo
 }
 
 // VMwareProvider defines the interface for VMware vSphere discovery
@@ -55,6 +59,9 @@ type VMwareProvider interface {
 	
 	// DiscoverTemplates discovers VM templates
 	DiscoverTemplates(ctx context.Context) ([]models.Template, error)
+	
+	// DiscoverPermissions discovers permissions and resolves their principals
+	DiscoverPermissions(ctx context.Context) ([]models.Permission, error)
 }
 
 // ProxmoxProvider defines the interface for Proxmox discovery
@@ -126,6 +133,8 @@ type DiscoveryResult struct {
 	Success       bool                   `json:"success"`
 	Error         string                 `json:"error,omitempty"`
 	Duration      string                 `json:"duration"`
+	Timeout       string                 `json:"timeout,omitempty"`
+	TimedOut      bool                   `json:"timed_out"`
 	ResourceCount int                    `json:"resource_count"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 }
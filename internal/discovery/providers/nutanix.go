@@ -0,0 +1,955 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// nutanixPageSize is the number of entities requested per list call
+const nutanixPageSize = 250
+
+// nutanixProvider implements the NutanixProvider interface against the
+// Prism v3 REST API
+type nutanixProvider struct {
+	log       *logger.Logger
+	config    config.NutanixConfig
+	client    *http.Client
+	session   *sessionJar
+	baseURL   string
+	connected bool
+	version   ProviderVersion
+
+	// containers maps storage container UUIDs to names for disk lookups
+	containers map[string]string
+
+	// subnets maps subnet UUIDs to names for NIC lookups
+	subnets map[string]string
+}
+
+// nutanixReference points at another Prism entity
+type nutanixReference struct {
+	Kind string `json:"kind"`
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// nutanixMetadata is the metadata block common to every v3 entity
+type nutanixMetadata struct {
+	UUID       string            `json:"uuid"`
+	Categories map[string]string `json:"categories"`
+}
+
+// nutanixVM is one entity of the /vms/list response
+type nutanixVM struct {
+	Metadata nutanixMetadata `json:"metadata"`
+	Spec     struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"spec"`
+	Status struct {
+		Name             string           `json:"name"`
+		Description      string           `json:"description"`
+		ClusterReference nutanixReference `json:"cluster_reference"`
+		Resources        struct {
+			PowerState        string           `json:"power_state"`
+			NumSockets        int              `json:"num_sockets"`
+			NumVCPUsPerSocket int              `json:"num_vcpus_per_socket"`
+			MemorySizeMiB     int64            `json:"memory_size_mib"`
+			HostReference     nutanixReference `json:"host_reference"`
+			MachineType       string           `json:"machine_type"`
+			BootConfig        struct {
+				BootType string `json:"boot_type"` // LEGACY, UEFI, SECURE_BOOT
+			} `json:"boot_config"`
+			GuestTools struct {
+				NutanixGuestTools struct {
+					State          string `json:"state"`
+					GuestOSVersion string `json:"guest_os_version"`
+					Version        string `json:"version"`
+				} `json:"nutanix_guest_tools"`
+			} `json:"guest_tools"`
+			DiskList []struct {
+				UUID             string `json:"uuid"`
+				DiskSizeBytes    int64  `json:"disk_size_bytes"`
+				DiskSizeMiB      int64  `json:"disk_size_mib"`
+				DeviceProperties struct {
+					DeviceType  string `json:"device_type"` // DISK, CDROM
+					DiskAddress struct {
+						AdapterType string `json:"adapter_type"` // SCSI, IDE, SATA, PCI
+						DeviceIndex int    `json:"device_index"`
+					} `json:"disk_address"`
+				} `json:"device_properties"`
+				StorageConfig struct {
+					StorageContainerReference nutanixReference `json:"storage_container_reference"`
+				} `json:"storage_config"`
+			} `json:"disk_list"`
+			NICList []struct {
+				UUID            string           `json:"uuid"`
+				MACAddress      string           `json:"mac_address"`
+				Model           string           `json:"model"` // VIRTIO, E1000
+				IsConnected     *bool            `json:"is_connected"`
+				SubnetReference nutanixReference `json:"subnet_reference"`
+				IPEndpointList  []struct {
+					IP string `json:"ip"`
+				} `json:"ip_endpoint_list"`
+			} `json:"nic_list"`
+		} `json:"resources"`
+	} `json:"status"`
+}
+
+// nutanixListMetadata is the pagination block of a list response
+type nutanixListMetadata struct {
+	TotalMatches int `json:"total_matches"`
+	Length       int `json:"length"`
+	Offset       int `json:"offset"`
+}
+
+// Catch a method signature drifting from the interface at compile time
+var _ NutanixProvider = (*nutanixProvider)(nil)
+
+func init() {
+	RegisterProviderInfo(ProviderInfo{
+		Name:         "nutanix",
+		Description:  "Nutanix Prism",
+		Capabilities: DeriveCapabilities(&nutanixProvider{}),
+		RequiredConfig: []string{
+			"providers.nutanix.server",
+			"providers.nutanix.username",
+			"providers.nutanix.password",
+		},
+	})
+}
+
+// NewNutanixProvider creates a new Nutanix provider
+func NewNutanixProvider(log *logger.Logger) NutanixProvider {
+	return &nutanixProvider{
+		log: log,
+	}
+}
+
+// ConnectNutanix verifies the Prism credentials and records the AOS version
+// of the first cluster
+func (p *nutanixProvider) ConnectNutanix(ctx context.Context, cfg config.NutanixConfig) error {
+	p.config = cfg
+
+	baseURL, err := nutanixBaseURL(cfg.Server, cfg.Port)
+	if err != nil {
+		return err
+	}
+	p.baseURL = baseURL
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	p.session = newSessionJar()
+	p.client = &http.Client{Transport: transport, Jar: p.session, Timeout: 60 * time.Second}
+
+	p.log.Info("Authenticating to Nutanix Prism", "server", cfg.Server, "username", cfg.Username)
+
+	// Prism accepts basic auth on every call and answers with a session
+	// cookie, which later calls reuse; listing clusters verifies the
+	// credentials and reports the AOS version
+	clusters, err := p.listClusters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Nutanix Prism: %w", err)
+	}
+	p.version = ProviderVersion{Product: "Nutanix AOS"}
+	for _, cluster := range clusters {
+		if version, _ := cluster.Metadata["version"].(string); version != "" {
+			p.version.Version = version
+			break
+		}
+	}
+
+	p.connected = true
+	p.log.Info("Successfully connected to Nutanix Prism", "server", cfg.Server, "version", p.version.Version)
+
+	return nil
+}
+
+// nutanixBaseURL builds the v3 API base URL, defaulting to https and the
+// configured port (9440)
+func nutanixBaseURL(server string, port int) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf("Nutanix server not configured")
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Nutanix URL: %w", err)
+	}
+	if u.Port() == "" {
+		if port == 0 {
+			port = 9440
+		}
+		u.Host = fmt.Sprintf("%s:%d", u.Host, port)
+	}
+
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/") + "/api/nutanix/v3", nil
+}
+
+// post sends an authenticated POST with a JSON body and decodes the response into out
+func (p *nutanixProvider) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.config.Username, p.config.Password)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return describeConnectError(p.config.Server, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	reportProgress(req.Context())
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(req, resp)
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// list pages through a v3 list endpoint, calling decode with the raw
+// entities of each page. Prism caps the page length, so offsets are
+// advanced until total_matches entities have been read.
+func (p *nutanixProvider) list(ctx context.Context, path, kind string, decode func(json.RawMessage) (int, error)) error {
+	offset := 0
+	for {
+		var page struct {
+			Entities json.RawMessage     `json:"entities"`
+			Metadata nutanixListMetadata `json:"metadata"`
+		}
+		request := map[string]interface{}{
+			"kind":   kind,
+			"length": nutanixPageSize,
+			"offset": offset,
+		}
+		if err := p.listPage(ctx, path, request, &page); err != nil {
+			return err
+		}
+
+		count := 0
+		if len(page.Entities) > 0 && string(page.Entities) != "null" {
+			var err error
+			if count, err = decode(page.Entities); err != nil {
+				return fmt.Errorf("failed to parse %s entities: %w", kind, err)
+			}
+		}
+
+		offset += count
+		if count == 0 || offset >= page.Metadata.TotalMatches {
+			return nil
+		}
+	}
+}
+
+// listPage requests one page of a list endpoint. A 401 once connected
+// means the Prism session expired mid-discovery, so the session cookie is
+// dropped and the page requested again with basic auth, which issues a new
+// session. v3 list calls are POSTs but only read, so replaying them is as
+// safe as replaying a GET; no other request is retried. A page rejected
+// again returns an *AuthError.
+func (p *nutanixProvider) listPage(ctx context.Context, path string, request, out interface{}) error {
+	err := p.post(ctx, path, request, out)
+	if !p.connected || !isUnauthorized(err) {
+		return err
+	}
+
+	p.log.Info("Prism session expired, authenticating again", "server", p.config.Server, "path", path)
+	p.session.reset()
+	err = p.post(ctx, path, request, out)
+	if isUnauthorized(err) {
+		return &AuthError{Provider: "nutanix", Err: fmt.Errorf("rejected after authenticating again: %w", err)}
+	}
+	return err
+}
+
+// sessionJar holds the Prism session cookie and can drop it for a new
+// session to be issued
+type sessionJar struct {
+	mu  sync.Mutex
+	jar *cookiejar.Jar
+}
+
+func newSessionJar() *sessionJar {
+	jar, _ := cookiejar.New(nil) // only fails for a bad public suffix list
+	return &sessionJar{jar: jar}
+}
+
+func (j *sessionJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar.SetCookies(u, cookies)
+}
+
+func (j *sessionJar) Cookies(u *url.URL) []*http.Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.jar.Cookies(u)
+}
+
+// reset drops every cookie
+func (j *sessionJar) reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.jar, _ = cookiejar.New(nil)
+}
+
+// Disconnect drops the Prism client and its session cookie
+func (p *nutanixProvider) Disconnect() error {
+	if p.connected {
+		p.connected = false
+		p.session.reset()
+		p.log.Info("Disconnected from Nutanix Prism")
+	}
+	return nil
+}
+
+// Discover performs complete infrastructure discovery
+func (p *nutanixProvider) Discover(ctx context.Context) (*models.Infrastructure, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("not connected to Nutanix Prism")
+	}
+
+	infrastructure := &models.Infrastructure{
+		Provider:      "nutanix",
+		Server:        p.config.Server,
+		Cluster:       p.config.Cluster,
+		DiscoveryTime: time.Now(),
+		Metadata:      make(map[string]interface{}),
+	}
+	infrastructure.Metadata["provider_version"] = p.version.Metadata()
+
+	// Per-phase durations in milliseconds
+	timings := make(map[string]int64)
+
+	// Discover Clusters
+	clusters, err := p.DiscoverClusters(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "clusters"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover clusters", "error", err)
+	} else {
+		var names []string
+		for _, cluster := range clusters {
+			names = append(names, cluster.Name)
+		}
+		infrastructure.Metadata["clusters"] = names
+		p.log.Info("Discovered clusters", "count", len(clusters))
+	}
+
+	// Hosts give the clusters' capacity
+	hosts, err := p.DiscoverHosts(ctx, p.config.Cluster)
+	if err != nil {
+		if authErr := authFailure(err, "hosts"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover hosts", "error", err)
+	} else {
+		infrastructure.Hosts = hosts
+		p.log.Info("Discovered hosts", "count", len(hosts))
+	}
+
+	// Discover Storage first so disks can name their storage container
+	p.log.Info("Discovering storage")
+	phaseStart := time.Now()
+	storage, err := watchPhase(ctx, p.log, "storage", p.DiscoverStorage)
+	timings["storage_ms"] = time.Since(phaseStart).Milliseconds()
+	if err != nil {
+		if authErr := authFailure(err, "storage"); authErr != nil {
+			return nil, authErr
+		}
+		phaseFailed(p.log, infrastructure, "Failed to discover storage", err)
+	} else {
+		infrastructure.Storage = storage
+		p.log.Info("Discovered storage", "count", len(storage))
+	}
+
+	// Discover Networks before VMs so NICs can name their subnet
+	p.log.Info("Discovering networks")
+	phaseStart = time.Now()
+	networks, err := watchPhase(ctx, p.log, "networks", p.DiscoverNetworks)
+	timings["networks_ms"] = time.Since(phaseStart).Milliseconds()
+	if err != nil {
+		if authErr := authFailure(err, "networks"); authErr != nil {
+			return nil, authErr
+		}
+		phaseFailed(p.log, infrastructure, "Failed to discover networks", err)
+	} else {
+		infrastructure.Networks = networks
+		p.log.Info("Discovered networks", "count", len(networks))
+	}
+
+	// Discover VMs
+	p.log.Info("Discovering virtual machines")
+	phaseStart = time.Now()
+	vms, err := watchPhase(ctx, p.log, "virtual machines", func(ctx context.Context) ([]models.VirtualMachine, error) {
+		return p.DiscoverVMs(ctx, VMDiscoveryFilters{Cluster: p.config.Cluster})
+	})
+	timings["vms_ms"] = time.Since(phaseStart).Milliseconds()
+	if err != nil {
+		if authErr := authFailure(err, "virtual machines"); authErr != nil {
+			return nil, authErr
+		}
+		phaseFailed(p.log, infrastructure, "Failed to discover VMs", err)
+	} else {
+		infrastructure.VirtualMachines = vms
+		p.log.Info("Discovered virtual machines", "count", len(vms))
+	}
+
+	// Discover Categories
+	categories, err := p.DiscoverCategories(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "categories"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover categories", "error", err)
+	} else {
+		infrastructure.Metadata["categories"] = categories
+		p.log.Info("Discovered categories", "count", len(categories))
+	}
+
+	// Add basic metadata
+	totalResources := len(infrastructure.VirtualMachines) + len(infrastructure.Networks) + len(infrastructure.Storage)
+	infrastructure.Metadata["total_resources"] = totalResources
+	infrastructure.Metadata["discovery_duration"] = time.Since(infrastructure.DiscoveryTime).String()
+	timings["total_ms"] = time.Since(infrastructure.DiscoveryTime).Milliseconds()
+	infrastructure.Metadata["timings"] = timings
+
+	return infrastructure, nil
+}
+
+// listClusters lists the Prism Element clusters, skipping the Prism Central
+// instance which also appears in /clusters/list
+func (p *nutanixProvider) listClusters(ctx context.Context) ([]models.Cluster, error) {
+	var clusters []models.Cluster
+
+	err := p.list(ctx, "/clusters/list", "cluster", func(raw json.RawMessage) (int, error) {
+		var entities []struct {
+			Metadata nutanixMetadata `json:"metadata"`
+			Status   struct {
+				Name      string `json:"name"`
+				Resources struct {
+					Nodes struct {
+						HypervisorServerList []struct {
+							IP      string `json:"ip"`
+							Type    string `json:"type"`
+							Version string `json:"version"`
+						} `json:"hypervisor_server_list"`
+					} `json:"nodes"`
+					Config struct {
+						ServiceList []string `json:"service_list"`
+						Build       struct {
+							Version string `json:"version"`
+						} `json:"build"`
+					} `json:"config"`
+				} `json:"resources"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+
+		for _, entity := range entities {
+			resources := entity.Status.Resources
+			if isPrismCentral(resources.Config.ServiceList) {
+				continue
+			}
+
+			cluster := models.Cluster{
+				ID:       entity.Metadata.UUID,
+				Name:     entity.Status.Name,
+				Metadata: map[string]interface{}{"version": resources.Config.Build.Version},
+			}
+			for _, server := range resources.Nodes.HypervisorServerList {
+				cluster.Hosts = append(cluster.Hosts, server.IP)
+			}
+			clusters = append(clusters, cluster)
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	return clusters, nil
+}
+
+// isPrismCentral reports whether a cluster's services mark it as Prism Central
+func isPrismCentral(services []string) bool {
+	for _, service := range services {
+		if service == "PRISM_CENTRAL" {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverClusters discovers the Prism Element clusters in scope
+func (p *nutanixProvider) DiscoverClusters(ctx context.Context) ([]models.Cluster, error) {
+	clusters, err := p.listClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var scoped []models.Cluster
+	for _, cluster := range clusters {
+		if p.inScope(cluster.Name) {
+			scoped = append(scoped, cluster)
+		}
+	}
+	return scoped, nil
+}
+
+// inScope reports whether a cluster is within the configured cluster, if any
+func (p *nutanixProvider) inScope(cluster string) bool {
+	return p.config.Cluster == "" || cluster == "" || strings.EqualFold(cluster, p.config.Cluster)
+}
+
+// DiscoverHosts discovers hosts, optionally limited to one cluster
+func (p *nutanixProvider) DiscoverHosts(ctx context.Context, cluster string) ([]models.Host, error) {
+	var hosts []models.Host
+
+	err := p.list(ctx, "/hosts/list", "host", func(raw json.RawMessage) (int, error) {
+		var entities []struct {
+			Metadata nutanixMetadata `json:"metadata"`
+			Status   struct {
+				Name             string           `json:"name"`
+				ClusterReference nutanixReference `json:"cluster_reference"`
+				Resources        struct {
+					NumCPUCores       int64 `json:"num_cpu_cores"`
+					MemoryCapacityMiB int64 `json:"memory_capacity_mib"`
+					Hypervisor        struct {
+						HypervisorFullName string `json:"hypervisor_full_name"`
+						NumVMs             int    `json:"num_vms"`
+					} `json:"hypervisor"`
+				} `json:"resources"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+
+		for _, entity := range entities {
+			if cluster != "" && !strings.EqualFold(entity.Status.ClusterReference.Name, cluster) {
+				continue
+			}
+			resources := entity.Status.Resources
+			hosts = append(hosts, models.Host{
+				ID:       entity.Metadata.UUID,
+				Name:     entity.Status.Name,
+				Type:     "Nutanix",
+				Version:  resources.Hypervisor.HypervisorFullName,
+				CPUCores: int(resources.NumCPUCores),
+				CPU:      models.HostResource{Total: resources.NumCPUCores},
+				Memory:   models.HostResource{Total: resources.MemoryCapacityMiB},
+				Cluster:  entity.Status.ClusterReference.Name,
+			})
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+
+	return hosts, nil
+}
+
+// DiscoverVMs discovers virtual machines
+func (p *nutanixProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFilters) ([]models.VirtualMachine, error) {
+	var vmList []models.VirtualMachine
+
+	err := p.list(ctx, "/vms/list", "vm", func(raw json.RawMessage) (int, error) {
+		var entities []nutanixVM
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+
+		for _, entity := range entities {
+			cluster := entity.Status.ClusterReference.Name
+			if filters.Cluster != "" && cluster != "" && !strings.EqualFold(cluster, filters.Cluster) {
+				continue
+			}
+			vmList = append(vmList, p.convertVM(entity))
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VMs: %w", err)
+	}
+
+	return vmList, nil
+}
+
+// convertVM maps a /vms/list entity to the VM model
+func (p *nutanixProvider) convertVM(entity nutanixVM) models.VirtualMachine {
+	resources := entity.Status.Resources
+
+	name := entity.Status.Name
+	if name == "" {
+		name = entity.Spec.Name
+	}
+
+	state := "poweredOff"
+	if resources.PowerState == "ON" {
+		state = "poweredOn"
+	}
+
+	sockets := resources.NumSockets
+	if sockets == 0 {
+		sockets = 1
+	}
+	vcpusPerSocket := resources.NumVCPUsPerSocket
+	if vcpusPerSocket == 0 {
+		vcpusPerSocket = 1
+	}
+
+	firmware := "BIOS"
+	if bootType := resources.BootConfig.BootType; bootType == "UEFI" || bootType == "SECURE_BOOT" {
+		firmware = "EFI"
+	}
+
+	ngt := resources.GuestTools.NutanixGuestTools
+	vm := models.VirtualMachine{
+		ID:              entity.Metadata.UUID,
+		Name:            name,
+		State:           state,
+		PowerState:      resources.PowerState,
+		OperatingSystem: ngt.GuestOSVersion,
+		CPUs:            sockets * vcpusPerSocket,
+		Memory:          resources.MemorySizeMiB,
+		Host:            resources.HostReference.Name,
+		Tools: models.VMTools{
+			Status:  ngt.State,
+			Version: ngt.Version,
+		},
+		Hardware: models.HardwareInfo{
+			NumCPU:            sockets * vcpusPerSocket,
+			NumCoresPerSocket: vcpusPerSocket,
+			MemoryMB:          resources.MemorySizeMiB,
+			Firmware:          firmware,
+		},
+		Config: models.VMConfig{
+			UUID: entity.Metadata.UUID,
+		},
+		Metadata: map[string]interface{}{
+			"cluster": entity.Status.ClusterReference.Name,
+		},
+	}
+	if resources.MachineType != "" {
+		vm.Metadata["machine_type"] = resources.MachineType
+	}
+
+	description := entity.Status.Description
+	if description == "" {
+		description = entity.Spec.Description
+	}
+	if description != "" {
+		vm.Annotations = map[string]string{"notes": description}
+	}
+
+	// Categories become Category:Value tags
+	for key, value := range entity.Metadata.Categories {
+		vm.Tags = append(vm.Tags, key+":"+value)
+	}
+	sort.Strings(vm.Tags)
+
+	for _, disk := range resources.DiskList {
+		if disk.DeviceProperties.DeviceType == "CDROM" {
+			continue
+		}
+
+		size := units.FromBytes(disk.DiskSizeBytes)
+		if size == 0 {
+			size = units.FromMiB(disk.DiskSizeMiB)
+		}
+		container := disk.StorageConfig.StorageContainerReference
+		datastore := container.Name
+		if datastore == "" {
+			datastore = p.containers[container.UUID]
+		}
+
+		address := disk.DeviceProperties.DiskAddress
+		vm.Disks = append(vm.Disks, models.Disk{
+			ID:          disk.UUID,
+			Size:        size.CeilGiB(),
+			Type:        "thin", // AOS thin provisions every vDisk
+			Datastore:   datastore,
+			DatastoreID: container.UUID,
+			Controller:  strings.ToLower(address.AdapterType),
+			Unit:        address.DeviceIndex,
+		})
+	}
+
+	for i, nic := range resources.NICList {
+		network := nic.SubnetReference.Name
+		if network == "" {
+			network = p.subnets[nic.SubnetReference.UUID]
+		}
+
+		card := models.NetworkCard{
+			ID:           nic.UUID,
+			Name:         fmt.Sprintf("nic%d", i),
+			Type:         strings.ToLower(nic.Model),
+			Network:      network,
+			MACAddress:   nic.MACAddress,
+			Key:          int32(i),
+			Connected:    nic.IsConnected == nil || *nic.IsConnected,
+			StartConnect: nic.IsConnected == nil || *nic.IsConnected,
+		}
+		if card.Type == "" {
+			card.Type = "virtio"
+		}
+		vm.NetworkCards = append(vm.NetworkCards, card)
+
+		// The first address of the first NIC reporting one is the primary IP
+		for _, endpoint := range nic.IPEndpointList {
+			if endpoint.IP == "" {
+				continue
+			}
+			if vm.IPAddress == "" {
+				vm.IPAddress = endpoint.IP
+			}
+			vm.IPAddresses = append(vm.IPAddresses, endpoint.IP)
+		}
+	}
+
+	return vm
+}
+
+// DiscoverNetworks discovers subnets
+func (p *nutanixProvider) DiscoverNetworks(ctx context.Context) ([]models.Network, error) {
+	var networkList []models.Network
+	p.subnets = make(map[string]string)
+
+	err := p.list(ctx, "/subnets/list", "subnet", func(raw json.RawMessage) (int, error) {
+		var entities []struct {
+			Metadata nutanixMetadata `json:"metadata"`
+			Status   struct {
+				Name             string           `json:"name"`
+				ClusterReference nutanixReference `json:"cluster_reference"`
+				Resources        struct {
+					SubnetType  string `json:"subnet_type"` // VLAN, OVERLAY
+					VLANID      int    `json:"vlan_id"`
+					VSwitchName string `json:"vswitch_name"`
+					IPConfig    *struct {
+						SubnetIP         string `json:"subnet_ip"`
+						PrefixLength     int    `json:"prefix_length"`
+						DefaultGatewayIP string `json:"default_gateway_ip"`
+						DHCPOptions      struct {
+							DomainNameServerList []string `json:"domain_name_server_list"`
+						} `json:"dhcp_options"`
+						PoolList []struct {
+							Range string `json:"range"`
+						} `json:"pool_list"`
+					} `json:"ip_config"`
+				} `json:"resources"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+
+		for _, entity := range entities {
+			p.subnets[entity.Metadata.UUID] = entity.Status.Name
+			if !p.inScope(entity.Status.ClusterReference.Name) {
+				continue
+			}
+
+			resources := entity.Status.Resources
+			network := models.Network{
+				ID:       entity.Metadata.UUID,
+				Name:     entity.Status.Name,
+				Type:     strings.ToLower(resources.SubnetType),
+				VLAN:     resources.VLANID,
+				VSwitch:  resources.VSwitchName,
+				Metadata: map[string]interface{}{"cluster": entity.Status.ClusterReference.Name},
+			}
+			// Subnets with an IP config are IPAM managed and hand out addresses
+			if ipConfig := resources.IPConfig; ipConfig != nil && ipConfig.SubnetIP != "" {
+				network.Subnet = fmt.Sprintf("%s/%d", ipConfig.SubnetIP, ipConfig.PrefixLength)
+				network.Gateway = ipConfig.DefaultGatewayIP
+				network.DNS = ipConfig.DHCPOptions.DomainNameServerList
+				network.DHCP = len(ipConfig.PoolList) > 0
+			}
+			networkList = append(networkList, network)
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subnets: %w", err)
+	}
+
+	return networkList, nil
+}
+
+// DiscoverStorage discovers storage containers
+func (p *nutanixProvider) DiscoverStorage(ctx context.Context) ([]models.Storage, error) {
+	var storageList []models.Storage
+	p.containers = make(map[string]string)
+
+	err := p.list(ctx, "/storage_containers/list", "storage_container", func(raw json.RawMessage) (int, error) {
+		var entities []struct {
+			Metadata nutanixMetadata `json:"metadata"`
+			Status   struct {
+				Name             string           `json:"name"`
+				ClusterReference nutanixReference `json:"cluster_reference"`
+				Resources        struct {
+					MaxCapacityBytes int64 `json:"max_capacity_bytes"`
+					UsageBytes       int64 `json:"usage_bytes"`
+				} `json:"resources"`
+			} `json:"status"`
+		}
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+
+		for _, entity := range entities {
+			p.containers[entity.Metadata.UUID] = entity.Status.Name
+			if !p.inScope(entity.Status.ClusterReference.Name) {
+				continue
+			}
+
+			resources := entity.Status.Resources
+			capacity := units.FromBytes(resources.MaxCapacityBytes)
+			used := units.FromBytes(resources.UsageBytes)
+			storageList = append(storageList, models.Storage{
+				ID:         entity.Metadata.UUID,
+				Name:       entity.Status.Name,
+				Type:       "storage_container",
+				Capacity:   capacity.ToGiB(),
+				FreeSpace:  (capacity - used).ToGiB(),
+				UsedSpace:  used.ToGiB(),
+				Accessible: true,
+				Metadata:   map[string]interface{}{"cluster": entity.Status.ClusterReference.Name},
+			})
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage containers: %w", err)
+	}
+
+	return storageList, nil
+}
+
+// DiscoverCategories discovers category keys and their values
+func (p *nutanixProvider) DiscoverCategories(ctx context.Context) (map[string][]string, error) {
+	var keys []string
+	err := p.list(ctx, "/categories/list", "category", func(raw json.RawMessage) (int, error) {
+		var entities []struct {
+			Name          string `json:"name"`
+			SystemDefined bool   `json:"system_defined"`
+		}
+		if err := json.Unmarshal(raw, &entities); err != nil {
+			return 0, err
+		}
+		for _, entity := range entities {
+			keys = append(keys, entity.Name)
+		}
+		return len(entities), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	// Each key's values are a separate listing; read them concurrently
+	values := make([][]string, len(keys))
+	errs := make([]error, len(keys))
+	forEachLimit(ctx, len(keys), concurrencyLimit(p.config.Concurrency), func(i int) {
+		key := keys[i]
+		values[i] = []string{}
+		err := p.list(ctx, "/categories/"+url.PathEscape(key)+"/list", "category", func(raw json.RawMessage) (int, error) {
+			var entities []struct {
+				Value string `json:"value"`
+			}
+			if err := json.Unmarshal(raw, &entities); err != nil {
+				return 0, err
+			}
+			for _, entity := range entities {
+				values[i] = append(values[i], entity.Value)
+			}
+			return len(entities), nil
+		})
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to list values of category %s: %w", key, err)
+			return
+		}
+		sort.Strings(values[i])
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	categories := make(map[string][]string, len(keys))
+	for i, key := range keys {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		categories[key] = values[i]
+	}
+
+	return categories, nil
+}
+
+// GetName returns the provider name
+func (p *nutanixProvider) GetName() string {
+	return "nutanix"
+}
+
+// ConnectionInfo describes the Prism connection
+func (p *nutanixProvider) ConnectionInfo() ConnectionInfo {
+	return ConnectionInfo{
+		Server:    p.config.Server,
+		Port:      p.config.Port,
+		Username:  p.config.Username,
+		Connected: p.connected,
+		Version:   p.version.Version,
+		Metadata:  p.version.Metadata(),
+	}
+}
+
+// IsConnected returns true if connected to Prism
+func (p *nutanixProvider) IsConnected() bool {
+	return p.connected && p.client != nil
+}
+
+// Connect without configuration (implements Provider interface)
+func (p *nutanixProvider) Connect(ctx context.Context) error {
+	return fmt.Errorf("use ConnectNutanix(ctx, config.NutanixConfig) instead")
+}
@@ -0,0 +1,114 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/simulator"
+	_ "github.com/vmware/govmomi/vapi/simulator"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+)
+
+// testLogger discards log output
+func testLogger() *logger.Logger {
+	return logger.NewWithOutput(io.Discard)
+}
+
+// simulatedVCenter starts a vcsim vCenter, with the vAPI endpoints, for the
+// duration of the test and returns a config that logs in to it. Options
+// adjust the inventory before it is created.
+func simulatedVCenter(t testing.TB, options ...func(*simulator.Model)) (*simulator.Model, config.VMwareConfig) {
+	t.Helper()
+
+	model := simulator.VPX()
+	for _, option := range options {
+		option(model)
+	}
+	if err := model.Create(); err != nil {
+		t.Fatalf("create vcsim model: %v", err)
+	}
+	// Only these credentials are accepted, rather than any non-empty ones
+	model.Service.Listen = &url.URL{User: url.UserPassword("valhalla@vsphere.local", "s3cret")}
+	model.Service.RegisterEndpoints = true
+	server := model.Service.NewServer()
+	t.Cleanup(func() {
+		server.Close()
+		model.Remove()
+	})
+
+	password, _ := server.URL.User.Password()
+	return model, config.VMwareConfig{
+		Server:   server.URL.Scheme + "://" + server.URL.Host + server.URL.Path,
+		Username: server.URL.User.Username(),
+		Password: password,
+		Insecure: true,
+	}
+}
+
+// connectedVMware returns a provider logged in to a vcsim vCenter
+func connectedVMware(t testing.TB, cfg config.VMwareConfig) *vmwareProvider {
+	t.Helper()
+
+	provider := NewVMwareProvider(testLogger()).(*vmwareProvider)
+	if err := provider.ConnectVMware(context.Background(), cfg); err != nil {
+		t.Fatalf("ConnectVMware: %v", err)
+	}
+	t.Cleanup(func() { provider.Disconnect() })
+	return provider
+}
+
+func TestVMwareConnect(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	ctx := context.Background()
+
+	t.Run("connect and disconnect", func(t *testing.T) {
+		provider := NewVMwareProvider(testLogger()).(*vmwareProvider)
+		if err := provider.ConnectVMware(ctx, cfg); err != nil {
+			t.Fatalf("ConnectVMware: %v", err)
+		}
+		if !provider.IsConnected() {
+			t.Error("IsConnected is false after connecting")
+		}
+
+		info := provider.ConnectionInfo()
+		if !info.Connected || info.Version == "" || info.ProviderVersion().APIType != "VirtualCenter" {
+			t.Errorf("ConnectionInfo = %+v", info)
+		}
+
+		if err := provider.Disconnect(); err != nil {
+			t.Fatalf("Disconnect: %v", err)
+		}
+		if provider.IsConnected() {
+			t.Error("IsConnected is true after disconnecting")
+		}
+		if _, err := provider.Discover(ctx); err == nil {
+			t.Error("Discover succeeded after disconnecting")
+		}
+		// A second disconnect is harmless
+		if err := provider.Disconnect(); err != nil {
+			t.Errorf("second Disconnect: %v", err)
+		}
+	})
+
+	t.Run("failed login", func(t *testing.T) {
+		wrong := cfg
+		wrong.Password = "not-the-password"
+
+		provider := NewVMwareProvider(testLogger()).(*vmwareProvider)
+		err := provider.ConnectVMware(ctx, wrong)
+		if err == nil {
+			t.Fatal("ConnectVMware succeeded with the wrong password")
+		}
+		if !strings.Contains(err.Error(), "invalid username or password") {
+			t.Errorf("error = %v, want it to name the invalid credentials", err)
+		}
+		if provider.IsConnected() {
+			t.Error("IsConnected is true after a failed login")
+		}
+	})
+}
@@ -0,0 +1,877 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+	"valhalla/internal/version"
+)
+
+// proxmoxProvider implements the ProxmoxProvider interface against the
+// Proxmox VE REST API
+type proxmoxProvider struct {
+	log       *logger.Logger
+	config    config.ProxmoxConfig
+	client    *http.Client
+	baseURL   string
+	ticket    string
+	csrfToken string
+	authMu    sync.Mutex // guards ticket and csrfToken once connected
+	connected bool
+	version   ProviderVersion
+
+	// resources caches the /cluster/resources listing for the current discovery
+	resources []proxmoxResource
+}
+
+// proxmoxResource is one entry of the /cluster/resources listing
+type proxmoxResource struct {
+	ID         string  `json:"id"`
+	Type       string  `json:"type"` // node, qemu, lxc, storage, pool, sdn
+	Node       string  `json:"node"`
+	Status     string  `json:"status"`
+	Name       string  `json:"name"`
+	VMID       int     `json:"vmid"`
+	Pool       string  `json:"pool"`
+	Template   int     `json:"template"`
+	MaxCPU     float64 `json:"maxcpu"`
+	MaxMem     int64   `json:"maxmem"`
+	MaxDisk    int64   `json:"maxdisk"`
+	Disk       int64   `json:"disk"`
+	Uptime     int64   `json:"uptime"`
+	Storage    string  `json:"storage"`
+	PluginType string  `json:"plugintype"`
+	Content    string  `json:"content"`
+	Shared     int     `json:"shared"`
+	HAState    string  `json:"hastate"`
+}
+
+// Catch a method signature drifting from the interface at compile time
+var _ ProxmoxProvider = (*proxmoxProvider)(nil)
+
+func init() {
+	RegisterProviderInfo(ProviderInfo{
+		Name:         "proxmox",
+		Description:  "Proxmox VE",
+		Capabilities: DeriveCapabilities(&proxmoxProvider{}),
+		RequiredConfig: []string{
+			"providers.proxmox.server",
+			"providers.proxmox.username",
+			"providers.proxmox.password",
+		},
+	})
+}
+
+// NewProxmoxProvider creates a new Proxmox provider
+func NewProxmoxProvider(log *logger.Logger) ProxmoxProvider {
+	return &proxmoxProvider{
+		log: log,
+	}
+}
+
+// ConnectProxmox authenticates to the Proxmox API with an API token when one
+// is configured, otherwise with a username/password ticket
+func (p *proxmoxProvider) ConnectProxmox(ctx context.Context, cfg config.ProxmoxConfig) error {
+	p.config = cfg
+
+	baseURL, err := proxmoxBaseURL(cfg.Server)
+	if err != nil {
+		return err
+	}
+	p.baseURL = baseURL
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	p.client = &http.Client{Transport: transport, Timeout: 60 * time.Second}
+
+	if cfg.TokenID == "" || cfg.Secret == "" {
+		p.log.Info("Authenticating to Proxmox", "server", cfg.Server, "username", cfg.Username)
+		if err := p.login(ctx); err != nil {
+			return fmt.Errorf("failed to login to Proxmox: %w", err)
+		}
+	} else {
+		p.log.Info("Using Proxmox API token", "server", cfg.Server, "token", p.tokenID())
+	}
+
+	// Verify the credentials with a cheap call
+	var version struct {
+		Version string `json:"version"`
+		Release string `json:"release"`
+		RepoID  string `json:"repoid"`
+	}
+	if err := p.get(ctx, "/version", &version); err != nil {
+		return fmt.Errorf("failed to query Proxmox version: %w", err)
+	}
+	p.version = ProviderVersion{
+		Product:    "Proxmox VE",
+		Version:    version.Version,
+		Build:      version.RepoID,
+		APIVersion: version.Release,
+	}
+
+	p.connected = true
+	p.log.Info("Successfully connected to Proxmox", "server", cfg.Server, "version", version.Version)
+
+	return nil
+}
+
+// proxmoxBaseURL builds the API base URL, defaulting to https and port 8006
+func proxmoxBaseURL(server string) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf("Proxmox server not configured")
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Proxmox URL: %w", err)
+	}
+	if u.Port() == "" {
+		u.Host += ":8006"
+	}
+
+	return strings.TrimSuffix(u.Scheme+"://"+u.Host+u.Path, "/") + "/api2/json", nil
+}
+
+// tokenID returns the full user@realm!tokenname identifier for token auth
+func (p *proxmoxProvider) tokenID() string {
+	if strings.Contains(p.config.TokenID, "!") {
+		return p.config.TokenID
+	}
+	return p.config.Username + "!" + p.config.TokenID
+}
+
+// login obtains an authentication ticket and CSRF token. Once connected it
+// is only called with authMu held.
+func (p *proxmoxProvider) login(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("username", p.config.Username)
+	form.Set("password", p.config.Password)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/access/ticket", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var ticket struct {
+		Ticket    string `json:"ticket"`
+		CSRFToken string `json:"CSRFPreventionToken"`
+	}
+	if err := p.do(req, &ticket); err != nil {
+		return err
+	}
+
+	p.ticket = ticket.Ticket
+	p.csrfToken = ticket.CSRFToken
+	return nil
+}
+
+// get performs an authenticated GET and decodes the "data" member into out.
+// A 401 once connected means the ticket expired mid-discovery: with password
+// auth the provider logs in again, once per expired ticket, and replays the
+// GET. API tokens cannot be renewed, and a GET rejected again means the
+// credentials no longer work; both return an *AuthError.
+func (p *proxmoxProvider) get(ctx context.Context, path string, out interface{}) error {
+	ticket := p.currentTicket()
+	err := p.getWith(ctx, path, ticket, out)
+	if !p.connected || !isUnauthorized(err) {
+		return err
+	}
+	if ticket == "" {
+		return &AuthError{Provider: "proxmox", Err: fmt.Errorf("API token rejected: %w", err)}
+	}
+
+	if err := p.renewTicket(ctx, ticket); err != nil {
+		return &AuthError{Provider: "proxmox", Err: fmt.Errorf("failed to log in again: %w", err)}
+	}
+	err = p.getWith(ctx, path, p.currentTicket(), out)
+	if isUnauthorized(err) {
+		return &AuthError{Provider: "proxmox", Err: fmt.Errorf("rejected after logging in again: %w", err)}
+	}
+	return err
+}
+
+// currentTicket returns the authentication ticket, or "" with token auth
+func (p *proxmoxProvider) currentTicket() string {
+	p.authMu.Lock()
+	defer p.authMu.Unlock()
+	return p.ticket
+}
+
+// renewTicket logs in again after expired was rejected, unless a
+// concurrent request has already replaced it
+func (p *proxmoxProvider) renewTicket(ctx context.Context, expired string) error {
+	p.authMu.Lock()
+	defer p.authMu.Unlock()
+	if p.ticket != expired {
+		return nil
+	}
+	p.log.Info("Proxmox ticket expired, logging in again", "server", p.config.Server)
+	return p.login(ctx)
+}
+
+// getWith performs a GET authenticated with ticket, or the API token when
+// ticket is empty
+func (p *proxmoxProvider) getWith(ctx context.Context, path, ticket string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	if ticket != "" {
+		req.AddCookie(&http.Cookie{Name: "PVEAuthCookie", Value: ticket})
+	} else {
+		req.Header.Set("Authorization", "PVEAPIToken="+p.tokenID()+"="+p.config.Secret)
+	}
+
+	return p.do(req, out)
+}
+
+// do sends a request and decodes the "data" member of the response into out
+func (p *proxmoxProvider) do(req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return describeConnectError(p.config.Server, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	reportProgress(req.Context())
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(req, resp)
+	}
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", req.URL.Path, err)
+	}
+	if out == nil || len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return nil
+	}
+
+	return json.Unmarshal(envelope.Data, out)
+}
+
+// Disconnect drops the Proxmox session; tickets expire on their own
+func (p *proxmoxProvider) Disconnect() error {
+	if p.connected {
+		p.ticket = ""
+		p.csrfToken = ""
+		p.connected = false
+		p.log.Info("Disconnected from Proxmox")
+	}
+	return nil
+}
+
+// Discover performs complete infrastructure discovery using a single
+// /cluster/resources listing for the whole cluster
+func (p *proxmoxProvider) Discover(ctx context.Context) (*models.Infrastructure, error) {
+	if !p.connected {
+		return nil, fmt.Errorf("not connected to Proxmox")
+	}
+
+	infrastructure := &models.Infrastructure{
+		Provider:      "proxmox",
+		Server:        p.config.Server,
+		Node:          p.config.Node,
+		DiscoveryTime: time.Now(),
+		Metadata:      make(map[string]interface{}),
+	}
+	infrastructure.Metadata["provider_version"] = p.version.Metadata()
+
+	// Per-phase durations in milliseconds
+	timings := make(map[string]int64)
+
+	p.log.Info("Listing cluster resources")
+	phaseStart := time.Now()
+	if err := p.loadResources(ctx); err != nil {
+		if authErr := authFailure(err, "cluster resources"); authErr != nil {
+			return nil, authErr
+		}
+		return nil, err
+	}
+	timings["resources_ms"] = time.Since(phaseStart).Milliseconds()
+
+	// Discover Nodes
+	nodes, err := p.DiscoverNodes(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "nodes"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover nodes", "error", err)
+	} else {
+		var names []string
+		for _, node := range nodes {
+			names = append(names, node.Name)
+		}
+		infrastructure.Metadata["nodes"] = names
+		infrastructure.Hosts = nodes
+		p.log.Info("Discovered nodes", "count", len(nodes))
+	}
+
+	// Discover VMs
+	p.log.Info("Discovering virtual machines")
+	vms, err := watchPhase(ctx, p.log, "virtual machines", func(ctx context.Context) ([]models.VirtualMachine, error) {
+		vms, err := p.DiscoverVMs(ctx, VMDiscoveryFilters{Node: p.config.Node, IncludeTemplates: p.config.IncludeTemplates})
+		if err == nil {
+			p.attachGuestConfig(ctx, vms)
+		}
+		return vms, err
+	})
+	if err != nil {
+		if authErr := authFailure(err, "virtual machines"); authErr != nil {
+			return nil, authErr
+		}
+		phaseFailed(p.log, infrastructure, "Failed to discover VMs", err)
+	} else {
+		infrastructure.VirtualMachines = vms
+
+		// Templates listed by include_templates are counted with templates
+		vmCount, containers := 0, 0
+		for _, vm := range vms {
+			switch {
+			case vm.Config.Template:
+			case vm.IsContainer():
+				containers++
+			default:
+				vmCount++
+			}
+		}
+		infrastructure.Metadata["vm_count"] = vmCount
+		infrastructure.Metadata["container_count"] = containers
+		p.log.Info("Discovered virtual machines", "vms", vmCount, "containers", containers)
+	}
+
+	// Discover Networks
+	p.log.Info("Discovering networks")
+	phaseStart = time.Now()
+	networks, err := watchPhase(ctx, p.log, "networks", p.DiscoverNetworks)
+	timings["networks_ms"] = time.Since(phaseStart).Milliseconds()
+	if err != nil {
+		if authErr := authFailure(err, "networks"); authErr != nil {
+			return nil, authErr
+		}
+		phaseFailed(p.log, infrastructure, "Failed to discover networks", err)
+	} else {
+		infrastructure.Networks = networks
+		p.log.Info("Discovered networks", "count", len(networks))
+	}
+
+	// Discover Templates
+	templates, err := p.DiscoverTemplates(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "templates"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover templates", "error", err)
+	} else {
+		infrastructure.Templates = templates
+		p.log.Info("Discovered templates", "count", len(templates))
+	}
+
+	// Discover Storage
+	p.log.Info("Discovering storage")
+	storage, err := p.DiscoverStorage(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "storage"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover storage", "error", err)
+	} else {
+		phaseStart = time.Now()
+		volumes, err := watchPhase(ctx, p.log, "storage contents", func(ctx context.Context) (map[string]map[string]bool, error) {
+			return p.attachStorageContents(ctx, storage), nil
+		})
+		timings["storage_contents_ms"] = time.Since(phaseStart).Milliseconds()
+		if err != nil {
+			phaseFailed(p.log, infrastructure, "Failed to list storage contents", err)
+		}
+		infrastructure.Storage = storage
+		p.log.Info("Discovered storage", "count", len(storage))
+
+		// Flag CD-ROMs whose ISO has since been deleted
+		if missing := missingISOFindings(infrastructure.VirtualMachines, storage, volumes); len(missing) > 0 {
+			p.log.Warn("Found VMs referencing ISOs that no longer exist", "count", len(missing))
+			infrastructure.Findings = append(infrastructure.Findings, missing...)
+		}
+	}
+
+	// Discover Pools
+	pools, err := p.DiscoverResourcePools(ctx)
+	if err != nil {
+		if authErr := authFailure(err, "pools"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover pools", "error", err)
+	} else {
+		infrastructure.ResourcePools = pools
+		p.log.Info("Discovered pools", "count", len(pools))
+	}
+
+	// Discover HA groups and attach HA resources to their VMs. Proxmox VE 9
+	// replaced HA groups with HA rules, so there are none to discover there.
+	if version.AtLeast(p.version.Version, "9.0") {
+		p.log.Warn("HA groups are replaced by HA rules in this Proxmox VE version, skipping", "version", p.version.Version)
+	} else {
+		groups, err := p.DiscoverHAGroups(ctx)
+		if err != nil {
+			if authErr := authFailure(err, "HA groups"); authErr != nil {
+				return nil, authErr
+			}
+			p.log.Error("Failed to discover HA groups", "error", err)
+		} else {
+			infrastructure.HAGroups = groups
+			p.log.Info("Discovered HA groups", "count", len(groups))
+		}
+	}
+	if err := p.attachHAResources(ctx, infrastructure.VirtualMachines); err != nil {
+		if authErr := authFailure(err, "HA resources"); authErr != nil {
+			return nil, authErr
+		}
+		p.log.Error("Failed to discover HA resources", "error", err)
+	}
+
+	// Add basic metadata
+	totalResources := len(infrastructure.VirtualMachines) + len(infrastructure.Networks) + len(infrastructure.Storage)
+	infrastructure.Metadata["total_resources"] = totalResources
+	infrastructure.Metadata["discovery_duration"] = time.Since(infrastructure.DiscoveryTime).String()
+	timings["total_ms"] = time.Since(infrastructure.DiscoveryTime).Milliseconds()
+	infrastructure.Metadata["timings"] = timings
+
+	return infrastructure, nil
+}
+
+// loadResources fetches /cluster/resources once; all Discover* methods read
+// from this listing instead of querying each node
+func (p *proxmoxProvider) loadResources(ctx context.Context) error {
+	var resources []proxmoxResource
+	if err := p.get(ctx, "/cluster/resources", &resources); err != nil {
+		return fmt.Errorf("failed to list cluster resources: %w", err)
+	}
+	p.resources = resources
+	return nil
+}
+
+// clusterResources returns the cached resource listing, loading it if needed
+func (p *proxmoxProvider) clusterResources(ctx context.Context) ([]proxmoxResource, error) {
+	if p.resources == nil {
+		if err := p.loadResources(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.resources, nil
+}
+
+// inScope reports whether a resource belongs to the configured node
+func (p *proxmoxProvider) inScope(resource proxmoxResource) bool {
+	return p.config.Node == "" || resource.Node == p.config.Node
+}
+
+// DiscoverNodes discovers Proxmox nodes
+func (p *proxmoxProvider) DiscoverNodes(ctx context.Context) ([]models.Host, error) {
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []models.Host
+	for _, resource := range resources {
+		if resource.Type != "node" || !p.inScope(resource) {
+			continue
+		}
+
+		hosts = append(hosts, models.Host{
+			ID:              resource.ID,
+			Name:            resource.Node,
+			Type:            "Proxmox",
+			State:           resource.Status,
+			ConnectionState: resource.Status,
+			CPUCores:        int(resource.MaxCPU),
+			CPU:             models.HostResource{Total: int64(resource.MaxCPU)},
+			Memory:          models.HostResource{Total: units.FromBytes(resource.MaxMem).ToMiB()},
+		})
+	}
+
+	return hosts, nil
+}
+
+// DiscoverVMs discovers QEMU virtual machines and LXC containers
+func (p *proxmoxProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFilters) ([]models.VirtualMachine, error) {
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var vmList []models.VirtualMachine
+	for _, resource := range resources {
+		if resource.Type != "qemu" && resource.Type != "lxc" {
+			continue
+		}
+		if filters.Node != "" && resource.Node != filters.Node {
+			continue
+		}
+		if resource.Template == 1 && !filters.IncludeTemplates {
+			continue
+		}
+
+		vm := models.VirtualMachine{
+			ID:           resource.ID,
+			Name:         resource.Name,
+			State:        resource.Status,
+			PowerState:   resource.Status,
+			Type:         resource.Type,
+			CPUs:         int(resource.MaxCPU),
+			Memory:       units.FromBytes(resource.MaxMem).ToMiB(),
+			Host:         resource.Node,
+			ResourcePool: resource.Pool,
+			Hardware: models.HardwareInfo{
+				NumCPU:   int(resource.MaxCPU),
+				MemoryMB: units.FromBytes(resource.MaxMem).ToMiB(),
+			},
+			Config: models.VMConfig{
+				Template: resource.Template == 1,
+			},
+			Metadata: map[string]interface{}{
+				"vmid":        resource.VMID,
+				"type":        resource.Type,
+				"max_disk_gb": units.FromBytes(resource.MaxDisk).CeilGiB(),
+			},
+		}
+		if resource.HAState != "" {
+			vm.Metadata["ha_state"] = resource.HAState
+		}
+
+		vmList = append(vmList, vm)
+	}
+
+	return vmList, nil
+}
+
+// DiscoverNetworks discovers networks
+func (p *proxmoxProvider) DiscoverNetworks(ctx context.Context) ([]models.Network, error) {
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bridges are per-node configuration and are not part of /cluster/resources;
+	// nodes in a cluster normally define the same bridges, so each is listed once
+	byName := make(map[string]*models.Network)
+	var names []string
+	for _, resource := range resources {
+		if resource.Type != "node" || !p.inScope(resource) || resource.Status != "online" {
+			continue
+		}
+
+		var interfaces []struct {
+			Iface       string      `json:"iface"`
+			Type        string      `json:"type"` // bridge, OVSBridge, vlan, bond, eth
+			CIDR        string      `json:"cidr"`
+			Gateway     string      `json:"gateway"`
+			Method      string      `json:"method"`
+			BridgePorts string      `json:"bridge_ports"`
+			VLANAware   interface{} `json:"bridge_vlan_aware"`
+			VLANID      interface{} `json:"vlan-id"`
+			VLANDevice  string      `json:"vlan-raw-device"`
+			Comments    string      `json:"comments"`
+		}
+		if err := p.get(ctx, fmt.Sprintf("/nodes/%s/network", resource.Node), &interfaces); err != nil {
+			p.log.Warn("Failed to list node networks", "node", resource.Node, "error", err)
+			continue
+		}
+
+		for _, iface := range interfaces {
+			if iface.Type != "bridge" && iface.Type != "OVSBridge" && iface.Type != "vlan" {
+				continue
+			}
+
+			if network, ok := byName[iface.Iface]; ok {
+				network.Metadata["nodes"] = append(network.Metadata["nodes"].([]string), resource.Node)
+				continue
+			}
+
+			network := &models.Network{
+				ID:       iface.Iface,
+				Name:     iface.Iface,
+				Type:     strings.ToLower(iface.Type),
+				Subnet:   iface.CIDR,
+				Gateway:  iface.Gateway,
+				DHCP:     iface.Method == "dhcp",
+				Metadata: map[string]interface{}{"nodes": []string{resource.Node}},
+			}
+			if iface.Type == "vlan" {
+				network.Bridge = iface.VLANDevice
+				network.VLAN, _ = strconv.Atoi(fmt.Sprint(iface.VLANID))
+				// Interfaces named like vmbr0.10 carry the VLAN in the name
+				if device, tag, ok := strings.Cut(iface.Iface, "."); ok && network.VLAN == 0 {
+					network.Bridge = device
+					network.VLAN, _ = strconv.Atoi(tag)
+				}
+			} else {
+				network.VSwitch = iface.BridgePorts
+				if aware := fmt.Sprint(iface.VLANAware); aware == "1" {
+					network.Metadata["vlan_aware"] = true
+				}
+			}
+			if iface.Comments != "" {
+				network.Metadata["comments"] = strings.TrimSpace(iface.Comments)
+			}
+
+			byName[iface.Iface] = network
+			names = append(names, iface.Iface)
+		}
+	}
+
+	networks := make([]models.Network, 0, len(names))
+	for _, name := range names {
+		networks = append(networks, *byName[name])
+	}
+	return networks, nil
+}
+
+// DiscoverStorage discovers storage; shared storage is listed once even though
+// the cluster reports it for every node
+func (p *proxmoxProvider) DiscoverStorage(ctx context.Context) ([]models.Storage, error) {
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var storageList []models.Storage
+	seen := make(map[string]bool)
+	for _, resource := range resources {
+		if resource.Type != "storage" || !p.inScope(resource) {
+			continue
+		}
+
+		shared := resource.Shared == 1
+		key := resource.Node + "/" + resource.Storage
+		if shared {
+			key = resource.Storage
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		storage := models.Storage{
+			ID:         resource.ID,
+			Name:       resource.Storage,
+			Type:       resource.PluginType,
+			Capacity:   units.FromBytes(resource.MaxDisk).ToGiB(),
+			UsedSpace:  units.FromBytes(resource.Disk).ToGiB(),
+			Accessible: resource.Status == "available",
+			Local:      !shared,
+			Metadata: map[string]interface{}{
+				"content": resource.Content,
+			},
+		}
+		storage.FreeSpace = units.FromBytes(resource.MaxDisk - resource.Disk).ToGiB()
+		if !shared {
+			storage.Metadata["node"] = resource.Node
+		}
+
+		storageList = append(storageList, storage)
+	}
+
+	sort.SliceStable(storageList, func(i, j int) bool {
+		return storageList[i].Name < storageList[j].Name
+	})
+
+	return storageList, nil
+}
+
+// DiscoverTemplates discovers VM and container templates
+func (p *proxmoxProvider) DiscoverTemplates(ctx context.Context) ([]models.Template, error) {
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []models.Template
+	for _, resource := range resources {
+		if (resource.Type != "qemu" && resource.Type != "lxc") || resource.Template != 1 || !p.inScope(resource) {
+			continue
+		}
+
+		templates = append(templates, models.Template{
+			ID:     resource.ID,
+			Name:   resource.Name,
+			CPUs:   int(resource.MaxCPU),
+			Memory: units.FromBytes(resource.MaxMem).ToMiB(),
+			Metadata: map[string]interface{}{
+				"vmid": resource.VMID,
+				"type": resource.Type,
+				"node": resource.Node,
+			},
+		})
+	}
+
+	return templates, nil
+}
+
+// DiscoverResourcePools discovers Proxmox pools; membership comes from the
+// pool field of each VM in the cluster resource listing
+func (p *proxmoxProvider) DiscoverResourcePools(ctx context.Context) ([]models.ResourcePool, error) {
+	var pools []struct {
+		PoolID  string `json:"poolid"`
+		Comment string `json:"comment"`
+	}
+	if err := p.get(ctx, "/pools", &pools); err != nil {
+		return nil, fmt.Errorf("failed to list pools: %w", err)
+	}
+
+	resources, err := p.clusterResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]string)
+	for _, resource := range resources {
+		if (resource.Type == "qemu" || resource.Type == "lxc") && resource.Pool != "" && p.inScope(resource) {
+			members[resource.Pool] = append(members[resource.Pool], resource.Name)
+		}
+	}
+
+	var poolList []models.ResourcePool
+	for _, pool := range pools {
+		resourcePool := models.ResourcePool{
+			ID:       "pool/" + pool.PoolID,
+			Name:     pool.PoolID,
+			VMs:      members[pool.PoolID],
+			Metadata: make(map[string]interface{}),
+		}
+		if pool.Comment != "" {
+			resourcePool.Metadata["comment"] = pool.Comment
+		}
+		poolList = append(poolList, resourcePool)
+	}
+
+	return poolList, nil
+}
+
+// DiscoverHAGroups discovers HA groups from /cluster/ha/groups
+func (p *proxmoxProvider) DiscoverHAGroups(ctx context.Context) ([]models.HAGroup, error) {
+	var groups []struct {
+		Group      string `json:"group"`
+		Nodes      string `json:"nodes"`
+		Restricted int    `json:"restricted"`
+		NoFailback int    `json:"nofailback"`
+		Comment    string `json:"comment"`
+	}
+	if err := p.get(ctx, "/cluster/ha/groups", &groups); err != nil {
+		return nil, fmt.Errorf("failed to list HA groups: %w", err)
+	}
+
+	var groupList []models.HAGroup
+	for _, group := range groups {
+		groupList = append(groupList, models.HAGroup{
+			Name:       group.Group,
+			Nodes:      strings.Split(group.Nodes, ","),
+			Restricted: group.Restricted == 1,
+			NoFailback: group.NoFailback == 1,
+			Comment:    group.Comment,
+		})
+	}
+
+	return groupList, nil
+}
+
+// attachHAResources sets the HA configuration of VMs managed by the HA stack
+func (p *proxmoxProvider) attachHAResources(ctx context.Context, vms []models.VirtualMachine) error {
+	var resources []struct {
+		SID         string `json:"sid"` // vm:100 or ct:101
+		Group       string `json:"group"`
+		State       string `json:"state"`
+		MaxRestart  int    `json:"max_restart"`
+		MaxRelocate int    `json:"max_relocate"`
+	}
+	if err := p.get(ctx, "/cluster/ha/resources", &resources); err != nil {
+		return fmt.Errorf("failed to list HA resources: %w", err)
+	}
+
+	byVMID := make(map[string]*models.HAResource)
+	for _, resource := range resources {
+		parts := strings.SplitN(resource.SID, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		byVMID[parts[1]] = &models.HAResource{
+			Group:       resource.Group,
+			State:       resource.State,
+			MaxRestart:  resource.MaxRestart,
+			MaxRelocate: resource.MaxRelocate,
+		}
+	}
+
+	for i := range vms {
+		ha, ok := byVMID[fmt.Sprint(vms[i].Metadata["vmid"])]
+		if !ok {
+			continue
+		}
+		haCopy := *ha
+		if status, ok := vms[i].Metadata["ha_state"].(string); ok {
+			haCopy.Status = status
+		}
+		vms[i].HA = &haCopy
+	}
+
+	return nil
+}
+
+// GetName returns the provider name
+func (p *proxmoxProvider) GetName() string {
+	return "proxmox"
+}
+
+// ConnectionInfo describes the Proxmox connection
+func (p *proxmoxProvider) ConnectionInfo() ConnectionInfo {
+	username := p.config.Username
+	if p.config.TokenID != "" && p.config.Secret != "" {
+		username = p.tokenID()
+	}
+	return ConnectionInfo{
+		Server:    p.config.Server,
+		Username:  username,
+		Connected: p.connected,
+		Version:   p.version.Version,
+		Metadata:  p.version.Metadata(),
+	}
+}
+
+// IsConnected returns true if connected to Proxmox
+func (p *proxmoxProvider) IsConnected() bool {
+	return p.connected && p.client != nil
+}
+
+// Connect without configuration (implements Provider interface)
+func (p *proxmoxProvider) Connect(ctx context.Context) error {
+	return fmt.Errorf("use ConnectProxmox(ctx, config.ProxmoxConfig) instead")
+}
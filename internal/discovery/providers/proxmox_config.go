@@ -0,0 +1,325 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// proxmoxDiskKey matches QEMU disk keys such as scsi0 or virtio1
+var proxmoxDiskKey = regexp.MustCompile(`^(scsi|virtio|sata|ide)(\d+)$`)
+
+// proxmoxLXCDiskKey matches container volume keys: the root filesystem and
+// mount points such as mp0
+var proxmoxLXCDiskKey = regexp.MustCompile(`^(rootfs|mp(\d+))$`)
+
+// proxmoxNetKey matches network device keys such as net0
+var proxmoxNetKey = regexp.MustCompile(`^net(\d+)$`)
+
+// proxmoxLinkedVolume matches the volume of a linked clone's disk, such as
+// base-9000-disk-0/vm-101-disk-0, capturing the template's VMID
+var proxmoxLinkedVolume = regexp.MustCompile(`^base-(\d+)-[^/]+/`)
+
+// proxmoxNICModels are the QEMU network device models
+var proxmoxNICModels = map[string]bool{
+	"virtio": true, "e1000": true, "e1000e": true, "rtl8139": true, "vmxnet3": true,
+}
+
+// attachGuestConfig reads each QEMU VM's and container's configuration to
+// fill in the CPU topology, firmware, disks and network cards that
+// /cluster/resources doesn't list. A guest whose configuration can't be read
+// keeps its summary. Configurations are read concurrently.
+func (p *proxmoxProvider) attachGuestConfig(ctx context.Context, vms []models.VirtualMachine) {
+	forEachLimit(ctx, len(vms), concurrencyLimit(p.config.Concurrency), func(i int) {
+		vm := &vms[i]
+		kind := vm.Type
+		if kind != models.VMTypeQEMU && kind != models.VMTypeLXC {
+			return
+		}
+
+		var cfg proxmoxConfig
+		path := fmt.Sprintf("/nodes/%s/%s/%v/config", vm.Host, kind, vm.Metadata["vmid"])
+		if err := p.get(ctx, path, &cfg); err != nil {
+			p.log.Warn("Failed to read guest configuration", "vm", vm.Name, "type", kind, "error", err)
+			return
+		}
+
+		if kind == models.VMTypeQEMU {
+			applyQemuConfig(vm, cfg)
+		} else {
+			applyLXCConfig(vm, cfg)
+		}
+	})
+}
+
+// proxmoxConfig is a guest configuration as returned by the API; values are
+// strings or numbers depending on the key
+type proxmoxConfig map[string]interface{}
+
+// value returns a configuration value as a string
+func (c proxmoxConfig) value(key string) string {
+	if v, ok := c[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// number returns a configuration value as an int, or fallback if unset
+func (c proxmoxConfig) number(key string, fallback int) int {
+	if n, err := strconv.Atoi(c.value(key)); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// applyQemuConfig fills a VM from its /nodes/{node}/qemu/{vmid}/config entry
+func applyQemuConfig(vm *models.VirtualMachine, cfg proxmoxConfig) {
+	cores := cfg.number("cores", 1)
+	sockets := cfg.number("sockets", 1)
+	vm.CPUs = cores * sockets
+	vm.Hardware.NumCPU = vm.CPUs
+	vm.Hardware.NumCoresPerSocket = cores
+
+	vm.Hardware.Firmware = "BIOS"
+	if cfg.value("bios") == "ovmf" {
+		vm.Hardware.Firmware = "EFI"
+	}
+	if uuid := proxmoxOptions(cfg.value("smbios1"))["uuid"]; uuid != "" {
+		vm.Config.UUID = uuid
+	}
+
+	applyCommonConfig(vm, cfg)
+
+	vm.Disks = nil
+	vm.NetworkCards = nil
+	vm.ISOs = nil
+	for key := range cfg {
+		if iso := proxmoxISO(cfg.value(key)); iso != "" && (key == "cdrom" || proxmoxDiskKey.MatchString(key)) {
+			vm.ISOs = append(vm.ISOs, iso)
+		} else if match := proxmoxDiskKey.FindStringSubmatch(key); match != nil {
+			if disk, ok := parseProxmoxDisk(key, match[1], match[2], cfg.value(key)); ok {
+				vm.Disks = append(vm.Disks, disk)
+			}
+		} else if match := proxmoxNetKey.FindStringSubmatch(key); match != nil {
+			vm.NetworkCards = append(vm.NetworkCards, parseProxmoxNIC(key, match[1], cfg.value(key)))
+		}
+	}
+
+	sort.Strings(vm.ISOs)
+	sortGuestDevices(vm)
+
+	// Linked clones keep their template's base volume in front of their own
+	for _, disk := range vm.Disks {
+		_, volume, _ := strings.Cut(disk.Name, ":")
+		if match := proxmoxLinkedVolume.FindStringSubmatch(volume); match != nil {
+			vm.Metadata["template_vmid"], _ = strconv.Atoi(match[1])
+			break
+		}
+	}
+}
+
+// applyLXCConfig fills a container from its /nodes/{node}/lxc/{vmid}/config entry
+func applyLXCConfig(vm *models.VirtualMachine, cfg proxmoxConfig) {
+	// Containers without a core limit may use every core of the node, which
+	// /cluster/resources already reports
+	if cores := cfg.number("cores", 0); cores > 0 {
+		vm.CPUs = cores
+		vm.Hardware.NumCPU = cores
+	}
+	vm.Hardware.NumCoresPerSocket = vm.CPUs
+
+	vm.Metadata["unprivileged"] = cfg.value("unprivileged") == "1"
+	if hostname := cfg.value("hostname"); hostname != "" {
+		vm.Metadata["hostname"] = hostname
+	}
+
+	applyCommonConfig(vm, cfg)
+
+	vm.Disks = nil
+	vm.NetworkCards = nil
+	for key := range cfg {
+		if match := proxmoxLXCDiskKey.FindStringSubmatch(key); match != nil {
+			// The root filesystem sorts before mount points
+			index := "0"
+			if match[2] != "" {
+				n, _ := strconv.Atoi(match[2])
+				index = strconv.Itoa(n + 1)
+			}
+			if disk, ok := parseProxmoxDisk(key, "", index, cfg.value(key)); ok {
+				// Record where the volume is mounted inside the container
+				disk.Path = "/"
+				if mountPoint := proxmoxOptions(cfg.value(key))["mp"]; mountPoint != "" {
+					disk.Path = mountPoint
+				}
+				vm.Disks = append(vm.Disks, disk)
+			}
+		} else if match := proxmoxNetKey.FindStringSubmatch(key); match != nil {
+			vm.NetworkCards = append(vm.NetworkCards, parseProxmoxNIC(key, match[1], cfg.value(key)))
+		}
+	}
+
+	sortGuestDevices(vm)
+}
+
+// applyCommonConfig fills the settings QEMU VMs and containers share
+func applyCommonConfig(vm *models.VirtualMachine, cfg proxmoxConfig) {
+	if memory := cfg.number("memory", 0); memory > 0 {
+		vm.Memory = int64(memory)
+		vm.Hardware.MemoryMB = int64(memory)
+	}
+	if ostype := cfg.value("ostype"); ostype != "" {
+		vm.OperatingSystem = ostype
+		vm.Config.GuestID = ostype
+	}
+	if description := cfg.value("description"); description != "" {
+		vm.Annotations = map[string]string{"notes": description}
+	}
+	if tags := cfg.value("tags"); tags != "" {
+		vm.Tags = strings.FieldsFunc(tags, func(r rune) bool { return r == ';' || r == ',' || r == ' ' })
+	}
+}
+
+// sortGuestDevices orders disks by bus and slot and network cards by index,
+// since the configuration is an unordered map
+func sortGuestDevices(vm *models.VirtualMachine) {
+	sort.Slice(vm.Disks, func(i, j int) bool {
+		if vm.Disks[i].Controller != vm.Disks[j].Controller {
+			return vm.Disks[i].Controller < vm.Disks[j].Controller
+		}
+		return vm.Disks[i].Unit < vm.Disks[j].Unit
+	})
+	sort.Slice(vm.NetworkCards, func(i, j int) bool {
+		return vm.NetworkCards[i].Key < vm.NetworkCards[j].Key
+	})
+}
+
+// parseProxmoxDisk parses a disk entry such as
+// "ceph:vm-100-disk-0,size=32G,format=raw"; CD-ROM drives are skipped
+func parseProxmoxDisk(key, bus, index, spec string) (models.Disk, bool) {
+	parts := strings.SplitN(spec, ",", 2)
+	volume := parts[0]
+	options := map[string]string{}
+	if len(parts) == 2 {
+		options = proxmoxOptions(parts[1])
+	}
+	if options["media"] == "cdrom" || volume == "none" || volume == "cdrom" {
+		return models.Disk{}, false
+	}
+
+	unit, _ := strconv.Atoi(index)
+	disk := models.Disk{
+		ID:         key,
+		Name:       volume,
+		Size:       parseProxmoxSize(options["size"]).CeilGiB(),
+		Type:       options["format"],
+		Controller: bus,
+		Unit:       unit,
+	}
+	if storage, _, ok := strings.Cut(volume, ":"); ok {
+		disk.Datastore = storage
+	} else {
+		// Passed-through block devices are given as an absolute path
+		disk.Path = volume
+	}
+	if disk.Type == "" {
+		disk.Type = "raw"
+	}
+
+	return disk, true
+}
+
+// proxmoxISO returns the ISO volume ID of a CD-ROM entry such as
+// "local:iso/debian-12.iso,media=cdrom", or "" for disks and empty drives
+func proxmoxISO(spec string) string {
+	volume, options, _ := strings.Cut(spec, ",")
+	if proxmoxOptions(options)["media"] != "cdrom" && !strings.Contains(volume, ":iso/") {
+		return ""
+	}
+	if volume == "none" || volume == "cdrom" || !strings.Contains(volume, ":") {
+		return ""
+	}
+	return volume
+}
+
+// parseProxmoxNIC parses a network device entry such as
+// "virtio=BC:24:11:00:00:01,bridge=vmbr0,tag=10"
+func parseProxmoxNIC(key, index, spec string) models.NetworkCard {
+	n, _ := strconv.Atoi(index)
+	card := models.NetworkCard{
+		ID:           key,
+		Name:         key,
+		Type:         models.NICTypeUnknown,
+		Key:          int32(n),
+		Connected:    true,
+		StartConnect: true,
+	}
+
+	for _, option := range strings.Split(spec, ",") {
+		name, val, _ := strings.Cut(option, "=")
+		switch {
+		case proxmoxNICModels[name]:
+			card.Type = name
+			card.MACAddress = val
+		case name == "macaddr" || name == "hwaddr":
+			card.MACAddress = val
+		case name == "name":
+			// Containers name the interface inside the guest, e.g. eth0
+			card.Name = val
+		case name == "model" || name == "type":
+			card.Type = val
+		case name == "bridge":
+			card.Network = val
+		case name == "link_down" && val == "1":
+			card.Connected = false
+			card.StartConnect = false
+		}
+	}
+
+	return card
+}
+
+// proxmoxOptions parses a comma separated key=value option list
+func proxmoxOptions(spec string) map[string]string {
+	options := make(map[string]string)
+	for _, option := range strings.Split(spec, ",") {
+		if name, val, ok := strings.Cut(option, "="); ok {
+			options[name] = val
+		}
+	}
+	return options
+}
+
+// parseProxmoxSize parses a Proxmox size such as "32G" or "512M"; a bare
+// number is in bytes
+func parseProxmoxSize(size string) units.ByteSize {
+	if size == "" {
+		return 0
+	}
+
+	multiplier := units.ByteSize(1)
+	switch size[len(size)-1] {
+	case 'K':
+		multiplier = units.KiB
+	case 'M':
+		multiplier = units.MiB
+	case 'G':
+		multiplier = units.GiB
+	case 'T':
+		multiplier = units.TiB
+	}
+	if multiplier != 1 {
+		size = size[:len(size)-1]
+	}
+
+	n, err := strconv.ParseFloat(size, 64)
+	if err != nil {
+		return 0
+	}
+	return units.ByteSize(n * float64(multiplier))
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// proxmoxVolume is one entry of /nodes/{node}/storage/{storage}/content
+type proxmoxVolume struct {
+	VolID   string `json:"volid"`
+	Content string `json:"content"` // iso, vztmpl, images, rootdir, backup, snippets
+	Format  string `json:"format"`
+	Size    int64  `json:"size"`
+	VMID    int    `json:"vmid"`
+}
+
+// attachStorageContents lists the volumes on each accessible storage and
+// attaches a per-content summary, plus the full listing on a deep scan. It
+// returns the IDs of every volume seen, keyed by storageKey, for storages
+// whose contents could be read. Storages are listed concurrently.
+func (p *proxmoxProvider) attachStorageContents(ctx context.Context, storage []models.Storage) map[string]map[string]bool {
+	var mu sync.Mutex
+	volumes := make(map[string]map[string]bool)
+
+	forEachLimit(ctx, len(storage), concurrencyLimit(p.config.Concurrency), func(i int) {
+		store := &storage[i]
+		if !store.Accessible {
+			return
+		}
+
+		node, _ := store.Metadata["node"].(string)
+		if !store.Local {
+			node = p.storageNode(store.Name)
+		}
+		if node == "" {
+			return
+		}
+
+		var entries []proxmoxVolume
+		path := fmt.Sprintf("/nodes/%s/storage/%s/content", node, store.Name)
+		if err := p.get(ctx, path, &entries); err != nil {
+			p.log.Warn("Failed to list storage contents", "storage", store.Name, "node", node, "error", err)
+			return
+		}
+
+		contents := &models.StorageContents{Summary: make(map[string]models.ContentSummary)}
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			seen[entry.VolID] = true
+
+			size := units.FromBytes(entry.Size).ToMiB()
+			summary := contents.Summary[entry.Content]
+			summary.Count++
+			summary.Size += size
+			contents.Summary[entry.Content] = summary
+
+			if p.config.DeepStorageScan {
+				contents.Items = append(contents.Items, models.StorageItem{
+					VolumeID: entry.VolID,
+					Content:  entry.Content,
+					Format:   entry.Format,
+					Size:     size,
+					VMID:     entry.VMID,
+				})
+			}
+		}
+		sort.Slice(contents.Items, func(i, j int) bool {
+			return contents.Items[i].VolumeID < contents.Items[j].VolumeID
+		})
+
+		store.Contents = contents
+		mu.Lock()
+		volumes[storageKey(node, store.Name, store.Local)] = seen
+		mu.Unlock()
+	})
+
+	return volumes
+}
+
+// storageNode picks a node to list a shared storage through: any node that
+// reports it available
+func (p *proxmoxProvider) storageNode(name string) string {
+	for _, resource := range p.resources {
+		if resource.Type == "storage" && resource.Storage == name && resource.Status == "available" && p.inScope(resource) {
+			return resource.Node
+		}
+	}
+	return ""
+}
+
+// storageKey identifies a storage the way DiscoverStorage lists it: local
+// storage of the same name exists once per node, shared storage once
+func storageKey(node, name string, local bool) string {
+	if local {
+		return node + "/" + name
+	}
+	return name
+}
+
+// missingISOFindings flags VMs whose CD-ROM references an ISO that is not on
+// the storage it names, as seen from the VM's node. ISOs on a storage whose
+// contents could not be read are not flagged, since whether they exist is
+// unknown.
+func missingISOFindings(vms []models.VirtualMachine, storage []models.Storage, volumes map[string]map[string]bool) []models.Finding {
+	var findings []models.Finding
+
+	// Storage names that are shared, and local storage by node
+	shared := make(map[string]bool)
+	known := make(map[string]bool, len(storage))
+	for _, store := range storage {
+		node, _ := store.Metadata["node"].(string)
+		known[storageKey(node, store.Name, store.Local)] = true
+		if !store.Local {
+			shared[store.Name] = true
+		}
+	}
+
+	for _, vm := range vms {
+		for _, iso := range vm.ISOs {
+			name, _, _ := strings.Cut(iso, ":")
+			key := storageKey(vm.Host, name, !shared[name])
+			if seen, ok := volumes[key]; ok && seen[iso] {
+				continue
+			} else if !ok && known[key] {
+				continue
+			}
+
+			findings = append(findings, models.Finding{
+				Severity: "warning",
+				Rule:     "missing-iso",
+				Resource: vm.Name,
+				Message:  fmt.Sprintf("CD-ROM references ISO %s which no longer exists on storage %s", iso, name),
+			})
+		}
+	}
+
+	return findings
+}
@@ -0,0 +1,39 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+)
+
+// AuthError reports a session that expired mid-discovery and could not be
+// renewed, so the remaining phases cannot run either
+type AuthError struct {
+	Provider string
+	Phase    string // discovery phase that failed, e.g. "virtual machines"
+	Err      error
+}
+
+func (e *AuthError) Error() string {
+	if e.Phase == "" {
+		return fmt.Sprintf("%s authentication failed: %v", e.Provider, e.Err)
+	}
+	return fmt.Sprintf("%s authentication failed while discovering %s: %v", e.Provider, e.Phase, e.Err)
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// authFailure returns err as an *AuthError naming phase when the session
+// could not be renewed, and nil for any other error. Discovery stops at
+// such a phase instead of failing every later call the same way.
+func authFailure(err error, phase string) error {
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		return nil
+	}
+	if authErr.Phase == "" {
+		authErr.Phase = phase
+	}
+	return authErr
+}
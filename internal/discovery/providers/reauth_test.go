@@ -0,0 +1,360 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"valhalla/internal/config"
+)
+
+// expiringProxmox serves a one-node Proxmox cluster with one VM whose
+// tickets expire when expireOn is first requested, as if they timed out
+// mid-discovery. It accepts password, the API token token-secret unless
+// revoked, and tickets unless rejectTickets is set, and counts logins and
+// requests by API path.
+type expiringProxmox struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	password      string
+	ticket        string
+	revoked       bool
+	rejectTickets bool
+	expireOn      string
+	expired       bool
+	logins        int
+	requests      map[string]int
+}
+
+func newExpiringProxmox(t *testing.T, expireOn string) *expiringProxmox {
+	t.Helper()
+
+	s := &expiringProxmox{password: "secret", expireOn: expireOn, requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *expiringProxmox) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/api2/json")
+
+	if path == "/access/ticket" {
+		if r.Method != http.MethodPost || r.FormValue("password") != s.password {
+			http.Error(w, `{"data":null}`, http.StatusUnauthorized)
+			return
+		}
+		s.logins++
+		s.ticket = fmt.Sprintf("PVE:root@pam:%d", s.logins)
+		fmt.Fprintf(w, `{"data":{"ticket":%q,"CSRFPreventionToken":"csrf"}}`, s.ticket)
+		return
+	}
+
+	s.requests[path]++
+	if path == s.expireOn && !s.expired {
+		s.expired = true
+		s.ticket = ""
+		s.revoked = true
+	}
+	if token := r.Header.Get("Authorization"); token != "" {
+		if s.revoked || !strings.HasSuffix(token, "=token-secret") {
+			http.Error(w, `{"data":null}`, http.StatusUnauthorized)
+			return
+		}
+	} else if cookie, err := r.Cookie("PVEAuthCookie"); err != nil || s.rejectTickets || s.ticket == "" || cookie.Value != s.ticket {
+		http.Error(w, `{"data":null}`, http.StatusUnauthorized)
+		return
+	}
+
+	switch path {
+	case "/version":
+		w.Write([]byte(`{"data":{"version":"8.2.4","release":"8.2","repoid":"faa83925"}}`))
+	case "/cluster/resources":
+		w.Write([]byte(`{"data":[
+			{"id":"node/pve1","type":"node","node":"pve1","status":"online","maxcpu":8,"maxmem":34359738368},
+			{"id":"qemu/100","type":"qemu","node":"pve1","status":"running","name":"web-01","vmid":100,"maxcpu":2,"maxmem":4294967296}
+		]}`))
+	default:
+		w.Write([]byte(`{"data":null}`))
+	}
+}
+
+// connectExpiringProxmox connects to server with password auth
+func connectExpiringProxmox(t *testing.T, server *expiringProxmox) *proxmoxProvider {
+	t.Helper()
+
+	p := NewProxmoxProvider(testLogger()).(*proxmoxProvider)
+	cfg := config.ProxmoxConfig{Server: server.URL, Username: "root@pam", Password: "secret"}
+	if err := p.ConnectProxmox(context.Background(), cfg); err != nil {
+		t.Fatalf("ConnectProxmox: %v", err)
+	}
+	return p
+}
+
+func TestProxmoxTicketRenewal(t *testing.T) {
+	// The ticket expires once discovery reaches the VM configs
+	server := newExpiringProxmox(t, "/nodes/pve1/qemu/100/config")
+	p := connectExpiringProxmox(t, server)
+
+	infra, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if len(infra.VirtualMachines) != 1 || infra.VirtualMachines[0].Name != "web-01" {
+		t.Errorf("VMs = %+v, want web-01", infra.VirtualMachines)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !server.expired {
+		t.Fatal("the ticket never expired; the test no longer covers renewal")
+	}
+	if server.logins != 2 {
+		t.Errorf("logged in %d times, want once to connect and once to renew", server.logins)
+	}
+	// The rejected GET is replayed with the new ticket
+	if n := server.requests["/nodes/pve1/qemu/100/config"]; n != 2 {
+		t.Errorf("VM config requested %d times, want 2", n)
+	}
+}
+
+func TestProxmoxConcurrentExpiryLogsInOnce(t *testing.T) {
+	server := newExpiringProxmox(t, "")
+	p := connectExpiringProxmox(t, server)
+
+	server.mu.Lock()
+	server.ticket = ""
+	server.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- p.get(context.Background(), "/version", nil)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("get after expiry: %v", err)
+		}
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.logins != 2 {
+		t.Errorf("logged in %d times for 8 concurrent expired requests, want 2", server.logins)
+	}
+}
+
+func TestProxmoxRenewalFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  bool
+		before func(*expiringProxmox)
+		want   string
+		logins int
+	}{
+		{
+			name:   "password changed",
+			before: func(s *expiringProxmox) { s.password = "rotated" },
+			want:   "failed to log in again",
+			logins: 1,
+		},
+		{
+			name:   "new ticket rejected",
+			before: func(s *expiringProxmox) { s.rejectTickets = true },
+			want:   "rejected after logging in again",
+			logins: 2,
+		},
+		{
+			name:  "API token revoked",
+			token: true,
+			want:  "API token rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newExpiringProxmox(t, "/cluster/resources")
+			var p *proxmoxProvider
+			if tt.token {
+				p = NewProxmoxProvider(testLogger()).(*proxmoxProvider)
+				cfg := config.ProxmoxConfig{Server: server.URL, TokenID: "root@pam!valhalla", Secret: "token-secret"}
+				if err := p.ConnectProxmox(context.Background(), cfg); err != nil {
+					t.Fatalf("ConnectProxmox: %v", err)
+				}
+			} else {
+				p = connectExpiringProxmox(t, server)
+			}
+
+			server.mu.Lock()
+			if tt.before != nil {
+				tt.before(server)
+			}
+			server.mu.Unlock()
+
+			_, err := p.Discover(context.Background())
+			var authErr *AuthError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("Discover = %v, want an *AuthError", err)
+			}
+			if authErr.Phase != "cluster resources" || !strings.Contains(err.Error(), "while discovering cluster resources") {
+				t.Errorf("error does not name the failed phase: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Discover = %v, want %q", err, tt.want)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if server.logins != tt.logins {
+				t.Errorf("logged in %d times, want %d", server.logins, tt.logins)
+			}
+		})
+	}
+}
+
+// expiringPrism serves Prism v3 list calls with one cluster and nothing
+// else. Basic auth issues a session cookie that later calls use; sessions
+// expire when expireOn is first requested, and a request with an expired
+// session is rejected even with credentials, as Prism does.
+type expiringPrism struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	password string
+	sessions map[string]bool
+	issued   int
+	expireOn string
+	expired  bool
+	requests map[string]int
+}
+
+func newExpiringPrism(t *testing.T, expireOn string) *expiringPrism {
+	t.Helper()
+
+	s := &expiringPrism{password: "secret", sessions: make(map[string]bool), expireOn: expireOn, requests: make(map[string]int)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *expiringPrism) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/api/nutanix/v3")
+	s.requests[path]++
+	if path == s.expireOn && !s.expired {
+		s.expired = true
+		s.sessions = make(map[string]bool)
+	}
+
+	if cookie, err := r.Cookie("NTNX_IGW_SESSION"); err == nil {
+		if !s.sessions[cookie.Value] {
+			http.Error(w, `{"message":"session expired"}`, http.StatusUnauthorized)
+			return
+		}
+	} else {
+		if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != s.password {
+			http.Error(w, `{"message":"invalid credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		s.issued++
+		session := fmt.Sprintf("session-%d", s.issued)
+		s.sessions[session] = true
+		http.SetCookie(w, &http.Cookie{Name: "NTNX_IGW_SESSION", Value: session, Path: "/"})
+	}
+
+	if path == "/clusters/list" {
+		w.Write([]byte(`{"entities":[{"metadata":{"uuid":"c-1"},"status":{"name":"cluster-1","resources":{"config":{"build":{"version":"6.5.2"}}}}}],"metadata":{"total_matches":1}}`))
+		return
+	}
+	w.Write([]byte(`{"entities":[],"metadata":{"total_matches":0}}`))
+}
+
+// connectExpiringPrism connects to server
+func connectExpiringPrism(t *testing.T, server *expiringPrism) *nutanixProvider {
+	t.Helper()
+
+	p := NewNutanixProvider(testLogger()).(*nutanixProvider)
+	cfg := config.NutanixConfig{Server: server.URL, Username: "admin", Password: "secret"}
+	if err := p.ConnectNutanix(context.Background(), cfg); err != nil {
+		t.Fatalf("ConnectNutanix: %v", err)
+	}
+	return p
+}
+
+func TestNutanixSessionRenewal(t *testing.T) {
+	// The session expires once discovery reaches the VMs
+	server := newExpiringPrism(t, "/vms/list")
+	p := connectExpiringPrism(t, server)
+
+	infra, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if warnings, failed := infra.Metadata["phase_warnings"]; failed {
+		t.Errorf("a discovery phase failed: %v", warnings)
+	}
+	if names, _ := infra.Metadata["clusters"].([]string); len(names) != 1 || names[0] != "cluster-1" {
+		t.Errorf("clusters = %v, want cluster-1", infra.Metadata["clusters"])
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !server.expired {
+		t.Fatal("the session never expired; the test no longer covers renewal")
+	}
+	if server.issued != 2 {
+		t.Errorf("%d sessions issued, want one to connect and one to renew", server.issued)
+	}
+	if n := server.requests["/vms/list"]; n != 2 {
+		t.Errorf("VMs listed %d times, want 2", n)
+	}
+}
+
+func TestNutanixRenewalFailure(t *testing.T) {
+	server := newExpiringPrism(t, "/hosts/list")
+	p := connectExpiringPrism(t, server)
+
+	server.mu.Lock()
+	server.password = "rotated"
+	server.mu.Unlock()
+
+	infra, err := p.Discover(context.Background())
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Discover = %v, %v; want an *AuthError", infra, err)
+	}
+	if authErr.Provider != "nutanix" || authErr.Phase != "hosts" {
+		t.Errorf("AuthError = %+v, want the hosts phase", authErr)
+	}
+	if !strings.Contains(err.Error(), "authentication failed while discovering hosts") || !strings.Contains(err.Error(), "rejected after authenticating again") {
+		t.Errorf("Discover = %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// One retry, then discovery stops: no later phase is attempted
+	if n := server.requests["/hosts/list"]; n != 2 {
+		t.Errorf("hosts listed %d times, want 2", n)
+	}
+	if n := server.requests["/vms/list"]; n != 0 {
+		t.Errorf("discovery went on to list VMs %d times after the auth failure", n)
+	}
+}
@@ -0,0 +1,70 @@
+package providers
+
+import (
+	"strings"
+)
+
+// ProviderVersion identifies the product and API version a provider reported
+// when connecting
+type ProviderVersion struct {
+	Product    string `json:"product,omitempty"`
+	Version    string `json:"version"`
+	Build      string `json:"build,omitempty"`
+	APIVersion string `json:"api_version,omitempty"`
+	APIType    string `json:"api_type,omitempty"` // VMware: VirtualCenter or HostAgent
+}
+
+// Metadata returns the version as an infrastructure metadata value
+func (v ProviderVersion) Metadata() map[string]interface{} {
+	metadata := map[string]interface{}{"version": v.Version}
+	if v.Product != "" {
+		metadata["product"] = v.Product
+	}
+	if v.Build != "" {
+		metadata["build"] = v.Build
+	}
+	if v.APIVersion != "" {
+		metadata["api_version"] = v.APIVersion
+	}
+	if v.APIType != "" {
+		metadata["api_type"] = v.APIType
+	}
+	return metadata
+}
+
+// String formats the version for display, e.g. "VMware vCenter Server 8.0.2 (build 22385739)"
+func (v ProviderVersion) String() string {
+	s := strings.TrimSpace(v.Product + " " + v.Version)
+	if v.Build != "" {
+		s += " (build " + v.Build + ")"
+	}
+	return s
+}
+
+// UnavailableCapabilities returns the registered capabilities a provider
+// cannot offer at the detected version
+func UnavailableCapabilities(provider string, version ProviderVersion) []Capability {
+	switch provider {
+	case "vmware":
+		// Standalone ESXi hosts have no alarm manager
+		if version.APIType == "HostAgent" {
+			return []Capability{CapabilityAlarms}
+		}
+	}
+	return nil
+}
+
+// ProviderVersion returns the version recorded in the connection metadata
+func (i ConnectionInfo) ProviderVersion() ProviderVersion {
+	field := func(key string) string {
+		value, _ := i.Metadata[key].(string)
+		return value
+	}
+	return ProviderVersion{
+		Product:    field("product"),
+		Version:    i.Version,
+		Build:      field("build"),
+		APIVersion: field("api_version"),
+		APIType:    field("api_type"),
+	}
+}
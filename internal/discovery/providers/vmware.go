@@ -2,15 +2,21 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"crypto/tls"
 	"fmt"
+	"net"
 	"net/url"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vmware/govmomi"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/property"
 	"github.com/vmware/govmomi/session"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -20,6 +26,7 @@ import (
 	"valhalla/internal/config"
 	"valhalla/internal/logger"
 	"valhalla/internal/models"
+	"valhalla/internal/units"
 )
 
 // vmwareProvider implements the VMwareProvider interface
@@ -29,6 +36,48 @@ type vmwareProvider struct {
 	finder    *find.Finder
 	config    config.VMwareConfig
 	connected bool
+	version   ProviderVersion
+
+	// alarmNames caches alarm definition names by reference
+	alarmNames map[string]string
+
+	// hostNames caches host system names by reference, filled on first use
+	hostNames map[string]string
+
+	// poolNames caches resource pool and vApp names by reference, filled
+	// on first use
+	poolNames map[string]string
+
+	// vmNames and templates are filled by DiscoverVMs from the same property
+	// retrieval, so pools and templates don't need their own round trips
+	vmNames   map[string]string
+	templates []models.Template
+
+	// scopeNetworks and scopeDatastores hold the references visible from the
+	// cluster's hosts when discovery is cluster-scoped; nil means unscoped
+	scopeNetworks   map[string]bool
+	scopeDatastores map[string]bool
+
+	// skipped lists the objects deleted between being listed and having
+	// their properties retrieved; retrievals run concurrently
+	skippedMu sync.Mutex
+	skipped   []string
+}
+
+// Catch a method signature drifting from the interface at compile time
+var _ VMwareProvider = (*vmwareProvider)(nil)
+
+func init() {
+	RegisterProviderInfo(ProviderInfo{
+		Name:        "vmware",
+		Description: "VMware vCenter / vSphere",
+		Capabilities: DeriveCapabilities(&vmwareProvider{}),
+		RequiredConfig: []string{
+			"providers.vmware.server",
+			"providers.vmware.username",
+			"providers.vmware.password",
+		},
+	})
 }
 
 // NewVMwareProvider creates a new VMware provider
@@ -38,7 +87,7 @@ func NewVMwareProvider(log *logger.Logger) VMwareProvider {
 	}
 }
 
-// Connect establishes connection to vCenter with VMware-specific configuration
+// ConnectVMware establishes connection to vCenter with VMware-specific configuration
 func (p *vmwareProvider) ConnectVMware(ctx context.Context, cfg config.VMwareConfig) error {
 	p.config = cfg
 	
@@ -60,7 +109,7 @@ func (p *vmwareProvider) ConnectVMware(ctx context.Context, cfg config.VMwareCon
 	// Create vim25 client
 	vimClient, err := vim25.NewClient(ctx, soapClient)
 	if err != nil {
-		return fmt.Errorf("failed to create vim25 client: %w", err)
+		return fmt.Errorf("failed to create vim25 client: %w", describeConnectError(cfg.Server, err))
 	}
 
 	// Create govmomi client
@@ -73,7 +122,31 @@ func (p *vmwareProvider) ConnectVMware(ctx context.Context, cfg config.VMwareCon
 	p.log.Info("Authenticating to vCenter", "server", cfg.Server, "username", cfg.Username)
 	err = p.client.Login(ctx, u.User)
 	if err != nil {
-		return fmt.Errorf("failed to login to vCenter: %w", err)
+		if isInvalidLogin(err) {
+			return fmt.Errorf("failed to login to vCenter: invalid username or password for %s: %w", cfg.Username, err)
+		}
+		return fmt.Errorf("failed to login to vCenter: %w", describeConnectError(cfg.Server, err))
+	}
+
+	// The session confirms the login really took, rather than trusting the
+	// absence of a fault
+	userSession, err := p.client.SessionManager.UserSession(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to verify vCenter session: %w", describeConnectError(cfg.Server, err))
+	}
+	if userSession == nil {
+		return fmt.Errorf("failed to verify vCenter session: %s reports no session for %s", cfg.Server, cfg.Username)
+	}
+	p.log.Debug("vCenter session established", "user", userSession.UserName)
+
+	// Record what we are talking to so discovery can adapt to it
+	about := p.client.ServiceContent.About
+	p.version = ProviderVersion{
+		Product:    about.FullName,
+		Version:    about.Version,
+		Build:      about.Build,
+		APIVersion: about.ApiVersion,
+		APIType:    about.ApiType,
 	}
 
 	// Create finder
@@ -90,11 +163,23 @@ func (p *vmwareProvider) ConnectVMware(ctx context.Context, cfg config.VMwareCon
 	}
 
 	p.connected = true
-	p.log.Info("Successfully connected to vCenter", "server", cfg.Server)
+	p.log.Info("Successfully connected to vCenter", "server", cfg.Server, "version", p.version.Version, "build", p.version.Build)
 	
 	return nil
 }
 
+// isInvalidLogin reports whether err is vCenter rejecting the credentials
+func isInvalidLogin(err error) bool {
+	// SOAP fault errors do not unwrap, so walk the chain by hand
+	for ; err != nil; err = errors.Unwrap(err) {
+		if soap.IsSoapFault(err) {
+			_, ok := soap.ToSoapFault(err).VimFault().(types.InvalidLogin)
+			return ok
+		}
+	}
+	return false
+}
+
 // Disconnect closes the vCenter connection
 func (p *vmwareProvider) Disconnect() error {
 	if p.client != nil && p.connected {
@@ -114,6 +199,34 @@ func (p *vmwareProvider) Disconnect() error {
 	return nil
 }
 
+// ConnectionInfo describes the vCenter connection
+func (p *vmwareProvider) ConnectionInfo() ConnectionInfo {
+	return ConnectionInfo{
+		Server:    p.config.Server,
+		Username:  p.config.Username,
+		Connected: p.connected,
+		Version:   p.version.Version,
+		Metadata:  p.version.Metadata(),
+	}
+}
+
+// Features lists the capabilities discovered inside other phases: tags and
+// snapshots along with the VMs, alarms with --with-alarms
+func (p *vmwareProvider) Features() []Capability {
+	return []Capability{CapabilityTags, CapabilitySnapshots, CapabilityAlarms}
+}
+
+// capabilityAvailable reports whether the connected endpoint's version
+// offers a capability
+func (p *vmwareProvider) capabilityAvailable(capability Capability) bool {
+	for _, unavailable := range UnavailableCapabilities("vmware", p.version) {
+		if unavailable == capability {
+			return false
+		}
+	}
+	return true
+}
+
 // Discover performs complete infrastructure discovery
 func (p *vmwareProvider) Discover(ctx context.Context) (*models.Infrastructure, error) {
 	if !p.connected {
@@ -128,26 +241,110 @@ func (p *vmwareProvider) Discover(ctx context.Context) (*models.Infrastructure,
 		DiscoveryTime: time.Now(),
 		Metadata:      make(map[string]interface{}),
 	}
+	infrastructure.Metadata["provider_version"] = p.version.Metadata()
+
+	// Standalone hosts lack some vCenter-only services
+	if p.config.WithAlarms && !p.capabilityAvailable(CapabilityAlarms) {
+		p.log.Warn("Alarm discovery is not available on this endpoint, skipping",
+			"api_type", p.version.APIType, "version", p.version.Version)
+		p.config.WithAlarms = false
+	}
+
+	// Per-phase durations in milliseconds
+	timings := make(map[string]int64)
+
+	p.skippedMu.Lock()
+	p.skipped = nil
+	p.skippedMu.Unlock()
+
+	// Restrict networks and storage to what the cluster's hosts can see
+	p.scopeNetworks, p.scopeDatastores = nil, nil
+	if p.config.Cluster != "" && !p.config.AllNetworks {
+		networks, datastores, err := p.clusterScope(ctx, p.config.Cluster)
+		if err != nil {
+			p.log.Warn("Failed to determine cluster scope, listing all networks and storage", "cluster", p.config.Cluster, "error", err)
+		} else {
+			p.scopeNetworks, p.scopeDatastores = networks, datastores
+			infrastructure.Metadata["cluster_scoped"] = true
+		}
+	}
 
 	// Discover VMs
 	p.log.Info("Discovering virtual machines")
-	vms, err := p.DiscoverVMs(ctx, VMDiscoveryFilters{
-		Datacenter: p.config.Datacenter,
-		Cluster:    p.config.Cluster,
+	phaseStart := time.Now()
+	vms, err := watchPhase(ctx, p.log, "virtual machines", func(ctx context.Context) ([]models.VirtualMachine, error) {
+		return p.DiscoverVMs(ctx, VMDiscoveryFilters{
+			Datacenter:   p.config.Datacenter,
+			Cluster:      p.config.Cluster,
+			PowerState:   p.config.VMs.PowerState,
+			Names:        p.config.VMs.Names,
+			Host:         p.config.VMs.Host,
+			ResourcePool: p.config.VMs.ResourcePool,
+			Folder:       p.config.VMs.Folder,
+
+			IncludeTemplates: p.config.IncludeTemplates,
+		})
 	})
+	timings["vms_ms"] = time.Since(phaseStart).Milliseconds()
 	if err != nil {
-		p.log.Error("Failed to discover VMs", "error", err)
+		phaseFailed(p.log, infrastructure, "Failed to discover VMs", err)
 		// Don't fail completely, just log and continue
 	} else {
 		infrastructure.VirtualMachines = vms
 		p.log.Info("Discovered virtual machines", "count", len(vms))
 	}
 
+	// Templates were retrieved along with the VMs
+	templates, err := p.DiscoverTemplates(ctx)
+	if err != nil {
+		p.log.Error("Failed to discover templates", "error", err)
+	} else {
+		infrastructure.Templates = templates
+		p.log.Info("Discovered templates", "count", len(templates))
+	}
+
+	// Discover resource pools
+	p.log.Info("Discovering resource pools")
+	phaseStart = time.Now()
+	pools, err := watchPhase(ctx, p.log, "resource pools", p.DiscoverResourcePools)
+	timings["resource_pools_ms"] = time.Since(phaseStart).Milliseconds()
+	if err != nil {
+		phaseFailed(p.log, infrastructure, "Failed to discover resource pools", err)
+	} else {
+		infrastructure.ResourcePools = pools
+		p.log.Info("Discovered resource pools", "count", len(pools))
+	}
+
+	// Clusters and hosts describe where VMs can be live migrated; all of the
+	// datacenter's are listed even when discovery is cluster-scoped
+	p.log.Info("Discovering clusters and hosts")
+	phaseStart = time.Now()
+	clusters, err := watchPhase(ctx, p.log, "clusters", func(ctx context.Context) ([]models.Cluster, error) {
+		return p.DiscoverClusters(ctx, "")
+	})
+	if err != nil {
+		phaseFailed(p.log, infrastructure, "Failed to discover clusters", err)
+	} else {
+		infrastructure.Clusters = clusters
+	}
+	hosts, err := watchPhase(ctx, p.log, "hosts", func(ctx context.Context) ([]models.Host, error) {
+		return p.DiscoverHosts(ctx, "")
+	})
+	if err != nil {
+		phaseFailed(p.log, infrastructure, "Failed to discover hosts", err)
+	} else {
+		infrastructure.Hosts = hosts
+	}
+	timings["clusters_ms"] = time.Since(phaseStart).Milliseconds()
+	p.log.Info("Discovered clusters and hosts", "clusters", len(clusters), "hosts", len(hosts))
+
 	// Discover Networks
 	p.log.Info("Discovering networks")
-	networks, err := p.DiscoverNetworks(ctx)
+	phaseStart = time.Now()
+	networks, err := watchPhase(ctx, p.log, "networks", p.DiscoverNetworks)
+	timings["networks_ms"] = time.Since(phaseStart).Milliseconds()
 	if err != nil {
-		p.log.Error("Failed to discover networks", "error", err)
+		phaseFailed(p.log, infrastructure, "Failed to discover networks", err)
 	} else {
 		infrastructure.Networks = networks
 		p.log.Info("Discovered networks", "count", len(networks))
@@ -155,18 +352,77 @@ func (p *vmwareProvider) Discover(ctx context.Context) (*models.Infrastructure,
 
 	// Discover Storage
 	p.log.Info("Discovering storage")
-	storage, err := p.DiscoverStorage(ctx)
+	phaseStart = time.Now()
+	storage, err := watchPhase(ctx, p.log, "storage", p.DiscoverStorage)
+	timings["storage_ms"] = time.Since(phaseStart).Milliseconds()
 	if err != nil {
-		p.log.Error("Failed to discover storage", "error", err)
+		phaseFailed(p.log, infrastructure, "Failed to discover storage", err)
 	} else {
 		infrastructure.Storage = storage
 		p.log.Info("Discovered storage", "count", len(storage))
 	}
 
+	// Permissions need a user directory lookup per principal, so they're opt-in
+	if p.config.WithPermissions {
+		p.log.Info("Discovering permissions")
+		phaseStart = time.Now()
+		permissions, err := watchPhase(ctx, p.log, "permissions", p.DiscoverPermissions)
+		timings["permissions_ms"] = time.Since(phaseStart).Milliseconds()
+		if err != nil {
+			phaseFailed(p.log, infrastructure, "Failed to discover permissions", err)
+		} else {
+			infrastructure.Permissions = permissions
+			infrastructure.Findings = append(infrastructure.Findings, permissionFindings(permissions)...)
+			p.log.Info("Discovered permissions", "count", len(permissions))
+		}
+	}
+
+	// Content libraries arrived in vCenter 6.0
+	if p.config.WithContentLibraries {
+		if !p.contentLibrariesAvailable() {
+			p.log.Warn("Content libraries are not available on this endpoint, skipping",
+				"api_type", p.version.APIType, "version", p.version.Version)
+		} else {
+			p.log.Info("Discovering content libraries")
+			phaseStart = time.Now()
+			libraries, err := watchPhase(ctx, p.log, "content libraries", p.DiscoverContentLibraries)
+			timings["content_libraries_ms"] = time.Since(phaseStart).Milliseconds()
+			if err != nil {
+				phaseFailed(p.log, infrastructure, "Failed to discover content libraries", err)
+			} else {
+				infrastructure.ContentLibraries = libraries
+				p.log.Info("Discovered content libraries", "count", len(libraries))
+			}
+		}
+	}
+
+	// Flag orphaned VMs and disks
+	infrastructure.Findings = append(infrastructure.Findings, orphanFindings(infrastructure)...)
+
+	// Flag raw device mappings, which generated code cannot recreate
+	if rdm := rdmFindings(infrastructure.VirtualMachines); len(rdm) > 0 {
+		p.log.Warn("Found raw device mapping disks that generated code cannot recreate", "disks", len(rdm))
+		infrastructure.Findings = append(infrastructure.Findings, rdm...)
+	}
+
+	// Summarize triggered alarms
+	if p.config.WithAlarms {
+		infrastructure.Metadata["alarms"] = alarmSummary(infrastructure)
+	}
+
+	// Objects deleted mid-run are missing from the results
+	p.skippedMu.Lock()
+	if len(p.skipped) > 0 {
+		infrastructure.Metadata["skipped_objects"] = append([]string(nil), p.skipped...)
+	}
+	p.skippedMu.Unlock()
+
 	// Add basic metadata
 	totalResources := len(infrastructure.VirtualMachines) + len(infrastructure.Networks) + len(infrastructure.Storage)
 	infrastructure.Metadata["total_resources"] = totalResources
 	infrastructure.Metadata["discovery_duration"] = time.Since(infrastructure.DiscoveryTime).String()
+	timings["total_ms"] = time.Since(infrastructure.DiscoveryTime).Milliseconds()
+	infrastructure.Metadata["timings"] = timings
 
 	return infrastructure, nil
 }
@@ -180,29 +436,66 @@ func (p *vmwareProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFil
 	}
 
 	var vmList []models.VirtualMachine
+
+	props := []string{"name", "runtime", "config", "summary", "guest", "snapshot", "availableField", "customValue", "resourcePool"}
+	if p.config.WithAlarms {
+		props = append(props, "triggeredAlarmState")
+	}
+	// The file layout is only needed for snapshot sizes and can be large
+	if p.config.IncludeSnapshots {
+		props = append(props, "layoutEx")
+	}
 	
-	// Simple approach - get basic properties for each VM
-	for _, vm := range vms {
-		var moVM mo.VirtualMachine
-		err := vm.Properties(ctx, vm.Reference(), []string{"name", "runtime", "config", "summary"}, &moVM)
-		if err != nil {
-			p.log.Error("Failed to get VM properties", "vm", vm.Name(), "error", err)
-			continue
+	refs := make([]types.ManagedObjectReference, len(vms))
+	folders := make(map[string]string, len(vms))
+	for i, vm := range vms {
+		refs[i] = vm.Reference()
+		// The finder gives each VM's inventory path, e.g. /DC0/vm/Prod/web-01
+		if vm.InventoryPath != "" {
+			folders[refs[i].Value] = path.Dir(vm.InventoryPath)
 		}
+	}
 
-		// Skip templates unless specifically requested
-		if moVM.Config != nil && moVM.Config.Template && !filters.IncludeTemplates {
-			continue
-		}
+	// Tags come from the tagging service rather than the property collector
+	vmTags := p.vmTags(ctx, refs)
+
+	// NICs on distributed switches reference their portgroup by key
+	portgroups := p.portgroupNames(ctx)
+
+	p.vmNames = make(map[string]string, len(refs))
+	p.templates = []models.Template{}
+
+	// Folders holding each VM's disks, to name linked clone parents
+	diskDirs := make(map[string]string)
+
+	for _, moVM := range retrieveObjects[mo.VirtualMachine](ctx, p, "VM", refs, props) {
+		p.vmNames[moVM.Reference().Value] = moVM.Name
 
 		vmModel := models.VirtualMachine{
 			ID:         moVM.Reference().Value,
 			Name:       moVM.Name,
 			State:      string(moVM.Runtime.PowerState),
 			PowerState: string(moVM.Runtime.PowerState),
+			Folder:     folders[moVM.Reference().Value],
 			Metadata:   make(map[string]interface{}),
 		}
 
+		if moVM.Runtime.Host != nil {
+			vmModel.Host = p.hostName(ctx, *moVM.Runtime.Host)
+		}
+		// Templates are in no resource pool
+		if moVM.ResourcePool != nil {
+			vmModel.ResourcePool = p.poolName(ctx, *moVM.ResourcePool)
+		}
+
+		// Orphaned, inaccessible and invalid VMs report that instead of a power state
+		if cs := moVM.Runtime.ConnectionState; cs != "" {
+			vmModel.Metadata["connection_state"] = string(cs)
+			if cs != types.VirtualMachineConnectionStateConnected {
+				vmModel.State = string(cs)
+			}
+		}
+
 		// Basic configuration
 		if moVM.Config != nil {
 			vmModel.CPUs = int(moVM.Config.Hardware.NumCPU)
@@ -220,6 +513,11 @@ func (p *vmwareProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFil
 				vmModel.Config.Modified = moVM.Config.Modified
 			}
 
+			// VM notes
+			if moVM.Config.Annotation != "" {
+				vmModel.Annotations = map[string]string{"notes": moVM.Config.Annotation}
+			}
+
 			vmModel.Hardware = models.HardwareInfo{
 				Version:           moVM.Config.Version,
 				NumCPU:           int(moVM.Config.Hardware.NumCPU),
@@ -227,17 +525,95 @@ func (p *vmwareProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFil
 				MemoryMB:         int64(moVM.Config.Hardware.MemoryMB),
 				Firmware:         moVM.Config.Firmware,
 			}
+
+			// Video card settings the recreated VM should match
+			for _, device := range moVM.Config.Hardware.Device {
+				if video, ok := device.(*types.VirtualMachineVideoCard); ok {
+					vmModel.Hardware.VideoRAMKB = video.VideoRamSizeInKB
+					vmModel.Hardware.NumDisplays = int(video.NumDisplays)
+					vmModel.Hardware.Enable3D = video.Enable3DSupport != nil && *video.Enable3DSupport
+					vmModel.Hardware.GraphicsMemoryKB = video.GraphicsMemorySizeInKB
+				}
+			}
 		}
 
-		// Guest information
+		// Custom attributes sit alongside the notes
+		for name, value := range customAttributes(moVM.AvailableField, moVM.CustomValue) {
+			if vmModel.Annotations == nil {
+				vmModel.Annotations = make(map[string]string)
+			}
+			vmModel.Annotations[name] = value
+		}
+
+		vmModel.Tags = vmTags[vmModel.ID]
+
+		// Guest information; addresses and host name are only current while
+		// VMware Tools runs, so powered off VMs are left without them
 		if moVM.Guest != nil {
 			vmModel.OperatingSystem = moVM.Guest.GuestFullName
+			if moVM.Guest.ToolsRunningStatus == string(types.VirtualMachineToolsRunningStatusGuestToolsRunning) {
+				vmModel.Hostname = moVM.Guest.HostName
+				vmModel.IPAddress = moVM.Guest.IpAddress
+				vmModel.IPAddresses = guestIPAddresses(moVM.Guest)
+			}
+		}
+
+		// VMware Tools status
+		if guest := moVM.Summary.Guest; guest != nil {
+			vmModel.Tools = models.VMTools{
+				Status:        string(guest.ToolsStatus),
+				RunningStatus: guest.ToolsRunningStatus,
+			}
+			if vmModel.OperatingSystem == "" {
+				vmModel.OperatingSystem = guest.GuestFullName
+			}
+		}
+
+		// Features that need manual work when migrating
+		if moVM.Snapshot != nil {
+			vmModel.Metadata["snapshots"] = countSnapshots(moVM.Snapshot.RootSnapshotList)
+			current := ""
+			if moVM.Snapshot.CurrentSnapshot != nil {
+				current = moVM.Snapshot.CurrentSnapshot.Value
+				vmModel.Metadata["current_snapshot"] = current
+			}
+			if p.config.IncludeSnapshots {
+				vmModel.Snapshots = snapshotTree(moVM.Snapshot.RootSnapshotList, "", current, snapshotSizes(moVM.LayoutEx))
+			}
+		}
+		if ft := moVM.Runtime.FaultToleranceState; ft != "" && ft != types.VirtualMachineFaultToleranceStateNotConfigured {
+			vmModel.Metadata["fault_tolerance"] = string(ft)
+		}
+		if moVM.Config != nil {
+			if n := countPassthroughDevices(moVM.Config.Hardware.Device); n > 0 {
+				vmModel.Metadata["passthrough_devices"] = n
+			}
 		}
 
 		// Extract basic disk and network info from config
 		if moVM.Config != nil && moVM.Config.Hardware.Device != nil {
 			vmModel.Disks = p.extractBasicDisks(moVM.Config.Hardware.Device)
-			vmModel.NetworkCards = p.extractBasicNetworkCards(moVM.Config.Hardware.Device)
+			vmModel.NetworkCards = p.extractBasicNetworkCards(moVM.Config.Hardware.Device, portgroups)
+		}
+		for _, disk := range vmModel.Disks {
+			if disk.Path != "" {
+				diskDirs[datastoreDir(disk.Path)] = vmModel.Name
+			}
+		}
+
+		// Triggered alarms
+		if len(moVM.TriggeredAlarmState) > 0 {
+			vmModel.Metadata["triggered_alarms"] = p.triggeredAlarms(ctx, moVM.TriggeredAlarmState)
+		}
+
+		p.dumpRaw("vms", moVM.Reference(), redactVM(moVM), vmModel)
+
+		// Templates are kept for DiscoverTemplates and only listed as VMs on request
+		if vmModel.Config.Template {
+			p.templates = append(p.templates, templateFromVM(vmModel))
+			if !filters.IncludeTemplates {
+				continue
+			}
 		}
 
 		// Apply filters
@@ -246,6 +622,8 @@ func (p *vmwareProvider) DiscoverVMs(ctx context.Context, filters VMDiscoveryFil
 		}
 	}
 
+	resolveLinkedClones(vmList, diskDirs)
+
 	return vmList, nil
 }
 
@@ -256,6 +634,16 @@ func (p *vmwareProvider) vmMatchesFilters(vm models.VirtualMachine, filters VMDi
 		return false
 	}
 
+	if filters.Host != "" && !strings.EqualFold(vm.Host, filters.Host) {
+		return false
+	}
+	if filters.ResourcePool != "" && !strings.EqualFold(vm.ResourcePool, filters.ResourcePool) {
+		return false
+	}
+	if filters.Folder != "" && !inFolder(vm.Folder, filters.Folder) {
+		return false
+	}
+
 	// Name filter
 	if len(filters.Names) > 0 {
 		nameMatch := false
@@ -273,6 +661,22 @@ func (p *vmwareProvider) vmMatchesFilters(vm models.VirtualMachine, filters VMDi
 	return true
 }
 
+// inFolder reports whether a VM's inventory folder, e.g. /DC0/vm/Prod/Web,
+// is folder or one of its subfolders. folder may be a full inventory path or
+// relative to the datacenter's VM folder, e.g. Prod.
+func inFolder(vmFolder, folder string) bool {
+	folder = strings.Trim(folder, "/")
+	// Full paths go through the datacenter's vm folder
+	if strings.Contains("/"+folder+"/", "/vm/") {
+		return vmFolder == "/"+folder || strings.HasPrefix(vmFolder, "/"+folder+"/")
+	}
+	relative := ""
+	if i := strings.Index(vmFolder, "/vm/"); i >= 0 {
+		relative = vmFolder[i+len("/vm/"):]
+	}
+	return relative == folder || strings.HasPrefix(relative, folder+"/")
+}
+
 // extractBasicDisks extracts basic disk information from VM hardware devices
 func (p *vmwareProvider) extractBasicDisks(devices []types.BaseVirtualDevice) []models.Disk {
 	var disks []models.Disk
@@ -281,7 +685,7 @@ func (p *vmwareProvider) extractBasicDisks(devices []types.BaseVirtualDevice) []
 		if disk, ok := device.(*types.VirtualDisk); ok {
 			diskModel := models.Disk{
 				ID:   fmt.Sprintf("%d", disk.Key),
-				Size: disk.CapacityInKB / 1024 / 1024, // Convert to GB
+				Size: units.FromKiB(disk.CapacityInKB).CeilGiB(),
 				Type: "unknown",
 			}
 
@@ -290,6 +694,8 @@ func (p *vmwareProvider) extractBasicDisks(devices []types.BaseVirtualDevice) []
 				switch b := backing.(type) {
 				case *types.VirtualDiskFlatVer2BackingInfo:
 					diskModel.Path = b.FileName
+					diskModel.Mode = b.DiskMode
+					diskModel.ChainDepth, diskModel.ParentPath = diskChain(b)
 					if b.ThinProvisioned != nil && *b.ThinProvisioned {
 						diskModel.Type = "thin"
 					} else {
@@ -301,6 +707,18 @@ func (p *vmwareProvider) extractBasicDisks(devices []types.BaseVirtualDevice) []
 				case *types.VirtualDiskSparseVer2BackingInfo:
 					diskModel.Path = b.FileName
 					diskModel.Type = "sparse"
+				case *types.VirtualDiskRawDiskMappingVer1BackingInfo:
+					diskModel.Path = b.FileName
+					diskModel.Mode = b.DiskMode
+					diskModel.LUN = b.LunUuid
+					if b.CompatibilityMode == string(types.VirtualDiskCompatibilityModePhysicalMode) {
+						diskModel.Type = models.DiskTypeRDMPhysical
+					} else {
+						diskModel.Type = models.DiskTypeRDMVirtual
+					}
+					if b.Datastore != nil {
+						diskModel.Datastore = b.Datastore.Value
+					}
 				}
 			}
 
@@ -316,17 +734,86 @@ func (p *vmwareProvider) extractBasicDisks(devices []types.BaseVirtualDevice) []
 	return disks
 }
 
+// diskChain walks a disk's delta backing chain, returning how many delta
+// disks sit on top of the base disk and the first backing file outside the
+// disk's own folder. Snapshots keep their deltas in the VM's folder, so a
+// parent elsewhere means the disk is a linked clone of another VM's disk.
+func diskChain(backing *types.VirtualDiskFlatVer2BackingInfo) (int, string) {
+	depth := 0
+	parentPath := ""
+	dir := datastoreDir(backing.FileName)
+	for parent := backing.Parent; parent != nil; parent = parent.Parent {
+		depth++
+		if parentPath == "" && datastoreDir(parent.FileName) != dir {
+			parentPath = parent.FileName
+		}
+	}
+	return depth, parentPath
+}
+
+// datastoreDir returns the folder of a "[datastore] folder/file.vmdk" path,
+// qualified by its datastore
+func datastoreDir(fileName string) string {
+	var p object.DatastorePath
+	if !p.FromString(fileName) {
+		return path.Dir(fileName)
+	}
+	return p.Datastore + "/" + path.Dir(p.Path)
+}
+
+// resolveLinkedClones names the VM or template each linked clone's disks are
+// based on, from the folder holding the parent disk. dirs maps VM folders to
+// VM names; an unknown folder is recorded as the parent disk path itself.
+func resolveLinkedClones(vms []models.VirtualMachine, dirs map[string]string) {
+	for i := range vms {
+		for _, disk := range vms[i].Disks {
+			if disk.ParentPath == "" {
+				continue
+			}
+			parent, ok := dirs[datastoreDir(disk.ParentPath)]
+			if !ok || parent == vms[i].Name {
+				parent = disk.ParentPath
+			}
+			vms[i].Config.LinkedCloneOf = parent
+			break
+		}
+	}
+}
+
+// countSnapshots counts the snapshots in a snapshot tree
+func countSnapshots(tree []types.VirtualMachineSnapshotTree) int {
+	count := len(tree)
+	for _, snapshot := range tree {
+		count += countSnapshots(snapshot.ChildSnapshotList)
+	}
+	return count
+}
+
+// countPassthroughDevices counts PCI, vGPU and USB devices passed through
+// from the host
+func countPassthroughDevices(devices []types.BaseVirtualDevice) int {
+	count := 0
+	for _, device := range devices {
+		switch device.(type) {
+		case *types.VirtualPCIPassthrough, *types.VirtualUSB:
+			count++
+		}
+	}
+	return count
+}
+
 // extractBasicNetworkCards extracts basic network card information
-func (p *vmwareProvider) extractBasicNetworkCards(devices []types.BaseVirtualDevice) []models.NetworkCard {
+func (p *vmwareProvider) extractBasicNetworkCards(devices []types.BaseVirtualDevice, portgroups map[string]string) []models.NetworkCard {
 	var networkCards []models.NetworkCard
 
 	for _, device := range devices {
 		if nic, ok := device.(types.BaseVirtualEthernetCard); ok {
 			card := models.NetworkCard{
 				ID:           fmt.Sprintf("%d", nic.GetVirtualEthernetCard().Key),
+				Key:          nic.GetVirtualEthernetCard().Key,
 				Connected:    nic.GetVirtualEthernetCard().Connectable.Connected,
 				StartConnect: nic.GetVirtualEthernetCard().Connectable.StartConnected,
-				Type:         "vmxnet3", // Default
+				Type:         models.NICTypeUnknown,
 				Network:      "VM Network", // Default
 			}
 
@@ -335,18 +822,26 @@ func (p *vmwareProvider) extractBasicNetworkCards(devices []types.BaseVirtualDev
 				card.MACAddress = mac
 			}
 
+			if slot, ok := nic.GetVirtualEthernetCard().SlotInfo.(*types.VirtualDevicePciBusSlotInfo); ok {
+				card.PCISlot = slot.PciSlotNumber
+			}
+
 			// Get network adapter type
 			switch nic.(type) {
 			case *types.VirtualVmxnet3:
-				card.Type = "vmxnet3"
+				card.Type = models.NICTypeVmxnet3
+			case *types.VirtualVmxnet2:
+				card.Type = models.NICTypeVmxnet2
+			case *types.VirtualVmxnet:
+				card.Type = models.NICTypeVmxnet
 			case *types.VirtualE1000:
-				card.Type = "e1000"
+				card.Type = models.NICTypeE1000
 			case *types.VirtualE1000e:
-				card.Type = "e1000e"
+				card.Type = models.NICTypeE1000e
 			case *types.VirtualPCNet32:
-				card.Type = "pcnet32"
-			default:
-				card.Type = "vmxnet3"
+				card.Type = models.NICTypePCNet32
+			case *types.VirtualSriovEthernetCard:
+				card.Type = models.NICTypeSRIOV
 			}
 
 			// Try to get network backing
@@ -355,8 +850,14 @@ func (p *vmwareProvider) extractBasicNetworkCards(devices []types.BaseVirtualDev
 				case *types.VirtualEthernetCardNetworkBackingInfo:
 					card.Network = b.DeviceName
 				case *types.VirtualEthernetCardDistributedVirtualPortBackingInfo:
-					if b.Port.PortgroupKey != "" {
-						card.Network = b.Port.PortgroupKey
+					if key := b.Port.PortgroupKey; key != "" {
+						card.Metadata = map[string]interface{}{"portgroup_key": key}
+						if name, ok := portgroups[key]; ok {
+							card.Network = name
+						} else {
+							p.log.Debug("Unknown distributed portgroup, keeping its key as the network", "portgroup_key", key)
+							card.Network = key
+						}
 					}
 				}
 			}
@@ -365,9 +866,89 @@ func (p *vmwareProvider) extractBasicNetworkCards(devices []types.BaseVirtualDev
 		}
 	}
 
+	// The device list order isn't stable between calls; device keys are
+	sort.SliceStable(networkCards, func(i, j int) bool {
+		return networkCards[i].Key < networkCards[j].Key
+	})
+
 	return networkCards
 }
 
+// portgroupNames maps distributed portgroup keys to portgroup names, fetching
+// every portgroup in one property collector call. On failure it logs a
+// warning and returns nil, leaving NICs named by key.
+func (p *vmwareProvider) portgroupNames(ctx context.Context) map[string]string {
+	networks, err := p.finder.NetworkList(ctx, "*")
+	if err != nil {
+		p.log.Warn("Failed to list networks, distributed portgroups stay named by key", "error", err)
+		return nil
+	}
+
+	var refs []types.ManagedObjectReference
+	for _, network := range networks {
+		if pg, ok := network.(*object.DistributedVirtualPortgroup); ok {
+			refs = append(refs, pg.Reference())
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	var portgroups []mo.DistributedVirtualPortgroup
+	pc := property.DefaultCollector(p.client.Client)
+	err = p.withRetry(ctx, "retrieve portgroup names", func() error {
+		var err error
+		portgroups, err = retrieveExisting[mo.DistributedVirtualPortgroup](ctx, p, pc, "portgroup", refs, []string{"name", "key"})
+		return err
+	})
+	if err != nil {
+		p.log.Warn("Failed to retrieve portgroup names, distributed portgroups stay named by key", "error", err)
+		return nil
+	}
+
+	names := make(map[string]string, len(portgroups))
+	for _, pg := range portgroups {
+		names[pg.Key] = pg.Name
+	}
+	return names
+}
+
+// clusterScope returns the network and datastore references visible from a cluster's hosts
+func (p *vmwareProvider) clusterScope(ctx context.Context, cluster string) (map[string]bool, map[string]bool, error) {
+	cc, err := p.finder.ClusterComputeResource(ctx, cluster)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find cluster %s: %w", cluster, err)
+	}
+
+	var moCluster mo.ClusterComputeResource
+	if err := cc.Properties(ctx, cc.Reference(), []string{"host"}, &moCluster); err != nil {
+		return nil, nil, fmt.Errorf("failed to get cluster hosts: %w", err)
+	}
+
+	networks := make(map[string]bool)
+	datastores := make(map[string]bool)
+	if len(moCluster.Host) == 0 {
+		return networks, datastores, nil
+	}
+
+	pc := property.DefaultCollector(p.client.Client)
+	hosts, err := retrieveExisting[mo.HostSystem](ctx, p, pc, "host", moCluster.Host, []string{"network", "datastore"})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get host networks and datastores: %w", err)
+	}
+
+	for _, host := range hosts {
+		for _, ref := range host.Network {
+			networks[ref.Value] = true
+		}
+		for _, ref := range host.Datastore {
+			datastores[ref.Value] = true
+		}
+	}
+
+	return networks, datastores, nil
+}
+
 // DiscoverNetworks discovers network configurations
 func (p *vmwareProvider) DiscoverNetworks(ctx context.Context) ([]models.Network, error) {
 	// Find all networks
@@ -379,6 +960,10 @@ func (p *vmwareProvider) DiscoverNetworks(ctx context.Context) ([]models.Network
 	var networkList []models.Network
 
 	for _, network := range networks {
+		if p.scopeNetworks != nil && !p.scopeNetworks[network.Reference().Value] {
+			continue
+		}
+
 		net := models.Network{
 			ID:       network.Reference().Value,
 			Name:     network.GetInventoryPath(),
@@ -400,6 +985,23 @@ func (p *vmwareProvider) DiscoverNetworks(ctx context.Context) ([]models.Network
 		networkList = append(networkList, net)
 	}
 
+	// VLAN tagging and security policies live on the port group
+	// configuration, read in bulk
+	byID := make(map[string]*models.Network, len(networkList))
+	var distributed, standard []types.ManagedObjectReference
+	for i := range networkList {
+		network := &networkList[i]
+		byID[network.ID] = network
+		switch network.Type {
+		case "distributed":
+			distributed = append(distributed, types.ManagedObjectReference{Type: "DistributedVirtualPortgroup", Value: network.ID})
+		case "standard":
+			standard = append(standard, types.ManagedObjectReference{Type: "Network", Value: network.ID})
+		}
+	}
+	p.distributedPortgroups(ctx, distributed, byID)
+	p.standardPortgroups(ctx, standard, byID)
+
 	return networkList, nil
 }
 
@@ -413,14 +1015,20 @@ func (p *vmwareProvider) DiscoverStorage(ctx context.Context) ([]models.Storage,
 
 	var storageList []models.Storage
 
+	props := []string{"name", "summary", "host", "info"}
+	if p.config.WithAlarms {
+		props = append(props, "triggeredAlarmState")
+	}
+
+	var refs []types.ManagedObjectReference
 	for _, ds := range datastores {
-		var moDS mo.Datastore
-		err := ds.Properties(ctx, ds.Reference(), []string{"name", "summary"}, &moDS)
-		if err != nil {
-			p.log.Error("Failed to get datastore properties", "datastore", ds.Name(), "error", err)
+		if p.scopeDatastores != nil && !p.scopeDatastores[ds.Reference().Value] {
 			continue
 		}
+		refs = append(refs, ds.Reference())
+	}
 
+	for _, moDS := range retrieveObjects[mo.Datastore](ctx, p, "datastore", refs, props) {
 		storage := models.Storage{
 			ID:         moDS.Reference().Value,
 			Name:       moDS.Name,
@@ -429,9 +1037,11 @@ func (p *vmwareProvider) DiscoverStorage(ctx context.Context) ([]models.Storage,
 		}
 
 		if moDS.Summary.Capacity > 0 {
-			storage.Capacity = moDS.Summary.Capacity / 1024 / 1024 / 1024 // Convert to GB
-			storage.FreeSpace = moDS.Summary.FreeSpace / 1024 / 1024 / 1024
-			storage.UsedSpace = storage.Capacity - storage.FreeSpace
+			capacity := units.FromBytes(moDS.Summary.Capacity)
+			free := units.FromBytes(moDS.Summary.FreeSpace)
+			storage.Capacity = capacity.ToGiB()
+			storage.FreeSpace = free.ToGiB()
+			storage.UsedSpace = (capacity - free).ToGiB()
 		}
 
 		// Get datastore type
@@ -439,24 +1049,505 @@ func (p *vmwareProvider) DiscoverStorage(ctx context.Context) ([]models.Storage,
 			storage.Type = moDS.Summary.Type
 		}
 
+		if info, ok := moDS.Info.(*types.VmfsDatastoreInfo); ok && info.Vmfs != nil && info.Vmfs.Ssd != nil {
+			storage.SSD = *info.Vmfs.Ssd
+		}
+
+		// Hosts that mount the datastore, which VMs can only move between
+		// datastores their host sees
+		if len(moDS.Host) > 0 {
+			hosts := make([]string, 0, len(moDS.Host))
+			for _, mount := range moDS.Host {
+				hosts = append(hosts, p.hostName(ctx, mount.Key))
+			}
+			sort.Strings(hosts)
+			storage.Metadata["hosts"] = hosts
+		}
+
+		// Triggered alarms
+		if len(moDS.TriggeredAlarmState) > 0 {
+			storage.Metadata["triggered_alarms"] = p.triggeredAlarms(ctx, moDS.TriggeredAlarmState)
+		}
+
+		p.dumpRaw("datastores", moDS.Reference(), moDS, storage)
+
 		storageList = append(storageList, storage)
 	}
 
 	return storageList, nil
 }
 
-// Simplified implementations for interface compliance
+// triggeredAlarms converts triggered alarm states into metadata entries
+func (p *vmwareProvider) triggeredAlarms(ctx context.Context, states []types.AlarmState) []map[string]interface{} {
+	var alarms []map[string]interface{}
+
+	for _, state := range states {
+		alarm := map[string]interface{}{
+			"alarm":  p.alarmName(ctx, state.Alarm),
+			"status": string(state.OverallStatus),
+			"time":   state.Time,
+		}
+		if state.Acknowledged != nil {
+			alarm["acknowledged"] = *state.Acknowledged
+		}
+		alarms = append(alarms, alarm)
+	}
+
+	return alarms
+}
+
+// hostName resolves a host system reference to its name, falling back to
+// the reference. All host names are fetched in one call on first use.
+func (p *vmwareProvider) hostName(ctx context.Context, ref types.ManagedObjectReference) string {
+	if p.hostNames == nil {
+		p.hostNames = make(map[string]string)
+
+		var hosts []mo.HostSystem
+		err := p.withRetry(ctx, "retrieve host names", func() error {
+			var err error
+			hosts, err = retrieveView[mo.HostSystem](ctx, p, p.client.ServiceContent.RootFolder, "HostSystem", []string{"name"})
+			return err
+		})
+		if err != nil {
+			p.log.Debug("Failed to resolve host names", "error", err)
+		}
+		for _, host := range hosts {
+			p.hostNames[host.Reference().Value] = host.Name
+		}
+	}
+
+	if name, ok := p.hostNames[ref.Value]; ok {
+		return name
+	}
+	return ref.Value
+}
+
+// poolName resolves the name of a resource pool or vApp, falling back to
+// its reference
+func (p *vmwareProvider) poolName(ctx context.Context, ref types.ManagedObjectReference) string {
+	if p.poolNames == nil {
+		p.poolNames = make(map[string]string)
+
+		var pools []mo.ResourcePool
+		err := p.withRetry(ctx, "retrieve resource pool names", func() error {
+			var err error
+			pools, err = retrieveView[mo.ResourcePool](ctx, p, p.client.ServiceContent.RootFolder, "ResourcePool", []string{"name"})
+			return err
+		})
+		if err != nil {
+			p.log.Debug("Failed to resolve resource pool names", "error", err)
+		}
+		for _, pool := range pools {
+			p.poolNames[pool.Reference().Value] = pool.Name
+		}
+	}
+
+	if name, ok := p.poolNames[ref.Value]; ok {
+		return name
+	}
+	return ref.Value
+}
+
+// alarmName resolves the name of an alarm definition, falling back to its reference
+func (p *vmwareProvider) alarmName(ctx context.Context, ref types.ManagedObjectReference) string {
+	if p.alarmNames == nil {
+		p.alarmNames = make(map[string]string)
+	}
+	if name, ok := p.alarmNames[ref.Value]; ok {
+		return name
+	}
+
+	name := ref.Value
+	var moAlarm mo.Alarm
+	pc := property.DefaultCollector(p.client.Client)
+	if err := pc.RetrieveOne(ctx, ref, []string{"info.name"}, &moAlarm); err != nil {
+		p.log.Debug("Failed to resolve alarm name", "alarm", ref.Value, "error", err)
+	} else if moAlarm.Info.Name != "" {
+		name = moAlarm.Info.Name
+	}
+
+	p.alarmNames[ref.Value] = name
+	return name
+}
+
+// alarmSummary counts resources with active alarms
+func alarmSummary(infra *models.Infrastructure) map[string]interface{} {
+	vmsWithAlarms := 0
+	datastoresWithAlarms := 0
+	total := 0
+
+	for _, vm := range infra.VirtualMachines {
+		if alarms, ok := vm.Metadata["triggered_alarms"].([]map[string]interface{}); ok {
+			vmsWithAlarms++
+			total += len(alarms)
+		}
+	}
+	for _, ds := range infra.Storage {
+		if alarms, ok := ds.Metadata["triggered_alarms"].([]map[string]interface{}); ok {
+			datastoresWithAlarms++
+			total += len(alarms)
+		}
+	}
+
+	return map[string]interface{}{
+		"vms_with_alarms":        vmsWithAlarms,
+		"datastores_with_alarms": datastoresWithAlarms,
+		"total_triggered":        total,
+	}
+}
+
+// DiscoverPermissions discovers permissions and resolves their principals
+func (p *vmwareProvider) DiscoverPermissions(ctx context.Context) ([]models.Permission, error) {
+	authManager := object.NewAuthorizationManager(p.client.Client)
+
+	perms, err := authManager.RetrieveAllPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve permissions: %w", err)
+	}
+
+	roles, err := authManager.RoleList(ctx)
+	if err != nil {
+		p.log.Warn("Failed to retrieve roles, role names will be omitted", "error", err)
+	}
+
+	// Principals usually appear on many objects, so resolve each only once
+	directories := p.principalDirectories(ctx)
+	defer func() {
+		for _, directory := range directories {
+			directory.Close(ctx)
+		}
+	}()
+
+	type resolution struct {
+		principal *principal
+		source    string
+	}
+	resolved := make(map[string]resolution)
+	var permissionList []models.Permission
+
+	for _, perm := range perms {
+		domain, name := splitPrincipal(perm.Principal)
+
+		permission := models.Permission{
+			Principal:     perm.Principal,
+			Name:          name,
+			Domain:        domain,
+			PrincipalType: "user",
+			RoleID:        perm.RoleId,
+			Propagate:     perm.Propagate,
+		}
+		if perm.Group {
+			permission.PrincipalType = "group"
+		}
+		if perm.Entity != nil {
+			permission.Entity = perm.Entity.Value
+			permission.EntityType = perm.Entity.Type
+		}
+		if role := roles.ById(perm.RoleId); role != nil {
+			permission.Role = role.Name
+		}
+
+		result, ok := resolved[perm.Principal]
+		if !ok {
+			result.principal, result.source = p.resolvePrincipal(ctx, directories, domain, name, perm.Group)
+			resolved[perm.Principal] = result
+		}
+
+		if result.principal != nil {
+			permission.Resolved = true
+			permission.Source = result.source
+			permission.FullName = result.principal.FullName
+			permission.Enabled = result.principal.Enabled
+			permission.MemberCount = result.principal.Members
+			if result.principal.Group {
+				permission.PrincipalType = "group"
+			} else {
+				permission.PrincipalType = "user"
+			}
+		} else {
+			permission.Note = "principal could not be resolved in any directory"
+		}
+
+		permissionList = append(permissionList, permission)
+	}
+
+	return permissionList, nil
+}
+
+// splitPrincipal splits a principal in DOMAIN\name or name@domain form
+func splitPrincipal(principal string) (string, string) {
+	if i := strings.Index(principal, "\\"); i >= 0 {
+		return principal[:i], principal[i+1:]
+	}
+	if i := strings.LastIndex(principal, "@"); i >= 0 {
+		return principal[i+1:], principal[:i]
+	}
+	return "", principal
+}
+
+// orphanFindings flags orphaned or inaccessible VMs and disks whose backing
+// datastore is missing or inaccessible
+func orphanFindings(infra *models.Infrastructure) []models.Finding {
+	var findings []models.Finding
+
+	// Disk checks need the full datastore list, which cluster scoping trims
+	scoped, _ := infra.Metadata["cluster_scoped"].(bool)
+	checkDisks := len(infra.Storage) > 0 && !scoped
+
+	datastores := make(map[string]models.Storage)
+	for _, ds := range infra.Storage {
+		datastores[ds.ID] = ds
+	}
+
+	for _, vm := range infra.VirtualMachines {
+		state, _ := vm.Metadata["connection_state"].(string)
+		switch types.VirtualMachineConnectionState(state) {
+		case types.VirtualMachineConnectionStateOrphaned,
+			types.VirtualMachineConnectionStateInaccessible,
+			types.VirtualMachineConnectionStateInvalid:
+			findings = append(findings, models.Finding{
+				Severity: "warning",
+				Rule:     "orphaned-vm",
+				Resource: vm.Name,
+				Message:  fmt.Sprintf("VM connection state is %s", state),
+			})
+		}
+
+		if !checkDisks {
+			continue
+		}
+
+		for _, disk := range vm.Disks {
+			ds, ok := datastores[disk.Datastore]
+			switch {
+			case !ok:
+				findings = append(findings, models.Finding{
+					Severity: "warning",
+					Rule:     "orphaned-disk",
+					Resource: fmt.Sprintf("%s/%s", vm.Name, disk.ID),
+					Message:  fmt.Sprintf("Disk %s references datastore %s, which was not found", disk.Path, disk.Datastore),
+				})
+			case !ds.Accessible:
+				findings = append(findings, models.Finding{
+					Severity: "warning",
+					Rule:     "orphaned-disk",
+					Resource: fmt.Sprintf("%s/%s", vm.Name, disk.ID),
+					Message:  fmt.Sprintf("Disk %s is on inaccessible datastore %s", disk.Path, ds.Name),
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// rdmFindings reports every VM disk backed by a raw device mapping
+func rdmFindings(vms []models.VirtualMachine) []models.Finding {
+	var findings []models.Finding
+
+	for _, vm := range vms {
+		for _, disk := range vm.Disks {
+			if !disk.IsRDM() {
+				continue
+			}
+			findings = append(findings, models.Finding{
+				Severity: "warning",
+				Rule:     "rdm-disk",
+				Resource: fmt.Sprintf("%s/%s", vm.Name, disk.ID),
+				Message:  fmt.Sprintf("Disk is a %s raw device mapping of LUN %s and must be re-mapped manually", disk.Type, disk.LUN),
+			})
+		}
+	}
+
+	return findings
+}
+
+// permissionFindings flags principals whose grants violate common access
+// policies: disabled or unresolvable accounts, and users granted roles
+// directly instead of through a group. Each principal is reported once,
+// listing the objects it holds roles on.
+func permissionFindings(permissions []models.Permission) []models.Finding {
+	type grants struct {
+		perm      models.Permission
+		resources []string
+	}
+	byPrincipal := make(map[string]*grants)
+	var principals []string
+
+	for _, perm := range permissions {
+		resource := perm.Entity
+		if perm.EntityType != "" {
+			resource = fmt.Sprintf("%s:%s", perm.EntityType, perm.Entity)
+		}
+		g, ok := byPrincipal[perm.Principal]
+		if !ok {
+			g = &grants{perm: perm}
+			byPrincipal[perm.Principal] = g
+			principals = append(principals, perm.Principal)
+		}
+		g.resources = append(g.resources, fmt.Sprintf("%s (%s)", resource, perm.Role))
+	}
+	sort.Strings(principals)
+
+	var findings []models.Finding
+	for _, principal := range principals {
+		g := byPrincipal[principal]
+		perm := g.perm
+		on := fmt.Sprintf("%d object(s): %s", len(g.resources), strings.Join(g.resources, ", "))
+
+		switch {
+		case !perm.Resolved:
+			findings = append(findings, models.Finding{
+				Severity: "warning",
+				Rule:     "permission-unresolved-principal",
+				Resource: principal,
+				Message:  fmt.Sprintf("%s could not be resolved (account may be removed) but holds roles on %s", principal, on),
+			})
+		case perm.Enabled != nil && !*perm.Enabled:
+			findings = append(findings, models.Finding{
+				Severity: "warning",
+				Rule:     "permission-disabled-account",
+				Resource: principal,
+				Message:  fmt.Sprintf("Disabled account %s holds roles on %s", principal, on),
+			})
+		}
 
+		if perm.PrincipalType == "user" {
+			findings = append(findings, models.Finding{
+				Severity: "info",
+				Rule:     "permission-user-grant",
+				Resource: principal,
+				Message:  fmt.Sprintf("User %s is granted roles directly instead of through a group on %s", principal, on),
+			})
+		}
+	}
+
+	return findings
+}
+
+// DiscoverResourcePools discovers the resource pools and vApps of the
+// configured cluster, or datacenter, with one property retrieval
 func (p *vmwareProvider) DiscoverResourcePools(ctx context.Context) ([]models.ResourcePool, error) {
-	p.log.Info("Resource pool discovery simplified for initial build")
-	return []models.ResourcePool{}, nil
+	root := p.client.ServiceContent.RootFolder
+	switch {
+	case p.config.Cluster != "":
+		cc, err := p.finder.ClusterComputeResource(ctx, p.config.Cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find cluster %s: %w", p.config.Cluster, err)
+		}
+		root = cc.Reference()
+	default:
+		dc, err := p.datacenterRoot(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		root = dc
+	}
+
+	var moPools []mo.ResourcePool
+	err := p.withRetry(ctx, "retrieve resource pool properties", func() error {
+		var err error
+		moPools, err = retrieveView[mo.ResourcePool](ctx, p, root, "ResourcePool", []string{"name", "parent", "config", "resourcePool", "vm"})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource pool properties: %w", err)
+	}
+
+	poolNames := make(map[string]string, len(moPools))
+	for _, moPool := range moPools {
+		poolNames[moPool.Reference().Value] = moPool.Name
+	}
+
+	var pools []models.ResourcePool
+	for _, moPool := range moPools {
+		pool := models.ResourcePool{
+			ID:       moPool.Reference().Value,
+			Name:     moPool.Name,
+			CPU:      resourceAllocation(moPool.Config.CpuAllocation),
+			Memory:   resourceAllocation(moPool.Config.MemoryAllocation),
+			Metadata: make(map[string]interface{}),
+		}
+		if moPool.Reference().Type == "VirtualApp" {
+			pool.Metadata["vapp"] = true
+		}
+
+		// A cluster's or host's root pool has the compute resource as parent
+		if moPool.Parent != nil {
+			if name, ok := poolNames[moPool.Parent.Value]; ok {
+				pool.Parent = name
+			} else {
+				pool.Metadata["root"] = true
+			}
+		}
+		for _, child := range moPool.ResourcePool {
+			if name, ok := poolNames[child.Value]; ok {
+				pool.Children = append(pool.Children, name)
+			}
+		}
+		for _, vm := range moPool.Vm {
+			if name, ok := p.vmNames[vm.Value]; ok {
+				pool.VMs = append(pool.VMs, name)
+			} else {
+				pool.VMs = append(pool.VMs, vm.Value)
+			}
+		}
+		sort.Strings(pool.Children)
+		sort.Strings(pool.VMs)
+
+		pools = append(pools, pool)
+	}
+
+	return pools, nil
 }
 
+// resourceAllocation converts a pool's CPU or memory allocation; unset
+// values read as 0 and a limit of -1 means unlimited
+func resourceAllocation(info types.ResourceAllocationInfo) models.ResourceAllocation {
+	var allocation models.ResourceAllocation
+	if info.Reservation != nil {
+		allocation.Reservation = *info.Reservation
+	}
+	if info.Limit != nil {
+		allocation.Limit = *info.Limit
+	}
+	if info.Shares != nil {
+		allocation.Shares = string(info.Shares.Level)
+		allocation.SharesValue = info.Shares.Shares
+	}
+	return allocation
+}
+
+// DiscoverTemplates returns the templates seen by DiscoverVMs, running VM
+// discovery first if it hasn't been
 func (p *vmwareProvider) DiscoverTemplates(ctx context.Context) ([]models.Template, error) {
-	p.log.Info("Template discovery simplified for initial build")
-	return []models.Template{}, nil
+	if p.templates == nil {
+		if _, err := p.DiscoverVMs(ctx, VMDiscoveryFilters{}); err != nil {
+			return nil, err
+		}
+	}
+	return p.templates, nil
+}
+
+// templateFromVM converts a discovered template VM to a template
+func templateFromVM(vm models.VirtualMachine) models.Template {
+	return models.Template{
+		ID:              vm.ID,
+		Name:            vm.Name,
+		OperatingSystem: vm.OperatingSystem,
+		CPUs:            vm.CPUs,
+		Memory:          vm.Memory,
+		Disks:           vm.Disks,
+		NetworkCards:    vm.NetworkCards,
+		Annotations:     vm.Annotations,
+		Tags:            vm.Tags,
+		Folder:          vm.Folder,
+		Metadata:        vm.Metadata,
+	}
 }
 
+// Simplified implementations for interface compliance
+
 func (p *vmwareProvider) DiscoverDatacenters(ctx context.Context) ([]models.Datacenter, error) {
 	dcs, err := p.finder.DatacenterList(ctx, "*")
 	if err != nil {
@@ -477,14 +1568,160 @@ func (p *vmwareProvider) DiscoverDatacenters(ctx context.Context) ([]models.Data
 	return datacenterList, nil
 }
 
+// DiscoverClusters discovers the clusters of a datacenter, or of the
+// connected one when datacenter is empty, including their EVC mode
 func (p *vmwareProvider) DiscoverClusters(ctx context.Context, datacenter string) ([]models.Cluster, error) {
-	p.log.Info("Cluster discovery simplified for initial build")
-	return []models.Cluster{}, nil
+	root, err := p.datacenterRoot(ctx, datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	var moClusters []mo.ClusterComputeResource
+	err = p.withRetry(ctx, "retrieve cluster properties", func() error {
+		var err error
+		moClusters, err = retrieveView[mo.ClusterComputeResource](ctx, p, root, "ClusterComputeResource", []string{"name", "summary", "host", "configurationEx"})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster properties: %w", err)
+	}
+
+	var clusters []models.Cluster
+	for _, moCluster := range moClusters {
+		cluster := models.Cluster{
+			ID:         moCluster.Reference().Value,
+			Name:       moCluster.Name,
+			Datacenter: datacenter,
+			Metadata:   make(map[string]interface{}),
+		}
+		if cluster.Datacenter == "" {
+			cluster.Datacenter = p.config.Datacenter
+		}
+
+		if summary, ok := moCluster.Summary.(*types.ClusterComputeResourceSummary); ok {
+			cluster.EVCMode = summary.CurrentEVCModeKey
+			cluster.TotalCPU = int64(summary.TotalCpu)
+			cluster.TotalMemory = units.FromBytes(summary.TotalMemory).ToMiB()
+		}
+		if config, ok := moCluster.ConfigurationEx.(*types.ClusterConfigInfoEx); ok {
+			cluster.DRS = config.DrsConfig.Enabled != nil && *config.DrsConfig.Enabled
+			cluster.HA = config.DasConfig.Enabled != nil && *config.DasConfig.Enabled
+		}
+		for _, host := range moCluster.Host {
+			cluster.Hosts = append(cluster.Hosts, p.hostName(ctx, host))
+		}
+		sort.Strings(cluster.Hosts)
+
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
 }
 
+// DiscoverHosts discovers the hosts of a cluster, or every host of the
+// connected datacenter when cluster is empty, including their CPU model and
+// the newest EVC mode they support
 func (p *vmwareProvider) DiscoverHosts(ctx context.Context, cluster string) ([]models.Host, error) {
-	p.log.Info("Host discovery simplified for initial build")
-	return []models.Host{}, nil
+	root, err := p.datacenterRoot(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var moHosts []mo.HostSystem
+	err = p.withRetry(ctx, "retrieve host properties", func() error {
+		var err error
+		moHosts, err = retrieveView[mo.HostSystem](ctx, p, root, "HostSystem", []string{"name", "summary", "parent"})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host properties: %w", err)
+	}
+
+	// Standalone hosts have a plain compute resource as parent
+	var parents []types.ManagedObjectReference
+	for _, moHost := range moHosts {
+		if moHost.Parent != nil && moHost.Parent.Type == "ClusterComputeResource" {
+			parents = append(parents, *moHost.Parent)
+		}
+	}
+	clusterNames := make(map[string]string)
+	if len(parents) > 0 {
+		var moClusters []mo.ClusterComputeResource
+		pc := property.DefaultCollector(p.client.Client)
+		err := p.withRetry(ctx, "retrieve cluster names", func() error {
+			var err error
+			moClusters, err = retrieveExisting[mo.ClusterComputeResource](ctx, p, pc, "cluster", parents, []string{"name"})
+			return err
+		})
+		if err != nil {
+			p.log.Debug("Failed to resolve host cluster names", "error", err)
+		}
+		for _, moCluster := range moClusters {
+			clusterNames[moCluster.Reference().Value] = moCluster.Name
+		}
+	}
+
+	var hosts []models.Host
+	for _, moHost := range moHosts {
+		host := models.Host{
+			ID:         moHost.Reference().Value,
+			Name:       moHost.Name,
+			Type:       "ESXi",
+			Datacenter: p.config.Datacenter,
+			Metadata:   make(map[string]interface{}),
+		}
+		if moHost.Parent != nil {
+			host.Cluster = clusterNames[moHost.Parent.Value]
+		}
+		if cluster != "" && host.Cluster != cluster {
+			continue
+		}
+
+		summary := moHost.Summary
+		host.State = string(summary.Runtime.PowerState)
+		host.ConnectionState = string(summary.Runtime.ConnectionState)
+		host.CPUGeneration = summary.MaxEVCModeKey
+		if summary.CurrentEVCModeKey != "" {
+			host.Metadata["current_evc_mode"] = summary.CurrentEVCModeKey
+		}
+		if summary.Config.Product != nil {
+			host.Version = summary.Config.Product.Version
+		}
+		if hw := summary.Hardware; hw != nil {
+			host.CPUModel = hw.CpuModel
+			host.CPUCores = int(hw.NumCpuCores)
+			host.CPU.Total = int64(hw.CpuMhz) * int64(hw.NumCpuCores)
+			host.Memory.Total = units.FromBytes(hw.MemorySize).ToMiB()
+		}
+		host.CPU.Used = int64(summary.QuickStats.OverallCpuUsage)
+		host.Memory.Used = int64(summary.QuickStats.OverallMemoryUsage)
+		host.CPU.Available = host.CPU.Total - host.CPU.Used
+		host.Memory.Available = host.Memory.Total - host.Memory.Used
+
+		hosts = append(hosts, host)
+	}
+
+	return hosts, nil
+}
+
+// datacenterRoot returns the named datacenter, the connected one when name
+// is empty, or the inventory root when no datacenter is configured
+func (p *vmwareProvider) datacenterRoot(ctx context.Context, name string) (types.ManagedObjectReference, error) {
+	switch {
+	case name != "":
+		dc, err := p.finder.Datacenter(ctx, name)
+		if err != nil {
+			return types.ManagedObjectReference{}, fmt.Errorf("failed to find datacenter %s: %w", name, err)
+		}
+		return dc.Reference(), nil
+	case p.config.Datacenter != "":
+		dc, err := p.finder.DefaultDatacenter(ctx)
+		if err != nil {
+			return types.ManagedObjectReference{}, fmt.Errorf("failed to find datacenter %s: %w", p.config.Datacenter, err)
+		}
+		return dc.Reference(), nil
+	}
+	return p.client.ServiceContent.RootFolder, nil
 }
 
 // GetName returns the provider name
@@ -501,3 +1738,24 @@ func (p *vmwareProvider) IsConnected() bool {
 func (p *vmwareProvider) Connect(ctx context.Context) error {
 	return fmt.Errorf("use ConnectVMware(ctx, config.VMwareConfig) instead")
 }
+
+// guestIPAddresses lists the addresses VMware Tools reports on the guest's
+// NICs, without duplicates or link-local addresses
+func guestIPAddresses(guest *types.GuestInfo) []string {
+	var addresses []string
+	seen := make(map[string]bool)
+	for _, nic := range guest.Net {
+		if nic.IpConfig == nil {
+			continue
+		}
+		for _, address := range nic.IpConfig.IpAddress {
+			ip := net.ParseIP(address.IpAddress)
+			if ip == nil || ip.IsLinkLocalUnicast() || seen[address.IpAddress] {
+				continue
+			}
+			seen[address.IpAddress] = true
+			addresses = append(addresses, address.IpAddress)
+		}
+	}
+	return addresses
+}
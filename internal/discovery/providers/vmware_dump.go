@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// rawDump pairs a raw govmomi object with the model converted from it
+type rawDump struct {
+	Raw   interface{} `json:"raw"`
+	Model interface{} `json:"model"`
+}
+
+// credentialKeys mark extraConfig entries whose values are redacted in raw dumps
+var credentialKeys = []string{"password", "passwd", "secret", "token", "credential"}
+
+// dumpRaw writes a raw object and its converted model to <dump_raw>/<kind>/<ref>.json
+// when raw dumping is enabled; failures are logged and never stop discovery
+func (p *vmwareProvider) dumpRaw(kind string, ref types.ManagedObjectReference, raw, model interface{}) {
+	if p.config.DumpRaw == "" {
+		return
+	}
+
+	dir := filepath.Join(p.config.DumpRaw, kind)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		p.log.Warn("Failed to create raw dump directory", "dir", dir, "error", err)
+		return
+	}
+
+	data, err := json.MarshalIndent(rawDump{Raw: raw, Model: model}, "", "  ")
+	if err != nil {
+		p.log.Warn("Failed to encode raw object", "type", kind, "ref", ref.Value, "error", err)
+		return
+	}
+
+	filename := filepath.Join(dir, ref.Value+".json")
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		p.log.Warn("Failed to write raw object", "file", filename, "error", err)
+	}
+}
+
+// redactVM returns a copy of a VM whose credential-like extraConfig values are redacted
+func redactVM(vm mo.VirtualMachine) mo.VirtualMachine {
+	if vm.Config == nil || len(vm.Config.ExtraConfig) == 0 {
+		return vm
+	}
+
+	config := *vm.Config
+	config.ExtraConfig = make([]types.BaseOptionValue, len(vm.Config.ExtraConfig))
+	for i, option := range vm.Config.ExtraConfig {
+		value := option.GetOptionValue()
+		if isCredentialKey(value.Key) {
+			option = &types.OptionValue{Key: value.Key, Value: "[redacted]"}
+		}
+		config.ExtraConfig[i] = option
+	}
+	vm.Config = &config
+
+	return vm
+}
+
+// isCredentialKey reports whether an option key looks like it holds a credential
+func isCredentialKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, marker := range credentialKeys {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/vmware/govmomi/vapi/library"
+	"github.com/vmware/govmomi/vapi/rest"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+	"valhalla/internal/version"
+)
+
+// contentLibraryMinVersion is the first vCenter version with content libraries
+const contentLibraryMinVersion = "6.0"
+
+// contentLibrariesAvailable reports whether the endpoint has a content
+// library service. When the version couldn't be read discovery is attempted
+// and fails on its own if the service is missing.
+func (p *vmwareProvider) contentLibrariesAvailable() bool {
+	if p.version.APIType == "HostAgent" {
+		return false
+	}
+	return !version.Below(p.version.Version, contentLibraryMinVersion)
+}
+
+// DiscoverContentLibraries lists the content libraries and their items
+// through the vAPI REST endpoint
+func (p *vmwareProvider) DiscoverContentLibraries(ctx context.Context) ([]models.ContentLibrary, error) {
+	rc := rest.NewClient(p.client.Client)
+	if err := rc.Login(ctx, url.UserPassword(p.config.Username, p.config.Password)); err != nil {
+		return nil, fmt.Errorf("failed to log in to the content library service: %w", err)
+	}
+	defer func() {
+		if err := rc.Logout(ctx); err != nil {
+			p.log.Debug("Failed to log out of content library service", "error", err)
+		}
+	}()
+
+	manager := library.NewManager(rc)
+
+	var libraries []library.Library
+	err := p.withRetry(ctx, "list content libraries", func() error {
+		var err error
+		libraries, err = manager.GetLibraries(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content libraries: %w", err)
+	}
+
+	result := make([]models.ContentLibrary, 0, len(libraries))
+	for _, lib := range libraries {
+		contentLibrary := models.ContentLibrary{
+			ID:          lib.ID,
+			Name:        lib.Name,
+			Type:        lib.Type,
+			Description: lib.Description,
+		}
+		for _, backing := range lib.Storage {
+			if backing.DatastoreID != "" {
+				contentLibrary.Datastores = append(contentLibrary.Datastores, backing.DatastoreID)
+			}
+		}
+		if lib.Subscription != nil {
+			contentLibrary.SubscriptionURL = lib.Subscription.SubscriptionURL
+		}
+
+		var items []library.Item
+		err := p.withRetry(ctx, "list content library items", func() error {
+			var err error
+			items, err = manager.GetLibraryItems(ctx, lib.ID)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list items of content library %s: %w", lib.Name, err)
+		}
+		for _, item := range items {
+			contentLibrary.Items = append(contentLibrary.Items, models.ContentLibraryItem{
+				ID:   item.ID,
+				Name: item.Name,
+				Type: item.Type,
+				Size: units.FromBytes(item.Size).ToMiB(),
+			})
+		}
+
+		result = append(result, contentLibrary)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/vapi/library"
+	"github.com/vmware/govmomi/vapi/rest"
+)
+
+// createLibrary adds a local library holding one ISO to the simulator
+func createLibrary(t *testing.T, p *vmwareProvider) string {
+	t.Helper()
+	ctx := context.Background()
+
+	ds, err := find.NewFinder(p.client.Client).DefaultDatastore(ctx)
+	if err != nil {
+		t.Fatalf("find datastore: %v", err)
+	}
+
+	rc := rest.NewClient(p.client.Client)
+	if err := rc.Login(ctx, url.UserPassword(p.config.Username, p.config.Password)); err != nil {
+		t.Fatalf("vAPI login: %v", err)
+	}
+	manager := library.NewManager(rc)
+
+	id, err := manager.CreateLibrary(ctx, library.Library{
+		Name:    "isos",
+		Type:    "LOCAL",
+		Storage: []library.StorageBackings{{DatastoreID: ds.Reference().Value, Type: "DATASTORE"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateLibrary: %v", err)
+	}
+	if _, err := manager.CreateLibraryItem(ctx, library.Item{Name: "ubuntu-22.04.iso", Type: "iso", LibraryID: id}); err != nil {
+		t.Fatalf("CreateLibraryItem: %v", err)
+	}
+	return ds.Reference().Value
+}
+
+func TestDiscoverContentLibraries(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	cfg.WithContentLibraries = true
+
+	tests := []struct {
+		name    string
+		version string
+		want    int
+	}{
+		{name: "supported", version: "6.5.0", want: 1},
+		{name: "unknown version is not gated", version: "", want: 1},
+		{name: "before 6.0", version: "5.5.0", want: 0},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := connectedVMware(t, cfg)
+			var datastore string
+			if i == 0 {
+				datastore = createLibrary(t, p)
+			}
+			p.version.Version = tt.version
+
+			infra, err := p.Discover(context.Background())
+			if err != nil {
+				t.Fatalf("Discover: %v", err)
+			}
+			if _, failed := infra.Metadata["phase_warnings"]; failed {
+				t.Errorf("a discovery phase failed: %v", infra.Metadata["phase_warnings"])
+			}
+			if len(infra.ContentLibraries) != tt.want {
+				t.Fatalf("got %d content libraries, want %d", len(infra.ContentLibraries), tt.want)
+			}
+			if tt.want == 0 || datastore == "" {
+				return
+			}
+
+			lib := infra.ContentLibraries[0]
+			if lib.Name != "isos" || lib.Type != "LOCAL" || len(lib.Datastores) != 1 || lib.Datastores[0] != datastore {
+				t.Errorf("library = %+v", lib)
+			}
+			if len(lib.Items) != 1 || lib.Items[0].Name != "ubuntu-22.04.iso" || lib.Items[0].Type != "iso" {
+				t.Errorf("items = %+v", lib.Items)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"valhalla/internal/models"
+)
+
+// trunkAllVLANs is the VLAN ID a standard port group uses to pass every
+// VLAN through to the guest
+const trunkAllVLANs = 4095
+
+// distributedPortgroups sets the VLAN and security policy of distributed
+// port groups from their default port config, and their switch and its MTU.
+// Trunk ranges go to Metadata["vlan_trunk"] and private VLANs to
+// Metadata["pvlan_id"], leaving VLAN at 0.
+func (p *vmwareProvider) distributedPortgroups(ctx context.Context, refs []types.ManagedObjectReference, networks map[string]*models.Network) {
+	switches := make(map[types.ManagedObjectReference][]*models.Network)
+	for _, pg := range retrieveObjects[mo.DistributedVirtualPortgroup](ctx, p, "portgroup", refs, []string{"config.defaultPortConfig", "config.distributedVirtualSwitch"}) {
+		network := networks[pg.Reference().Value]
+		if network == nil {
+			continue
+		}
+		if pg.Config.DistributedVirtualSwitch != nil {
+			switches[*pg.Config.DistributedVirtualSwitch] = append(switches[*pg.Config.DistributedVirtualSwitch], network)
+		}
+		setting, ok := pg.Config.DefaultPortConfig.(*types.VMwareDVSPortSetting)
+		if !ok {
+			continue
+		}
+
+		if security := setting.SecurityPolicy; security != nil {
+			network.SecurityPolicy = &models.SecurityPolicy{
+				AllowPromiscuous: boolPolicy(security.AllowPromiscuous),
+				MACChanges:       boolPolicy(security.MacChanges),
+				ForgedTransmits:  boolPolicy(security.ForgedTransmits),
+			}
+		}
+
+		switch vlan := setting.Vlan.(type) {
+		case *types.VmwareDistributedVirtualSwitchVlanIdSpec:
+			network.VLAN = int(vlan.VlanId)
+		case *types.VmwareDistributedVirtualSwitchTrunkVlanSpec:
+			network.Metadata["vlan_trunk"] = vlanRanges(vlan.VlanId)
+		case *types.VmwareDistributedVirtualSwitchPvlanSpec:
+			network.Metadata["pvlan_id"] = int(vlan.PvlanId)
+		}
+	}
+
+	// The MTU is a setting of the switch rather than the port group
+	switchRefs := make([]types.ManagedObjectReference, 0, len(switches))
+	for ref := range switches {
+		switchRefs = append(switchRefs, ref)
+	}
+	for _, dvs := range retrieveObjects[mo.DistributedVirtualSwitch](ctx, p, "distributed switch", switchRefs, []string{"name", "config"}) {
+		info, ok := dvs.Config.(*types.VMwareDVSConfigInfo)
+		for _, network := range switches[dvs.Reference()] {
+			network.VSwitch = dvs.Name
+			if ok {
+				network.MTU = int(info.MaxMtu)
+			}
+		}
+	}
+}
+
+// boolPolicy returns the value of a distributed port setting, false if unset
+func boolPolicy(policy *types.BoolPolicy) bool {
+	return policy != nil && policy.Value != nil && *policy.Value
+}
+
+// hostPortgroup is a standard port group's configuration on one host
+type hostPortgroup struct {
+	vlan     int
+	vswitch  string
+	mtu      int
+	security models.SecurityPolicy
+}
+
+// standardPortgroups sets the VLAN, switch, MTU and security policy of
+// standard port groups, which are configured on each host the port group
+// exists on. Hosts disagreeing are reported rather than averaged: the VLAN
+// and MTU keep the lowest value with every host's in Metadata["host_vlans"]
+// and Metadata["host_mtus"], and the security policy accepts whatever any
+// host accepts, with every host's in Metadata["host_security_policies"].
+// The hosts carrying the port group are listed in Metadata["hosts"].
+func (p *vmwareProvider) standardPortgroups(ctx context.Context, refs []types.ManagedObjectReference, networks map[string]*models.Network) {
+	if len(refs) == 0 {
+		return
+	}
+	hosts, err := p.finder.HostSystemList(ctx, "*")
+	if err != nil {
+		p.log.Warn("Failed to list hosts for port group settings", "error", err)
+		return
+	}
+	hostRefs := make([]types.ManagedObjectReference, 0, len(hosts))
+	for _, host := range hosts {
+		hostRefs = append(hostRefs, host.Reference())
+	}
+
+	wanted := make(map[types.ManagedObjectReference]bool, len(refs))
+	for _, ref := range refs {
+		wanted[ref] = true
+	}
+
+	hostSettings := make(map[*models.Network]map[string]hostPortgroup)
+	for _, host := range retrieveObjects[mo.HostSystem](ctx, p, "host", hostRefs, []string{"name", "network", "config.network.portgroup", "config.network.vswitch"}) {
+		if host.Config == nil || host.Config.Network == nil {
+			continue
+		}
+		// Port group specs only carry the name; match them to the
+		// host's networks in scope
+		byName := make(map[string]*models.Network)
+		for _, ref := range host.Network {
+			if network := networks[ref.Value]; network != nil && wanted[ref] {
+				byName[path.Base(network.Name)] = network
+			}
+		}
+		vswitches := make(map[string]types.HostVirtualSwitch, len(host.Config.Network.Vswitch))
+		for _, vswitch := range host.Config.Network.Vswitch {
+			vswitches[vswitch.Key] = vswitch
+		}
+
+		for _, pg := range host.Config.Network.Portgroup {
+			network := byName[pg.Spec.Name]
+			if network == nil {
+				continue
+			}
+			settings := hostPortgroup{vlan: int(pg.Spec.VlanId)}
+			if vswitch, ok := vswitches[pg.Vswitch]; ok {
+				settings.vswitch = vswitch.Name
+				settings.mtu = int(vswitch.Mtu)
+			}
+			// The computed policy includes what the port group inherits
+			// from its switch
+			if security := pg.ComputedPolicy.Security; security != nil {
+				settings.security = models.SecurityPolicy{
+					AllowPromiscuous: security.AllowPromiscuous != nil && *security.AllowPromiscuous,
+					MACChanges:       security.MacChanges != nil && *security.MacChanges,
+					ForgedTransmits:  security.ForgedTransmits != nil && *security.ForgedTransmits,
+				}
+			}
+			if hostSettings[network] == nil {
+				hostSettings[network] = make(map[string]hostPortgroup)
+			}
+			hostSettings[network][host.Name] = settings
+		}
+	}
+
+	for network, settings := range hostSettings {
+		names := make([]string, 0, len(settings))
+		for host := range settings {
+			names = append(names, host)
+		}
+		sort.Strings(names)
+		network.Metadata["hosts"] = names
+
+		vlan, mtu := -1, -1
+		var vswitch string
+		var security models.SecurityPolicy
+		var first *hostPortgroup
+		vlanConflict, mtuConflict, vswitchConflict, securityConflict := false, false, false, false
+		for _, host := range settings {
+			host := host
+			if first == nil {
+				first, vswitch = &host, host.vswitch
+			} else {
+				vlanConflict = vlanConflict || host.vlan != first.vlan
+				mtuConflict = mtuConflict || host.mtu != first.mtu
+				vswitchConflict = vswitchConflict || host.vswitch != first.vswitch
+				securityConflict = securityConflict || host.security != first.security
+			}
+			if vlan < 0 || host.vlan < vlan {
+				vlan = host.vlan
+			}
+			if host.mtu > 0 && (mtu < 0 || host.mtu < mtu) {
+				mtu = host.mtu
+			}
+			security.AllowPromiscuous = security.AllowPromiscuous || host.security.AllowPromiscuous
+			security.MACChanges = security.MACChanges || host.security.MACChanges
+			security.ForgedTransmits = security.ForgedTransmits || host.security.ForgedTransmits
+		}
+
+		if vlanConflict {
+			vlans := make(map[string]int, len(settings))
+			for host, s := range settings {
+				vlans[host] = s.vlan
+			}
+			network.Metadata["host_vlans"] = vlans
+			p.log.Warn("Hosts disagree on a port group's VLAN", "network", network.Name, "vlan", vlan)
+		}
+		if mtuConflict {
+			mtus := make(map[string]int, len(settings))
+			for host, s := range settings {
+				mtus[host] = s.mtu
+			}
+			network.Metadata["host_mtus"] = mtus
+			p.log.Warn("Hosts disagree on a port group's switch MTU", "network", network.Name, "mtu", mtu)
+		}
+		if securityConflict {
+			policies := make(map[string]models.SecurityPolicy, len(settings))
+			for host, s := range settings {
+				policies[host] = s.security
+			}
+			network.Metadata["host_security_policies"] = policies
+			p.log.Warn("Hosts disagree on a port group's security policy", "network", network.Name)
+		}
+
+		if !vswitchConflict {
+			network.VSwitch = vswitch
+		}
+		if mtu > 0 {
+			network.MTU = mtu
+		}
+		network.SecurityPolicy = &security
+
+		if vlan == trunkAllVLANs {
+			network.Metadata["vlan_trunk"] = []string{"0-4094"}
+			continue
+		}
+		network.VLAN = vlan
+	}
+}
+
+// vlanRanges formats trunk ranges as "100" or "100-199", in order
+func vlanRanges(ranges []types.NumericRange) []string {
+	sorted := append([]types.NumericRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	formatted := make([]string, 0, len(sorted))
+	for _, r := range sorted {
+		if r.Start == r.End {
+			formatted = append(formatted, fmt.Sprint(r.Start))
+		} else {
+			formatted = append(formatted, fmt.Sprintf("%d-%d", r.Start, r.End))
+		}
+	}
+	return formatted
+}
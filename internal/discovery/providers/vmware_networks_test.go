@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestDistributedPortgroupNICs(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(t, cfg)
+
+	infra, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+	if warnings, failed := infra.Metadata["phase_warnings"]; failed {
+		t.Fatalf("a discovery phase failed: %v", warnings)
+	}
+
+	portgroups := make(map[string]string)
+	for _, network := range infra.Networks {
+		if network.Type == "distributed" {
+			portgroups[network.ID] = network.Name
+			if network.VSwitch != "DVS0" {
+				t.Errorf("portgroup %s is on switch %q, want DVS0", network.Name, network.VSwitch)
+			}
+		}
+	}
+
+	nics := 0
+	for _, vm := range infra.VirtualMachines {
+		for _, nic := range vm.NetworkCards {
+			key, _ := nic.Metadata["portgroup_key"].(string)
+			if key == "" {
+				continue
+			}
+			nics++
+			if nic.Network == key || !strings.HasSuffix(portgroups[key], "/"+nic.Network) {
+				t.Errorf("%s NIC %s: network = %q, want the name of portgroup %s (%s)", vm.Name, nic.ID, nic.Network, key, portgroups[key])
+			}
+		}
+	}
+	if nics == 0 {
+		t.Fatal("no NICs on distributed portgroups were discovered")
+	}
+}
+
+func TestUnknownPortgroupKeepsKey(t *testing.T) {
+	nic := &types.VirtualVmxnet3{VirtualVmxnet: types.VirtualVmxnet{VirtualEthernetCard: types.VirtualEthernetCard{
+		VirtualDevice: types.VirtualDevice{
+			Key: 4000,
+			Backing: &types.VirtualEthernetCardDistributedVirtualPortBackingInfo{
+				Port: types.DistributedVirtualSwitchPortConnection{PortgroupKey: "dvportgroup-99"},
+			},
+			Connectable: &types.VirtualDeviceConnectInfo{},
+		},
+	}}}
+
+	p := &vmwareProvider{log: testLogger()}
+	cards := p.extractBasicNetworkCards([]types.BaseVirtualDevice{nic}, map[string]string{"dvportgroup-13": "Prod"})
+	if len(cards) != 1 || cards[0].Network != "dvportgroup-99" {
+		t.Errorf("cards = %+v, want the NIC named by its portgroup key", cards)
+	}
+}
+
+func TestStandardPortgroupHosts(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(t, cfg)
+
+	infra, err := p.Discover(context.Background())
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	found := false
+	for _, network := range infra.Networks {
+		if network.Type != "standard" || !strings.HasSuffix(network.Name, "/VM Network") {
+			continue
+		}
+		found = true
+		hosts, _ := network.Metadata["hosts"].([]string)
+		if len(hosts) == 0 || len(hosts) != len(infra.Hosts) {
+			t.Errorf("%s is on hosts %v, want all %d hosts", network.Name, hosts, len(infra.Hosts))
+		}
+		if network.VSwitch != "vSwitch0" || network.SecurityPolicy == nil {
+			t.Errorf("%s: switch %q, security policy %v", network.Name, network.VSwitch, network.SecurityPolicy)
+		}
+	}
+	if !found {
+		t.Fatal("VM Network was not discovered")
+	}
+}
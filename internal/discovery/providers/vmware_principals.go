@@ -0,0 +1,362 @@
+package providers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/vmware/govmomi/ssoadmin"
+	ssotypes "github.com/vmware/govmomi/ssoadmin/types"
+	"github.com/vmware/govmomi/sts"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"valhalla/internal/config"
+)
+
+// principal is what a directory knows about a permission's principal.
+// Enabled and Members are nil when the directory cannot tell.
+type principal struct {
+	Group    bool
+	FullName string
+	Enabled  *bool
+	Members  *int
+}
+
+// principalDirectory resolves principals for permission discovery
+type principalDirectory interface {
+	// Name identifies the directory in Permission.Source
+	Name() string
+	Lookup(ctx context.Context, domain, name string, group bool) (*principal, error)
+	Close(ctx context.Context)
+}
+
+// principalDirectories returns the directories to resolve principals in, in
+// the order they are asked: the LDAP server from providers.vmware.ldap when
+// configured, the SSO admin API when the account can use it, and the vCenter
+// user directory, which knows users and groups but not their state.
+func (p *vmwareProvider) principalDirectories(ctx context.Context) []principalDirectory {
+	var directories []principalDirectory
+
+	if p.config.LDAP.URL != "" {
+		directory, err := newLDAPDirectory(p.config.LDAP)
+		if err != nil {
+			p.log.Warn("Failed to connect to the LDAP server, principals will be resolved without it", "url", p.config.LDAP.URL, "error", err)
+		} else {
+			directories = append(directories, directory)
+		}
+	}
+
+	directory, err := p.ssoDirectory(ctx)
+	if err != nil {
+		p.log.Debug("SSO admin API not available, enabled state and group members will come from LDAP only", "error", err)
+	} else {
+		directories = append(directories, directory)
+	}
+
+	return append(directories, &vcenterDirectory{p: p})
+}
+
+// resolvePrincipal asks each directory in turn, returning the first match
+// and the directory that had it
+func (p *vmwareProvider) resolvePrincipal(ctx context.Context, directories []principalDirectory, domain, name string, group bool) (*principal, string) {
+	for _, directory := range directories {
+		result, err := directory.Lookup(ctx, domain, name, group)
+		if err != nil {
+			p.log.Debug("Failed to resolve principal", "directory", directory.Name(), "principal", name, "domain", domain, "error", err)
+			continue
+		}
+		return result, directory.Name()
+	}
+	return nil, ""
+}
+
+// vcenterDirectory searches the vCenter user directory, which every
+// account can read
+type vcenterDirectory struct {
+	p *vmwareProvider
+}
+
+func (d *vcenterDirectory) Name() string { return "vcenter" }
+
+func (d *vcenterDirectory) Close(context.Context) {}
+
+func (d *vcenterDirectory) Lookup(ctx context.Context, domain, name string, group bool) (*principal, error) {
+	result, err := d.p.lookupPrincipal(ctx, domain, name, group)
+	if err != nil {
+		return nil, err
+	}
+	return &principal{Group: result.Group, FullName: result.FullName}, nil
+}
+
+// lookupPrincipal searches the vCenter user directory for an exact principal match
+func (p *vmwareProvider) lookupPrincipal(ctx context.Context, domain, name string, group bool) (*types.UserSearchResult, error) {
+	if p.client.ServiceContent.UserDirectory == nil {
+		return nil, fmt.Errorf("user directory not available")
+	}
+
+	req := types.RetrieveUserGroups{
+		This:       *p.client.ServiceContent.UserDirectory,
+		Domain:     domain,
+		SearchStr:  name,
+		ExactMatch: true,
+		FindUsers:  !group,
+		FindGroups: group,
+	}
+
+	res, err := methods.RetrieveUserGroups(ctx, p.client.Client, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res.Returnval {
+		result := r.GetUserSearchResult()
+		_, resultName := splitPrincipal(result.Principal)
+		if strings.EqualFold(resultName, name) {
+			return result, nil
+		}
+	}
+
+	return nil, fmt.Errorf("principal not found")
+}
+
+// ssoAdminDirectory asks the SSO admin API, which knows whether accounts
+// are disabled and who belongs to groups, but needs an SSO administrator
+type ssoAdminDirectory struct {
+	client *ssoadmin.Client
+}
+
+// ssoDirectory logs in to the SSO admin API with a token issued for the
+// discovery account
+func (p *vmwareProvider) ssoDirectory(ctx context.Context) (*ssoAdminDirectory, error) {
+	vc := p.client.Client
+	client, err := ssoadmin.NewClient(ctx, vc)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := sts.NewClient(ctx, vc)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := tokens.Issue(ctx, sts.TokenRequest{
+		Certificate: vc.Certificate(),
+		Userinfo:    url.UserPassword(p.config.Username, p.config.Password),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue SSO token: %w", err)
+	}
+
+	header := soap.Header{Security: signer}
+	if err := client.Login(client.WithHeader(ctx, header)); err != nil {
+		return nil, fmt.Errorf("failed to log in to the SSO admin API: %w", err)
+	}
+	return &ssoAdminDirectory{client: client}, nil
+}
+
+func (d *ssoAdminDirectory) Name() string { return "sso" }
+
+func (d *ssoAdminDirectory) Close(ctx context.Context) {
+	_ = d.client.Logout(ctx)
+}
+
+func (d *ssoAdminDirectory) Lookup(ctx context.Context, domain, name string, group bool) (*principal, error) {
+	id := name
+	if domain != "" {
+		id = name + "@" + domain
+	}
+
+	if group {
+		g, err := d.client.FindGroup(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if g == nil {
+			return nil, fmt.Errorf("principal not found")
+		}
+		users, err := d.client.FindUsersInGroup(ctx, id, "")
+		if err != nil {
+			return nil, err
+		}
+		groups, err := d.client.FindGroupsInGroup(ctx, id, "")
+		if err != nil {
+			return nil, err
+		}
+		members := len(users) + len(groups)
+		return &principal{Group: true, FullName: g.Details.Description, Members: &members}, nil
+	}
+
+	if user, err := d.client.FindPersonUser(ctx, id); err == nil && user != nil {
+		return &principal{FullName: personName(user.Details), Enabled: enabled(!user.Disabled)}, nil
+	}
+	if user, err := d.client.FindSolutionUser(ctx, id); err == nil && user != nil {
+		return &principal{FullName: user.Details.Description, Enabled: enabled(!user.Disabled)}, nil
+	}
+
+	// Users from external identity sources have no details in SSO
+	user, err := d.client.FindUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fmt.Errorf("principal not found")
+	}
+	return &principal{FullName: user.Description}, nil
+}
+
+// personName joins a person user's first and last names
+func personName(details ssotypes.AdminPersonDetails) string {
+	return strings.TrimSpace(details.FirstName + " " + details.LastName)
+}
+
+// ldapDirectory searches the LDAP server behind vCenter's identity source,
+// for accounts that cannot use the SSO admin API
+type ldapDirectory struct {
+	conn   ldapConn
+	baseDN string
+}
+
+// ldapConn is the part of an LDAP connection principal lookups use
+type ldapConn interface {
+	Search(request *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close()
+}
+
+// ldapGroupClasses and ldapUserClasses are the object classes of group and
+// user entries in Active Directory and OpenLDAP, in lower case
+var (
+	ldapGroupClasses = []string{"group", "groupofnames", "groupofuniquenames", "posixgroup"}
+	ldapUserClasses  = []string{"user", "person", "inetorgperson", "posixaccount"}
+)
+
+// ldapAttributes are read from every matching entry: names, the classes
+// telling users from groups, Active Directory's and OpenLDAP's account
+// state, and group members
+var ldapAttributes = []string{
+	"cn", "displayName", "objectClass",
+	"userAccountControl", "nsAccountLock", "pwdAccountLockedTime",
+	"member", "uniqueMember", "memberUid",
+}
+
+// adAccountDisabled is the ACCOUNTDISABLE flag of userAccountControl
+const adAccountDisabled = 0x2
+
+// newLDAPDirectory connects and binds to the configured LDAP server
+func newLDAPDirectory(cfg config.LDAPConfig) (*ldapDirectory, error) {
+	if cfg.BaseDN == "" {
+		return nil, fmt.Errorf("providers.vmware.ldap.base_dn is required")
+	}
+
+	conn, err := ldap.DialURL(cfg.URL, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: cfg.Insecure}))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.BindDN != "" {
+		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+	} else {
+		err = conn.UnauthenticatedBind("")
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind: %w", err)
+	}
+	return &ldapDirectory{conn: conn, baseDN: cfg.BaseDN}, nil
+}
+
+func (d *ldapDirectory) Name() string { return "ldap" }
+
+func (d *ldapDirectory) Close(context.Context) {
+	d.conn.Close()
+}
+
+func (d *ldapDirectory) Lookup(_ context.Context, _ string, name string, group bool) (*principal, error) {
+	res, err := d.conn.Search(ldap.NewSearchRequest(
+		d.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		ldapFilter(name, group), ldapAttributes, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("principal not found")
+	}
+
+	// A user and a group may share a name; only the kind asked for counts
+	var matches []*principal
+	for _, entry := range res.Entries {
+		if result := ldapPrincipal(entry); result.Group == group {
+			matches = append(matches, result)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("principal not found")
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("%d entries match %s", len(matches), name)
+	}
+	return matches[0], nil
+}
+
+// ldapFilter matches entries named name by account name, uid or common
+// name, limited to group entries when group is set and user entries
+// otherwise
+func ldapFilter(name string, group bool) string {
+	classes := ldapUserClasses
+	if group {
+		classes = ldapGroupClasses
+	}
+	var classFilter strings.Builder
+	for _, class := range classes {
+		classFilter.WriteString("(objectClass=" + class + ")")
+	}
+
+	escaped := ldap.EscapeFilter(name)
+	return fmt.Sprintf("(&(|%s)(|(sAMAccountName=%s)(uid=%s)(cn=%s)))", classFilter.String(), escaped, escaped, escaped)
+}
+
+// ldapPrincipal reads a principal from a directory entry
+func ldapPrincipal(entry *ldap.Entry) *principal {
+	result := &principal{FullName: entry.GetAttributeValue("displayName")}
+	if result.FullName == "" {
+		result.FullName = entry.GetAttributeValue("cn")
+	}
+
+	for _, class := range entry.GetAttributeValues("objectClass") {
+		for _, groupClass := range ldapGroupClasses {
+			if strings.EqualFold(class, groupClass) {
+				result.Group = true
+			}
+		}
+	}
+	if result.Group {
+		members := len(entry.GetAttributeValues("member")) +
+			len(entry.GetAttributeValues("uniqueMember")) +
+			len(entry.GetAttributeValues("memberUid"))
+		result.Members = &members
+		return result
+	}
+
+	// Without any of these the directory does not say, and Enabled stays nil
+	switch {
+	case entry.GetAttributeValue("userAccountControl") != "":
+		flags, err := strconv.Atoi(entry.GetAttributeValue("userAccountControl"))
+		if err == nil {
+			result.Enabled = enabled(flags&adAccountDisabled == 0)
+		}
+	case entry.GetAttributeValue("nsAccountLock") != "":
+		result.Enabled = enabled(!strings.EqualFold(entry.GetAttributeValue("nsAccountLock"), "true"))
+	case entry.GetAttributeValue("pwdAccountLockedTime") != "":
+		result.Enabled = enabled(false)
+	}
+	return result
+}
+
+// enabled returns a pointer to an account's enabled state
+func enabled(state bool) *bool {
+	return &state
+}
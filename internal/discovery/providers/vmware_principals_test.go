@@ -0,0 +1,179 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"valhalla/internal/models"
+)
+
+func TestLDAPPrincipal(t *testing.T) {
+	tests := []struct {
+		name        string
+		attributes  map[string][]string
+		wantGroup   bool
+		wantEnabled *bool
+		wantMembers *int
+	}{
+		{
+			name:        "active directory user",
+			attributes:  map[string][]string{"objectClass": {"top", "person", "user"}, "userAccountControl": {"512"}},
+			wantEnabled: enabled(true),
+		},
+		{
+			name:        "disabled active directory user",
+			attributes:  map[string][]string{"objectClass": {"user"}, "userAccountControl": {"514"}},
+			wantEnabled: enabled(false),
+		},
+		{
+			name:       "user without account state",
+			attributes: map[string][]string{"objectClass": {"inetOrgPerson"}},
+		},
+		{
+			name:       "unparsable userAccountControl",
+			attributes: map[string][]string{"objectClass": {"user"}, "userAccountControl": {"n/a"}},
+		},
+		{
+			name:        "locked openldap user",
+			attributes:  map[string][]string{"objectClass": {"inetOrgPerson"}, "nsAccountLock": {"TRUE"}},
+			wantEnabled: enabled(false),
+		},
+		{
+			name:        "active directory group",
+			attributes:  map[string][]string{"objectClass": {"group"}, "member": {"CN=a", "CN=b", "CN=c"}},
+			wantGroup:   true,
+			wantMembers: intPtr(3),
+		},
+		{
+			name:        "posix group",
+			attributes:  map[string][]string{"objectClass": {"posixGroup"}, "memberUid": {"alice"}},
+			wantGroup:   true,
+			wantMembers: intPtr(1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ldapPrincipal(ldap.NewEntry("CN=test", tt.attributes))
+			if got.Group != tt.wantGroup {
+				t.Errorf("Group = %v, want %v", got.Group, tt.wantGroup)
+			}
+			if !equalPtr(got.Enabled, tt.wantEnabled) {
+				t.Errorf("Enabled = %v, want %v", deref(got.Enabled), deref(tt.wantEnabled))
+			}
+			if !equalPtr(got.Members, tt.wantMembers) {
+				t.Errorf("Members = %v, want %v", deref(got.Members), deref(tt.wantMembers))
+			}
+		})
+	}
+}
+
+// fakeLDAP answers searches from a fixed set of entries, matching an entry
+// when the filter names its cn and one of its object classes. With
+// allClasses it ignores the object classes, returning users and groups alike.
+type fakeLDAP struct {
+	entries    []*ldap.Entry
+	allClasses bool
+}
+
+func (f *fakeLDAP) Search(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	res := &ldap.SearchResult{}
+	for _, entry := range f.entries {
+		if !strings.Contains(request.Filter, "(cn="+ldap.EscapeFilter(entry.GetAttributeValue("cn"))+")") {
+			continue
+		}
+		matched := f.allClasses
+		for _, class := range entry.GetAttributeValues("objectClass") {
+			matched = matched || strings.Contains(request.Filter, "(objectClass="+strings.ToLower(class)+")")
+		}
+		if matched {
+			res.Entries = append(res.Entries, entry)
+		}
+	}
+	return res, nil
+}
+
+func (f *fakeLDAP) Close() {}
+
+func TestLDAPLookupUserAndGroupWithSameName(t *testing.T) {
+	entries := []*ldap.Entry{
+		ldap.NewEntry("CN=backup,OU=Service Accounts,DC=corp,DC=example", map[string][]string{
+			"cn": {"backup"}, "displayName": {"Backup Service"}, "objectClass": {"top", "person", "user"}, "userAccountControl": {"514"},
+		}),
+		ldap.NewEntry("CN=backup,OU=Groups,DC=corp,DC=example", map[string][]string{
+			"cn": {"backup"}, "objectClass": {"top", "group"}, "member": {"CN=a", "CN=b"},
+		}),
+	}
+
+	for _, allClasses := range []bool{false, true} {
+		name := "filtered by class"
+		if allClasses {
+			name = "server returns both"
+		}
+		t.Run(name, func(t *testing.T) {
+			directory := &ldapDirectory{conn: &fakeLDAP{entries: entries, allClasses: allClasses}, baseDN: "DC=corp,DC=example"}
+
+			user, err := directory.Lookup(context.Background(), "CORP", "backup", false)
+			if err != nil {
+				t.Fatalf("Lookup user: %v", err)
+			}
+			if user.Group || user.FullName != "Backup Service" || !equalPtr(user.Enabled, enabled(false)) {
+				t.Errorf("user = %+v, enabled %v", user, deref(user.Enabled))
+			}
+
+			group, err := directory.Lookup(context.Background(), "CORP", "backup", true)
+			if err != nil {
+				t.Fatalf("Lookup group: %v", err)
+			}
+			if !group.Group || group.Enabled != nil || !equalPtr(group.Members, intPtr(2)) {
+				t.Errorf("group = %+v, members %v", group, deref(group.Members))
+			}
+		})
+	}
+}
+
+func TestPermissionFindingsOncePerPrincipal(t *testing.T) {
+	permissions := []models.Permission{
+		{Principal: `CORP\alice`, PrincipalType: "user", Resolved: true, Enabled: enabled(true), Role: "Admin", Entity: "group-d1", EntityType: "Folder"},
+		{Principal: `CORP\alice`, PrincipalType: "user", Resolved: true, Enabled: enabled(true), Role: "ReadOnly", Entity: "vm-1", EntityType: "VirtualMachine"},
+		{Principal: `CORP\bob`, PrincipalType: "user", Resolved: true, Enabled: enabled(false), Role: "Admin", Entity: "group-d1", EntityType: "Folder"},
+		{Principal: `CORP\ops`, PrincipalType: "group", Resolved: true, MemberCount: intPtr(4), Role: "Admin", Entity: "group-d1", EntityType: "Folder"},
+		{Principal: `CORP\gone`, PrincipalType: "group", Role: "Admin", Entity: "group-d1", EntityType: "Folder"},
+	}
+
+	rules := make(map[string][]string)
+	for _, finding := range permissionFindings(permissions) {
+		rules[finding.Rule] = append(rules[finding.Rule], finding.Resource)
+	}
+
+	if got := rules["permission-user-grant"]; strings.Join(got, ",") != `CORP\alice,CORP\bob` {
+		t.Errorf("user grants flagged for %v, want alice and bob once each", got)
+	}
+	if got := rules["permission-disabled-account"]; strings.Join(got, ",") != `CORP\bob` {
+		t.Errorf("disabled accounts flagged for %v, want bob", got)
+	}
+	if got := rules["permission-unresolved-principal"]; strings.Join(got, ",") != `CORP\gone` {
+		t.Errorf("unresolved principals flagged for %v, want gone", got)
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
@@ -0,0 +1,212 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vmware/govmomi/property"
+	"github.com/vmware/govmomi/view"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// withRetry runs fn, retrying failures up to the configured number of times
+// with exponential backoff
+func (p *vmwareProvider) withRetry(ctx context.Context, operation string, fn func() error) error {
+	policy := p.config.Discovery
+	backoff := policy.Backoff
+
+	err := fn()
+	for attempt := 1; err != nil && attempt <= policy.MaxRetries; attempt++ {
+		p.log.Debug("Retrying VMware operation", "operation", operation, "attempt", attempt, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+
+		err = fn()
+	}
+
+	return err
+}
+
+// defaultBatchSize is how many objects one property retrieval covers when
+// the policy leaves it unset; large enough to keep round trips down on big
+// inventories without building one huge response
+const defaultBatchSize = 500
+
+// retrieveObjects retrieves properties for refs in batches according to the
+// discovery policy. When a batch fails and per-object fallback is enabled,
+// its objects are retrieved one at a time so a single bad object does not
+// lose the whole batch. Objects that still fail are logged and skipped.
+// Up to the configured concurrency of batches are retrieved at once.
+// Results keep the order of refs.
+func retrieveObjects[T mo.Reference](ctx context.Context, p *vmwareProvider, kind string, refs []types.ManagedObjectReference, props []string) []T {
+	pc := property.DefaultCollector(p.client.Client)
+	policy := p.config.Discovery
+
+	batchSize := policy.BatchSize
+	if batchSize < 1 {
+		batchSize = defaultBatchSize
+	}
+
+	retrieve := func(batch []types.ManagedObjectReference) ([]T, error) {
+		var objects []T
+		err := p.withRetry(ctx, "retrieve "+kind+" properties", func() error {
+			var err error
+			objects, err = retrieveExisting[T](ctx, p, pc, kind, batch, props)
+			return err
+		})
+		reportProgress(ctx)
+		return objects, err
+	}
+
+	progress := p.log.NewProgress("Retrieving "+kind+" properties", len(refs))
+	defer progress.Done()
+
+	var batches [][]types.ManagedObjectReference
+	for start := 0; start < len(refs); start += batchSize {
+		end := start + batchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+		batches = append(batches, refs[start:end])
+	}
+
+	var mu sync.Mutex
+	byRef := make(map[types.ManagedObjectReference]T)
+	forEachLimit(ctx, len(batches), concurrencyLimit(policy.Concurrency), func(i int) {
+		batch := batches[i]
+
+		objects, err := retrieve(batch)
+		if err != nil && len(batch) > 1 && policy.PerObjectFallback {
+			p.log.Warn("Batch property retrieval failed, falling back to per-object retrieval",
+				"type", kind, "objects", len(batch), "error", err)
+
+			objects = nil
+			for _, ref := range batch {
+				single, err := retrieve([]types.ManagedObjectReference{ref})
+				if err != nil {
+					p.log.Error("Failed to get "+kind+" properties", "ref", ref.Value, "error", err)
+					continue
+				}
+				objects = append(objects, single...)
+			}
+		} else if err != nil {
+			p.log.Error("Failed to get "+kind+" properties", "objects", len(batch), "error", err)
+			mu.Lock()
+			progress.Add(len(batch))
+			mu.Unlock()
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, object := range objects {
+			byRef[object.Reference()] = object
+		}
+		progress.Add(len(batch))
+	})
+
+	var result []T
+	for _, ref := range refs {
+		if object, ok := byRef[ref]; ok {
+			result = append(result, object)
+		}
+	}
+
+	return result
+}
+
+// retrieveExisting retrieves properties for refs with a single collector
+// call. An object deleted since refs were listed fails the whole call with
+// ManagedObjectNotFound, so the object it names is dropped, recorded as
+// skipped, and the call repeated for the rest.
+func retrieveExisting[T any](ctx context.Context, p *vmwareProvider, pc *property.Collector, kind string, refs []types.ManagedObjectReference, props []string) ([]T, error) {
+	for {
+		var objects []T
+		err := pc.Retrieve(ctx, refs, props, &objects)
+		missing, ok := missingObject(err)
+		if !ok {
+			return objects, err
+		}
+
+		remaining := make([]types.ManagedObjectReference, 0, len(refs))
+		for _, ref := range refs {
+			if ref != missing {
+				remaining = append(remaining, ref)
+			}
+		}
+		if len(remaining) == len(refs) {
+			// Not one of the requested objects, so dropping it cannot help
+			return nil, err
+		}
+
+		p.skipObject(kind, missing)
+		refs = remaining
+		if len(refs) == 0 {
+			return nil, nil
+		}
+	}
+}
+
+// maxViewAttempts bounds how often a container view retrieval is repeated
+// for objects deleted while it ran
+const maxViewAttempts = 5
+
+// retrieveView retrieves properties for every object of moType under root
+// through a container view. An object deleted during the call fails it with
+// ManagedObjectNotFound; a new view no longer lists it, so the object is
+// recorded as skipped and the call repeated.
+func retrieveView[T any](ctx context.Context, p *vmwareProvider, root types.ManagedObjectReference, moType string, props []string) ([]T, error) {
+	for attempt := 1; ; attempt++ {
+		objects, err := func() ([]T, error) {
+			v, err := view.NewManager(p.client.Client).CreateContainerView(ctx, root, []string{moType}, true)
+			if err != nil {
+				return nil, err
+			}
+			defer v.Destroy(ctx)
+
+			var objects []T
+			err = v.Retrieve(ctx, []string{moType}, props, &objects)
+			return objects, err
+		}()
+
+		missing, ok := missingObject(err)
+		if !ok || attempt == maxViewAttempts {
+			return objects, err
+		}
+		p.skipObject(moType, missing)
+	}
+}
+
+// missingObject returns the object a ManagedObjectNotFound fault names,
+// if err is one
+func missingObject(err error) (types.ManagedObjectReference, bool) {
+	if err == nil || !soap.IsSoapFault(err) {
+		return types.ManagedObjectReference{}, false
+	}
+	switch fault := soap.ToSoapFault(err).VimFault().(type) {
+	case types.ManagedObjectNotFound:
+		return fault.Obj, true
+	case *types.ManagedObjectNotFound:
+		return fault.Obj, true
+	}
+	return types.ManagedObjectReference{}, false
+}
+
+// skipObject records an object that disappeared during discovery. Skipped
+// objects end up in the skipped_objects metadata of the infrastructure.
+func (p *vmwareProvider) skipObject(kind string, ref types.ManagedObjectReference) {
+	p.log.Warn("Object was deleted during discovery, skipping it", "type", kind, "ref", ref.Value)
+
+	p.skippedMu.Lock()
+	defer p.skippedMu.Unlock()
+	p.skipped = append(p.skipped, fmt.Sprintf("%s %s was deleted during discovery", kind, ref.Value))
+}
@@ -0,0 +1,289 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestBatchedRetrievalKeepsOrder(t *testing.T) {
+	_, cfg := simulatedVCenter(t, func(model *simulator.Model) {
+		model.Machine = 12 // per host and per cluster resource pool
+	})
+	cfg.Datacenter = "DC0"
+
+	// One retrieval for the whole inventory is the reference result
+	p := connectedVMware(t, cfg)
+	vms, err := p.finder.VirtualMachineList(context.Background(), "*")
+	if err != nil {
+		t.Fatalf("VirtualMachineList: %v", err)
+	}
+	refs := make([]types.ManagedObjectReference, len(vms))
+	for i, vm := range vms {
+		refs[i] = vm.Reference()
+	}
+	if len(refs) < 24 {
+		t.Fatalf("got %d VMs, want a larger inventory", len(refs))
+	}
+
+	single := retrieveObjects[mo.VirtualMachine](context.Background(), p, "VM", refs, []string{"name"})
+	want := vmNames(single)
+	if len(want) != len(refs) {
+		t.Fatalf("single retrieval returned %d of %d VMs", len(want), len(refs))
+	}
+	for i, vm := range single {
+		if vm.Reference() != refs[i] {
+			t.Fatalf("result %d is %s, want %s: results must keep the order of refs", i, vm.Reference(), refs[i])
+		}
+	}
+
+	for _, policy := range []struct{ batchSize, concurrency int }{{1, 1}, {5, 1}, {5, 8}, {7, 3}} {
+		p.config.Discovery.BatchSize = policy.batchSize
+		p.config.Discovery.Concurrency = policy.concurrency
+
+		got := vmNames(retrieveObjects[mo.VirtualMachine](context.Background(), p, "VM", refs, []string{"name"}))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("batch size %d with %d workers: got %v, want %v", policy.batchSize, policy.concurrency, got, want)
+		}
+	}
+
+	// The order of the final VM list is the same however it was retrieved
+	p.config.Discovery.BatchSize, p.config.Discovery.Concurrency = 500, 1
+	first, err := p.DiscoverVMs(context.Background(), VMDiscoveryFilters{})
+	if err != nil {
+		t.Fatalf("DiscoverVMs: %v", err)
+	}
+	p.config.Discovery.BatchSize, p.config.Discovery.Concurrency = 4, 6
+	second, err := p.DiscoverVMs(context.Background(), VMDiscoveryFilters{})
+	if err != nil {
+		t.Fatalf("DiscoverVMs: %v", err)
+	}
+	if len(first) != len(refs) {
+		t.Errorf("DiscoverVMs found %d of %d VMs", len(first), len(refs))
+	}
+	for i := range first {
+		if i >= len(second) || first[i].ID != second[i].ID {
+			t.Fatalf("VM order differs between batch sizes at %d", i)
+		}
+	}
+}
+
+func TestRetrievalSkipsDeletedVM(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(t, cfg)
+	ctx := context.Background()
+
+	vms, err := p.finder.VirtualMachineList(ctx, "*")
+	if err != nil {
+		t.Fatalf("VirtualMachineList: %v", err)
+	}
+	refs := make([]types.ManagedObjectReference, len(vms))
+	for i, vm := range vms {
+		refs[i] = vm.Reference()
+	}
+
+	// Deleted after being listed, before its properties are retrieved
+	deleted := vms[1]
+	destroyVM(t, deleted)
+
+	// One batch and no per-object fallback: only dropping the missing
+	// object from the batch keeps the others
+	p.config.Discovery.BatchSize, p.config.Discovery.PerObjectFallback = 500, false
+	got := retrieveObjects[mo.VirtualMachine](ctx, p, "VM", refs, []string{"name"})
+	if len(got) != len(refs)-1 {
+		t.Fatalf("retrieved %d VMs, want the %d that still exist", len(got), len(refs)-1)
+	}
+	for _, vm := range got {
+		if vm.Reference() == deleted.Reference() {
+			t.Error("deleted VM was retrieved")
+		}
+	}
+	if len(p.skipped) != 1 || !strings.Contains(p.skipped[0], deleted.Reference().Value) {
+		t.Errorf("skipped = %v, want the deleted VM", p.skipped)
+	}
+}
+
+func TestDiscoverWhileVMsAreDeleted(t *testing.T) {
+	_, cfg := simulatedVCenter(t, func(model *simulator.Model) {
+		model.Machine = 8
+	})
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(t, cfg)
+	p.config.Discovery.BatchSize = 3
+
+	// A second session deletes VMs while discovery runs
+	deleter := connectedVMware(t, cfg)
+	vms, err := deleter.finder.VirtualMachineList(context.Background(), "*")
+	if err != nil {
+		t.Fatalf("VirtualMachineList: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, vm := range vms[:len(vms)/2] {
+			destroyVM(t, vm)
+		}
+	}()
+
+	infra, err := p.Discover(context.Background())
+	<-done
+	if err != nil {
+		t.Fatalf("Discover failed while VMs were deleted: %v", err)
+	}
+	if n := len(infra.VirtualMachines); n < len(vms)-len(vms)/2 || n > len(vms) {
+		t.Errorf("discovered %d VMs, want between %d and %d", n, len(vms)-len(vms)/2, len(vms))
+	}
+}
+
+// destroyVM powers off and deletes a simulated VM
+func destroyVM(t *testing.T, vm *object.VirtualMachine) {
+	t.Helper()
+
+	ctx := context.Background()
+	if task, err := vm.PowerOff(ctx); err == nil {
+		task.Wait(ctx) // already off is fine
+	}
+	task, err := vm.Destroy(ctx)
+	if err == nil {
+		err = task.Wait(ctx)
+	}
+	if err != nil {
+		t.Errorf("destroy %s: %v", vm.Reference().Value, err)
+	}
+}
+
+// vmNames lists the names of retrieved VMs in order
+func vmNames(vms []mo.VirtualMachine) []string {
+	names := make([]string, len(vms))
+	for i, vm := range vms {
+		names[i] = vm.Name
+	}
+	return names
+}
+
+// soapCounter counts the SOAP calls a provider makes, by method
+type soapCounter struct {
+	soap.RoundTripper
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// countSOAPCalls routes the provider's SOAP calls through a counter
+func countSOAPCalls(p *vmwareProvider) *soapCounter {
+	counter := &soapCounter{RoundTripper: p.client.Client.RoundTripper, calls: make(map[string]int)}
+	p.client.Client.RoundTripper = counter
+	return counter
+}
+
+// RoundTrip counts the call and passes it on
+func (c *soapCounter) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	method := strings.TrimSuffix(strings.TrimPrefix(fmt.Sprintf("%T", req), "*methods."), "Body")
+	c.mu.Lock()
+	c.calls[method]++
+	c.mu.Unlock()
+	return c.RoundTripper.RoundTrip(ctx, req, res)
+}
+
+// reset returns the calls counted so far, the property retrievals among
+// them, and starts counting again
+func (c *soapCounter) reset() (total, retrievals int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for method, n := range c.calls {
+		total += n
+		if strings.Contains(method, "RetrieveProperties") {
+			retrievals += n
+		}
+	}
+	c.calls = make(map[string]int)
+	return total, retrievals
+}
+
+func TestBatchedRetrievalRoundTrips(t *testing.T) {
+	_, cfg := simulatedVCenter(t, func(model *simulator.Model) {
+		model.Machine = 12
+	})
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(t, cfg)
+	counter := countSOAPCalls(p)
+
+	vms, err := p.finder.VirtualMachineList(context.Background(), "*")
+	if err != nil {
+		t.Fatalf("VirtualMachineList: %v", err)
+	}
+	refs := make([]types.ManagedObjectReference, len(vms))
+	for i, vm := range vms {
+		refs[i] = vm.Reference()
+	}
+
+	for _, tt := range []struct {
+		batchSize int
+		want      int
+	}{
+		{batchSize: 500, want: 1},
+		{batchSize: 5, want: (len(refs) + 4) / 5},
+		{batchSize: 1, want: len(refs)},
+	} {
+		p.config.Discovery.BatchSize = tt.batchSize
+		counter.reset()
+		retrieveObjects[mo.VirtualMachine](context.Background(), p, "VM", refs, []string{"name"})
+		if _, retrievals := counter.reset(); retrievals != tt.want {
+			t.Errorf("batch size %d: %d property retrievals for %d VMs, want %d", tt.batchSize, retrievals, len(refs), tt.want)
+		}
+	}
+}
+
+// BenchmarkDiscoverVMs discovers the VMs of a vCenter with 3,000 of them,
+// retrieving their properties in batches and one object at a time, and
+// reports the SOAP round trips of each: soap-calls/op counts every call,
+// retrievals/op the property collector calls among them. Listing the
+// inventory takes the same few calls either way. Run with
+//
+//	go test ./internal/discovery/providers -run '^$' -bench DiscoverVMs -benchtime 3x
+func BenchmarkDiscoverVMs(b *testing.B) {
+	_, cfg := simulatedVCenter(b, func(model *simulator.Model) {
+		model.Machine = 1500 // per standalone host and per cluster
+	})
+	cfg.Datacenter = "DC0"
+	p := connectedVMware(b, cfg)
+	counter := countSOAPCalls(p)
+
+	for _, bm := range []struct {
+		name      string
+		batchSize int
+	}{
+		{"batched", defaultBatchSize},
+		{"per-object", 1},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			p.config.Discovery.BatchSize = bm.batchSize
+			counter.reset()
+
+			var vms int
+			for i := 0; i < b.N; i++ {
+				discovered, err := p.DiscoverVMs(context.Background(), VMDiscoveryFilters{})
+				if err != nil {
+					b.Fatalf("DiscoverVMs: %v", err)
+				}
+				vms = len(discovered)
+			}
+
+			total, retrievals := counter.reset()
+			b.ReportMetric(float64(vms), "vms")
+			b.ReportMetric(float64(total)/float64(b.N), "soap-calls/op")
+			b.ReportMetric(float64(retrievals)/float64(b.N), "retrievals/op")
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"github.com/vmware/govmomi/vim25/types"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// snapshotTree converts a snapshot tree, linking each snapshot to its
+// parent's ID and marking the one with ID current
+func snapshotTree(tree []types.VirtualMachineSnapshotTree, parent, current string, sizes map[string]int64) []models.Snapshot {
+	var snapshots []models.Snapshot
+	for _, node := range tree {
+		id := node.Snapshot.Value
+		snapshots = append(snapshots, models.Snapshot{
+			ID:          id,
+			Name:        node.Name,
+			Description: node.Description,
+			CreateTime:  node.CreateTime,
+			Size:        units.FromBytes(sizes[id]).ToMiB(),
+			Quiesced:    node.Quiesced,
+			Parent:      parent,
+			Current:     id == current,
+			Children:    snapshotTree(node.ChildSnapshotList, id, current, sizes),
+		})
+	}
+	return snapshots
+}
+
+// snapshotSizes totals the bytes each snapshot holds on disk, keyed by
+// snapshot reference: its state and memory files, plus the delta disks
+// created when it was taken, which deleting it would consolidate. A delta is
+// found as the link following the snapshot's last link in any longer chain
+// for the same disk, whether a child snapshot's or the VM's current one.
+func snapshotSizes(layout *types.VirtualMachineFileLayoutEx) map[string]int64 {
+	if layout == nil || len(layout.Snapshot) == 0 {
+		return nil
+	}
+
+	files := make(map[int32]types.VirtualMachineFileLayoutExFileInfo, len(layout.File))
+	for _, file := range layout.File {
+		files[file.Key] = file
+	}
+
+	// Every known chain per disk key
+	chains := make(map[int32][][]types.VirtualMachineFileLayoutExDiskUnit)
+	for _, disk := range layout.Disk {
+		chains[disk.Key] = append(chains[disk.Key], disk.Chain)
+	}
+	for _, snapshot := range layout.Snapshot {
+		for _, disk := range snapshot.Disk {
+			chains[disk.Key] = append(chains[disk.Key], disk.Chain)
+		}
+	}
+
+	sizes := make(map[string]int64, len(layout.Snapshot))
+	for _, snapshot := range layout.Snapshot {
+		var size int64
+		if file, ok := files[snapshot.DataKey]; ok && file.Type == string(types.VirtualMachineFileLayoutExFileTypeSnapshotData) {
+			size += file.Size
+		}
+		if file, ok := files[snapshot.MemoryKey]; ok && file.Type == string(types.VirtualMachineFileLayoutExFileTypeSnapshotMemory) {
+			size += file.Size
+		}
+
+		for _, disk := range snapshot.Disk {
+			n := len(disk.Chain)
+			if n == 0 {
+				continue
+			}
+			for _, chain := range chains[disk.Key] {
+				if len(chain) <= n || !sameFileKeys(chain[n-1].FileKey, disk.Chain[n-1].FileKey) {
+					continue
+				}
+				for _, key := range chain[n].FileKey {
+					size += files[key].Size
+				}
+				break
+			}
+		}
+
+		sizes[snapshot.Key.Value] = size
+	}
+
+	return sizes
+}
+
+// sameFileKeys reports whether two disk chain links name the same files
+func sameFileKeys(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/object"
+	"valhalla/internal/models"
+)
+
+// createSnapshot takes a snapshot of vm and returns its ID
+func createSnapshot(t *testing.T, vm *object.VirtualMachine, name string) string {
+	t.Helper()
+	ctx := context.Background()
+
+	task, err := vm.CreateSnapshot(ctx, name, "", false, false)
+	if err != nil {
+		t.Fatalf("CreateSnapshot %s: %v", name, err)
+	}
+	info, err := task.WaitForResult(ctx, nil)
+	if err != nil {
+		t.Fatalf("CreateSnapshot %s: %v", name, err)
+	}
+	return info.Result.(object.Reference).Reference().Value
+}
+
+func TestDiscoverSnapshotTree(t *testing.T) {
+	_, cfg := simulatedVCenter(t)
+	cfg.Datacenter = "DC0"
+	cfg.IncludeSnapshots = true
+	p := connectedVMware(t, cfg)
+	ctx := context.Background()
+
+	vm, err := find.NewFinder(p.client.Client, true).VirtualMachine(ctx, "/DC0/vm/DC0_H0_VM0")
+	if err != nil {
+		t.Fatalf("find VM: %v", err)
+	}
+
+	// base -> patched -> upgraded, then back to base for a second branch
+	base := createSnapshot(t, vm, "base")
+	patched := createSnapshot(t, vm, "patched")
+	upgraded := createSnapshot(t, vm, "upgraded")
+	task, err := vm.RevertToSnapshot(ctx, base, true)
+	if err != nil {
+		t.Fatalf("RevertToSnapshot: %v", err)
+	}
+	if err := task.Wait(ctx); err != nil {
+		t.Fatalf("RevertToSnapshot: %v", err)
+	}
+	rollback := createSnapshot(t, vm, "rollback")
+
+	infra, err := p.Discover(ctx)
+	if err != nil {
+		t.Fatalf("Discover: %v", err)
+	}
+
+	var snapshots []models.Snapshot
+	for _, discovered := range infra.VirtualMachines {
+		if discovered.Name == "DC0_H0_VM0" {
+			snapshots = discovered.Snapshots
+		}
+	}
+
+	type node struct {
+		name    string
+		id      string
+		parent  string
+		current bool
+		depth   int
+	}
+	var got []node
+	var walk func(tree []models.Snapshot, depth int)
+	walk = func(tree []models.Snapshot, depth int) {
+		for _, snapshot := range tree {
+			got = append(got, node{snapshot.Name, snapshot.ID, snapshot.Parent, snapshot.Current, depth})
+			walk(snapshot.Children, depth+1)
+		}
+	}
+	walk(snapshots, 0)
+
+	want := []node{
+		{"base", base, "", false, 0},
+		{"patched", patched, base, false, 1},
+		{"upgraded", upgraded, patched, false, 2},
+		{"rollback", rollback, base, true, 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("snapshot tree =\n%+v\nwant\n%+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("snapshot tree =\n%+v\nwant\n%+v", got, want)
+			break
+		}
+	}
+	if len(snapshots) != 1 {
+		t.Errorf("%d root snapshots, want 1", len(snapshots))
+	}
+}
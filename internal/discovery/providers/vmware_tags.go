@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// vmTagBatchSize is how many objects one tag association lookup covers. The
+// property retrieval batch size doesn't apply; it defaults to one object per
+// call, which would mean a REST round trip per VM.
+const vmTagBatchSize = 500
+
+// vmTags reads the vSphere tags attached to refs from the tagging service and
+// returns them as sorted "category:tag" strings keyed by reference value.
+// The tagging service lives behind the vAPI REST endpoint rather than SOAP,
+// so when it cannot be reached a warning is logged and nil is returned,
+// leaving discovery to carry on without tags.
+func (p *vmwareProvider) vmTags(ctx context.Context, refs []types.ManagedObjectReference) map[string][]string {
+	if len(refs) == 0 {
+		return nil
+	}
+
+	// Standalone hosts have no tagging service
+	if p.version.APIType != "VirtualCenter" {
+		p.log.Debug("Skipping tag discovery, endpoint has no tagging service", "api_type", p.version.APIType)
+		return nil
+	}
+
+	rc := rest.NewClient(p.client.Client)
+	if err := rc.Login(ctx, url.UserPassword(p.config.Username, p.config.Password)); err != nil {
+		p.log.Warn("Tagging service unreachable, continuing without VM tags", "error", err)
+		return nil
+	}
+	defer func() {
+		if err := rc.Logout(ctx); err != nil {
+			p.log.Debug("Failed to log out of tagging service", "error", err)
+		}
+	}()
+
+	manager := tags.NewManager(rc)
+
+	categories, err := manager.GetCategories(ctx)
+	if err != nil {
+		p.log.Warn("Failed to list tag categories, continuing without VM tags", "error", err)
+		return nil
+	}
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	result := make(map[string][]string)
+	for start := 0; start < len(refs); start += vmTagBatchSize {
+		end := start + vmTagBatchSize
+		if end > len(refs) {
+			end = len(refs)
+		}
+
+		objects := make([]mo.Reference, 0, end-start)
+		for _, ref := range refs[start:end] {
+			objects = append(objects, ref)
+		}
+
+		var attached []tags.AttachedTags
+		err := p.withRetry(ctx, "retrieve VM tags", func() error {
+			var err error
+			attached, err = manager.GetAttachedTagsOnObjects(ctx, objects)
+			return err
+		})
+		if err != nil {
+			p.log.Warn("Failed to retrieve VM tags, continuing without VM tags", "error", err)
+			return nil
+		}
+
+		for _, entry := range attached {
+			ref := entry.ObjectID.Reference().Value
+			for _, tag := range entry.Tags {
+				category := categoryNames[tag.CategoryID]
+				if category == "" {
+					category = tag.CategoryID
+				}
+				result[ref] = append(result[ref], category+":"+tag.Name)
+			}
+		}
+	}
+
+	for ref := range result {
+		sort.Strings(result[ref])
+	}
+
+	return result
+}
+
+// customAttributes resolves a VM's custom field values to their attribute
+// names, skipping values whose definition is not visible or that are empty
+func customAttributes(fields []types.CustomFieldDef, values []types.BaseCustomFieldValue) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	names := make(map[int32]string, len(fields))
+	for _, field := range fields {
+		names[field.Key] = field.Name
+	}
+
+	attributes := make(map[string]string)
+	for _, value := range values {
+		stringValue, ok := value.(*types.CustomFieldStringValue)
+		if !ok || stringValue.Value == "" {
+			continue
+		}
+		name, ok := names[stringValue.Key]
+		if !ok {
+			continue
+		}
+		attributes[name] = stringValue.Value
+	}
+
+	return attributes
+}
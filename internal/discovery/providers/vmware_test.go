@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/types"
+
+	"valhalla/internal/models"
+)
+
+func TestExtractNetworkCardTypes(t *testing.T) {
+	card := func(key int32) types.VirtualEthernetCard {
+		return types.VirtualEthernetCard{
+			VirtualDevice: types.VirtualDevice{
+				Key:         key,
+				Backing:     &types.VirtualEthernetCardNetworkBackingInfo{VirtualDeviceDeviceBackingInfo: types.VirtualDeviceDeviceBackingInfo{DeviceName: "VM Network"}},
+				Connectable: &types.VirtualDeviceConnectInfo{},
+			},
+		}
+	}
+
+	tests := []struct {
+		device types.BaseVirtualDevice
+		want   string
+	}{
+		{&types.VirtualVmxnet3{VirtualVmxnet: types.VirtualVmxnet{VirtualEthernetCard: card(4000)}}, models.NICTypeVmxnet3},
+		{&types.VirtualVmxnet2{VirtualVmxnet: types.VirtualVmxnet{VirtualEthernetCard: card(4001)}}, models.NICTypeVmxnet2},
+		{&types.VirtualVmxnet{VirtualEthernetCard: card(4002)}, models.NICTypeVmxnet},
+		{&types.VirtualE1000{VirtualEthernetCard: card(4003)}, models.NICTypeE1000},
+		{&types.VirtualE1000e{VirtualEthernetCard: card(4004)}, models.NICTypeE1000e},
+		{&types.VirtualPCNet32{VirtualEthernetCard: card(4005)}, models.NICTypePCNet32},
+		{&types.VirtualSriovEthernetCard{VirtualEthernetCard: card(4006)}, models.NICTypeSRIOV},
+		{&types.VirtualVmxnet3Vrdma{VirtualVmxnet3: types.VirtualVmxnet3{VirtualVmxnet: types.VirtualVmxnet{VirtualEthernetCard: card(4007)}}}, models.NICTypeUnknown},
+	}
+
+	p := &vmwareProvider{log: testLogger()}
+	for _, tt := range tests {
+		cards := p.extractBasicNetworkCards([]types.BaseVirtualDevice{tt.device}, nil)
+		if len(cards) != 1 {
+			t.Fatalf("%T: got %d network cards, want 1", tt.device, len(cards))
+		}
+		if cards[0].Type != tt.want {
+			t.Errorf("%T: type = %q, want %q", tt.device, cards[0].Type, tt.want)
+		}
+		if cards[0].Network != "VM Network" {
+			t.Errorf("%T: network = %q, want VM Network", tt.device, cards[0].Network)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+)
+
+// Watchdog settings for long-running discovery phases
+type Watchdog struct {
+	// Heartbeat is how often a phase still running is logged; 0 disables
+	Heartbeat time.Duration
+
+	// Stall aborts a phase when no API call completes for this long; 0
+	// disables
+	Stall time.Duration
+}
+
+type watchdogKey struct{}
+
+type progressKey struct{}
+
+// WithWatchdog returns a context whose discovery phases are watched with w
+func WithWatchdog(ctx context.Context, w Watchdog) context.Context {
+	return context.WithValue(ctx, watchdogKey{}, w)
+}
+
+// StallError reports a phase aborted for making no progress
+type StallError struct {
+	Operation string
+	Idle      time.Duration
+}
+
+func (e *StallError) Error() string {
+	return fmt.Sprintf("%s stalled: no progress for %s", e.Operation, e.Idle)
+}
+
+// reportProgress tells the watchdog of the phase running under ctx, if any,
+// that an API call completed
+func reportProgress(ctx context.Context) {
+	if progress, ok := ctx.Value(progressKey{}).(*phaseProgress); ok {
+		progress.touch()
+	}
+}
+
+// phaseProgress records when a phase last made progress
+type phaseProgress struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+func (p *phaseProgress) touch() {
+	p.mu.Lock()
+	p.last = time.Now()
+	p.mu.Unlock()
+}
+
+func (p *phaseProgress) idle() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Since(p.last)
+}
+
+// watchPhase runs one discovery phase under the watchdog in ctx. While the
+// phase runs it logs a heartbeat every interval; if no API call completes
+// within the stall timeout, the phase's context is cancelled and a
+// *StallError is returned once it has stopped. Without a watchdog in ctx
+// fn simply runs.
+func watchPhase[T any](ctx context.Context, log *logger.Logger, operation string, fn func(ctx context.Context) (T, error)) (T, error) {
+	w, ok := ctx.Value(watchdogKey{}).(Watchdog)
+	if !ok || (w.Heartbeat <= 0 && w.Stall <= 0) {
+		return fn(ctx)
+	}
+
+	progress := &phaseProgress{last: time.Now()}
+	phaseCtx, cancel := context.WithCancel(context.WithValue(ctx, progressKey{}, progress))
+	defer cancel()
+
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn(phaseCtx)
+		done <- outcome{value, err}
+	}()
+
+	// Tick often enough to honour both intervals
+	tick := w.Heartbeat
+	if w.Stall > 0 && (tick <= 0 || w.Stall/4 < tick) {
+		tick = w.Stall / 4
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	lastBeat := start
+	var stalled *StallError
+	for {
+		select {
+		case out := <-done:
+			if stalled != nil {
+				var zero T
+				return zero, stalled
+			}
+			return out.value, out.err
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case now := <-ticker.C:
+			idle := progress.idle()
+			if w.Heartbeat > 0 && now.Sub(lastBeat) >= w.Heartbeat {
+				lastBeat = now
+				elapsed := now.Sub(start).Round(time.Second)
+				log.Info(fmt.Sprintf("Still waiting on %s (%s elapsed)", operation, elapsed),
+					"operation", operation, "idle", idle.Round(time.Second).String())
+			}
+			if stalled == nil && w.Stall > 0 && idle >= w.Stall {
+				stalled = &StallError{Operation: operation, Idle: idle.Round(time.Second)}
+				log.Warn("Aborting stalled operation", "operation", operation, "idle", stalled.Idle.String())
+				cancel()
+			}
+		}
+	}
+}
+
+// phaseFailed logs a phase that did not complete. Stalled phases are
+// warnings recorded in the infrastructure's phase_warnings metadata, since
+// discovery carries on with the next phase.
+func phaseFailed(log *logger.Logger, infra *models.Infrastructure, msg string, err error) {
+	var stall *StallError
+	if !errors.As(err, &stall) {
+		log.Error(msg, "error", err)
+		return
+	}
+
+	log.Warn(msg, "error", err)
+	warnings, _ := infra.Metadata["phase_warnings"].([]string)
+	infra.Metadata["phase_warnings"] = append(warnings, err.Error())
+}
@@ -0,0 +1,148 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+)
+
+func TestWatchPhase(t *testing.T) {
+	w := Watchdog{Heartbeat: 20 * time.Millisecond, Stall: 100 * time.Millisecond}
+
+	t.Run("stalled phase is aborted", func(t *testing.T) {
+		var logged bytes.Buffer
+		start := time.Now()
+		_, err := watchPhase(WithWatchdog(context.Background(), w), logger.NewWithOutput(&logged), "networks", func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+
+		var stall *StallError
+		if !errors.As(err, &stall) {
+			t.Fatalf("err = %v, want a *StallError", err)
+		}
+		if stall.Operation != "networks" {
+			t.Errorf("Operation = %q", stall.Operation)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("stall took %s to detect", elapsed)
+		}
+		if !strings.Contains(logged.String(), "Still waiting on networks") {
+			t.Errorf("no heartbeat was logged:\n%s", logged.String())
+		}
+	})
+
+	t.Run("progress keeps a slow phase alive", func(t *testing.T) {
+		got, err := watchPhase(WithWatchdog(context.Background(), w), testLogger(), "virtual machines", func(ctx context.Context) (int, error) {
+			for i := 0; i < 6; i++ {
+				time.Sleep(40 * time.Millisecond)
+				reportProgress(ctx)
+			}
+			return 42, nil
+		})
+		if err != nil || got != 42 {
+			t.Errorf("watchPhase = %d, %v; want 42 after 240ms of steady progress", got, err)
+		}
+	})
+
+	t.Run("without a watchdog the phase just runs", func(t *testing.T) {
+		got, err := watchPhase(context.Background(), testLogger(), "storage", func(ctx context.Context) (string, error) {
+			return "done", nil
+		})
+		if err != nil || got != "done" {
+			t.Errorf("watchPhase = %q, %v", got, err)
+		}
+	})
+
+	t.Run("cancelling the run is not a stall", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(WithWatchdog(context.Background(), w))
+		time.AfterFunc(10*time.Millisecond, cancel)
+		_, err := watchPhase(ctx, testLogger(), "networks", func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("err = %v, want context.Canceled", err)
+		}
+	})
+}
+
+// stallingProxmox serves a one-node Proxmox cluster with one VM whose node
+// network listing never answers. It counts the network requests made.
+func stallingProxmox(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var networkCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/api2/json") {
+		case "/version":
+			w.Write([]byte(`{"data":{"version":"8.2.4","release":"8.2","repoid":"faa83925"}}`))
+		case "/cluster/resources":
+			w.Write([]byte(`{"data":[
+				{"id":"node/pve1","type":"node","node":"pve1","status":"online","maxcpu":8,"maxmem":34359738368},
+				{"id":"qemu/100","type":"qemu","node":"pve1","status":"running","name":"web-01","vmid":100,"maxcpu":2,"maxmem":4294967296}
+			]}`))
+		case "/nodes/pve1/network":
+			atomic.AddInt32(&networkCalls, 1)
+			<-r.Context().Done()
+		default:
+			w.Write([]byte(`{"data":null}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &networkCalls
+}
+
+func TestStalledPhaseBecomesWarning(t *testing.T) {
+	server, networkCalls := stallingProxmox(t)
+
+	provider := NewProxmoxProvider(testLogger())
+	cfg := config.ProxmoxConfig{Server: server.URL, TokenID: "root@pam!valhalla", Secret: "token-secret"}
+	if err := provider.ConnectProxmox(context.Background(), cfg); err != nil {
+		t.Fatalf("ConnectProxmox: %v", err)
+	}
+
+	ctx := WithWatchdog(context.Background(), Watchdog{Heartbeat: 50 * time.Millisecond, Stall: 200 * time.Millisecond})
+	done := make(chan struct{})
+	var infra *models.Infrastructure
+	var err error
+	go func() {
+		infra, err = provider.Discover(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Discover hung on the stalled network listing")
+	}
+	if err != nil {
+		t.Fatalf("Discover: %v; a stalled phase must not fail the run", err)
+	}
+
+	if atomic.LoadInt32(networkCalls) == 0 {
+		t.Fatal("the network listing was never requested")
+	}
+	if len(infra.VirtualMachines) != 1 || infra.VirtualMachines[0].Name != "web-01" {
+		t.Errorf("VMs = %+v, want web-01 from the phase before the stall", infra.VirtualMachines)
+	}
+	if len(infra.Networks) != 0 {
+		t.Errorf("Networks = %+v from a stalled phase", infra.Networks)
+	}
+
+	warnings, _ := infra.Metadata["phase_warnings"].([]string)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "networks stalled") {
+		t.Errorf("phase_warnings = %v, want the networks stall", warnings)
+	}
+}
@@ -0,0 +1,126 @@
+package enrich
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// Complexity factors; each adds its configured weight to a VM's score
+const (
+	FactorSnapshots        = "snapshots"
+	FactorRDM              = "rdm"
+	FactorPassthrough      = "passthrough"
+	FactorFaultTolerance   = "fault_tolerance"
+	FactorManyNICs         = "many_nics"
+	FactorIndependentDisks = "independent_disks"
+	FactorLargeDisks       = "large_disks"
+	FactorUnknownOS        = "unknown_os"
+	FactorMissingTools     = "missing_tools"
+)
+
+// ComplexityFactorsKey is the VM metadata key listing the factors behind its score
+const ComplexityFactorsKey = "complexity_factors"
+
+// ScoreComplexity sets each VM's migration complexity score from the
+// features that need manual work, and records the contributing factors in
+// its metadata. Templates are not scored.
+func ScoreComplexity(infrastructures []*models.Infrastructure, cfg config.ComplexityConfig) {
+	for _, infra := range infrastructures {
+		for i := range infra.VirtualMachines {
+			vm := &infra.VirtualMachines[i]
+			if vm.Config.Template {
+				continue
+			}
+
+			factors := complexityFactors(*vm, cfg)
+			vm.Complexity = 0
+			for _, factor := range factors {
+				vm.Complexity += cfg.Weights[factor]
+			}
+
+			if vm.Metadata == nil {
+				vm.Metadata = make(map[string]interface{})
+			}
+			if len(factors) > 0 {
+				vm.Metadata[ComplexityFactorsKey] = factors
+			} else {
+				delete(vm.Metadata, ComplexityFactorsKey)
+			}
+		}
+	}
+}
+
+// complexityFactors lists the factors that apply to a VM
+func complexityFactors(vm models.VirtualMachine, cfg config.ComplexityConfig) []string {
+	var factors []string
+
+	if metadataInt(vm.Metadata, "snapshots") > 0 {
+		factors = append(factors, FactorSnapshots)
+	}
+
+	var rdm, independent, large bool
+	for _, disk := range vm.Disks {
+		rdm = rdm || disk.IsRDM()
+		independent = independent || disk.IsIndependent()
+		large = large || (cfg.LargeDiskGiB > 0 && disk.Size >= cfg.LargeDiskGiB)
+	}
+	if rdm {
+		factors = append(factors, FactorRDM)
+	}
+	if metadataInt(vm.Metadata, "passthrough_devices") > 0 {
+		factors = append(factors, FactorPassthrough)
+	}
+	if _, ok := vm.Metadata["fault_tolerance"]; ok {
+		factors = append(factors, FactorFaultTolerance)
+	}
+	if cfg.MaxNICs > 0 && len(vm.NetworkCards) > cfg.MaxNICs {
+		factors = append(factors, FactorManyNICs)
+	}
+	if independent {
+		factors = append(factors, FactorIndependentDisks)
+	}
+	if large {
+		factors = append(factors, FactorLargeDisks)
+	}
+
+	guest := strings.ToLower(vm.Config.GuestID)
+	if vm.OperatingSystem == "" && (guest == "" || guest == "other" || strings.HasPrefix(guest, "otherguest")) {
+		factors = append(factors, FactorUnknownOS)
+	}
+	if vm.Tools.Status == "toolsNotInstalled" {
+		factors = append(factors, FactorMissingTools)
+	}
+
+	return factors
+}
+
+// ComplexityFactors returns the factors recorded for a VM by ScoreComplexity
+func ComplexityFactors(vm models.VirtualMachine) []string {
+	switch factors := vm.Metadata[ComplexityFactorsKey].(type) {
+	case []string:
+		return factors
+	case []interface{}:
+		// Read back from a discovery file
+		names := make([]string, 0, len(factors))
+		for _, factor := range factors {
+			names = append(names, fmt.Sprint(factor))
+		}
+		return names
+	}
+	return nil
+}
+
+// metadataInt reads a count from VM metadata, which holds float64 values
+// once read back from a discovery file
+func metadataInt(metadata map[string]interface{}, key string) int {
+	switch v := metadata[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,52 @@
+package enrich
+
+import (
+	"fmt"
+
+	"valhalla/internal/models"
+)
+
+// DiskChainRule is the finding rule for disks with long delta disk chains
+const DiskChainRule = "disk-chain"
+
+// MaxDiskChainDepth is the number of delta disks above which a chain is
+// reported; each level adds read latency and consolidation time
+const MaxDiskChainDepth = 5
+
+// DiskChainAdvisories adds a warning finding for every disk whose delta
+// disk chain is deeper than MaxDiskChainDepth. Advisories from an earlier
+// run are replaced, so it can be applied again to a loaded discovery file.
+func DiskChainAdvisories(infrastructures []*models.Infrastructure) int {
+	count := 0
+	for _, infra := range infrastructures {
+		findings := infra.Findings[:0]
+		for _, finding := range infra.Findings {
+			if finding.Rule != DiskChainRule {
+				findings = append(findings, finding)
+			}
+		}
+		infra.Findings = findings
+
+		for _, vm := range infra.VirtualMachines {
+			for _, disk := range vm.Disks {
+				if disk.ChainDepth <= MaxDiskChainDepth {
+					continue
+				}
+
+				message := fmt.Sprintf("Disk %s has a chain of %d delta disks; consolidate before migrating", disk.ID, disk.ChainDepth)
+				if vm.Config.LinkedCloneOf != "" {
+					message += fmt.Sprintf(" (linked clone of %s)", vm.Config.LinkedCloneOf)
+				}
+
+				infra.Findings = append(infra.Findings, models.Finding{
+					Severity: "warning",
+					Rule:     DiskChainRule,
+					Resource: vm.Name,
+					Message:  message,
+				})
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,145 @@
+package enrich
+
+import (
+	"sort"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// Live migration compatibility between two clusters
+const (
+	MigrationCompatible   = "compatible"
+	MigrationIncompatible = "incompatible"
+	MigrationUnknown      = "unknown"
+)
+
+// evcModes lists the EVC baselines of each CPU vendor, oldest first. A VM
+// running at one level can live migrate to any cluster at the same or a
+// newer level of the same vendor.
+var evcModes = map[string][]string{
+	"intel": {
+		"intel-merom", "intel-penryn", "intel-nehalem", "intel-westmere",
+		"intel-sandybridge", "intel-ivybridge", "intel-haswell", "intel-broadwell",
+		"intel-skylake", "intel-cascadelake", "intel-icelake", "intel-sapphirerapids",
+	},
+	"amd": {
+		"amd-rev-e", "amd-rev-f", "amd-greyhound-no3dnow", "amd-greyhound",
+		"amd-bulldozer", "amd-piledriver", "amd-steamroller", "amd-zen",
+		"amd-zen2", "amd-zen3", "amd-zen4",
+	},
+}
+
+// evcLevel returns the vendor and rank of an EVC mode, or ok false when the
+// mode is empty or not known
+func evcLevel(mode string) (vendor string, rank int, ok bool) {
+	mode = strings.ToLower(mode)
+	for vendor, modes := range evcModes {
+		for i, m := range modes {
+			if m == mode {
+				return vendor, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// MigrationPair is the live migration compatibility from one cluster to another
+type MigrationPair struct {
+	Source string `json:"source" yaml:"source"`
+	Target string `json:"target" yaml:"target"`
+	Status string `json:"status" yaml:"status"`
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// clusterCPU is the CPU feature level VMs see in a cluster (what they can
+// leave with) and the level every host can provide (what they can arrive at)
+type clusterCPU struct {
+	vendor         string
+	source, target int
+	known          bool
+	reason         string
+}
+
+// clusterLevel derives a cluster's CPU level from its EVC mode, or from its
+// hosts' CPU generations when EVC is off
+func clusterLevel(cluster models.Cluster, hosts []models.Host) clusterCPU {
+	if cluster.EVCMode != "" {
+		vendor, rank, ok := evcLevel(cluster.EVCMode)
+		if !ok {
+			return clusterCPU{reason: "unrecognised EVC mode " + cluster.EVCMode + " on " + cluster.Name}
+		}
+		return clusterCPU{vendor: vendor, source: rank, target: rank, known: true}
+	}
+
+	level := clusterCPU{source: -1, target: -1}
+	for _, host := range hosts {
+		if host.Cluster != cluster.Name {
+			continue
+		}
+		vendor, rank, ok := evcLevel(host.CPUGeneration)
+		if !ok {
+			return clusterCPU{reason: "unknown CPU generation on host " + host.Name}
+		}
+		if level.vendor != "" && level.vendor != vendor {
+			return clusterCPU{reason: cluster.Name + " mixes CPU vendors"}
+		}
+		level.vendor = vendor
+		// Without EVC a VM powered on the newest host may use all its features
+		if rank > level.source {
+			level.source = rank
+		}
+		if level.target < 0 || rank < level.target {
+			level.target = rank
+		}
+	}
+	if level.vendor == "" {
+		return clusterCPU{reason: "no host CPU data for " + cluster.Name}
+	}
+	level.known = true
+	return level
+}
+
+// LiveMigrationMatrix reports, for every ordered pair of distinct clusters,
+// whether running VMs can be live migrated from one to the other based on
+// their EVC modes and host CPU generations
+func LiveMigrationMatrix(clusters []models.Cluster, hosts []models.Host) []MigrationPair {
+	levels := make(map[string]clusterCPU, len(clusters))
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		levels[cluster.Name] = clusterLevel(cluster, hosts)
+		names = append(names, cluster.Name)
+	}
+	sort.Strings(names)
+
+	var pairs []MigrationPair
+	for _, source := range names {
+		for _, target := range names {
+			if source == target {
+				continue
+			}
+			pairs = append(pairs, migrationPair(source, target, levels[source], levels[target]))
+		}
+	}
+	return pairs
+}
+
+// migrationPair compares the level VMs leave source with to the level
+// target can provide
+func migrationPair(source, target string, from, to clusterCPU) MigrationPair {
+	pair := MigrationPair{Source: source, Target: target}
+	switch {
+	case !from.known:
+		pair.Status, pair.Reason = MigrationUnknown, from.reason
+	case !to.known:
+		pair.Status, pair.Reason = MigrationUnknown, to.reason
+	case from.vendor != to.vendor:
+		pair.Status, pair.Reason = MigrationIncompatible, "CPU vendor differs"
+	case from.source > to.target:
+		pair.Status = MigrationIncompatible
+		pair.Reason = "requires " + evcModes[from.vendor][from.source] + ", target provides " + evcModes[to.vendor][to.target]
+	default:
+		pair.Status = MigrationCompatible
+	}
+	return pair
+}
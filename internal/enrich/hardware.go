@@ -0,0 +1,85 @@
+package enrich
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// HardwareAdvisoryRule is the finding rule for VMs below the minimum
+// virtual hardware version
+const HardwareAdvisoryRule = "hardware-version"
+
+// hardwareFeatures lists notable features by the VMware virtual hardware
+// version that introduced them, oldest first
+var hardwareFeatures = []struct {
+	version int
+	feature string
+}{
+	{10, "SATA controllers"},
+	{11, "128 vCPUs and 4 TB memory"},
+	{13, "NVMe controllers"},
+	{13, "UEFI secure boot"},
+	{14, "virtual TPM"},
+	{14, "virtualization-based security"},
+	{14, "per-VM EVC"},
+	{15, "256 vCPUs"},
+	{17, "virtual watchdog timer"},
+	{17, "precision clock"},
+	{17, "virtual SGX"},
+}
+
+// MissingHardwareFeatures lists the features a VM on hardware version
+// current lacks compared to version target
+func MissingHardwareFeatures(current, target int) []string {
+	var missing []string
+	for _, f := range hardwareFeatures {
+		if f.version > current && f.version <= target {
+			missing = append(missing, f.feature)
+		}
+	}
+	return missing
+}
+
+// HardwareAdvisories adds an info finding for every VMware VM whose virtual
+// hardware version is below minVersion, naming the features it is missing.
+// Advisories from an earlier run are replaced, so it can be applied again to
+// a loaded discovery file. A minVersion of 0 only clears them.
+func HardwareAdvisories(infrastructures []*models.Infrastructure, minVersion int) int {
+	count := 0
+	for _, infra := range infrastructures {
+		findings := infra.Findings[:0]
+		for _, finding := range infra.Findings {
+			if finding.Rule != HardwareAdvisoryRule {
+				findings = append(findings, finding)
+			}
+		}
+		infra.Findings = findings
+
+		if minVersion <= 0 {
+			continue
+		}
+
+		for _, vm := range infra.VirtualMachines {
+			version := vm.Hardware.VersionNumber()
+			if vm.Config.Template || version == 0 || version >= minVersion {
+				continue
+			}
+
+			message := fmt.Sprintf("Hardware version %s is below vmx-%d", vm.Hardware.Version, minVersion)
+			if missing := MissingHardwareFeatures(version, minVersion); len(missing) > 0 {
+				message += "; missing " + strings.Join(missing, ", ")
+			}
+
+			infra.Findings = append(infra.Findings, models.Finding{
+				Severity: "info",
+				Rule:     HardwareAdvisoryRule,
+				Resource: vm.Name,
+				Message:  message,
+			})
+			count++
+		}
+	}
+	return count
+}
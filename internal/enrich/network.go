@@ -0,0 +1,143 @@
+package enrich
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// defaultNetworkCheckTimeout bounds lookups and probes when no timeout is
+// configured
+const defaultNetworkCheckTimeout = 2 * time.Second
+
+// NetworkCheck is what VerifyNetwork found for a VM's primary IP, kept in
+// Metadata["network_check"]
+type NetworkCheck struct {
+	IP             string `json:"ip"`
+	ResolvedName   string `json:"resolved_name,omitempty"`   // reverse DNS name, without the trailing dot
+	RDNSMatch      bool   `json:"rdns_match"`                // the resolved name resolves back to IP
+	Probed         bool   `json:"probed"`                    // false when policy skipped the port probes
+	ReachablePorts []int  `json:"reachable_ports,omitempty"` // probed ports accepting connections
+}
+
+// NetworkCheckStats summarizes a VerifyNetwork run
+type NetworkCheckStats struct {
+	Checked     int
+	Skipped     int      // VMs probed for DNS only because their IP is public
+	Unreachable []string // probed VMs with none of the ports open
+	Mismatched  []string // VMs without reverse DNS or whose name doesn't resolve back
+}
+
+// VerifyNetwork checks the primary IP of every VM that has one: it looks up
+// the reverse DNS name, confirms that name resolves back to the IP and, for
+// private addresses or when ProbePublic is set, tries a TCP connection to
+// each configured port. Results replace Metadata["network_check"].
+func VerifyNetwork(ctx context.Context, infrastructures []*models.Infrastructure, cfg config.NetworkCheckConfig) NetworkCheckStats {
+	var vms []*models.VirtualMachine
+	for _, infra := range infrastructures {
+		for i := range infra.VirtualMachines {
+			vm := &infra.VirtualMachines[i]
+			delete(vm.Metadata, "network_check")
+			if net.ParseIP(vm.IPAddress) != nil {
+				vms = append(vms, vm)
+			}
+		}
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultNetworkCheckTimeout
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	checks := make([]NetworkCheck, len(vms))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, vm := range vms {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, ip string) {
+			defer func() { <-slots; wg.Done() }()
+			checks[i] = checkAddress(ctx, ip, cfg)
+		}(i, vm.IPAddress)
+	}
+	wg.Wait()
+
+	var stats NetworkCheckStats
+	for i, vm := range vms {
+		check := checks[i]
+		if vm.Metadata == nil {
+			vm.Metadata = make(map[string]interface{})
+		}
+		vm.Metadata["network_check"] = check
+
+		stats.Checked++
+		if !check.RDNSMatch {
+			stats.Mismatched = append(stats.Mismatched, vm.Name)
+		}
+		switch {
+		case !check.Probed:
+			stats.Skipped++
+		case len(cfg.Ports) > 0 && len(check.ReachablePorts) == 0:
+			stats.Unreachable = append(stats.Unreachable, vm.Name)
+		}
+	}
+	sort.Strings(stats.Unreachable)
+	sort.Strings(stats.Mismatched)
+	return stats
+}
+
+// checkAddress resolves and probes one IP
+func checkAddress(ctx context.Context, ip string, cfg config.NetworkCheckConfig) NetworkCheck {
+	check := NetworkCheck{IP: ip}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	names, err := net.DefaultResolver.LookupAddr(lookupCtx, ip)
+	cancel()
+	if err == nil && len(names) > 0 {
+		check.ResolvedName = strings.TrimSuffix(names[0], ".")
+
+		lookupCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		addrs, err := net.DefaultResolver.LookupHost(lookupCtx, check.ResolvedName)
+		cancel()
+		if err == nil {
+			want := net.ParseIP(ip)
+			for _, addr := range addrs {
+				if want.Equal(net.ParseIP(addr)) {
+					check.RDNSMatch = true
+					break
+				}
+			}
+		}
+	}
+
+	if !cfg.ProbePublic && !isInternal(net.ParseIP(ip)) {
+		return check
+	}
+	check.Probed = true
+
+	dialer := net.Dialer{Timeout: cfg.Timeout}
+	for _, port := range cfg.Ports {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		check.ReachablePorts = append(check.ReachablePorts, port)
+	}
+	return check
+}
+
+// isInternal reports whether ip is private (RFC 1918 or IPv6 unique local),
+// loopback or link-local, the addresses probed by default
+func isInternal(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+}
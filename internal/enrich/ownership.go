@@ -0,0 +1,224 @@
+// Package enrich derives additional information about discovered resources
+// from data already present in discovery results.
+package enrich
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"valhalla/internal/config"
+	"valhalla/internal/models"
+)
+
+// Ownership fields a rule can populate
+const (
+	FieldOwner       = "owner"
+	FieldEnvironment = "environment"
+)
+
+// Rule sources
+const (
+	SourceAnnotation = "annotation"
+	SourceTag        = "tag"
+	SourceFolder     = "folder"
+	SourceDefault    = "default"
+)
+
+// OwnershipRules is a compiled, ordered list of ownership rules
+type OwnershipRules struct {
+	rules []compiledRule
+}
+
+// compiledRule is a validated rule with its folder pattern compiled
+type compiledRule struct {
+	config.OwnershipRule
+	pattern *regexp.Regexp
+}
+
+// RuleStats reports how many VMs a rule assigned a value to
+type RuleStats struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Matches int    `json:"matches"`
+}
+
+// OwnershipStats reports rule coverage across the VMs that were enriched
+type OwnershipStats struct {
+	VMs          int         `json:"vms"`
+	Owners       int         `json:"owners"`       // VMs with an owner after enrichment
+	Environments int         `json:"environments"` // VMs with an environment after enrichment
+	Rules        []RuleStats `json:"rules"`
+}
+
+// CompileOwnershipRules validates rules and compiles their folder patterns
+func CompileOwnershipRules(rules []config.OwnershipRule) (*OwnershipRules, error) {
+	compiled := &OwnershipRules{}
+
+	for i, rule := range rules {
+		rule.Field = strings.ToLower(rule.Field)
+		if rule.Field == "" {
+			rule.Field = FieldOwner
+		}
+		if rule.Field != FieldOwner && rule.Field != FieldEnvironment {
+			return nil, fmt.Errorf("ownership rule %d: unknown field %q (expected owner or environment)", i+1, rule.Field)
+		}
+
+		c := compiledRule{OwnershipRule: rule}
+		c.Source = strings.ToLower(rule.Source)
+
+		switch c.Source {
+		case SourceAnnotation, SourceTag:
+			if rule.Key == "" {
+				return nil, fmt.Errorf("ownership rule %d: %s rules need a key", i+1, c.Source)
+			}
+		case SourceFolder:
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("ownership rule %d: invalid pattern: %w", i+1, err)
+			}
+			c.pattern = pattern
+		case SourceDefault:
+			if rule.Value == "" {
+				return nil, fmt.Errorf("ownership rule %d: default rules need a value", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("ownership rule %d: unknown source %q (expected annotation, tag, folder or default)", i+1, rule.Source)
+		}
+
+		compiled.rules = append(compiled.rules, c)
+	}
+
+	return compiled, nil
+}
+
+// Empty reports whether there are no rules to apply
+func (r *OwnershipRules) Empty() bool {
+	return r == nil || len(r.rules) == 0
+}
+
+// Apply sets Owner and Environment on every VM from the first rule that
+// matches for each field. A VM no rule matches keeps its existing values.
+func (r *OwnershipRules) Apply(infrastructures []*models.Infrastructure) OwnershipStats {
+	stats := OwnershipStats{}
+	for _, rule := range r.rules {
+		stats.Rules = append(stats.Rules, RuleStats{Rule: rule.describe(), Field: rule.Field})
+	}
+
+	for _, infra := range infrastructures {
+		for i := range infra.VirtualMachines {
+			vm := &infra.VirtualMachines[i]
+			stats.VMs++
+
+			assigned := make(map[string]bool)
+			for j, rule := range r.rules {
+				if assigned[rule.Field] {
+					continue
+				}
+				value, ok := rule.match(vm)
+				if !ok {
+					continue
+				}
+
+				assigned[rule.Field] = true
+				stats.Rules[j].Matches++
+				if rule.Field == FieldOwner {
+					vm.Owner = value
+				} else {
+					vm.Environment = value
+				}
+			}
+
+			if vm.Owner != "" {
+				stats.Owners++
+			}
+			if vm.Environment != "" {
+				stats.Environments++
+			}
+		}
+	}
+
+	return stats
+}
+
+// match returns the value a rule derives for a VM
+func (r compiledRule) match(vm *models.VirtualMachine) (string, bool) {
+	switch r.Source {
+	case SourceAnnotation:
+		return annotationValue(vm.Annotations, r.Key)
+	case SourceTag:
+		return tagValue(vm.Tags, r.Key)
+	case SourceFolder:
+		match := r.pattern.FindStringSubmatch(vm.Folder)
+		if match == nil {
+			return "", false
+		}
+		value := match[0]
+		if len(match) > 1 {
+			value = match[1]
+		}
+		return value, value != ""
+	case SourceDefault:
+		return r.Value, true
+	}
+	return "", false
+}
+
+// describe returns a short human-readable form of the rule
+func (r compiledRule) describe() string {
+	switch r.Source {
+	case SourceFolder:
+		return fmt.Sprintf("folder =~ %s", r.Pattern)
+	case SourceDefault:
+		return fmt.Sprintf("default %s", r.Value)
+	default:
+		return fmt.Sprintf("%s %s", r.Source, r.Key)
+	}
+}
+
+// annotationValue looks up key in the annotations, falling back to a
+// "key: value" or "key=value" line inside free-form notes. Annotations are
+// read in name order so the same VM always gets the same value.
+func annotationValue(annotations map[string]string, key string) (string, bool) {
+	names := make([]string, 0, len(annotations))
+	for name := range annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if value := strings.TrimSpace(annotations[name]); strings.EqualFold(name, key) && value != "" {
+			return value, true
+		}
+	}
+
+	for _, name := range names {
+		for _, line := range strings.Split(annotations[name], "\n") {
+			sep := strings.IndexAny(line, ":=")
+			if sep < 0 || !strings.EqualFold(strings.TrimSpace(line[:sep]), key) {
+				continue
+			}
+			if value := strings.TrimSpace(line[sep+1:]); value != "" {
+				return value, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// tagValue finds a tag in category, written as "Category:Value" or
+// "Category/Value"
+func tagValue(tags []string, category string) (string, bool) {
+	for _, tag := range tags {
+		sep := strings.IndexAny(tag, ":/")
+		if sep < 0 || !strings.EqualFold(strings.TrimSpace(tag[:sep]), category) {
+			continue
+		}
+		if value := strings.TrimSpace(tag[sep+1:]); value != "" {
+			return value, true
+		}
+	}
+	return "", false
+}
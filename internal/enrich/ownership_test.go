@@ -0,0 +1,27 @@
+package enrich
+
+import "testing"
+
+func TestAnnotationValueIsDeterministic(t *testing.T) {
+	annotations := map[string]string{
+		"Owner":  "team-a",
+		"owner":  "team-b",
+		"notes":  "owner: team-c",
+		"readme": "Owner = team-d",
+	}
+
+	// Map iteration order changes between runs, so repeat the lookup
+	for i := 0; i < 50; i++ {
+		if got, _ := annotationValue(annotations, "owner"); got != "team-a" {
+			t.Fatalf("annotationValue = %q, want team-a", got)
+		}
+	}
+
+	delete(annotations, "Owner")
+	delete(annotations, "owner")
+	for i := 0; i < 50; i++ {
+		if got, _ := annotationValue(annotations, "owner"); got != "team-c" {
+			t.Fatalf("annotationValue from notes = %q, want team-c", got)
+		}
+	}
+}
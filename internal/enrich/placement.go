@@ -0,0 +1,272 @@
+package enrich
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"valhalla/internal/models"
+)
+
+// RebalanceAdvisory is the note every rebalancing plan carries
+const RebalanceAdvisory = "Advisory only: proposed from discovered utilization and disk placement, no changes were made"
+
+// datastoreState tracks a datastore's projected usage while moves are planned
+type datastoreState struct {
+	store *models.Storage
+	used  int64
+
+	// hosts that can see the datastore; nil when unknown, taken as all hosts
+	hosts map[string]bool
+}
+
+// limit is the usage in GiB the datastore should stay at or under
+func (d *datastoreState) limit(threshold float64) float64 {
+	return threshold / 100 * float64(d.store.Capacity)
+}
+
+// over reports whether the datastore is above the threshold
+func (d *datastoreState) over(threshold float64) bool {
+	return float64(d.used) > d.limit(threshold)
+}
+
+// utilization is the used percentage, to one decimal place
+func (d *datastoreState) utilization() float64 {
+	return math.Round(float64(d.used)/float64(d.store.Capacity)*1000) / 10
+}
+
+// visibleFrom reports whether a VM on host can use the datastore
+func (d *datastoreState) visibleFrom(host string) bool {
+	return host == "" || d.hosts == nil || d.hosts[host]
+}
+
+// label names the datastore, qualified by node for per-node local storage
+func (d *datastoreState) label() string {
+	if node, _ := d.store.Metadata["node"].(string); d.store.Local && node != "" {
+		return fmt.Sprintf("%s (%s)", d.store.Name, node)
+	}
+	return d.store.Name
+}
+
+// placementCandidate is a VM whose disks on an over-threshold datastore
+// could move off it together
+type placementCandidate struct {
+	vm   *models.VirtualMachine
+	size int64
+}
+
+// RebalanceStorage sets a rebalancing plan on every infrastructure with a
+// datastore above threshold percent used, replacing any earlier plan, and
+// returns the number of proposed moves. A threshold of 0 only clears plans.
+func RebalanceStorage(infrastructures []*models.Infrastructure, threshold float64) int {
+	count := 0
+	for _, infra := range infrastructures {
+		infra.Rebalancing = nil
+		if threshold <= 0 {
+			continue
+		}
+
+		infra.Rebalancing = PlanRebalancing(infra, threshold)
+		if infra.Rebalancing != nil {
+			count += len(infra.Rebalancing.Moves)
+		}
+	}
+	return count
+}
+
+// PlanRebalancing proposes moving VMs off datastores above threshold
+// percent used, fullest first, or returns nil when none are. Each datastore
+// sheds the fewest VMs that bring it under the threshold: the largest VMs
+// go first, except that once one VM suffices the smallest such VM is moved.
+// A VM only moves to a datastore its host can see, of the same SSD or
+// non-SSD kind, that stays under the threshold after taking it, preferring
+// the one left with the most headroom. Raw device mappings never move.
+func PlanRebalancing(infra *models.Infrastructure, threshold float64) *models.RebalancePlan {
+	var states, over []*datastoreState
+	for i := range infra.Storage {
+		store := &infra.Storage[i]
+		if !store.Accessible || store.Capacity <= 0 {
+			continue
+		}
+
+		state := &datastoreState{store: store, used: store.UsedSpace, hosts: storageHosts(*store)}
+		states = append(states, state)
+		if state.over(threshold) {
+			over = append(over, state)
+		}
+	}
+	if len(over) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(over, func(i, j int) bool {
+		if over[i].utilization() != over[j].utilization() {
+			return over[i].utilization() > over[j].utilization()
+		}
+		return over[i].store.Name < over[j].store.Name
+	})
+
+	plan := &models.RebalancePlan{Advisory: RebalanceAdvisory, Threshold: threshold}
+	for _, source := range over {
+		candidates := placementCandidates(infra.VirtualMachines, source, states)
+
+		for source.over(threshold) {
+			need := float64(source.used) - source.limit(threshold)
+			index, target := pickMove(candidates, need, source, states, threshold)
+			if index < 0 {
+				break
+			}
+
+			candidate := candidates[index]
+			plan.Moves = append(plan.Moves, models.StorageMove{
+				VM:   candidate.vm.Name,
+				From: source.label(),
+				To:   target.label(),
+				Size: candidate.size,
+			})
+			source.used -= candidate.size
+			target.used += candidate.size
+			candidates = append(candidates[:index], candidates[index+1:]...)
+		}
+
+		if source.over(threshold) {
+			plan.Unresolved = append(plan.Unresolved, models.OverThreshold{
+				Datastore:   source.label(),
+				Utilization: source.utilization(),
+			})
+		}
+	}
+
+	return plan
+}
+
+// placementCandidates lists the VMs with disks on source, largest footprint
+// first
+func placementCandidates(vms []models.VirtualMachine, source *datastoreState, states []*datastoreState) []placementCandidate {
+	var candidates []placementCandidate
+	for i := range vms {
+		vm := &vms[i]
+
+		var size int64
+		for _, disk := range vm.Disks {
+			if disk.IsRDM() || datastoreFor(disk, vm.Host, states) != source {
+				continue
+			}
+			size += disk.Size
+		}
+		if size > 0 {
+			candidates = append(candidates, placementCandidate{vm: vm, size: size})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].size != candidates[j].size {
+			return candidates[i].size > candidates[j].size
+		}
+		return candidates[i].vm.Name < candidates[j].vm.Name
+	})
+	return candidates
+}
+
+// pickMove chooses the next candidate to move off source and where to, or
+// returns -1 when no candidate has a target. Candidates are largest first.
+func pickMove(candidates []placementCandidate, need float64, source *datastoreState, states []*datastoreState, threshold float64) (int, *datastoreState) {
+	index := -1
+	var target *datastoreState
+
+	for i, candidate := range candidates {
+		best := placementTarget(candidate, source, states, threshold)
+		if best == nil {
+			continue
+		}
+
+		// Take the largest movable VM, or the smallest that finishes the job
+		if index < 0 || float64(candidate.size) >= need {
+			index, target = i, best
+		}
+		if float64(candidate.size) < need {
+			break
+		}
+	}
+
+	return index, target
+}
+
+// placementTarget returns the datastore left with the most headroom after
+// taking candidate, among those it may move to, or nil if there are none
+func placementTarget(candidate placementCandidate, source *datastoreState, states []*datastoreState, threshold float64) *datastoreState {
+	var best *datastoreState
+	var bestHeadroom float64
+
+	for _, state := range states {
+		if state == source || state.store.SSD != source.store.SSD || !state.visibleFrom(candidate.vm.Host) {
+			continue
+		}
+
+		headroom := state.limit(threshold) - float64(state.used+candidate.size)
+		if headroom < 0 {
+			continue
+		}
+		if best == nil || headroom > bestHeadroom {
+			best, bestHeadroom = state, headroom
+		}
+	}
+
+	return best
+}
+
+// datastoreFor finds the datastore a disk is on, by ID or name since
+// VMware disks carry the datastore's moref, telling apart local storage of
+// the same name on different hosts by the VM's host
+func datastoreFor(disk models.Disk, host string, states []*datastoreState) *datastoreState {
+	var found *datastoreState
+	for _, state := range states {
+		if !onDatastore(disk, *state.store) {
+			continue
+		}
+		if state.visibleFrom(host) {
+			return state
+		}
+		if found == nil {
+			found = state
+		}
+	}
+	return found
+}
+
+// onDatastore reports whether a disk is on store
+func onDatastore(disk models.Disk, store models.Storage) bool {
+	if disk.DatastoreID != "" {
+		return disk.DatastoreID == store.ID
+	}
+	return disk.Datastore == store.ID || disk.Datastore == store.Name
+}
+
+// storageHosts returns the hosts that can see a datastore: those recorded
+// in its metadata, or the node of per-node local storage. It is nil when
+// neither is known.
+func storageHosts(store models.Storage) map[string]bool {
+	var names []string
+	switch hosts := store.Metadata["hosts"].(type) {
+	case []string:
+		names = hosts
+	case []interface{}: // as loaded from a discovery file
+		for _, host := range hosts {
+			if name, ok := host.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	if node, _ := store.Metadata["node"].(string); len(names) == 0 && store.Local && node != "" {
+		names = []string{node}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	hosts := make(map[string]bool, len(names))
+	for _, name := range names {
+		hosts[name] = true
+	}
+	return hosts
+}
@@ -0,0 +1,177 @@
+package enrich
+
+import (
+	"reflect"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// datastore returns an accessible datastore with used of capacity GiB taken
+func datastore(id, name string, capacity, used int64) models.Storage {
+	return models.Storage{
+		ID: id, Name: name, Capacity: capacity, UsedSpace: used, FreeSpace: capacity - used, Accessible: true,
+	}
+}
+
+// seenBy restricts a datastore to the given hosts
+func seenBy(store models.Storage, hosts ...string) models.Storage {
+	store.Metadata = map[string]interface{}{"hosts": hosts}
+	return store
+}
+
+// vmOn returns a VM on host with one disk of size GiB on datastore
+func vmOn(name, host, datastore string, size int64) models.VirtualMachine {
+	return models.VirtualMachine{
+		Name: name,
+		Host: host,
+		Disks: []models.Disk{
+			{ID: "2000", Name: "Hard disk 1", Size: size, Type: "thin", Datastore: datastore},
+		},
+	}
+}
+
+func TestPlanRebalancing(t *testing.T) {
+	tests := []struct {
+		name       string
+		infra      models.Infrastructure
+		moves      []models.StorageMove
+		unresolved []models.OverThreshold
+	}{
+		{
+			name: "vmware disks name the datastore moref",
+			infra: models.Infrastructure{
+				Storage: []models.Storage{
+					datastore("datastore-1", "ds1", 1000, 900),
+					datastore("datastore-2", "ds2", 1000, 100),
+				},
+				VirtualMachines: []models.VirtualMachine{
+					vmOn("small", "", "datastore-1", 30),
+					vmOn("medium", "", "datastore-1", 120),
+					vmOn("large", "", "datastore-1", 200),
+				},
+			},
+			// 100 GiB must go: the smallest VM that is enough moves alone
+			moves: []models.StorageMove{{VM: "medium", From: "ds1", To: "ds2", Size: 120}},
+		},
+		{
+			name: "smallest set of VMs",
+			infra: models.Infrastructure{
+				Storage: []models.Storage{
+					datastore("ds-a", "a", 1000, 950),
+					datastore("ds-b", "b", 1000, 0),
+				},
+				VirtualMachines: []models.VirtualMachine{
+					vmOn("vm1", "", "a", 100),
+					vmOn("vm2", "", "a", 80),
+					vmOn("vm3", "", "a", 20),
+				},
+			},
+			moves: []models.StorageMove{
+				{VM: "vm1", From: "a", To: "b", Size: 100},
+				{VM: "vm2", From: "a", To: "b", Size: 80},
+			},
+		},
+		{
+			name: "target must be visible from the VM's host",
+			infra: models.Infrastructure{
+				Storage: []models.Storage{
+					seenBy(datastore("ds-a", "a", 1000, 900), "esx1", "esx2"),
+					seenBy(datastore("ds-b", "b", 1000, 0), "esx2"),
+					seenBy(datastore("ds-c", "c", 1000, 500), "esx1"),
+				},
+				VirtualMachines: []models.VirtualMachine{
+					vmOn("web", "esx1", "ds-a", 150),
+				},
+			},
+			moves: []models.StorageMove{{VM: "web", From: "a", To: "c", Size: 150}},
+		},
+		{
+			name: "ssd and non-ssd do not mix",
+			infra: models.Infrastructure{
+				Storage: func() []models.Storage {
+					ssd := datastore("ds-ssd", "flash", 1000, 0)
+					ssd.SSD = true
+					return []models.Storage{datastore("ds-a", "a", 1000, 900), ssd}
+				}(),
+				VirtualMachines: []models.VirtualMachine{
+					vmOn("web", "", "ds-a", 150),
+				},
+			},
+			unresolved: []models.OverThreshold{{Datastore: "a", Utilization: 90}},
+		},
+		{
+			name: "raw device mappings never move",
+			infra: models.Infrastructure{
+				Storage: []models.Storage{
+					datastore("ds-a", "a", 1000, 900),
+					datastore("ds-b", "b", 1000, 0),
+				},
+				VirtualMachines: []models.VirtualMachine{
+					{
+						Name: "sql",
+						Disks: []models.Disk{
+							{ID: "2000", Size: 150, Type: models.DiskTypeRDMPhysical, Datastore: "ds-a"},
+						},
+					},
+				},
+			},
+			unresolved: []models.OverThreshold{{Datastore: "a", Utilization: 90}},
+		},
+		{
+			name: "local storage of the same name on each node",
+			infra: models.Infrastructure{
+				Storage: func() []models.Storage {
+					pve1 := datastore("pve1/local", "local", 1000, 100)
+					pve1.Local, pve1.Metadata = true, map[string]interface{}{"node": "pve1"}
+					pve2 := datastore("pve2/local", "local", 1000, 900)
+					pve2.Local, pve2.Metadata = true, map[string]interface{}{"node": "pve2"}
+					return []models.Storage{pve1, pve2, seenBy(datastore("ceph", "ceph", 1000, 0), "pve1", "pve2")}
+				}(),
+				VirtualMachines: []models.VirtualMachine{
+					vmOn("app", "pve2", "local", 150),
+				},
+			},
+			moves: []models.StorageMove{{VM: "app", From: "local (pve2)", To: "ceph", Size: 150}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanRebalancing(&tt.infra, 80)
+			if plan == nil {
+				t.Fatal("PlanRebalancing returned no plan")
+			}
+			if plan.Advisory != RebalanceAdvisory {
+				t.Errorf("Advisory = %q", plan.Advisory)
+			}
+			if !reflect.DeepEqual(plan.Moves, tt.moves) {
+				t.Errorf("Moves = %+v, want %+v", plan.Moves, tt.moves)
+			}
+			if !reflect.DeepEqual(plan.Unresolved, tt.unresolved) {
+				t.Errorf("Unresolved = %+v, want %+v", plan.Unresolved, tt.unresolved)
+			}
+		})
+	}
+}
+
+func TestRebalanceStorage(t *testing.T) {
+	infra := &models.Infrastructure{
+		Storage: []models.Storage{
+			datastore("datastore-1", "ds1", 1000, 900),
+			datastore("datastore-2", "ds2", 1000, 100),
+		},
+		VirtualMachines: []models.VirtualMachine{vmOn("web", "", "datastore-1", 150)},
+	}
+	infrastructures := []*models.Infrastructure{infra}
+
+	if got := RebalanceStorage(infrastructures, 95); got != 0 || infra.Rebalancing != nil {
+		t.Errorf("under threshold: %d moves, plan %+v", got, infra.Rebalancing)
+	}
+	if got := RebalanceStorage(infrastructures, 80); got != 1 || infra.Rebalancing == nil {
+		t.Fatalf("over threshold: %d moves, want 1", got)
+	}
+	if got := RebalanceStorage(infrastructures, 0); got != 0 || infra.Rebalancing != nil {
+		t.Errorf("threshold 0 kept plan %+v", infra.Rebalancing)
+	}
+}
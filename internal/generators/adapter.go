@@ -0,0 +1,75 @@
+package generators
+
+import (
+	"valhalla/internal/models"
+	"valhalla/internal/version"
+)
+
+// vsphereAdapterTypes are the adapter_type values the vsphere Terraform
+// provider accepts, with the minimum vSphere version that supports each
+var vsphereAdapterTypes = []struct {
+	Type       string
+	MinVersion string
+}{
+	{models.NICTypeVmxnet3, ""},
+	{models.NICTypeE1000, ""},
+	{models.NICTypeE1000e, ""},
+	{models.NICTypeSRIOV, "6.0"},
+}
+
+// legacyAdapterTypes maps adapters the provider can't create to the nearest
+// supported one
+var legacyAdapterTypes = map[string]string{
+	models.NICTypePCNet32: models.NICTypeE1000,
+	models.NICTypeVmxnet:  models.NICTypeVmxnet3,
+	models.NICTypeVmxnet2: models.NICTypeVmxnet3,
+	models.NICTypeSRIOV:   models.NICTypeVmxnet3,
+}
+
+// VSphereAdapterTypes returns the adapter types valid for a vSphere version;
+// an unknown version allows all of them
+func VSphereAdapterTypes(vsphereVersion string) []string {
+	var valid []string
+	for _, adapter := range vsphereAdapterTypes {
+		if adapter.MinVersion == "" || !version.Below(vsphereVersion, adapter.MinVersion) {
+			valid = append(valid, adapter.Type)
+		}
+	}
+	return valid
+}
+
+// VSphereAdapterType returns the adapter type to emit for a discovered network
+// card type, or false if the attribute should be left to the provider default
+func (g *BaseGenerator) VSphereAdapterType(vm, nicType, version string) (string, bool) {
+	valid := VSphereAdapterTypes(version)
+	if contains(valid, nicType) {
+		return nicType, true
+	}
+
+	if mapped, ok := legacyAdapterTypes[nicType]; ok && contains(valid, mapped) {
+		g.Log().Warn("Mapping unsupported network adapter type",
+			"vm", vm, "type", nicType, "adapter_type", mapped, "version", version)
+		return mapped, true
+	}
+
+	g.Log().Warn("Omitting unknown network adapter type, provider default applies",
+		"vm", vm, "type", nicType)
+	return "", false
+}
+
+// vmwareVersion returns the vSphere version recorded at discovery, if any
+func vmwareVersion(infra *models.Infrastructure) string {
+	version, _ := infra.Metadata["provider_version"].(map[string]interface{})
+	v, _ := version["version"].(string)
+	return v
+}
+
+// contains reports whether values includes value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestLargeAnnotationsGoToNotesFiles(t *testing.T) {
+	runbook := strings.Repeat("step: restart the service and check the logs\n", 300*1024/45)
+
+	for _, format := range GetAvailableFormats() {
+		t.Run(format, func(t *testing.T) {
+			infra := vmwareFixture()
+			infra.VirtualMachines[0].Annotations = map[string]string{
+				"notes": runbook,
+				"owner": "team-web",
+			}
+
+			files := generate(t, format, []*models.Infrastructure{infra}, GenerateOptions{})
+
+			var notes []string
+			for path, content := range files {
+				if strings.HasPrefix(path, "notes/") {
+					notes = append(notes, path)
+					if !strings.Contains(content, runbook) {
+						t.Errorf("%s does not hold the whole annotation", path)
+					}
+					continue
+				}
+				if len(content) > len(runbook)/2 {
+					t.Errorf("%s is %d bytes; the annotation was inlined", path, len(content))
+				}
+			}
+			if len(notes) != 1 {
+				t.Fatalf("got notes files %v, want one", notes)
+			}
+
+			all := joined(files)
+			if !strings.Contains(all, "see "+notes[0]) {
+				t.Errorf("no comment refers to %s", notes[0])
+			}
+			if !strings.Contains(all, "owner: team-web") {
+				t.Errorf("small annotation was not inlined as a comment")
+			}
+		})
+	}
+}
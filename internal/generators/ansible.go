@@ -40,7 +40,7 @@ func (g *AnsibleGenerator) Generate(infrastructures []*models.Infrastructure, op
 	})
 
 	// Generate inventory
-	inventory := g.generateInventory(infrastructures)
+	inventory, hostNames, notes := g.generateInventory(infrastructures, opts)
 	results = append(results, &GenerateResult{
 		Path:      "inventory.yml",
 		Content:   []byte(inventory),
@@ -48,7 +48,9 @@ func (g *AnsibleGenerator) Generate(infrastructures []*models.Infrastructure, op
 		Type:      "inventory",
 		Provider:  "ansible",
 		Resources: []string{"inventory"},
+		Metadata:  map[string]interface{}{"resource_names": hostNames},
 	})
+	results = append(results, notes...)
 
 	// Generate group vars
 	groupVars := g.generateGroupVars(infrastructures)
@@ -61,6 +63,9 @@ func (g *AnsibleGenerator) Generate(infrastructures []*models.Infrastructure, op
 		Resources: []string{},
 	})
 
+	// Generate vault files for the credentials the group vars reference
+	results = append(results, g.generateVaultFiles(infrastructures, opts)...)
+
 	// Generate provider-specific playbooks
 	for _, infra := range infrastructures {
 		providerResults, err := g.generateForProvider(infra, opts)
@@ -70,8 +75,31 @@ func (g *AnsibleGenerator) Generate(infrastructures []*models.Infrastructure, op
 		results = append(results, providerResults...)
 	}
 
+	// Generate the playbook checking what the provider playbooks created
+	if verify := g.generateVerifyPlaybook(infrastructures); verify != "" {
+		results = append(results, &GenerateResult{
+			Path:      "verify.yml",
+			Content:   []byte(verify),
+			Size:      len(verify),
+			Type:      "playbook",
+			Provider:  "ansible",
+			Resources: []string{"playbook"},
+		})
+	}
+
+	// Post-clone instructions from annotations, only on request
+	if opts.EmitPostProvision {
+		var vms []models.VirtualMachine
+		for _, infra := range infrastructures {
+			vms = append(vms, infra.VirtualMachines...)
+		}
+		if doc := PostProvisionDoc(vms, "ansible"); doc != nil {
+			results = append(results, doc)
+		}
+	}
+
 	// Generate requirements
-	requirements := g.generateRequirements()
+	requirements := g.generateRequirements(infrastructures)
 	results = append(results, &GenerateResult{
 		Path:      "requirements.yml",
 		Content:   []byte(requirements),
@@ -81,6 +109,14 @@ func (g *AnsibleGenerator) Generate(infrastructures []*models.Infrastructure, op
 		Resources: []string{},
 	})
 
+	if err := g.RunHooks(results, opts); err != nil {
+		return nil, err
+	}
+
+	if err := g.CheckSecrets(results, opts); err != nil {
+		return nil, err
+	}
+
 	// Write files if not dry run
 	if !opts.DryRun {
 		for _, result := range results {
@@ -134,8 +170,48 @@ func (g *AnsibleGenerator) generateMainPlaybook(infrastructures []*models.Infras
 	return playbook
 }
 
-// generateInventory generates the Ansible inventory
-func (g *AnsibleGenerator) generateInventory(infrastructures []*models.Infrastructure) string {
+// generateVerifyPlaybook generates the playbook that runs the verification
+// tasks of each provider that has them, currently VMware, or "" when none
+// does
+func (g *AnsibleGenerator) generateVerifyPlaybook(infrastructures []*models.Infrastructure) string {
+	var tasks []string
+	for _, infra := range infrastructures {
+		switch strings.ToLower(infra.Provider) {
+		case "vmware", "vsphere":
+			tasks = append(tasks, "tasks/verify_vmware.yml")
+		}
+	}
+	if len(tasks) == 0 {
+		return ""
+	}
+
+	playbook := `---
+# Valhalla Generated Verification Playbook
+# Run after site.yml to check that the recreated resources exist with their
+# discovered settings:
+#   ansible-playbook -i inventory.yml verify.yml
+
+- name: Verify Infrastructure
+  hosts: localhost
+  gather_facts: false
+  vars:
+    ansible_python_interpreter: "{{ ansible_playbook_python }}"
+
+  tasks:
+    - name: Include provider-specific checks
+      include_tasks: "{{ item }}"
+      loop:
+`
+	for _, task := range tasks {
+		playbook += fmt.Sprintf("        - %s\n", task)
+	}
+	return playbook
+}
+
+// generateInventory generates the Ansible inventory, returning it with the
+// host name given to each VM and the notes files of annotations too large
+// to comment inline
+func (g *AnsibleGenerator) generateInventory(infrastructures []*models.Infrastructure, opts GenerateOptions) (string, map[string]string, []*GenerateResult) {
 	inventory := `---
 # Valhalla Generated Inventory
 # This inventory contains discovered infrastructure hosts
@@ -144,6 +220,10 @@ all:
   children:
 `
 
+	// Host names are inventory-wide, so they must be unique across groups
+	hosts := NewResourceNames(SanitizeHostname)
+	var notes []*GenerateResult
+
 	for _, infra := range infrastructures {
 		groupName := fmt.Sprintf("%s_%s", strings.ToLower(infra.Provider), 
 			strings.ReplaceAll(strings.ToLower(infra.Server), ".", "_"))
@@ -152,20 +232,44 @@ all:
       hosts:
 `, groupName)
 
-		for _, vm := range infra.VirtualMachines {
+		hostNames := hosts.VMs(infra.VirtualMachines)
+		for i, vm := range infra.VirtualMachines {
 			if vm.Config.Template {
 				continue
 			}
 			
-			hostName := strings.ReplaceAll(strings.ToLower(vm.Name), " ", "_")
+			// Provisioning records new addresses in vm_ip_addresses; until
+			// then the address discovered on the source VM is used
+			hostName := hostNames[i]
+			address := vm.PrimaryIPv4()
+			if address == "" {
+				address = "pending"
+			}
+			comments, sidecar := g.AnnotationComments(vm, hostName, "        #", opts.MaxFieldSize)
+			if sidecar != nil {
+				notes = append(notes, sidecar)
+			}
+			inventory += comments
 			inventory += fmt.Sprintf(`        %s:
-          ansible_host: "{{ vm_ip_addresses['%s'] | default('pending') }}"
+          ansible_host: "{{ vm_ip_addresses['%s'] | default('%s') }}"
           vm_name: "%s"
           vm_cpus: %d
           vm_memory: %d
           vm_os: "%s"
           vm_state: "%s"
-`, hostName, vm.Name, vm.Name, vm.CPUs, vm.Memory, vm.OperatingSystem, vm.State)
+`, hostName, EscapeYAML(vm.Name), address, EscapeYAML(vm.Name), vm.CPUs, vm.Memory, EscapeYAML(vm.OperatingSystem), vm.State)
+			if vm.Hostname != "" {
+				inventory += fmt.Sprintf("          vm_hostname: \"%s\"\n", EscapeYAML(vm.Hostname))
+			}
+			if vm.Type != "" {
+				inventory += fmt.Sprintf("          vm_type: \"%s\"\n", vm.Type)
+			}
+			if vm.Owner != "" {
+				inventory += fmt.Sprintf("          vm_owner: \"%s\"\n", EscapeYAML(vm.Owner))
+			}
+			if vm.Environment != "" {
+				inventory += fmt.Sprintf("          vm_environment: \"%s\"\n", EscapeYAML(vm.Environment))
+			}
 		}
 
 		inventory += fmt.Sprintf(`      vars:
@@ -173,11 +277,18 @@ all:
         provider_server: "%s"
         datacenter: "%s"
         cluster: "%s"
-
 `, infra.Provider, infra.Server, infra.Datacenter, infra.Cluster)
+		if strings.EqualFold(infra.Provider, "proxmox") {
+			host, port := proxmoxAPIEndpoint(infra.Server)
+			inventory += fmt.Sprintf(`        proxmox_node: "%s"
+        proxmox_api_host: "%s"
+        proxmox_api_port: %s
+`, EscapeYAML(proxmoxDefaultNode(infra)), host, port)
+		}
+		inventory += "\n"
 	}
 
-	return inventory
+	return inventory, hosts.Mapping, notes
 }
 
 // generateGroupVars generates group variables
@@ -216,18 +327,39 @@ providers:
     connection_timeout: 30
 `, provider, infra.Server, infra.Datacenter, infra.Cluster)
 
-		if provider == "vmware" || provider == "vsphere" {
-			groupVars += `    username: "{{ vsphere_username }}"
-    password: "{{ vsphere_password }}"
-`
-		} else if provider == "proxmox" {
-			groupVars += `    username: "{{ proxmox_username }}"
-    password: "{{ proxmox_password }}"
-    node: "{{ proxmox_node }}"
-`
+		if creds, ok := ansibleCredentialVars[ansibleProviderKey(provider)]; ok {
+			groupVars += fmt.Sprintf(`    username: "{{ %s }}"
+    password: "{{ %s }}"
+`, creds[0], creds[1])
+		}
+		if provider == "proxmox" {
+			// The Proxmox modules take the API host and port separately
+			host, port := proxmoxAPIEndpoint(infra.Server)
+			groupVars += fmt.Sprintf(`    node: "{{ proxmox_node }}"
+    api_host: "%s"
+    api_port: %s
+    # Container template to create LXC guests from; discovery can't tell
+    # which one a container was built with
+    lxc_ostemplate: "local:vztmpl/CHANGE_ME.tar.zst"
+`, host, port)
+		}
+		if provider == "nutanix" {
+			// The Nutanix modules take the Prism host and port separately
+			host, port := nutanixEndpoint(infra.Server)
+			groupVars += fmt.Sprintf(`    host: "%s"
+    port: %d
+`, EscapeYAML(host), port)
 		}
 	}
 
+	// Node for Proxmox guests whose node wasn't discovered
+	if infra := proxmoxInfrastructure(infrastructures); infra != nil {
+		groupVars += fmt.Sprintf(`
+# Proxmox node for guests whose node was not discovered
+proxmox_node: "%s"
+`, EscapeYAML(proxmoxDefaultNode(infra)))
+	}
+
 	groupVars += `
 # Network mappings (customize as needed)
 network_mappings:
@@ -255,7 +387,7 @@ datastore_mappings:
 	for _, infra := range infrastructures {
 		for _, storage := range infra.Storage {
 			if !datastores[storage.Name] {
-				groupVars += fmt.Sprintf(`  "%s": "%s"  # Type: %s, Capacity: %dGB
+				groupVars += fmt.Sprintf(`  "%s": "%s"  # Type: %s, Capacity: %dGiB
 `, storage.Name, storage.Name, storage.Type, storage.Capacity)
 				datastores[storage.Name] = true
 			}
@@ -265,6 +397,111 @@ datastore_mappings:
 	return groupVars
 }
 
+// ansibleCredentialVars lists the username and password variables that the
+// group vars reference for each provider
+var ansibleCredentialVars = map[string][]string{
+	"vmware":  {"vsphere_username", "vsphere_password"},
+	"proxmox": {"proxmox_username", "proxmox_password"},
+	"nutanix": {"nutanix_username", "nutanix_password"},
+}
+
+// ansibleProviderKey normalizes provider aliases for credential lookups
+func ansibleProviderKey(provider string) string {
+	provider = strings.ToLower(provider)
+	if provider == "vsphere" {
+		return "vmware"
+	}
+	return provider
+}
+
+// credentialVarNames returns the credential variables needed by the providers
+// present in the input, in the order the providers first appear
+func credentialVarNames(infrastructures []*models.Infrastructure) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, infra := range infrastructures {
+		provider := ansibleProviderKey(infra.Provider)
+		if seen[provider] {
+			continue
+		}
+		seen[provider] = true
+		names = append(names, ansibleCredentialVars[provider]...)
+	}
+	return names
+}
+
+// generateVaultFiles generates group_vars/all/vault.yml.example with
+// placeholders for every credential variable. With IncludeSecrets it also
+// writes a plaintext vault.yml filled from opts.Secrets and a .gitignore that
+// keeps it out of version control.
+func (g *AnsibleGenerator) generateVaultFiles(infrastructures []*models.Infrastructure, opts GenerateOptions) []*GenerateResult {
+	names := credentialVarNames(infrastructures)
+	if len(names) == 0 {
+		return nil
+	}
+
+	example := `---
+# Valhalla Generated Vault Variables
+# Copy to vault.yml, fill in the credentials and encrypt it before use:
+#   cp group_vars/all/vault.yml.example group_vars/all/vault.yml
+#   ansible-vault encrypt group_vars/all/vault.yml
+# Then run playbooks with --ask-vault-pass or --vault-password-file.
+
+`
+	for _, name := range names {
+		example += fmt.Sprintf("%s: \"CHANGE_ME\"\n", name)
+	}
+
+	results := []*GenerateResult{{
+		Path:      "group_vars/all/vault.yml.example",
+		Content:   []byte(example),
+		Size:      len(example),
+		Type:      "variables",
+		Provider:  "ansible",
+		Resources: names,
+	}}
+
+	if !opts.IncludeSecrets {
+		return results
+	}
+
+	vault := `---
+# Valhalla Generated Vault Variables - PLAINTEXT CREDENTIALS
+# Encrypt before sharing: ansible-vault encrypt group_vars/all/vault.yml
+
+`
+	for _, name := range names {
+		value := opts.Secrets[name]
+		if value == "" {
+			g.Log().Warn("No value available for credential variable", "variable", name)
+			value = "CHANGE_ME"
+		}
+		vault += fmt.Sprintf("%s: \"%s\"\n", name, EscapeYAML(value))
+	}
+
+	gitignore := `# Valhalla Generated - plaintext credentials must not be committed
+group_vars/all/vault.yml
+`
+
+	results = append(results, &GenerateResult{
+		Path:      "group_vars/all/vault.yml",
+		Content:   []byte(vault),
+		Size:      len(vault),
+		Type:      "secrets",
+		Provider:  "ansible",
+		Resources: names,
+	}, &GenerateResult{
+		Path:      ".gitignore",
+		Content:   []byte(gitignore),
+		Size:      len(gitignore),
+		Type:      "config",
+		Provider:  "ansible",
+		Resources: []string{},
+	})
+
+	return results
+}
+
 // generateForProvider generates provider-specific playbooks
 func (g *AnsibleGenerator) generateForProvider(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
 	switch strings.ToLower(infra.Provider) {
@@ -285,15 +522,27 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
 # VMware vSphere Tasks - Generated by Valhalla
 # Server: %s
 # Datacenter: %s
+`, infra.Server, infra.Datacenter)
+
+	// Folders and pools are created first so the VMs can be placed in them
+	var resources []string
+	if tasks := g.vmwareFolderTasks(infra); tasks != "" {
+		content += tasks
+		resources = append(resources, "vcenter_folder")
+	}
+	if tasks := g.vmwarePoolTasks(infra); tasks != "" {
+		content += tasks
+		resources = append(resources, "vmware_resource_pool")
+	}
+	resources = append(resources, "vmware_guest")
+	pools := vmResourcePools(infra.ResourcePools)
 
+	content += `
 - name: Create VMware Virtual Machines
   community.vmware.vmware_guest:
-    hostname: "{{ providers.vmware.server }}"
-    username: "{{ providers.vmware.username }}"
-    password: "{{ providers.vmware.password }}"
-    validate_certs: "{{ providers.vmware.validate_certs }}"
-    datacenter: "{{ providers.vmware.datacenter }}"
-    cluster: "{{ providers.vmware.cluster }}"
+` + vmwareConnection + `    cluster: "{{ providers.vmware.cluster }}"
+    folder: "{{ item.folder | default(omit) }}"
+    resource_pool: "{{ item.resource_pool | default(omit) }}"
     name: "{{ item.name }}"
     state: "{{ item.state | default('present') }}"
     guest_id: "{{ item.guest_id }}"
@@ -301,16 +550,19 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
       num_cpus: "{{ item.cpus }}"
       memory_mb: "{{ item.memory }}"
       scsi: paravirtual
+      version: "{{ item.hardware_version | default(omit) }}"
+    advanced_settings: "{{ item.advanced_settings | default(omit) }}"
     disk: "{{ item.disks }}"
     networks: "{{ item.networks }}"
+    customvalues: "{{ item.customvalues | default(omit) }}"
     wait_for_ip_address: "{{ wait_for_ip }}"
     wait_for_ip_address_timeout: "{{ wait_timeout }}"
   loop:
-`, infra.Server, infra.Datacenter)
+`
 
 	// Generate VM list
 	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
+		if vm.Config.Template || vm.IsContainer() {
 			continue
 		}
 
@@ -319,8 +571,31 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
       guest_id: "%s"
       cpus: %d
       memory: %d
-      disks:
-`, vm.Name, strings.ToLower(vm.State), vm.Config.GuestID, vm.CPUs, vm.Memory)
+`, EscapeYAML(vm.Name), strings.ToLower(vm.State), vm.Config.GuestID, vm.CPUs, vm.Memory)
+
+		if folder := vmFolderPath(vm.Folder); folder != "" {
+			content += fmt.Sprintf("      folder: \"/{{ providers.vmware.datacenter }}/vm/%s\"\n", EscapeYAML(folder))
+		}
+		if pool, ok := pools[vm.Name]; ok {
+			content += fmt.Sprintf("      resource_pool: \"%s\"\n", EscapeYAML(pool))
+		}
+
+		if hwVersion, upgradedFrom := g.HardwareVersion(vm, opts.UpgradeHWVersion); hwVersion > 0 {
+			if upgradedFrom != "" {
+				content += fmt.Sprintf("      # Upgraded from %s by --upgrade-hw-version\n", upgradedFrom)
+			}
+			content += fmt.Sprintf("      hardware_version: %d\n", hwVersion)
+		}
+
+		// Video card settings are only settable as VMX options
+		if settings := g.VideoExtraConfig(vm); len(settings) > 0 {
+			content += "      advanced_settings:\n"
+			for _, setting := range settings {
+				content += fmt.Sprintf("        - key: \"%s\"\n          value: \"%s\"\n", setting[0], setting[1])
+			}
+		}
+
+		content += "      disks:\n"
 
 		// Add disks
 		for i, disk := range vm.Disks {
@@ -334,11 +609,24 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
 
 		content += "      networks:\n"
 		// Add networks
-		for _, nic := range vm.NetworkCards {
-			content += fmt.Sprintf(`        - name: "{{ network_mappings['%s'] }}"
-          device_type: "%s"
-          start_connected: %t
-`, nic.Network, nic.Type, nic.StartConnect)
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			content += fmt.Sprintf("        - name: \"{{ network_mappings['%s'] }}\"\n", nic.Network)
+			// Leave unknown adapters to the module default
+			if nic.Type != models.NICTypeUnknown {
+				content += fmt.Sprintf("          device_type: \"%s\"\n", nic.Type)
+			}
+			content += fmt.Sprintf("          start_connected: %t\n", nic.StartConnect)
+		}
+
+		// Add inferred ownership as custom attributes
+		if vm.Owner != "" || vm.Environment != "" {
+			content += "      customvalues:\n"
+			if vm.Owner != "" {
+				content += fmt.Sprintf("        - key: Owner\n          value: \"%s\"\n", EscapeYAML(vm.Owner))
+			}
+			if vm.Environment != "" {
+				content += fmt.Sprintf("        - key: Environment\n          value: \"%s\"\n", EscapeYAML(vm.Environment))
+			}
 		}
 	}
 
@@ -363,6 +651,11 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
   loop: "{{ vm_deploy_result.results }}"
   when: vm_deploy_result is defined
 `
+	if opts.EmitPostProvision {
+		content += g.postProvisionTasks(infra.VirtualMachines)
+	}
+
+	verify := g.vmwareVerifyTasks(infra)
 
 	return []*GenerateResult{{
 		Path:      "tasks/vmware.yml",
@@ -370,70 +663,66 @@ func (g *AnsibleGenerator) generateVMware(infra *models.Infrastructure, opts Gen
 		Size:      len(content),
 		Type:      "tasks",
 		Provider:  "vmware",
-		Resources: []string{"vmware_guest"},
-	}}, nil
-}
-
-// generateProxmox generates Proxmox-specific Ansible tasks
-func (g *AnsibleGenerator) generateProxmox(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
-	content := `---
-# Proxmox Tasks - Generated by Valhalla
-# TODO: Implement Proxmox task generation
-
-- name: Proxmox infrastructure deployment
-  debug:
-    msg: "Proxmox Ansible tasks not yet implemented"
-`
-
-	return []*GenerateResult{{
-		Path:      "tasks/proxmox.yml",
-		Content:   []byte(content),
-		Size:      len(content),
+		Resources: resources,
+	}, {
+		Path:      "tasks/verify_vmware.yml",
+		Content:   []byte(verify),
+		Size:      len(verify),
 		Type:      "tasks",
-		Provider:  "proxmox",
-		Resources: []string{},
-	}}, nil
-}
-
-// generateNutanix generates Nutanix-specific Ansible tasks
-func (g *AnsibleGenerator) generateNutanix(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
-	content := `---
-# Nutanix Tasks - Generated by Valhalla
-# TODO: Implement Nutanix task generation
-
-- name: Nutanix infrastructure deployment
-  debug:
-    msg: "Nutanix Ansible tasks not yet implemented"
-`
-
-	return []*GenerateResult{{
-		Path:      "tasks/nutanix.yml",
-		Content:   []byte(content),
-		Size:      len(content),
-		Type:      "tasks",
-		Provider:  "nutanix",
-		Resources: []string{},
+		Provider:  "vmware",
+		Resources: []string{"assert"},
 	}}, nil
 }
 
-// generateRequirements generates Ansible requirements
-func (g *AnsibleGenerator) generateRequirements() string {
-	return `---
+// generateRequirements generates Ansible requirements. community.general
+// is only needed for the Proxmox modules, which it dropped in 11.0.0, and
+// nutanix.ncp for the Nutanix ones.
+func (g *AnsibleGenerator) generateRequirements(infrastructures []*models.Infrastructure) string {
+	requirements := `---
 # Ansible Requirements - Generated by Valhalla
 # Install with: ansible-galaxy install -r requirements.yml
 
 collections:
   - name: community.vmware
     version: ">=3.0.0"
-  - name: community.general
-    version: ">=5.0.0"
-  - name: ansible.posix
+`
+	if proxmoxInfrastructure(infrastructures) != nil {
+		requirements += `  - name: community.general
+    version: ">=7.0.0,<11.0.0"
+`
+	}
+	if nutanixInfrastructure(infrastructures) != nil {
+		requirements += `  - name: nutanix.ncp
+    version: ">=1.9.0"
+`
+	}
+	return requirements + `  - name: ansible.posix
     version: ">=1.0.0"
 
 roles: []
 `
 }
 
+// proxmoxInfrastructure returns the first Proxmox infrastructure, or nil
+func proxmoxInfrastructure(infrastructures []*models.Infrastructure) *models.Infrastructure {
+	for _, infra := range infrastructures {
+		if strings.EqualFold(infra.Provider, "proxmox") {
+			return infra
+		}
+	}
+	return nil
+}
+
+// nutanixInfrastructure returns the first Nutanix infrastructure, or nil
+func nutanixInfrastructure(infrastructures []*models.Infrastructure) *models.Infrastructure {
+	for _, infra := range infrastructures {
+		if strings.EqualFold(infra.Provider, "nutanix") {
+			return infra
+		}
+	}
+	return nil
+}
+
 // writeFile writes a generate result to a file
 func (g *AnsibleGenerator) writeFile(result *GenerateResult, outputDir string) error {
 	// Ensure output directory exists
@@ -442,9 +731,13 @@ func (g *AnsibleGenerator) writeFile(result *GenerateResult, outputDir string) e
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Write file
+	// Write file, keeping plaintext secrets readable by the owner only
+	mode := os.FileMode(0644)
+	if result.Type == "secrets" {
+		mode = 0600
+	}
 	filePath := filepath.Join(outputDir, result.Path)
-	if err := os.WriteFile(filePath, result.Content, 0644); err != nil {
+	if err := os.WriteFile(filePath, result.Content, mode); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -463,3 +756,28 @@ func (g *AnsibleGenerator) Validate(results []*GenerateResult) error {
 	g.Log().Info("Ansible validation not yet implemented")
 	return nil
 }
+
+// postProvisionTasks renders a commented-out post-task per VM with
+// post_provision.* annotations, for someone to turn into real steps
+func (g *AnsibleGenerator) postProvisionTasks(vms []models.VirtualMachine) string {
+	var tasks string
+	for _, vm := range vms {
+		steps := PostProvisionSteps(vm)
+		if vm.Config.Template || len(steps) == 0 {
+			continue
+		}
+		tasks += fmt.Sprintf(`
+# - name: Post-provision %s
+#   delegate_to: "%s"
+#   # Replace the instructions with real tasks before uncommenting
+%s#   ansible.builtin.debug:
+#     msg: "Post-provision steps for %s have not been written yet"
+`, EscapeYAML(vm.Name), EscapeYAML(vm.Name), postProvisionComment(steps, "#   # "), EscapeYAML(vm.Name))
+	}
+	if tasks == "" {
+		return ""
+	}
+	return `
+# Post-provision steps from annotations, see POST_PROVISION.md. They are
+# never run automatically.` + tasks
+}
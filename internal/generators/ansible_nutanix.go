@@ -0,0 +1,203 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// generateNutanix generates Nutanix-specific Ansible tasks: VMs are created
+// with nutanix.ncp.ntnx_vms on their discovered cluster, with disks on their
+// storage containers and NICs on their subnets, then the VMs that were
+// powered off are powered off again
+func (g *AnsibleGenerator) generateNutanix(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
+	vms, _ := countGuests(infra.VirtualMachines)
+	content := fmt.Sprintf(`---
+# Nutanix Tasks - Generated by Valhalla
+# Server: %s
+# VMs: %d
+`, infra.Server, vms)
+
+	var resources []string
+	if vms > 0 {
+		content += g.nutanixVMTasks(infra, opts)
+		resources = append(resources, "ntnx_vms")
+	} else {
+		content += `
+- name: Nutanix infrastructure deployment
+  debug:
+    msg: "No Nutanix VMs were discovered"
+`
+	}
+	if opts.EmitPostProvision {
+		content += g.postProvisionTasks(infra.VirtualMachines)
+	}
+
+	return []*GenerateResult{{
+		Path:      "tasks/nutanix.yml",
+		Content:   []byte(content),
+		Size:      len(content),
+		Type:      "tasks",
+		Provider:  "nutanix",
+		Resources: resources,
+	}}, nil
+}
+
+// nutanixVMTasks renders the tasks that create the VMs, one loop item per
+// VM, and power off those that were off
+func (g *AnsibleGenerator) nutanixVMTasks(infra *models.Infrastructure, opts GenerateOptions) string {
+	content := `
+- name: Create Nutanix Virtual Machines
+  nutanix.ncp.ntnx_vms:
+` + nutanixConnection + `    state: present
+    name: "{{ item.name }}"
+    cluster: "{{ item.cluster | default(omit) }}"
+    vcpus: "{{ item.vcpus }}"
+    cores_per_vcpu: "{{ item.cores_per_vcpu }}"
+    memory_gb: "{{ item.memory_gb }}"
+    boot_config: "{{ item.boot_config | default(omit) }}"
+    disks: "{{ item.disks | default(omit) }}"
+    networks: "{{ item.networks | default(omit) }}"
+    categories: "{{ item.categories | default(omit) }}"
+  loop:
+`
+
+	var unplaced []string
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+
+		content += fmt.Sprintf("    - name: \"%s\"\n", EscapeYAML(vm.Name))
+		content += fmt.Sprintf("      running: %t\n", !strings.EqualFold(vm.PowerState, "OFF"))
+		if cluster := nutanixCluster(infra, vm); cluster != "" {
+			content += fmt.Sprintf("      cluster:\n        name: \"%s\"\n", EscapeYAML(cluster))
+		} else {
+			unplaced = append(unplaced, vm.Name)
+			content += "      # No cluster was discovered for this VM: set cluster.name\n"
+		}
+
+		// ntnx_vms calls sockets vcpus
+		perSocket := vm.Hardware.NumCoresPerSocket
+		if perSocket <= 0 || vm.CPUs%perSocket != 0 {
+			perSocket = vm.CPUs
+		}
+		sockets := 1
+		if perSocket > 0 {
+			sockets = vm.CPUs / perSocket
+		}
+		content += fmt.Sprintf("      vcpus: %d\n      cores_per_vcpu: %d\n", sockets, perSocket)
+
+		// Memory can only be given in whole GiB
+		memoryGB := units.FromMiB(vm.Memory).CeilGiB()
+		if units.FromGiB(memoryGB) != units.FromMiB(vm.Memory) {
+			content += fmt.Sprintf("      # Rounded up from %d MiB\n", vm.Memory)
+		}
+		content += fmt.Sprintf("      memory_gb: %d\n", memoryGB)
+
+		if strings.EqualFold(vm.Hardware.Firmware, "EFI") {
+			content += "      boot_config:\n        boot_type: UEFI\n"
+		}
+
+		// Disks are recreated empty on the container they were discovered on
+		var disks string
+		for _, disk := range vm.Disks {
+			if disk.Datastore == "" {
+				content += fmt.Sprintf("      # Disk %s omitted: no storage container was discovered for it\n", disk.ID)
+				continue
+			}
+			bus := strings.ToUpper(disk.Controller)
+			if bus == "" {
+				bus = "SCSI"
+			}
+			disks += fmt.Sprintf(`        - type: DISK
+          size_gb: %d
+          bus: %s
+          storage_container:
+            name: "{{ datastore_mappings['%s'] }}"
+`, disk.Size, bus, EscapeYAML(disk.Datastore))
+		}
+		if disks != "" {
+			content += "      disks:\n" + disks
+		}
+
+		var networks string
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			if nic.Network == "" {
+				content += fmt.Sprintf("      # NIC %s omitted: no subnet was discovered for it\n", nic.ID)
+				continue
+			}
+			networks += fmt.Sprintf(`        - is_connected: %t
+          subnet:
+            name: "{{ network_mappings['%s'] }}"
+`, nic.StartConnect, EscapeYAML(nic.Network))
+		}
+		if networks != "" {
+			content += "      networks:\n" + networks
+		}
+
+		content += nutanixCategories(vm)
+	}
+
+	if len(unplaced) > 0 {
+		sort.Strings(unplaced)
+		g.Log().Warn("Nutanix VMs without a discovered cluster need cluster.name set by hand", "vms", strings.Join(unplaced, ", "))
+	}
+
+	return content + `  register: nutanix_vm_result
+  when: deployment_mode in ['recreate', 'create']
+
+- name: Power off Nutanix Virtual Machines that were off
+  nutanix.ncp.ntnx_vms:
+` + nutanixConnection + `    state: power_off
+    vm_uuid: "{{ item.vm_uuid }}"
+  loop: "{{ nutanix_vm_result.results }}"
+  when:
+    - nutanix_vm_result is defined
+    - item.vm_uuid is defined
+    - not item.item.running
+`
+}
+
+// nutanixConnection is the Prism connection shared by the Nutanix tasks
+const nutanixConnection = `    nutanix_host: "{{ providers.nutanix.host }}"
+    nutanix_port: "{{ providers.nutanix.port }}"
+    nutanix_username: "{{ providers.nutanix.username }}"
+    nutanix_password: "{{ providers.nutanix.password }}"
+    validate_certs: "{{ providers.nutanix.validate_certs }}"
+`
+
+// nutanixCategories renders a VM's Category:Value tags as a loop item dict
+// of category values, if it has any
+func nutanixCategories(vm models.VirtualMachine) string {
+	values := make(map[string][]string)
+	for _, tag := range vm.Tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			continue
+		}
+		values[key] = append(values[key], value)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	item := "      categories:\n"
+	for _, key := range keys {
+		item += fmt.Sprintf("        \"%s\":\n", EscapeYAML(key))
+		sort.Strings(values[key])
+		for _, value := range values[key] {
+			item += fmt.Sprintf("          - \"%s\"\n", EscapeYAML(value))
+		}
+	}
+	return item
+}
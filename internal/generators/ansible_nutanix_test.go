@@ -0,0 +1,98 @@
+package generators
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"valhalla/internal/models"
+)
+
+// ansibleTask is the part of an Ansible task the tests look at
+type ansibleTask struct {
+	Name string                 `yaml:"name"`
+	VMs  map[string]interface{} `yaml:"nutanix.ncp.ntnx_vms"`
+	Loop interface{}            `yaml:"loop"`
+}
+
+func TestAnsibleNutanixTasks(t *testing.T) {
+	files := generate(t, "ansible", []*models.Infrastructure{nutanixClusterFixture()}, GenerateOptions{})
+
+	var tasks []ansibleTask
+	if err := yaml.Unmarshal([]byte(files["tasks/nutanix.yml"]), &tasks); err != nil {
+		t.Fatalf("tasks/nutanix.yml is not YAML: %v\n%s", err, files["tasks/nutanix.yml"])
+	}
+	if len(tasks) == 0 || tasks[0].VMs["state"] != "present" {
+		t.Fatalf("first task does not create VMs with ntnx_vms: %+v", tasks)
+	}
+
+	items, ok := tasks[0].Loop.([]interface{})
+	if !ok {
+		t.Fatalf("create task loop = %#v, want a list", tasks[0].Loop)
+	}
+	var names []string
+	for _, item := range items {
+		vm := item.(map[string]interface{})
+		names = append(names, vm["name"].(string))
+	}
+	if want := []string{"web-01", "db-01"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("loop items %v, want one per non-template VM %v", names, want)
+	}
+
+	web := items[0].(map[string]interface{})
+	if web["vcpus"] != 2 || web["cores_per_vcpu"] != 2 || web["memory_gb"] != 8 {
+		t.Errorf("web-01 sized %v vCPUs of %v cores, %v GiB; want 2 of 2, 8", web["vcpus"], web["cores_per_vcpu"], web["memory_gb"])
+	}
+	if cluster := web["cluster"].(map[string]interface{}); cluster["name"] != "PE-Cluster-01" {
+		t.Errorf("web-01 cluster = %v", cluster)
+	}
+	disk := web["disks"].([]interface{})[0].(map[string]interface{})
+	if container := disk["storage_container"].(map[string]interface{}); container["name"] != "{{ datastore_mappings['default-container'] }}" {
+		t.Errorf("web-01 disk container = %v", container)
+	}
+	nic := web["networks"].([]interface{})[0].(map[string]interface{})
+	if subnet := nic["subnet"].(map[string]interface{}); subnet["name"] != "{{ network_mappings['VLAN 10'] }}" {
+		t.Errorf("web-01 subnet = %v", subnet)
+	}
+	if db := items[1].(map[string]interface{}); db["running"] != false {
+		t.Error("powered off db-01 is not marked as not running")
+	}
+
+	// Credentials come from the vault, never the config
+	if !strings.Contains(files["group_vars/all.yml"], `password: "{{ nutanix_password }}"`) {
+		t.Errorf("group_vars do not reference the vaulted password:\n%s", files["group_vars/all.yml"])
+	}
+	if !strings.Contains(files["group_vars/all/vault.yml.example"], "nutanix_password:") {
+		t.Error("vault.yml.example does not list nutanix_password")
+	}
+}
+
+func TestAnsibleRequirements(t *testing.T) {
+	tests := []struct {
+		name     string
+		infra    *models.Infrastructure
+		included []string
+		excluded []string
+	}{
+		{name: "vmware", infra: vmwareFixture(), excluded: []string{"nutanix.ncp", "community.general"}},
+		{name: "nutanix", infra: nutanixClusterFixture(), included: []string{"nutanix.ncp"}, excluded: []string{"community.general"}},
+		{name: "proxmox", infra: proxmoxFixture(), included: []string{"community.general"}, excluded: []string{"nutanix.ncp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requirements := generate(t, "ansible", []*models.Infrastructure{tt.infra}, GenerateOptions{})["requirements.yml"]
+			for _, collection := range tt.included {
+				if !strings.Contains(requirements, "- name: "+collection+"\n") {
+					t.Errorf("requirements.yml does not list %s:\n%s", collection, requirements)
+				}
+			}
+			for _, collection := range tt.excluded {
+				if strings.Contains(requirements, collection) {
+					t.Errorf("requirements.yml lists %s:\n%s", collection, requirements)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+package generators
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// proxmoxDiskBuses are the QEMU disk buses, in the order they are emitted
+var proxmoxDiskBuses = []string{"scsi", "virtio", "sata", "ide"}
+
+// generateProxmox generates Proxmox-specific Ansible tasks: QEMU VMs are
+// created with community.general.proxmox_kvm and LXC containers with
+// community.general.proxmox, then the guests that were running are started
+func (g *AnsibleGenerator) generateProxmox(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
+	vms, containers := countGuests(infra.VirtualMachines)
+	content := fmt.Sprintf(`---
+# Proxmox Tasks - Generated by Valhalla
+# Server: %s
+# QEMU VMs: %d, LXC containers: %d
+`, infra.Server, vms, containers)
+
+	var resources []string
+	if vms > 0 {
+		content += g.proxmoxKVMTasks(infra, opts)
+		resources = append(resources, "proxmox_kvm")
+	}
+	if containers > 0 {
+		content += g.proxmoxLXCTasks(infra, opts)
+		resources = append(resources, "proxmox")
+	}
+	if opts.EmitPostProvision {
+		content += g.postProvisionTasks(infra.VirtualMachines)
+	}
+	if len(resources) == 0 {
+		content += `
+- name: Proxmox infrastructure deployment
+  debug:
+    msg: "No Proxmox guests were discovered"
+`
+	}
+
+	return []*GenerateResult{{
+		Path:      "tasks/proxmox.yml",
+		Content:   []byte(content),
+		Size:      len(content),
+		Type:      "tasks",
+		Provider:  "proxmox",
+		Resources: resources,
+	}}, nil
+}
+
+// proxmoxKVMTasks renders the tasks that create and start QEMU VMs. Linked
+// clones of a discovered template are cloned from it instead of created.
+func (g *AnsibleGenerator) proxmoxKVMTasks(infra *models.Infrastructure, opts GenerateOptions) string {
+	var created, cloned string
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+		if template := proxmoxSourceTemplate(infra, vm); template != nil {
+			cloned += g.proxmoxCloneItem(vm, *template, opts)
+			continue
+		}
+
+		created += proxmoxGuestItem(vm)
+		if vm.Config.GuestID != "" {
+			created += fmt.Sprintf("      ostype: \"%s\"\n", EscapeYAML(vm.Config.GuestID))
+		}
+		if strings.EqualFold(vm.Hardware.Firmware, "EFI") {
+			created += "      bios: ovmf\n"
+		}
+		created += proxmoxCPUMemory(vm)
+
+		// New disks are given as storage:size in GiB
+		byBus := make(map[string][]string)
+		for _, disk := range vm.Disks {
+			if disk.Datastore == "" {
+				created += fmt.Sprintf("      # Passthrough disk %s (%s) omitted\n", disk.ID, EscapeYAML(disk.Path))
+				continue
+			}
+			byBus[disk.Controller] = append(byBus[disk.Controller],
+				fmt.Sprintf("        %s: \"%s:%d,format=%s\"\n", disk.ID, EscapeYAML(disk.Datastore), disk.Size, disk.Type))
+		}
+		for _, bus := range proxmoxDiskBuses {
+			if len(byBus[bus]) == 0 {
+				continue
+			}
+			created += fmt.Sprintf("      %s:\n%s", bus, strings.Join(byBus[bus], ""))
+		}
+
+		created += g.proxmoxNetItem(vm, opts)
+		created += proxmoxTags(vm)
+	}
+
+	var content string
+	if created != "" {
+		content += `
+- name: Create Proxmox QEMU Virtual Machines
+  community.general.proxmox_kvm:
+` + proxmoxConnection + `    vmid: "{{ item.vmid | default(omit) }}"
+    name: "{{ item.name }}"
+    state: present
+    ostype: "{{ item.ostype | default(omit) }}"
+    bios: "{{ item.bios | default(omit) }}"
+    cores: "{{ item.cores }}"
+    sockets: "{{ item.sockets }}"
+    memory: "{{ item.memory }}"
+    scsi: "{{ item.scsi | default(omit) }}"
+    virtio: "{{ item.virtio | default(omit) }}"
+    sata: "{{ item.sata | default(omit) }}"
+    ide: "{{ item.ide | default(omit) }}"
+    net: "{{ item.net | default(omit) }}"
+    tags: "{{ item.tags | default(omit) }}"
+  loop:
+` + created + `  register: proxmox_vm_result
+  when: deployment_mode in ['recreate', 'create']
+
+- name: Start Proxmox QEMU Virtual Machines that were running
+  community.general.proxmox_kvm:
+` + strings.ReplaceAll(proxmoxConnection, "item.", "item.item.") + `    name: "{{ item.item.name }}"
+    state: started
+  loop: "{{ proxmox_vm_result.results }}"
+  when:
+    - proxmox_vm_result is defined
+    - item.item.running
+`
+	}
+
+	if cloned != "" {
+		// The clone runs on the template's node; target places the copy
+		content += `
+# Clones need their template on the target cluster under the same name
+- name: Clone Proxmox QEMU Virtual Machines from templates
+  community.general.proxmox_kvm:
+` + strings.ReplaceAll(proxmoxConnection, "item.node", "item.clone_node") + `    clone: "{{ item.clone }}"
+    newid: "{{ item.vmid | default(omit) }}"
+    name: "{{ item.name }}"
+    target: "{{ item.node | default(omit) }}"
+    full: "{{ item.full }}"
+    timeout: 600
+  loop:
+` + cloned + `  register: proxmox_clone_result
+  when: deployment_mode in ['recreate', 'create']
+
+- name: Apply discovered hardware to cloned Proxmox QEMU Virtual Machines
+  community.general.proxmox_kvm:
+` + strings.ReplaceAll(proxmoxConnection, "item.", "item.item.") + `    vmid: "{{ item.item.vmid | default(omit) }}"
+    name: "{{ item.item.name }}"
+    update: true
+    cores: "{{ item.item.cores }}"
+    sockets: "{{ item.item.sockets }}"
+    memory: "{{ item.item.memory }}"
+    net: "{{ item.item.net | default(omit) }}"
+    tags: "{{ item.item.tags | default(omit) }}"
+  loop: "{{ proxmox_clone_result.results }}"
+  when: proxmox_clone_result is defined
+
+- name: Start cloned Proxmox QEMU Virtual Machines that were running
+  community.general.proxmox_kvm:
+` + strings.ReplaceAll(proxmoxConnection, "item.", "item.item.") + `    name: "{{ item.item.name }}"
+    state: started
+  loop: "{{ proxmox_clone_result.results }}"
+  when:
+    - proxmox_clone_result is defined
+    - item.item.running
+`
+	}
+	return content
+}
+
+// proxmoxCloneItem renders the loop item of a VM cloned from template, a
+// full copy unless linked clones are allowed. Its disks come from the
+// template, so only the CPU, memory and network devices are carried over.
+func (g *AnsibleGenerator) proxmoxCloneItem(vm models.VirtualMachine, template models.Template, opts GenerateOptions) string {
+	item := proxmoxGuestItem(vm)
+	item += fmt.Sprintf("      clone: \"%s\"\n", EscapeYAML(template.Name))
+	if node, _ := template.Metadata["node"].(string); node != "" {
+		item += fmt.Sprintf("      clone_node: \"%s\"\n", EscapeYAML(node))
+	}
+	item += fmt.Sprintf("      full: %t\n", !opts.AllowLinkedClones)
+	item += proxmoxCPUMemory(vm)
+	item += g.proxmoxNetItem(vm, opts)
+	item += proxmoxTags(vm)
+	return item
+}
+
+// proxmoxSourceTemplate returns the discovered template a linked clone was
+// cloned from, or nil when the VM isn't one or its template wasn't found
+func proxmoxSourceTemplate(infra *models.Infrastructure, vm models.VirtualMachine) *models.Template {
+	vmid := vm.Metadata["template_vmid"]
+	if vmid == nil {
+		return nil
+	}
+	for i, template := range infra.Templates {
+		// VMIDs are float64 once loaded from a discovery file
+		if fmt.Sprint(template.Metadata["vmid"]) == fmt.Sprint(vmid) {
+			return &infra.Templates[i]
+		}
+	}
+	return nil
+}
+
+// proxmoxCPUMemory renders a QEMU VM's CPU topology and memory as loop
+// item settings
+func proxmoxCPUMemory(vm models.VirtualMachine) string {
+	cores := vm.Hardware.NumCoresPerSocket
+	if cores <= 0 || vm.CPUs%cores != 0 {
+		cores = vm.CPUs
+	}
+	sockets := 1
+	if cores > 0 {
+		sockets = vm.CPUs / cores
+	}
+	return fmt.Sprintf("      cores: %d\n      sockets: %d\n      memory: %d\n", cores, sockets, vm.Memory)
+}
+
+// proxmoxNetItem renders a QEMU VM's network devices as a loop item dict,
+// if it has any
+func (g *AnsibleGenerator) proxmoxNetItem(vm models.VirtualMachine, opts GenerateOptions) string {
+	nics := g.OrderedNetworkCards(vm)
+	if len(nics) == 0 {
+		return ""
+	}
+	item := "      net:\n"
+	for _, nic := range nics {
+		item += fmt.Sprintf("        %s: \"%s\"\n", nic.ID, proxmoxKVMNet(nic, opts.PreserveMAC))
+	}
+	return item
+}
+
+// proxmoxLXCTasks renders the tasks that create and start LXC containers
+func (g *AnsibleGenerator) proxmoxLXCTasks(infra *models.Infrastructure, opts GenerateOptions) string {
+	content := `
+- name: Create Proxmox LXC Containers
+  community.general.proxmox:
+` + proxmoxConnection + `    vmid: "{{ item.vmid | default(omit) }}"
+    hostname: "{{ item.hostname }}"
+    state: present
+    ostemplate: "{{ item.ostemplate | default(providers.proxmox.lxc_ostemplate) }}"
+    unprivileged: "{{ item.unprivileged | default(omit) }}"
+    cores: "{{ item.cores | default(omit) }}"
+    memory: "{{ item.memory }}"
+    disk: "{{ item.disk | default(omit) }}"
+    mounts: "{{ item.mounts | default(omit) }}"
+    netif: "{{ item.netif | default(omit) }}"
+    tags: "{{ item.tags | default(omit) }}"
+  loop:
+`
+
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || !vm.IsContainer() {
+			continue
+		}
+
+		hostname, _ := vm.Metadata["hostname"].(string)
+		if hostname == "" {
+			hostname = vm.Name
+		}
+
+		content += proxmoxGuestItem(vm)
+		content += fmt.Sprintf("      hostname: \"%s\"\n", EscapeYAML(hostname))
+		if unprivileged, ok := vm.Metadata["unprivileged"].(bool); ok {
+			content += fmt.Sprintf("      unprivileged: %t\n", unprivileged)
+		}
+		if vm.CPUs > 0 {
+			content += fmt.Sprintf("      cores: %d\n", vm.CPUs)
+		}
+		content += fmt.Sprintf("      memory: %d\n", vm.Memory)
+
+		// The root filesystem is the disk; mount points keep their paths
+		var mounts []string
+		for _, disk := range vm.Disks {
+			if disk.Datastore == "" {
+				content += fmt.Sprintf("      # Bind mount %s (%s) omitted\n", disk.ID, EscapeYAML(disk.Name))
+				continue
+			}
+			if disk.ID == "rootfs" {
+				content += fmt.Sprintf("      disk: \"%s:%d\"\n", EscapeYAML(disk.Datastore), disk.Size)
+				continue
+			}
+			mounts = append(mounts, fmt.Sprintf("        %s: \"%s:%d,mp=%s\"\n",
+				disk.ID, EscapeYAML(disk.Datastore), disk.Size, EscapeYAML(disk.Path)))
+		}
+		if len(mounts) > 0 {
+			content += "      mounts:\n" + strings.Join(mounts, "")
+		}
+
+		if nics := g.OrderedNetworkCards(vm); len(nics) > 0 {
+			content += "      netif:\n"
+			for _, nic := range nics {
+				content += fmt.Sprintf("        %s: \"%s\"\n", nic.ID, proxmoxLXCNet(nic, opts.PreserveMAC))
+			}
+		}
+		content += proxmoxTags(vm)
+	}
+
+	content += `  register: proxmox_lxc_result
+  when: deployment_mode in ['recreate', 'create']
+
+- name: Start Proxmox LXC Containers that were running
+  community.general.proxmox:
+` + strings.ReplaceAll(proxmoxConnection, "item.", "item.item.") + `    vmid: "{{ item.item.vmid | default(omit) }}"
+    hostname: "{{ item.item.hostname }}"
+    state: started
+  loop: "{{ proxmox_lxc_result.results }}"
+  when:
+    - proxmox_lxc_result is defined
+    - item.item.running
+`
+	return content
+}
+
+// proxmoxConnection is the API connection shared by the Proxmox tasks
+const proxmoxConnection = `    api_host: "{{ providers.proxmox.api_host }}"
+    api_port: "{{ providers.proxmox.api_port }}"
+    api_user: "{{ providers.proxmox.username }}"
+    api_password: "{{ providers.proxmox.password }}"
+    validate_certs: "{{ providers.proxmox.validate_certs }}"
+    node: "{{ item.node | default(providers.proxmox.node) }}"
+`
+
+// proxmoxGuestItem starts a loop item with the settings QEMU VMs and
+// containers share
+func proxmoxGuestItem(vm models.VirtualMachine) string {
+	item := fmt.Sprintf("    - name: \"%s\"\n", EscapeYAML(vm.Name))
+	if vmid := fmt.Sprint(vm.Metadata["vmid"]); vm.Metadata["vmid"] != nil {
+		item += fmt.Sprintf("      vmid: %s\n", vmid)
+	}
+	if vm.Host != "" {
+		item += fmt.Sprintf("      node: \"%s\"\n", EscapeYAML(vm.Host))
+	}
+	item += fmt.Sprintf("      running: %t\n", strings.EqualFold(vm.State, "running"))
+	return item
+}
+
+// proxmoxTags renders a guest's tags as a loop item list, if it has any
+func proxmoxTags(vm models.VirtualMachine) string {
+	if len(vm.Tags) == 0 {
+		return ""
+	}
+	tags := append([]string(nil), vm.Tags...)
+	sort.Strings(tags)
+
+	item := "      tags:\n"
+	for _, tag := range tags {
+		item += fmt.Sprintf("        - \"%s\"\n", EscapeYAML(tag))
+	}
+	return item
+}
+
+// proxmoxKVMNet renders a QEMU network device such as
+// "virtio=BC:24:11:00:00:01,bridge=vmbr0"; the MAC is only kept when
+// preserveMAC is set, otherwise Proxmox assigns a new one
+func proxmoxKVMNet(nic models.NetworkCard, preserveMAC bool) string {
+	model := nic.Type
+	if model == "" || model == models.NICTypeUnknown {
+		model = "virtio"
+	}
+	if preserveMAC && nic.MACAddress != "" {
+		model += "=" + nic.MACAddress
+	}
+
+	spec := model
+	if nic.Network != "" {
+		spec += ",bridge=" + nic.Network
+	}
+	if !nic.StartConnect {
+		spec += ",link_down=1"
+	}
+	return EscapeYAML(spec)
+}
+
+// proxmoxLXCNet renders a container network interface such as
+// "name=eth0,bridge=vmbr0,ip=dhcp". Guest addresses aren't discovered, so
+// interfaces come up with DHCP.
+func proxmoxLXCNet(nic models.NetworkCard, preserveMAC bool) string {
+	spec := "name=" + nic.Name
+	if nic.Network != "" {
+		spec += ",bridge=" + nic.Network
+	}
+	if preserveMAC && nic.MACAddress != "" {
+		spec += ",hwaddr=" + nic.MACAddress
+	}
+	spec += ",ip=dhcp"
+	if !nic.StartConnect {
+		spec += ",link_down=1"
+	}
+	return EscapeYAML(spec)
+}
+
+// proxmoxAPIEndpoint splits a configured Proxmox server, a host name or a
+// URL, into the host and port the Ansible modules connect to
+func proxmoxAPIEndpoint(server string) (string, string) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return server, "8006"
+	}
+
+	port := u.Port()
+	if _, err := strconv.Atoi(port); err != nil {
+		port = "8006"
+	}
+	return u.Hostname(), port
+}
@@ -0,0 +1,344 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// vmwareLogin is the vCenter login shared by the VMware tasks
+const vmwareLogin = `    hostname: "{{ providers.vmware.server }}"
+    username: "{{ providers.vmware.username }}"
+    password: "{{ providers.vmware.password }}"
+    validate_certs: "{{ providers.vmware.validate_certs }}"
+`
+
+// vmwareConnection is the login with the datacenter, for the modules that
+// take one
+const vmwareConnection = vmwareLogin + `    datacenter: "{{ providers.vmware.datacenter }}"
+`
+
+// vmwareFolderTasks renders the task creating the VM folders the discovered
+// VMs are in, every ancestor once and parents before their children.
+// vcenter_folder leaves folders that already exist alone.
+func (g *AnsibleGenerator) vmwareFolderTasks(infra *models.Infrastructure) string {
+	paths := vmwareFolders(infra)
+	if len(paths) == 0 {
+		return ""
+	}
+
+	content := `
+- name: Create VMware VM folders
+  community.vmware.vcenter_folder:
+` + vmwareConnection + `    folder_type: vm
+    folder_name: "{{ item.name }}"
+    parent_folder: "{{ item.parent | default(omit) }}"
+    state: present
+  loop:
+`
+	for _, path := range paths {
+		content += fmt.Sprintf("    - name: \"%s\"\n", EscapeYAML(folderName(path)))
+		if parent := parentFolder(path); parent != "" {
+			content += fmt.Sprintf("      parent: \"%s\"\n", EscapeYAML(parent))
+		}
+	}
+	return content + "  when: deployment_mode in ['recreate', 'create']\n"
+}
+
+// vmwarePoolTasks renders the task creating the discovered resource pools
+// with their allocations, parents before their children. Cluster root pools
+// exist already, and vApps and the pools inside them cannot be created with
+// vmware_resource_pool, so those are left out. Pools that already exist are
+// updated to the discovered allocations.
+func (g *AnsibleGenerator) vmwarePoolTasks(infra *models.Infrastructure) string {
+	pools := creatablePools(infra.ResourcePools)
+	if len(pools) == 0 {
+		return ""
+	}
+
+	content := `
+- name: Create VMware resource pools
+  community.vmware.vmware_resource_pool:
+` + vmwareConnection + `    cluster: "{{ providers.vmware.cluster }}"
+    resource_pool: "{{ item.name }}"
+    parent_resource_pool: "{{ item.parent | default(omit) }}"
+    cpu_reservation: "{{ item.cpu_reservation | default(omit) }}"
+    cpu_limit: "{{ item.cpu_limit | default(omit) }}"
+    cpu_shares: "{{ item.cpu_shares | default(omit) }}"
+    mem_reservation: "{{ item.mem_reservation | default(omit) }}"
+    mem_limit: "{{ item.mem_limit | default(omit) }}"
+    mem_shares: "{{ item.mem_shares | default(omit) }}"
+    state: present
+  loop:
+`
+	for _, pool := range pools {
+		content += fmt.Sprintf("    - name: \"%s\"\n", EscapeYAML(pool.pool.Name))
+		if pool.parent != "" {
+			content += fmt.Sprintf("      parent: \"%s\"\n", EscapeYAML(pool.parent))
+		}
+		content += poolAllocation("cpu", pool.pool.CPU)
+		content += poolAllocation("mem", pool.pool.Memory)
+	}
+	return content + "  when: deployment_mode in ['recreate', 'create']\n"
+}
+
+// vmwareVerifyTasks renders the checks of tasks/verify_vmware.yml: every
+// folder vmwareFolderTasks creates exists, every pool vmwarePoolTasks creates
+// has its discovered reservations, limits and share levels, and every VM has
+// its discovered CPUs, memory, guest OS, folder, NIC count and power state.
+// VMs that are missing fail their assert rather than the info task.
+func (g *AnsibleGenerator) vmwareVerifyTasks(infra *models.Infrastructure) string {
+	content := fmt.Sprintf(`---
+# VMware vSphere Verification - Generated by Valhalla
+# Server: %s
+# Checks that the resources created by tasks/vmware.yml exist with their
+# discovered settings
+`, infra.Server)
+
+	if folders := vmwareFolders(infra); len(folders) > 0 {
+		content += `
+- name: Gather VMware VM folders
+  community.vmware.vmware_folder_info:
+` + vmwareConnection + `  register: verify_folders
+
+- name: Assert VMware VM folders exist
+  ansible.builtin.assert:
+    that:
+      - item in (verify_folders.flat_folder_info | map(attribute='path') | list)
+    fail_msg: "VM folder {{ item }} does not exist"
+    quiet: true
+  loop:
+`
+		for _, path := range folders {
+			content += fmt.Sprintf("    - \"/{{ providers.vmware.datacenter }}/vm/%s\"\n", EscapeYAML(path))
+		}
+	}
+
+	if pools := creatablePools(infra.ResourcePools); len(pools) > 0 {
+		content += `
+- name: Gather VMware resource pools
+  community.vmware.vmware_resource_pool_info:
+` + vmwareLogin + `  register: verify_pools
+
+- name: Assert VMware resource pools exist with their allocations
+  ansible.builtin.assert:
+    that:
+      - pool | length > 0
+      - item.cpu_reservation is not defined or pool[0].cpu_allocation_reservation == item.cpu_reservation
+      - item.cpu_limit is not defined or pool[0].cpu_allocation_limit == item.cpu_limit
+      - item.cpu_shares is not defined or pool[0].cpu_allocation_shares_level == item.cpu_shares
+      - item.mem_reservation is not defined or pool[0].mem_allocation_reservation == item.mem_reservation
+      - item.mem_limit is not defined or pool[0].mem_allocation_limit == item.mem_limit
+      - item.mem_shares is not defined or pool[0].mem_allocation_shares_level == item.mem_shares
+    fail_msg: "Resource pool {{ item.name }} is missing or its allocation differs"
+    quiet: true
+  vars:
+    pool: "{{ verify_pools.resource_pool_info | selectattr('name', 'equalto', item.name) | list }}"
+  loop:
+`
+		for _, pool := range pools {
+			content += fmt.Sprintf("    - name: \"%s\"\n", EscapeYAML(pool.pool.Name))
+			content += poolAllocation("cpu", pool.pool.CPU)
+			content += poolAllocation("mem", pool.pool.Memory)
+		}
+	}
+
+	content += `
+- name: Gather VMware VMs
+  community.vmware.vmware_guest_info:
+` + vmwareConnection + `    name: "{{ item.name }}"
+  loop:
+`
+	var expected string
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+		folder := "/{{ providers.vmware.datacenter }}/vm"
+		if path := vmFolderPath(vm.Folder); path != "" {
+			folder += "/" + EscapeYAML(path)
+		}
+		expected += fmt.Sprintf(`    - name: "%s"
+      cpus: %d
+      memory: %d
+      guest_id: "%s"
+      folder: "%s"
+      nics: %d
+`, EscapeYAML(vm.Name), vm.CPUs, vm.Memory, vm.Config.GuestID, folder, len(vm.NetworkCards))
+		switch state := strings.ToLower(vm.State); state {
+		case "poweredon", "poweredoff":
+			expected += fmt.Sprintf("      power_state: %s\n", state)
+		}
+	}
+	content += expected + `  register: verify_vms
+  failed_when: false
+
+- name: Assert VMware VMs exist with their settings
+  ansible.builtin.assert:
+    that:
+      - item.instance is defined
+      - item.instance.hw_processor_count == item.item.cpus
+      - item.instance.hw_memtotal_mb == item.item.memory
+      - item.instance.hw_guest_id == item.item.guest_id
+      - item.instance.hw_folder == item.item.folder
+      - item.instance.hw_interfaces | length == item.item.nics
+      - item.item.power_state is not defined or item.instance.hw_power_status | lower == item.item.power_state
+    fail_msg: "VM {{ item.item.name }} is missing or differs from discovery"
+    quiet: true
+  loop: "{{ verify_vms.results }}"
+  loop_control:
+    label: "{{ item.item.name }}"
+`
+	return content
+}
+
+// vmwareFolders returns the VM folder paths of the VMs that are created,
+// with every ancestor once, parents before their children
+func vmwareFolders(infra *models.Infrastructure) []string {
+	folders := make(map[string]bool)
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+		path := vmFolderPath(vm.Folder)
+		for path != "" {
+			folders[path] = true
+			path = parentFolder(path)
+		}
+	}
+
+	paths := make([]string, 0, len(folders))
+	for path := range folders {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], "/"), strings.Count(paths[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return paths[i] < paths[j]
+	})
+	return paths
+}
+
+// orderedPool is a resource pool to create, with its parent left empty when
+// the parent is the cluster's root pool
+type orderedPool struct {
+	pool   models.ResourcePool
+	parent string
+	depth  int
+}
+
+// creatablePools returns the pools below the root pools that are not in a
+// vApp, parents first
+func creatablePools(pools []models.ResourcePool) []orderedPool {
+	byName := make(map[string]models.ResourcePool, len(pools))
+	for _, pool := range pools {
+		byName[pool.Name] = pool
+	}
+
+	var ordered []orderedPool
+	for _, pool := range pools {
+		if isRootPool(pool) || isVApp(pool) {
+			continue
+		}
+
+		entry := orderedPool{pool: pool, parent: pool.Parent}
+		creatable := true
+		// Walk up to the root pool, counting the pools on the way
+		for parent, seen := pool.Parent, 0; parent != "" && seen < len(pools); seen++ {
+			ancestor, ok := byName[parent]
+			if !ok || isRootPool(ancestor) {
+				break
+			}
+			if isVApp(ancestor) {
+				creatable = false
+				break
+			}
+			entry.depth++
+			parent = ancestor.Parent
+		}
+		if !creatable {
+			continue
+		}
+		if parent, ok := byName[pool.Parent]; ok && isRootPool(parent) {
+			entry.parent = ""
+		}
+		ordered = append(ordered, entry)
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].depth != ordered[j].depth {
+			return ordered[i].depth < ordered[j].depth
+		}
+		return ordered[i].pool.Name < ordered[j].pool.Name
+	})
+	return ordered
+}
+
+// vmResourcePools maps each VM name to the resource pool it is in, for the
+// pools creatablePools returns
+func vmResourcePools(pools []models.ResourcePool) map[string]string {
+	result := make(map[string]string)
+	for _, pool := range creatablePools(pools) {
+		for _, vm := range pool.pool.VMs {
+			result[vm] = pool.pool.Name
+		}
+	}
+	return result
+}
+
+// poolAllocation renders a pool's CPU or memory allocation as loop item
+// fields. Unset and unlimited (-1) limits and custom share levels have no
+// module value, so those fall back to the module defaults.
+func poolAllocation(prefix string, allocation models.ResourceAllocation) string {
+	var item string
+	if allocation.Reservation > 0 {
+		item += fmt.Sprintf("      %s_reservation: %d\n", prefix, allocation.Reservation)
+	}
+	if allocation.Limit > 0 {
+		item += fmt.Sprintf("      %s_limit: %d\n", prefix, allocation.Limit)
+	}
+	switch allocation.Shares {
+	case "low", "normal", "high":
+		item += fmt.Sprintf("      %s_shares: %s\n", prefix, allocation.Shares)
+	}
+	return item
+}
+
+// isRootPool reports whether a pool is a cluster's or host's root pool
+func isRootPool(pool models.ResourcePool) bool {
+	root, _ := pool.Metadata["root"].(bool)
+	return root
+}
+
+// isVApp reports whether a pool is a vApp
+func isVApp(pool models.ResourcePool) bool {
+	vapp, _ := pool.Metadata["vapp"].(bool)
+	return vapp
+}
+
+// vmFolderPath returns a VM's folder below its datacenter's VM folder, e.g.
+// Prod/Web for /DC0/vm/Prod/Web, or "" for VMs directly in the VM folder
+func vmFolderPath(folder string) string {
+	if i := strings.Index(folder, "/vm/"); i >= 0 {
+		return strings.Trim(folder[i+len("/vm/"):], "/")
+	}
+	return ""
+}
+
+// parentFolder returns the parent of a folder path, or "" for a top level
+// folder
+func parentFolder(path string) string {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// folderName returns the last element of a folder path
+func folderName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
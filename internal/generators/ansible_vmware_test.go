@@ -0,0 +1,119 @@
+package generators
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+	"valhalla/internal/models"
+)
+
+// placedFixture returns vmwareFixture with its VMs in nested folders and a
+// resource pool below the cluster's root pool
+func placedFixture() *models.Infrastructure {
+	infra := vmwareFixture()
+	infra.VirtualMachines[0].Folder = "/DC1/vm/Prod/Web"
+	infra.VirtualMachines[1].Folder = "/DC1/vm/Prod/DB"
+	infra.ResourcePools = []models.ResourcePool{
+		{Name: "Resources", Metadata: map[string]interface{}{"root": true}},
+		{
+			Name: "Prod", Parent: "Resources", VMs: []string{"web-01"},
+			CPU:    models.ResourceAllocation{Reservation: 2000, Limit: -1, Shares: "high"},
+			Memory: models.ResourceAllocation{Reservation: 4096, Limit: 8192, Shares: "custom", SharesValue: 100},
+		},
+	}
+	return infra
+}
+
+// verifyTask is the part of a verification task the tests look at
+type verifyTask struct {
+	Name   string                 `yaml:"name"`
+	Assert map[string]interface{} `yaml:"ansible.builtin.assert"`
+	Loop   interface{}            `yaml:"loop"`
+}
+
+func TestAnsibleVMwareVerify(t *testing.T) {
+	files := generate(t, "ansible", []*models.Infrastructure{placedFixture()}, GenerateOptions{})
+
+	if playbook := files["verify.yml"]; !strings.Contains(playbook, "- tasks/verify_vmware.yml") {
+		t.Fatalf("verify.yml does not include the VMware checks:\n%s", playbook)
+	}
+
+	var tasks []verifyTask
+	if err := yaml.Unmarshal([]byte(files["tasks/verify_vmware.yml"]), &tasks); err != nil {
+		t.Fatalf("tasks/verify_vmware.yml is not YAML: %v\n%s", err, files["tasks/verify_vmware.yml"])
+	}
+	byName := make(map[string]verifyTask)
+	asserts := 0
+	for _, task := range tasks {
+		byName[task.Name] = task
+		if task.Assert != nil {
+			asserts++
+		}
+	}
+	if asserts != 3 {
+		t.Fatalf("got %d assert tasks, want one each for folders, pools and VMs", asserts)
+	}
+
+	folders := byName["Assert VMware VM folders exist"]
+	want := []interface{}{
+		"/{{ providers.vmware.datacenter }}/vm/Prod",
+		"/{{ providers.vmware.datacenter }}/vm/Prod/DB",
+		"/{{ providers.vmware.datacenter }}/vm/Prod/Web",
+	}
+	if !reflect.DeepEqual(folders.Loop, want) {
+		t.Errorf("folders checked %v, want every created folder once %v", folders.Loop, want)
+	}
+
+	pools := byName["Assert VMware resource pools exist with their allocations"]
+	items, _ := pools.Loop.([]interface{})
+	if len(items) != 1 {
+		t.Fatalf("pools checked %v, want Prod only", pools.Loop)
+	}
+	wantPool := map[string]interface{}{"name": "Prod", "cpu_reservation": 2000, "cpu_shares": "high", "mem_reservation": 4096, "mem_limit": 8192}
+	if !reflect.DeepEqual(items[0], wantPool) {
+		t.Errorf("Prod is checked against %v, want %v", items[0], wantPool)
+	}
+
+	vms := byName["Assert VMware VMs exist with their settings"]
+	that := fmt.Sprint(vms.Assert["that"])
+	for _, want := range []string{"hw_processor_count", "hw_memtotal_mb", "hw_guest_id", "hw_folder", "hw_interfaces", "hw_power_status"} {
+		if !strings.Contains(that, want) {
+			t.Errorf("VM assert does not check %s: %v", want, that)
+		}
+	}
+
+	wantVMs := []interface{}{
+		map[string]interface{}{"name": "web-01", "cpus": 2, "memory": 4096, "guest_id": "ubuntu64Guest", "folder": "/{{ providers.vmware.datacenter }}/vm/Prod/Web", "nics": 1, "power_state": "poweredon"},
+		map[string]interface{}{"name": "db-01", "cpus": 4, "memory": 8192, "guest_id": "rhel8_64Guest", "folder": "/{{ providers.vmware.datacenter }}/vm/Prod/DB", "nics": 1, "power_state": "poweredoff"},
+	}
+	if gather := byName["Gather VMware VMs"].Loop; !reflect.DeepEqual(gather, wantVMs) {
+		t.Errorf("VMs checked:\n%v\nwant one per created VM:\n%v", gather, wantVMs)
+	}
+}
+
+func TestAnsibleVerifyOnlyForVMware(t *testing.T) {
+	files := generate(t, "ansible", []*models.Infrastructure{proxmoxFixture()}, GenerateOptions{})
+	if _, ok := files["verify.yml"]; ok {
+		t.Error("verify.yml generated without VMware checks to run")
+	}
+}
+
+func TestAnsibleVMwareCreationOrder(t *testing.T) {
+	tasks := generate(t, "ansible", []*models.Infrastructure{placedFixture()}, GenerateOptions{})["tasks/vmware.yml"]
+
+	folders := strings.Index(tasks, "vcenter_folder")
+	pools := strings.Index(tasks, "vmware_resource_pool:")
+	vms := strings.Index(tasks, "vmware_guest:")
+	if folders < 0 || pools < folders || vms < pools {
+		t.Errorf("folders at %d, pools at %d, VMs at %d; want folders and pools before VMs", folders, pools, vms)
+	}
+	if !strings.Contains(tasks, "    - name: \"Prod\"\n    - name: \"DB\"\n      parent: \"Prod\"\n    - name: \"Web\"\n      parent: \"Prod\"\n") {
+		t.Errorf("folders are not parents first with shared ancestors once:\n%s", tasks)
+	}
+	if strings.Contains(tasks, `name: "Resources"`) {
+		t.Error("the root resource pool is created")
+	}
+}
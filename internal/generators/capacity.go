@@ -0,0 +1,136 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// CapacityCheck projects the load the generated VMs put on a target
+// cluster against the allowed overcommit
+type CapacityCheck struct {
+	Target string // target cluster, or server when the whole infrastructure is the target
+	VMs    int
+
+	// Configured vCPUs and memory of the generated VMs, and of the VMs
+	// already on the target when it was discovered separately
+	VCPUs          int
+	MemoryMiB      int64
+	ExistingVCPUs  int
+	ExistingMemMiB int64
+
+	Cores             int
+	MemoryCapacityMiB int64
+
+	MaxCPURatio    float64
+	MaxMemoryRatio float64
+}
+
+// CPURatio is the projected vCPUs per physical core
+func (c *CapacityCheck) CPURatio() float64 {
+	return float64(c.VCPUs+c.ExistingVCPUs) / float64(c.Cores)
+}
+
+// MemoryRatio is the projected configured memory per MiB of host memory
+func (c *CapacityCheck) MemoryRatio() float64 {
+	return float64(c.MemoryMiB+c.ExistingMemMiB) / float64(c.MemoryCapacityMiB)
+}
+
+// Exceeded lists the limits the projected load is over, if any
+func (c *CapacityCheck) Exceeded() []string {
+	var exceeded []string
+	if c.MaxCPURatio > 0 && c.CPURatio() > c.MaxCPURatio {
+		exceeded = append(exceeded, fmt.Sprintf("vCPU overcommit %.2f:1 is over %.2f:1", c.CPURatio(), c.MaxCPURatio))
+	}
+	if c.MaxMemoryRatio > 0 && c.MemoryRatio() > c.MaxMemoryRatio {
+		exceeded = append(exceeded, fmt.Sprintf("memory overcommit %.2f:1 is over %.2f:1", c.MemoryRatio(), c.MaxMemoryRatio))
+	}
+	return exceeded
+}
+
+// Comment renders the check as the comment block heading virtual_machines.tf
+func (c *CapacityCheck) Comment() string {
+	comment := fmt.Sprintf(`# Capacity of %s for the %d VMs below
+#   vCPUs:  %d on %d cores, %.2f:1 (limit %.2f:1)
+#   Memory: %d MiB of %d MiB, %.2f:1 (limit %.2f:1)
+`, c.Target, c.VMs,
+		c.VCPUs+c.ExistingVCPUs, c.Cores, c.CPURatio(), c.MaxCPURatio,
+		c.MemoryMiB+c.ExistingMemMiB, c.MemoryCapacityMiB, c.MemoryRatio(), c.MaxMemoryRatio)
+	if c.ExistingVCPUs > 0 || c.ExistingMemMiB > 0 {
+		comment += fmt.Sprintf("#   Includes %d vCPUs and %d MiB of VMs already on the target\n", c.ExistingVCPUs, c.ExistingMemMiB)
+	}
+	for _, exceeded := range c.Exceeded() {
+		comment += "#   WARNING: " + exceeded + "\n"
+	}
+	return comment + "\n"
+}
+
+// CapacityKey identifies the infrastructure a capacity check was made for
+// in GenerateOptions.Capacity
+func CapacityKey(infra *models.Infrastructure) string {
+	return strings.ToLower(infra.Provider) + "|" + infra.Server
+}
+
+// CheckCapacity projects the VMs of infra onto cluster of target, or onto
+// all of target's hosts when cluster is empty. VMs already in target count
+// towards the load unless target is infra itself, whose VMs are the ones
+// being generated. Templates and containers are left out.
+func CheckCapacity(infra, target *models.Infrastructure, cluster string, maxCPURatio, maxMemoryRatio float64) (*CapacityCheck, error) {
+	check := &CapacityCheck{
+		Target:         cluster,
+		MaxCPURatio:    maxCPURatio,
+		MaxMemoryRatio: maxMemoryRatio,
+	}
+	if check.Target == "" {
+		check.Target = target.Server
+	}
+
+	members := make(map[string]bool)
+	for _, c := range target.Clusters {
+		if strings.EqualFold(c.Name, cluster) {
+			for _, host := range c.Hosts {
+				members[host] = true
+			}
+		}
+	}
+	hosts := make(map[string]bool)
+	for _, host := range target.Hosts {
+		if cluster != "" && !strings.EqualFold(host.Cluster, cluster) && !members[host.Name] {
+			continue
+		}
+		hosts[host.Name] = true
+
+		// Older discovery files only have the CPU total, which is in MHz on ESXi
+		cores := host.CPUCores
+		if cores == 0 && !strings.EqualFold(host.Type, "ESXi") {
+			cores = int(host.CPU.Total)
+		}
+		check.Cores += cores
+		check.MemoryCapacityMiB += host.Memory.Total
+	}
+	if check.Cores == 0 || check.MemoryCapacityMiB == 0 {
+		return nil, fmt.Errorf("no host capacity was discovered for %s", check.Target)
+	}
+
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+		check.VMs++
+		check.VCPUs += vm.CPUs
+		check.MemoryMiB += vm.Memory
+	}
+
+	if target != infra {
+		for _, vm := range target.VirtualMachines {
+			if vm.Config.Template || vm.IsContainer() || (cluster != "" && !hosts[vm.Host]) {
+				continue
+			}
+			check.ExistingVCPUs += vm.CPUs
+			check.ExistingMemMiB += vm.Memory
+		}
+	}
+
+	return check, nil
+}
@@ -0,0 +1,138 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// targetFixture returns a small target environment: cluster DR with two
+// 8-core, 64 GiB hosts running one VM, and a standalone host
+func targetFixture() *models.Infrastructure {
+	return &models.Infrastructure{
+		Provider: "vmware",
+		Server:   "vcenter-dr.example.com",
+		Clusters: []models.Cluster{{Name: "DR", Hosts: []string{"esx-dr-1", "esx-dr-2"}}},
+		Hosts: []models.Host{
+			{Name: "esx-dr-1", Type: "ESXi", CPUCores: 8, Memory: models.HostResource{Total: 65536}},
+			{Name: "esx-dr-2", Type: "ESXi", CPUCores: 8, Memory: models.HostResource{Total: 65536}},
+			{Name: "esx-lab", Type: "ESXi", CPUCores: 32, Memory: models.HostResource{Total: 524288}},
+		},
+		VirtualMachines: []models.VirtualMachine{
+			{Name: "dr-dns", Host: "esx-dr-1", CPUs: 2, Memory: 4096},
+			{Name: "lab-vm", Host: "esx-lab", CPUs: 16, Memory: 65536},
+		},
+	}
+}
+
+// sourceVMs returns an infrastructure of n VMs of the given size, and a
+// template that never counts
+func sourceVMs(n, cpus int, memory int64) *models.Infrastructure {
+	infra := &models.Infrastructure{Provider: "vmware", Server: "vcenter.example.com"}
+	for i := 0; i < n; i++ {
+		infra.VirtualMachines = append(infra.VirtualMachines, models.VirtualMachine{Name: "vm", CPUs: cpus, Memory: memory})
+	}
+	infra.VirtualMachines = append(infra.VirtualMachines, models.VirtualMachine{
+		Name: "template", CPUs: 64, Memory: 1 << 20, Config: models.VMConfig{Template: true},
+	})
+	return infra
+}
+
+func TestCheckCapacity(t *testing.T) {
+	tests := []struct {
+		name        string
+		infra       *models.Infrastructure
+		cluster     string
+		cpuRatio    float64
+		memoryRatio float64
+		exceeded    []string
+	}{
+		{
+			name:  "fits",
+			infra: sourceVMs(4, 4, 8192), cluster: "DR",
+			// 16+2 vCPUs on 16 cores, 32+4 GiB of 128 GiB
+			cpuRatio: 1.125, memoryRatio: 0.28125,
+		},
+		{
+			name:  "vcpus over",
+			infra: sourceVMs(16, 4, 2048), cluster: "DR",
+			cpuRatio: 4.125, memoryRatio: 0.28125,
+			exceeded: []string{"vCPU overcommit 4.12:1 is over 4.00:1"},
+		},
+		{
+			name:  "memory over",
+			infra: sourceVMs(4, 2, 32768), cluster: "DR",
+			cpuRatio: 0.625, memoryRatio: 1.03125,
+			exceeded: []string{"memory overcommit 1.03:1 is over 1.00:1"},
+		},
+		{
+			name:  "whole target without a cluster",
+			infra: sourceVMs(4, 4, 8192),
+			// 16+18 vCPUs on 48 cores, 32+68 GiB of 640 GiB
+			cpuRatio: 34.0 / 48, memoryRatio: 100.0 / 640,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := CheckCapacity(tt.infra, targetFixture(), tt.cluster, 4, 1)
+			if err != nil {
+				t.Fatalf("CheckCapacity: %v", err)
+			}
+			if want := len(tt.infra.VirtualMachines) - 1; check.VMs != want {
+				t.Errorf("VMs = %d, want %d without the template", check.VMs, want)
+			}
+			if got := check.CPURatio(); got != tt.cpuRatio {
+				t.Errorf("CPURatio = %v, want %v", got, tt.cpuRatio)
+			}
+			if got := check.MemoryRatio(); got != tt.memoryRatio {
+				t.Errorf("MemoryRatio = %v, want %v", got, tt.memoryRatio)
+			}
+			if got := check.Exceeded(); strings.Join(got, "|") != strings.Join(tt.exceeded, "|") {
+				t.Errorf("Exceeded = %q, want %q", got, tt.exceeded)
+			}
+		})
+	}
+}
+
+func TestCheckCapacityOfSourceItself(t *testing.T) {
+	// Generating for the discovered cluster: its own VMs are the ones being
+	// generated and must not count twice
+	target := targetFixture()
+	check, err := CheckCapacity(target, target, "DR", 4, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.ExistingVCPUs != 0 || check.VCPUs != 18 {
+		t.Errorf("VCPUs = %d, existing %d; want 18 and 0", check.VCPUs, check.ExistingVCPUs)
+	}
+}
+
+func TestCheckCapacityWithoutHosts(t *testing.T) {
+	if _, err := CheckCapacity(sourceVMs(1, 1, 1024), targetFixture(), "Nowhere", 4, 1); err == nil {
+		t.Error("unknown cluster checked")
+	}
+}
+
+func TestCapacityComment(t *testing.T) {
+	infra := vmwareFixture()
+	check, err := CheckCapacity(infra, targetFixture(), "DR", 0.25, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := generate(t, "terraform", []*models.Infrastructure{infra}, GenerateOptions{
+		Capacity: map[string]*CapacityCheck{CapacityKey(infra): check},
+	})
+	vms := files["virtual_machines.tf"]
+	if !strings.HasPrefix(vms, "# Capacity of DR for the 2 VMs below\n") {
+		t.Errorf("virtual_machines.tf does not start with the capacity comment:\n%s", vms)
+	}
+	if !strings.Contains(vms, "#   WARNING: vCPU overcommit 0.50:1 is over 0.25:1") {
+		t.Errorf("capacity comment has no warning:\n%s", vms)
+	}
+	if strings.Contains(files["data.tf"], "# Capacity") {
+		t.Error("capacity comment added to data.tf")
+	}
+}
@@ -0,0 +1,240 @@
+package generators
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the line comparison table; larger files only report
+// their size change
+const maxDiffCells = 4_000_000
+
+// File statuses reported by DiffResults
+const (
+	DiffAdded     = "added"
+	DiffModified  = "modified"
+	DiffUnchanged = "unchanged"
+)
+
+// FileDiff describes how a generated file differs from the file on disk
+type FileDiff struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"` // added, modified, unchanged
+	OldSize int    `json:"old_size"`
+	NewSize int    `json:"new_size"`
+	Binary  bool   `json:"binary,omitempty"` // binary or too large to diff line by line
+	Unified string `json:"unified,omitempty"`
+}
+
+// DiffResults compares generated results with the files already in
+// outputDir, producing a unified diff for each changed text file
+func DiffResults(results []*GenerateResult, outputDir string) ([]FileDiff, error) {
+	var diffs []FileDiff
+
+	for _, result := range results {
+		diff := FileDiff{Path: result.Path, NewSize: len(result.Content)}
+
+		existing, err := os.ReadFile(filepath.Join(outputDir, result.Path))
+		switch {
+		case os.IsNotExist(err):
+			diff.Status = DiffAdded
+		case err != nil:
+			return nil, fmt.Errorf("failed to read existing %s: %w", result.Path, err)
+		case bytes.Equal(existing, result.Content):
+			diff.Status = DiffUnchanged
+			diff.OldSize = len(existing)
+		default:
+			diff.Status = DiffModified
+			diff.OldSize = len(existing)
+		}
+
+		if diff.Status != DiffUnchanged {
+			if isBinary(existing) || isBinary(result.Content) {
+				diff.Binary = true
+			} else if unified, ok := unifiedDiff(result.Path, string(existing), string(result.Content)); ok {
+				diff.Unified = unified
+			} else {
+				diff.Binary = true
+			}
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	return diffs, nil
+}
+
+// isBinary reports whether content looks like binary data
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns a unified diff between old and new, or false if the
+// files are too large to compare line by line
+func unifiedDiff(path, old, new string) (string, bool) {
+	a := splitLines(old)
+	b := splitLines(new)
+	if len(a)*len(b) > maxDiffCells {
+		return "", false
+	}
+
+	ops := editScript(a, b)
+
+	var sb strings.Builder
+	fromFile, toFile := "a/"+path, "b/"+path
+	if old == "" {
+		fromFile = "/dev/null"
+	}
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromFile, toFile))
+
+	// Walk the script, emitting hunks of changes with surrounding context
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			// Stop once the unchanged run is long enough to separate hunks
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end += min(diffContext, run-end)
+				break
+			}
+			end = run
+		}
+
+		oldStart, newStart := lineNumbers(ops[:start])
+		var oldCount, newCount int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount)))
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+
+		i = end
+	}
+
+	return sb.String(), true
+}
+
+// editScript computes a minimal line edit script using the longest common
+// subsequence of a and b
+func editScript(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', a[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', b[j]})
+	}
+
+	return ops
+}
+
+// lineNumbers returns the 1-based old and new line numbers following ops
+func lineNumbers(ops []diffOp) (int, int) {
+	oldLine, newLine := 1, 1
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldLine++
+		}
+		if op.kind != '-' {
+			newLine++
+		}
+	}
+	return oldLine, newLine
+}
+
+// hunkRange formats a unified diff hunk range
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits content into lines without their terminators
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// min returns the smaller of two ints
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
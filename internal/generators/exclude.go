@@ -0,0 +1,154 @@
+package generators
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// ExcludeOptions lists datastores and networks that will not exist in the
+// target environment and must not be referenced by generated code
+type ExcludeOptions struct {
+	Datastores []string `json:"datastores,omitempty"`
+	Networks   []string `json:"networks,omitempty"`
+}
+
+// ExclusionNote describes how excluding resources affected a VM
+type ExclusionNote struct {
+	Provider string `json:"provider"`
+	VM       string `json:"vm"`
+	Skipped  bool   `json:"skipped"` // the VM was left out entirely
+	Reason   string `json:"reason"`
+}
+
+// ExcludeResources returns copies of the infrastructures without the excluded
+// datastores and networks. Datastores may be given by name or ID; a disk is
+// matched by its datastore ID where it has one, since datastore names are
+// not unique across hosts. Disks and network cards on excluded resources are
+// dropped from VMs; VMs whose disks or network cards all use excluded
+// resources are skipped, since nothing meaningful would be left to generate.
+func ExcludeResources(infrastructures []*models.Infrastructure, opts ExcludeOptions) ([]*models.Infrastructure, []ExclusionNote) {
+	datastores := nameSet(opts.Datastores)
+	networks := nameSet(opts.Networks)
+	if len(datastores) == 0 && len(networks) == 0 {
+		return infrastructures, nil
+	}
+
+	var result []*models.Infrastructure
+	var notes []ExclusionNote
+
+	for _, infra := range infrastructures {
+		infraCopy := *infra
+		infraCopy.VirtualMachines = nil
+		infraCopy.Networks = nil
+		infraCopy.Storage = nil
+
+		// VMware network names are inventory paths, while network cards
+		// carry the base name
+		for _, network := range infra.Networks {
+			if !networks[strings.ToLower(network.Name)] && !networks[strings.ToLower(path.Base(network.Name))] && !networks[strings.ToLower(network.ID)] {
+				infraCopy.Networks = append(infraCopy.Networks, network)
+			}
+		}
+		// Whether each discovered datastore, by ID, is excluded
+		storageExcluded := make(map[string]bool)
+		for _, storage := range infra.Storage {
+			excluded := datastores[strings.ToLower(storage.Name)] || datastores[strings.ToLower(storage.ID)]
+			storageExcluded[storage.ID] = excluded
+			if !excluded {
+				infraCopy.Storage = append(infraCopy.Storage, storage)
+			}
+		}
+
+		for _, vm := range infra.VirtualMachines {
+			var disks []models.Disk
+			for _, disk := range vm.Disks {
+				if !diskExcluded(disk, datastores, storageExcluded) {
+					disks = append(disks, disk)
+				}
+			}
+			var nics []models.NetworkCard
+			for _, nic := range vm.NetworkCards {
+				if !networks[strings.ToLower(nic.Network)] {
+					nics = append(nics, nic)
+				}
+			}
+
+			droppedDisks := len(vm.Disks) - len(disks)
+			droppedNICs := len(vm.NetworkCards) - len(nics)
+
+			switch {
+			case droppedDisks > 0 && len(disks) == 0:
+				notes = append(notes, ExclusionNote{Provider: infra.Provider, VM: vm.Name, Skipped: true,
+					Reason: "all disks are on excluded datastores"})
+				continue
+			case droppedNICs > 0 && len(nics) == 0:
+				notes = append(notes, ExclusionNote{Provider: infra.Provider, VM: vm.Name, Skipped: true,
+					Reason: "all network cards are on excluded networks"})
+				continue
+			case droppedDisks > 0 || droppedNICs > 0:
+				notes = append(notes, ExclusionNote{Provider: infra.Provider, VM: vm.Name,
+					Reason: fmt.Sprintf("dropped %d disk(s) and %d network card(s) on excluded resources", droppedDisks, droppedNICs)})
+			}
+
+			vm.Disks = disks
+			vm.NetworkCards = nics
+			infraCopy.VirtualMachines = append(infraCopy.VirtualMachines, vm)
+		}
+
+		result = append(result, &infraCopy)
+	}
+
+	return result, notes
+}
+
+// diskExcluded reports whether a disk is on an excluded datastore. A disk on
+// a discovered datastore is matched by its datastore ID, which VMware disks
+// carry as their datastore; otherwise, as in older discovery files without
+// IDs, by the datastore name or ID given.
+func diskExcluded(disk models.Disk, datastores, storageExcluded map[string]bool) bool {
+	for _, id := range []string{disk.DatastoreID, disk.Datastore} {
+		if excluded, ok := storageExcluded[id]; ok && id != "" {
+			return excluded
+		}
+	}
+	return datastores[strings.ToLower(disk.Datastore)] || datastores[strings.ToLower(disk.DatastoreID)]
+}
+
+// nameSet builds a case-insensitive set of names
+func nameSet(names []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			set[strings.ToLower(name)] = true
+		}
+	}
+	return set
+}
+
+// ExcludeComplex returns copies of the infrastructures without VMs whose
+// migration complexity score is above max; templates are always kept
+func ExcludeComplex(infrastructures []*models.Infrastructure, max int) ([]*models.Infrastructure, []ExclusionNote) {
+	var result []*models.Infrastructure
+	var notes []ExclusionNote
+
+	for _, infra := range infrastructures {
+		infraCopy := *infra
+		infraCopy.VirtualMachines = nil
+
+		for _, vm := range infra.VirtualMachines {
+			if !vm.Config.Template && vm.Complexity > max {
+				notes = append(notes, ExclusionNote{Provider: infra.Provider, VM: vm.Name, Skipped: true,
+					Reason: fmt.Sprintf("complexity %d is above %d", vm.Complexity, max)})
+				continue
+			}
+			infraCopy.VirtualMachines = append(infraCopy.VirtualMachines, vm)
+		}
+
+		result = append(result, &infraCopy)
+	}
+
+	return result, notes
+}
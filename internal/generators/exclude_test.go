@@ -0,0 +1,144 @@
+package generators
+
+import (
+	"reflect"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestExcludeDatastores(t *testing.T) {
+	fixture := func() *models.Infrastructure {
+		infra := vmwareFixture()
+		infra.Storage = append(infra.Storage,
+			models.Storage{ID: "datastore-12", Name: "ds1"},
+			models.Storage{ID: "datastore-13", Name: "archive"})
+		infra.VirtualMachines[0].Disks = append(infra.VirtualMachines[0].Disks,
+			models.Disk{ID: "2001", Name: "Hard disk 2", Datastore: "ds1", DatastoreID: "datastore-12"},
+			// Renamed since the disk was read; only the ID still matches
+			models.Disk{ID: "2002", Name: "Hard disk 3", Datastore: "old-archive", DatastoreID: "datastore-13"})
+		infra.VirtualMachines[1].Disks[0].DatastoreID = ""
+		return infra
+	}
+
+	tests := []struct {
+		name     string
+		exclude  []string
+		morefs   bool // disks name their datastore by moref, as VMware discovers them
+		webDisks []string
+		skipped  []string
+		storage  []string
+	}{
+		{
+			name:     "by ID",
+			exclude:  []string{"datastore-12"},
+			webDisks: []string{"2000", "2002"},
+			storage:  []string{"datastore-11", "datastore-13"},
+		},
+		{
+			name:     "by name resolved to ID",
+			exclude:  []string{"Archive"},
+			webDisks: []string{"2000", "2001"},
+			storage:  []string{"datastore-11", "datastore-12"},
+		},
+		{
+			name:    "by name shared by two datastores",
+			exclude: []string{"ds1"},
+			// db-01's disk has no datastore ID and is matched by name
+			webDisks: []string{"2002"},
+			skipped:  []string{"db-01"},
+			storage:  []string{"datastore-13"},
+		},
+		{
+			name:     "by name on disks carrying the moref",
+			exclude:  []string{"archive"},
+			morefs:   true,
+			webDisks: []string{"2000", "2001"},
+			storage:  []string{"datastore-11", "datastore-12"},
+		},
+		{
+			name:    "every datastore a VM uses, by name",
+			exclude: []string{"ds1", "archive"},
+			morefs:  true,
+			skipped: []string{"web-01", "db-01"},
+			storage: []string(nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infra := fixture()
+			if tt.morefs {
+				for i := range infra.VirtualMachines {
+					for j := range infra.VirtualMachines[i].Disks {
+						if disk := &infra.VirtualMachines[i].Disks[j]; disk.DatastoreID != "" {
+							disk.Datastore, disk.DatastoreID = disk.DatastoreID, ""
+						}
+					}
+				}
+			}
+			result, notes := ExcludeResources([]*models.Infrastructure{infra}, ExcludeOptions{Datastores: tt.exclude})
+
+			var storage []string
+			for _, s := range result[0].Storage {
+				storage = append(storage, s.ID)
+			}
+			if !reflect.DeepEqual(storage, tt.storage) {
+				t.Errorf("storage = %v, want %v", storage, tt.storage)
+			}
+
+			var webDisks []string
+			for _, vm := range result[0].VirtualMachines {
+				if vm.Name != "web-01" {
+					continue
+				}
+				for _, disk := range vm.Disks {
+					webDisks = append(webDisks, disk.ID)
+				}
+			}
+			if !reflect.DeepEqual(webDisks, tt.webDisks) {
+				t.Errorf("web-01 disks = %v, want %v", webDisks, tt.webDisks)
+			}
+
+			var skipped []string
+			for _, note := range notes {
+				if note.Skipped {
+					skipped = append(skipped, note.VM)
+				}
+			}
+			if !reflect.DeepEqual(skipped, tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+func TestExcludeNetworks(t *testing.T) {
+	infra := vmwareFixture()
+	// VMware network names are inventory paths; cards carry the base name
+	infra.Networks = []models.Network{
+		{ID: "network-1", Name: "/DC1/network/VM Network"},
+		{ID: "dvportgroup-2", Name: "/DC1/network/DSwitch/Backup"},
+	}
+	infra.VirtualMachines[0].NetworkCards = append(infra.VirtualMachines[0].NetworkCards,
+		models.NetworkCard{ID: "4001", Key: 4001, Network: "Backup"})
+
+	result, notes := ExcludeResources([]*models.Infrastructure{infra}, ExcludeOptions{Networks: []string{"vm network"}})
+
+	if got := result[0].Networks; len(got) != 1 || got[0].ID != "dvportgroup-2" {
+		t.Errorf("networks = %+v, want only Backup", got)
+	}
+	var vms []string
+	for _, vm := range result[0].VirtualMachines {
+		vms = append(vms, vm.Name)
+		if vm.Name == "web-01" && (len(vm.NetworkCards) != 1 || vm.NetworkCards[0].Network != "Backup") {
+			t.Errorf("web-01 cards = %+v, want only Backup", vm.NetworkCards)
+		}
+	}
+	if want := []string{"web-01", "ubuntu-template"}; !reflect.DeepEqual(vms, want) {
+		t.Errorf("VMs = %v, want %v", vms, want)
+	}
+	if len(notes) != 2 || !notes[1].Skipped || notes[1].VM != "db-01" {
+		t.Errorf("notes = %+v, want web-01 trimmed and db-01 skipped", notes)
+	}
+}
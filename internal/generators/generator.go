@@ -2,10 +2,12 @@ package generators
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"valhalla/internal/logger"
 	"valhalla/internal/models"
+	"valhalla/internal/validation"
 )
 
 // Generator defines the interface for IaC generators
@@ -34,8 +36,56 @@ type GenerateOptions struct {
 	FormatCode   bool              `json:"format_code"`
 	AddComments  bool              `json:"add_comments"`
 	Modular      bool              `json:"modular"`
+	Flatten      bool              `json:"flatten"`
+	MaxFieldSize int               `json:"max_field_size,omitempty"`
+	PreserveMAC  bool              `json:"preserve_mac"`
+	SkipRDMVMs   bool              `json:"skip_rdm_vms"`
+	UpgradeHWVersion int           `json:"upgrade_hw_version,omitempty"` // raise older VMs to this hardware version; 0 keeps the discovered one
+	Stack        string            `json:"stack,omitempty"`
+
+	// EmitPostProvision carries post_provision.* annotations into commented
+	// out post-clone blocks and POST_PROVISION.md; nothing is ever run
+	EmitPostProvision bool `json:"emit_post_provision"`
+
+	// AllowLinkedClones recreates linked clones of discovered templates as
+	// linked clones rather than full copies
+	AllowLinkedClones bool `json:"allow_linked_clones"`
+
+	// WithImports adds Terraform import blocks and an import.sh script
+	// adopting the discovered VMs rather than creating them
+	WithImports bool `json:"with_imports"`
+
+	// IncludeAllDataSources looks up every discovered network, datastore,
+	// host and resource pool, not just those the generated VMs refer to
+	IncludeAllDataSources bool `json:"include_all_data_sources"`
+
+	// CreateNetworks creates the discovered port groups the VMs use, with
+	// their VLAN and security policy, rather than only looking them up
+	CreateNetworks bool `json:"create_networks"`
+
+	// IncludeSecrets writes plaintext credentials from Secrets, keyed by
+	// generated variable name, into git-ignored files
+	IncludeSecrets bool              `json:"include_secrets"`
+	Secrets        map[string]string `json:"-"`
+
+	// AllowSecrets writes files even when the secret scan finds credentials
+	// in them; SecretRules tunes the scan's rules
+	AllowSecrets bool                     `json:"allow_secrets"`
+	SecretRules  validation.RuleOverrides `json:"-"`
+
+	// Capacity holds the projected target capacity per infrastructure, by
+	// CapacityKey, stated at the top of the generated VMs
+	Capacity map[string]*CapacityCheck `json:"-"`
+
+	// Hooks post-process the generated files before they are checked and
+	// written; see PrepareHooks
+	Hooks []Hook `json:"-"`
 }
 
+// DefaultMaxFieldSize is the annotation size in bytes above which generated
+// code references a sidecar notes file instead of inlining the value
+const DefaultMaxFieldSize = 4096
+
 // GenerateResult represents the result of IaC generation
 type GenerateResult struct {
 	Path      string                 `json:"path"`
@@ -127,28 +177,146 @@ func (g *BaseGenerator) FilterInfrastructureByProvider(infrastructures []*models
 
 // GenerateResourceName creates a valid resource name from a given name
 func (g *BaseGenerator) GenerateResourceName(name string) string {
-	// Replace invalid characters with underscores
-	resourceName := strings.ReplaceAll(name, " ", "_")
-	resourceName = strings.ReplaceAll(resourceName, "-", "_")
-	resourceName = strings.ReplaceAll(resourceName, ".", "_")
-	resourceName = strings.ToLower(resourceName)
-	
-	// Ensure it starts with a letter
-	if len(resourceName) > 0 && (resourceName[0] < 'a' || resourceName[0] > 'z') {
-		resourceName = "res_" + resourceName
-	}
-	
-	return resourceName
+	return SanitizeIdentifier(name)
 }
 
 // SanitizeValue sanitizes a value for use in generated code
 func (g *BaseGenerator) SanitizeValue(value string) string {
-	// Escape quotes and other special characters
-	value = strings.ReplaceAll(value, `"`, `\"`)
+	// Escape backslashes first so escaped quotes are not doubled
 	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
 	return value
 }
 
+// CheckSecrets scans generated files for hardcoded credentials and logs
+// what it finds. Error-level findings fail a run that would write files,
+// unless opts.AllowSecrets is set. The plaintext secrets files requested
+// with IncludeSecrets are not scanned.
+func (g *BaseGenerator) CheckSecrets(results []*GenerateResult, opts GenerateOptions) error {
+	validator := validation.NewValidator(g.log)
+	scanOpts := validation.ValidateOptions{Rules: opts.SecretRules}
+
+	var blocking []string
+	for _, result := range results {
+		if result.Type == "secrets" {
+			continue
+		}
+		for _, issue := range validator.ScanSecrets(result.Path, result.Content, scanOpts) {
+			g.log.Warn("Possible secret in generated file", "file", result.Path, "line", issue.Line, "rule", issue.Rule, "message", issue.Message)
+			if issue.Severity == validation.SeverityError {
+				blocking = append(blocking, fmt.Sprintf("%s:%d", result.Path, issue.Line))
+			}
+		}
+	}
+
+	if len(blocking) == 0 || opts.DryRun || opts.AllowSecrets {
+		return nil
+	}
+	return fmt.Errorf("refusing to write generated files containing possible secrets (%s); move them into variables or pass --allow-secrets", strings.Join(blocking, ", "))
+}
+
+// hasRDM reports whether any of a VM's disks is a raw device mapping
+func hasRDM(vm models.VirtualMachine) bool {
+	for _, disk := range vm.Disks {
+		if disk.IsRDM() {
+			return true
+		}
+	}
+	return false
+}
+
+// countGuests counts virtual machines and containers, excluding templates
+func countGuests(vms []models.VirtualMachine) (int, int) {
+	var machines, containers int
+	for _, vm := range vms {
+		switch {
+		case vm.Config.Template:
+		case vm.IsContainer():
+			containers++
+		default:
+			machines++
+		}
+	}
+	return machines, containers
+}
+
+// PrimaryDatastore returns the datastore a VM should be placed on: the
+// datastore of its first disk, or the first known datastore when the VM has
+// no disk information (for example when imported from a CSV inventory)
+func (g *BaseGenerator) PrimaryDatastore(vm models.VirtualMachine, storage []models.Storage) string {
+	if len(vm.Disks) > 0 {
+		return vm.Disks[0].Datastore
+	}
+	if len(storage) > 0 {
+		return storage[0].Name
+	}
+	return ""
+}
+
+// OrderedNetworkCards returns a VM's network cards in device key order, the
+// order the guest enumerates them in. Preserving MACs pins each card's MAC
+// but keeps this order, so interface names inside the guest don't change.
+func (g *BaseGenerator) OrderedNetworkCards(vm models.VirtualMachine) []models.NetworkCard {
+	nics := append([]models.NetworkCard(nil), vm.NetworkCards...)
+	sort.SliceStable(nics, func(i, j int) bool {
+		return nics[i].Key < nics[j].Key
+	})
+	return nics
+}
+
+// AnnotationComments renders VM annotations as comment lines using the given
+// comment prefix. Values longer than limit bytes are never inlined; they are
+// collected into a notes/<resourceName>.txt sidecar result which the comment refers to.
+func (g *BaseGenerator) AnnotationComments(vm models.VirtualMachine, resourceName, prefix string, limit int) (string, *GenerateResult) {
+	if len(vm.Annotations) == 0 {
+		return "", nil
+	}
+	if limit <= 0 {
+		limit = DefaultMaxFieldSize
+	}
+
+	keys := make([]string, 0, len(vm.Annotations))
+	for k := range vm.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	notesPath := fmt.Sprintf("notes/%s.txt", resourceName)
+
+	var comments strings.Builder
+	var notes strings.Builder
+
+	for _, key := range keys {
+		value := vm.Annotations[key]
+		if len(value) > limit {
+			comments.WriteString(fmt.Sprintf("%s %s: see %s (%d bytes)\n", prefix, key, notesPath, len(value)))
+			notes.WriteString(fmt.Sprintf("== %s ==\n%s\n\n", key, value))
+			continue
+		}
+
+		for i, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+			if i == 0 {
+				comments.WriteString(fmt.Sprintf("%s %s: %s\n", prefix, key, line))
+			} else {
+				comments.WriteString(fmt.Sprintf("%s   %s\n", prefix, line))
+			}
+		}
+	}
+
+	if notes.Len() == 0 {
+		return comments.String(), nil
+	}
+
+	content := notes.String()
+	return comments.String(), &GenerateResult{
+		Path:      notesPath,
+		Content:   []byte(content),
+		Size:      len(content),
+		Type:      "notes",
+		Resources: []string{},
+	}
+}
+
 // ResourceCounter tracks resource counts for naming
 type ResourceCounter struct {
 	counts map[string]int
@@ -175,3 +343,58 @@ func (rc *ResourceCounter) GetUniqueName(resourceType, baseName string) string {
 	}
 	return fmt.Sprintf("%s_%d", baseName, count)
 }
+
+// ResourceNames hands out distinct resource names within one generated
+// file set. Names that collide once sanitized, such as web-01 and Web.01,
+// get a _2, _3, ... suffix in the order they are requested, so the same
+// inventory always yields the same names.
+type ResourceNames struct {
+	sanitize func(string) string
+	counter  *ResourceCounter
+	taken    map[string]bool
+
+	// Mapping records the name assigned to each original name; VMs sharing
+	// an exact name are keyed by "name (id)" after the first
+	Mapping map[string]string
+}
+
+// NewResourceNames creates a registry that sanitizes names with sanitize
+func NewResourceNames(sanitize func(string) string) *ResourceNames {
+	return &ResourceNames{
+		sanitize: sanitize,
+		counter:  NewResourceCounter(),
+		taken:    make(map[string]bool),
+		Mapping:  make(map[string]string),
+	}
+}
+
+// Name returns a name for original that no earlier call has returned
+func (r *ResourceNames) Name(original string) string {
+	base := r.sanitize(original)
+	name := r.counter.GetUniqueName(base, base)
+	// A suffixed name can itself be another VM's sanitized name
+	for r.taken[name] {
+		name = r.counter.GetUniqueName(base, base)
+	}
+	r.taken[name] = true
+	return name
+}
+
+// VMs names every VM except templates, returning names aligned with vms
+// and "" for templates
+func (r *ResourceNames) VMs(vms []models.VirtualMachine) []string {
+	names := make([]string, len(vms))
+	for i, vm := range vms {
+		if vm.Config.Template {
+			continue
+		}
+		names[i] = r.Name(vm.Name)
+
+		key := vm.Name
+		if _, seen := r.Mapping[key]; seen {
+			key = fmt.Sprintf("%s (%s)", vm.Name, vm.ID)
+		}
+		r.Mapping[key] = names[i]
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package generators
+
+import (
+	"strconv"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// HardwareVersion returns the virtual hardware version to generate for a VM:
+// the discovered version, or upgradeTo when that is newer, in which case
+// upgradedFrom names the discovered version. A zero version means it is
+// unknown and the provider default applies.
+func (g *BaseGenerator) HardwareVersion(vm models.VirtualMachine, upgradeTo int) (version int, upgradedFrom string) {
+	version = vm.Hardware.VersionNumber()
+	if version > 0 && upgradeTo > version {
+		return upgradeTo, vm.Hardware.Version
+	}
+	return version, ""
+}
+
+// VideoExtraConfig returns the VMX settings that reproduce a VM's video card,
+// which the vSphere providers only expose through extra config, as ordered
+// key/value pairs. It is empty when the video card wasn't discovered.
+func (g *BaseGenerator) VideoExtraConfig(vm models.VirtualMachine) [][2]string {
+	hw := vm.Hardware
+	if hw.VideoRAMKB <= 0 {
+		return nil
+	}
+
+	settings := [][2]string{
+		{"svga.vramSize", strconv.FormatInt(units.FromKiB(hw.VideoRAMKB).Bytes(), 10)},
+	}
+	if hw.NumDisplays > 0 {
+		settings = append(settings, [2]string{"svga.numDisplays", strconv.Itoa(hw.NumDisplays)})
+	}
+	if hw.Enable3D {
+		settings = append(settings, [2]string{"mks.enable3d", "TRUE"})
+		if hw.GraphicsMemoryKB > 0 {
+			settings = append(settings, [2]string{"svga.graphicsMemoryKB", strconv.FormatInt(hw.GraphicsMemoryKB, 10)})
+		}
+	}
+	return settings
+}
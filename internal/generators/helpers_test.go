@@ -0,0 +1,199 @@
+package generators
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+)
+
+// testLogger discards log output
+func testLogger() *logger.Logger {
+	return logger.NewWithOutput(io.Discard)
+}
+
+// vmwareFixture returns a small vCenter inventory: two VMs on one network
+// and datastore, and a template
+func vmwareFixture() *models.Infrastructure {
+	return &models.Infrastructure{
+		Provider:      "vmware",
+		Server:        "vcenter.example.com",
+		Datacenter:    "DC1",
+		Cluster:       "Cluster1",
+		DiscoveryTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		VirtualMachines: []models.VirtualMachine{
+			{
+				ID: "vm-101", Name: "web-01", PowerState: "poweredOn", State: "poweredOn",
+				CPUs: 2, Memory: 4096,
+				Config:   models.VMConfig{GuestID: "ubuntu64Guest", UUID: "4201-0001"},
+				Hardware: models.HardwareInfo{Version: "vmx-19", Firmware: "efi"},
+				Disks: []models.Disk{
+					{ID: "2000", Name: "Hard disk 1", Size: 40, Type: "thin", Datastore: "ds1", DatastoreID: "datastore-11"},
+				},
+				NetworkCards: []models.NetworkCard{
+					{ID: "4000", Key: 4000, Name: "Network adapter 1", Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:01", Connected: true, StartConnect: true},
+				},
+			},
+			{
+				ID: "vm-102", Name: "db-01", PowerState: "poweredOff", State: "poweredOff",
+				CPUs: 4, Memory: 8192,
+				Config:   models.VMConfig{GuestID: "rhel8_64Guest", UUID: "4201-0002"},
+				Hardware: models.HardwareInfo{Version: "vmx-19", Firmware: "bios"},
+				Disks: []models.Disk{
+					{ID: "2000", Name: "Hard disk 1", Size: 100, Type: "thick", Datastore: "ds1", DatastoreID: "datastore-11"},
+				},
+				NetworkCards: []models.NetworkCard{
+					{ID: "4000", Key: 4000, Name: "Network adapter 1", Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:02", StartConnect: true},
+				},
+			},
+			{
+				ID: "vm-900", Name: "ubuntu-template", PowerState: "poweredOff",
+				CPUs: 2, Memory: 2048,
+				Config: models.VMConfig{Template: true, GuestID: "ubuntu64Guest", UUID: "4201-0900"},
+			},
+		},
+		Networks: []models.Network{
+			{ID: "network-1", Name: "VM Network", Type: "Network"},
+		},
+		Storage: []models.Storage{
+			{ID: "datastore-11", Name: "ds1", Type: "VMFS", Capacity: 2048, FreeSpace: 1024, Accessible: true},
+		},
+	}
+}
+
+// generate runs a generator without writing files and returns the results
+// by path
+func generate(t *testing.T, format string, infrastructures []*models.Infrastructure, opts GenerateOptions) map[string]string {
+	t.Helper()
+
+	results := generateResults(t, format, infrastructures, opts)
+	files := make(map[string]string, len(results))
+	for _, result := range results {
+		files[result.Path] = string(result.Content)
+	}
+	return files
+}
+
+// generateResults runs a generator without writing files
+func generateResults(t *testing.T, format string, infrastructures []*models.Infrastructure, opts GenerateOptions) []*GenerateResult {
+	t.Helper()
+
+	generator, err := NewGenerator(format, testLogger())
+	if err != nil {
+		t.Fatalf("NewGenerator(%s): %v", format, err)
+	}
+	opts.DryRun = true
+	if opts.OutputDir == "" {
+		opts.OutputDir = t.TempDir()
+	}
+
+	results, err := generator.Generate(infrastructures, opts)
+	if err != nil {
+		t.Fatalf("Generate(%s): %v", format, err)
+	}
+	return results
+}
+
+// joined returns every generated file's content, in no particular order
+func joined(files map[string]string) string {
+	var all strings.Builder
+	for _, content := range files {
+		all.WriteString(content)
+	}
+	return all.String()
+}
+
+// checkHCL fails the test unless the .tf files among files parse, no block
+// is declared twice and every data source, variable and vSphere resource
+// referenced exists
+func checkHCL(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	parser := hclparse.NewParser()
+	declared := make(map[string]string)
+	type reference struct{ path, name string }
+	var references []reference
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		if strings.HasSuffix(path, ".tf") {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		file, diags := parser.ParseHCL([]byte(files[path]), path)
+		if diags.HasErrors() {
+			t.Errorf("%s does not parse: %s", path, diags.Error())
+			continue
+		}
+		body := file.Body.(*hclsyntax.Body)
+
+		for _, block := range body.Blocks {
+			var key string
+			switch {
+			case block.Type == "resource" && len(block.Labels) == 2:
+				key = block.Labels[0] + "." + block.Labels[1]
+			case block.Type == "data" && len(block.Labels) == 2:
+				key = "data." + block.Labels[0] + "." + block.Labels[1]
+			case block.Type == "variable" && len(block.Labels) == 1:
+				key = "var." + block.Labels[0]
+			default:
+				continue
+			}
+			if previous, ok := declared[key]; ok {
+				t.Errorf("%s:%d: %s is already declared in %s", path, block.TypeRange.Start.Line, key, previous)
+			}
+			declared[key] = path
+		}
+
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			attribute, ok := node.(*hclsyntax.Attribute)
+			if !ok {
+				return nil
+			}
+			for _, traversal := range attribute.Expr.Variables() {
+				if name := referenceName(traversal); name != "" {
+					references = append(references, reference{path, name})
+				}
+			}
+			return nil
+		})
+	}
+
+	for _, ref := range references {
+		if _, ok := declared[ref.name]; !ok {
+			t.Errorf("%s: %s is referenced but not declared", ref.path, ref.name)
+		}
+	}
+}
+
+// referenceName returns the data source, variable or vSphere resource a
+// traversal refers to, as data.<type>.<name>, var.<name> or
+// vsphere_<type>.<name>, or "" for anything else
+func referenceName(traversal hcl.Traversal) string {
+	var parts []string
+	for _, step := range traversal {
+		switch step := step.(type) {
+		case hcl.TraverseRoot:
+			parts = append(parts, step.Name)
+		case hcl.TraverseAttr:
+			parts = append(parts, step.Name)
+		default:
+			return ""
+		}
+		if parts[0] == "var" && len(parts) == 2 || parts[0] == "data" && len(parts) == 3 ||
+			strings.HasPrefix(parts[0], "vsphere_") && len(parts) == 2 {
+			return strings.Join(parts, ".")
+		}
+	}
+	return ""
+}
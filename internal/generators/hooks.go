@@ -0,0 +1,260 @@
+package generators
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultHookTimeout is how long a hook command may run per file
+const DefaultHookTimeout = 30 * time.Second
+
+// Hook post-processes generated files, such as to inject a company header.
+// For each generated file matching Files, Command is run with the file's
+// content on stdin, or Template is rendered with HookData, and the output
+// replaces the content.
+type Hook struct {
+	Name     string
+	Files    []string // shell-style patterns of generated paths, e.g. *.tf; empty matches every file
+	Command  string
+	Args     []string
+	Template string
+	Timeout  time.Duration
+
+	tmpl *template.Template
+}
+
+// HookData is what template hooks are rendered with
+type HookData struct {
+	Path     string // generated path, relative to the output directory
+	Content  string
+	Type     string // result type, e.g. main, variables or playbook
+	Provider string
+	Format   string // generator format, e.g. terraform
+}
+
+// hookFuncs are the functions available to template hooks
+var hookFuncs = template.FuncMap{
+	"comment": commentLines,
+}
+
+// PrepareHooks checks hooks before anything is generated and parses their
+// templates. Each hook needs a name and either a command or a template.
+func PrepareHooks(hooks []Hook) error {
+	seen := make(map[string]bool)
+	for i := range hooks {
+		hook := &hooks[i]
+		if hook.Name == "" {
+			return fmt.Errorf("generate hook %d has no name", i+1)
+		}
+		if seen[hook.Name] {
+			return fmt.Errorf("generate hook %s is defined twice", hook.Name)
+		}
+		seen[hook.Name] = true
+
+		if (hook.Command == "") == (hook.Template == "") {
+			return fmt.Errorf("generate hook %s needs either a command or a template", hook.Name)
+		}
+		for _, pattern := range hook.Files {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("generate hook %s has an invalid file pattern %q: %w", hook.Name, pattern, err)
+			}
+		}
+		if hook.Template != "" {
+			tmpl, err := parseHookTemplate(hook.Name, hook.Template)
+			if err != nil {
+				return fmt.Errorf("generate hook %s has an invalid template: %w", hook.Name, err)
+			}
+			hook.tmpl = tmpl
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the hook applies to a generated path. Patterns
+// without a slash match the file name in any directory.
+func (h Hook) Matches(name string) bool {
+	if len(h.Files) == 0 {
+		return true
+	}
+	name = filepath.ToSlash(name)
+	for _, pattern := range h.Files {
+		target := name
+		if !strings.Contains(pattern, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RunHooks runs opts.Hooks over the results, in order, once generation and
+// formatting are done and before the results are checked. Results record
+// the hooks applied to them in their "hooks" metadata. A dry run only
+// records the hooks that would run.
+func (g *BaseGenerator) RunHooks(results []*GenerateResult, opts GenerateOptions) error {
+	if len(opts.Hooks) == 0 {
+		return nil
+	}
+
+	for _, result := range results {
+		var applied []string
+		for _, hook := range opts.Hooks {
+			if !hook.Matches(result.Path) {
+				continue
+			}
+			applied = append(applied, hook.Name)
+			if opts.DryRun {
+				continue
+			}
+
+			data := HookData{
+				Path:     filepath.ToSlash(result.Path),
+				Content:  string(result.Content),
+				Type:     result.Type,
+				Provider: result.Provider,
+				Format:   g.format,
+			}
+			content, err := hook.run(data)
+			if err != nil {
+				return fmt.Errorf("hook %s failed on %s: %w", hook.Name, result.Path, err)
+			}
+			result.Content = content
+			result.Size = len(content)
+			g.log.Debug("Ran generate hook", "hook", hook.Name, "file", result.Path, "size_bytes", result.Size)
+		}
+
+		if len(applied) > 0 {
+			if result.Metadata == nil {
+				result.Metadata = make(map[string]interface{})
+			}
+			result.Metadata["hooks"] = applied
+		}
+	}
+	return nil
+}
+
+// run applies the hook to one file and returns the new content
+func (h Hook) run(data HookData) ([]byte, error) {
+	if h.Template != "" {
+		tmpl := h.tmpl
+		if tmpl == nil {
+			var err error
+			if tmpl, err = parseHookTemplate(h.Name, h.Template); err != nil {
+				return nil, fmt.Errorf("invalid template: %w", err)
+			}
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return h.runCommand(data)
+}
+
+// parseHookTemplate parses a template hook, failing on unknown fields
+func parseHookTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(hookFuncs).Option("missingkey=error").Parse(text)
+}
+
+// runCommand runs the hook's command with the content on stdin. The command
+// runs in an empty temporary directory, removed afterwards, with an
+// environment of just PATH and the file's details, so it neither sees the
+// output directory nor inherits credentials from the environment. Hooks see
+// files before they are written, so they cannot change the other generated
+// files.
+func (h Hook) runCommand(data HookData) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "valhalla-hook-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hook directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Relative commands would otherwise resolve against the temporary
+	// directory
+	command := h.Command
+	if strings.ContainsRune(command, os.PathSeparator) && !filepath.IsAbs(command) {
+		if command, err = filepath.Abs(command); err != nil {
+			return nil, err
+		}
+	}
+
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command, h.Args...)
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"VALHALLA_HOOK=" + h.Name,
+		"VALHALLA_FILE=" + data.Path,
+		"VALHALLA_FILE_TYPE=" + data.Type,
+		"VALHALLA_PROVIDER=" + data.Provider,
+		"VALHALLA_FORMAT=" + data.Format,
+	}
+	cmd.Stdin = strings.NewReader(data.Content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	// A hook that forgot to echo its input would silently empty the file
+	if stdout.Len() == 0 && len(data.Content) > 0 {
+		return nil, errors.New("no output; hooks must write the whole new content to stdout")
+	}
+	return stdout.Bytes(), nil
+}
+
+// commentLines turns text into comment lines in the syntax of the file
+// being generated, for template hooks injecting headers. JSON has no
+// comments, so it returns "" for .json files.
+func commentLines(name, text string) string {
+	var prefix string
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return ""
+	case ".go", ".ts", ".js", ".cs":
+		prefix = "//"
+	case ".md":
+		return "<!--\n" + strings.TrimRight(text, "\n") + "\n-->\n"
+	default:
+		// HCL, YAML, Python, shell, requirements files and INI inventories
+		prefix = "#"
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			b.WriteString(prefix + "\n")
+			continue
+		}
+		b.WriteString(prefix + " " + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,294 @@
+package generators
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// generateWithHooks runs the Terraform generator over the VMware fixture
+// with hooks, writing to a temporary directory unless dryRun is set, and
+// returns the results by path and the directory
+func generateWithHooks(t *testing.T, hooks []Hook, dryRun bool) (map[string]*GenerateResult, string, error) {
+	t.Helper()
+
+	if err := PrepareHooks(hooks); err != nil {
+		t.Fatalf("PrepareHooks: %v", err)
+	}
+	generator, err := NewGenerator("terraform", testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	results, err := generator.Generate([]*models.Infrastructure{vmwareFixture()}, GenerateOptions{OutputDir: dir, DryRun: dryRun, Hooks: hooks})
+	// Written results carry the path they were written to
+	byPath := make(map[string]*GenerateResult, len(results))
+	for _, result := range results {
+		path := result.Path
+		if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsAbs(path) {
+			path = filepath.ToSlash(rel)
+		}
+		byPath[path] = result
+	}
+	return byPath, dir, err
+}
+
+// requireShell skips command hook tests where hooks can't be shell scripts
+func requireShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("command hooks are tested with shell scripts")
+	}
+}
+
+func TestPrepareHooks(t *testing.T) {
+	tests := []struct {
+		name  string
+		hooks []Hook
+		want  string
+	}{
+		{name: "valid", hooks: []Hook{{Name: "a", Command: "cat"}, {Name: "b", Template: "{{ .Content }}", Files: []string{"*.tf"}}}},
+		{name: "no name", hooks: []Hook{{Command: "cat"}}, want: "hook 1 has no name"},
+		{name: "duplicate", hooks: []Hook{{Name: "a", Command: "cat"}, {Name: "a", Command: "cat"}}, want: "defined twice"},
+		{name: "neither command nor template", hooks: []Hook{{Name: "a"}}, want: "either a command or a template"},
+		{name: "both command and template", hooks: []Hook{{Name: "a", Command: "cat", Template: "x"}}, want: "either a command or a template"},
+		{name: "bad pattern", hooks: []Hook{{Name: "a", Command: "cat", Files: []string{"[.tf"}}}, want: "invalid file pattern"},
+		{name: "bad template", hooks: []Hook{{Name: "a", Template: "{{ .Content"}}, want: "invalid template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := PrepareHooks(tt.hooks)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("PrepareHooks: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("PrepareHooks error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHookMatches(t *testing.T) {
+	tests := []struct {
+		files []string
+		path  string
+		want  bool
+	}{
+		{nil, "main.tf", true},
+		{[]string{"*.tf"}, "main.tf", true},
+		{[]string{"*.tf"}, "modules/vm/main.tf", true},
+		{[]string{"*.tf"}, "inventory.yml", false},
+		{[]string{"modules/*/*.tf"}, "modules/vm/main.tf", true},
+		{[]string{"modules/*/*.tf"}, "main.tf", false},
+		{[]string{"*.yml", "*.py"}, "__main__.py", true},
+	}
+
+	for _, tt := range tests {
+		if got := (Hook{Name: "h", Files: tt.files}).Matches(tt.path); got != tt.want {
+			t.Errorf("Hook{Files: %v}.Matches(%q) = %v, want %v", tt.files, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateHook(t *testing.T) {
+	hooks := []Hook{
+		{Name: "header", Files: []string{"*.tf"}, Template: `{{ comment .Path "Owned by platform team" }}{{ .Content }}`},
+		{Name: "policy", Files: []string{"outputs.tf"}, Template: "{{ .Content }}\n# {{ .Format }}/{{ .Provider }}/{{ .Type }}\n"},
+	}
+	results, dir, err := generateWithHooks(t, hooks, false)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	for path, result := range results {
+		if !strings.HasSuffix(path, ".tf") {
+			continue
+		}
+		if !strings.HasPrefix(string(result.Content), "# Owned by platform team\n") {
+			t.Errorf("%s has no header:\n%s", path, result.Content)
+		}
+		if result.Size != len(result.Content) {
+			t.Errorf("%s size %d, content is %d bytes", path, result.Size, len(result.Content))
+		}
+	}
+
+	// Hooks run in order, each on the previous one's output
+	outputs := results["outputs.tf"]
+	if names, _ := outputs.Metadata["hooks"].([]string); strings.Join(names, ",") != "header,policy" {
+		t.Errorf("outputs.tf hooks = %v, want header,policy", outputs.Metadata["hooks"])
+	}
+	if !strings.HasSuffix(string(outputs.Content), "# terraform/vmware/outputs\n") || !strings.HasPrefix(string(outputs.Content), "# Owned") {
+		t.Errorf("outputs.tf:\n%s", outputs.Content)
+	}
+
+	// The hooked content is what gets written
+	written, err := os.ReadFile(filepath.Join(dir, "outputs.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(outputs.Content) {
+		t.Errorf("written outputs.tf differs from the hooked content:\n%s", written)
+	}
+}
+
+func TestTemplateHookFailureAborts(t *testing.T) {
+	hooks := []Hook{{Name: "strict", Template: "{{ .Missing }}"}}
+	if _, _, err := generateWithHooks(t, hooks, false); err == nil || !strings.Contains(err.Error(), "hook strict failed on") {
+		t.Errorf("Generate error = %v, want the failing hook and file", err)
+	}
+}
+
+func TestExampleHeaderHook(t *testing.T) {
+	requireShell(t)
+
+	script, err := filepath.Abs(filepath.Join("..", "..", "examples", "hooks", "add-header.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hooks := []Hook{{Name: "header", Files: []string{"*.tf", "*.json"}, Command: script}}
+	results, _, err := generateWithHooks(t, hooks, false)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	vms := string(results["virtual_machines.tf"].Content)
+	if !strings.HasPrefix(vms, "# Copyright (c) Example Corp.") || !strings.Contains(vms, "Generated by Valhalla (terraform, vmware)") {
+		t.Errorf("virtual_machines.tf has no header:\n%s", vms)
+	}
+	if !strings.Contains(vms, `resource "vsphere_virtual_machine" "web_01"`) {
+		t.Errorf("the hook dropped the generated content:\n%s", vms)
+	}
+
+	files := make(map[string]string, len(results))
+	for path, result := range results {
+		files[path] = string(result.Content)
+	}
+	checkHCL(t, files)
+}
+
+func TestCommandHookIsSandboxed(t *testing.T) {
+	requireShell(t)
+	t.Setenv("VSPHERE_PASSWORD", "hunter2")
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "hook.sh")
+	body := `#!/bin/sh
+touch leaked
+echo "# dir=$(pwd) file=$VALHALLA_FILE type=$VALHALLA_FILE_TYPE hook=$VALHALLA_HOOK password=${VSPHERE_PASSWORD:-unset}"
+cat
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	generator, err := NewGenerator("terraform", testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	hooks := []Hook{{Name: "env", Files: []string{"provider.tf"}, Command: script}}
+	results, err := generator.Generate([]*models.Infrastructure{vmwareFixture()}, GenerateOptions{OutputDir: out, Hooks: hooks})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	var provider string
+	for _, result := range results {
+		if result.Path == filepath.Join(out, "provider.tf") {
+			provider = string(result.Content)
+		}
+	}
+	first := strings.SplitN(provider, "\n", 2)[0]
+	if !strings.Contains(first, "file=provider.tf type=provider hook=env") {
+		t.Errorf("hook environment: %s", first)
+	}
+	if !strings.Contains(first, "password=unset") {
+		t.Errorf("hook inherited credentials from the environment: %s", first)
+	}
+	if strings.Contains(first, "dir="+out) || strings.Contains(first, "dir="+dir+" ") {
+		t.Errorf("hook ran outside its own directory: %s", first)
+	}
+	for _, d := range []string{out, dir} {
+		if _, err := os.Stat(filepath.Join(d, "leaked")); err == nil {
+			t.Errorf("hook wrote a file into %s", d)
+		}
+	}
+}
+
+func TestCommandHookFailures(t *testing.T) {
+	requireShell(t)
+
+	tests := []struct {
+		name    string
+		args    []string
+		timeout time.Duration
+		want    string
+	}{
+		{name: "exit status", args: []string{"-c", "echo 'policy check failed' >&2; exit 3"}, want: "policy check failed"},
+		{name: "no output", args: []string{"-c", "cat >/dev/null"}, want: "no output"},
+		{name: "timeout", args: []string{"-c", "exec sleep 5"}, timeout: 100 * time.Millisecond, want: "timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks := []Hook{{Name: "check", Files: []string{"variables.tf"}, Command: "sh", Args: tt.args, Timeout: tt.timeout}}
+			_, _, err := generateWithHooks(t, hooks, false)
+			if err == nil || !strings.Contains(err.Error(), "hook check failed on variables.tf") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Generate error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHooksRunBeforeSecretScan(t *testing.T) {
+	secret := "Sup3r" + "S3cret!"
+	hooks := []Hook{{Name: "inline", Files: []string{"variables.tf"}, Template: "{{ .Content }}\nlocals {\n  vsphere_password = \"" + secret + "\"\n}\n"}}
+	if _, _, err := generateWithHooks(t, hooks, false); err == nil || !strings.Contains(err.Error(), "variables.tf") {
+		t.Errorf("Generate error = %v, want the secret scan to flag the hook's output", err)
+	}
+}
+
+func TestHooksDryRun(t *testing.T) {
+	requireShell(t)
+
+	// A dry run only records the hooks; this one would fail if run
+	hooks := []Hook{{Name: "fail", Files: []string{"*.tf"}, Command: "sh", Args: []string{"-c", "exit 1"}}}
+	results, dir, err := generateWithHooks(t, hooks, true)
+	if err != nil {
+		t.Fatalf("dry run ran the hook: %v", err)
+	}
+	for path, result := range results {
+		names, _ := result.Metadata["hooks"].([]string)
+		if want := strings.HasSuffix(path, ".tf"); want != (len(names) == 1 && names[0] == "fail") {
+			t.Errorf("%s: hooks that would run = %v", path, names)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("dry run wrote %d files", len(entries))
+	}
+}
+
+func TestCommentLines(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"main.tf", "# Owned by\n#\n# platform\n"},
+		{"__main__.py", "# Owned by\n#\n# platform\n"},
+		{"index.ts", "// Owned by\n//\n// platform\n"},
+		{"README.md", "<!--\nOwned by\n\nplatform\n-->\n"},
+		{"provenance.json", ""},
+	}
+
+	for _, tt := range tests {
+		if got := commentLines(tt.name, "Owned by\n\nplatform\n"); got != tt.want {
+			t.Errorf("commentLines(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package generators
+
+import (
+	"reflect"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestCollidingNamesGetDistinctResources(t *testing.T) {
+	fixture := func() *models.Infrastructure {
+		infra := vmwareFixture()
+		vm := infra.VirtualMachines[0]
+		infra.VirtualMachines = infra.VirtualMachines[:1]
+		for i, name := range []string{"Web.01", "web_01", "web-01"} {
+			clone := vm
+			clone.ID = "vm-2" + string(rune('0'+i))
+			clone.Name = name
+			infra.VirtualMachines = append(infra.VirtualMachines, clone)
+		}
+		return infra
+	}
+
+	// The first VM with a name keeps the plain name
+	plain := map[string]string{"terraform": "web_01", "pulumi-python": "web_01", "ansible": "web-01"}
+
+	for _, format := range []string{"terraform", "pulumi-python", "ansible"} {
+		t.Run(format, func(t *testing.T) {
+			mapping := resourceNames(t, generateResults(t, format, []*models.Infrastructure{fixture()}, GenerateOptions{}))
+			if len(mapping) != 4 {
+				t.Fatalf("resource_names = %v, want four VMs", mapping)
+			}
+
+			seen := make(map[string]string)
+			for original, name := range mapping {
+				if other, ok := seen[name]; ok {
+					t.Errorf("%q and %q both map to %q", original, other, name)
+				}
+				seen[name] = original
+			}
+			if mapping["web-01"] != plain[format] {
+				t.Errorf("the first web-01 is %q, want %s", mapping["web-01"], plain[format])
+			}
+			if _, ok := mapping["web-01 (vm-22)"]; !ok {
+				t.Errorf("the second web-01 is not keyed by its ID: %v", mapping)
+			}
+
+			again := resourceNames(t, generateResults(t, format, []*models.Infrastructure{fixture()}, GenerateOptions{}))
+			if !reflect.DeepEqual(again, mapping) {
+				t.Errorf("names differ between runs: %v and %v", mapping, again)
+			}
+		})
+	}
+
+	checkHCL(t, generate(t, "terraform", []*models.Infrastructure{fixture()}, GenerateOptions{}))
+}
+
+// resourceNames returns the original name to resource name mapping that a
+// generator recorded in its results' metadata
+func resourceNames(t *testing.T, results []*GenerateResult) map[string]string {
+	t.Helper()
+
+	for _, result := range results {
+		if mapping, ok := result.Metadata["resource_names"].(map[string]string); ok {
+			return mapping
+		}
+	}
+	t.Fatal("no result records resource_names")
+	return nil
+}
@@ -0,0 +1,125 @@
+package generators
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode/utf8"
+)
+
+// transliterations maps common non-ASCII lowercase letters to ASCII
+var transliterations = map[rune]string{
+	// Latin with diacritics
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i",
+	'î': "i", 'ï': "i", 'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o",
+	'ö': "oe", 'ø': "o", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "ue", 'ý': "y",
+	'ÿ': "y", 'ß': "ss", 'ł': "l", 'ś': "s", 'ź': "z", 'ż': "z", 'ć': "c",
+	'ń': "n", 'ę': "e", 'ą': "a", 'č': "c", 'š': "s", 'ž': "z", 'ř': "r",
+	'ě': "e", 'ů': "u", 'ğ': "g", 'ş': "s", 'ı': "i",
+
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g",
+
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o",
+}
+
+// SanitizeIdentifier converts a name into a lowercase identifier made of
+// [a-z0-9_] that starts with a letter. Non-ASCII letters are transliterated
+// where possible; if any character had to be dropped (CJK, emoji, ...) a short
+// hash of the original name is appended so distinct names stay distinct.
+func SanitizeIdentifier(name string) string {
+	id := sanitizeName(name, "")
+
+	if len(id) == 0 || id[0] < 'a' || id[0] > 'z' {
+		id = "res_" + id
+	}
+
+	return id
+}
+
+// SanitizeHostname converts a name into a lowercase hostname-style string
+// that keeps hyphens and dots, using the same transliteration and hash
+// fallback as SanitizeIdentifier
+func SanitizeHostname(name string) string {
+	host := sanitizeName(name, "-.")
+	if host == "" {
+		host = "host"
+	}
+	return host
+}
+
+// sanitizeName lowercases and transliterates a name, replacing any other
+// ASCII character not in keep with an underscore
+func sanitizeName(name, keep string) string {
+	var b strings.Builder
+	lossy := false
+
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r < utf8.RuneSelf:
+			if strings.ContainsRune(keep, r) {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte('_')
+			}
+		default:
+			if t, ok := transliterations[r]; ok {
+				b.WriteString(t)
+			} else {
+				lossy = true
+			}
+		}
+	}
+
+	result := b.String()
+	if lossy {
+		if result != "" && !strings.HasSuffix(result, "_") {
+			result += "_"
+		}
+		result += shortHash(name)
+	}
+
+	return result
+}
+
+// shortHash returns an 8 character hex hash of a string
+func shortHash(s string) string {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// EscapeHCL escapes a value for use inside a double-quoted HCL string,
+// including template sequences that Terraform would otherwise interpolate
+func EscapeHCL(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, "\r", `\r`)
+	value = strings.ReplaceAll(value, "\t", `\t`)
+	value = strings.ReplaceAll(value, "${", "$${")
+	value = strings.ReplaceAll(value, "%{", "%%{")
+	return value
+}
+
+// EscapeYAML escapes a value for use inside a double-quoted YAML scalar
+func EscapeYAML(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	value = strings.ReplaceAll(value, "\r", `\r`)
+	value = strings.ReplaceAll(value, "\t", `\t`)
+	return value
+}
@@ -0,0 +1,106 @@
+package generators
+
+import (
+	"regexp"
+	"sort"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestPreserveMACKeepsDeviceOrder(t *testing.T) {
+	// MACs deliberately sort opposite to the device keys
+	nics := []models.NetworkCard{
+		{ID: "4000", Key: 4000, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:03"},
+		{ID: "4001", Key: 4001, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:02"},
+		{ID: "4002", Key: 4002, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:01"},
+	}
+	want := []string{"00:50:56:aa:00:03", "00:50:56:aa:00:02", "00:50:56:aa:00:01"}
+	permutations := [][]int{{0, 1, 2}, {2, 1, 0}, {1, 2, 0}, {2, 0, 1}}
+	mac := regexp.MustCompile(`mac_address\s*=\s*"([^"]+)"`)
+
+	for _, order := range permutations {
+		infra := vmwareFixture()
+		infra.VirtualMachines = infra.VirtualMachines[:1]
+		infra.VirtualMachines[0].NetworkCards = []models.NetworkCard{nics[order[0]], nics[order[1]], nics[order[2]]}
+
+		files := generate(t, "terraform", []*models.Infrastructure{infra}, GenerateOptions{PreserveMAC: true})
+
+		var got []string
+		for _, match := range mac.FindAllStringSubmatch(joined(files), -1) {
+			got = append(got, match[1])
+		}
+		if len(got) != len(want) {
+			t.Fatalf("order %v: got MACs %v, want %v", order, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("order %v: got MACs %v, want %v in device key order", order, got, want)
+				break
+			}
+		}
+
+		ordered := NewBaseGenerator("test", "test", testLogger()).OrderedNetworkCards(infra.VirtualMachines[0])
+		for i, nic := range ordered {
+			if nic.Key != int32(4000+i) {
+				t.Errorf("order %v: OrderedNetworkCards put key %d at %d", order, nic.Key, i)
+			}
+		}
+	}
+}
+
+func TestShuffledInputGeneratesIdenticalOutput(t *testing.T) {
+	// Four NICs on the same network, as returned in different device list
+	// orders from one discovery to the next
+	nics := []models.NetworkCard{
+		{ID: "4000", Key: 4000, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:13", Connected: true},
+		{ID: "4001", Key: 4001, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:12", Connected: true},
+		{ID: "4002", Key: 4002, Type: "vmxnet3", Network: "VM Network", MACAddress: "00:50:56:aa:00:11", Connected: true},
+		{ID: "4003", Key: 4003, Type: "e1000e", Network: "VM Network", MACAddress: "00:50:56:aa:00:10", Connected: true},
+	}
+	permutations := [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}, {2, 0, 3, 1}}
+
+	for _, format := range []string{"terraform", "pulumi-python", "ansible"} {
+		t.Run(format, func(t *testing.T) {
+			var first map[string]string
+			for _, order := range permutations {
+				infra := vmwareFixture()
+				vm := &infra.VirtualMachines[0]
+				vm.NetworkCards = nil
+				for _, i := range order {
+					vm.NetworkCards = append(vm.NetworkCards, nics[i])
+				}
+				// The VM list comes back rotated too, and is put in order as
+				// discovery does; NICs are left in the order they came in
+				vms := infra.VirtualMachines
+				shift := order[0] % len(vms)
+				infra.VirtualMachines = append(append([]models.VirtualMachine{}, vms[shift:]...), vms[:shift]...)
+				infra.SortResources()
+
+				files := generate(t, format, []*models.Infrastructure{infra}, GenerateOptions{})
+				if first == nil {
+					first = files
+					continue
+				}
+				if len(files) != len(first) {
+					t.Fatalf("order %v: generated %v, want %v", order, fileNames(files), fileNames(first))
+				}
+				for name, content := range first {
+					if files[name] != content {
+						t.Errorf("order %v: %s differs from the first generation:\n%s\nwant\n%s", order, name, files[name], content)
+					}
+				}
+			}
+		})
+	}
+}
+
+// fileNames lists the generated file names in order
+func fileNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
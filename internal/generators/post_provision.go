@@ -0,0 +1,142 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"valhalla/internal/models"
+)
+
+// PostProvisionPrefix starts the annotation keys that hold post-clone
+// instructions, e.g. post_provision.join_ad
+const PostProvisionPrefix = "post_provision."
+
+// PostProvisionStep is one post-clone instruction taken from a VM annotation
+type PostProvisionStep struct {
+	Key   string   // annotation key
+	Lines []string // instruction text, one entry per line
+}
+
+// PostProvisionSteps returns the post_provision.* annotations of a VM in key
+// order. Line endings are normalized and control characters dropped, so each
+// line can be embedded in a comment or document as is.
+func PostProvisionSteps(vm models.VirtualMachine) []PostProvisionStep {
+	var steps []PostProvisionStep
+	for key, value := range vm.Annotations {
+		if !strings.HasPrefix(strings.ToLower(key), PostProvisionPrefix) {
+			continue
+		}
+		if lines := instructionLines(value); len(lines) > 0 {
+			steps = append(steps, PostProvisionStep{Key: key, Lines: lines})
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Key < steps[j].Key })
+	return steps
+}
+
+// instructionLines splits annotation text into lines, expanding tabs,
+// removing other control characters and dropping blank leading and trailing
+// lines
+func instructionLines(value string) []string {
+	value = strings.ReplaceAll(value, "\r\n", "\n")
+	value = strings.ReplaceAll(value, "\r", "\n")
+
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.Map(func(r rune) rune {
+			switch {
+			case r == '\t':
+				return ' '
+			case unicode.IsControl(r):
+				return -1
+			}
+			return r
+		}, line)
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// PostProvisionDoc collects the post-provision steps of every VM into
+// POST_PROVISION.md, or returns nil when no VM has any
+func PostProvisionDoc(vms []models.VirtualMachine, provider string) *GenerateResult {
+	var sections []string
+	var resources []string
+	for _, vm := range vms {
+		steps := PostProvisionSteps(vm)
+		if vm.Config.Template || len(steps) == 0 {
+			continue
+		}
+
+		section := fmt.Sprintf("## %s\n", vm.Name)
+		for _, step := range steps {
+			text := strings.Join(step.Lines, "\n")
+			fence := markdownFence(text)
+			section += fmt.Sprintf("\n### %s\n\n%s\n%s\n%s\n", step.Key, fence, text, fence)
+		}
+		sections = append(sections, section)
+		resources = append(resources, vm.Name)
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+
+	content := `# Post-Provision Steps
+
+Instructions found in ` + "`post_provision.*`" + ` VM annotations. Nothing here is
+run automatically: generated code only carries these steps as commented-out
+blocks to review, turn into real commands and enable by hand.
+
+` + strings.Join(sections, "\n")
+
+	return &GenerateResult{
+		Path:      "POST_PROVISION.md",
+		Content:   []byte(content),
+		Size:      len(content),
+		Type:      "docs",
+		Provider:  provider,
+		Resources: resources,
+	}
+}
+
+// markdownFence returns a tilde fence longer than any tilde run in text, so
+// the text can't close the code block early
+func markdownFence(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '~' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "~~~"
+	}
+	return strings.Repeat("~", longest+1)
+}
+
+// postProvisionComment renders a VM's steps as comment lines, each step key
+// followed by its indented text
+func postProvisionComment(steps []PostProvisionStep, prefix string) string {
+	var comment string
+	for _, step := range steps {
+		comment += fmt.Sprintf("%s%s:\n", prefix, step.Key)
+		for _, line := range step.Lines {
+			comment += strings.TrimRight(fmt.Sprintf("%s  %s", prefix, line), " ") + "\n"
+		}
+	}
+	return comment
+}
@@ -0,0 +1,64 @@
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// ProvenanceFile is written next to generated code to trace it back to the
+// discovery runs it was generated from
+const ProvenanceFile = "provenance.json"
+
+// Source identifies one discovery run that generated code was built from,
+// with the provenance discovery recorded, if any
+type Source struct {
+	Provider      string                 `json:"provider"`
+	Server        string                 `json:"server"`
+	DiscoveryTime time.Time              `json:"discovery_time"`
+	Provenance    map[string]interface{} `json:"provenance,omitempty"`
+}
+
+// Sources lists the discovery runs behind infrastructures
+func Sources(infrastructures []*models.Infrastructure) []Source {
+	sources := make([]Source, 0, len(infrastructures))
+	for _, infra := range infrastructures {
+		provenance, _ := infra.Metadata["provenance"].(map[string]interface{})
+		sources = append(sources, Source{
+			Provider:      infra.Provider,
+			Server:        infra.Server,
+			DiscoveryTime: infra.DiscoveryTime,
+			Provenance:    provenance,
+		})
+	}
+	return sources
+}
+
+// ProvenanceRecord ties a generation run to its sources
+type ProvenanceRecord struct {
+	GeneratedAt     time.Time `json:"generated_at"`
+	ValhallaVersion string    `json:"valhalla_version,omitempty"`
+	Format          string    `json:"format"`
+	Sources         []Source  `json:"sources"`
+}
+
+// WriteProvenance saves a provenance record as JSON
+func WriteProvenance(record *ProvenanceRecord, filename string) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create provenance directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	return nil
+}
@@ -2,8 +2,10 @@ package generators
 
 import (
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"valhalla/internal/logger"
@@ -31,7 +33,7 @@ func (g *PulumiGenerator) Generate(infrastructures []*models.Infrastructure, opt
 	var results []*GenerateResult
 
 	// Generate Pulumi.yaml
-	pulumiYaml := g.generatePulumiYaml()
+	pulumiYaml := g.generatePulumiYaml(infrastructures)
 	results = append(results, &GenerateResult{
 		Path:      "Pulumi.yaml",
 		Content:   []byte(pulumiYaml),
@@ -41,6 +43,9 @@ func (g *PulumiGenerator) Generate(infrastructures []*models.Infrastructure, opt
 		Resources: []string{},
 	})
 
+	// Generate Pulumi.<stack>.yaml with discovered configuration
+	results = append(results, g.generateStackConfig(infrastructures, opts))
+
 	// Generate language-specific files
 	for _, infra := range infrastructures {
 		providerResults, err := g.generateForProvider(infra, opts)
@@ -56,6 +61,19 @@ func (g *PulumiGenerator) Generate(infrastructures []*models.Infrastructure, opt
 		results = append(results, packageFile)
 	}
 
+	// TypeScript projects also need a compiler configuration
+	if g.language == "typescript" {
+		results = append(results, g.generateTSConfig())
+	}
+
+	if err := g.RunHooks(results, opts); err != nil {
+		return nil, err
+	}
+
+	if err := g.CheckSecrets(results, opts); err != nil {
+		return nil, err
+	}
+
 	// Write files if not dry run
 	if !opts.DryRun {
 		for _, result := range results {
@@ -68,31 +86,115 @@ func (g *PulumiGenerator) Generate(infrastructures []*models.Infrastructure, opt
 	return results, nil
 }
 
-// generatePulumiYaml generates the Pulumi.yaml project file
-func (g *PulumiGenerator) generatePulumiYaml() string {
+// generatePulumiYaml generates the Pulumi.yaml project file. Only keys in
+// the project namespace are declared; Pulumi rejects schema for provider
+// keys, so those are documented and set in the stack file instead.
+func (g *PulumiGenerator) generatePulumiYaml(infrastructures []*models.Infrastructure) string {
 	runtime := g.language
-	if g.language == "typescript" {
+	switch g.language {
+	case "typescript":
 		runtime = "nodejs"
+	case "csharp":
+		runtime = "dotnet"
 	}
 
-	return fmt.Sprintf(`name: valhalla-infrastructure
+	project := fmt.Sprintf(`name: %s
 runtime: %s
 description: Infrastructure discovered and generated by Valhalla
 
+# Provider configuration is set per stack:
+#   vsphere:server              vSphere server address
+#   vsphere:user                vSphere username
+#   vsphere:password            vSphere password (secret)
+#   vsphere:allowUnverifiedSsl  Allow unverified SSL certificates
 config:
-  # Provider-specific configuration
-  vsphere:server:
-    description: vSphere server address
-  vsphere:user:
-    description: vSphere username
-    secret: true
-  vsphere:password:
-    description: vSphere password
-    secret: true
-  vsphere:allowUnverifiedSsl:
-    description: Allow unverified SSL certificates
-    default: true
-`, runtime)
+  datacenter:
+    type: string
+    description: vSphere datacenter
+  cluster:
+    type: string
+    description: vSphere compute cluster
+`, pulumiProject, runtime)
+
+	for _, infra := range infrastructures {
+		if isVMware(infra) && hasDisklessVMs(infra.VirtualMachines) {
+			project += `  default_datastore:
+    type: string
+    description: Datastore for VMs without discovered disks
+`
+			break
+		}
+	}
+
+	return project
+}
+
+// isVMware reports whether an infrastructure was discovered from vSphere
+func isVMware(infra *models.Infrastructure) bool {
+	switch strings.ToLower(infra.Provider) {
+	case "vmware", "vsphere":
+		return true
+	}
+	return false
+}
+
+// pulumiProject is the project name written to Pulumi.yaml; stack config
+// keys without a provider namespace are scoped to it
+const pulumiProject = "valhalla-infrastructure"
+
+// generateStackConfig generates Pulumi.<stack>.yaml pre-populated with the
+// discovered server, datacenter and cluster. Credentials are left as
+// placeholders to be set with `pulumi config set --secret`.
+func (g *PulumiGenerator) generateStackConfig(infrastructures []*models.Infrastructure, opts GenerateOptions) *GenerateResult {
+	stack := opts.Stack
+	if stack == "" {
+		stack = "dev"
+	}
+	stack = SanitizeHostname(stack)
+
+	var sb strings.Builder
+	sb.WriteString("# Stack configuration generated by Valhalla from discovered infrastructure\n")
+	sb.WriteString("config:\n")
+
+	var vmware *models.Infrastructure
+	for _, infra := range infrastructures {
+		switch strings.ToLower(infra.Provider) {
+		case "vmware", "vsphere":
+			if vmware == nil {
+				vmware = infra
+			} else if infra.Server != vmware.Server {
+				g.Log().Warn("Multiple vSphere servers discovered, stack config uses the first",
+					"used", vmware.Server, "ignored", infra.Server)
+			}
+		}
+	}
+
+	if vmware != nil {
+		sb.WriteString(fmt.Sprintf("  vsphere:server: \"%s\"\n", EscapeYAML(vmware.Server)))
+		sb.WriteString("  vsphere:allowUnverifiedSsl: \"true\"\n")
+		if vmware.Datacenter != "" {
+			sb.WriteString(fmt.Sprintf("  %s:datacenter: \"%s\"\n", pulumiProject, EscapeYAML(vmware.Datacenter)))
+		}
+		if vmware.Cluster != "" {
+			sb.WriteString(fmt.Sprintf("  %s:cluster: \"%s\"\n", pulumiProject, EscapeYAML(vmware.Cluster)))
+		}
+		if hasDisklessVMs(vmware.VirtualMachines) && len(vmware.Storage) > 0 {
+			sb.WriteString(fmt.Sprintf("  %s:default_datastore: \"%s\"\n", pulumiProject, EscapeYAML(vmware.Storage[0].Name)))
+		}
+		sb.WriteString("  # Credentials are not discovered; set them as secrets before `pulumi up`:\n")
+		sb.WriteString(fmt.Sprintf("  #   pulumi config set --stack %s vsphere:user <username>\n", stack))
+		sb.WriteString(fmt.Sprintf("  #   pulumi config set --stack %s --secret vsphere:password <password>\n", stack))
+	}
+
+	content := sb.String()
+	return &GenerateResult{
+		Path:      fmt.Sprintf("Pulumi.%s.yaml", stack),
+		Content:   []byte(content),
+		Size:      len(content),
+		Type:      "config",
+		Provider:  "pulumi",
+		Resources: []string{},
+	}
 }
 
 // generatePackageFile generates language-specific package files
@@ -180,6 +282,38 @@ require (
 	return nil
 }
 
+// generateTSConfig generates the tsconfig.json Pulumi's Node.js runtime
+// compiles index.ts with
+func (g *PulumiGenerator) generateTSConfig() *GenerateResult {
+	tsconfig := `{
+  "compilerOptions": {
+    "strict": true,
+    "outDir": "bin",
+    "target": "es2020",
+    "module": "commonjs",
+    "moduleResolution": "node",
+    "sourceMap": true,
+    "experimentalDecorators": true,
+    "pretty": true,
+    "noFallthroughCasesInSwitch": true,
+    "noImplicitReturns": true,
+    "forceConsistentCasingInFileNames": true
+  },
+  "files": [
+    "index.ts"
+  ]
+}
+`
+	return &GenerateResult{
+		Path:      "tsconfig.json",
+		Content:   []byte(tsconfig),
+		Size:      len(tsconfig),
+		Type:      "config",
+		Provider:  "pulumi",
+		Resources: []string{},
+	}
+}
+
 // generateForProvider generates Pulumi code for a specific provider
 func (g *PulumiGenerator) generateForProvider(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
 	switch strings.ToLower(infra.Provider) {
@@ -199,35 +333,49 @@ func (g *PulumiGenerator) generateVMware(infra *models.Infrastructure, opts Gene
 	var content string
 	var filename string
 
+	commentPrefix := "//"
+	if g.language == "python" {
+		commentPrefix = "#"
+	}
+	program := g.vmwareProgram(infra, opts, commentPrefix)
+
 	switch g.language {
 	case "python":
-		content = g.generateVMwarePython(infra)
+		content = g.generateVMwarePython(program)
 		filename = "__main__.py"
 	case "typescript":
-		content = g.generateVMwareTypeScript(infra)
+		content = g.generateVMwareTypeScript(program)
 		filename = "index.ts"
 	case "go":
-		content = g.generateVMwareGo(infra)
+		content = g.generateVMwareGo(program)
 		filename = "main.go"
 	case "csharp":
-		content = g.generateVMwareCSharp(infra)
+		content = g.generateVMwareCSharp(program)
 		filename = "Program.cs"
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", g.language)
 	}
 
-	return []*GenerateResult{{
+	results := []*GenerateResult{{
 		Path:      filename,
 		Content:   []byte(content),
 		Size:      len(content),
 		Type:      "main",
 		Provider:  "vmware",
 		Resources: []string{"vsphere_virtual_machine"},
-	}}, nil
+		Metadata:  map[string]interface{}{"resource_names": program.names.Mapping},
+	}}
+
+	for _, note := range program.Notes {
+		note.Provider = "vmware"
+		results = append(results, note)
+	}
+
+	return results, nil
 }
 
 // generateVMwarePython generates Python Pulumi code
-func (g *PulumiGenerator) generateVMwarePython(infra *models.Infrastructure) string {
+func (g *PulumiGenerator) generateVMwarePython(program *pulumiProgram) string {
 	code := `import pulumi
 import pulumi_vsphere as vsphere
 
@@ -245,116 +393,104 @@ cluster = vsphere.get_compute_cluster(
 
 `
 
-	// Generate data sources for networks and datastores
-	networks := make(map[string]bool)
-	datastores := make(map[string]bool)
-
-	for _, vm := range infra.VirtualMachines {
-		for _, nic := range vm.NetworkCards {
-			if nic.Network != "" {
-				networks[nic.Network] = true
-			}
-		}
-		for _, disk := range vm.Disks {
-			if disk.Datastore != "" {
-				datastores[disk.Datastore] = true
-			}
-		}
-	}
-
-	for network := range networks {
-		resourceName := g.GenerateResourceName(network)
+	for _, network := range program.Networks {
 		code += fmt.Sprintf(`# Get network: %s
 %s = vsphere.get_network(
     name="%s",
     datacenter_id=datacenter.id
 )
 
-`, network, resourceName, network)
+`, network.Name, network.Resource, network.Name)
 	}
 
-	for datastore := range datastores {
-		resourceName := g.GenerateResourceName(datastore)
+	for _, datastore := range program.Datastores {
 		code += fmt.Sprintf(`# Get datastore: %s
 %s = vsphere.get_datastore(
-    name="%s",
+    name=%s,
     datacenter_id=datacenter.id
 )
 
-`, datastore, resourceName, datastore)
+`, datastore.Name, datastore.Resource, datastore.nameExpr("config.require"))
 	}
 
 	// Generate VMs
 	code += "# Virtual Machines\n"
-	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
-			continue
-		}
-
-		resourceName := g.GenerateResourceName(vm.Name)
-		datastoreResourceName := g.GenerateResourceName(vm.Disks[0].Datastore)
-		
-		code += fmt.Sprintf(`%s = vsphere.VirtualMachine("%s",
+	for _, vm := range program.VMs {
+		code += vm.Comments + fmt.Sprintf(`%s = vsphere.VirtualMachine("%s",
     name="%s",
     resource_pool_id=cluster.resource_pool_id,
     datastore_id=%s.id,
     num_cpus=%d,
     memory=%d,
     guest_id="%s",
-    network_interfaces=[`,
-			resourceName, resourceName, vm.Name, datastoreResourceName,
-			vm.CPUs, vm.Memory, vm.Config.GuestID)
-
-		// Add network interfaces
-		for i, nic := range vm.NetworkCards {
-			networkResourceName := g.GenerateResourceName(nic.Network)
-			if i > 0 {
-				code += ","
+`, vm.Resource, vm.Resource, vm.Name, vm.Datastore, vm.CPUs, vm.Memory, vm.GuestID)
+		if vm.Firmware != "" {
+			code += fmt.Sprintf("    firmware=\"%s\",\n", vm.Firmware)
+		}
+		if vm.HardwareVersion > 0 {
+			if vm.UpgradedFrom != "" {
+				code += fmt.Sprintf("    # Upgraded from %s by --upgrade-hw-version\n", vm.UpgradedFrom)
 			}
-			code += fmt.Sprintf(`
-        vsphere.VirtualMachineNetworkInterfaceArgs(
-            network_id=%s.id,
-            adapter_type="%s"
-        )`, networkResourceName, nic.Type)
+			code += fmt.Sprintf("    hardware_version=%d,\n", vm.HardwareVersion)
+		}
+		if len(vm.ExtraConfig) > 0 {
+			code += "    extra_config={\n"
+			for _, setting := range vm.ExtraConfig {
+				code += fmt.Sprintf("        \"%s\": \"%s\",\n", setting[0], setting[1])
+			}
+			code += "    },\n"
 		}
 
-		code += "\n    ],\n    disks=["
-
-		// Add disks
-		for i, disk := range vm.Disks {
-			datastoreResourceName := g.GenerateResourceName(disk.Datastore)
-			if i > 0 {
-				code += ","
+		code += "    network_interfaces=[\n"
+		for _, nic := range vm.NICs {
+			code += fmt.Sprintf("        vsphere.VirtualMachineNetworkInterfaceArgs(\n            network_id=%s.id,\n", nic.Network)
+			if nic.AdapterType != "" {
+				code += fmt.Sprintf("            adapter_type=\"%s\",\n", nic.AdapterType)
 			}
-			code += fmt.Sprintf(`
-        vsphere.VirtualMachineDiskArgs(
-            label="disk%d",
+			if nic.MACAddress != "" {
+				code += fmt.Sprintf("            use_static_mac=True,\n            mac_address=\"%s\",\n", nic.MACAddress)
+			}
+			code += "        ),\n"
+		}
+
+		code += "    ],\n    disks=[\n"
+		for _, omitted := range vm.OmittedDisks {
+			code += fmt.Sprintf("        # WARNING: %s\n", omitted)
+		}
+		for _, disk := range vm.Disks {
+			code += fmt.Sprintf(`        vsphere.VirtualMachineDiskArgs(
+            label="%s",
             size=%d,
-            thin_provisioned=%t,
-            datastore_id=%s.id
-        )`, i, disk.Size, strings.Contains(disk.Type, "thin"), datastoreResourceName)
+            thin_provisioned=%s,
+            datastore_id=%s.id,
+        ),
+`, disk.Label, disk.Size, pythonBool(disk.Thin), disk.Datastore)
 		}
 
-		code += "\n    ]\n)\n\n"
+		code += "    ],\n)\n\n"
 	}
 
 	// Add exports
 	code += "# Exports\n"
-	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
-			continue
-		}
-		resourceName := g.GenerateResourceName(vm.Name)
+	for _, vm := range program.VMs {
 		code += fmt.Sprintf(`pulumi.export("%s_id", %s.id)
 pulumi.export("%s_ip", %s.default_ip_address)
-`, vm.Name, resourceName, vm.Name, resourceName)
+`, vm.Name, vm.Resource, vm.Name, vm.Resource)
 	}
 
 	return code
 }
 
+// pythonBool formats a boolean as a Python literal
+func pythonBool(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
 // generateVMwareTypeScript generates TypeScript Pulumi code
-func (g *PulumiGenerator) generateVMwareTypeScript(infra *models.Infrastructure) string {
+func (g *PulumiGenerator) generateVMwareTypeScript(program *pulumiProgram) string {
 	code := `import * as pulumi from "@pulumi/pulumi";
 import * as vsphere from "@pulumi/vsphere";
 
@@ -374,150 +510,394 @@ const cluster = vsphere.getComputeCluster({
 
 `
 
-	// Generate data sources for networks and datastores
-	networks := make(map[string]bool)
-	datastores := make(map[string]bool)
-
-	for _, vm := range infra.VirtualMachines {
-		for _, nic := range vm.NetworkCards {
-			if nic.Network != "" {
-				networks[nic.Network] = true
-			}
-		}
-		for _, disk := range vm.Disks {
-			if disk.Datastore != "" {
-				datastores[disk.Datastore] = true
-			}
-		}
-	}
-
-	for network := range networks {
-		resourceName := g.GenerateResourceName(network)
+	for _, network := range program.Networks {
 		code += fmt.Sprintf(`// Get network: %s
 const %s = vsphere.getNetwork({
     name: "%s",
     datacenterId: datacenter.then(dc => dc.id)
 });
 
-`, network, resourceName, network)
+`, network.Name, network.Resource, network.Name)
 	}
 
-	for datastore := range datastores {
-		resourceName := g.GenerateResourceName(datastore)
+	for _, datastore := range program.Datastores {
 		code += fmt.Sprintf(`// Get datastore: %s
 const %s = vsphere.getDatastore({
-    name: "%s",
+    name: %s,
     datacenterId: datacenter.then(dc => dc.id)
 });
 
-`, datastore, resourceName, datastore)
+`, datastore.Name, datastore.Resource, datastore.nameExpr("config.require"))
 	}
 
 	// Generate VMs
 	code += "// Virtual Machines\n"
-	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
-			continue
-		}
-
-		resourceName := g.GenerateResourceName(vm.Name)
-		datastoreResourceName := g.GenerateResourceName(vm.Disks[0].Datastore)
-		
-		code += fmt.Sprintf(`const %s = new vsphere.VirtualMachine("%s", {
+	for _, vm := range program.VMs {
+		code += vm.Comments + fmt.Sprintf(`const %s = new vsphere.VirtualMachine("%s", {
     name: "%s",
     resourcePoolId: cluster.then(c => c.resourcePoolId),
     datastoreId: %s.then(ds => ds.id),
     numCpus: %d,
     memory: %d,
     guestId: "%s",
-    networkInterfaces: [`,
-			resourceName, resourceName, vm.Name, datastoreResourceName,
-			vm.CPUs, vm.Memory, vm.Config.GuestID)
-
-		// Add network interfaces
-		for i, nic := range vm.NetworkCards {
-			networkResourceName := g.GenerateResourceName(nic.Network)
-			if i > 0 {
-				code += ","
+`, vm.Resource, vm.Resource, vm.Name, vm.Datastore, vm.CPUs, vm.Memory, vm.GuestID)
+		if vm.Firmware != "" {
+			code += fmt.Sprintf("    firmware: \"%s\",\n", vm.Firmware)
+		}
+		if vm.HardwareVersion > 0 {
+			if vm.UpgradedFrom != "" {
+				code += fmt.Sprintf("    // Upgraded from %s by --upgrade-hw-version\n", vm.UpgradedFrom)
 			}
-			code += fmt.Sprintf(`
-        {
-            networkId: %s.then(net => net.id),
-            adapterType: "%s"
-        }`, networkResourceName, nic.Type)
+			code += fmt.Sprintf("    hardwareVersion: %d,\n", vm.HardwareVersion)
+		}
+		if len(vm.ExtraConfig) > 0 {
+			code += "    extraConfig: {\n"
+			for _, setting := range vm.ExtraConfig {
+				code += fmt.Sprintf("        \"%s\": \"%s\",\n", setting[0], setting[1])
+			}
+			code += "    },\n"
 		}
 
-		code += "\n    ],\n    disks: ["
-
-		// Add disks
-		for i, disk := range vm.Disks {
-			datastoreResourceName := g.GenerateResourceName(disk.Datastore)
-			if i > 0 {
-				code += ","
+		code += "    networkInterfaces: [\n"
+		for _, nic := range vm.NICs {
+			code += fmt.Sprintf("        {\n            networkId: %s.then(net => net.id),\n", nic.Network)
+			if nic.AdapterType != "" {
+				code += fmt.Sprintf("            adapterType: \"%s\",\n", nic.AdapterType)
+			}
+			if nic.MACAddress != "" {
+				code += fmt.Sprintf("            useStaticMac: true,\n            macAddress: \"%s\",\n", nic.MACAddress)
 			}
-			code += fmt.Sprintf(`
-        {
-            label: "disk%d",
+			code += "        },\n"
+		}
+
+		code += "    ],\n    disks: [\n"
+		for _, omitted := range vm.OmittedDisks {
+			code += fmt.Sprintf("        // WARNING: %s\n", omitted)
+		}
+		for _, disk := range vm.Disks {
+			code += fmt.Sprintf(`        {
+            label: "%s",
             size: %d,
             thinProvisioned: %t,
-            datastoreId: %s.then(ds => ds.id)
-        }`, i, disk.Size, strings.Contains(disk.Type, "thin"), datastoreResourceName)
+            datastoreId: %s.then(ds => ds.id),
+        },
+`, disk.Label, disk.Size, disk.Thin, disk.Datastore)
 		}
 
-		code += "\n    ]\n});\n\n"
+		code += "    ],\n});\n\n"
 	}
 
 	// Add exports
 	code += "// Exports\n"
-	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
-			continue
-		}
-		resourceName := g.GenerateResourceName(vm.Name)
+	for _, vm := range program.VMs {
 		code += fmt.Sprintf(`export const %s_id = %s.id;
 export const %s_ip = %s.defaultIpAddress;
-`, strings.ReplaceAll(vm.Name, "-", "_"), resourceName, strings.ReplaceAll(vm.Name, "-", "_"), resourceName)
+`, vm.Resource, vm.Resource, vm.Resource, vm.Resource)
 	}
 
 	return code
 }
 
-// generateVMwareGo generates Go Pulumi code
-func (g *PulumiGenerator) generateVMwareGo(infra *models.Infrastructure) string {
-	// TODO: Implement Go code generation
-	return `package main
+// sortedNames returns the keys of a name set in order
+func sortedNames(names map[string]bool) []string {
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// generateVMwareGo generates Go Pulumi code. Lookups are plain invokes, so
+// their results are used directly rather than as outputs.
+func (g *PulumiGenerator) generateVMwareGo(program *pulumiProgram) string {
+	code := `package main
 
 import (
+	"github.com/pulumi/pulumi-vsphere/sdk/v4/go/vsphere"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
 func main() {
 	pulumi.Run(func(ctx *pulumi.Context) error {
-		// TODO: Implement VMware infrastructure in Go
-		return nil
-	})
-}
+		// Get configuration
+		cfg := config.New(ctx, "")
+		datacenterName := cfg.Require("datacenter")
+
+		// Get datacenter
+		datacenter, err := vsphere.LookupDatacenter(ctx, &vsphere.LookupDatacenterArgs{
+			Name: &datacenterName,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Get compute cluster
+		cluster, err := vsphere.LookupComputeCluster(ctx, &vsphere.LookupComputeClusterArgs{
+			Name:         cfg.Require("cluster"),
+			DatacenterId: &datacenter.Id,
+		})
+		if err != nil {
+			return err
+		}
+
 `
+
+	for _, network := range program.Networks {
+		code += fmt.Sprintf(`		// Get network: %s
+		%s, err := vsphere.GetNetwork(ctx, &vsphere.GetNetworkArgs{
+			Name:         "%s",
+			DatacenterId: &datacenter.Id,
+		})
+		if err != nil {
+			return err
+		}
+
+`, network.Name, network.Resource, network.Name)
+	}
+
+	for _, datastore := range program.Datastores {
+		code += fmt.Sprintf(`		// Get datastore: %s
+		%s, err := vsphere.GetDatastore(ctx, &vsphere.GetDatastoreArgs{
+			Name:         %s,
+			DatacenterId: &datacenter.Id,
+		})
+		if err != nil {
+			return err
+		}
+
+`, datastore.Name, datastore.Resource, datastore.nameExpr("cfg.Require"))
+	}
+
+	// The cluster lookup must be used for the program to compile
+	if len(program.VMs) == 0 {
+		code += "\t\t_ = cluster\n\n"
+	}
+
+	// Generate VMs
+	code += "\t\t// Virtual Machines\n"
+	for _, vm := range program.VMs {
+		code += indentLines(vm.Comments, "\t\t") + fmt.Sprintf(`		%s, err := vsphere.NewVirtualMachine(ctx, "%s", &vsphere.VirtualMachineArgs{
+			Name:           pulumi.String("%s"),
+			ResourcePoolId: pulumi.String(cluster.ResourcePoolId),
+			DatastoreId:    pulumi.String(%s.Id),
+			NumCpus:        pulumi.Int(%d),
+			Memory:         pulumi.Int(%d),
+			GuestId:        pulumi.String("%s"),
+`, vm.Resource, vm.Resource, vm.Name, vm.Datastore, vm.CPUs, vm.Memory, vm.GuestID)
+		if vm.Firmware != "" {
+			code += fmt.Sprintf("\t\t\tFirmware:       pulumi.String(\"%s\"),\n", vm.Firmware)
+		}
+		if vm.HardwareVersion > 0 {
+			if vm.UpgradedFrom != "" {
+				code += fmt.Sprintf("\t\t\t// Upgraded from %s by --upgrade-hw-version\n", vm.UpgradedFrom)
+			}
+			code += fmt.Sprintf("\t\t\tHardwareVersion: pulumi.Int(%d),\n", vm.HardwareVersion)
+		}
+		if len(vm.ExtraConfig) > 0 {
+			code += "\t\t\tExtraConfig: pulumi.StringMap{\n"
+			for _, setting := range vm.ExtraConfig {
+				code += fmt.Sprintf("\t\t\t\t\"%s\": pulumi.String(\"%s\"),\n", setting[0], setting[1])
+			}
+			code += "\t\t\t},\n"
+		}
+
+		code += "\t\t\tNetworkInterfaces: vsphere.VirtualMachineNetworkInterfaceArray{\n"
+		for _, nic := range vm.NICs {
+			code += fmt.Sprintf("\t\t\t\t&vsphere.VirtualMachineNetworkInterfaceArgs{\n\t\t\t\t\tNetworkId: pulumi.String(%s.Id),\n", nic.Network)
+			if nic.AdapterType != "" {
+				code += fmt.Sprintf("\t\t\t\t\tAdapterType: pulumi.String(\"%s\"),\n", nic.AdapterType)
+			}
+			if nic.MACAddress != "" {
+				code += fmt.Sprintf("\t\t\t\t\tUseStaticMac: pulumi.Bool(true),\n\t\t\t\t\tMacAddress: pulumi.String(\"%s\"),\n", nic.MACAddress)
+			}
+			code += "\t\t\t\t},\n"
+		}
+
+		code += "\t\t\t},\n\t\t\tDisks: vsphere.VirtualMachineDiskArray{\n"
+		for _, omitted := range vm.OmittedDisks {
+			code += fmt.Sprintf("\t\t\t\t// WARNING: %s\n", omitted)
+		}
+		for _, disk := range vm.Disks {
+			code += fmt.Sprintf(`				&vsphere.VirtualMachineDiskArgs{
+					Label:           pulumi.String("%s"),
+					Size:            pulumi.Int(%d),
+					ThinProvisioned: pulumi.Bool(%t),
+					DatastoreId:     pulumi.String(%s.Id),
+				},
+`, disk.Label, disk.Size, disk.Thin, disk.Datastore)
+		}
+
+		code += "\t\t\t},\n\t\t})\n\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n\n"
+	}
+
+	// Add exports
+	code += "\t\t// Exports\n"
+	for _, vm := range program.VMs {
+		code += fmt.Sprintf("\t\tctx.Export(\"%s_id\", %s.ID())\n\t\tctx.Export(\"%s_ip\", %s.DefaultIpAddress)\n",
+			vm.Resource, vm.Resource, vm.Resource, vm.Resource)
+	}
+
+	code += "\n\t\treturn nil\n\t})\n}\n"
+
+	// Align fields the way gofmt would; unformatted code still compiles
+	formatted, err := format.Source([]byte(code))
+	if err != nil {
+		g.Log().Warn("Failed to format generated Go program", "error", err)
+		return code
+	}
+	return string(formatted)
 }
 
-// generateVMwareCSharp generates C# Pulumi code
-func (g *PulumiGenerator) generateVMwareCSharp(infra *models.Infrastructure) string {
-	// TODO: Implement C# code generation
-	return `using System.Collections.Generic;
+// generateVMwareCSharp generates C# Pulumi code as a top-level program
+func (g *PulumiGenerator) generateVMwareCSharp(program *pulumiProgram) string {
+	code := `using System.Collections.Generic;
 using Pulumi;
 using VSphere = Pulumi.VSphere;
 
 return await Deployment.RunAsync(() =>
 {
-    // TODO: Implement VMware infrastructure in C#
-});
+    // Get configuration
+    var config = new Config();
+
+    // Get datacenter
+    var datacenter = VSphere.GetDatacenter.Invoke(new()
+    {
+        Name = config.Require("datacenter"),
+    });
+
+    // Get compute cluster
+    var cluster = VSphere.GetComputeCluster.Invoke(new()
+    {
+        Name = config.Require("cluster"),
+        DatacenterId = datacenter.Apply(dc => dc.Id),
+    });
+
 `
+
+	for _, network := range program.Networks {
+		code += fmt.Sprintf(`    // Get network: %s
+    var %s = VSphere.GetNetwork.Invoke(new()
+    {
+        Name = "%s",
+        DatacenterId = datacenter.Apply(dc => dc.Id),
+    });
+
+`, network.Name, network.Resource, network.Name)
+	}
+
+	for _, datastore := range program.Datastores {
+		code += fmt.Sprintf(`    // Get datastore: %s
+    var %s = VSphere.GetDatastore.Invoke(new()
+    {
+        Name = %s,
+        DatacenterId = datacenter.Apply(dc => dc.Id),
+    });
+
+`, datastore.Name, datastore.Resource, datastore.nameExpr("config.Require"))
+	}
+
+	// Generate VMs
+	code += "    // Virtual Machines\n"
+	for _, vm := range program.VMs {
+		code += indentLines(vm.Comments, "    ") + fmt.Sprintf(`    var %s = new VSphere.VirtualMachine("%s", new()
+    {
+        Name = "%s",
+        ResourcePoolId = cluster.Apply(c => c.ResourcePoolId),
+        DatastoreId = %s.Apply(ds => ds.Id),
+        NumCpus = %d,
+        Memory = %d,
+        GuestId = "%s",
+`, vm.Resource, vm.Resource, vm.Name, vm.Datastore, vm.CPUs, vm.Memory, vm.GuestID)
+		if vm.Firmware != "" {
+			code += fmt.Sprintf("        Firmware = \"%s\",\n", vm.Firmware)
+		}
+		if vm.HardwareVersion > 0 {
+			if vm.UpgradedFrom != "" {
+				code += fmt.Sprintf("        // Upgraded from %s by --upgrade-hw-version\n", vm.UpgradedFrom)
+			}
+			code += fmt.Sprintf("        HardwareVersion = %d,\n", vm.HardwareVersion)
+		}
+		if len(vm.ExtraConfig) > 0 {
+			code += "        ExtraConfig =\n        {\n"
+			for _, setting := range vm.ExtraConfig {
+				code += fmt.Sprintf("            { \"%s\", \"%s\" },\n", setting[0], setting[1])
+			}
+			code += "        },\n"
+		}
+
+		// An empty new[] has no element type to infer, so leave out empty lists
+		if len(vm.NICs) > 0 {
+			code += "        NetworkInterfaces = new[]\n        {\n"
+		}
+		for _, nic := range vm.NICs {
+			code += fmt.Sprintf("            new VSphere.Inputs.VirtualMachineNetworkInterfaceArgs\n            {\n                NetworkId = %s.Apply(net => net.Id),\n", nic.Network)
+			if nic.AdapterType != "" {
+				code += fmt.Sprintf("                AdapterType = \"%s\",\n", nic.AdapterType)
+			}
+			if nic.MACAddress != "" {
+				code += fmt.Sprintf("                UseStaticMac = true,\n                MacAddress = \"%s\",\n", nic.MACAddress)
+			}
+			code += "            },\n"
+		}
+		if len(vm.NICs) > 0 {
+			code += "        },\n"
+		}
+
+		for _, omitted := range vm.OmittedDisks {
+			code += fmt.Sprintf("        // WARNING: %s\n", omitted)
+		}
+		if len(vm.Disks) > 0 {
+			code += "        Disks = new[]\n        {\n"
+		}
+		for _, disk := range vm.Disks {
+			code += fmt.Sprintf(`            new VSphere.Inputs.VirtualMachineDiskArgs
+            {
+                Label = "%s",
+                Size = %d,
+                ThinProvisioned = %t,
+                DatastoreId = %s.Apply(ds => ds.Id),
+            },
+`, disk.Label, disk.Size, disk.Thin, disk.Datastore)
+		}
+		if len(vm.Disks) > 0 {
+			code += "        },\n"
+		}
+
+		code += "    });\n\n"
+	}
+
+	// Add exports
+	code += "    // Exports\n    return new Dictionary<string, object?>\n    {\n"
+	for _, vm := range program.VMs {
+		code += fmt.Sprintf("        [\"%s_id\"] = %s.Id,\n        [\"%s_ip\"] = %s.DefaultIpAddress,\n",
+			vm.Resource, vm.Resource, vm.Resource, vm.Resource)
+	}
+	code += "    };\n});\n"
+
+	return code
+}
+
+// indentLines indents every line of a block of text
+func indentLines(text, indent string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = indent + line
+		}
+	}
+	return strings.Join(lines, "")
 }
 
 // generateProxmox generates Pulumi code for Proxmox infrastructure
 func (g *PulumiGenerator) generateProxmox(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
 	// TODO: Implement Proxmox Pulumi generation
-	g.Log().Info("Proxmox Pulumi generation not yet implemented")
+	vms, containers := countGuests(infra.VirtualMachines)
+	g.Log().Info("Proxmox Pulumi generation not yet implemented", "vms", vms, "containers", containers)
 	return []*GenerateResult{}, nil
 }
 
@@ -531,12 +911,12 @@ func (g *PulumiGenerator) generateNutanix(infra *models.Infrastructure, opts Gen
 // writeFile writes a generate result to a file
 func (g *PulumiGenerator) writeFile(result *GenerateResult, outputDir string) error {
 	// Ensure output directory exists
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+	filePath := filepath.Join(outputDir, result.Path)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Write file
-	filePath := filepath.Join(outputDir, result.Path)
 	if err := os.WriteFile(filePath, result.Content, 0644); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
@@ -545,19 +925,32 @@ func (g *PulumiGenerator) writeFile(result *GenerateResult, outputDir string) er
 	return nil
 }
 
-// GetSupportedFormats returns supported output formats
+// GetSupportedFormats returns the format for this generator's language, so
+// it round-trips through NewGenerator
 func (g *PulumiGenerator) GetSupportedFormats() []string {
-	return []string{
-		"pulumi-python",
-		"pulumi-typescript",
-		"pulumi-go",
-		"pulumi-csharp",
-	}
+	return []string{g.GetFormat()}
+}
+
+// pulumiEntryFiles is the program file each language's runtime starts from
+var pulumiEntryFiles = map[string]string{
+	"python":     "__main__.py",
+	"typescript": "index.ts",
+	"go":         "main.go",
+	"csharp":     "Program.cs",
 }
 
-// Validate validates the generated templates
+// Validate checks that the program's entry file was generated and is not
+// empty. The program itself isn't compiled.
 func (g *PulumiGenerator) Validate(results []*GenerateResult) error {
-	// TODO: Implement Pulumi validation
-	g.Log().Info("Pulumi validation not yet implemented")
-	return nil
+	entry := pulumiEntryFiles[g.language]
+	for _, result := range results {
+		if filepath.Base(result.Path) != entry {
+			continue
+		}
+		if len(strings.TrimSpace(string(result.Content))) == 0 {
+			return fmt.Errorf("%s is empty", entry)
+		}
+		return nil
+	}
+	return fmt.Errorf("no %s was generated; Pulumi programs are only generated for VMware infrastructure", entry)
 }
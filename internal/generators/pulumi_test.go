@@ -0,0 +1,176 @@
+package generators
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// Mock Pulumi SDK modules for running a generated Python program: every
+// call succeeds, and each VirtualMachine's name and arguments are recorded
+const (
+	mockPulumi = `class _Any:
+    def __init__(self, *args, **kwargs): pass
+    def __getattr__(self, name): return _Any()
+    def __call__(self, *args, **kwargs): return _Any()
+
+Config = _Any
+exports = {}
+
+def export(name, value):
+    exports[name] = value
+
+def __getattr__(name):
+    return _Any
+`
+	mockVSphere = `from pulumi import _Any
+
+created = {}
+
+class VirtualMachine(_Any):
+    def __init__(self, resource_name, **kwargs):
+        if resource_name in created:
+            raise Exception("duplicate resource " + resource_name)
+        created[resource_name] = sorted(kwargs)
+
+def __getattr__(name):
+    return _Any
+`
+	mockRunner = `import json, runpy, sys
+sys.path.insert(0, sys.argv[1])
+runpy.run_path(sys.argv[2], run_name="__main__")
+import pulumi, pulumi_vsphere
+print(json.dumps({"created": pulumi_vsphere.created, "exports": sorted(pulumi.exports)}))
+`
+)
+
+// pulumiFixture adds a diskless VM and one with quotes in its name to the
+// VMware fixture
+func pulumiFixture() *models.Infrastructure {
+	infra := vmwareFixture()
+	infra.VirtualMachines = append(infra.VirtualMachines,
+		models.VirtualMachine{
+			ID: "vm-103", Name: "iso-appliance", CPUs: 1, Memory: 1024,
+			Config: models.VMConfig{GuestID: "otherLinux64Guest", UUID: "4201-0003"},
+		},
+		models.VirtualMachine{
+			ID: "vm-104", Name: `app "blue"`, CPUs: 1, Memory: 1024,
+			Config: models.VMConfig{GuestID: "otherLinux64Guest", UUID: "4201-0004"},
+			Disks:  []models.Disk{{ID: "2000", Size: 20, Type: "thin", Datastore: "ds1", DatastoreID: "datastore-11"}},
+		},
+	)
+	return infra
+}
+
+func TestPulumiPythonRunsAgainstMockProvider(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+
+	files := generate(t, "pulumi-python", []*models.Infrastructure{pulumiFixture()}, GenerateOptions{})
+	for _, name := range []string{"Pulumi.yaml", "requirements.txt", "__main__.py"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("no %s generated", name)
+		}
+	}
+
+	dir := t.TempDir()
+	mocks := map[string]string{
+		"pulumi.py":         mockPulumi,
+		"pulumi_vsphere.py": mockVSphere,
+		"run.py":            mockRunner,
+		"__main__.py":       files["__main__.py"],
+	}
+	for name, content := range mocks {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := exec.Command(python, filepath.Join(dir, "run.py"), dir, filepath.Join(dir, "__main__.py")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("program failed: %v\n%s\n%s", err, out, files["__main__.py"])
+	}
+	var run struct {
+		Created map[string][]string `json:"created"`
+		Exports []string            `json:"exports"`
+	}
+	if err := json.Unmarshal(out, &run); err != nil {
+		t.Fatalf("reading mock output %q: %v", out, err)
+	}
+
+	var names []string
+	for name, args := range run.Created {
+		names = append(names, name)
+		given := strings.Join(args, " ") + " "
+		for _, arg := range []string{"datastore_id", "name", "resource_pool_id"} {
+			if !strings.Contains(given, arg+" ") {
+				t.Errorf("%s has no %s", name, arg)
+			}
+		}
+	}
+	sort.Strings(names)
+	if want := []string{"app__blue_", "db_01", "iso_appliance", "web_01"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("created %v, want one VM per non-template VM %v", names, want)
+	}
+	if len(run.Exports) == 0 {
+		t.Error("program exports nothing")
+	}
+}
+
+func TestPulumiGoParses(t *testing.T) {
+	files := generate(t, "pulumi-go", []*models.Infrastructure{pulumiFixture()}, GenerateOptions{})
+
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", files["main.go"], 0)
+	if err != nil {
+		t.Fatalf("main.go does not parse: %v\n%s", err, files["main.go"])
+	}
+	if file.Name.Name != "main" {
+		t.Errorf("package %s, want main", file.Name.Name)
+	}
+	if got := strings.Count(files["main.go"], "vsphere.NewVirtualMachine("); got != 4 {
+		t.Errorf("got %d VirtualMachine resources, want 4", got)
+	}
+}
+
+func TestPulumiTypeScriptProgram(t *testing.T) {
+	files := generate(t, "pulumi-typescript", []*models.Infrastructure{pulumiFixture()}, GenerateOptions{})
+	for _, name := range []string{"Pulumi.yaml", "package.json", "tsconfig.json", "index.ts"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("no %s generated", name)
+		}
+	}
+
+	var pkg struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal([]byte(files["package.json"]), &pkg); err != nil {
+		t.Fatalf("package.json: %v", err)
+	}
+	if pkg.Dependencies["@pulumi/vsphere"] == "" {
+		t.Errorf("package.json does not depend on @pulumi/vsphere: %v", pkg.Dependencies)
+	}
+
+	program := files["index.ts"]
+	if got := strings.Count(program, "new vsphere.VirtualMachine("); got != 4 {
+		t.Errorf("got %d VirtualMachine resources, want 4", got)
+	}
+	if !strings.Contains(program, `name: "app \"blue\""`) {
+		t.Error("quoted VM name is not escaped")
+	}
+	for _, pair := range []string{"()", "[]", "{}"} {
+		if strings.Count(program, pair[:1]) != strings.Count(program, pair[1:]) {
+			t.Errorf("index.ts has unbalanced %s", pair)
+		}
+	}
+}
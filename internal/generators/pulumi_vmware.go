@@ -0,0 +1,181 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// pulumiProgram is the language-neutral shape of a VMware Pulumi program.
+// Names are resolved to resource identifiers and string values are already
+// escaped, so the per-language renderers only format it.
+type pulumiProgram struct {
+	Networks   []pulumiLookup
+	Datastores []pulumiLookup
+	VMs        []pulumiVM
+
+	// Annotations too long to inline as comments
+	Notes []*GenerateResult
+
+	// VM resource names, deduplicated
+	names *ResourceNames
+}
+
+// pulumiLookup is a network or datastore data source lookup. ConfigKey,
+// when set, names the stack config key the name is read from at run time.
+type pulumiLookup struct {
+	Resource  string
+	Name      string
+	ConfigKey string
+}
+
+// nameExpr renders the looked up name: a string literal, or a call of the
+// language's required-config function with ConfigKey
+func (l pulumiLookup) nameExpr(require string) string {
+	if l.ConfigKey != "" {
+		return fmt.Sprintf(`%s("%s")`, require, l.ConfigKey)
+	}
+	return `"` + l.Name + `"`
+}
+
+// pulumiVM is one vsphere.VirtualMachine resource
+type pulumiVM struct {
+	Resource        string
+	Name            string
+	Comments        string // annotation comments, prefixed for the language
+	Datastore       string // resource of the datastore lookup to place it on
+	CPUs            int
+	Memory          int64
+	GuestID         string
+	Firmware        string
+	HardwareVersion int
+	UpgradedFrom    string
+	ExtraConfig     [][2]string
+	NICs            []pulumiNIC
+	Disks           []pulumiDisk
+	OmittedDisks    []string // disks that can't be expressed, with the reason
+}
+
+// pulumiNIC is a network interface; MACAddress is only set when it should
+// be kept static
+type pulumiNIC struct {
+	Network     string
+	AdapterType string
+	MACAddress  string
+}
+
+// pulumiDisk is a virtual disk
+type pulumiDisk struct {
+	Label     string
+	Size      int64
+	Thin      bool
+	Datastore string
+}
+
+// defaultDatastore is looked up for VMs without discovered disks
+var defaultDatastore = pulumiLookup{
+	Resource:  "default_datastore",
+	Name:      "default_datastore from stack config",
+	ConfigKey: "default_datastore",
+}
+
+// vmwareProgram maps discovered VMware infrastructure onto the resources a
+// Pulumi program declares, the same way the Terraform generator does.
+// commentPrefix starts a line comment in the target language.
+func (g *PulumiGenerator) vmwareProgram(infra *models.Infrastructure, opts GenerateOptions, commentPrefix string) *pulumiProgram {
+	version := vmwareVersion(infra)
+	program := &pulumiProgram{names: NewResourceNames(g.GenerateResourceName)}
+	vmNames := program.names.VMs(infra.VirtualMachines)
+
+	networks := make(map[string]bool)
+	datastores := make(map[string]bool)
+
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+
+		comments, sidecar := g.AnnotationComments(vm, vmNames[i], commentPrefix, opts.MaxFieldSize)
+		if sidecar != nil {
+			program.Notes = append(program.Notes, sidecar)
+		}
+
+		// Diskless VMs go on the configured default datastore, as in Terraform
+		datastore := g.PrimaryDatastore(vm, infra.Storage)
+		datastoreResource := g.GenerateResourceName(datastore)
+		if len(vm.Disks) == 0 {
+			datastoreResource = defaultDatastore.Resource
+			program.Datastores = []pulumiLookup{defaultDatastore}
+		} else if datastore != "" {
+			datastores[datastore] = true
+		}
+
+		hwVersion, upgradedFrom := g.HardwareVersion(vm, opts.UpgradeHWVersion)
+		shaped := pulumiVM{
+			Resource:        vmNames[i],
+			Name:            g.SanitizeValue(vm.Name),
+			Comments:        comments,
+			Datastore:       datastoreResource,
+			CPUs:            vm.CPUs,
+			Memory:          vm.Memory,
+			GuestID:         g.SanitizeValue(vm.Config.GuestID),
+			Firmware:        strings.ToLower(vm.Hardware.Firmware),
+			HardwareVersion: hwVersion,
+			UpgradedFrom:    upgradedFrom,
+			ExtraConfig:     g.VideoExtraConfig(vm),
+		}
+
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			if nic.Network != "" {
+				networks[nic.Network] = true
+			}
+			shapedNIC := pulumiNIC{Network: g.GenerateResourceName(nic.Network)}
+			if adapterType, ok := g.VSphereAdapterType(vm.Name, nic.Type, version); ok {
+				shapedNIC.AdapterType = adapterType
+			}
+			// Keep the discovered MAC so DHCP reservations and licensing survive
+			if opts.PreserveMAC {
+				shapedNIC.MACAddress = nic.MACAddress
+			}
+			shaped.NICs = append(shaped.NICs, shapedNIC)
+		}
+
+		// Raw device mappings can't be expressed and are left out
+		for _, disk := range vm.Disks {
+			if disk.IsRDM() {
+				shaped.OmittedDisks = append(shaped.OmittedDisks,
+					fmt.Sprintf("%s disk %s (LUN %s) omitted", disk.Type, disk.ID, disk.LUN))
+				continue
+			}
+			if disk.Datastore != "" {
+				datastores[disk.Datastore] = true
+			}
+			shaped.Disks = append(shaped.Disks, pulumiDisk{
+				Label:     fmt.Sprintf("disk%d", len(shaped.Disks)),
+				Size:      disk.Size,
+				Thin:      strings.Contains(disk.Type, "thin"),
+				Datastore: g.GenerateResourceName(disk.Datastore),
+			})
+		}
+
+		program.VMs = append(program.VMs, shaped)
+	}
+
+	// Lookups must be declared before use, so keep them in a stable order
+	for _, network := range sortedNames(networks) {
+		program.Networks = append(program.Networks, pulumiLookup{
+			Resource: g.GenerateResourceName(network),
+			Name:     g.SanitizeValue(network),
+		})
+	}
+	// The default datastore, if any, is already in Datastores
+	for _, datastore := range sortedNames(datastores) {
+		program.Datastores = append(program.Datastores, pulumiLookup{
+			Resource: g.GenerateResourceName(datastore),
+			Name:     g.SanitizeValue(datastore),
+		})
+	}
+
+	return program
+}
@@ -0,0 +1,209 @@
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// SampleOptions controls pilot subset selection
+type SampleOptions struct {
+	Count    int    `json:"count,omitempty"`     // total VMs to keep, 0 for no limit
+	PerGroup int    `json:"per_group,omitempty"` // VMs to keep per group, 0 to disable grouping
+	GroupBy  string `json:"group_by,omitempty"`  // folder, tag
+	Seed     int64  `json:"seed"`
+}
+
+// Manifest records which VMs were selected for a pilot generation
+type Manifest struct {
+	CreatedAt  time.Time       `json:"created_at"`
+	Seed       int64           `json:"seed"`
+	GroupBy    string          `json:"group_by,omitempty"`
+	TotalVMs   int             `json:"total_vms"`
+	SampledVMs int             `json:"sampled_vms"`
+	VMs        []ManifestEntry `json:"vms"`
+
+	// Sources are the discovery runs the sample was drawn from
+	Sources []Source `json:"sources,omitempty"`
+}
+
+// ManifestEntry identifies a single VM in a manifest
+type ManifestEntry struct {
+	Provider string `json:"provider"`
+	Server   string `json:"server"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+}
+
+// key returns the identity used to match manifest entries against VMs
+func (e ManifestEntry) key() string {
+	id := e.ID
+	if id == "" {
+		id = e.Name
+	}
+	return strings.ToLower(e.Provider) + "|" + e.Server + "|" + id
+}
+
+// sampleCandidate pairs a VM with its manifest identity
+type sampleCandidate struct {
+	vm    models.VirtualMachine
+	entry ManifestEntry
+}
+
+// SampleVMs selects a representative subset of VMs for generation. Templates
+// are never sampled. The returned infrastructures are copies containing only
+// the sampled VMs; the manifest lists the selection so it can be excluded later.
+func SampleVMs(infrastructures []*models.Infrastructure, opts SampleOptions) ([]*models.Infrastructure, *Manifest, error) {
+	groupBy := strings.ToLower(opts.GroupBy)
+	if opts.PerGroup > 0 && groupBy == "" {
+		groupBy = "folder"
+	}
+	if groupBy != "" && groupBy != "folder" && groupBy != "tag" {
+		return nil, nil, fmt.Errorf("unsupported group-by value: %s (expected folder or tag)", opts.GroupBy)
+	}
+
+	// Build the candidate pool in a stable order so a seed always yields the same sample
+	var pool []sampleCandidate
+	for _, infra := range infrastructures {
+		for _, vm := range infra.VirtualMachines {
+			if vm.Config.Template {
+				continue
+			}
+			pool = append(pool, sampleCandidate{
+				vm:    vm,
+				entry: ManifestEntry{Provider: infra.Provider, Server: infra.Server, ID: vm.ID, Name: vm.Name},
+			})
+		}
+	}
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].entry.key()+"|"+pool[i].entry.Name < pool[j].entry.key()+"|"+pool[j].entry.Name
+	})
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	selected := pool
+	if opts.PerGroup > 0 {
+		selected = samplePerGroup(pool, groupBy, opts.PerGroup)
+	}
+	if opts.Count > 0 && len(selected) > opts.Count {
+		selected = selected[:opts.Count]
+	}
+
+	manifest := &Manifest{
+		CreatedAt:  time.Now(),
+		Seed:       opts.Seed,
+		GroupBy:    groupBy,
+		TotalVMs:   len(pool),
+		SampledVMs: len(selected),
+		Sources:    Sources(infrastructures),
+	}
+
+	keep := make(map[string]bool)
+	for _, candidate := range selected {
+		keep[candidate.entry.key()] = true
+		manifest.VMs = append(manifest.VMs, candidate.entry)
+	}
+	sort.Slice(manifest.VMs, func(i, j int) bool {
+		return manifest.VMs[i].key() < manifest.VMs[j].key()
+	})
+
+	return retainVMs(infrastructures, keep, true), manifest, nil
+}
+
+// samplePerGroup picks up to n candidates from each group; a VM with several
+// tags is considered once, in the first of its groups that still has room
+func samplePerGroup(pool []sampleCandidate, groupBy string, n int) []sampleCandidate {
+	counts := make(map[string]int)
+	var selected []sampleCandidate
+
+	for _, candidate := range pool {
+		groups := []string{candidate.vm.Folder}
+		if groupBy == "tag" {
+			groups = candidate.vm.Tags
+			if len(groups) == 0 {
+				groups = []string{""}
+			}
+		}
+
+		for _, group := range groups {
+			if counts[group] < n {
+				counts[group]++
+				selected = append(selected, candidate)
+				break
+			}
+		}
+	}
+
+	return selected
+}
+
+// ReadManifest loads a manifest written by a previous sampled generation
+func ReadManifest(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return &manifest, nil
+}
+
+// WriteManifest saves a manifest as JSON
+func WriteManifest(manifest *Manifest, filename string) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// ExcludeManifest removes the VMs listed in a manifest from the infrastructures
+func ExcludeManifest(infrastructures []*models.Infrastructure, manifest *Manifest) []*models.Infrastructure {
+	exclude := make(map[string]bool)
+	for _, entry := range manifest.VMs {
+		exclude[entry.key()] = true
+	}
+	return retainVMs(infrastructures, exclude, false)
+}
+
+// retainVMs returns copies of the infrastructures keeping only VMs whose key
+// is (keep=true) or is not (keep=false) in the set; templates are always kept
+func retainVMs(infrastructures []*models.Infrastructure, set map[string]bool, keep bool) []*models.Infrastructure {
+	var result []*models.Infrastructure
+
+	for _, infra := range infrastructures {
+		infraCopy := *infra
+		infraCopy.VirtualMachines = nil
+
+		for _, vm := range infra.VirtualMachines {
+			entry := ManifestEntry{Provider: infra.Provider, Server: infra.Server, ID: vm.ID, Name: vm.Name}
+			if vm.Config.Template || set[entry.key()] == keep {
+				infraCopy.VirtualMachines = append(infraCopy.VirtualMachines, vm)
+			}
+		}
+
+		result = append(result, &infraCopy)
+	}
+
+	return result
+}
@@ -0,0 +1,34 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSecretsRefusesToWrite(t *testing.T) {
+	secret := "Sup3r" + "S3cret!"
+	results := []*GenerateResult{
+		{Path: "provider.tf", Content: []byte("provider \"vsphere\" {\n  password = var.vsphere_password\n}\n")},
+		{Path: "terraform.tfvars", Content: []byte(`vsphere_password = "` + secret + `"` + "\n")},
+		{Path: "secrets.auto.tfvars", Type: "secrets", Content: []byte(`vsphere_password = "` + secret + `"` + "\n")},
+	}
+	generator := NewBaseGenerator("terraform", "terraform", testLogger())
+
+	err := generator.CheckSecrets(results, GenerateOptions{})
+	if err == nil {
+		t.Fatal("CheckSecrets allowed a hardcoded password")
+	}
+	if !strings.Contains(err.Error(), "terraform.tfvars:1") || strings.Contains(err.Error(), "secrets.auto.tfvars") {
+		t.Errorf("error names the wrong files: %v", err)
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Errorf("error repeats the secret: %v", err)
+	}
+
+	if err := generator.CheckSecrets(results, GenerateOptions{AllowSecrets: true}); err != nil {
+		t.Errorf("--allow-secrets: %v", err)
+	}
+	if err := generator.CheckSecrets(results, GenerateOptions{DryRun: true}); err != nil {
+		t.Errorf("dry run: %v", err)
+	}
+}
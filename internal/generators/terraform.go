@@ -33,9 +33,30 @@ func (g *TerraformGenerator) Generate(infrastructures []*models.Infrastructure,
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate for provider %s: %w", infra.Provider, err)
 		}
+		if check := opts.Capacity[CapacityKey(infra)]; check != nil {
+			for _, result := range providerResults {
+				if result.Path == "virtual_machines.tf" {
+					result.Content = append([]byte(check.Comment()), result.Content...)
+					result.Size = len(result.Content)
+				}
+			}
+		}
 		results = append(results, providerResults...)
 	}
 
+	// Merge everything into a single main.tf if requested
+	if opts.Flatten {
+		results = g.flattenResults(results)
+	}
+
+	if err := g.RunHooks(results, opts); err != nil {
+		return nil, err
+	}
+
+	if err := g.CheckSecrets(results, opts); err != nil {
+		return nil, err
+	}
+
 	// Write files if not dry run
 	if !opts.DryRun {
 		for _, result := range results {
@@ -66,6 +87,37 @@ func (g *TerraformGenerator) generateForProvider(infra *models.Infrastructure, o
 func (g *TerraformGenerator) generateVMware(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
 	var results []*GenerateResult
 
+	// Raw device mappings need manual work; optionally leave those VMs out entirely
+	var rdmVMs []models.VirtualMachine
+	for _, vm := range infra.VirtualMachines {
+		if !vm.Config.Template && hasRDM(vm) {
+			rdmVMs = append(rdmVMs, vm)
+		}
+	}
+	if len(rdmVMs) > 0 {
+		g.Log().Warn("VMs with raw device mappings need manual steps", "vms", len(rdmVMs), "skipped", opts.SkipRDMVMs)
+		if opts.SkipRDMVMs {
+			infraCopy := *infra
+			infraCopy.VirtualMachines = nil
+			for _, vm := range infra.VirtualMachines {
+				if !hasRDM(vm) {
+					infraCopy.VirtualMachines = append(infraCopy.VirtualMachines, vm)
+				}
+			}
+			infra = &infraCopy
+		}
+
+		steps := g.generateRDMManualSteps(rdmVMs, opts.SkipRDMVMs)
+		results = append(results, &GenerateResult{
+			Path:      "MANUAL_STEPS.md",
+			Content:   []byte(steps),
+			Size:      len(steps),
+			Type:      "docs",
+			Provider:  "vmware",
+			Resources: []string{},
+		})
+	}
+
 	// Generate provider configuration
 	providerConfig := g.generateVMwareProvider(infra)
 	results = append(results, &GenerateResult{
@@ -88,8 +140,15 @@ func (g *TerraformGenerator) generateVMware(infra *models.Infrastructure, opts G
 		Resources: []string{},
 	})
 
-	// Generate data sources
-	dataSources := g.generateVMwareDataSources(infra)
+	// Generate data sources; the VMs refer to them by the same labels. The
+	// port groups are created first so the network lookups can wait for them.
+	data := g.newVMwareDataSources(infra)
+	var networks string
+	var networkResources []string
+	if opts.CreateNetworks {
+		networks, networkResources = g.generateVMwareNetworks(infra, data, opts)
+	}
+	dataSources := g.generateVMwareDataSources(infra, data, opts)
 	results = append(results, &GenerateResult{
 		Path:      "data.tf",
 		Content:   []byte(dataSources),
@@ -98,10 +157,24 @@ func (g *TerraformGenerator) generateVMware(infra *models.Infrastructure, opts G
 		Provider:  "vmware",
 		Resources: []string{},
 	})
+	if opts.CreateNetworks {
+		results = append(results, &GenerateResult{
+			Path:      "networks.tf",
+			Content:   []byte(networks),
+			Size:      len(networks),
+			Type:      "resources",
+			Provider:  "vmware",
+			Resources: networkResources,
+		})
+	}
+
+	// Resource names are shared by the VM resources and their outputs
+	names := NewResourceNames(g.GenerateResourceName)
+	vmNames := names.VMs(infra.VirtualMachines)
 
 	// Generate VMs
 	if len(infra.VirtualMachines) > 0 {
-		vms := g.generateVMwareVMs(infra.VirtualMachines)
+		vms, notes := g.generateVMwareVMs(infra.VirtualMachines, vmNames, data, infra.Templates, vmwareVersion(infra), opts)
 		results = append(results, &GenerateResult{
 			Path:      "virtual_machines.tf",
 			Content:   []byte(vms),
@@ -109,11 +182,24 @@ func (g *TerraformGenerator) generateVMware(infra *models.Infrastructure, opts G
 			Type:      "resources",
 			Provider:  "vmware",
 			Resources: []string{"vsphere_virtual_machine"},
+			Metadata:  map[string]interface{}{"resource_names": names.Mapping},
 		})
+		results = append(results, notes...)
+
+		if opts.WithImports {
+			results = append(results, g.importResults(g.vmwareImports(infra, vmNames), "vmware")...)
+		}
+	}
+
+	// Post-clone instructions from annotations, only on request
+	if opts.EmitPostProvision {
+		if doc := PostProvisionDoc(infra.VirtualMachines, "vmware"); doc != nil {
+			results = append(results, doc)
+		}
 	}
 
 	// Generate outputs
-	outputs := g.generateVMwareOutputs(infra)
+	outputs := g.generateVMwareOutputs(infra, vmNames)
 	results = append(results, &GenerateResult{
 		Path:      "outputs.tf",
 		Content:   []byte(outputs),
@@ -126,6 +212,35 @@ func (g *TerraformGenerator) generateVMware(infra *models.Infrastructure, opts G
 	return results, nil
 }
 
+// generateRDMManualSteps documents the raw device mappings that generated
+// code leaves out
+func (g *TerraformGenerator) generateRDMManualSteps(vms []models.VirtualMachine, skipped bool) string {
+	content := `# Manual Steps
+
+## Raw device mappings
+
+The VMs below use raw device mapping (RDM) disks. The vSphere Terraform
+provider cannot recreate them. Planning them as ordinary disks would replace
+the mapped LUNs, so they have been left out of the generated code.
+`
+	if skipped {
+		content += "\nThese VMs were skipped entirely (--skip-rdm-vms).\n"
+	} else {
+		content += "\nThe VMs are generated without their RDM disks; re-map each LUN after apply.\n"
+	}
+
+	content += "\n| VM | Disk | Mode | LUN | Mapping file |\n|----|------|------|-----|--------------|\n"
+	for _, vm := range vms {
+		for _, disk := range vm.Disks {
+			if disk.IsRDM() {
+				content += fmt.Sprintf("| %s | %s | %s | %s | %s |\n", vm.Name, disk.ID, disk.Type, disk.LUN, disk.Path)
+			}
+		}
+	}
+
+	return content
+}
+
 // generateVMwareProvider generates VMware provider configuration
 func (g *TerraformGenerator) generateVMwareProvider(infra *models.Infrastructure) string {
 	return fmt.Sprintf(`terraform {
@@ -178,11 +293,40 @@ variable "datacenter" {
   type        = string
   default     = "%s"
 }
-`, infra.Server, infra.Datacenter)
+`, infra.Server, infra.Datacenter) + g.generateDefaultDatastoreVariable(infra)
+}
+
+// generateDefaultDatastoreVariable declares the datastore VMs without
+// discovered disks are placed on, defaulting to the first discovered one
+func (g *TerraformGenerator) generateDefaultDatastoreVariable(infra *models.Infrastructure) string {
+	if !hasDisklessVMs(infra.VirtualMachines) {
+		return ""
+	}
+
+	variable := `
+variable "default_datastore" {
+  description = "Datastore for VMs without discovered disks"
+  type        = string
+`
+	if len(infra.Storage) > 0 {
+		variable += fmt.Sprintf("  default     = \"%s\"\n", EscapeHCL(infra.Storage[0].Name))
+	}
+	return variable + "}\n"
+}
+
+// hasDisklessVMs reports whether any generated VM has no discovered disk
+// datastore to be placed on
+func hasDisklessVMs(vms []models.VirtualMachine) bool {
+	for _, vm := range vms {
+		if !vm.Config.Template && !vm.IsContainer() && primaryDatastore(vm) == "" {
+			return true
+		}
+	}
+	return false
 }
 
 // generateVMwareDataSources generates data source definitions
-func (g *TerraformGenerator) generateVMwareDataSources(infra *models.Infrastructure) string {
+func (g *TerraformGenerator) generateVMwareDataSources(infra *models.Infrastructure, data *vmwareDataSources, opts GenerateOptions) string {
 	dataConfig := `data "vsphere_datacenter" "dc" {
   name = var.datacenter
 }
@@ -195,65 +339,248 @@ data "vsphere_compute_cluster" "cluster" {
   name          = "%s"
   datacenter_id = data.vsphere_datacenter.dc.id
 }
-`, infra.Cluster)
+`, EscapeHCL(infra.Cluster))
+	}
+
+	networks, datastores := g.selectedVMwareData(infra, data, opts)
+	for _, key := range data.networks.sortedKeys(networks) {
+		network := data.networks.byKey[key]
+		dataConfig += fmt.Sprintf(`
+data "vsphere_network" "%s" {
+  name          = "%s"
+  datacenter_id = data.vsphere_datacenter.dc.id
+`, network.label, EscapeHCL(network.name))
+		// Port groups created by --create-networks exist once they apply
+		if created := data.created[key]; len(created) > 0 {
+			dataConfig += fmt.Sprintf("\n  depends_on = [%s]\n", strings.Join(created, ", "))
+		}
+		dataConfig += "}\n"
+	}
+
+	for _, datastore := range data.datastores.sorted(datastores) {
+		dataConfig += fmt.Sprintf(`
+data "vsphere_datastore" "%s" {
+  name          = "%s"
+  datacenter_id = data.vsphere_datacenter.dc.id
+}
+`, datastore.label, EscapeHCL(datastore.name))
+	}
+
+	if hasDisklessVMs(infra.VirtualMachines) {
+		dataConfig += `
+data "vsphere_datastore" "default_datastore" {
+  name          = var.default_datastore
+  datacenter_id = data.vsphere_datacenter.dc.id
+}
+`
+	}
+
+	// Templates that linked clones are recreated from
+	templates := make(map[string]bool)
+	for _, vm := range infra.VirtualMachines {
+		if template := linkedCloneTemplate(vm, infra.Templates, opts); template != "" {
+			templates[template] = true
+		}
+	}
+	for _, template := range sortedNames(templates) {
+		dataConfig += fmt.Sprintf(`
+data "vsphere_virtual_machine" "%s" {
+  name          = "%s"
+  datacenter_id = data.vsphere_datacenter.dc.id
+}
+`, g.GenerateResourceName(template), EscapeHCL(template))
 	}
 
-	// Add common data sources for networks and datastores
+	// Hosts and resource pools are never referenced by generated VMs, which
+	// are placed through the cluster; they are only part of the full catalog
+	if opts.IncludeAllDataSources {
+		dataConfig += g.generateVMwareCatalogDataSources(infra)
+	}
+
+	// Inferred ownership is carried over as vSphere custom attributes
+	hasOwner, hasEnvironment := ownershipFields(infra.VirtualMachines)
+	if hasOwner {
+		dataConfig += `
+data "vsphere_custom_attribute" "owner" {
+  name = "Owner"
+}
+`
+	}
+	if hasEnvironment {
+		dataConfig += `
+data "vsphere_custom_attribute" "environment" {
+  name = "Environment"
+}
+`
+	}
+
+	return dataConfig
+}
+
+// selectedVMwareData returns the keys of the networks and datastores to
+// look up: those the generated VMs refer to, or every discovered one for
+// --include-all-data-sources
+func (g *TerraformGenerator) selectedVMwareData(infra *models.Infrastructure, data *vmwareDataSources, opts GenerateOptions) (map[string]bool, map[string]bool) {
+	networks, datastores := g.referencedVMwareData(infra, data)
+	if opts.IncludeAllDataSources {
+		for key, source := range data.networks.byKey {
+			networks[key] = networks[key] || source.known
+		}
+		for key, source := range data.datastores.byKey {
+			datastores[key] = datastores[key] || source.known
+		}
+	}
+
+	// Drop resources the target cluster can't use when discovery was
+	// cluster-scoped: it only lists what the cluster's hosts can reach
+	if scoped, _ := infra.Metadata["cluster_scoped"].(bool); scoped {
+		networks = g.filterClusterScoped(networks, data.networks, "network")
+		datastores = g.filterClusterScoped(datastores, data.datastores, "datastore")
+	}
+
+	return networks, datastores
+}
+
+// referencedVMwareData returns the keys of the networks and datastores that
+// the VMs being generated refer to. Templates and containers are not
+// generated and raw device mappings are left out of the disks, so they
+// refer to nothing.
+func (g *TerraformGenerator) referencedVMwareData(infra *models.Infrastructure, data *vmwareDataSources) (map[string]bool, map[string]bool) {
 	networks := make(map[string]bool)
 	datastores := make(map[string]bool)
 
 	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
 		for _, nic := range vm.NetworkCards {
 			if nic.Network != "" {
-				networks[nic.Network] = true
+				networks[data.networks.key(data.nicNetwork(nic))] = true
 			}
 		}
 		for _, disk := range vm.Disks {
-			if disk.Datastore != "" {
-				datastores[disk.Datastore] = true
+			if datastore := diskDatastore(disk); datastore != "" && !disk.IsRDM() {
+				datastores[data.datastores.key(datastore)] = true
 			}
 		}
+		if datastore := primaryDatastore(vm); datastore != "" {
+			datastores[data.datastores.key(datastore)] = true
+		}
 	}
 
-	for network := range networks {
-		resourceName := g.GenerateResourceName(network)
+	return networks, datastores
+}
+
+// generateVMwareCatalogDataSources looks up every discovered host and
+// resource pool, for --include-all-data-sources
+func (g *TerraformGenerator) generateVMwareCatalogDataSources(infra *models.Infrastructure) string {
+	var dataConfig string
+
+	names := NewResourceNames(g.GenerateResourceName)
+	for i, label := range g.catalogHostLabels(infra, names) {
 		dataConfig += fmt.Sprintf(`
-data "vsphere_network" "%s" {
+data "vsphere_host" "%s" {
   name          = "%s"
   datacenter_id = data.vsphere_datacenter.dc.id
 }
-`, resourceName, network)
+`, label, EscapeHCL(infra.Hosts[i].Name))
 	}
 
-	for datastore := range datastores {
-		resourceName := g.GenerateResourceName(datastore)
+	for _, pool := range infra.ResourcePools {
 		dataConfig += fmt.Sprintf(`
-data "vsphere_datastore" "%s" {
+data "vsphere_resource_pool" "%s" {
   name          = "%s"
   datacenter_id = data.vsphere_datacenter.dc.id
 }
-`, resourceName, datastore)
+`, names.Name("pool_"+pool.Name), EscapeHCL(pool.Name))
 	}
 
 	return dataConfig
 }
 
-// generateVMwareVMs generates VM resource definitions
-func (g *TerraformGenerator) generateVMwareVMs(vms []models.VirtualMachine) string {
-	var vmConfigs []string
+// catalogHostLabels names the host data sources of the full catalog in
+// names, in the order of infra.Hosts
+func (g *TerraformGenerator) catalogHostLabels(infra *models.Infrastructure, names *ResourceNames) []string {
+	labels := make([]string, 0, len(infra.Hosts))
+	for _, host := range infra.Hosts {
+		labels = append(labels, names.Name("host_"+host.Name))
+	}
+	return labels
+}
 
+// linkedCloneTemplate returns the discovered template a VM should be linked
+// cloned from, or "" when linked clones are not allowed or the VM's parent
+// is not a discovered template
+func linkedCloneTemplate(vm models.VirtualMachine, templates []models.Template, opts GenerateOptions) string {
+	if !opts.AllowLinkedClones || vm.Config.LinkedCloneOf == "" || vm.Config.Template {
+		return ""
+	}
+	for _, template := range templates {
+		if template.Name == vm.Config.LinkedCloneOf {
+			return template.Name
+		}
+	}
+	return ""
+}
+
+// ownershipFields reports whether any VM has an owner or environment set
+func ownershipFields(vms []models.VirtualMachine) (bool, bool) {
+	var hasOwner, hasEnvironment bool
 	for _, vm := range vms {
-		// Skip templates
-		if vm.Config.Template {
+		hasOwner = hasOwner || vm.Owner != ""
+		hasEnvironment = hasEnvironment || vm.Environment != ""
+	}
+	return hasOwner, hasEnvironment
+}
+
+// filterClusterScoped keeps only the referenced data sources that were
+// discovered, and so are visible from the target cluster
+func (g *TerraformGenerator) filterClusterScoped(referenced map[string]bool, sources *dataSourceSet, kind string) map[string]bool {
+	filtered := make(map[string]bool)
+	for key := range referenced {
+		if sources.byKey[key].known {
+			filtered[key] = true
+		} else {
+			g.Log().Warn("Skipping data source not visible from the target cluster", "type", kind, "name", sources.byKey[key].name)
+		}
+	}
+	return filtered
+}
+
+// generateVMwareVMs generates VM resource definitions, along with sidecar
+// notes files for annotations too large to inline as comments
+func (g *TerraformGenerator) generateVMwareVMs(vms []models.VirtualMachine, names []string, data *vmwareDataSources, templates []models.Template, version string, opts GenerateOptions) (string, []*GenerateResult) {
+	var vmConfigs []string
+	var notes []*GenerateResult
+
+	for i, vm := range vms {
+		// Skip templates; containers have no vSphere equivalent
+		if vm.Config.Template || vm.IsContainer() {
 			continue
 		}
 
-		resourceName := g.GenerateResourceName(vm.Name)
-		
-		config := fmt.Sprintf(`resource "vsphere_virtual_machine" "%s" {
+		resourceName := names[i]
+
+		comments, sidecar := g.AnnotationComments(vm, resourceName, "#", opts.MaxFieldSize)
+		if sidecar != nil {
+			sidecar.Provider = "vmware"
+			notes = append(notes, sidecar)
+		}
+
+		// Diskless VMs, such as appliances booting from an ISO, have no
+		// datastore to follow
+		datastoreID := "data.vsphere_datastore.default_datastore.id"
+		datastoreNote := ""
+		if datastore := primaryDatastore(vm); datastore != "" {
+			datastoreID = fmt.Sprintf("data.vsphere_datastore.%s.id", data.datastores.label(datastore))
+		} else {
+			datastoreNote = "  # No disks were discovered for this VM; it is placed on var.default_datastore\n"
+		}
+
+		config := comments + fmt.Sprintf(`resource "vsphere_virtual_machine" "%s" {
   name             = "%s"
   resource_pool_id = data.vsphere_compute_cluster.cluster.resource_pool_id
-  datastore_id     = data.vsphere_datastore.%s.id
+%s  datastore_id     = %s
   
   num_cpus = %d
   memory   = %d
@@ -261,23 +588,57 @@ func (g *TerraformGenerator) generateVMwareVMs(vms []models.VirtualMachine) stri
   guest_id = "%s"
   
   firmware = "%s"
-`, resourceName, vm.Name, g.GenerateResourceName(vm.Disks[0].Datastore), 
+`, resourceName, EscapeHCL(vm.Name), datastoreNote, datastoreID,
    vm.CPUs, vm.Memory, vm.Config.GuestID, strings.ToLower(vm.Hardware.Firmware))
 
+		// Pin the hardware version so the VM isn't recreated at the provider default
+		if hwVersion, upgradedFrom := g.HardwareVersion(vm, opts.UpgradeHWVersion); hwVersion > 0 {
+			if upgradedFrom != "" {
+				config += fmt.Sprintf("\n  # Upgraded from %s by --upgrade-hw-version\n", upgradedFrom)
+			} else {
+				config += "\n"
+			}
+			config += fmt.Sprintf("  hardware_version = %d\n", hwVersion)
+		}
+
 		// Add network interfaces
-		for _, nic := range vm.NetworkCards {
-			networkResourceName := g.GenerateResourceName(nic.Network)
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			if nic.Network == "" {
+				config += fmt.Sprintf("\n  # Network adapter %s omitted: no network was discovered for it\n", nic.ID)
+				continue
+			}
+			networkResourceName := data.networks.label(data.nicNetwork(nic))
 			config += fmt.Sprintf(`
   network_interface {
     network_id   = data.vsphere_network.%s.id
-    adapter_type = "%s"
-  }
-`, networkResourceName, nic.Type)
+`, networkResourceName)
+
+			// Unknown adapters fall back to the provider default
+			if adapterType, ok := g.VSphereAdapterType(vm.Name, nic.Type, version); ok {
+				config += fmt.Sprintf("    adapter_type = \"%s\"\n", adapterType)
+			}
+
+			// Keep the discovered MAC so DHCP reservations and licensing survive
+			if opts.PreserveMAC && nic.MACAddress != "" {
+				config += fmt.Sprintf(`    use_static_mac = true
+    mac_address    = "%s"
+`, nic.MACAddress)
+			}
+
+			config += "  }\n"
 		}
 
-		// Add disks
-		for i, disk := range vm.Disks {
-			datastoreResourceName := g.GenerateResourceName(disk.Datastore)
+		// Add disks; raw device mappings can't be expressed and are left out
+		label := 0
+		for _, disk := range vm.Disks {
+			if disk.IsRDM() {
+				config += fmt.Sprintf(`
+  # WARNING: %s disk %s (LUN %s) omitted, see MANUAL_STEPS.md
+`, disk.Type, disk.ID, disk.LUN)
+				continue
+			}
+
+			datastoreResourceName := data.datastores.label(diskDatastore(disk))
 			config += fmt.Sprintf(`
   disk {
     label            = "disk%d"
@@ -285,34 +646,87 @@ func (g *TerraformGenerator) generateVMwareVMs(vms []models.VirtualMachine) stri
     thin_provisioned = %t
     datastore_id     = data.vsphere_datastore.%s.id
   }
-`, i, disk.Size, strings.Contains(disk.Type, "thin"), datastoreResourceName)
+`, label, disk.Size, strings.Contains(disk.Type, "thin"), datastoreResourceName)
+			label++
+		}
+
+		// Linked clones share their template's base disks instead of copying them
+		if template := linkedCloneTemplate(vm, templates, opts); template != "" {
+			config += fmt.Sprintf(`
+  clone {
+    template_uuid = data.vsphere_virtual_machine.%s.id
+    linked_clone  = true
+  }
+`, g.GenerateResourceName(template))
+		} else if vm.Config.LinkedCloneOf != "" {
+			config += fmt.Sprintf(`
+  # Linked clone of %s, recreated as a full copy of its disks
+`, vm.Config.LinkedCloneOf)
+		}
+
+		// Video card settings are only settable as VMX options
+		if settings := g.VideoExtraConfig(vm); len(settings) > 0 {
+			config += "\n  extra_config = {\n"
+			for _, setting := range settings {
+				config += fmt.Sprintf("    \"%s\" = \"%s\"\n", setting[0], setting[1])
+			}
+			config += "  }\n"
+		}
+
+		// Add inferred ownership as custom attributes
+		if vm.Owner != "" || vm.Environment != "" {
+			config += "\n  custom_attributes = {\n"
+			if vm.Owner != "" {
+				config += fmt.Sprintf("    (data.vsphere_custom_attribute.owner.id) = \"%s\"\n", EscapeHCL(vm.Owner))
+			}
+			if vm.Environment != "" {
+				config += fmt.Sprintf("    (data.vsphere_custom_attribute.environment.id) = \"%s\"\n", EscapeHCL(vm.Environment))
+			}
+			config += "  }\n"
 		}
 
 		config += "}\n"
+
+		// Left commented out: the steps are prose and need a human to turn
+		// them into commands
+		if steps := PostProvisionSteps(vm); opts.EmitPostProvision && len(steps) > 0 {
+			config += fmt.Sprintf(`
+# Post-provision steps from annotations, see POST_PROVISION.md. Review,
+# replace the instructions with commands and uncomment to run them.
+# resource "null_resource" "%s_post_provision" {
+#   depends_on = [vsphere_virtual_machine.%s]
+#
+#   provisioner "remote-exec" {
+%s#     inline = []
+#   }
+# }
+`, resourceName, resourceName, postProvisionComment(steps, "#     # "))
+		}
+
 		vmConfigs = append(vmConfigs, config)
 	}
 
-	return strings.Join(vmConfigs, "\n")
+	return strings.Join(vmConfigs, "\n"), notes
 }
 
 // generateVMwareOutputs generates output definitions
-func (g *TerraformGenerator) generateVMwareOutputs(infra *models.Infrastructure) string {
+func (g *TerraformGenerator) generateVMwareOutputs(infra *models.Infrastructure, names []string) string {
 	outputs := `output "virtual_machines" {
   description = "Information about created virtual machines"
   value = {
 `
 
-	for _, vm := range infra.VirtualMachines {
-		if vm.Config.Template {
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
 			continue
 		}
-		resourceName := g.GenerateResourceName(vm.Name)
+		resourceName := names[i]
 		outputs += fmt.Sprintf(`    "%s" = {
       id   = vsphere_virtual_machine.%s.id
       name = vsphere_virtual_machine.%s.name
       ip   = vsphere_virtual_machine.%s.default_ip_address
     }
-`, vm.Name, resourceName, resourceName, resourceName)
+`, EscapeHCL(vm.Name), resourceName, resourceName, resourceName)
 	}
 
 	outputs += `  }
@@ -322,30 +736,65 @@ func (g *TerraformGenerator) generateVMwareOutputs(infra *models.Infrastructure)
 	return outputs
 }
 
-// generateProxmox generates Terraform files for Proxmox infrastructure
-func (g *TerraformGenerator) generateProxmox(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
-	// TODO: Implement Proxmox Terraform generation
-	g.Log().Info("Proxmox Terraform generation not yet implemented")
-	return []*GenerateResult{}, nil
-}
+// flattenResults concatenates all generated sections into a single main.tf,
+// preserving the order in which they were generated
+func (g *TerraformGenerator) flattenResults(results []*GenerateResult) []*GenerateResult {
+	if len(results) == 0 {
+		return results
+	}
+
+	var content strings.Builder
+	var resources []string
+	var others []*GenerateResult
+	providers := make(map[string]bool)
+	var providerNames []string
+
+	for _, result := range results {
+		// Sidecar files such as notes are kept as-is
+		if !strings.HasSuffix(result.Path, ".tf") {
+			others = append(others, result)
+			continue
+		}
 
-// generateNutanix generates Terraform files for Nutanix infrastructure
-func (g *TerraformGenerator) generateNutanix(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
-	// TODO: Implement Nutanix Terraform generation
-	g.Log().Info("Nutanix Terraform generation not yet implemented")
-	return []*GenerateResult{}, nil
+		if content.Len() > 0 {
+			content.WriteString("\n")
+		}
+		content.WriteString(fmt.Sprintf("# ---- %s ----\n", result.Path))
+		content.Write(result.Content)
+
+		resources = append(resources, result.Resources...)
+		if !providers[result.Provider] {
+			providers[result.Provider] = true
+			providerNames = append(providerNames, result.Provider)
+		}
+	}
+
+	flattened := content.String()
+	return append([]*GenerateResult{{
+		Path:      "main.tf",
+		Content:   []byte(flattened),
+		Size:      len(flattened),
+		Type:      "main",
+		Provider:  strings.Join(providerNames, ","),
+		Resources: resources,
+	}}, others...)
 }
 
 // writeFile writes a generate result to a file
 func (g *TerraformGenerator) writeFile(result *GenerateResult, outputDir string) error {
 	// Ensure output directory exists
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
+	dir := filepath.Dir(filepath.Join(outputDir, result.Path))
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Write file
 	filePath := filepath.Join(outputDir, result.Path)
-	if err := os.WriteFile(filePath, result.Content, 0644); err != nil {
+	mode := os.FileMode(0644)
+	if strings.HasSuffix(result.Path, ".sh") {
+		mode = 0755
+	}
+	if err := os.WriteFile(filePath, result.Content, mode); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
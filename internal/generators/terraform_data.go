@@ -0,0 +1,170 @@
+package generators
+
+import (
+	"path"
+	"sort"
+
+	"valhalla/internal/models"
+)
+
+// dataSource is a data block looking up one network or datastore
+type dataSource struct {
+	label string // the block's Terraform label
+	name  string // the name vSphere finds the object by
+	known bool   // whether the object was discovered, not just referred to
+}
+
+// dataSourceSet holds the data sources for one kind of object, keyed by
+// object ID. Discovered objects are found by their ID or name; references
+// to anything else get a data source of their own, looked up by the
+// reference, the first time they are seen.
+type dataSourceSet struct {
+	byKey  map[string]*dataSource
+	byRef  map[string]string
+	labels *ResourceNames
+}
+
+func newDataSourceSet(sanitize func(string) string) *dataSourceSet {
+	return &dataSourceSet{
+		byKey:  make(map[string]*dataSource),
+		byRef:  make(map[string]string),
+		labels: NewResourceNames(sanitize),
+	}
+}
+
+// add registers a discovered object, found by any of refs. Earlier objects
+// keep a reference they share with a later one.
+func (s *dataSourceSet) add(key, name string, refs ...string) {
+	s.byKey[key] = &dataSource{label: s.labels.Name(name), name: name, known: true}
+	for _, ref := range refs {
+		if _, taken := s.byRef[ref]; !taken && ref != "" {
+			s.byRef[ref] = key
+		}
+	}
+}
+
+// key returns the key of the data source ref resolves to
+func (s *dataSourceSet) key(ref string) string {
+	if key, ok := s.byRef[ref]; ok {
+		return key
+	}
+	s.byKey[ref] = &dataSource{label: s.labels.Name(ref), name: ref}
+	s.byRef[ref] = ref
+	return ref
+}
+
+// label returns the Terraform label of the data source ref resolves to
+func (s *dataSourceSet) label(ref string) string {
+	return s.byKey[s.key(ref)].label
+}
+
+// sorted returns the data sources with the given keys in label order
+func (s *dataSourceSet) sorted(keys map[string]bool) []*dataSource {
+	sources := make([]*dataSource, 0, len(keys))
+	for _, key := range s.sortedKeys(keys) {
+		sources = append(sources, s.byKey[key])
+	}
+	return sources
+}
+
+// sortedKeys returns the given keys in label order
+func (s *dataSourceSet) sortedKeys(keys map[string]bool) []string {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return s.byKey[sorted[i]].label < s.byKey[sorted[j]].label
+	})
+	return sorted
+}
+
+// vmwareDataSources names the vsphere_network and vsphere_datastore data
+// sources of one infrastructure. VMware disks refer to their datastore by
+// moref and network cards to their network by name, so both are resolved
+// to the discovered object, and each object gets exactly one data block.
+type vmwareDataSources struct {
+	networks   *dataSourceSet
+	datastores *dataSourceSet
+
+	// created holds, by network key, the resources --create-networks
+	// creates the network with; its data source waits for them
+	created map[string][]string
+}
+
+// newVMwareDataSources labels every discovered network and datastore, in
+// name order so that labels don't depend on which of them are used
+func (g *TerraformGenerator) newVMwareDataSources(infra *models.Infrastructure) *vmwareDataSources {
+	d := &vmwareDataSources{
+		networks:   newDataSourceSet(g.GenerateResourceName),
+		datastores: newDataSourceSet(g.GenerateResourceName),
+		created:    make(map[string][]string),
+	}
+
+	// Diskless VMs use a datastore data source of this name
+	d.datastores.labels.Name("default_datastore")
+
+	storage := append([]models.Storage(nil), infra.Storage...)
+	sort.SliceStable(storage, func(i, j int) bool {
+		if storage[i].Name != storage[j].Name {
+			return storage[i].Name < storage[j].Name
+		}
+		return storage[i].ID < storage[j].ID
+	})
+	for _, s := range storage {
+		key := s.ID
+		if key == "" {
+			key = s.Name
+		}
+		d.datastores.add(key, s.Name, key, s.Name)
+	}
+
+	// VMware network names are inventory paths; network cards and the
+	// provider's lookups use the base name
+	networks := append([]models.Network(nil), infra.Networks...)
+	sort.SliceStable(networks, func(i, j int) bool {
+		if path.Base(networks[i].Name) != path.Base(networks[j].Name) {
+			return path.Base(networks[i].Name) < path.Base(networks[j].Name)
+		}
+		return networks[i].ID < networks[j].ID
+	})
+	for _, n := range networks {
+		key := n.ID
+		if key == "" {
+			key = n.Name
+		}
+		d.networks.add(key, path.Base(n.Name), key, n.Name, path.Base(n.Name))
+	}
+
+	return d
+}
+
+// diskDatastore returns the reference to a disk's datastore, its ID where
+// recorded
+func diskDatastore(disk models.Disk) string {
+	if disk.DatastoreID != "" {
+		return disk.DatastoreID
+	}
+	return disk.Datastore
+}
+
+// nicNetwork returns the reference to a network card's network: the
+// portgroup key of a discovered distributed portgroup, which is its ID, or
+// the name. Portgroup names need not be unique across distributed switches.
+func (d *vmwareDataSources) nicNetwork(nic models.NetworkCard) string {
+	if key, _ := nic.Metadata["portgroup_key"].(string); key != "" {
+		if _, ok := d.networks.byRef[key]; ok {
+			return key
+		}
+	}
+	return nic.Network
+}
+
+// primaryDatastore returns the reference to the datastore a VM is placed
+// on, that of its first disk, or "" when there is none to follow
+func primaryDatastore(vm models.VirtualMachine) string {
+	if len(vm.Disks) == 0 {
+		return ""
+	}
+	return diskDatastore(vm.Disks[0])
+}
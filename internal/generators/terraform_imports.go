@@ -0,0 +1,159 @@
+package generators
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// terraformImport adopts one existing resource into Terraform state
+type terraformImport struct {
+	Address string // resource address, e.g. vsphere_virtual_machine.web_01
+	ID      string // provider import ID; empty when it can't be derived
+	Note    string // comment shown above the import, if any
+}
+
+// importResults renders imports as imports.tf, import blocks for Terraform
+// 1.5 and later, and import.sh, which runs the same imports with terraform
+// import for older versions. Networks, datastores and storage are read
+// through data sources or referenced by name, so only VMs are imported.
+func (g *TerraformGenerator) importResults(imports []terraformImport, provider string) []*GenerateResult {
+	blocks := `# Import blocks adopting the discovered VMs into Terraform state, so that
+# terraform plan compares them instead of creating duplicates (Terraform 1.5+).
+# Networks and storage are not managed here and need no import.
+`
+	script := `#!/usr/bin/env bash
+# Imports the discovered VMs into Terraform state for Terraform older than
+# 1.5, which does not understand import blocks: remove imports.tf, run
+# terraform init, then run this script from this directory.
+set -euo pipefail
+`
+
+	var skipped []string
+	for _, imp := range imports {
+		if imp.ID == "" {
+			skipped = append(skipped, imp.Address)
+			note := fmt.Sprintf("\n# %s: %s, import it by hand\n", imp.Address, imp.Note)
+			blocks += note
+			script += note
+			continue
+		}
+
+		note := ""
+		if imp.Note != "" {
+			note = "# " + imp.Note + "\n"
+		}
+		blocks += fmt.Sprintf(`
+%simport {
+  to = %s
+  id = "%s"
+}
+`, note, imp.Address, EscapeHCL(imp.ID))
+		script += fmt.Sprintf("\nterraform import %s %s\n", shellQuote(imp.Address), shellQuote(imp.ID))
+	}
+	if len(skipped) > 0 {
+		g.Log().Warn("VMs without an import ID need importing by hand", "resources", strings.Join(skipped, ", "))
+	}
+
+	return []*GenerateResult{{
+		Path:      "imports.tf",
+		Content:   []byte(blocks),
+		Size:      len(blocks),
+		Type:      "imports",
+		Provider:  provider,
+		Resources: []string{},
+	}, {
+		Path:      "import.sh",
+		Content:   []byte(script),
+		Size:      len(script),
+		Type:      "script",
+		Provider:  provider,
+		Resources: []string{},
+	}}
+}
+
+// vmwareImports lists the imports of the generated vSphere VMs. The
+// provider imports by inventory path; the UUID is noted to identify the VM
+// in case it has moved since discovery.
+func (g *TerraformGenerator) vmwareImports(infra *models.Infrastructure, names []string) []terraformImport {
+	var imports []terraformImport
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+
+		imp := terraformImport{Address: "vsphere_virtual_machine." + names[i], ID: vmInventoryPath(infra, vm)}
+		switch {
+		case imp.ID == "":
+			imp.Note = "no folder or datacenter was discovered"
+		case vm.Config.UUID != "":
+			imp.Note = "uuid: " + vm.Config.UUID
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// proxmoxImports lists the imports of the generated Proxmox VMs, which the
+// bpg/proxmox provider imports as node/vmid
+func (g *TerraformGenerator) proxmoxImports(infra *models.Infrastructure, names []string) []terraformImport {
+	var imports []terraformImport
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+
+		imp := terraformImport{Address: "proxmox_virtual_environment_vm." + names[i]}
+		node := vm.Host
+		if node == "" {
+			node = infra.Node
+		}
+		if node != "" && vm.Metadata["vmid"] != nil {
+			imp.ID = fmt.Sprintf("%s/%v", node, vm.Metadata["vmid"])
+		} else {
+			imp.Note = "no node or VM ID was discovered"
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// nutanixImports lists the imports of the generated Nutanix VMs, which the
+// provider imports by VM UUID
+func (g *TerraformGenerator) nutanixImports(infra *models.Infrastructure, names []string) []terraformImport {
+	var imports []terraformImport
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template {
+			continue
+		}
+
+		imp := terraformImport{Address: "nutanix_virtual_machine." + names[i], ID: vm.Config.UUID}
+		if imp.ID == "" {
+			imp.Note = "no VM UUID was discovered"
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// vmInventoryPath returns the vCenter inventory path of a VM, such as
+// /DC0/vm/web/web-01. Discovered folders are already escaped; a "/" in the
+// VM name is escaped the same way.
+func vmInventoryPath(infra *models.Infrastructure, vm models.VirtualMachine) string {
+	name := strings.ReplaceAll(vm.Name, "/", "%2f")
+	switch {
+	case vm.Folder != "":
+		return path.Join(vm.Folder, name)
+	case infra.Datacenter != "":
+		return "/" + infra.Datacenter + "/vm/" + name
+	default:
+		return ""
+	}
+}
+
+// shellQuote quotes a value as a single shell word
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,329 @@
+package generators
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// generateVMwareNetworks creates the port groups of the networks looked up
+// in data.tf, for --create-networks, with their discovered VLAN and security
+// policy. Distributed port groups are created on their switch, which is
+// looked up rather than created; standard port groups are created on every
+// host they were discovered on, each with that host's settings where hosts
+// disagreed. The network data sources wait for the port groups through
+// data.created, so the VMs keep referring to them.
+func (g *TerraformGenerator) generateVMwareNetworks(infra *models.Infrastructure, data *vmwareDataSources, opts GenerateOptions) (string, []string) {
+	discovered := make(map[string]models.Network, len(infra.Networks))
+	for _, network := range infra.Networks {
+		key := network.ID
+		if key == "" {
+			key = network.Name
+		}
+		discovered[key] = network
+	}
+
+	config := `# Port groups recreated from discovery with their VLAN and security policy.
+# The switches they are on are looked up, not created.
+`
+	var resources []string
+	switches := make(map[string]string)
+	hosts := make(map[string]string)
+	labels := NewResourceNames(g.GenerateResourceName)
+
+	// The full catalog in data.tf looks up the discovered hosts already
+	catalog := make(map[string]string)
+	if opts.IncludeAllDataSources {
+		for i, label := range g.catalogHostLabels(infra, labels) {
+			if _, ok := catalog[infra.Hosts[i].Name]; !ok {
+				catalog[infra.Hosts[i].Name] = label
+			}
+		}
+	}
+
+	networks, _ := g.selectedVMwareData(infra, data, opts)
+	for _, key := range data.networks.sortedKeys(networks) {
+		network, ok := discovered[key]
+		if !ok {
+			continue
+		}
+		source := data.networks.byKey[key]
+
+		switch network.Type {
+		case "distributed":
+			if network.VSwitch == "" {
+				config += fmt.Sprintf("\n# %s not created: its distributed switch was not discovered\n", commentSafe(network.Name))
+				continue
+			}
+			if _, ok := switches[network.VSwitch]; !ok {
+				switches[network.VSwitch] = labels.Name("dvs_" + network.VSwitch)
+			}
+			config += g.distributedPortGroup(network, source.label, switches[network.VSwitch])
+			data.created[key] = append(data.created[key], "vsphere_distributed_port_group."+source.label)
+			resources = appendUnique(resources, "vsphere_distributed_port_group")
+
+		case "standard":
+			names := standardPortGroupHosts(network)
+			switch {
+			case len(names) == 0:
+				config += fmt.Sprintf("\n# %s not created: the hosts it is on were not discovered\n", commentSafe(network.Name))
+				continue
+			case network.VSwitch == "":
+				config += fmt.Sprintf("\n# %s not created: hosts have it on differently named switches\n", commentSafe(network.Name))
+				g.Log().Warn("Port group is on differently named switches across hosts; not creating it", "network", network.Name)
+				continue
+			}
+
+			vlans, vlansMixed := hostVLANs(network)
+			policies, policiesMixed := hostSecurityPolicies(network)
+			if vlansMixed || policiesMixed {
+				g.Log().Warn("Hosts disagree on a port group's settings; creating each host's as discovered", "network", network.Name)
+				config += fmt.Sprintf("\n# Hosts disagree on the VLAN or security policy of %s; each host's port\n# group is created with its own settings rather than an average\n", commentSafe(network.Name))
+			}
+			for _, host := range names {
+				hostLabel, ok := catalog[host]
+				if !ok {
+					if _, ok := hosts[host]; !ok {
+						hosts[host] = labels.Name("host_" + host)
+					}
+					hostLabel = hosts[host]
+				}
+				vlan, ok := vlans[host]
+				if !ok {
+					vlan = standardVLAN(network)
+				}
+				policy, ok := policies[host]
+				if !ok {
+					policy = network.SecurityPolicy
+				}
+				label := labels.Name(source.label + "_" + host)
+				config += g.hostPortGroup(network, label, hostLabel, vlan, policy)
+				data.created[key] = append(data.created[key], "vsphere_host_port_group."+label)
+			}
+			resources = appendUnique(resources, "vsphere_host_port_group")
+
+		default:
+			config += fmt.Sprintf("\n# %s not created: %s networks are managed outside vSphere\n", commentSafe(network.Name), commentSafe(network.Type))
+		}
+	}
+
+	for _, name := range sortedKeys(switches) {
+		config += fmt.Sprintf(`
+data "vsphere_distributed_virtual_switch" "%s" {
+  name          = "%s"
+  datacenter_id = data.vsphere_datacenter.dc.id
+}
+`, switches[name], EscapeHCL(name))
+	}
+	for _, name := range sortedKeys(hosts) {
+		config += fmt.Sprintf(`
+data "vsphere_host" "%s" {
+  name          = "%s"
+  datacenter_id = data.vsphere_datacenter.dc.id
+}
+`, hosts[name], EscapeHCL(name))
+	}
+
+	return config, resources
+}
+
+// distributedPortGroup returns the resource creating a distributed port
+// group on the switch looked up as dvs
+func (g *TerraformGenerator) distributedPortGroup(network models.Network, label, dvs string) string {
+	config := fmt.Sprintf(`
+resource "vsphere_distributed_port_group" "%s" {
+  name                            = "%s"
+  distributed_virtual_switch_uuid = data.vsphere_distributed_virtual_switch.%s.id
+`, label, EscapeHCL(path.Base(network.Name)), dvs)
+
+	if network.MTU > 0 {
+		config += fmt.Sprintf("  # %s had an MTU of %d, set on the switch\n", commentSafe(network.VSwitch), network.MTU)
+	}
+
+	switch trunk := metadataStrings(network.Metadata["vlan_trunk"]); {
+	case len(trunk) > 0:
+		for _, r := range trunk {
+			min, max := vlanRange(r)
+			config += fmt.Sprintf(`
+  vlan_range {
+    min_vlan = %d
+    max_vlan = %d
+  }
+`, min, max)
+		}
+	case metadataInt(network.Metadata["pvlan_id"]) > 0:
+		config += fmt.Sprintf("\n  port_private_secondary_vlan_id = %d\n", metadataInt(network.Metadata["pvlan_id"]))
+	case network.VLAN > 0:
+		config += fmt.Sprintf("\n  vlan_id = %d\n", network.VLAN)
+	}
+
+	return config + securityPolicyAttributes(network.SecurityPolicy) + "}\n"
+}
+
+// hostPortGroup returns the resource creating a standard port group on the
+// host looked up as host
+func (g *TerraformGenerator) hostPortGroup(network models.Network, label, host string, vlan int, policy *models.SecurityPolicy) string {
+	config := fmt.Sprintf(`
+resource "vsphere_host_port_group" "%s" {
+  name                = "%s"
+  host_system_id      = data.vsphere_host.%s.id
+  virtual_switch_name = "%s"
+`, label, EscapeHCL(path.Base(network.Name)), host, EscapeHCL(network.VSwitch))
+
+	if network.MTU > 0 {
+		config += fmt.Sprintf("  # %s had an MTU of %d, set on the switch\n", commentSafe(network.VSwitch), network.MTU)
+	}
+	if vlan > 0 {
+		config += fmt.Sprintf("\n  vlan_id = %d\n", vlan)
+	}
+
+	return config + securityPolicyAttributes(policy) + "}\n"
+}
+
+// securityPolicyAttributes sets a port group's security policy; without a
+// discovered policy the port group inherits its switch's
+func securityPolicyAttributes(policy *models.SecurityPolicy) string {
+	if policy == nil {
+		return "\n  # Security policy not discovered; inherited from the switch\n"
+	}
+	return fmt.Sprintf(`
+  allow_promiscuous      = %t
+  allow_mac_changes      = %t
+  allow_forged_transmits = %t
+`, policy.AllowPromiscuous, policy.MACChanges, policy.ForgedTransmits)
+}
+
+// standardPortGroupHosts returns the hosts a standard port group was
+// discovered on
+func standardPortGroupHosts(network models.Network) []string {
+	hosts := metadataStrings(network.Metadata["hosts"])
+	if len(hosts) == 0 {
+		// Discoveries predating the host list still name the hosts whose
+		// policies differ
+		policies, _ := hostSecurityPolicies(network)
+		for host := range policies {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// standardVLAN returns the VLAN ID of a standard port group, 4095 when it
+// trunks every VLAN
+func standardVLAN(network models.Network) int {
+	if len(metadataStrings(network.Metadata["vlan_trunk"])) > 0 {
+		return 4095
+	}
+	return network.VLAN
+}
+
+// hostVLANs returns each host's VLAN ID of a standard port group the hosts
+// disagree on, and whether they do
+func hostVLANs(network models.Network) (map[string]int, bool) {
+	vlans := make(map[string]int)
+	switch byHost := network.Metadata["host_vlans"].(type) {
+	case map[string]int:
+		for host, vlan := range byHost {
+			vlans[host] = vlan
+		}
+	case map[string]interface{}:
+		// Read back from a saved discovery
+		for host, vlan := range byHost {
+			vlans[host] = metadataInt(vlan)
+		}
+	}
+	return vlans, len(vlans) > 0
+}
+
+// hostSecurityPolicies returns each host's security policy of a standard
+// port group the hosts disagree on, and whether they do
+func hostSecurityPolicies(network models.Network) (map[string]*models.SecurityPolicy, bool) {
+	policies := make(map[string]*models.SecurityPolicy)
+	switch byHost := network.Metadata["host_security_policies"].(type) {
+	case map[string]models.SecurityPolicy:
+		for host, policy := range byHost {
+			policy := policy
+			policies[host] = &policy
+		}
+	case map[string]interface{}:
+		// Read back from a saved discovery
+		for host, value := range byHost {
+			policy, _ := value.(map[string]interface{})
+			policies[host] = &models.SecurityPolicy{
+				AllowPromiscuous: policy["allow_promiscuous"] == true,
+				MACChanges:       policy["mac_changes"] == true,
+				ForgedTransmits:  policy["forged_transmits"] == true,
+			}
+		}
+	}
+	return policies, len(policies) > 0
+}
+
+// vlanRange parses a trunk range recorded as "100" or "100-199"
+func vlanRange(r string) (int, int) {
+	first, last, found := strings.Cut(r, "-")
+	min, _ := strconv.Atoi(strings.TrimSpace(first))
+	if !found {
+		return min, min
+	}
+	max, _ := strconv.Atoi(strings.TrimSpace(last))
+	return min, max
+}
+
+// metadataStrings returns a list of strings from metadata, as discovered or
+// read back from a saved discovery
+func metadataStrings(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return append([]string(nil), list...)
+	case []interface{}:
+		strs := make([]string, 0, len(list))
+		for _, item := range list {
+			strs = append(strs, fmt.Sprint(item))
+		}
+		return strs
+	}
+	return nil
+}
+
+// metadataInt returns a number from metadata, as discovered or read back
+// from a saved discovery
+func metadataInt(value interface{}) int {
+	switch n := value.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// commentSafe keeps a discovered value on one comment line
+func commentSafe(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+// appendUnique appends value unless list holds it already
+func appendUnique(list []string, value string) []string {
+	if contains(list, value) {
+		return list
+	}
+	return append(list, value)
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
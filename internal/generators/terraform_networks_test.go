@@ -0,0 +1,165 @@
+package generators
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// networkFixture returns the VMware fixture with a standard port group on
+// two hosts disagreeing on its security policy, distributed port groups
+// with a VLAN and a trunk, and an NSX segment. Only one of the hosts is in
+// the host inventory.
+func networkFixture() *models.Infrastructure {
+	infra := vmwareFixture()
+	infra.Hosts = []models.Host{{ID: "host-1", Name: "esx-01"}}
+	infra.Networks = []models.Network{
+		{
+			ID: "network-1", Name: "/DC1/network/VM Network", Type: "standard", VLAN: 10, VSwitch: "vSwitch0", MTU: 1500,
+			SecurityPolicy: &models.SecurityPolicy{ForgedTransmits: true},
+			Metadata: map[string]interface{}{
+				"hosts": []string{"esx-01", "esx-02"},
+				"host_security_policies": map[string]models.SecurityPolicy{
+					"esx-01": {},
+					"esx-02": {ForgedTransmits: true},
+				},
+			},
+		},
+		{
+			ID: "dvportgroup-1", Name: "/DC1/network/Prod", Type: "distributed", VLAN: 100, VSwitch: "DSwitch", MTU: 9000,
+			SecurityPolicy: &models.SecurityPolicy{AllowPromiscuous: true},
+		},
+		{
+			ID: "dvportgroup-2", Name: "/DC1/network/Trunk", Type: "distributed", VSwitch: "DSwitch",
+			SecurityPolicy: &models.SecurityPolicy{},
+			Metadata:       map[string]interface{}{"vlan_trunk": []string{"100-199", "300"}},
+		},
+		{ID: "network-o1", Name: "/DC1/network/NSX Segment", Type: "opaque"},
+	}
+	return infra
+}
+
+func TestTerraformCreateNetworks(t *testing.T) {
+	infra := networkFixture()
+
+	// Saved discoveries carry the metadata as decoded JSON
+	data, err := json.Marshal(infra)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved models.Infrastructure
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, infra := range map[string]*models.Infrastructure{"discovered": infra, "saved": &saved} {
+		t.Run(name, func(t *testing.T) {
+			files := generate(t, "terraform", []*models.Infrastructure{infra}, GenerateOptions{CreateNetworks: true, IncludeAllDataSources: true})
+			checkHCL(t, files)
+			networks := files["networks.tf"]
+
+			// Each host keeps its own policy rather than the merged one
+			for host, forged := range map[string]string{"esx_01": "false", "esx_02": "true"} {
+				block := portGroupBlock(t, networks, "vsphere_host_port_group", "vm_network_"+host)
+				for _, want := range []string{
+					`name                = "VM Network"`,
+					"host_system_id      = data.vsphere_host.host_" + host + ".id",
+					`virtual_switch_name = "vSwitch0"`,
+					"vlan_id = 10",
+					"allow_promiscuous      = false",
+					"allow_forged_transmits = " + forged,
+				} {
+					if !strings.Contains(block, want) {
+						t.Errorf("host port group on %s lacks %q:\n%s", host, want, block)
+					}
+				}
+			}
+			if !strings.Contains(networks, "Hosts disagree on the VLAN or security policy of /DC1/network/VM Network") {
+				t.Errorf("mixed host policies are not reported:\n%s", networks)
+			}
+
+			prod := portGroupBlock(t, networks, "vsphere_distributed_port_group", "prod")
+			for _, want := range []string{
+				"distributed_virtual_switch_uuid = data.vsphere_distributed_virtual_switch.dvs_dswitch.id",
+				"vlan_id = 100",
+				"allow_promiscuous      = true",
+				"allow_mac_changes      = false",
+				"DSwitch had an MTU of 9000",
+			} {
+				if !strings.Contains(prod, want) {
+					t.Errorf("distributed port group lacks %q:\n%s", want, prod)
+				}
+			}
+
+			trunk := portGroupBlock(t, networks, "vsphere_distributed_port_group", "trunk")
+			if !strings.Contains(trunk, "min_vlan = 100\n    max_vlan = 199") || !strings.Contains(trunk, "min_vlan = 300\n    max_vlan = 300") {
+				t.Errorf("trunk ranges are not reproduced:\n%s", trunk)
+			}
+
+			if strings.Contains(networks, `"nsx_segment"`) || !strings.Contains(networks, "NSX Segment not created") {
+				t.Errorf("opaque network is created:\n%s", networks)
+			}
+
+			// The host the full catalog looks up is not looked up twice
+			if !strings.Contains(files["data.tf"], `data "vsphere_host" "host_esx_01"`) || strings.Contains(networks, `data "vsphere_host" "host_esx_01"`) {
+				t.Errorf("host esx-01 is not looked up once, in data.tf:\n%s\n%s", files["data.tf"], networks)
+			}
+
+			// The VMs keep using the lookups, which wait for the port groups
+			if !strings.Contains(files["data.tf"], "depends_on = [vsphere_host_port_group.vm_network_esx_01, vsphere_host_port_group.vm_network_esx_02]") {
+				t.Errorf("network data source does not wait for its port groups:\n%s", files["data.tf"])
+			}
+			if !strings.Contains(files["data.tf"], "depends_on = [vsphere_distributed_port_group.prod]") {
+				t.Errorf("distributed network data source does not wait for its port group:\n%s", files["data.tf"])
+			}
+		})
+	}
+}
+
+func TestTerraformCreateNetworksOnlyReferenced(t *testing.T) {
+	files := generate(t, "terraform", []*models.Infrastructure{networkFixture()}, GenerateOptions{CreateNetworks: true})
+	checkHCL(t, files)
+
+	networks := files["networks.tf"]
+	if !strings.Contains(networks, `"vm_network_esx_01"`) {
+		t.Errorf("port group the VMs use is not created:\n%s", networks)
+	}
+	if strings.Contains(networks, "vsphere_distributed") {
+		t.Errorf("port groups no VM uses are created:\n%s", networks)
+	}
+
+	if files := generate(t, "terraform", []*models.Infrastructure{networkFixture()}, GenerateOptions{}); files["networks.tf"] != "" {
+		t.Errorf("networks.tf generated without --create-networks:\n%s", files["networks.tf"])
+	}
+}
+
+func TestTerraformCreateNetworksWithoutHosts(t *testing.T) {
+	infra := networkFixture()
+	infra.Networks[0].Metadata = nil
+
+	files := generate(t, "terraform", []*models.Infrastructure{infra}, GenerateOptions{CreateNetworks: true})
+	checkHCL(t, files)
+	if strings.Contains(files["networks.tf"], "vsphere_host_port_group") || !strings.Contains(files["networks.tf"], "hosts it is on were not discovered") {
+		t.Errorf("port group created without knowing its hosts:\n%s", files["networks.tf"])
+	}
+	if strings.Contains(files["data.tf"], "depends_on") {
+		t.Errorf("data source waits for a port group that is not created:\n%s", files["data.tf"])
+	}
+}
+
+// portGroupBlock returns the resource of the given type named name
+func portGroupBlock(t *testing.T, content, resourceType, name string) string {
+	t.Helper()
+
+	start := strings.Index(content, `resource "`+resourceType+`" "`+name+`" {`)
+	if start < 0 {
+		t.Fatalf("no %s %s in:\n%s", resourceType, name, content)
+	}
+	end := strings.Index(content[start:], "\n}\n")
+	if end < 0 {
+		return content[start:]
+	}
+	return content[start : start+end]
+}
@@ -0,0 +1,361 @@
+package generators
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"valhalla/internal/models"
+	"valhalla/internal/units"
+)
+
+// nutanixDefaultPort is the Prism API port
+const nutanixDefaultPort = 9440
+
+// generateNutanix generates Terraform files for Nutanix infrastructure using
+// the nutanix/nutanix provider. Each VM becomes a nutanix_virtual_machine on
+// its discovered cluster, with disks on their storage containers and NICs on
+// subnets looked up by name.
+func (g *TerraformGenerator) generateNutanix(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
+	provider := g.generateNutanixProvider()
+	variables := g.generateNutanixVariables(infra)
+	data := g.generateNutanixDataSources(infra)
+	results := []*GenerateResult{{
+		Path:      "provider.tf",
+		Content:   []byte(provider),
+		Size:      len(provider),
+		Type:      "provider",
+		Provider:  "nutanix",
+		Resources: []string{"nutanix"},
+	}, {
+		Path:      "variables.tf",
+		Content:   []byte(variables),
+		Size:      len(variables),
+		Type:      "variables",
+		Provider:  "nutanix",
+		Resources: []string{},
+	}, {
+		Path:      "data.tf",
+		Content:   []byte(data),
+		Size:      len(data),
+		Type:      "data",
+		Provider:  "nutanix",
+		Resources: []string{},
+	}}
+
+	names := NewResourceNames(g.GenerateResourceName)
+	vmNames := names.VMs(infra.VirtualMachines)
+
+	if vms, _ := countGuests(infra.VirtualMachines); vms > 0 {
+		resources, notes := g.generateNutanixVMs(infra, vmNames, opts)
+		results = append(results, &GenerateResult{
+			Path:      "virtual_machines.tf",
+			Content:   []byte(resources),
+			Size:      len(resources),
+			Type:      "resources",
+			Provider:  "nutanix",
+			Resources: []string{"nutanix_virtual_machine"},
+			Metadata:  map[string]interface{}{"resource_names": names.Mapping},
+		})
+		results = append(results, notes...)
+
+		if opts.WithImports {
+			results = append(results, g.importResults(g.nutanixImports(infra, vmNames), "nutanix")...)
+		}
+	}
+
+	if opts.EmitPostProvision {
+		if doc := PostProvisionDoc(infra.VirtualMachines, "nutanix"); doc != nil {
+			results = append(results, doc)
+		}
+	}
+
+	outputs := g.generateNutanixOutputs(infra, vmNames)
+	results = append(results, &GenerateResult{
+		Path:      "outputs.tf",
+		Content:   []byte(outputs),
+		Size:      len(outputs),
+		Type:      "outputs",
+		Provider:  "nutanix",
+		Resources: []string{},
+	})
+
+	return results, nil
+}
+
+// generateNutanixProvider generates the nutanix/nutanix provider configuration
+func (g *TerraformGenerator) generateNutanixProvider() string {
+	return `terraform {
+  required_providers {
+    nutanix = {
+      source  = "nutanix/nutanix"
+      version = ">= 1.9.0"
+    }
+  }
+  required_version = ">= 1.0"
+}
+
+provider "nutanix" {
+  endpoint = var.nutanix_endpoint
+  port     = var.nutanix_port
+  username = var.nutanix_username
+  password = var.nutanix_password
+  insecure = var.nutanix_insecure
+}
+`
+}
+
+// generateNutanixVariables generates the Prism connection variables,
+// defaulting to the server discovery ran against
+func (g *TerraformGenerator) generateNutanixVariables(infra *models.Infrastructure) string {
+	endpoint, port := nutanixEndpoint(infra.Server)
+	return fmt.Sprintf(`variable "nutanix_endpoint" {
+  description = "Prism Central or Prism Element host name or IP"
+  type        = string
+  default     = "%s"
+}
+
+variable "nutanix_port" {
+  description = "Prism API port"
+  type        = number
+  default     = %d
+}
+
+variable "nutanix_username" {
+  description = "Prism username"
+  type        = string
+}
+
+variable "nutanix_password" {
+  description = "Prism password"
+  type        = string
+  sensitive   = true
+}
+
+variable "nutanix_insecure" {
+  description = "Allow unverified SSL certificates"
+  type        = bool
+  default     = true
+}
+`, EscapeHCL(endpoint), port)
+}
+
+// generateNutanixDataSources looks up the clusters and subnets the
+// generated VMs refer to
+func (g *TerraformGenerator) generateNutanixDataSources(infra *models.Infrastructure) string {
+	clusters := make(map[string]bool)
+	subnets := make(map[string]bool)
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template {
+			continue
+		}
+		if cluster := nutanixCluster(infra, vm); cluster != "" {
+			clusters[cluster] = true
+		}
+		for _, nic := range vm.NetworkCards {
+			if nic.Network != "" {
+				subnets[nic.Network] = true
+			}
+		}
+	}
+
+	var blocks []string
+	for _, cluster := range sortedNames(clusters) {
+		blocks = append(blocks, fmt.Sprintf(`data "nutanix_cluster" "%s" {
+  name = "%s"
+}
+`, g.GenerateResourceName(cluster), EscapeHCL(cluster)))
+	}
+	for _, subnet := range sortedNames(subnets) {
+		blocks = append(blocks, fmt.Sprintf(`data "nutanix_subnet" "%s" {
+  subnet_name = "%s"
+}
+`, g.GenerateResourceName(subnet), EscapeHCL(subnet)))
+	}
+	return strings.Join(blocks, "\n")
+}
+
+// generateNutanixVMs generates a nutanix_virtual_machine resource per VM,
+// returning any annotation sidecar notes alongside
+func (g *TerraformGenerator) generateNutanixVMs(infra *models.Infrastructure, names []string, opts GenerateOptions) (string, []*GenerateResult) {
+	var configs []string
+	var notes []*GenerateResult
+	var unplaced []string
+
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template {
+			continue
+		}
+
+		resourceName := names[i]
+		comments, sidecar := g.AnnotationComments(vm, resourceName, "#", opts.MaxFieldSize)
+		if sidecar != nil {
+			sidecar.Provider = "nutanix"
+			notes = append(notes, sidecar)
+		}
+
+		config := comments + fmt.Sprintf("resource \"nutanix_virtual_machine\" \"%s\" {\n", resourceName)
+
+		cluster := nutanixCluster(infra, vm)
+		if cluster == "" {
+			unplaced = append(unplaced, vm.Name)
+			config += "  # No cluster was discovered for this VM: set cluster_uuid\n"
+		}
+
+		perSocket := vm.Hardware.NumCoresPerSocket
+		if perSocket <= 0 || vm.CPUs%perSocket != 0 {
+			perSocket = vm.CPUs
+		}
+		sockets := 1
+		if perSocket > 0 {
+			sockets = vm.CPUs / perSocket
+		}
+
+		attributes := [][2]string{{"name", fmt.Sprintf("\"%s\"", EscapeHCL(vm.Name))}}
+		if cluster != "" {
+			attributes = append(attributes, [2]string{"cluster_uuid", fmt.Sprintf("data.nutanix_cluster.%s.id", g.GenerateResourceName(cluster))})
+		}
+		attributes = append(attributes,
+			[2]string{"num_sockets", strconv.Itoa(sockets)},
+			[2]string{"num_vcpus_per_socket", strconv.Itoa(perSocket)},
+			[2]string{"memory_size_mib", strconv.FormatInt(vm.Memory, 10)},
+		)
+		if strings.EqualFold(vm.Hardware.Firmware, "EFI") {
+			attributes = append(attributes, [2]string{"boot_type", "\"UEFI\""})
+		}
+		if strings.EqualFold(vm.PowerState, "OFF") {
+			attributes = append(attributes, [2]string{"power_state", "\"OFF\""})
+		}
+		config += alignedAttributes("  ", attributes)
+
+		// Categories were discovered as Category:Value tags
+		for _, tag := range vm.Tags {
+			key, value, ok := strings.Cut(tag, ":")
+			if !ok {
+				continue
+			}
+			config += fmt.Sprintf(`
+  categories {
+    name  = "%s"
+    value = "%s"
+  }
+`, EscapeHCL(key), EscapeHCL(value))
+		}
+
+		// Disks are recreated empty on the container they were discovered on
+		for _, disk := range vm.Disks {
+			if disk.DatastoreID == "" {
+				config += fmt.Sprintf("\n  # Disk %s omitted: no storage container was discovered for it\n", disk.ID)
+				continue
+			}
+			adapter := strings.ToUpper(disk.Controller)
+			if adapter == "" {
+				adapter = "SCSI"
+			}
+			config += fmt.Sprintf(`
+  disk_list {
+    disk_size_mib = %d
+
+    device_properties {
+      device_type  = "DISK"
+      disk_address = {
+        adapter_type = "%s"
+        device_index = %d
+      }
+    }
+
+    # %s
+    storage_config {
+      storage_container_reference {
+        kind = "storage_container"
+        uuid = "%s"
+      }
+    }
+  }
+`, units.FromGiB(disk.Size).ToMiB(), adapter, disk.Unit, EscapeHCL(disk.Datastore), EscapeHCL(disk.DatastoreID))
+		}
+
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			if nic.Network == "" {
+				config += fmt.Sprintf("\n  # NIC %s omitted: no subnet was discovered for it\n", nic.ID)
+				continue
+			}
+			attributes := [][2]string{{"subnet_uuid", fmt.Sprintf("data.nutanix_subnet.%s.id", g.GenerateResourceName(nic.Network))}}
+			if opts.PreserveMAC && nic.MACAddress != "" {
+				attributes = append(attributes, [2]string{"mac_address", fmt.Sprintf("\"%s\"", nic.MACAddress)})
+			}
+			if nic.Type != "" && nic.Type != models.NICTypeUnknown {
+				attributes = append(attributes, [2]string{"model", fmt.Sprintf("\"%s\"", strings.ToUpper(nic.Type))})
+			}
+			config += "\n  nic_list {\n" + alignedAttributes("    ", attributes) + "  }\n"
+		}
+
+		config += "}\n"
+		configs = append(configs, config)
+	}
+
+	if len(unplaced) > 0 {
+		sort.Strings(unplaced)
+		g.Log().Warn("Nutanix VMs without a discovered cluster need cluster_uuid set by hand", "vms", strings.Join(unplaced, ", "))
+	}
+	return strings.Join(configs, "\n"), notes
+}
+
+// generateNutanixOutputs generates outputs for the generated VMs
+func (g *TerraformGenerator) generateNutanixOutputs(infra *models.Infrastructure, names []string) string {
+	outputs := `output "virtual_machines" {
+  description = "Information about created virtual machines"
+  value = {
+`
+
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template {
+			continue
+		}
+		resourceName := names[i]
+		outputs += fmt.Sprintf(`    "%s" = {
+      id           = nutanix_virtual_machine.%s.id
+      cluster_uuid = nutanix_virtual_machine.%s.cluster_uuid
+      nic_list     = nutanix_virtual_machine.%s.nic_list_status
+    }
+`, EscapeHCL(vm.Name), resourceName, resourceName, resourceName)
+	}
+
+	outputs += `  }
+}
+`
+
+	return outputs
+}
+
+// nutanixCluster returns the cluster a VM is placed on: the cluster
+// discovery was scoped to, or else the cluster the VM was discovered on
+func nutanixCluster(infra *models.Infrastructure, vm models.VirtualMachine) string {
+	if infra.Cluster != "" {
+		return infra.Cluster
+	}
+	cluster, _ := vm.Metadata["cluster"].(string)
+	return cluster
+}
+
+// nutanixEndpoint splits a configured Prism server, a host name or a URL,
+// into the host and port the provider takes
+func nutanixEndpoint(server string) (string, int) {
+	if server == "" {
+		return "", nutanixDefaultPort
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return server, nutanixDefaultPort
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		port = nutanixDefaultPort
+	}
+	return u.Hostname(), port
+}
@@ -0,0 +1,146 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// nutanixClusterFixture returns a Prism Element cluster as discovered: two
+// VMs on two subnets, one with a disk whose container is unknown, and a
+// template
+func nutanixClusterFixture() *models.Infrastructure {
+	return &models.Infrastructure{
+		Provider:      "nutanix",
+		Server:        "prism.example.com:9440",
+		Cluster:       "PE-Cluster-01",
+		DiscoveryTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		VirtualMachines: []models.VirtualMachine{
+			{
+				ID: "a1b2", Name: "web-01", PowerState: "ON",
+				CPUs: 4, Memory: 8192, Tags: []string{"Environment:Production", "untagged"},
+				Hardware: models.HardwareInfo{Firmware: "EFI", NumCoresPerSocket: 2},
+				Disks: []models.Disk{
+					{ID: "scsi.0", Controller: "scsi", Unit: 0, Size: 40, Datastore: "default-container", DatastoreID: "c0ffee00-0000-4000-8000-000000000001"},
+				},
+				NetworkCards: []models.NetworkCard{
+					{ID: "nic-1", Key: 0, Type: "normal_nic", Network: "VLAN 10", MACAddress: "50:6b:8d:00:00:01"},
+				},
+			},
+			{
+				ID: "c3d4", Name: "db-01", PowerState: "OFF",
+				CPUs: 3, Memory: 16384,
+				Disks: []models.Disk{
+					{ID: "scsi.0", Controller: "scsi", Unit: 0, Size: 100, Datastore: "db-container", DatastoreID: "c0ffee00-0000-4000-8000-000000000002"},
+					{ID: "ide.0", Controller: "ide", Unit: 0, Size: 1},
+				},
+				NetworkCards: []models.NetworkCard{
+					{ID: "nic-1", Key: 0, Network: "VLAN 20"},
+					{ID: "nic-2", Key: 1, Network: "VLAN 10"},
+				},
+			},
+			{
+				ID: "e5f6", Name: "centos-template", Config: models.VMConfig{Template: true},
+				NetworkCards: []models.NetworkCard{{ID: "nic-1", Network: "VLAN 99"}},
+			},
+		},
+	}
+}
+
+func TestTerraformNutanix(t *testing.T) {
+	files := generate(t, "terraform", []*models.Infrastructure{nutanixClusterFixture()}, GenerateOptions{})
+	checkHCL(t, files)
+
+	vms := files["virtual_machines.tf"]
+	if got := strings.Count(vms, `resource "nutanix_virtual_machine"`); got != 2 {
+		t.Errorf("got %d VM resources, want one per non-template VM (2):\n%s", got, vms)
+	}
+
+	variables := files["variables.tf"]
+	if !strings.Contains(variables, `default     = "prism.example.com"`) || !strings.Contains(variables, "default     = 9440") {
+		t.Errorf("variables.tf does not default to the discovered server:\n%s", variables)
+	}
+
+	data := files["data.tf"]
+	for _, want := range []string{
+		`data "nutanix_cluster" "pe_cluster_01"`,
+		`subnet_name = "VLAN 10"`,
+		`subnet_name = "VLAN 20"`,
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("data.tf has no %s:\n%s", want, data)
+		}
+	}
+	if strings.Contains(data, "VLAN 99") {
+		t.Error("subnet used only by a template is looked up")
+	}
+
+	web := nutanixBlock(t, vms, "web_01")
+	for _, want := range []string{
+		"cluster_uuid         = data.nutanix_cluster.pe_cluster_01.id",
+		"num_sockets          = 2",
+		"num_vcpus_per_socket = 2",
+		"memory_size_mib      = 8192",
+		`boot_type            = "UEFI"`,
+		"name  = \"Environment\"\n    value = \"Production\"",
+		"disk_size_mib = 40960",
+		`uuid = "c0ffee00-0000-4000-8000-000000000001"`,
+		"subnet_uuid = data.nutanix_subnet.vlan_10.id",
+	} {
+		if !strings.Contains(web, want) {
+			t.Errorf("web_01 has no %q:\n%s", want, web)
+		}
+	}
+
+	db := nutanixBlock(t, vms, "db_01")
+	for _, want := range []string{
+		"num_sockets          = 1",
+		"num_vcpus_per_socket = 3",
+		`power_state          = "OFF"`,
+		"# Disk ide.0 omitted",
+		"subnet_uuid = data.nutanix_subnet.vlan_20.id",
+	} {
+		if !strings.Contains(db, want) {
+			t.Errorf("db_01 has no %q:\n%s", want, db)
+		}
+	}
+	if strings.Index(db, "vlan_20") > strings.Index(db, "vlan_10") {
+		t.Error("NICs are not in device order")
+	}
+}
+
+func TestNutanixEndpoint(t *testing.T) {
+	tests := []struct {
+		server string
+		host   string
+		port   int
+	}{
+		{"", "", 9440},
+		{"prism.example.com", "prism.example.com", 9440},
+		{"prism.example.com:9441", "prism.example.com", 9441},
+		{"https://10.0.0.5:443/api/nutanix/v3", "10.0.0.5", 443},
+	}
+	for _, tt := range tests {
+		host, port := nutanixEndpoint(tt.server)
+		if host != tt.host || port != tt.port {
+			t.Errorf("nutanixEndpoint(%q) = %q, %d; want %q, %d", tt.server, host, port, tt.host, tt.port)
+		}
+	}
+}
+
+// nutanixBlock returns the nutanix_virtual_machine resource named name
+func nutanixBlock(t *testing.T, content, name string) string {
+	t.Helper()
+
+	start := strings.Index(content, `resource "nutanix_virtual_machine" "`+name+`" {`)
+	if start < 0 {
+		t.Fatalf("no resource %s in:\n%s", name, content)
+	}
+	end := strings.Index(content[start:], "\n}\n")
+	if end < 0 {
+		return content[start:]
+	}
+	return content[start : start+end]
+}
@@ -0,0 +1,374 @@
+package generators
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// generateProxmox generates Terraform files for Proxmox infrastructure using
+// the bpg/proxmox provider. QEMU VMs become proxmox_virtual_environment_vm
+// resources on the node they were discovered on; containers need an OS
+// template Terraform can't recover from discovery and are left out.
+func (g *TerraformGenerator) generateProxmox(infra *models.Infrastructure, opts GenerateOptions) ([]*GenerateResult, error) {
+	vms, containers := countGuests(infra.VirtualMachines)
+	if containers > 0 {
+		g.Log().Warn("Proxmox containers are not generated for Terraform", "containers", containers)
+	}
+
+	provider := g.generateProxmoxProvider()
+	variables := g.generateProxmoxVariables(infra)
+	results := []*GenerateResult{{
+		Path:      "provider.tf",
+		Content:   []byte(provider),
+		Size:      len(provider),
+		Type:      "provider",
+		Provider:  "proxmox",
+		Resources: []string{"proxmox"},
+	}, {
+		Path:      "variables.tf",
+		Content:   []byte(variables),
+		Size:      len(variables),
+		Type:      "variables",
+		Provider:  "proxmox",
+		Resources: []string{},
+	}}
+
+	names := NewResourceNames(g.GenerateResourceName)
+	vmNames := names.VMs(infra.VirtualMachines)
+
+	if vms > 0 || containers > 0 {
+		resources, notes := g.generateProxmoxVMs(infra.VirtualMachines, vmNames, opts)
+		results = append(results, &GenerateResult{
+			Path:      "virtual_machines.tf",
+			Content:   []byte(resources),
+			Size:      len(resources),
+			Type:      "resources",
+			Provider:  "proxmox",
+			Resources: []string{"proxmox_virtual_environment_vm"},
+			Metadata:  map[string]interface{}{"resource_names": names.Mapping},
+		})
+		results = append(results, notes...)
+
+		if opts.WithImports && vms > 0 {
+			results = append(results, g.importResults(g.proxmoxImports(infra, vmNames), "proxmox")...)
+		}
+	}
+
+	if opts.EmitPostProvision {
+		if doc := PostProvisionDoc(infra.VirtualMachines, "proxmox"); doc != nil {
+			results = append(results, doc)
+		}
+	}
+
+	outputs := g.generateProxmoxOutputs(infra, vmNames)
+	results = append(results, &GenerateResult{
+		Path:      "outputs.tf",
+		Content:   []byte(outputs),
+		Size:      len(outputs),
+		Type:      "outputs",
+		Provider:  "proxmox",
+		Resources: []string{},
+	})
+
+	return results, nil
+}
+
+// generateProxmoxProvider generates the bpg/proxmox provider configuration
+func (g *TerraformGenerator) generateProxmoxProvider() string {
+	return `terraform {
+  required_providers {
+    proxmox = {
+      source  = "bpg/proxmox"
+      version = ">= 0.46.0"
+    }
+  }
+  required_version = ">= 1.0"
+}
+
+provider "proxmox" {
+  endpoint  = var.proxmox_endpoint
+  api_token = var.proxmox_api_token
+  insecure  = var.proxmox_insecure
+}
+`
+}
+
+// generateProxmoxVariables generates the Proxmox variable definitions. The
+// node only places VMs whose node wasn't discovered.
+func (g *TerraformGenerator) generateProxmoxVariables(infra *models.Infrastructure) string {
+	node := proxmoxDefaultNode(infra)
+
+	variables := fmt.Sprintf(`variable "proxmox_endpoint" {
+  description = "Proxmox VE API endpoint"
+  type        = string
+  default     = "%s"
+}
+
+variable "proxmox_api_token" {
+  description = "Proxmox VE API token, as user@realm!token=secret"
+  type        = string
+  sensitive   = true
+}
+
+variable "proxmox_insecure" {
+  description = "Allow unverified SSL certificates"
+  type        = bool
+  default     = true
+}
+
+variable "proxmox_node" {
+  description = "Node for VMs whose node was not discovered"
+  type        = string
+`, EscapeHCL(proxmoxEndpointURL(infra.Server)))
+	if node != "" {
+		variables += fmt.Sprintf("  default     = \"%s\"\n", EscapeHCL(node))
+	}
+	return variables + "}\n"
+}
+
+// generateProxmoxVMs generates a proxmox_virtual_environment_vm resource per
+// QEMU VM, returning any annotation sidecar notes alongside
+func (g *TerraformGenerator) generateProxmoxVMs(vms []models.VirtualMachine, names []string, opts GenerateOptions) (string, []*GenerateResult) {
+	var configs []string
+	var notes []*GenerateResult
+	var skipped []string
+
+	for i, vm := range vms {
+		if vm.Config.Template {
+			continue
+		}
+		if vm.IsContainer() {
+			skipped = append(skipped, vm.Name)
+			continue
+		}
+
+		resourceName := names[i]
+		comments, sidecar := g.AnnotationComments(vm, resourceName, "#", opts.MaxFieldSize)
+		if sidecar != nil {
+			sidecar.Provider = "proxmox"
+			notes = append(notes, sidecar)
+		}
+
+		node := "var.proxmox_node"
+		if vm.Host != "" {
+			node = fmt.Sprintf("\"%s\"", EscapeHCL(vm.Host))
+		}
+
+		config := comments + fmt.Sprintf(`resource "proxmox_virtual_environment_vm" "%s" {
+  name      = "%s"
+  node_name = %s
+`, resourceName, EscapeHCL(vm.Name), node)
+		if vm.Metadata["vmid"] != nil {
+			config += fmt.Sprintf("  vm_id     = %v\n", vm.Metadata["vmid"])
+		}
+		config += fmt.Sprintf("  started   = %t\n", strings.EqualFold(vm.State, "running"))
+		if len(vm.Tags) > 0 {
+			tags := make([]string, 0, len(vm.Tags))
+			for _, tag := range vm.Tags {
+				tags = append(tags, fmt.Sprintf("\"%s\"", EscapeHCL(strings.ToLower(tag))))
+			}
+			sort.Strings(tags)
+			config += fmt.Sprintf("  tags      = [%s]\n", strings.Join(tags, ", "))
+		}
+
+		efi := strings.EqualFold(vm.Hardware.Firmware, "EFI")
+		if efi {
+			config += "  bios      = \"ovmf\"\n"
+		}
+
+		cores := vm.Hardware.NumCoresPerSocket
+		if cores <= 0 || vm.CPUs%cores != 0 {
+			cores = vm.CPUs
+		}
+		sockets := 1
+		if cores > 0 {
+			sockets = vm.CPUs / cores
+		}
+		config += fmt.Sprintf(`
+  cpu {
+    cores   = %d
+    sockets = %d
+  }
+
+  memory {
+    dedicated = %d
+  }
+`, cores, sockets, vm.Memory)
+
+		if vm.Config.GuestID != "" {
+			config += fmt.Sprintf(`
+  operating_system {
+    type = "%s"
+  }
+`, EscapeHCL(vm.Config.GuestID))
+		}
+
+		// Disks are recreated empty on the storage they were discovered on
+		efiStorage := ""
+		for _, disk := range vm.Disks {
+			if disk.Datastore == "" {
+				config += fmt.Sprintf("\n  # Passthrough disk %s (%s) omitted\n", disk.ID, disk.Path)
+				continue
+			}
+			if efiStorage == "" {
+				efiStorage = disk.Datastore
+			}
+			config += fmt.Sprintf(`
+  disk {
+    datastore_id = "%s"
+    interface    = "%s"
+    size         = %d
+    file_format  = "%s"
+  }
+`, EscapeHCL(disk.Datastore), disk.ID, disk.Size, disk.Type)
+		}
+
+		// OVMF keeps its variables on a small EFI disk next to the VM's disks
+		if efi && efiStorage != "" {
+			config += fmt.Sprintf(`
+  efi_disk {
+    datastore_id = "%s"
+    type         = "4m"
+  }
+`, EscapeHCL(efiStorage))
+		}
+
+		// The provider takes a single CD-ROM drive
+		for j, iso := range vm.ISOs {
+			if j > 0 {
+				config += fmt.Sprintf("\n  # Additional ISO %s omitted: only one CD-ROM drive is supported\n", iso)
+				continue
+			}
+			config += fmt.Sprintf(`
+  cdrom {
+    file_id   = "%s"
+    interface = "ide2"
+  }
+`, EscapeHCL(iso))
+		}
+
+		for _, nic := range g.OrderedNetworkCards(vm) {
+			if nic.Network == "" {
+				config += fmt.Sprintf("\n  # Network device %s omitted: no bridge was discovered for it\n", nic.ID)
+				continue
+			}
+			model := nic.Type
+			if model == "" || model == models.NICTypeUnknown {
+				model = "virtio"
+			}
+			attributes := [][2]string{
+				{"bridge", fmt.Sprintf("\"%s\"", EscapeHCL(nic.Network))},
+				{"model", fmt.Sprintf("\"%s\"", model)},
+			}
+			if opts.PreserveMAC && nic.MACAddress != "" {
+				attributes = append(attributes, [2]string{"mac_address", fmt.Sprintf("\"%s\"", nic.MACAddress)})
+			}
+			if !nic.StartConnect {
+				attributes = append(attributes, [2]string{"disconnected", "true"})
+			}
+			config += "\n  network_device {\n" + alignedAttributes("    ", attributes) + "  }\n"
+		}
+
+		config += "}\n"
+		configs = append(configs, config)
+	}
+
+	content := strings.Join(configs, "\n")
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		header := "# LXC containers are not generated: recreating them needs an OS template\n# that discovery can't recover. Skipped: " + strings.Join(skipped, ", ") + "\n"
+		if content != "" {
+			header += "\n"
+		}
+		content = header + content
+	}
+	return content, notes
+}
+
+// generateProxmoxOutputs generates outputs for the generated VMs
+func (g *TerraformGenerator) generateProxmoxOutputs(infra *models.Infrastructure, names []string) string {
+	outputs := `output "virtual_machines" {
+  description = "Information about created virtual machines"
+  value = {
+`
+
+	for i, vm := range infra.VirtualMachines {
+		if vm.Config.Template || vm.IsContainer() {
+			continue
+		}
+		resourceName := names[i]
+		outputs += fmt.Sprintf(`    "%s" = {
+      id        = proxmox_virtual_environment_vm.%s.id
+      vm_id     = proxmox_virtual_environment_vm.%s.vm_id
+      node_name = proxmox_virtual_environment_vm.%s.node_name
+    }
+`, EscapeHCL(vm.Name), resourceName, resourceName, resourceName)
+	}
+
+	outputs += `  }
+}
+`
+
+	return outputs
+}
+
+// proxmoxDefaultNode returns the node discovery was scoped to, or else the
+// first node discovered, for guests whose node is unknown
+func proxmoxDefaultNode(infra *models.Infrastructure) string {
+	if infra.Node != "" {
+		return infra.Node
+	}
+	switch nodes := infra.Metadata["nodes"].(type) {
+	case []string:
+		if len(nodes) > 0 {
+			return nodes[0]
+		}
+	case []interface{}: // as loaded from a discovery file
+		if len(nodes) > 0 {
+			node, _ := nodes[0].(string)
+			return node
+		}
+	}
+	return ""
+}
+
+// alignedAttributes renders HCL attributes with their equals signs lined
+// up, as terraform fmt does
+func alignedAttributes(indent string, attributes [][2]string) string {
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute[0]) > width {
+			width = len(attribute[0])
+		}
+	}
+
+	var rendered string
+	for _, attribute := range attributes {
+		rendered += fmt.Sprintf("%s%-*s = %s\n", indent, width, attribute[0], attribute[1])
+	}
+	return rendered
+}
+
+// proxmoxEndpointURL turns a configured Proxmox server, a host name or a
+// URL, into the endpoint URL the provider expects, defaulting to HTTPS on
+// port 8006
+func proxmoxEndpointURL(server string) string {
+	if server == "" {
+		return ""
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return server
+	}
+	if u.Port() == "" {
+		u.Host += ":8006"
+	}
+	return u.Scheme + "://" + u.Host + "/"
+}
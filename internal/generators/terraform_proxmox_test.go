@@ -0,0 +1,164 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// proxmoxFixture returns a two-node Proxmox cluster as discovered: a UEFI
+// VM with a passthrough disk on pve1, a VM whose node is unknown, a
+// container and a template
+func proxmoxFixture() *models.Infrastructure {
+	return &models.Infrastructure{
+		Provider:      "proxmox",
+		Server:        "pve.example.com",
+		DiscoveryTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Metadata:      map[string]interface{}{"nodes": []interface{}{"pve1", "pve2"}},
+		VirtualMachines: []models.VirtualMachine{
+			{
+				ID: "qemu/100", Name: "web-01", Host: "pve1", State: "running",
+				CPUs: 4, Memory: 4096, Tags: []string{"Prod", "web"},
+				Config:   models.VMConfig{GuestID: "l26"},
+				Hardware: models.HardwareInfo{Firmware: "EFI", NumCoresPerSocket: 2},
+				Metadata: map[string]interface{}{"vmid": 100},
+				Disks: []models.Disk{
+					{ID: "scsi0", Size: 32, Type: "raw", Datastore: "local-lvm"},
+					{ID: "scsi1", Size: 500, Path: "/dev/disk/by-id/ata-WDC", Type: "raw"},
+				},
+				ISOs: []string{"local:iso/ubuntu.iso", "local:iso/drivers.iso"},
+				NetworkCards: []models.NetworkCard{
+					{ID: "net0", Key: 0, Type: "virtio", Network: "vmbr0", MACAddress: "BC:24:11:00:00:01", StartConnect: true},
+					{ID: "net1", Key: 1, Type: "e1000", Network: "vmbr1", MACAddress: "BC:24:11:00:00:02"},
+				},
+			},
+			{
+				ID: "qemu/101", Name: "db-01", State: "stopped",
+				CPUs: 3, Memory: 8192,
+				Metadata: map[string]interface{}{"vmid": 101},
+				Disks:    []models.Disk{{ID: "virtio0", Size: 100, Type: "qcow2", Datastore: "ceph"}},
+			},
+			{
+				ID: "lxc/200", Name: "dns", Host: "pve2", Type: models.VMTypeLXC, State: "running",
+				CPUs: 1, Memory: 512,
+			},
+			{
+				ID: "qemu/9000", Name: "ubuntu-template", Host: "pve1",
+				Config: models.VMConfig{Template: true},
+			},
+		},
+	}
+}
+
+func TestTerraformProxmox(t *testing.T) {
+	files := generate(t, "terraform", []*models.Infrastructure{proxmoxFixture()}, GenerateOptions{})
+	checkHCL(t, files)
+
+	if !strings.Contains(files["provider.tf"], `source  = "bpg/proxmox"`) {
+		t.Errorf("provider.tf does not use bpg/proxmox:\n%s", files["provider.tf"])
+	}
+	variables := files["variables.tf"]
+	for _, want := range []string{
+		`default     = "https://pve.example.com:8006/"`,
+		`variable "proxmox_api_token"`,
+		`default     = "pve1"`,
+	} {
+		if !strings.Contains(variables, want) {
+			t.Errorf("variables.tf has no %s:\n%s", want, variables)
+		}
+	}
+
+	vms := files["virtual_machines.tf"]
+	if got := strings.Count(vms, `resource "proxmox_virtual_environment_vm"`); got != 2 {
+		t.Errorf("got %d VM resources, want 2 (no container or template):\n%s", got, vms)
+	}
+	if !strings.Contains(vms, "Skipped: dns") {
+		t.Error("skipped container is not listed")
+	}
+
+	web := proxmoxBlock(t, vms, "web_01")
+	for _, want := range []string{
+		`node_name = "pve1"`,
+		"vm_id     = 100",
+		"started   = true",
+		`tags      = ["prod", "web"]`,
+		`bios      = "ovmf"`,
+		"cores   = 2\n    sockets = 2",
+		"dedicated = 4096",
+		`datastore_id = "local-lvm"`,
+		`interface    = "scsi0"`,
+		"# Passthrough disk scsi1 (/dev/disk/by-id/ata-WDC) omitted",
+		"efi_disk {\n    datastore_id = \"local-lvm\"",
+		`file_id   = "local:iso/ubuntu.iso"`,
+		"# Additional ISO local:iso/drivers.iso omitted",
+		"bridge = \"vmbr0\"\n    model  = \"virtio\"\n",
+		"bridge       = \"vmbr1\"\n    model        = \"e1000\"\n    disconnected = true\n",
+	} {
+		if !strings.Contains(web, want) {
+			t.Errorf("web_01 has no %q:\n%s", want, web)
+		}
+	}
+	if strings.Contains(web, "mac_address") {
+		t.Error("MAC addresses set without --preserve-mac")
+	}
+
+	db := proxmoxBlock(t, vms, "db_01")
+	for _, want := range []string{
+		"node_name = var.proxmox_node",
+		"started   = false",
+		"cores   = 3\n    sockets = 1",
+		`datastore_id = "ceph"`,
+		`file_format  = "qcow2"`,
+	} {
+		if !strings.Contains(db, want) {
+			t.Errorf("db_01 has no %q:\n%s", want, db)
+		}
+	}
+
+	outputs := files["outputs.tf"]
+	if !strings.Contains(outputs, "proxmox_virtual_environment_vm.web_01.vm_id") || strings.Contains(outputs, "dns") {
+		t.Errorf("outputs.tf does not list exactly the generated VMs:\n%s", outputs)
+	}
+}
+
+func TestTerraformProxmoxPreserveMAC(t *testing.T) {
+	files := generate(t, "terraform", []*models.Infrastructure{proxmoxFixture()}, GenerateOptions{PreserveMAC: true})
+	if web := proxmoxBlock(t, files["virtual_machines.tf"], "web_01"); !strings.Contains(web, `mac_address = "BC:24:11:00:00:01"`) {
+		t.Errorf("web_01 does not keep its MAC:\n%s", web)
+	}
+}
+
+func TestProxmoxEndpointURL(t *testing.T) {
+	tests := []struct {
+		server string
+		want   string
+	}{
+		{"", ""},
+		{"pve.example.com", "https://pve.example.com:8006/"},
+		{"https://pve.example.com", "https://pve.example.com:8006/"},
+		{"https://pve.example.com:443/api2/json", "https://pve.example.com:443/"},
+		{"10.0.0.5:8006", "https://10.0.0.5:8006/"},
+	}
+	for _, tt := range tests {
+		if got := proxmoxEndpointURL(tt.server); got != tt.want {
+			t.Errorf("proxmoxEndpointURL(%q) = %q, want %q", tt.server, got, tt.want)
+		}
+	}
+}
+
+// proxmoxBlock returns the proxmox_virtual_environment_vm resource named name
+func proxmoxBlock(t *testing.T, content, name string) string {
+	t.Helper()
+
+	start := strings.Index(content, `resource "proxmox_virtual_environment_vm" "`+name+`" {`)
+	if start < 0 {
+		t.Fatalf("no resource %s in:\n%s", name, content)
+	}
+	end := strings.Index(content[start:], "\n}\n")
+	if end < 0 {
+		return content[start:]
+	}
+	return content[start : start+end]
+}
@@ -0,0 +1,132 @@
+package generators
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestTerraformDisklessVM(t *testing.T) {
+	infra := vmwareFixture()
+	infra.VirtualMachines = append(infra.VirtualMachines,
+		models.VirtualMachine{
+			ID: "vm-103", Name: "iso-appliance", PowerState: "poweredOn",
+			CPUs: 1, Memory: 1024,
+			Config: models.VMConfig{GuestID: "otherLinux64Guest", UUID: "4201-0003"},
+		},
+		models.VirtualMachine{
+			ID: "vm-104", Name: "rdm-appliance", PowerState: "poweredOn",
+			CPUs: 1, Memory: 1024,
+			Config: models.VMConfig{GuestID: "otherLinux64Guest", UUID: "4201-0004"},
+			Disks: []models.Disk{
+				{ID: "2000", Size: 500, Type: models.DiskTypeRDMPhysical, LUN: "naa.600a0980", Datastore: "ds1", DatastoreID: "datastore-11"},
+			},
+		},
+	)
+
+	files := generate(t, "terraform", []*models.Infrastructure{infra}, GenerateOptions{})
+	checkHCL(t, files)
+
+	vms := files["virtual_machines.tf"]
+	block := resourceBlock(t, vms, "iso_appliance")
+	if !strings.Contains(block, "datastore_id     = data.vsphere_datastore.default_datastore.id") {
+		t.Errorf("diskless VM is not placed on the default datastore:\n%s", block)
+	}
+	if !strings.Contains(block, "No disks were discovered") {
+		t.Errorf("diskless VM has no comment explaining its datastore:\n%s", block)
+	}
+	if strings.Contains(block, "network_interface") {
+		t.Errorf("network-less VM has a network_interface block:\n%s", block)
+	}
+
+	if block := resourceBlock(t, vms, "rdm_appliance"); !strings.Contains(block, "omitted, see MANUAL_STEPS.md") {
+		t.Errorf("RDM disk is not reported as omitted:\n%s", block)
+	}
+	if !strings.Contains(files["variables.tf"], `variable "default_datastore"`) {
+		t.Error("variables.tf does not declare default_datastore")
+	}
+}
+
+// resourceBlock returns the vsphere_virtual_machine resource named name
+func resourceBlock(t *testing.T, content, name string) string {
+	t.Helper()
+
+	start := strings.Index(content, `resource "vsphere_virtual_machine" "`+name+`" {`)
+	if start < 0 {
+		t.Fatalf("no resource %s in:\n%s", name, content)
+	}
+	end := strings.Index(content[start:], "\n}\n")
+	if end < 0 {
+		return content[start:]
+	}
+	return content[start : start+end]
+}
+
+func TestTerraformDataSources(t *testing.T) {
+	// Fifty discovered networks, of which the VMs use three; one datastore
+	// is known to the VMs only by moref and one has quotes in its name
+	infra := vmwareFixture()
+	infra.Networks = nil
+	for i := 0; i < 50; i++ {
+		infra.Networks = append(infra.Networks, models.Network{
+			ID: fmt.Sprintf("network-%d", i), Name: fmt.Sprintf("/DC1/network/net-%02d", i), Type: "Network",
+		})
+	}
+	infra.Storage = append(infra.Storage, models.Storage{ID: "datastore-12", Name: `ds "fast"`, Type: "VMFS", Accessible: true})
+	infra.VirtualMachines[0].NetworkCards[0].Network = "net-03"
+	infra.VirtualMachines[1].NetworkCards[0].Network = "net-17"
+	infra.VirtualMachines[1].Disks[0] = models.Disk{ID: "2000", Size: 100, Type: "thick", Datastore: "datastore-11"}
+	infra.VirtualMachines = append(infra.VirtualMachines, models.VirtualMachine{
+		ID: "vm-103", Name: "cache-01", CPUs: 2, Memory: 4096,
+		Config: models.VMConfig{GuestID: "ubuntu64Guest", UUID: "4201-0003"},
+		Disks: []models.Disk{
+			{ID: "2000", Size: 20, Type: "thin", Datastore: "datastore-12"},
+		},
+		NetworkCards: []models.NetworkCard{
+			{ID: "4000", Key: 4000, Type: "vmxnet3", Network: "net-42"},
+		},
+	})
+
+	tests := []struct {
+		name       string
+		opts       GenerateOptions
+		networks   int
+		datastores int
+	}{
+		{name: "referenced only", networks: 3, datastores: 2},
+		{name: "include all", opts: GenerateOptions{IncludeAllDataSources: true}, networks: 50, datastores: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := generate(t, "terraform", []*models.Infrastructure{infra}, tt.opts)
+			checkHCL(t, files)
+
+			data := files["data.tf"]
+			if got := strings.Count(data, `data "vsphere_network"`); got != tt.networks {
+				t.Errorf("%d network data sources, want %d", got, tt.networks)
+			}
+			if got := strings.Count(data, `data "vsphere_datastore"`); got != tt.datastores {
+				t.Errorf("%d datastore data sources, want %d:\n%s", got, tt.datastores, data)
+			}
+			for _, name := range []string{`"net-03"`, `"net-17"`, `"net-42"`, `"ds1"`, `"ds \"fast\""`} {
+				if !strings.Contains(data, "name          = "+name+"\n") {
+					t.Errorf("data.tf does not look up %s", name)
+				}
+			}
+			if strings.Contains(data, `"datastore-1`) {
+				t.Errorf("datastore looked up by moref:\n%s", data)
+			}
+
+			vms := files["virtual_machines.tf"]
+			if block := resourceBlock(t, vms, "db_01"); !strings.Contains(block, "data.vsphere_datastore.ds1.id") {
+				t.Errorf("moref disk is not placed on ds1:\n%s", block)
+			}
+			if block := resourceBlock(t, vms, "cache_01"); !strings.Contains(block, "data.vsphere_datastore.ds__fast_.id") {
+				t.Errorf("moref disk is not placed on ds \"fast\":\n%s", block)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package generators_test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"valhalla/internal/generators"
+	"valhalla/internal/logger"
+	"valhalla/internal/output"
+	"valhalla/internal/validation"
+)
+
+// unicodeNames are the VM names in testdata/unicode-discovery.json
+var unicodeNames = []string{"сервер-01", "サーバー", "web 🚀", "web 🌕", `café "prod" ${var.x}`}
+
+// resourceName matches a Terraform resource label, a Pulumi resource
+// variable in any language or an Ansible inventory host
+var resourceName = regexp.MustCompile(`(?mi)^resource "vsphere_virtual_machine" "([^"]+)"` +
+	`|^\s*(?:const |var )?(\w+)(?:, err :=| =) (?:new )?vsphere\.(?:New)?VirtualMachine\(` +
+	`|^        ([^\s:#]+):$`)
+
+func TestUnicodeNamesEndToEnd(t *testing.T) {
+	infrastructures, err := output.ReadFile(filepath.Join("testdata", "unicode-discovery.json"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	log := logger.NewWithOutput(io.Discard)
+
+	for _, format := range generators.GetAvailableFormats() {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			generator, err := generators.NewGenerator(format, log)
+			if err != nil {
+				t.Fatalf("NewGenerator: %v", err)
+			}
+			if _, err := generator.Generate(infrastructures, generators.GenerateOptions{OutputDir: dir}); err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+
+			results, err := validation.NewValidator(log).ValidateDirectory(dir, validation.ValidateOptions{Format: "auto", Recursive: true})
+			if err != nil {
+				t.Fatalf("ValidateDirectory: %v", err)
+			}
+			for _, result := range results {
+				for _, issue := range result.Issues {
+					if issue.Severity == validation.SeverityError {
+						t.Errorf("%s:%d: %s", result.Path, issue.Line, issue.Message)
+					}
+				}
+			}
+
+			var all strings.Builder
+			err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() {
+					return err
+				}
+				rel, _ := filepath.Rel(dir, path)
+				for _, r := range rel {
+					if r > 0x7f {
+						t.Errorf("file name %q is not ASCII", rel)
+						break
+					}
+				}
+				data, err := os.ReadFile(path)
+				all.Write(data)
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The last name is escaped differently by each language
+			for _, name := range unicodeNames[:4] {
+				if !strings.Contains(all.String(), name) {
+					t.Errorf("VM name %q does not appear in the generated code", name)
+				}
+			}
+
+			seen := make(map[string]bool)
+			for _, match := range resourceName.FindAllStringSubmatch(all.String(), -1) {
+				name := match[1] + match[2] + match[3]
+				if seen[name] {
+					t.Errorf("resource name %q is used twice", name)
+				}
+				seen[name] = true
+			}
+			if len(seen) != len(unicodeNames) {
+				t.Errorf("got resource names %v, want %d distinct names", seen, len(unicodeNames))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package generators
+
+import (
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+// nutanixFixture returns a Prism Central inventory with one VM whose memory
+// is not a whole number of GiB
+func nutanixFixture() *models.Infrastructure {
+	return &models.Infrastructure{
+		Provider: "nutanix",
+		Server:   "prism.example.com",
+		Cluster:  "PE1",
+		VirtualMachines: []models.VirtualMachine{
+			{
+				ID: "0005a1b2-0001", Name: "app-01", PowerState: "ON",
+				CPUs: 2, Memory: 6200,
+				Disks: []models.Disk{
+					{ID: "scsi.0", Size: 40, Controller: "scsi", Datastore: "default-container", DatastoreID: "sc-1"},
+				},
+			},
+		},
+		Storage: []models.Storage{{ID: "sc-1", Name: "default-container"}},
+	}
+}
+
+func TestGeneratedSizeConversions(t *testing.T) {
+	infras := []*models.Infrastructure{nutanixFixture()}
+
+	terraform := joined(generate(t, "terraform", infras, GenerateOptions{}))
+	if !strings.Contains(terraform, "disk_size_mib = 40960") {
+		t.Error("Terraform does not size the 40 GiB disk as 40960 MiB")
+	}
+
+	ansible := joined(generate(t, "ansible", infras, GenerateOptions{}))
+	if !strings.Contains(ansible, "memory_gb: 7") {
+		t.Error("Ansible does not round 6200 MiB of memory up to 7 GiB")
+	}
+	if !strings.Contains(ansible, "# Rounded up from 6200 MiB") {
+		t.Error("Ansible does not note the memory rounding")
+	}
+
+	vm := models.VirtualMachine{Hardware: models.HardwareInfo{VideoRAMKB: 16384}}
+	settings := NewBaseGenerator("test", "test", testLogger()).VideoExtraConfig(vm)
+	if len(settings) == 0 || settings[0] != [2]string{"svga.vramSize", "16777216"} {
+		t.Errorf("VideoExtraConfig = %v, want svga.vramSize 16777216", settings)
+	}
+}
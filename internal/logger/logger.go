@@ -3,22 +3,33 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
-// Logger wraps standard logger with structured logging capabilities
+// Logger writes structured log lines. Loggers derived with With share
+// their destination, so redirecting one redirects them all.
 type Logger struct {
-	logger *log.Logger
+	out    *sink
 	format string
 	level  LogLevel
 	fields map[string]interface{}
 }
 
+// sink is where a logger and those derived from it write. Discovery logs
+// from many goroutines, so each line is written under mu to keep lines
+// whole.
+type sink struct {
+	mu   sync.Mutex
+	w    io.Writer
+	file *RotatingFile // set when logging to a file opened by SetFile
+}
+
 // LogLevel represents the logging level
 type LogLevel int
 
@@ -29,8 +40,14 @@ const (
 	LevelError
 )
 
-// New creates a new logger instance
+// New creates a new logger instance writing to stdout
 func New() *Logger {
+	return NewWithOutput(os.Stdout)
+}
+
+// NewWithOutput creates a new logger instance writing to w, for callers
+// embedding Valhalla that need to redirect or discard its logs
+func NewWithOutput(w io.Writer) *Logger {
 	format := viper.GetString("log-format")
 	if format == "" {
 		format = "text"
@@ -42,7 +59,7 @@ func New() *Logger {
 	}
 
 	return &Logger{
-		logger: log.New(os.Stdout, "", 0),
+		out:    &sink{w: w},
 		format: strings.ToLower(format),
 		level:  level,
 		fields: make(map[string]interface{}),
@@ -92,7 +109,56 @@ func (l *Logger) log(level LogLevel, msg string, args ...interface{}) {
 		output = l.formatText(entry)
 	}
 
-	l.logger.Println(output)
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	fmt.Fprintln(l.out.w, output)
+}
+
+// SetOutput redirects the logger, and every logger derived from it, to w
+func (l *Logger) SetOutput(w io.Writer) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.closeFile()
+	l.out.w = w
+}
+
+// SetFile redirects the logger, and every logger derived from it, to a
+// file at path, appending to it. The file is rotated at DefaultMaxFileSize,
+// keeping DefaultMaxBackups old files, so long discovery runs cannot fill
+// the disk.
+func (l *Logger) SetFile(path string) error {
+	file, err := NewRotatingFile(path, DefaultMaxFileSize, DefaultMaxBackups)
+	if err != nil {
+		return err
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.closeFile()
+	l.out.w, l.out.file = file, file
+	return nil
+}
+
+// Close closes the log file opened by SetFile, if any; later log lines go
+// to stderr
+func (l *Logger) Close() error {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	if l.out.file == nil {
+		return nil
+	}
+	err := l.out.file.Close()
+	l.out.file = nil
+	l.out.w = os.Stderr
+	return err
+}
+
+// closeFile closes a log file being replaced; the caller holds out.mu
+func (l *Logger) closeFile() {
+	if l.out.file != nil {
+		l.out.file.Close()
+		l.out.file = nil
+	}
 }
 
 // levelString returns string representation of log level
@@ -164,7 +230,7 @@ func (l *Logger) With(key string, value interface{}) *Logger {
 	newFields[key] = value
 
 	return &Logger{
-		logger: l.logger,
+		out:    l.out,
 		format: l.format,
 		level:  l.level,
 		fields: newFields,
@@ -187,11 +253,12 @@ func (l *Logger) WithOperation(operation string) *Logger {
 }
 
 // Progress logs progress messages with consistent formatting
-func (l *Logger) Progress(msg string, current, total int) {
-	l.Info(msg,
+func (l *Logger) Progress(msg string, current, total int, args ...interface{}) {
+	allArgs := append([]interface{}{
 		"progress", current,
 		"total", total,
-		"percentage", float64(current)/float64(total)*100)
+		"percentage", float64(current)/float64(total)*100}, args...)
+	l.Info(msg, allArgs...)
 }
 
 // StartOperation logs the start of an operation
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"time"
+)
+
+// progressInterval is the minimum time between progress log lines
+const progressInterval = 5 * time.Second
+
+// progressSmoothing weights the latest throughput sample in the moving average
+const progressSmoothing = 0.3
+
+// ProgressTracker logs progress of a counted task together with an estimate
+// of the time remaining, based on a moving average of the throughput so far
+type ProgressTracker struct {
+	log     *Logger
+	msg     string
+	total   int
+	current int
+
+	start      time.Time
+	lastSample time.Time
+	lastLogged time.Time
+	rate       float64 // items per second
+}
+
+// NewProgress starts tracking progress of total items
+func (l *Logger) NewProgress(msg string, total int) *ProgressTracker {
+	now := time.Now()
+	return &ProgressTracker{
+		log:        l,
+		msg:        msg,
+		total:      total,
+		start:      now,
+		lastSample: now,
+		lastLogged: now,
+	}
+}
+
+// Add records n more processed items and logs progress with an ETA at most
+// once per progress interval
+func (t *ProgressTracker) Add(n int) {
+	now := time.Now()
+	t.current += n
+
+	if elapsed := now.Sub(t.lastSample).Seconds(); elapsed > 0 {
+		sample := float64(n) / elapsed
+		if t.rate == 0 {
+			t.rate = sample
+		} else {
+			t.rate = progressSmoothing*sample + (1-progressSmoothing)*t.rate
+		}
+		t.lastSample = now
+	}
+
+	if t.current >= t.total || now.Sub(t.lastLogged) < progressInterval {
+		return
+	}
+	t.lastLogged = now
+
+	t.log.Progress(t.msg, t.current, t.total, "remaining", t.Remaining().String())
+}
+
+// Remaining estimates the time left from the current throughput
+func (t *ProgressTracker) Remaining() time.Duration {
+	if t.rate <= 0 || t.current >= t.total {
+		return 0
+	}
+	seconds := float64(t.total-t.current) / t.rate
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second)
+}
+
+// Done logs the total number of items processed and how long it took
+func (t *ProgressTracker) Done() {
+	t.log.Info(t.msg,
+		"progress", t.current,
+		"total", t.total,
+		"duration", time.Since(t.start).Round(time.Millisecond).String())
+}
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// DefaultMaxFileSize is the size a log file is rotated at
+	DefaultMaxFileSize = 10 << 20
+
+	// DefaultMaxBackups is how many rotated log files are kept
+	DefaultMaxBackups = 3
+)
+
+// RotatingFile appends to a log file and rotates it before a write would
+// take it past maxSize: the file becomes path.1, path.1 becomes path.2 and
+// so on, and files past maxBackups are removed. A single write larger than
+// maxSize still goes to the fresh file whole.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens path for appending, creating it and its directory
+// when missing. Logs name servers and accounts, so the file is only
+// readable by its owner.
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxFileSize
+	}
+	if maxBackups < 0 {
+		maxBackups = 0
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %w", err)
+		}
+	}
+
+	f := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the log file, rotating it first when p doesn't fit.
+// When the old files cannot be shifted, p is still written and the file
+// keeps growing rather than losing log lines.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// open opens the log file for appending, continuing from its current size
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// rotate shifts the old files up by one, dropping the oldest, moves the
+// current file to path.1 and starts a new one. A new file is opened even
+// when shifting fails, so logging goes on in the current file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	f.file = nil
+
+	var err error
+	if f.maxBackups == 0 {
+		err = removeIfExists(f.path)
+	}
+	for i := f.maxBackups; i > 0 && err == nil; i-- {
+		src := f.path
+		if i > 1 {
+			src = f.backup(i - 1)
+		}
+		// Renaming over an existing file fails on Windows
+		if err = removeIfExists(f.backup(i)); err == nil {
+			if err = os.Rename(src, f.backup(i)); errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		}
+	}
+
+	if openErr := f.open(); openErr != nil {
+		return openErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
+
+// removeIfExists removes a file, ignoring that it doesn't exist
+func removeIfExists(name string) error {
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// backup returns the name of the nth most recent rotated file
+func (f *RotatingFile) backup(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
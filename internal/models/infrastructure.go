@@ -1,6 +1,10 @@
 package models
 
 import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,44 +21,164 @@ type Infrastructure struct {
 	Storage        []Storage             `json:"storage" yaml:"storage"`
 	ResourcePools  []ResourcePool        `json:"resource_pools,omitempty" yaml:"resource_pools,omitempty"`
 	Templates      []Template            `json:"templates,omitempty" yaml:"templates,omitempty"`
+	Clusters       []Cluster             `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	Hosts          []Host                `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	HAGroups       []HAGroup             `json:"ha_groups,omitempty" yaml:"ha_groups,omitempty"`
+	Permissions    []Permission          `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	ContentLibraries []ContentLibrary    `json:"content_libraries,omitempty" yaml:"content_libraries,omitempty"`
+	Findings       []Finding             `json:"findings,omitempty" yaml:"findings,omitempty"`
+	Rebalancing    *RebalancePlan        `json:"rebalancing,omitempty" yaml:"rebalancing,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
 
+// SortResources orders the resource slices by name, then UUID or ID, so
+// output and generated code do not depend on the order the hypervisor
+// returned objects in
+func (i *Infrastructure) SortResources() {
+	sort.SliceStable(i.VirtualMachines, func(a, b int) bool {
+		x, y := i.VirtualMachines[a], i.VirtualMachines[b]
+		if x.Name != y.Name {
+			return x.Name < y.Name
+		}
+		if x.Config.UUID != y.Config.UUID {
+			return x.Config.UUID < y.Config.UUID
+		}
+		return x.ID < y.ID
+	})
+	sort.SliceStable(i.Networks, func(a, b int) bool {
+		return lessByNameID(i.Networks[a].Name, i.Networks[a].ID, i.Networks[b].Name, i.Networks[b].ID)
+	})
+	sort.SliceStable(i.Storage, func(a, b int) bool {
+		return lessByNameID(i.Storage[a].Name, i.Storage[a].ID, i.Storage[b].Name, i.Storage[b].ID)
+	})
+	sort.SliceStable(i.ResourcePools, func(a, b int) bool {
+		return lessByNameID(i.ResourcePools[a].Name, i.ResourcePools[a].ID, i.ResourcePools[b].Name, i.ResourcePools[b].ID)
+	})
+	sort.SliceStable(i.Templates, func(a, b int) bool {
+		return lessByNameID(i.Templates[a].Name, i.Templates[a].ID, i.Templates[b].Name, i.Templates[b].ID)
+	})
+	sort.SliceStable(i.Clusters, func(a, b int) bool {
+		return lessByNameID(i.Clusters[a].Name, i.Clusters[a].ID, i.Clusters[b].Name, i.Clusters[b].ID)
+	})
+	sort.SliceStable(i.Hosts, func(a, b int) bool {
+		return lessByNameID(i.Hosts[a].Name, i.Hosts[a].ID, i.Hosts[b].Name, i.Hosts[b].ID)
+	})
+	sort.SliceStable(i.HAGroups, func(a, b int) bool {
+		return i.HAGroups[a].Name < i.HAGroups[b].Name
+	})
+	sort.SliceStable(i.ContentLibraries, func(a, b int) bool {
+		return lessByNameID(i.ContentLibraries[a].Name, i.ContentLibraries[a].ID, i.ContentLibraries[b].Name, i.ContentLibraries[b].ID)
+	})
+}
+
+// lessByNameID orders by name, falling back to ID for duplicate names
+func lessByNameID(nameA, idA, nameB, idB string) bool {
+	if nameA != nameB {
+		return nameA < nameB
+	}
+	return idA < idB
+}
+
 // VirtualMachine represents a discovered virtual machine
 type VirtualMachine struct {
 	ID              string                 `json:"id" yaml:"id"`
 	Name            string                 `json:"name" yaml:"name"`
 	State           string                 `json:"state" yaml:"state"`
 	PowerState      string                 `json:"power_state" yaml:"power_state"`
+	Type            string                 `json:"type,omitempty" yaml:"type,omitempty"` // qemu or lxc on Proxmox; empty for other providers
 	OperatingSystem string                 `json:"operating_system,omitempty" yaml:"operating_system,omitempty"`
 	CPUs            int                    `json:"cpus" yaml:"cpus"`
-	Memory          int64                  `json:"memory" yaml:"memory"` // Memory in MB
+	Memory          int64                  `json:"memory" yaml:"memory"` // Memory in MiB
 	Disks           []Disk                 `json:"disks" yaml:"disks"`
 	NetworkCards    []NetworkCard          `json:"network_cards" yaml:"network_cards"`
+	ISOs            []string               `json:"isos,omitempty" yaml:"isos,omitempty"` // ISO images attached as CD-ROMs, as storage volume IDs
 	Annotations     map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 	Tags            []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
 	ResourcePool    string                 `json:"resource_pool,omitempty" yaml:"resource_pool,omitempty"`
 	Folder          string                 `json:"folder,omitempty" yaml:"folder,omitempty"`
 	Host            string                 `json:"host,omitempty" yaml:"host,omitempty"`
+	Hostname        string                 `json:"hostname,omitempty" yaml:"hostname,omitempty"` // guest host name as reported by guest tools
+	IPAddress       string                 `json:"ip_address,omitempty" yaml:"ip_address,omitempty"` // primary guest IP as reported by guest tools
+	IPAddresses     []string               `json:"ip_addresses,omitempty" yaml:"ip_addresses,omitempty"` // all guest IPs as reported by guest tools
+	Owner           string                 `json:"owner,omitempty" yaml:"owner,omitempty"`
+	Environment     string                 `json:"environment,omitempty" yaml:"environment,omitempty"`
+	HA              *HAResource            `json:"ha,omitempty" yaml:"ha,omitempty"`
+	Complexity      int                    `json:"complexity,omitempty" yaml:"complexity,omitempty"` // migration complexity score, see Metadata["complexity_factors"]
+	Snapshots       []Snapshot             `json:"snapshots,omitempty" yaml:"snapshots,omitempty"` // root snapshots, each with its children
 	Tools           VMTools                `json:"tools,omitempty" yaml:"tools,omitempty"`
 	Hardware        HardwareInfo           `json:"hardware" yaml:"hardware"`
 	Config          VMConfig               `json:"config" yaml:"config"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
 
+// Proxmox guest types
+const (
+	VMTypeQEMU = "qemu"
+	VMTypeLXC  = "lxc"
+)
+
+// IsContainer reports whether the guest is a container rather than a virtual machine
+func (vm VirtualMachine) IsContainer() bool {
+	return vm.Type == VMTypeLXC
+}
+
+// PrimaryIPv4 returns the primary IP when it is IPv4, or else the first
+// IPv4 address of the guest, or "" when none was discovered
+func (vm VirtualMachine) PrimaryIPv4() string {
+	for _, address := range append([]string{vm.IPAddress}, vm.IPAddresses...) {
+		if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+			return address
+		}
+	}
+	return ""
+}
+
 // Disk represents a virtual disk
 type Disk struct {
 	ID           string `json:"id" yaml:"id"`
 	Name         string `json:"name,omitempty" yaml:"name,omitempty"`
-	Size         int64  `json:"size" yaml:"size"` // Size in GB
+	Size         int64  `json:"size" yaml:"size"` // Size in GiB
 	Type         string `json:"type" yaml:"type"` // thick, thin, etc.
 	Datastore    string `json:"datastore" yaml:"datastore"`
+	DatastoreID  string `json:"datastore_id,omitempty" yaml:"datastore_id,omitempty"` // provider ID of the datastore, where names are not unique
 	Path         string `json:"path,omitempty" yaml:"path,omitempty"`
 	SCSI         string `json:"scsi,omitempty" yaml:"scsi,omitempty"`
 	Controller   string `json:"controller,omitempty" yaml:"controller,omitempty"`
 	Unit         int    `json:"unit,omitempty" yaml:"unit,omitempty"`
+	LUN          string `json:"lun,omitempty" yaml:"lun,omitempty"` // LUN UUID backing a raw device mapping
+	Mode         string `json:"mode,omitempty" yaml:"mode,omitempty"` // persistent, independent_persistent, etc.
+	ChainDepth   int    `json:"chain_depth,omitempty" yaml:"chain_depth,omitempty"` // delta disks stacked on the base disk; 0 for a flat disk
+	ParentPath   string `json:"parent_path,omitempty" yaml:"parent_path,omitempty"` // first backing file outside the VM's own folder, set for linked clones
+}
+
+// Raw device mapping disk types
+const (
+	DiskTypeRDMPhysical = "rdm-physical"
+	DiskTypeRDMVirtual  = "rdm-virtual"
+)
+
+// IsIndependent reports whether the disk is excluded from snapshots
+func (d Disk) IsIndependent() bool {
+	return strings.HasPrefix(d.Mode, "independent")
 }
 
+// IsRDM reports whether the disk is a raw device mapping
+func (d Disk) IsRDM() bool {
+	return d.Type == DiskTypeRDMPhysical || d.Type == DiskTypeRDMVirtual
+}
+
+// Network card types recorded at discovery
+const (
+	NICTypeVmxnet3 = "vmxnet3"
+	NICTypeVmxnet2 = "vmxnet2"
+	NICTypeVmxnet  = "vmxnet"
+	NICTypeE1000   = "e1000"
+	NICTypeE1000e  = "e1000e"
+	NICTypePCNet32 = "pcnet32"
+	NICTypeSRIOV   = "sriov"
+	NICTypeUnknown = "unknown"
+)
+
 // NetworkCard represents a virtual network card
 type NetworkCard struct {
 	ID          string `json:"id" yaml:"id"`
@@ -62,8 +186,66 @@ type NetworkCard struct {
 	Type        string `json:"type" yaml:"type"` // vmxnet3, e1000, etc.
 	Network     string `json:"network" yaml:"network"`
 	MACAddress  string `json:"mac_address,omitempty" yaml:"mac_address,omitempty"`
+	Key         int32  `json:"key,omitempty" yaml:"key,omitempty"`           // device key, the order the guest sees
+	PCISlot     int32  `json:"pci_slot,omitempty" yaml:"pci_slot,omitempty"` // PCI slot number, if assigned
 	Connected   bool   `json:"connected" yaml:"connected"`
 	StartConnect bool   `json:"start_connect" yaml:"start_connect"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// Snapshot is one VM snapshot and, in Children, the snapshots taken from
+// it. Parent links each child back to the snapshot it was taken from.
+type Snapshot struct {
+	ID          string     `json:"id" yaml:"id"`
+	Name        string     `json:"name" yaml:"name"`
+	Description string     `json:"description,omitempty" yaml:"description,omitempty"`
+	CreateTime  time.Time  `json:"create_time" yaml:"create_time"`
+	Size        int64      `json:"size,omitempty" yaml:"size,omitempty"` // Size in MiB of the snapshot's files and the delta disks it froze, when known
+	Quiesced    bool       `json:"quiesced" yaml:"quiesced"`
+	Parent      string     `json:"parent,omitempty" yaml:"parent,omitempty"`   // ID of the parent snapshot; empty for a root
+	Current     bool       `json:"current,omitempty" yaml:"current,omitempty"` // the VM runs from this snapshot's state
+	Children    []Snapshot `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// SnapshotTree nests snapshots listed flat, as discovery files written
+// before snapshots had children list them, under their parents. Snapshots
+// whose parent is not listed are roots; a tree is returned unchanged.
+func SnapshotTree(snapshots []Snapshot) []Snapshot {
+	ids := make(map[string]bool, len(snapshots))
+	for _, snapshot := range snapshots {
+		ids[snapshot.ID] = true
+	}
+
+	children := make(map[string][]Snapshot)
+	var roots []Snapshot
+	for _, snapshot := range snapshots {
+		if ids[snapshot.Parent] {
+			children[snapshot.Parent] = append(children[snapshot.Parent], snapshot)
+			continue
+		}
+		roots = append(roots, snapshot)
+	}
+	if len(children) == 0 {
+		return snapshots
+	}
+
+	var attach func(tree []Snapshot) []Snapshot
+	attach = func(tree []Snapshot) []Snapshot {
+		for i := range tree {
+			tree[i].Children = append(tree[i].Children, attach(children[tree[i].ID])...)
+		}
+		return tree
+	}
+	return attach(roots)
+}
+
+// CountSnapshots counts the snapshots in a snapshot tree
+func CountSnapshots(tree []Snapshot) int {
+	count := len(tree)
+	for _, snapshot := range tree {
+		count += CountSnapshots(snapshot.Children)
+	}
+	return count
 }
 
 // VMTools represents VMware Tools information
@@ -80,6 +262,20 @@ type HardwareInfo struct {
 	NumCoresPerSocket int  `json:"num_cores_per_socket" yaml:"num_cores_per_socket"`
 	MemoryMB        int64  `json:"memory_mb" yaml:"memory_mb"`
 	Firmware        string `json:"firmware" yaml:"firmware"` // BIOS, EFI
+	VideoRAMKB      int64  `json:"video_ram_kb,omitempty" yaml:"video_ram_kb,omitempty"`
+	NumDisplays     int    `json:"num_displays,omitempty" yaml:"num_displays,omitempty"`
+	Enable3D        bool   `json:"enable_3d,omitempty" yaml:"enable_3d,omitempty"`
+	GraphicsMemoryKB int64 `json:"graphics_memory_kb,omitempty" yaml:"graphics_memory_kb,omitempty"` // 3D graphics memory
+}
+
+// VersionNumber returns the VMware virtual hardware version as a number,
+// e.g. 13 for "vmx-13", or 0 when it is unknown
+func (h HardwareInfo) VersionNumber() int {
+	n, err := strconv.Atoi(strings.TrimPrefix(h.Version, "vmx-"))
+	if err != nil {
+		return 0
+	}
+	return n
 }
 
 // VMConfig represents virtual machine configuration
@@ -90,6 +286,7 @@ type VMConfig struct {
 	InstanceUUID     string `json:"instance_uuid,omitempty" yaml:"instance_uuid,omitempty"`
 	ChangeVersion    string `json:"change_version,omitempty" yaml:"change_version,omitempty"`
 	Modified         time.Time `json:"modified,omitempty" yaml:"modified,omitempty"`
+	LinkedCloneOf    string `json:"linked_clone_of,omitempty" yaml:"linked_clone_of,omitempty"` // VM or template whose disks this VM's delta disks are based on
 }
 
 // Network represents a discovered network
@@ -104,25 +301,58 @@ type Network struct {
 	DNS         []string               `json:"dns,omitempty" yaml:"dns,omitempty"`
 	DHCP        bool                   `json:"dhcp" yaml:"dhcp"`
 	Bridge      string                 `json:"bridge,omitempty" yaml:"bridge,omitempty"`
+	MTU         int                    `json:"mtu,omitempty" yaml:"mtu,omitempty"` // of the switch the network is on
+	SecurityPolicy *SecurityPolicy     `json:"security_policy,omitempty" yaml:"security_policy,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
 
+// SecurityPolicy is the layer 2 security policy of a port group; each
+// setting is true when the port group accepts it
+type SecurityPolicy struct {
+	AllowPromiscuous bool `json:"allow_promiscuous" yaml:"allow_promiscuous"`
+	MACChanges       bool `json:"mac_changes" yaml:"mac_changes"`
+	ForgedTransmits  bool `json:"forged_transmits" yaml:"forged_transmits"`
+}
+
 // Storage represents discovered storage
 type Storage struct {
 	ID          string                 `json:"id" yaml:"id"`
 	Name        string                 `json:"name" yaml:"name"`
 	Type        string                 `json:"type" yaml:"type"` // VMFS, NFS, local, etc.
-	Capacity    int64                  `json:"capacity" yaml:"capacity"` // Capacity in GB
-	FreeSpace   int64                  `json:"free_space" yaml:"free_space"` // Free space in GB
-	UsedSpace   int64                  `json:"used_space" yaml:"used_space"` // Used space in GB
+	Capacity    int64                  `json:"capacity" yaml:"capacity"` // Capacity in GiB
+	FreeSpace   int64                  `json:"free_space" yaml:"free_space"` // Free space in GiB
+	UsedSpace   int64                  `json:"used_space" yaml:"used_space"` // Used space in GiB
 	URL         string                 `json:"url,omitempty" yaml:"url,omitempty"`
 	Accessible  bool                   `json:"accessible" yaml:"accessible"`
 	Multipath   bool                   `json:"multipath,omitempty" yaml:"multipath,omitempty"`
 	SSD         bool                   `json:"ssd,omitempty" yaml:"ssd,omitempty"`
 	Local       bool                   `json:"local,omitempty" yaml:"local,omitempty"`
+	Contents    *StorageContents       `json:"contents,omitempty" yaml:"contents,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
 
+// StorageContents summarizes the volumes a storage holds by content type,
+// e.g. iso, vztmpl, images, rootdir or backup
+type StorageContents struct {
+	Summary map[string]ContentSummary `json:"summary" yaml:"summary"`
+	Items   []StorageItem             `json:"items,omitempty" yaml:"items,omitempty"` // full listing, only on a deep scan
+}
+
+// ContentSummary counts the volumes of one content type
+type ContentSummary struct {
+	Count int   `json:"count" yaml:"count"`
+	Size  int64 `json:"size" yaml:"size"` // Total size in MiB
+}
+
+// StorageItem is a single volume on a storage
+type StorageItem struct {
+	VolumeID string `json:"volid" yaml:"volid"`
+	Content  string `json:"content" yaml:"content"`
+	Format   string `json:"format,omitempty" yaml:"format,omitempty"`
+	Size     int64  `json:"size" yaml:"size"` // Size in MiB
+	VMID     int    `json:"vmid,omitempty" yaml:"vmid,omitempty"`
+}
+
 // ResourcePool represents a resource pool
 type ResourcePool struct {
 	ID          string                 `json:"id" yaml:"id"`
@@ -143,6 +373,45 @@ type ResourceAllocation struct {
 	SharesValue int32  `json:"shares_value,omitempty" yaml:"shares_value,omitempty"`
 }
 
+// HAGroup represents a high availability group restricting which nodes
+// HA-managed VMs may run on (Proxmox /cluster/ha/groups)
+type HAGroup struct {
+	Name       string   `json:"name" yaml:"name"`
+	Nodes      []string `json:"nodes" yaml:"nodes"` // node[:priority]
+	Restricted bool     `json:"restricted" yaml:"restricted"`
+	NoFailback bool     `json:"nofailback" yaml:"nofailback"`
+	Comment    string   `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// HAResource represents a VM's high availability configuration and state
+type HAResource struct {
+	Group       string `json:"group,omitempty" yaml:"group,omitempty"`
+	State       string `json:"state" yaml:"state"` // started, stopped, disabled, ignored
+	MaxRestart  int    `json:"max_restart" yaml:"max_restart"`
+	MaxRelocate int    `json:"max_relocate" yaml:"max_relocate"`
+	Status      string `json:"status,omitempty" yaml:"status,omitempty"` // current HA manager status
+}
+
+// ContentLibrary is a vSphere content library and the items it holds
+type ContentLibrary struct {
+	ID              string               `json:"id" yaml:"id"`
+	Name            string               `json:"name" yaml:"name"`
+	Type            string               `json:"type" yaml:"type"` // LOCAL or SUBSCRIBED
+	Description     string               `json:"description,omitempty" yaml:"description,omitempty"`
+	Datastores      []string             `json:"datastores,omitempty" yaml:"datastores,omitempty"` // datastore IDs backing the library
+	SubscriptionURL string               `json:"subscription_url,omitempty" yaml:"subscription_url,omitempty"`
+	Items           []ContentLibraryItem `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
+// ContentLibraryItem is one OVF template, VM template, ISO or file in a
+// content library
+type ContentLibraryItem struct {
+	ID   string `json:"id" yaml:"id"`
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type,omitempty" yaml:"type,omitempty"` // ovf, vm-template, iso or file
+	Size int64  `json:"size" yaml:"size"`                     // Size in MiB
+}
+
 // Template represents a virtual machine template
 type Template struct {
 	ID              string                 `json:"id" yaml:"id"`
@@ -152,6 +421,7 @@ type Template struct {
 	Memory          int64                  `json:"memory" yaml:"memory"`
 	Disks           []Disk                 `json:"disks" yaml:"disks"`
 	NetworkCards    []NetworkCard          `json:"network_cards" yaml:"network_cards"`
+	ISOs            []string               `json:"isos,omitempty" yaml:"isos,omitempty"` // ISO images attached as CD-ROMs, as storage volume IDs
 	Annotations     map[string]string      `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 	Tags            []string               `json:"tags,omitempty" yaml:"tags,omitempty"`
 	Folder          string                 `json:"folder,omitempty" yaml:"folder,omitempty"`
@@ -166,8 +436,11 @@ type Host struct {
 	Version         string                 `json:"version" yaml:"version"`
 	State           string                 `json:"state" yaml:"state"`
 	ConnectionState string                 `json:"connection_state" yaml:"connection_state"`
-	CPU             HostResource           `json:"cpu" yaml:"cpu"`
-	Memory          HostResource           `json:"memory" yaml:"memory"`
+	CPUModel        string                 `json:"cpu_model,omitempty" yaml:"cpu_model,omitempty"`
+	CPUGeneration   string                 `json:"cpu_generation,omitempty" yaml:"cpu_generation,omitempty"` // newest EVC mode the CPU supports, e.g. intel-icelake
+	CPUCores        int                    `json:"cpu_cores,omitempty" yaml:"cpu_cores,omitempty"` // physical cores, or logical CPUs where only those are reported
+	CPU             HostResource           `json:"cpu" yaml:"cpu"`                                 // MHz on ESXi, CPUs on Proxmox and Nutanix
+	Memory          HostResource           `json:"memory" yaml:"memory"`                           // MiB
 	Storage         []Storage              `json:"storage" yaml:"storage"`
 	Networks        []Network              `json:"networks" yaml:"networks"`
 	VMs             []string               `json:"vms" yaml:"vms"`
@@ -192,6 +465,7 @@ type Cluster struct {
 	ResourcePools   []string               `json:"resource_pools,omitempty" yaml:"resource_pools,omitempty"`
 	DRS             bool                   `json:"drs,omitempty" yaml:"drs,omitempty"`
 	HA              bool                   `json:"ha,omitempty" yaml:"ha,omitempty"`
+	EVCMode         string                 `json:"evc_mode,omitempty" yaml:"evc_mode,omitempty"` // EVC baseline, e.g. intel-skylake; empty when EVC is off
 	VMs             []string               `json:"vms" yaml:"vms"`
 	TotalCPU        int64                  `json:"total_cpu" yaml:"total_cpu"`
 	TotalMemory     int64                  `json:"total_memory" yaml:"total_memory"`
@@ -213,3 +487,54 @@ type Datacenter struct {
 	Storage  []string               `json:"datastores,omitempty" yaml:"datastores,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`
 }
+
+// Permission represents a role granted to a principal on an inventory object
+type Permission struct {
+	Principal     string `json:"principal" yaml:"principal"` // raw principal, e.g. VSPHERE.LOCAL\app-team
+	Name          string `json:"name" yaml:"name"`
+	Domain        string `json:"domain,omitempty" yaml:"domain,omitempty"`
+	PrincipalType string `json:"principal_type" yaml:"principal_type"` // user, group
+	FullName      string `json:"full_name,omitempty" yaml:"full_name,omitempty"`
+	Resolved      bool   `json:"resolved" yaml:"resolved"`
+	Source        string `json:"source,omitempty" yaml:"source,omitempty"`             // directory that resolved the principal: ldap, sso or vcenter
+	Enabled       *bool  `json:"enabled,omitempty" yaml:"enabled,omitempty"`           // unset when the directory doesn't report account state
+	MemberCount   *int   `json:"member_count,omitempty" yaml:"member_count,omitempty"` // direct members of a group
+	Role          string `json:"role,omitempty" yaml:"role,omitempty"`
+	RoleID        int32  `json:"role_id" yaml:"role_id"`
+	Entity        string `json:"entity,omitempty" yaml:"entity,omitempty"`
+	EntityType    string `json:"entity_type,omitempty" yaml:"entity_type,omitempty"`
+	Propagate     bool   `json:"propagate" yaml:"propagate"`
+	Note          string `json:"note,omitempty" yaml:"note,omitempty"`
+}
+
+// Finding represents a policy or configuration finding raised during discovery
+type Finding struct {
+	Severity string `json:"severity" yaml:"severity"` // info, warning, error
+	Rule     string `json:"rule" yaml:"rule"`
+	Resource string `json:"resource" yaml:"resource"`
+	Message  string `json:"message" yaml:"message"`
+}
+
+// RebalancePlan proposes storage vMotions that bring datastores above the
+// utilization threshold back under it. It is worked out from the discovered
+// model alone and is advisory; nothing is moved.
+type RebalancePlan struct {
+	Advisory   string          `json:"advisory" yaml:"advisory"`
+	Threshold  float64         `json:"threshold" yaml:"threshold"` // utilization percent datastores should stay at or under
+	Moves      []StorageMove   `json:"moves,omitempty" yaml:"moves,omitempty"`
+	Unresolved []OverThreshold `json:"unresolved,omitempty" yaml:"unresolved,omitempty"` // datastores the moves cannot bring under the threshold
+}
+
+// StorageMove is one proposed move of a VM's disks between datastores
+type StorageMove struct {
+	VM   string `json:"vm" yaml:"vm"`
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	Size int64  `json:"size" yaml:"size"` // Size in GiB
+}
+
+// OverThreshold is a datastore left above the threshold
+type OverThreshold struct {
+	Datastore   string  `json:"datastore" yaml:"datastore"`
+	Utilization float64 `json:"utilization" yaml:"utilization"` // percent used after the proposed moves
+}
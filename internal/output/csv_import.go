@@ -0,0 +1,220 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// CSVColumns is the column layout shared by CSV output and CSV import.
+//
+// Each row describes one resource, identified by Resource_Type (VM, Network
+// or Storage). VM rows use State, CPUs, Memory_MB, OS, Host and Network
+// (network names separated by ";"). Network rows use Type, VLAN, MTU and the
+// security policy columns Promiscuous, MAC_Changes and Forged_Transmits
+// (accept or reject; all empty when no policy was discovered). Storage
+// rows use Type, Capacity_GB and Free_GB. Rows sharing Provider, Server,
+// Datacenter, Cluster and Node are grouped into one infrastructure.
+var CSVColumns = []string{
+	"Provider", "Server", "Datacenter", "Cluster", "Node", "Resource_Type", "Name",
+	"State", "CPUs", "Memory_MB", "OS", "Host", "Type", "Capacity_GB", "Free_GB",
+	"VLAN", "Network", "MTU", "Promiscuous", "MAC_Changes", "Forged_Transmits",
+}
+
+// ParseCSV builds infrastructures from CSV in the CSVColumns layout. Columns
+// are matched by header name, so they may appear in any order and optional
+// columns may be omitted.
+func ParseCSV(r io.Reader) ([]*models.Infrastructure, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	// Spreadsheet exports often start with a UTF-8 byte order mark
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"provider", "server", "resource_type", "name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV is missing required column: %s", required)
+		}
+	}
+
+	var infrastructures []*models.Infrastructure
+	byKey := make(map[string]*models.Infrastructure)
+	now := time.Now()
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			i, ok := columns[strings.ToLower(name)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		// Skip blank rows, which spreadsheets often leave at the end
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		provider := strings.ToLower(field("Provider"))
+		server := field("Server")
+		if provider == "" || server == "" {
+			return nil, fmt.Errorf("CSV line %d: provider and server are required", line)
+		}
+
+		key := strings.Join([]string{provider, server, field("Datacenter"), field("Cluster"), field("Node")}, "|")
+		infra, exists := byKey[key]
+		if !exists {
+			infra = &models.Infrastructure{
+				Provider:      provider,
+				Server:        server,
+				Datacenter:    field("Datacenter"),
+				Cluster:       field("Cluster"),
+				Node:          field("Node"),
+				DiscoveryTime: now,
+				Metadata:      map[string]interface{}{"source": "csv"},
+			}
+			byKey[key] = infra
+			infrastructures = append(infrastructures, infra)
+		}
+
+		name := field("Name")
+		if name == "" {
+			return nil, fmt.Errorf("CSV line %d: name is required", line)
+		}
+
+		switch strings.ToLower(field("Resource_Type")) {
+		case "vm":
+			cpus, err := parseCSVInt(field("CPUs"), "CPUs", line)
+			if err != nil {
+				return nil, err
+			}
+			memory, err := parseCSVInt(field("Memory_MB"), "Memory_MB", line)
+			if err != nil {
+				return nil, err
+			}
+
+			vm := models.VirtualMachine{
+				ID:              name,
+				Name:            name,
+				State:           field("State"),
+				PowerState:      field("State"),
+				OperatingSystem: field("OS"),
+				CPUs:            int(cpus),
+				Memory:          memory,
+				Host:            field("Host"),
+				Hardware: models.HardwareInfo{
+					NumCPU:   int(cpus),
+					MemoryMB: memory,
+				},
+			}
+			for i, network := range strings.Split(field("Network"), ";") {
+				network = strings.TrimSpace(network)
+				if network == "" {
+					continue
+				}
+				vm.NetworkCards = append(vm.NetworkCards, models.NetworkCard{
+					ID:        fmt.Sprintf("nic-%d", i),
+					Network:   network,
+					Connected: true,
+				})
+			}
+			infra.VirtualMachines = append(infra.VirtualMachines, vm)
+
+		case "network":
+			vlan, err := parseCSVInt(field("VLAN"), "VLAN", line)
+			if err != nil {
+				return nil, err
+			}
+			mtu, err := parseCSVInt(field("MTU"), "MTU", line)
+			if err != nil {
+				return nil, err
+			}
+			network := models.Network{
+				ID:   name,
+				Name: name,
+				Type: field("Type"),
+				VLAN: int(vlan),
+				MTU:  int(mtu),
+			}
+			if field("Promiscuous") != "" || field("MAC_Changes") != "" || field("Forged_Transmits") != "" {
+				network.SecurityPolicy = &models.SecurityPolicy{}
+				for _, setting := range []struct {
+					column string
+					value  *bool
+				}{
+					{"Promiscuous", &network.SecurityPolicy.AllowPromiscuous},
+					{"MAC_Changes", &network.SecurityPolicy.MACChanges},
+					{"Forged_Transmits", &network.SecurityPolicy.ForgedTransmits},
+				} {
+					switch strings.ToLower(field(setting.column)) {
+					case "accept":
+						*setting.value = true
+					case "reject", "":
+					default:
+						return nil, fmt.Errorf("CSV line %d: invalid %s value %q (expected accept or reject)", line, setting.column, field(setting.column))
+					}
+				}
+			}
+			infra.Networks = append(infra.Networks, network)
+
+		case "storage":
+			capacity, err := parseCSVInt(field("Capacity_GB"), "Capacity_GB", line)
+			if err != nil {
+				return nil, err
+			}
+			free, err := parseCSVInt(field("Free_GB"), "Free_GB", line)
+			if err != nil {
+				return nil, err
+			}
+			infra.Storage = append(infra.Storage, models.Storage{
+				ID:         name,
+				Name:       name,
+				Type:       field("Type"),
+				Capacity:   capacity,
+				FreeSpace:  free,
+				UsedSpace:  capacity - free,
+				Accessible: true,
+			})
+
+		default:
+			return nil, fmt.Errorf("CSV line %d: unknown resource type %q (expected VM, Network or Storage)", line, field("Resource_Type"))
+		}
+	}
+
+	return infrastructures, nil
+}
+
+// parseCSVInt parses an optional integer column, treating empty as zero
+func parseCSVInt(value, column string, line int) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("CSV line %d: invalid %s value %q", line, column, value)
+	}
+	return n, nil
+}
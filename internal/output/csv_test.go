@@ -0,0 +1,65 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestCSVGolden(t *testing.T) {
+	infrastructures := testInfrastructures()
+	infrastructures[0].Networks = append(infrastructures[0].Networks, models.Network{
+		ID: "dvportgroup-1", Name: `DMZ "edge"`, Type: "DistributedVirtualPortgroup", VLAN: 20, MTU: 9000,
+		SecurityPolicy: &models.SecurityPolicy{AllowPromiscuous: false, MACChanges: true, ForgedTransmits: true},
+	})
+
+	data, err := NewFormatter("csv").Format(infrastructures)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "infrastructure.csv")
+	if *update {
+		if err := os.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("CSV output differs from %s; rerun with -update if the change is intended\ngot:\n%s", golden, data)
+	}
+
+	// Every row must parse back to the same columns, commas and quotes included
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	for i, record := range records {
+		if len(record) != len(CSVColumns) {
+			t.Errorf("row %d has %d columns, want %d", i, len(record), len(CSVColumns))
+		}
+	}
+	if got := records[2][columnIndex("Name")]; got != "db, primary" {
+		t.Errorf("VM name read back as %q", got)
+	}
+}
+
+// columnIndex returns the position of a CSVColumns column
+func columnIndex(name string) int {
+	for i, column := range CSVColumns {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,136 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/generators"
+	"valhalla/internal/models"
+)
+
+// dotStyles are the node attributes of each resource kind in DOT output
+var dotStyles = map[string]string{
+	"host":      `shape=box, style=filled, fillcolor="#9ecae1"`,
+	"vm":        `shape=ellipse, style=filled, fillcolor="#fdd49e"`,
+	"network":   `shape=diamond, style=filled, fillcolor="#a1d99b"`,
+	"datastore": `shape=cylinder, style=filled, fillcolor="#d9d9d9"`,
+}
+
+// dotGraph assigns node IDs and collects the statements of a DOT graph
+type dotGraph struct {
+	b   strings.Builder
+	ids map[string]string // kind, infrastructure and name to node ID
+	use map[string]bool   // node IDs taken
+}
+
+// formatDOT formats output as a Graphviz DOT graph of each infrastructure's
+// topology: hosts, VMs, networks and datastores, with edges from each VM to
+// its host, the networks of its NICs and the datastores of its disks.
+// Render it with e.g. dot -Tpng topo.dot -o topo.png.
+func (f *Formatter) formatDOT(infrastructures []*models.Infrastructure) ([]byte, error) {
+	g := &dotGraph{ids: make(map[string]string), use: make(map[string]bool)}
+
+	g.b.WriteString("digraph valhalla {\n")
+	g.b.WriteString("  rankdir=LR;\n")
+	g.b.WriteString("  node [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for i, infra := range infrastructures {
+		scope := fmt.Sprint(i)
+		g.b.WriteString(fmt.Sprintf("\n  subgraph cluster_%d {\n", i))
+		g.b.WriteString(fmt.Sprintf("    label=%s;\n", dotQuote(fmt.Sprintf("%s (%s)", strings.ToUpper(infra.Provider), infra.Server))))
+
+		for _, host := range infra.Hosts {
+			g.node(scope, "host", host.Name, host.Name)
+		}
+		for _, network := range infra.Networks {
+			label := network.Name
+			if network.VLAN > 0 {
+				label += fmt.Sprintf("\nVLAN %d", network.VLAN)
+			}
+			g.node(scope, "network", network.Name, label)
+		}
+		for _, store := range infra.Storage {
+			g.node(scope, "datastore", store.Name, fmt.Sprintf("%s\n%d GiB", store.Name, store.Capacity))
+		}
+
+		for _, vm := range infra.VirtualMachines {
+			if vm.Config.Template {
+				continue
+			}
+			// VMs of the same name in different folders are separate nodes
+			id := g.declare("vm", vm.Name, fmt.Sprintf("%s\n%d vCPU, %d MiB", vm.Name, vm.CPUs, vm.Memory))
+
+			// Resources only referenced by a VM still get a node
+			if vm.Host != "" {
+				g.edge(id, g.node(scope, "host", vm.Host, vm.Host), "")
+			}
+			linked := make(map[string]bool)
+			for _, nic := range vm.NetworkCards {
+				if nic.Network == "" || linked["network:"+nic.Network] {
+					continue
+				}
+				linked["network:"+nic.Network] = true
+				g.edge(id, g.node(scope, "network", nic.Network, nic.Network), "style=dashed")
+			}
+			for _, disk := range vm.Disks {
+				if disk.Datastore == "" || linked["datastore:"+disk.Datastore] {
+					continue
+				}
+				linked["datastore:"+disk.Datastore] = true
+				g.edge(id, g.node(scope, "datastore", disk.Datastore, disk.Datastore), "style=dotted")
+			}
+		}
+
+		g.b.WriteString("  }\n")
+	}
+
+	g.b.WriteString("}\n")
+	return []byte(g.b.String()), nil
+}
+
+// node returns the ID of a resource's node, declaring it the first time the
+// resource is seen in the infrastructure
+func (g *dotGraph) node(scope, kind, name, label string) string {
+	key := kind + "\x00" + scope + "\x00" + name
+	if id, ok := g.ids[key]; ok {
+		return id
+	}
+	id := g.declare(kind, name, label)
+	g.ids[key] = id
+	return id
+}
+
+// declare writes a new node and returns its ID. IDs are sanitized names
+// prefixed with the kind; names that sanitize to a taken ID get a numeric
+// suffix.
+func (g *dotGraph) declare(kind, name, label string) string {
+	base := kind + "_" + generators.SanitizeIdentifier(name)
+	id := base
+	for n := 2; g.use[id]; n++ {
+		id = fmt.Sprintf("%s_%d", base, n)
+	}
+	g.use[id] = true
+
+	g.b.WriteString(fmt.Sprintf("    %s [label=%s, %s];\n", id, dotQuote(label), dotStyles[kind]))
+	return id
+}
+
+// edge writes an edge between two nodes with optional attributes
+func (g *dotGraph) edge(from, to, attrs string) {
+	if attrs != "" {
+		g.b.WriteString(fmt.Sprintf("    %s -> %s [%s];\n", from, to, attrs))
+		return
+	}
+	g.b.WriteString(fmt.Sprintf("    %s -> %s;\n", from, to))
+}
+
+// dotQuote quotes a value as a DOT string. Newlines become centered line
+// breaks; other backslashes are escaped so Graphviz doesn't read them as
+// label escapes.
+func dotQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\r", "")
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return `"` + value + `"`
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// PassphraseEnv names the environment variable holding the passphrase of
+// encrypted discovery results. It is only read from the environment so the
+// passphrase never shows up in shell history or process listings.
+const PassphraseEnv = "VALHALLA_PASSPHRASE"
+
+// EncryptedSuffix is the conventional extension of encrypted results, e.g.
+// discovery.json.enc
+const EncryptedSuffix = ".enc"
+
+var (
+	// ErrNoPassphrase is returned when encrypted results are read or
+	// written without VALHALLA_PASSPHRASE set
+	ErrNoPassphrase = errors.New("encrypted results need a passphrase in " + PassphraseEnv)
+
+	// ErrBadPassphrase is returned when encrypted results don't decrypt,
+	// because the passphrase is wrong or the file was modified
+	ErrBadPassphrase = errors.New("wrong passphrase or corrupted encrypted results")
+)
+
+// encryptedMagic starts every encrypted file; it is followed by the scrypt
+// salt, the AES-GCM nonce and the sealed content
+var encryptedMagic = []byte("VALHALLA-ENCRYPTED-1\n")
+
+const (
+	saltSize = 16
+	keySize  = 32
+
+	// scrypt cost parameters recommended for interactive use
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Passphrase returns the passphrase set in VALHALLA_PASSPHRASE
+func Passphrase() (string, error) {
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		return "", ErrNoPassphrase
+	}
+	return passphrase, nil
+}
+
+// IsEncrypted reports whether data was written by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedMagic)
+}
+
+// Encrypt seals data with AES-256-GCM under a key derived from passphrase
+// with scrypt and a random salt
+func Encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	out := append([]byte{}, encryptedMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	// The header is authenticated too, so it cannot be swapped
+	return aead.Seal(out, nonce, data, out), nil
+}
+
+// Decrypt opens data sealed by Encrypt
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, fmt.Errorf("not encrypted discovery results")
+	}
+	rest := data[len(encryptedMagic):]
+	if len(rest) < saltSize {
+		return nil, ErrBadPassphrase
+	}
+	salt := rest[:saltSize]
+
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	headerSize := len(encryptedMagic) + saltSize + aead.NonceSize()
+	if len(data) < headerSize {
+		return nil, ErrBadPassphrase
+	}
+	nonce := data[len(encryptedMagic)+saltSize : headerSize]
+
+	plain, err := aead.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plain, nil
+}
+
+// newAEAD derives the key for salt and returns its AES-GCM cipher
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -1,19 +1,40 @@
 package output
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/olekukonko/tablewriter"
 	"gopkg.in/yaml.v3"
+	"valhalla/internal/enrich"
 	"valhalla/internal/models"
 )
 
 // Formatter handles output formatting for discovery results
 type Formatter struct {
-	format string
+	format        string
+	yamlDocuments bool
+	maxFieldSize  int
+	fields        [][]string
+}
+
+// Formats lists the supported output formats
+var Formats = []string{"table", "json", "yaml", "csv", "summary", "html", "markdown", "dot"}
+
+// ValidateFormat checks that format is a supported output format, so a typo
+// is reported before discovery rather than after
+func ValidateFormat(format string) error {
+	switch strings.ToLower(format) {
+	case "table", "json", "yaml", "yml", "csv", "summary", "html", "markdown", "md", "dot":
+		return nil
+	}
+	return fmt.Errorf("unsupported output format: %s (supported: %s)", format, strings.Join(Formats, ", "))
 }
 
 // NewFormatter creates a new output formatter
@@ -23,8 +44,41 @@ func NewFormatter(format string) *Formatter {
 	}
 }
 
+// WithYAMLDocuments makes YAML output emit one document per infrastructure
+func (f *Formatter) WithYAMLDocuments(enabled bool) *Formatter {
+	f.yamlDocuments = enabled
+	return f
+}
+
+// WithMaxFieldSize truncates annotation and metadata string values longer than
+// limit bytes; zero applies DefaultMaxFieldSize to every format but JSON
+func (f *Formatter) WithMaxFieldSize(limit int) *Formatter {
+	f.maxFieldSize = limit
+	return f
+}
+
+// WithFields projects JSON and YAML output down to the given parsed field
+// paths (see ParseFieldPaths); nil keeps every field
+func (f *Formatter) WithFields(paths [][]string) *Formatter {
+	f.fields = paths
+	return f
+}
+
+// DefaultMaxFieldSize is the size in bytes annotation and metadata values
+// are truncated at in every format but JSON, when no limit is set
+const DefaultMaxFieldSize = 4096
+
 // Format formats the infrastructure results according to the specified format
 func (f *Formatter) Format(infrastructures []*models.Infrastructure) ([]byte, error) {
+	// JSON keeps values whole unless a limit is set
+	limit := f.maxFieldSize
+	if limit == 0 && f.format != "json" {
+		limit = DefaultMaxFieldSize
+	}
+	if limit > 0 {
+		infrastructures = truncateFields(infrastructures, limit)
+	}
+
 	switch f.format {
 	case "json":
 		return f.formatJSON(infrastructures)
@@ -34,19 +88,140 @@ func (f *Formatter) Format(infrastructures []*models.Infrastructure) ([]byte, er
 		return f.formatTable(infrastructures)
 	case "csv":
 		return f.formatCSV(infrastructures)
+	case "summary":
+		return []byte(f.FormatSummary(infrastructures)), nil
+	case "html":
+		return f.formatHTML(infrastructures)
+	case "markdown", "md":
+		return f.formatMarkdown(infrastructures)
+	case "dot":
+		return f.formatDOT(infrastructures)
 	default:
-		return nil, fmt.Errorf("unsupported output format: %s", f.format)
+		return nil, ValidateFormat(f.format)
+	}
+}
+
+// truncateFields returns copies of the infrastructures with oversized
+// annotation and metadata values truncated; the originals are not modified
+func truncateFields(infrastructures []*models.Infrastructure, limit int) []*models.Infrastructure {
+	truncated := make([]*models.Infrastructure, 0, len(infrastructures))
+
+	for _, infra := range infrastructures {
+		infraCopy := *infra
+		infraCopy.Metadata = truncateMetadata(infra.Metadata, limit)
+
+		infraCopy.VirtualMachines = make([]models.VirtualMachine, len(infra.VirtualMachines))
+		for i, vm := range infra.VirtualMachines {
+			vm.Annotations = truncateAnnotations(vm.Annotations, limit)
+			vm.Metadata = truncateMetadata(vm.Metadata, limit)
+			infraCopy.VirtualMachines[i] = vm
+		}
+
+		infraCopy.Templates = make([]models.Template, len(infra.Templates))
+		for i, template := range infra.Templates {
+			template.Annotations = truncateAnnotations(template.Annotations, limit)
+			template.Metadata = truncateMetadata(template.Metadata, limit)
+			infraCopy.Templates[i] = template
+		}
+
+		truncated = append(truncated, &infraCopy)
+	}
+
+	return truncated
+}
+
+// truncateAnnotations truncates oversized annotation values
+func truncateAnnotations(annotations map[string]string, limit int) map[string]string {
+	if annotations == nil {
+		return nil
+	}
+
+	result := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		result[k] = truncateString(v, limit)
+	}
+	return result
+}
+
+// truncateMetadata truncates oversized string values in a metadata map
+func truncateMetadata(metadata map[string]interface{}, limit int) map[string]interface{} {
+	if metadata == nil {
+		return nil
 	}
+
+	result := make(map[string]interface{}, len(metadata))
+	for k, v := range metadata {
+		if str, ok := v.(string); ok {
+			result[k] = truncateString(str, limit)
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// truncateString cuts a value to at most limit bytes on a rune boundary and
+// appends a marker recording how much was removed
+func truncateString(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+
+	return fmt.Sprintf("%s [truncated %d bytes]", value[:cut], len(value)-cut)
 }
 
 // formatJSON formats output as JSON
 func (f *Formatter) formatJSON(infrastructures []*models.Infrastructure) ([]byte, error) {
-	return json.MarshalIndent(infrastructures, "", "  ")
+	documents, err := f.documents(infrastructures)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(documents, "", "  ")
 }
 
 // formatYAML formats output as YAML
 func (f *Formatter) formatYAML(infrastructures []*models.Infrastructure) ([]byte, error) {
-	return yaml.Marshal(infrastructures)
+	documents, err := f.documents(infrastructures)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.yamlDocuments {
+		return yaml.Marshal(documents)
+	}
+
+	// One "---" separated document per infrastructure
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	for _, document := range documents {
+		if err := encoder.Encode(document); err != nil {
+			return nil, fmt.Errorf("failed to encode YAML document: %w", err)
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish YAML output: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// documents returns the values to encode for each infrastructure, projected
+// to the selected fields when a projection is set
+func (f *Formatter) documents(infrastructures []*models.Infrastructure) ([]interface{}, error) {
+	if f.fields != nil {
+		return projectInfrastructures(infrastructures, f.fields)
+	}
+
+	documents := make([]interface{}, len(infrastructures))
+	for i, infra := range infrastructures {
+		documents[i] = infra
+	}
+	return documents, nil
 }
 
 // formatTable formats output as a human-readable table
@@ -66,17 +241,48 @@ func (f *Formatter) formatTable(infrastructures []*models.Infrastructure) ([]byt
 		if infra.Node != "" {
 			output.WriteString(fmt.Sprintf("Node: %s\n", infra.Node))
 		}
+		if version := ProviderVersion(infra); version != "" {
+			output.WriteString(fmt.Sprintf("Version: %s\n", version))
+		}
 		
 		output.WriteString(fmt.Sprintf("Discovery Time: %s\n\n", 
 			infra.DiscoveryTime.Format("2006-01-02 15:04:05")))
 
-		// Virtual Machines Table
-		if len(infra.VirtualMachines) > 0 {
-			output.WriteString("Virtual Machines:\n")
-			vmTable := f.createVMTable(infra.VirtualMachines)
+		// Virtual Machines and Containers Tables
+		var vms, containers []models.VirtualMachine
+		for _, vm := range infra.VirtualMachines {
+			if vm.IsContainer() {
+				containers = append(containers, vm)
+			} else {
+				vms = append(vms, vm)
+			}
+		}
+		if len(vms) > 0 {
+			output.WriteString(fmt.Sprintf("Virtual Machines (%d):\n", len(vms)))
+			vmTable := f.createVMTable(vms)
 			output.WriteString(vmTable)
 			output.WriteString("\n")
 		}
+		if len(containers) > 0 {
+			output.WriteString(fmt.Sprintf("Containers (%d):\n", len(containers)))
+			containerTable := f.createVMTable(containers)
+			output.WriteString(containerTable)
+			output.WriteString("\n")
+		}
+
+		// Ownership Table
+		if ownerTable := f.createOwnerTable(infra.VirtualMachines); ownerTable != "" {
+			output.WriteString("VMs by Owner:\n")
+			output.WriteString(ownerTable)
+			output.WriteString("\n")
+		}
+
+		// Complexity Table
+		if complexityTable := f.createComplexityTable(infra.VirtualMachines); complexityTable != "" {
+			output.WriteString("Migration Complexity:\n")
+			output.WriteString(complexityTable)
+			output.WriteString("\n")
+		}
 
 		// Networks Table
 		if len(infra.Networks) > 0 {
@@ -86,6 +292,15 @@ func (f *Formatter) formatTable(infrastructures []*models.Infrastructure) ([]byt
 			output.WriteString("\n")
 		}
 
+		// Network Security Compliance
+		if issues := networkComplianceIssues([]*models.Infrastructure{infra}); len(issues) > 0 {
+			output.WriteString(fmt.Sprintf("Network Security Compliance (%d port groups flagged):\n", len(issues)))
+			for _, issue := range issues {
+				output.WriteString(fmt.Sprintf("  %s: %s\n", issue.Resource, issue.Detail))
+			}
+			output.WriteString("\n")
+		}
+
 		// Storage Table
 		if len(infra.Storage) > 0 {
 			output.WriteString("Storage:\n")
@@ -94,6 +309,27 @@ func (f *Formatter) formatTable(infrastructures []*models.Infrastructure) ([]byt
 			output.WriteString("\n")
 		}
 
+		// Storage Contents Table
+		if contentsTable := f.createStorageContentsTable(infra.Storage); contentsTable != "" {
+			output.WriteString("Storage Contents:\n")
+			output.WriteString(contentsTable)
+			output.WriteString("\n")
+		}
+
+		// Clusters Table
+		if len(infra.Clusters) > 0 {
+			output.WriteString("Clusters:\n")
+			output.WriteString(f.createClusterTable(infra.Clusters, infra.Hosts))
+			output.WriteString("\n")
+		}
+
+		// Live Migration Compatibility Table
+		if len(infra.Clusters) > 1 {
+			output.WriteString("Live Migration Compatibility:\n")
+			output.WriteString(f.createMigrationTable(enrich.LiveMigrationMatrix(infra.Clusters, infra.Hosts)))
+			output.WriteString("\n")
+		}
+
 		// Resource Pools Table
 		if len(infra.ResourcePools) > 0 {
 			output.WriteString("Resource Pools:\n")
@@ -102,6 +338,14 @@ func (f *Formatter) formatTable(infrastructures []*models.Infrastructure) ([]byt
 			output.WriteString("\n")
 		}
 
+		// HA Groups Table
+		if len(infra.HAGroups) > 0 {
+			output.WriteString("HA Groups:\n")
+			haTable := f.createHAGroupTable(infra.HAGroups)
+			output.WriteString(haTable)
+			output.WriteString("\n")
+		}
+
 		// Templates Table
 		if len(infra.Templates) > 0 {
 			output.WriteString("Templates:\n")
@@ -110,9 +354,38 @@ func (f *Formatter) formatTable(infrastructures []*models.Infrastructure) ([]byt
 			output.WriteString("\n")
 		}
 
+		// Permissions Table
+		if len(infra.Permissions) > 0 {
+			output.WriteString("Permissions:\n")
+			permissionTable := f.createPermissionTable(infra.Permissions)
+			output.WriteString(permissionTable)
+			output.WriteString("\n")
+		}
+
+		// Findings Table
+		if len(infra.Findings) > 0 {
+			output.WriteString("Findings:\n")
+			findingTable := f.createFindingTable(infra.Findings)
+			output.WriteString(findingTable)
+			output.WriteString("\n")
+		}
+
+		// Storage Rebalancing Table
+		if plan := infra.Rebalancing; plan != nil {
+			output.WriteString(fmt.Sprintf("Storage Rebalancing (datastores over %.0f%% used):\n", plan.Threshold))
+			output.WriteString(plan.Advisory + "\n")
+			if len(plan.Moves) > 0 {
+				output.WriteString(f.createRebalanceTable(plan.Moves))
+			}
+			for _, unresolved := range plan.Unresolved {
+				output.WriteString(fmt.Sprintf("No moves bring %s under the threshold; %.1f%% used after the moves above\n",
+					unresolved.Datastore, unresolved.Utilization))
+			}
+			output.WriteString("\n")
+		}
+
 		// Summary
-		total := len(infra.VirtualMachines) + len(infra.Networks) + 
-			len(infra.Storage) + len(infra.ResourcePools) + len(infra.Templates)
+		total := countResources(infra).Total() + len(infra.ResourcePools)
 		output.WriteString(fmt.Sprintf("Total Resources: %d\n", total))
 		output.WriteString(strings.Repeat("=", 80) + "\n")
 	}
@@ -125,7 +398,7 @@ func (f *Formatter) createVMTable(vms []models.VirtualMachine) string {
 	var output strings.Builder
 	
 	table := tablewriter.NewWriter(&output)
-	table.SetHeader([]string{"Name", "State", "CPU", "Memory (MB)", "OS", "Host"})
+	table.SetHeader([]string{"Name", "State", "CPU", "Memory (MiB)", "OS", "Host", "Snapshots"})
 	table.SetBorder(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
@@ -147,6 +420,7 @@ func (f *Formatter) createVMTable(vms []models.VirtualMachine) string {
 			strconv.FormatInt(vm.Memory, 10),
 			osVal,
 			host,
+			strconv.Itoa(snapshotCount(vm)),
 		})
 	}
 	
@@ -154,12 +428,132 @@ func (f *Formatter) createVMTable(vms []models.VirtualMachine) string {
 	return output.String()
 }
 
+// snapshotCount is the number of snapshots a VM has, from its snapshot tree
+// when discovered with one and otherwise from the counted metadata
+func snapshotCount(vm models.VirtualMachine) int {
+	if len(vm.Snapshots) > 0 {
+		return models.CountSnapshots(vm.Snapshots)
+	}
+	switch count := vm.Metadata["snapshots"].(type) {
+	case int:
+		return count
+	case float64: // as loaded from a discovery file
+		return int(count)
+	}
+	return 0
+}
+
+// createOwnerTable groups VMs by inferred owner, or returns "" when no VM
+// has ownership information
+func (f *Formatter) createOwnerTable(vms []models.VirtualMachine) string {
+	type ownerTotals struct {
+		vms          int
+		cpus         int
+		memory       int64
+		environments map[string]bool
+	}
+
+	totals := make(map[string]*ownerTotals)
+	var owned bool
+	for _, vm := range vms {
+		owner := vm.Owner
+		if owner == "" {
+			owner = "(none)"
+		} else {
+			owned = true
+		}
+		t, ok := totals[owner]
+		if !ok {
+			t = &ownerTotals{environments: make(map[string]bool)}
+			totals[owner] = t
+		}
+		t.vms++
+		t.cpus += vm.CPUs
+		t.memory += vm.Memory
+		if vm.Environment != "" {
+			t.environments[vm.Environment] = true
+		}
+	}
+	if !owned {
+		return ""
+	}
+
+	owners := make([]string, 0, len(totals))
+	for owner := range totals {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Owner", "VMs", "CPU", "Memory (MiB)", "Environments"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, owner := range owners {
+		t := totals[owner]
+		environments := make([]string, 0, len(t.environments))
+		for environment := range t.environments {
+			environments = append(environments, environment)
+		}
+		sort.Strings(environments)
+
+		table.Append([]string{
+			owner,
+			strconv.Itoa(t.vms),
+			strconv.Itoa(t.cpus),
+			strconv.FormatInt(t.memory, 10),
+			strings.Join(environments, ", "),
+		})
+	}
+
+	table.Render()
+	return output.String()
+}
+
+// createComplexityTable ranks VMs by migration complexity, most complex
+// first, or returns "" when no VM has a score
+func (f *Formatter) createComplexityTable(vms []models.VirtualMachine) string {
+	var ranked []models.VirtualMachine
+	for _, vm := range vms {
+		if vm.Complexity > 0 {
+			ranked = append(ranked, vm)
+		}
+	}
+	if len(ranked) == 0 {
+		return ""
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Complexity > ranked[j].Complexity
+	})
+
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Rank", "Name", "Score", "Factors"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for i, vm := range ranked {
+		table.Append([]string{
+			strconv.Itoa(i + 1),
+			vm.Name,
+			strconv.Itoa(vm.Complexity),
+			strings.Join(enrich.ComplexityFactors(vm), ", "),
+		})
+	}
+
+	table.Render()
+	return output.String()
+}
+
 // createNetworkTable creates a table for networks
 func (f *Formatter) createNetworkTable(networks []models.Network) string {
 	var output strings.Builder
 	
 	table := tablewriter.NewWriter(&output)
-	table.SetHeader([]string{"Name", "Type", "VLAN", "VSwitch", "DHCP"})
+	table.SetHeader([]string{"Name", "Type", "VLAN", "VSwitch", "MTU", "Security", "DHCP"})
 	table.SetBorder(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
@@ -174,6 +568,11 @@ func (f *Formatter) createNetworkTable(networks []models.Network) string {
 			vswitch = "N/A"
 		}
 		
+		mtu := "N/A"
+		if network.MTU > 0 {
+			mtu = strconv.Itoa(network.MTU)
+		}
+
 		dhcp := "No"
 		if network.DHCP {
 			dhcp = "Yes"
@@ -184,6 +583,8 @@ func (f *Formatter) createNetworkTable(networks []models.Network) string {
 			network.Type,
 			vlan,
 			vswitch,
+			mtu,
+			securityPolicySummary(network.SecurityPolicy),
 			dhcp,
 		})
 	}
@@ -197,7 +598,7 @@ func (f *Formatter) createStorageTable(storage []models.Storage) string {
 	var output strings.Builder
 	
 	table := tablewriter.NewWriter(&output)
-	table.SetHeader([]string{"Name", "Type", "Capacity (GB)", "Free (GB)", "Used (%)", "Accessible"})
+	table.SetHeader([]string{"Name", "Type", "Capacity (GiB)", "Free (GiB)", "Used (%)", "Accessible"})
 	table.SetBorder(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
@@ -227,12 +628,59 @@ func (f *Formatter) createStorageTable(storage []models.Storage) string {
 	return output.String()
 }
 
+// createStorageContentsTable creates a table of volume counts and sizes per
+// storage and content type; it is empty when no storage listed its contents
+func (f *Formatter) createStorageContentsTable(storage []models.Storage) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Storage", "Content", "Count", "Size (MiB)"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	rows := 0
+	for _, store := range storage {
+		if store.Contents == nil {
+			continue
+		}
+
+		// Local storage of the same name exists on every node
+		name := store.Name
+		if node, ok := store.Metadata["node"].(string); ok && store.Local {
+			name = fmt.Sprintf("%s (%s)", store.Name, node)
+		}
+
+		var contentTypes []string
+		for contentType := range store.Contents.Summary {
+			contentTypes = append(contentTypes, contentType)
+		}
+		sort.Strings(contentTypes)
+
+		for _, contentType := range contentTypes {
+			summary := store.Contents.Summary[contentType]
+			table.Append([]string{
+				name,
+				contentType,
+				strconv.Itoa(summary.Count),
+				strconv.FormatInt(summary.Size, 10),
+			})
+			rows++
+		}
+	}
+	if rows == 0 {
+		return ""
+	}
+
+	table.Render()
+	return output.String()
+}
+
 // createResourcePoolTable creates a table for resource pools
 func (f *Formatter) createResourcePoolTable(pools []models.ResourcePool) string {
 	var output strings.Builder
 	
 	table := tablewriter.NewWriter(&output)
-	table.SetHeader([]string{"Name", "CPU Limit", "Memory Limit", "CPU Shares", "Memory Shares"})
+	table.SetHeader([]string{"Name", "VMs", "CPU Limit", "Memory Limit", "CPU Shares", "Memory Shares"})
 	table.SetBorder(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
@@ -247,12 +695,22 @@ func (f *Formatter) createResourcePoolTable(pools []models.ResourcePool) string
 			memLimit = strconv.FormatInt(pool.Memory.Limit, 10)
 		}
 		
+		// Membership-only pools (Proxmox) have no allocation settings
+		cpuShares, memShares := pool.CPU.Shares, pool.Memory.Shares
+		if cpuShares == "" {
+			cpuShares = "-"
+		}
+		if memShares == "" {
+			memShares = "-"
+		}
+		
 		table.Append([]string{
 			pool.Name,
+			strconv.Itoa(len(pool.VMs)),
 			cpuLimit,
 			memLimit,
-			pool.CPU.Shares,
-			pool.Memory.Shares,
+			cpuShares,
+			memShares,
 		})
 	}
 	
@@ -260,12 +718,91 @@ func (f *Formatter) createResourcePoolTable(pools []models.ResourcePool) string
 	return output.String()
 }
 
+// createClusterTable creates a table for clusters with their EVC mode and
+// the CPU models of their hosts
+func (f *Formatter) createClusterTable(clusters []models.Cluster, hosts []models.Host) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Name", "EVC Mode", "Hosts", "CPU Models"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, cluster := range clusters {
+		evcMode := cluster.EVCMode
+		if evcMode == "" {
+			evcMode = "Disabled"
+		}
+
+		seen := make(map[string]bool)
+		var cpuModels []string
+		for _, host := range hosts {
+			if host.Cluster == cluster.Name && host.CPUModel != "" && !seen[host.CPUModel] {
+				seen[host.CPUModel] = true
+				cpuModels = append(cpuModels, host.CPUModel)
+			}
+		}
+		sort.Strings(cpuModels)
+
+		table.Append([]string{
+			cluster.Name,
+			evcMode,
+			strconv.Itoa(len(cluster.Hosts)),
+			strings.Join(cpuModels, ", "),
+		})
+	}
+
+	table.Render()
+	return output.String()
+}
+
+// createMigrationTable creates a table of live migration compatibility
+// between cluster pairs
+func (f *Formatter) createMigrationTable(pairs []enrich.MigrationPair) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Source", "Target", "Status", "Reason"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, pair := range pairs {
+		table.Append([]string{pair.Source, pair.Target, pair.Status, pair.Reason})
+	}
+
+	table.Render()
+	return output.String()
+}
+
+// createHAGroupTable creates a table for HA groups
+func (f *Formatter) createHAGroupTable(groups []models.HAGroup) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Name", "Nodes", "Restricted", "No Failback", "Comment"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, group := range groups {
+		table.Append([]string{
+			group.Name,
+			strings.Join(group.Nodes, ", "),
+			strconv.FormatBool(group.Restricted),
+			strconv.FormatBool(group.NoFailback),
+			group.Comment,
+		})
+	}
+
+	table.Render()
+	return output.String()
+}
+
 // createTemplateTable creates a table for templates
 func (f *Formatter) createTemplateTable(templates []models.Template) string {
 	var output strings.Builder
 	
 	table := tablewriter.NewWriter(&output)
-	table.SetHeader([]string{"Name", "OS", "CPU", "Memory (MB)", "Disks"})
+	table.SetHeader([]string{"Name", "OS", "CPU", "Memory (MiB)", "Disks"})
 	table.SetBorder(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 
@@ -290,39 +827,200 @@ func (f *Formatter) createTemplateTable(templates []models.Template) string {
 	return output.String()
 }
 
-// formatCSV formats output as CSV
-func (f *Formatter) formatCSV(infrastructures []*models.Infrastructure) ([]byte, error) {
+// createPermissionTable creates a table for permissions
+func (f *Formatter) createPermissionTable(permissions []models.Permission) string {
 	var output strings.Builder
 	
-	// CSV Header
-	output.WriteString("Provider,Server,Datacenter,Cluster,Node,Resource_Type,Name,State,CPUs,Memory_MB,OS,Host,Type,Capacity_GB,Free_GB,VLAN,Network\n")
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Principal", "Type", "Role", "Entity", "Propagate", "Resolved", "Enabled", "Members"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, perm := range permissions {
+		propagate := "No"
+		if perm.Propagate {
+			propagate = "Yes"
+		}
+		
+		resolved := "No"
+		if perm.Resolved {
+			resolved = fmt.Sprintf("Yes (%s)", perm.Source)
+		}
+
+		// Blank when the resolving directory doesn't say
+		enabled, members := "", ""
+		if perm.Enabled != nil {
+			enabled = "No"
+			if *perm.Enabled {
+				enabled = "Yes"
+			}
+		}
+		if perm.MemberCount != nil {
+			members = fmt.Sprintf("%d", *perm.MemberCount)
+		}
+		
+		table.Append([]string{
+			perm.Principal,
+			perm.PrincipalType,
+			perm.Role,
+			perm.Entity,
+			propagate,
+			resolved,
+			enabled,
+			members,
+		})
+	}
+	
+	table.Render()
+	return output.String()
+}
+
+// createFindingTable creates a table for findings
+func (f *Formatter) createFindingTable(findings []models.Finding) string {
+	var output strings.Builder
 	
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"Severity", "Rule", "Resource", "Message"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, finding := range findings {
+		table.Append([]string{
+			strings.ToUpper(finding.Severity),
+			finding.Rule,
+			finding.Resource,
+			finding.Message,
+		})
+	}
+	
+	table.Render()
+	return output.String()
+}
+
+// createRebalanceTable creates a table of proposed storage moves
+func (f *Formatter) createRebalanceTable(moves []models.StorageMove) string {
+	var output strings.Builder
+
+	table := tablewriter.NewWriter(&output)
+	table.SetHeader([]string{"VM", "From", "To", "Size (GiB)"})
+	table.SetBorder(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, move := range moves {
+		table.Append([]string{
+			move.VM,
+			move.From,
+			move.To,
+			strconv.FormatInt(move.Size, 10),
+		})
+	}
+
+	table.Render()
+	return output.String()
+}
+
+// formatCSV formats output as RFC 4180 CSV in the CSVColumns layout, one
+// row per VM, network and storage resource
+func (f *Formatter) formatCSV(infrastructures []*models.Infrastructure) ([]byte, error) {
+	var output bytes.Buffer
+
+	// RFC 4180 line endings, which Excel expects
+	writer := csv.NewWriter(&output)
+	writer.UseCRLF = true
+
+	if err := writer.Write(CSVColumns); err != nil {
+		return nil, err
+	}
+
 	for _, infra := range infrastructures {
-		// Virtual Machines
+		scope := csvRow{
+			"Provider":   infra.Provider,
+			"Server":     infra.Server,
+			"Datacenter": infra.Datacenter,
+			"Cluster":    infra.Cluster,
+			"Node":       infra.Node,
+		}
+
 		for _, vm := range infra.VirtualMachines {
-			output.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,VM,%s,%s,%d,%d,%s,%s,,,,,%s\n",
-				infra.Provider, infra.Server, infra.Datacenter, infra.Cluster, infra.Node,
-				vm.Name, vm.State, vm.CPUs, vm.Memory, 
-				strings.ReplaceAll(vm.OperatingSystem, ",", ";"), vm.Host,
-				strings.Join(f.getVMNetworks(vm), ";")))
+			row := scope.with(csvRow{
+				"Resource_Type": "VM",
+				"Name":          vm.Name,
+				"State":         vm.State,
+				"CPUs":          strconv.Itoa(vm.CPUs),
+				"Memory_MB":     strconv.FormatInt(vm.Memory, 10),
+				"OS":            vm.OperatingSystem,
+				"Host":          vm.Host,
+				"Network":       strings.Join(f.getVMNetworks(vm), ";"),
+			})
+			if err := writer.Write(row.record()); err != nil {
+				return nil, err
+			}
 		}
-		
-		// Networks
+
 		for _, network := range infra.Networks {
-			output.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,Network,%s,,%s,,,,%s,,,%d,\n",
-				infra.Provider, infra.Server, infra.Datacenter, infra.Cluster, infra.Node,
-				network.Name, network.Type, network.VLAN))
+			row := scope.with(csvRow{
+				"Resource_Type": "Network",
+				"Name":          network.Name,
+				"Type":          network.Type,
+				"VLAN":          strconv.Itoa(network.VLAN),
+			})
+			if network.MTU > 0 {
+				row["MTU"] = strconv.Itoa(network.MTU)
+			}
+			if policy := network.SecurityPolicy; policy != nil {
+				row["Promiscuous"] = csvPolicy(policy.AllowPromiscuous)
+				row["MAC_Changes"] = csvPolicy(policy.MACChanges)
+				row["Forged_Transmits"] = csvPolicy(policy.ForgedTransmits)
+			}
+			if err := writer.Write(row.record()); err != nil {
+				return nil, err
+			}
 		}
-		
-		// Storage
+
 		for _, storage := range infra.Storage {
-			output.WriteString(fmt.Sprintf("%s,%s,%s,%s,%s,Storage,%s,,,,,,%s,%d,%d,,\n",
-				infra.Provider, infra.Server, infra.Datacenter, infra.Cluster, infra.Node,
-				storage.Name, storage.Type, storage.Capacity, storage.FreeSpace))
+			row := scope.with(csvRow{
+				"Resource_Type": "Storage",
+				"Name":          storage.Name,
+				"Type":          storage.Type,
+				"Capacity_GB":   strconv.FormatInt(storage.Capacity, 10),
+				"Free_GB":       strconv.FormatInt(storage.FreeSpace, 10),
+			})
+			if err := writer.Write(row.record()); err != nil {
+				return nil, err
+			}
 		}
 	}
-	
-	return []byte(output.String()), nil
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+// csvRow holds one CSV row's values by column name, so that each resource
+// type only sets its own columns and cannot shift the others
+type csvRow map[string]string
+
+// with returns a copy of the row with the values of other added
+func (r csvRow) with(other csvRow) csvRow {
+	row := make(csvRow, len(r)+len(other))
+	for column, value := range r {
+		row[column] = value
+	}
+	for column, value := range other {
+		row[column] = value
+	}
+	return row
+}
+
+// record lays the row out in CSVColumns order, leaving unset columns empty
+func (r csvRow) record() []string {
+	record := make([]string, len(CSVColumns))
+	for i, column := range CSVColumns {
+		record[i] = r[column]
+	}
+	return record
 }
 
 // getVMNetworks extracts network names from a VM
@@ -336,38 +1034,242 @@ func (f *Formatter) getVMNetworks(vm models.VirtualMachine) []string {
 	return networks
 }
 
+// ProviderVersion formats the provider_version metadata recorded at discovery
+func ProviderVersion(infra *models.Infrastructure) string {
+	version, ok := infra.Metadata["provider_version"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	field := func(key string) string {
+		value, _ := version[key].(string)
+		return value
+	}
+
+	s := strings.TrimSpace(field("product") + " " + field("version"))
+	if build := field("build"); build != "" {
+		s += " (build " + build + ")"
+	}
+	return s
+}
+
+// resourceCounts holds the resource totals reported by summaries
+type resourceCounts struct {
+	VMs       int
+	Networks  int
+	Storage   int
+	Templates int
+}
+
+// countResources counts the resources of one infrastructure. Templates
+// listed among the VMs by --include-templates are also in Templates, so
+// they are only counted there.
+func countResources(infra *models.Infrastructure) resourceCounts {
+	counts := resourceCounts{
+		Networks:  len(infra.Networks),
+		Storage:   len(infra.Storage),
+		Templates: len(infra.Templates),
+	}
+	inline := 0
+	for _, vm := range infra.VirtualMachines {
+		if vm.Config.Template {
+			inline++
+		} else {
+			counts.VMs++
+		}
+	}
+	// Results saved without the templates section still count them
+	if counts.Templates == 0 {
+		counts.Templates = inline
+	}
+	return counts
+}
+
+// add adds other to the counts
+func (c *resourceCounts) add(other resourceCounts) {
+	c.VMs += other.VMs
+	c.Networks += other.Networks
+	c.Storage += other.Storage
+	c.Templates += other.Templates
+}
+
+// Total is the grand total of all counted resources
+func (c resourceCounts) Total() int {
+	return c.VMs + c.Networks + c.Storage + c.Templates
+}
+
+// summaryItem is one resource listed in a summary section
+type summaryItem struct {
+	Server   string
+	Resource string
+	Detail   string
+}
+
+// orphanedResources lists the orphaned VMs and disks found
+func orphanedResources(infrastructures []*models.Infrastructure) []summaryItem {
+	var orphans []summaryItem
+	for _, infra := range infrastructures {
+		for _, finding := range infra.Findings {
+			if finding.Rule == "orphaned-vm" || finding.Rule == "orphaned-disk" {
+				orphans = append(orphans, summaryItem{Server: infra.Server, Resource: finding.Resource, Detail: finding.Message})
+			}
+		}
+	}
+	return orphans
+}
+
+// rdmVMs lists the VMs with raw device mappings and their LUNs
+func rdmVMs(infrastructures []*models.Infrastructure) []summaryItem {
+	var vms []summaryItem
+	for _, infra := range infrastructures {
+		for _, vm := range infra.VirtualMachines {
+			var luns []string
+			for _, disk := range vm.Disks {
+				if disk.IsRDM() {
+					luns = append(luns, fmt.Sprintf("%s %s", disk.Type, disk.LUN))
+				}
+			}
+			if len(luns) > 0 {
+				vms = append(vms, summaryItem{Server: infra.Server, Resource: vm.Name, Detail: strings.Join(luns, ", ")})
+			}
+		}
+	}
+	return vms
+}
+
+// networkComplianceIssues lists the port groups accepting promiscuous mode,
+// and those whose hosts disagree on the security policy
+func networkComplianceIssues(infrastructures []*models.Infrastructure) []summaryItem {
+	var issues []summaryItem
+	for _, infra := range infrastructures {
+		for _, network := range infra.Networks {
+			var problems []string
+			if network.SecurityPolicy != nil && network.SecurityPolicy.AllowPromiscuous {
+				problems = append(problems, "promiscuous mode accepted")
+			}
+			if hosts := mixedPolicyHosts(network); len(hosts) > 0 {
+				problems = append(problems, "hosts disagree on the security policy: "+strings.Join(hosts, "; "))
+			}
+			if len(problems) > 0 {
+				issues = append(issues, summaryItem{Server: infra.Server, Resource: network.Name, Detail: strings.Join(problems, ", ")})
+			}
+		}
+	}
+	return issues
+}
+
+// mixedPolicyHosts describes each host's security policy for a standard
+// port group whose hosts disagree, in host order
+func mixedPolicyHosts(network models.Network) []string {
+	var hosts []string
+	switch policies := network.Metadata["host_security_policies"].(type) {
+	case map[string]models.SecurityPolicy:
+		for host, policy := range policies {
+			policy := policy
+			hosts = append(hosts, host+" "+securityPolicySummary(&policy))
+		}
+	case map[string]interface{}:
+		// Read back from a saved discovery
+		for host, value := range policies {
+			policy, _ := value.(map[string]interface{})
+			hosts = append(hosts, host+" "+securityPolicySummary(&models.SecurityPolicy{
+				AllowPromiscuous: policy["allow_promiscuous"] == true,
+				MACChanges:       policy["mac_changes"] == true,
+				ForgedTransmits:  policy["forged_transmits"] == true,
+			}))
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// securityPolicySummary lists the settings a security policy accepts, as
+// "reject all" when it accepts none or "N/A" when it was not discovered
+func securityPolicySummary(policy *models.SecurityPolicy) string {
+	if policy == nil {
+		return "N/A"
+	}
+	var accepted []string
+	if policy.AllowPromiscuous {
+		accepted = append(accepted, "promiscuous")
+	}
+	if policy.MACChanges {
+		accepted = append(accepted, "MAC changes")
+	}
+	if policy.ForgedTransmits {
+		accepted = append(accepted, "forged transmits")
+	}
+	if len(accepted) == 0 {
+		return "reject all"
+	}
+	return "accept " + strings.Join(accepted, ", ")
+}
+
+// csvPolicy formats a security policy setting for CSV output
+func csvPolicy(accept bool) string {
+	if accept {
+		return "accept"
+	}
+	return "reject"
+}
+
 // FormatSummary creates a summary of the discovery results
 func (f *Formatter) FormatSummary(infrastructures []*models.Infrastructure) string {
 	var output strings.Builder
 	
-	totalVMs := 0
-	totalNetworks := 0
-	totalStorage := 0
-	totalTemplates := 0
+	var totals resourceCounts
 	
 	output.WriteString("=== Discovery Summary ===\n\n")
 	
 	for _, infra := range infrastructures {
-		totalVMs += len(infra.VirtualMachines)
-		totalNetworks += len(infra.Networks)
-		totalStorage += len(infra.Storage)
-		totalTemplates += len(infra.Templates)
+		counts := countResources(infra)
+		totals.add(counts)
 		
 		output.WriteString(fmt.Sprintf("%s (%s):\n", 
 			strings.ToUpper(infra.Provider), infra.Server))
-		output.WriteString(fmt.Sprintf("  Virtual Machines: %d\n", len(infra.VirtualMachines)))
-		output.WriteString(fmt.Sprintf("  Networks: %d\n", len(infra.Networks)))
-		output.WriteString(fmt.Sprintf("  Storage: %d\n", len(infra.Storage)))
-		output.WriteString(fmt.Sprintf("  Templates: %d\n", len(infra.Templates)))
+		if version := ProviderVersion(infra); version != "" {
+			output.WriteString(fmt.Sprintf("  Version: %s\n", version))
+		}
+		output.WriteString(fmt.Sprintf("  Virtual Machines: %d\n", counts.VMs))
+		output.WriteString(fmt.Sprintf("  Networks: %d\n", counts.Networks))
+		output.WriteString(fmt.Sprintf("  Storage: %d\n", counts.Storage))
+		output.WriteString(fmt.Sprintf("  Templates: %d\n", counts.Templates))
+		output.WriteString("\n")
+	}
+	
+	// Orphaned resources
+	if orphans := orphanedResources(infrastructures); len(orphans) > 0 {
+		output.WriteString(fmt.Sprintf("Orphaned Resources (%d):\n", len(orphans)))
+		for _, orphan := range orphans {
+			output.WriteString(fmt.Sprintf("  [%s] %s: %s\n", orphan.Server, orphan.Resource, orphan.Detail))
+		}
+		output.WriteString("\n")
+	}
+	
+	// VMs with raw device mappings
+	if vms := rdmVMs(infrastructures); len(vms) > 0 {
+		output.WriteString(fmt.Sprintf("WARNING: VMs with Raw Device Mappings (%d):\n", len(vms)))
+		for _, vm := range vms {
+			output.WriteString(fmt.Sprintf("  [%s] %s: %s\n", vm.Server, vm.Resource, vm.Detail))
+		}
+		output.WriteString("\n")
+	}
+	
+	// Port groups failing the layer 2 security baseline
+	if issues := networkComplianceIssues(infrastructures); len(issues) > 0 {
+		output.WriteString(fmt.Sprintf("WARNING: Port Groups Flagged by Security Policy (%d):\n", len(issues)))
+		for _, issue := range issues {
+			output.WriteString(fmt.Sprintf("  [%s] %s: %s\n", issue.Server, issue.Resource, issue.Detail))
+		}
 		output.WriteString("\n")
 	}
 	
 	output.WriteString("Total Resources:\n")
-	output.WriteString(fmt.Sprintf("  Virtual Machines: %d\n", totalVMs))
-	output.WriteString(fmt.Sprintf("  Networks: %d\n", totalNetworks))
-	output.WriteString(fmt.Sprintf("  Storage: %d\n", totalStorage))
-	output.WriteString(fmt.Sprintf("  Templates: %d\n", totalTemplates))
-	output.WriteString(fmt.Sprintf("  Grand Total: %d\n", totalVMs+totalNetworks+totalStorage+totalTemplates))
+	output.WriteString(fmt.Sprintf("  Virtual Machines: %d\n", totals.VMs))
+	output.WriteString(fmt.Sprintf("  Networks: %d\n", totals.Networks))
+	output.WriteString(fmt.Sprintf("  Storage: %d\n", totals.Storage))
+	output.WriteString(fmt.Sprintf("  Templates: %d\n", totals.Templates))
+	output.WriteString(fmt.Sprintf("  Grand Total: %d\n", totals.Total()))
 	
 	return output.String()
 }
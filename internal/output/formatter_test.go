@@ -0,0 +1,86 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLargeAnnotationsTruncated(t *testing.T) {
+	runbook := strings.Repeat("x", 400*1024)
+	marker := fmt.Sprintf("[truncated %d bytes]", len(runbook)-DefaultMaxFieldSize)
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			infrastructures := testInfrastructures()
+			infrastructures[0].VirtualMachines[0].Annotations["notes"] = runbook
+			infrastructures[0].VirtualMachines[0].Metadata = map[string]interface{}{"runbook": runbook}
+
+			data, err := NewFormatter(format).Format(infrastructures)
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+
+			if format == "json" {
+				if !strings.Contains(string(data), runbook) {
+					t.Error("JSON output lost the full annotation")
+				}
+				return
+			}
+			if len(data) > 3*DefaultMaxFieldSize+64*1024 {
+				t.Errorf("output is %d bytes; the annotation was not truncated", len(data))
+			}
+			if strings.Contains(string(data), runbook[:DefaultMaxFieldSize+1]) {
+				t.Error("output holds more than the limit of the annotation")
+			}
+			if got := infrastructures[0].VirtualMachines[0].Annotations["notes"]; got != runbook {
+				t.Error("Format modified the infrastructure it was given")
+			}
+		})
+	}
+
+	t.Run("json with limit", func(t *testing.T) {
+		infrastructures := testInfrastructures()
+		infrastructures[0].VirtualMachines[0].Annotations["notes"] = runbook
+
+		data, err := NewFormatter("json").WithMaxFieldSize(DefaultMaxFieldSize).Format(infrastructures)
+		if err != nil {
+			t.Fatalf("Format: %v", err)
+		}
+		if !strings.Contains(string(data), marker) {
+			t.Errorf("JSON output is missing the %q marker", marker)
+		}
+	})
+}
+
+func TestMarkdownEscapesCells(t *testing.T) {
+	infrastructures := testInfrastructures()
+	infrastructures[0].VirtualMachines[0].Name = "web|01 *new*"
+	infrastructures[0].VirtualMachines[0].Annotations["notes"] = "line one\nline two"
+
+	data, err := NewFormatter("markdown").Format(infrastructures)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	report := string(data)
+
+	for _, want := range []string{
+		"| **Total (5 resources)** | | | 3 | 1 | 1 | 0 |",
+		"### Virtual Machines (2)",
+		`| web\|01 \*new\* | `,
+		"notes=line one<br>line two",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("markdown report has no %q:\n%s", want, report)
+		}
+	}
+
+	// Every row of a table has the header's number of cells
+	for _, line := range strings.Split(report, "\n") {
+		if strings.HasPrefix(line, "| Name | State |") || strings.HasPrefix(line, "| web") || strings.HasPrefix(line, "| db") {
+			if cells := strings.Count(strings.ReplaceAll(line, `\|`, ""), "|"); cells != 10 {
+				t.Errorf("row has %d separators, want 10: %s", cells, line)
+			}
+		}
+	}
+}
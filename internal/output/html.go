@@ -0,0 +1,214 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// htmlReport is the data rendered by htmlTemplate
+type htmlReport struct {
+	Infrastructures []htmlInfrastructure
+	Totals          resourceCounts
+	Orphans         []summaryItem
+	RDMVMs          []summaryItem
+	Compliance      []summaryItem
+}
+
+// htmlInfrastructure is one infrastructure's section of the HTML report
+type htmlInfrastructure struct {
+	*models.Infrastructure
+	Version string
+	Counts  resourceCounts
+}
+
+// formatHTML formats output as a self-contained HTML report with a section
+// per infrastructure. html/template escapes every discovered value, so VM
+// names and annotations cannot break the markup.
+func (f *Formatter) formatHTML(infrastructures []*models.Infrastructure) ([]byte, error) {
+	report := htmlReport{
+		Orphans:    orphanedResources(infrastructures),
+		RDMVMs:     rdmVMs(infrastructures),
+		Compliance: networkComplianceIssues(infrastructures),
+	}
+	for _, infra := range infrastructures {
+		counts := countResources(infra)
+		report.Totals.add(counts)
+		report.Infrastructures = append(report.Infrastructures, htmlInfrastructure{
+			Infrastructure: infra,
+			Version:        ProviderVersion(infra),
+			Counts:         counts,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, report); err != nil {
+		return nil, fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"upper":       strings.ToUpper,
+	"join":        strings.Join,
+	"snapshots":   snapshotCount,
+	"networks":    (&Formatter{}).getVMNetworks,
+	"annotations": formatAnnotations,
+	"usedPercent": usedPercent,
+	"security":    securityPolicySummary,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Valhalla Discovery Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; }
+details { margin: 1em 0; border: 1px solid #ccc; border-radius: 4px; padding: 0.5em 1em; }
+summary { font-weight: bold; cursor: pointer; }
+table { border-collapse: collapse; margin: 0.5em 0 1em; font-size: 0.9em; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #f0f0f0; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.num { text-align: right; }
+.warning { color: #a40; }
+</style>
+</head>
+<body>
+<h1>Valhalla Discovery Report</h1>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Provider</th><th>Server</th><th>Version</th><th>VMs</th><th>Networks</th><th>Storage</th><th>Templates</th></tr>
+{{- range .Infrastructures}}
+<tr><td>{{upper .Provider}}</td><td>{{.Server}}</td><td>{{.Version}}</td><td class="num">{{.Counts.VMs}}</td><td class="num">{{.Counts.Networks}}</td><td class="num">{{.Counts.Storage}}</td><td class="num">{{.Counts.Templates}}</td></tr>
+{{- end}}
+<tr><th colspan="3">Total ({{.Totals.Total}} resources)</th><th class="num">{{.Totals.VMs}}</th><th class="num">{{.Totals.Networks}}</th><th class="num">{{.Totals.Storage}}</th><th class="num">{{.Totals.Templates}}</th></tr>
+</table>
+{{- if .Orphans}}
+
+<h3>Orphaned Resources ({{len .Orphans}})</h3>
+<ul>
+{{- range .Orphans}}
+<li>[{{.Server}}] {{.Resource}}: {{.Detail}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .RDMVMs}}
+
+<h3 class="warning">VMs with Raw Device Mappings ({{len .RDMVMs}})</h3>
+<ul>
+{{- range .RDMVMs}}
+<li>[{{.Server}}] {{.Resource}}: {{.Detail}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Compliance}}
+
+<h3 class="warning">Port Groups Flagged by Security Policy ({{len .Compliance}})</h3>
+<ul>
+{{- range .Compliance}}
+<li>[{{.Server}}] {{.Resource}}: {{.Detail}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{range .Infrastructures}}
+<details open>
+<summary>{{upper .Provider}} Infrastructure ({{.Server}})</summary>
+<p>
+{{- if .Datacenter}}Datacenter: {{.Datacenter}}<br>{{end}}
+{{- if .Cluster}}Cluster: {{.Cluster}}<br>{{end}}
+{{- if .Node}}Node: {{.Node}}<br>{{end}}
+{{- if .Version}}Version: {{.Version}}<br>{{end}}
+Discovery Time: {{.DiscoveryTime.Format "2006-01-02 15:04:05"}}</p>
+{{- if .VirtualMachines}}
+
+<details open>
+<summary>Virtual Machines ({{len .VirtualMachines}})</summary>
+<table class="sortable">
+<thead><tr><th>Name</th><th>State</th><th>CPU</th><th>Memory (MiB)</th><th>OS</th><th>Host</th><th>Networks</th><th>Snapshots</th><th>Annotations</th></tr></thead>
+<tbody>
+{{- range .VirtualMachines}}
+<tr><td>{{.Name}}</td><td>{{.State}}</td><td class="num">{{.CPUs}}</td><td class="num">{{.Memory}}</td><td>{{.OperatingSystem}}</td><td>{{.Host}}</td><td>{{join (networks .) ", "}}</td><td class="num">{{snapshots .}}</td><td>{{annotations .Annotations}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</details>
+{{- end}}
+{{- if .Networks}}
+
+<details open>
+<summary>Networks ({{len .Networks}})</summary>
+<table class="sortable">
+<thead><tr><th>Name</th><th>Type</th><th>VLAN</th><th>VSwitch</th><th>MTU</th><th>Security</th><th>DHCP</th></tr></thead>
+<tbody>
+{{- range .Networks}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td class="num">{{if gt .VLAN 0}}{{.VLAN}}{{end}}</td><td>{{.VSwitch}}</td><td class="num">{{if gt .MTU 0}}{{.MTU}}{{end}}</td><td>{{security .SecurityPolicy}}</td><td>{{if .DHCP}}Yes{{else}}No{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</details>
+{{- end}}
+{{- if .Storage}}
+
+<details open>
+<summary>Storage ({{len .Storage}})</summary>
+<table class="sortable">
+<thead><tr><th>Name</th><th>Type</th><th>Capacity (GiB)</th><th>Free (GiB)</th><th>Used (%)</th><th>Accessible</th></tr></thead>
+<tbody>
+{{- range .Storage}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td class="num">{{.Capacity}}</td><td class="num">{{.FreeSpace}}</td><td class="num">{{usedPercent .}}</td><td>{{if .Accessible}}Yes{{else}}No{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+</details>
+{{- end}}
+</details>
+{{end}}
+<script>
+// Sort a table by the clicked column, numerically when both cells are numbers
+document.querySelectorAll("table.sortable th").forEach(function (th) {
+  th.addEventListener("click", function () {
+    var table = th.closest("table"), body = table.tBodies[0];
+    var index = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var asc = !th.classList.contains("asc");
+    th.parentNode.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+    th.classList.add(asc ? "asc" : "desc");
+    var rows = Array.prototype.slice.call(body.rows);
+    rows.sort(function (a, b) {
+      var x = a.cells[index].textContent, y = b.cells[index].textContent;
+      var nx = parseFloat(x), ny = parseFloat(y);
+      var cmp = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+      return asc ? cmp : -cmp;
+    });
+    rows.forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// formatAnnotations renders annotations as sorted key=value pairs
+func formatAnnotations(annotations map[string]string) string {
+	pairs := make([]string, 0, len(annotations))
+	for key, value := range annotations {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "; ")
+}
+
+// usedPercent is the share of a storage's capacity in use, or "" when its
+// capacity is unknown
+func usedPercent(store models.Storage) string {
+	if store.Capacity <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", float64(store.UsedSpace)/float64(store.Capacity)*100)
+}
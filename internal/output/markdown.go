@@ -0,0 +1,148 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"valhalla/internal/models"
+)
+
+// formatMarkdown formats output as a Markdown report for wikis and pull
+// requests: a summary table, then a section per infrastructure with its
+// VMs, networks and storage. Cell values are escaped so pipes and newlines
+// in discovered names cannot break the tables.
+func (f *Formatter) formatMarkdown(infrastructures []*models.Infrastructure) ([]byte, error) {
+	var b strings.Builder
+
+	b.WriteString("# Valhalla Discovery Report\n\n")
+	b.WriteString("## Summary\n\n")
+
+	var totals resourceCounts
+	var summary [][]string
+	for _, infra := range infrastructures {
+		counts := countResources(infra)
+		totals.add(counts)
+		summary = append(summary, []string{
+			strings.ToUpper(infra.Provider), infra.Server, ProviderVersion(infra),
+			fmt.Sprint(counts.VMs), fmt.Sprint(counts.Networks), fmt.Sprint(counts.Storage), fmt.Sprint(counts.Templates),
+		})
+	}
+	writeMarkdownTable(&b, []string{"Provider", "Server", "Version", "VMs", "Networks", "Storage", "Templates"}, summary)
+	b.WriteString(fmt.Sprintf("| **Total (%d resources)** | | | %d | %d | %d | %d |\n",
+		totals.Total(), totals.VMs, totals.Networks, totals.Storage, totals.Templates))
+
+	for _, section := range []struct {
+		title string
+		items []summaryItem
+	}{
+		{"Orphaned Resources", orphanedResources(infrastructures)},
+		{"VMs with Raw Device Mappings", rdmVMs(infrastructures)},
+		{"Port Groups Flagged by Security Policy", networkComplianceIssues(infrastructures)},
+	} {
+		if len(section.items) == 0 {
+			continue
+		}
+		b.WriteString(fmt.Sprintf("\n### %s (%d)\n\n", section.title, len(section.items)))
+		for _, item := range section.items {
+			b.WriteString(fmt.Sprintf("- [%s] %s: %s\n", markdownEscape(item.Server), markdownEscape(item.Resource), markdownEscape(item.Detail)))
+		}
+	}
+
+	for _, infra := range infrastructures {
+		b.WriteString(fmt.Sprintf("\n## %s Infrastructure (%s)\n\n", strings.ToUpper(infra.Provider), markdownEscape(infra.Server)))
+		if infra.Datacenter != "" {
+			b.WriteString(fmt.Sprintf("- Datacenter: %s\n", markdownEscape(infra.Datacenter)))
+		}
+		if infra.Cluster != "" {
+			b.WriteString(fmt.Sprintf("- Cluster: %s\n", markdownEscape(infra.Cluster)))
+		}
+		if infra.Node != "" {
+			b.WriteString(fmt.Sprintf("- Node: %s\n", markdownEscape(infra.Node)))
+		}
+		if version := ProviderVersion(infra); version != "" {
+			b.WriteString(fmt.Sprintf("- Version: %s\n", markdownEscape(version)))
+		}
+		b.WriteString(fmt.Sprintf("- Discovery Time: %s\n", infra.DiscoveryTime.Format("2006-01-02 15:04:05")))
+
+		if len(infra.VirtualMachines) > 0 {
+			b.WriteString(fmt.Sprintf("\n### Virtual Machines (%d)\n\n", len(infra.VirtualMachines)))
+			rows := make([][]string, 0, len(infra.VirtualMachines))
+			for _, vm := range infra.VirtualMachines {
+				rows = append(rows, []string{
+					vm.Name, vm.State, fmt.Sprint(vm.CPUs), fmt.Sprint(vm.Memory), vm.OperatingSystem, vm.Host,
+					strings.Join(f.getVMNetworks(vm), ", "), fmt.Sprint(snapshotCount(vm)), formatAnnotations(vm.Annotations),
+				})
+			}
+			writeMarkdownTable(&b, []string{"Name", "State", "CPU", "Memory (MiB)", "OS", "Host", "Networks", "Snapshots", "Annotations"}, rows)
+		}
+
+		if len(infra.Networks) > 0 {
+			b.WriteString(fmt.Sprintf("\n### Networks (%d)\n\n", len(infra.Networks)))
+			rows := make([][]string, 0, len(infra.Networks))
+			for _, network := range infra.Networks {
+				vlan, mtu, dhcp := "", "", "No"
+				if network.VLAN > 0 {
+					vlan = fmt.Sprint(network.VLAN)
+				}
+				if network.MTU > 0 {
+					mtu = fmt.Sprint(network.MTU)
+				}
+				if network.DHCP {
+					dhcp = "Yes"
+				}
+				rows = append(rows, []string{network.Name, network.Type, vlan, network.VSwitch, mtu, securityPolicySummary(network.SecurityPolicy), dhcp})
+			}
+			writeMarkdownTable(&b, []string{"Name", "Type", "VLAN", "VSwitch", "MTU", "Security", "DHCP"}, rows)
+		}
+
+		if len(infra.Storage) > 0 {
+			b.WriteString(fmt.Sprintf("\n### Storage (%d)\n\n", len(infra.Storage)))
+			rows := make([][]string, 0, len(infra.Storage))
+			for _, store := range infra.Storage {
+				accessible := "No"
+				if store.Accessible {
+					accessible = "Yes"
+				}
+				rows = append(rows, []string{store.Name, store.Type, fmt.Sprint(store.Capacity), fmt.Sprint(store.FreeSpace), usedPercent(store), accessible})
+			}
+			writeMarkdownTable(&b, []string{"Name", "Type", "Capacity (GiB)", "Free (GiB)", "Used (%)", "Accessible"}, rows)
+		}
+	}
+
+	return []byte(b.String()), nil
+}
+
+// writeMarkdownTable writes a pipe table, escaping the row cells
+func writeMarkdownTable(b *strings.Builder, header []string, rows [][]string) {
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = markdownEscape(cell)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+}
+
+// markdownEscaper escapes the characters that end a table cell or start
+// inline markup
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"|", `\|`,
+	"*", `\*`,
+	"_", `\_`,
+	"`", "\\`",
+	"<", "&lt;",
+	"[", `\[`,
+	"]", `\]`,
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+// markdownEscape escapes a discovered value for use in Markdown text or a
+// table cell
+func markdownEscape(value string) string {
+	return markdownEscaper.Replace(value)
+}
@@ -0,0 +1,148 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// identityFields are always kept by projection so output stays identifiable
+var identityFields = []string{"provider", "server", "discovery_time"}
+
+// ParseFieldPaths splits dotted field paths and checks each one against the
+// infrastructure model. A "*" segment matches every field at that level.
+func ParseFieldPaths(fields []string) ([][]string, error) {
+	var paths [][]string
+
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		path := strings.Split(field, ".")
+		if err := checkFieldPath(reflect.TypeOf(models.Infrastructure{}), path); err != nil {
+			return nil, fmt.Errorf("unknown field %q: %w", field, err)
+		}
+		paths = append(paths, path)
+	}
+
+	for _, field := range identityFields {
+		paths = append(paths, []string{field})
+	}
+
+	return paths, nil
+}
+
+// checkFieldPath verifies that a path resolves through the JSON field names of t
+func checkFieldPath(t reflect.Type, path []string) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	if len(path) == 0 {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Map, reflect.Interface:
+		// Free-form maps such as metadata and annotations accept any key
+		return nil
+	case reflect.Struct:
+		if t == reflect.TypeOf(time.Time{}) {
+			return fmt.Errorf("cannot select %s from a scalar value", path[0])
+		}
+	default:
+		return fmt.Errorf("cannot select %s from a scalar value", path[0])
+	}
+
+	segment := path[0]
+	if segment == "*" {
+		// A wildcard is valid if any field can take the rest of the path
+		for i := 0; i < t.NumField(); i++ {
+			if jsonFieldName(t.Field(i)) != "" && checkFieldPath(t.Field(i).Type, path[1:]) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("no field matches %s", strings.Join(path, "."))
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if jsonFieldName(t.Field(i)) == segment {
+			return checkFieldPath(t.Field(i).Type, path[1:])
+		}
+	}
+
+	return fmt.Errorf("no field named %s", segment)
+}
+
+// jsonFieldName returns the JSON name of a struct field, or "" if it is not encoded
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" || field.PkgPath != "" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// projectInfrastructures converts the infrastructures to their generic JSON
+// representation and keeps only the given field paths
+func projectInfrastructures(infrastructures []*models.Infrastructure, paths [][]string) ([]interface{}, error) {
+	data, err := json.Marshal(infrastructures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode infrastructure: %w", err)
+	}
+
+	var generic []interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("failed to decode infrastructure: %w", err)
+	}
+
+	projected := make([]interface{}, len(generic))
+	for i, infra := range generic {
+		projected[i] = projectValue(infra, paths)
+	}
+
+	return projected, nil
+}
+
+// projectValue keeps the parts of a decoded JSON value selected by paths
+func projectValue(value interface{}, paths [][]string) interface{} {
+	for _, path := range paths {
+		if len(path) == 0 {
+			return value
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{})
+		for key, child := range v {
+			var rest [][]string
+			for _, path := range paths {
+				if path[0] == key || path[0] == "*" {
+					rest = append(rest, path[1:])
+				}
+			}
+			if len(rest) > 0 {
+				result[key] = projectValue(child, rest)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, child := range v {
+			result[i] = projectValue(child, paths)
+		}
+		return result
+	default:
+		return value
+	}
+}
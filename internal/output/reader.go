@@ -0,0 +1,158 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"valhalla/internal/models"
+)
+
+// ReadFile reads discovery results from a file written by discover or
+// import. Encrypted files are decrypted with the passphrase in
+// VALHALLA_PASSPHRASE first. CSV is recognized by the .csv extension, before
+// any .enc; otherwise the content decides between a JSON list, JSON objects
+// one per line (NDJSON), and YAML as a single list or one document per
+// infrastructure.
+func ReadFile(filename string) ([]*models.Infrastructure, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	if IsEncrypted(data) {
+		passphrase, err := Passphrase()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if data, err = Decrypt(data, passphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+
+	name := filename
+	if strings.EqualFold(filepath.Ext(name), EncryptedSuffix) {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return ParseCSV(bytes.NewReader(data))
+	}
+	return Parse(data)
+}
+
+// Parse parses discovery results in any of the JSON and YAML layouts
+// ReadFile accepts. JSON is tried first, since YAML would also accept it but
+// decode it more loosely, and YAML is the fallback. Snapshots listed flat,
+// as older discovery files list them, are nested into their tree.
+func Parse(data []byte) ([]*models.Infrastructure, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+
+	infrastructures, jsonErr := parseJSON(trimmed)
+	if jsonErr == nil {
+		nestSnapshots(infrastructures)
+		return infrastructures, nil
+	}
+
+	infrastructures, err := parseYAML(bytes.TrimPrefix(data, utf8BOM))
+	if err != nil {
+		// Content that reads as JSON gets the JSON error, which points at
+		// the actual problem
+		if bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")) {
+			return nil, jsonErr
+		}
+		return nil, err
+	}
+	nestSnapshots(infrastructures)
+	return infrastructures, nil
+}
+
+// nestSnapshots nests every VM's snapshots into their tree
+func nestSnapshots(infrastructures []*models.Infrastructure) {
+	for _, infra := range infrastructures {
+		for i := range infra.VirtualMachines {
+			vm := &infra.VirtualMachines[i]
+			vm.Snapshots = models.SnapshotTree(vm.Snapshots)
+		}
+	}
+}
+
+// utf8BOM is written at the start of files by some Windows editors
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// parseJSON parses a JSON list of infrastructures, or JSON objects one per
+// line
+func parseJSON(data []byte) ([]*models.Infrastructure, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		var infrastructures []*models.Infrastructure
+		if err := json.Unmarshal(data, &infrastructures); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		return infrastructures, nil
+	case bytes.HasPrefix(data, []byte("{")):
+		return parseNDJSON(data)
+	}
+	return nil, fmt.Errorf("not JSON")
+}
+
+// parseNDJSON parses one or more JSON infrastructure objects, one per line
+func parseNDJSON(data []byte) ([]*models.Infrastructure, error) {
+	var infrastructures []*models.Infrastructure
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var infra models.Infrastructure
+		if err := decoder.Decode(&infra); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse JSON object %d: %w", len(infrastructures)+1, err)
+		}
+		infrastructures = append(infrastructures, &infra)
+	}
+
+	return infrastructures, nil
+}
+
+// parseYAML parses YAML discovery results written either as a single list
+// or as one document per infrastructure
+func parseYAML(data []byte) ([]*models.Infrastructure, error) {
+	var infrastructures []*models.Infrastructure
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		}
+		if len(doc.Content) == 0 {
+			continue
+		}
+
+		if doc.Content[0].Kind == yaml.SequenceNode {
+			var list []*models.Infrastructure
+			if err := doc.Decode(&list); err != nil {
+				return nil, fmt.Errorf("failed to parse YAML: %w", err)
+			}
+			infrastructures = append(infrastructures, list...)
+			continue
+		}
+
+		var infra models.Infrastructure
+		if err := doc.Decode(&infra); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document: %w", err)
+		}
+		infrastructures = append(infrastructures, &infra)
+	}
+
+	return infrastructures, nil
+}
@@ -0,0 +1,232 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// testInfrastructures returns two small infrastructures covering the
+// resource types every output format writes
+func testInfrastructures() []*models.Infrastructure {
+	discovered := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return []*models.Infrastructure{
+		{
+			Provider:      "vmware",
+			Server:        "vcenter.example.com",
+			Datacenter:    "DC1",
+			Cluster:       "Cluster1",
+			DiscoveryTime: discovered,
+			VirtualMachines: []models.VirtualMachine{
+				{
+					ID: "vm-1", Name: "web-01", PowerState: "poweredOn", OperatingSystem: "Ubuntu Linux (64-bit)",
+					CPUs: 2, Memory: 4096, Config: models.VMConfig{GuestID: "ubuntu64Guest", UUID: "4201-aaaa"},
+					Disks:        []models.Disk{{Name: "Hard disk 1", Size: 40, Datastore: "ds1", DatastoreID: "datastore-1", Type: "thin"}},
+					NetworkCards: []models.NetworkCard{{Name: "Network adapter 1", Network: "VM Network", MACAddress: "00:50:56:00:00:01", Type: "vmxnet3", Connected: true}},
+					Annotations:  map[string]string{"notes": "web tier"},
+				},
+				{
+					ID: "vm-2", Name: "db, primary", PowerState: "poweredOff",
+					CPUs: 4, Memory: 8192, Config: models.VMConfig{GuestID: "rhel8_64Guest", UUID: "4201-bbbb"},
+					Disks: []models.Disk{{Name: "Hard disk 1", Size: 100, Datastore: "ds1", DatastoreID: "datastore-1", Type: "thick"}},
+				},
+			},
+			Networks: []models.Network{{ID: "network-1", Name: "VM Network", Type: "Network", VLAN: 10}},
+			Storage:  []models.Storage{{ID: "datastore-1", Name: "ds1", Type: "VMFS", Capacity: 1024, FreeSpace: 512, Accessible: true}},
+		},
+		{
+			Provider:      "proxmox",
+			Server:        "pve.example.com",
+			DiscoveryTime: discovered,
+			VirtualMachines: []models.VirtualMachine{
+				{ID: "100", Name: "app-01", PowerState: "running", CPUs: 2, Memory: 2048},
+			},
+		},
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	for _, documents := range []bool{false, true} {
+		name := "list"
+		if documents {
+			name = "documents"
+		}
+		t.Run(name, func(t *testing.T) {
+			want := testInfrastructures()
+			data, err := NewFormatter("yaml").WithYAMLDocuments(documents).Format(want)
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if got := strings.Count(string(data), "---"); documents && got != len(want)-1 {
+				t.Errorf("got %d document separators, want %d", got, len(want)-1)
+			}
+
+			got, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			again, err := NewFormatter("yaml").WithYAMLDocuments(documents).Format(got)
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("round trip changed the output\ngot:\n%s\nwant:\n%s", again, data)
+			}
+		})
+	}
+}
+
+func TestParseJSONLayouts(t *testing.T) {
+	want := testInfrastructures()
+	list, err := NewFormatter("json").Format(want)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+
+	tests := map[string][]byte{
+		"list":            list,
+		"byte order mark": append([]byte("\xef\xbb\xbf"), list...),
+		"ndjson": []byte(`{"provider":"vmware","server":"a","discovery_time":"2024-03-01T12:00:00Z"}
+{"provider":"proxmox","server":"b","discovery_time":"2024-03-01T12:00:00Z"}`),
+		"yaml flow list": []byte(`[{provider: vmware, server: a}, {provider: proxmox, server: b}]`),
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse(data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(got) != 2 || got[0].Provider != "vmware" || got[1].Provider != "proxmox" {
+				t.Errorf("Parse returned %d infrastructures: %+v", len(got), got)
+			}
+		})
+	}
+}
+
+func TestParseNestsFlatSnapshots(t *testing.T) {
+	flat, err := os.ReadFile(filepath.Join("testdata", "snapshots-flat.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	infrastructures, err := Parse(flat)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	vm := infrastructures[0].VirtualMachines[0]
+	if count := snapshotCount(vm); count != 4 {
+		t.Errorf("snapshotCount = %d, want 4", count)
+	}
+
+	data, err := NewFormatter("json").Format(infrastructures)
+	if err != nil {
+		t.Fatalf("Format: %v", err)
+	}
+	golden := filepath.Join("testdata", "snapshots.json")
+	if *update {
+		if err := os.WriteFile(golden, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("nested snapshots differ from %s; rerun with -update if the change is intended\ngot:\n%s", golden, data)
+	}
+
+	// A file with the tree reads back unchanged
+	nested, err := Parse(want)
+	if err != nil {
+		t.Fatalf("Parse %s: %v", golden, err)
+	}
+	if data, err := NewFormatter("json").Format(nested); err != nil || !bytes.Equal(data, want) {
+		t.Errorf("%s did not read back unchanged: %v\n%s", golden, err, data)
+	}
+}
+
+func TestParseReportsJSONErrors(t *testing.T) {
+	_, err := Parse([]byte(`[{"provider": "vmware"}`))
+	if err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Errorf("Parse error = %v, want the JSON syntax error", err)
+	}
+}
+
+func TestReadEncryptedFile(t *testing.T) {
+	t.Setenv(PassphraseEnv, "correct horse battery staple")
+	passphrase, err := Passphrase()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, format := range []string{"json", "yaml", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := NewFormatter(format).Format(testInfrastructures())
+			if err != nil {
+				t.Fatalf("Format: %v", err)
+			}
+			encrypted, err := Encrypt(data, passphrase)
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if bytes.Contains(encrypted, []byte("web-01")) {
+				t.Fatal("encrypted data holds plain text")
+			}
+
+			filename := filepath.Join(dir, "discovery."+format+EncryptedSuffix)
+			if err := os.WriteFile(filename, encrypted, 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadFile(filename)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if len(got) != 2 || len(got[0].VirtualMachines) != 2 || got[1].VirtualMachines[0].Name != "app-01" {
+				t.Errorf("ReadFile returned %+v", got)
+			}
+		})
+	}
+}
+
+func TestDecryptRejects(t *testing.T) {
+	encrypted, err := Encrypt([]byte(`[]`), "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := map[string]struct {
+		data       []byte
+		passphrase string
+	}{
+		"wrong passphrase": {encrypted, "wrong"},
+		"tampered":         {tampered, "right"},
+		"truncated":        {encrypted[:len(encryptedMagic)+4], "right"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Decrypt(tt.data, tt.passphrase); !errors.Is(err, ErrBadPassphrase) {
+				t.Errorf("Decrypt error = %v, want ErrBadPassphrase", err)
+			}
+		})
+	}
+
+	filename := filepath.Join(t.TempDir(), "discovery.json.enc")
+	if err := os.WriteFile(filename, encrypted, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PassphraseEnv, "")
+	if _, err := ReadFile(filename); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("ReadFile error = %v, want ErrNoPassphrase", err)
+	}
+}
@@ -0,0 +1,219 @@
+package output
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"valhalla/internal/models"
+)
+
+// Condition is one --where filter: a dotted field path as in --fields, an
+// operator and the value to compare with
+type Condition struct {
+	Path     []string
+	Operator string
+	Value    string
+}
+
+// whereOperators are the supported operators, two-character ones first so
+// ">=" is not read as ">"
+var whereOperators = []string{"!=", ">=", "<=", "=", ">", "<"}
+
+// ParseConditions parses --where expressions such as
+// virtual_machines.power_state=poweredOn or virtual_machines.cpus>=4 and
+// checks each path against the infrastructure model. "=" and "!=" ignore
+// case and accept * and ? patterns; the ordering operators compare numbers
+// numerically and anything else, such as RFC 3339 times, as text.
+func ParseConditions(expressions []string) ([]Condition, error) {
+	var conditions []Condition
+
+	for _, expression := range expressions {
+		expression = strings.TrimSpace(expression)
+		if expression == "" {
+			continue
+		}
+
+		i := strings.IndexAny(expression, "!=<>")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid condition %q: want <field><operator><value>, e.g. virtual_machines.cpus>=4", expression)
+		}
+		var condition Condition
+		for _, operator := range whereOperators {
+			if strings.HasPrefix(expression[i:], operator) {
+				condition.Operator = operator
+				break
+			}
+		}
+		if condition.Operator == "" {
+			return nil, fmt.Errorf("invalid condition %q: unknown operator (supported: %s)", expression, strings.Join(whereOperators, " "))
+		}
+
+		field := strings.TrimSpace(expression[:i])
+		condition.Path = strings.Split(field, ".")
+		condition.Value = strings.TrimSpace(expression[i+len(condition.Operator):])
+		for _, segment := range condition.Path {
+			if segment == "*" {
+				return nil, fmt.Errorf("invalid condition %q: wildcards are not allowed in field paths", expression)
+			}
+		}
+		if err := checkFieldPath(reflect.TypeOf(models.Infrastructure{}), condition.Path); err != nil {
+			return nil, fmt.Errorf("unknown field %q in condition: %w", field, err)
+		}
+
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// String returns the condition as it was written
+func (c Condition) String() string {
+	return strings.Join(c.Path, ".") + c.Operator + c.Value
+}
+
+// Where returns the infrastructures matching every condition. A condition on
+// a resource list such as virtual_machines.cpus>=4 keeps the resources of
+// that list it matches; any other condition, such as provider=vmware, keeps
+// or drops whole infrastructures. A path through a nested list, e.g.
+// virtual_machines.disks.datastore=ds1, matches when any element does. The
+// given infrastructures are not modified.
+func Where(infrastructures []*models.Infrastructure, conditions []Condition) []*models.Infrastructure {
+	if len(conditions) == 0 {
+		return infrastructures
+	}
+
+	var kept []*models.Infrastructure
+	for _, infra := range infrastructures {
+		filtered := *infra
+		v := reflect.ValueOf(&filtered).Elem()
+
+		matched := true
+		for _, condition := range conditions {
+			field := structField(v, condition.Path[0])
+			if field.Kind() == reflect.Slice && len(condition.Path) > 1 {
+				elements := reflect.MakeSlice(field.Type(), 0, field.Len())
+				for i := 0; i < field.Len(); i++ {
+					if condition.matches(field.Index(i), condition.Path[1:]) {
+						elements = reflect.Append(elements, field.Index(i))
+					}
+				}
+				field.Set(elements)
+				continue
+			}
+			if !condition.matches(v, condition.Path) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			kept = append(kept, &filtered)
+		}
+	}
+
+	return kept
+}
+
+// matches reports whether the values at path under v satisfy the condition.
+// "!=" holds when no value equals, so it also holds for an empty list.
+func (c Condition) matches(v reflect.Value, path []string) bool {
+	values := fieldValues(v, path, nil)
+	if c.Operator == "!=" {
+		for _, value := range values {
+			if compare(value, "=", c.Value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range values {
+		if compare(value, c.Operator, c.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValues appends the text of every scalar value reachable through path
+// from v, fanning out over lists
+func fieldValues(v reflect.Value, path []string, values []string) []string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return values
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			values = fieldValues(v.Index(i), path, values)
+		}
+		return values
+	}
+
+	if len(path) == 0 {
+		if t, ok := v.Interface().(time.Time); ok {
+			return append(values, t.Format(time.RFC3339))
+		}
+		return append(values, fmt.Sprint(v.Interface()))
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if value := v.MapIndex(reflect.ValueOf(path[0])); value.IsValid() {
+			return fieldValues(value, path[1:], values)
+		}
+	case reflect.Struct:
+		if field := structField(v, path[0]); field.IsValid() {
+			return fieldValues(field, path[1:], values)
+		}
+	}
+	return values
+}
+
+// structField returns the field of struct v with the given JSON name
+func structField(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		if jsonFieldName(v.Type().Field(i)) == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// compare applies operator to a field value and the condition's value
+func compare(value, operator, want string) bool {
+	if operator == "=" {
+		ok, _ := path.Match(strings.ToLower(want), strings.ToLower(value))
+		return ok
+	}
+
+	var cmp int
+	x, errX := strconv.ParseFloat(value, 64)
+	y, errY := strconv.ParseFloat(want, 64)
+	switch {
+	case errX == nil && errY == nil && x < y:
+		cmp = -1
+	case errX == nil && errY == nil && x > y:
+		cmp = 1
+	case errX == nil && errY == nil:
+		cmp = 0
+	default:
+		cmp = strings.Compare(value, want)
+	}
+
+	switch operator {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
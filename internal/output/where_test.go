@@ -0,0 +1,107 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"valhalla/internal/models"
+)
+
+func TestParseConditions(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string // path, operator and value joined by spaces
+		err        string
+	}{
+		{expression: "virtual_machines.cpus>=4", want: "virtual_machines.cpus >= 4"},
+		{expression: " provider = vmware ", want: "provider = vmware"},
+		{expression: "virtual_machines.annotations.owner!=ops", want: "virtual_machines.annotations.owner != ops"},
+		{expression: "virtual_machines.name=a=b", want: "virtual_machines.name = a=b"},
+		{expression: "storage.free_space<100", want: "storage.free_space < 100"},
+		{expression: "virtual_machines.cpus", err: "invalid condition"},
+		{expression: "=vmware", err: "invalid condition"},
+		{expression: "provider!vmware", err: "unknown operator"},
+		{expression: "virtual_machines.*=x", err: "wildcards"},
+		{expression: "virtual_machines.nope=x", err: "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			conditions, err := ParseConditions([]string{tt.expression})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("ParseConditions error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConditions: %v", err)
+			}
+			c := conditions[0]
+			if got := strings.Join(c.Path, ".") + " " + c.Operator + " " + c.Value; got != tt.want {
+				t.Errorf("parsed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		name  string
+		where []string
+		want  string // remaining VMs by infrastructure
+	}{
+		{name: "none", want: "vmware:web-01,db, primary proxmox:app-01"},
+		{name: "number", where: []string{"virtual_machines.cpus>2"}, want: "vmware:db, primary proxmox:"},
+		{name: "number not text", where: []string{"virtual_machines.memory>=10000"}, want: "vmware: proxmox:"},
+		{name: "case and pattern", where: []string{"virtual_machines.power_state=POWERED*"}, want: "vmware:web-01,db, primary proxmox:"},
+		{name: "not equal", where: []string{"virtual_machines.power_state!=poweredOff"}, want: "vmware:web-01 proxmox:app-01"},
+		{name: "map key", where: []string{"virtual_machines.annotations.notes=web tier"}, want: "vmware:web-01 proxmox:"},
+		{name: "nested list", where: []string{"virtual_machines.disks.type=thick"}, want: "vmware:db, primary proxmox:"},
+		{name: "empty nested list not equal", where: []string{"virtual_machines.network_cards.network!=VM Network"}, want: "vmware:db, primary proxmox:app-01"},
+		{name: "infrastructure", where: []string{"provider=proxmox"}, want: "proxmox:app-01"},
+		{name: "time", where: []string{"discovery_time>=2024-03-01T00:00:00Z"}, want: "vmware:web-01,db, primary proxmox:app-01"},
+		{name: "all must match", where: []string{"provider=vmware", "virtual_machines.cpus<4"}, want: "vmware:web-01"},
+		{name: "other list untouched", where: []string{"networks.vlan=20"}, want: "vmware:web-01,db, primary proxmox:app-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, err := ParseConditions(tt.where)
+			if err != nil {
+				t.Fatalf("ParseConditions: %v", err)
+			}
+			infrastructures := testInfrastructures()
+			got := Where(infrastructures, conditions)
+
+			var summary []string
+			for _, infra := range got {
+				var names []string
+				for _, vm := range infra.VirtualMachines {
+					names = append(names, vm.Name)
+				}
+				summary = append(summary, infra.Provider+":"+strings.Join(names, ","))
+			}
+			if strings.Join(summary, " ") != tt.want {
+				t.Errorf("Where(%v) = %q, want %q", tt.where, strings.Join(summary, " "), tt.want)
+			}
+			if len(infrastructures[0].VirtualMachines) != 2 {
+				t.Error("Where modified the infrastructures it was given")
+			}
+		})
+	}
+}
+
+func TestWhereDropsNetworks(t *testing.T) {
+	conditions, err := ParseConditions([]string{"networks.vlan=20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Where([]*models.Infrastructure{testInfrastructures()[0]}, conditions)
+	if len(got) != 1 {
+		t.Fatalf("Where kept %d infrastructures, want 1", len(got))
+	}
+	if len(got[0].Networks) != 0 || len(got[0].Storage) != 1 {
+		t.Errorf("Where kept %d networks and %d storage, want 0 and 1", len(got[0].Networks), len(got[0].Storage))
+	}
+}
@@ -0,0 +1,79 @@
+// Package secrets keeps provider credentials in the operating system's
+// keyring (the macOS Keychain, Windows Credential Manager, or a Secret
+// Service such as GNOME Keyring on Linux) so the config file only holds a
+// reference to them.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// ReferencePrefix marks a config value that refers to a keyring entry, e.g.
+// keyring:valhalla/vmware/vcenter.example.com
+const ReferencePrefix = "keyring:"
+
+var (
+	// ErrNotFound is returned when a referenced keyring entry doesn't exist
+	ErrNotFound = errors.New("keyring entry not found")
+
+	// ErrUnavailable is returned when there is no keyring to use, such as
+	// on a headless Linux host without a Secret Service
+	ErrUnavailable = errors.New("OS keyring not available")
+)
+
+// Key returns the keyring key of a provider's secret for a server
+func Key(provider, server string) string {
+	return "valhalla/" + strings.ToLower(provider) + "/" + strings.ToLower(server)
+}
+
+// Reference returns the config value referring to the keyring entry key
+func Reference(key string) string {
+	return ReferencePrefix + key
+}
+
+// ParseReference returns the keyring key a config value refers to, and
+// whether it is a reference at all
+func ParseReference(value string) (string, bool) {
+	if !strings.HasPrefix(value, ReferencePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, ReferencePrefix), true
+}
+
+// Set stores a secret for a user under key
+func Set(key, user, secret string) error {
+	if err := keyring.Set(key, user, secret); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return nil
+}
+
+// Get returns the secret stored for a user under key
+func Get(key, user string) (string, error) {
+	secret, err := keyring.Get(key, user)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s for user %s", ErrNotFound, key, user)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return secret, nil
+}
+
+// Resolve returns value, or the secret it refers to when it is a keyring
+// reference. Missing entries name the command that stores them again.
+func Resolve(provider, user, value string) (string, error) {
+	key, ok := ParseReference(value)
+	if !ok {
+		return value, nil
+	}
+	secret, err := Get(key, user)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s credentials from the keyring (run valhalla auth %s --save --keyring, or set them in the environment): %w", provider, provider, err)
+	}
+	return secret, nil
+}
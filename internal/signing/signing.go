@@ -0,0 +1,144 @@
+// Package signing signs discovery and generation outputs with Ed25519 keys,
+// so a file can be traced to the holder of a key and checked for tampering.
+// Signatures are detached: file.json is signed by file.json.sig, the
+// base64-encoded signature of its exact bytes. Everything in the file,
+// including the provenance block, is covered.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureSuffix is appended to a file's path to name its signature
+const SignatureSuffix = ".sig"
+
+// ErrBadSignature is returned when a signature does not match the file
+var ErrBadSignature = errors.New("signature does not match")
+
+// GenerateKey creates a key pair, PEM-encoded: the private key as PKCS #8,
+// the public key as PKIX
+func GenerateKey() (private, public []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	private = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER})
+	public = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+	return private, public, nil
+}
+
+// LoadPrivateKey reads a PEM-encoded Ed25519 private key
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key %s: %w", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is %T, not Ed25519", path, key)
+	}
+	return private, nil
+}
+
+// LoadPublicKey reads a PEM-encoded Ed25519 public key
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key %s: %w", path, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is %T, not Ed25519", path, key)
+	}
+	return public, nil
+}
+
+// readPEM returns the DER bytes of the first PEM block of the given type
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no %s block in %s", blockType, path)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+// Sign returns the encoded signature of data
+func Sign(data []byte, key ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)) + "\n")
+}
+
+// Verify checks an encoded signature of data
+func Verify(data, signature []byte, key ed25519.PublicKey) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("malformed signature: %w", err)
+	}
+	if !ed25519.Verify(key, data, raw) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// SignFile writes the signature of a file next to it and returns its path
+func SignFile(path string, key ed25519.PrivateKey) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	sigPath := path + SignatureSuffix
+	if err := os.WriteFile(sigPath, Sign(data, key), 0644); err != nil {
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+	return sigPath, nil
+}
+
+// VerifyFile checks a file against the signature next to it
+func VerifyFile(path string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	signature, err := os.ReadFile(path + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to read signature of %s: %w", path, err)
+	}
+	if err := Verify(data, signature, key); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
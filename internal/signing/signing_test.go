@@ -0,0 +1,133 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// discovery is a discovery result with a provenance block
+const discovery = `[{"provider":"vmware","metadata":{"provenance":{"hostname":"ops-01","provider_user":"svc-valhalla@vsphere.local"}}}]`
+
+// keyPair writes a new key pair to dir and returns the paths
+func keyPair(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+
+	private, public, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath, publicPath := filepath.Join(dir, name+".key"), filepath.Join(dir, name+".pub")
+	if err := os.WriteFile(privatePath, private, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, public, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return privatePath, publicPath
+}
+
+func TestSignFile(t *testing.T) {
+	dir := t.TempDir()
+	privatePath, publicPath := keyPair(t, dir, "valhalla")
+	_, otherPublicPath := keyPair(t, dir, "other")
+
+	private, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey: %v", err)
+	}
+	public, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey: %v", err)
+	}
+	otherPublic, err := LoadPublicKey(otherPublicPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string            // written over the file after signing, if set
+		key     ed25519.PublicKey // verified with, if not the signing key
+		wantErr error
+	}{
+		{name: "unchanged", wantErr: nil},
+		{
+			name:    "provenance edited",
+			content: strings.Replace(discovery, "svc-valhalla", "administrator", 1),
+			wantErr: ErrBadSignature,
+		},
+		{name: "other key", key: otherPublic, wantErr: ErrBadSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "discovery.json")
+			if err := os.WriteFile(path, []byte(discovery), 0644); err != nil {
+				t.Fatal(err)
+			}
+			sigPath, err := SignFile(path, private)
+			if err != nil {
+				t.Fatalf("SignFile: %v", err)
+			}
+			if sigPath != path+SignatureSuffix {
+				t.Errorf("signature written to %s", sigPath)
+			}
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			key := public
+			if tt.key != nil {
+				key = tt.key
+			}
+			err = VerifyFile(path, key)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("VerifyFile: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyFile = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyFileWithoutSignature(t *testing.T) {
+	dir := t.TempDir()
+	_, publicPath := keyPair(t, dir, "valhalla")
+	public, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "discovery.json")
+	if err := os.WriteFile(path, []byte(discovery), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyFile(path, public); err == nil {
+		t.Error("unsigned file verified")
+	}
+	if err := os.WriteFile(path+SignatureSuffix, []byte("not base64!\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyFile(path, public); err == nil || errors.Is(err, ErrBadSignature) {
+		t.Errorf("VerifyFile = %v, want a malformed signature error", err)
+	}
+}
+
+func TestLoadKeyOfWrongType(t *testing.T) {
+	dir := t.TempDir()
+	privatePath, publicPath := keyPair(t, dir, "valhalla")
+
+	if _, err := LoadPrivateKey(publicPath); err == nil {
+		t.Error("public key loaded as a private key")
+	}
+	if _, err := LoadPublicKey(privatePath); err == nil {
+		t.Error("private key loaded as a public key")
+	}
+}
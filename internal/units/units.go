@@ -0,0 +1,105 @@
+// Package units converts between byte sizes.
+//
+// Valhalla reports sizes in binary units everywhere: a size labelled GB in
+// the models and output is a GiB (2^30 bytes) and MB is a MiB (2^20 bytes),
+// matching how vCenter and Proxmox display capacity. Decimal units are only
+// used when explicitly formatted with HumanSI.
+package units
+
+import (
+	"fmt"
+	"math"
+)
+
+// ByteSize is a size in bytes
+type ByteSize int64
+
+// Binary (IEC) units
+const (
+	Byte ByteSize = 1
+	KiB           = 1024 * Byte
+	MiB           = 1024 * KiB
+	GiB           = 1024 * MiB
+	TiB           = 1024 * GiB
+	PiB           = 1024 * TiB
+)
+
+// Decimal (SI) units
+const (
+	KB ByteSize = 1000
+	MB          = 1000 * KB
+	GB          = 1000 * MB
+	TB          = 1000 * GB
+	PB          = 1000 * TB
+)
+
+// FromBytes returns the size of n bytes
+func FromBytes(n int64) ByteSize {
+	return ByteSize(n)
+}
+
+// FromKiB returns the size of n KiB
+func FromKiB(n int64) ByteSize {
+	return ByteSize(n) * KiB
+}
+
+// FromMiB returns the size of n MiB
+func FromMiB(n int64) ByteSize {
+	return ByteSize(n) * MiB
+}
+
+// FromGiB returns the size of n GiB
+func FromGiB(n int64) ByteSize {
+	return ByteSize(n) * GiB
+}
+
+// Bytes returns the size in bytes
+func (b ByteSize) Bytes() int64 {
+	return int64(b)
+}
+
+// ToMiB returns the size in MiB rounded to the nearest whole MiB
+func (b ByteSize) ToMiB() int64 {
+	return b.round(MiB)
+}
+
+// ToGiB returns the size in GiB rounded to the nearest whole GiB
+func (b ByteSize) ToGiB() int64 {
+	return b.round(GiB)
+}
+
+// CeilGiB returns the size in whole GiB rounded up. Use it for sizes that
+// are recreated, such as disks, which must never come out smaller.
+func (b ByteSize) CeilGiB() int64 {
+	return int64(math.Ceil(float64(b) / float64(GiB)))
+}
+
+// round divides by unit, rounding half away from zero
+func (b ByteSize) round(unit ByteSize) int64 {
+	return int64(math.Round(float64(b) / float64(unit)))
+}
+
+// HumanIEC formats the size with binary units, e.g. "1.5 GiB"
+func (b ByteSize) HumanIEC() string {
+	return b.human(1024, []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"})
+}
+
+// HumanSI formats the size with decimal units, e.g. "1.6 GB"
+func (b ByteSize) HumanSI() string {
+	return b.human(1000, []string{"B", "kB", "MB", "GB", "TB", "PB"})
+}
+
+// human scales the size by base until it fits the largest suitable unit
+func (b ByteSize) human(base float64, suffixes []string) string {
+	value := float64(b)
+	i := 0
+	for math.Abs(value) >= base && i < len(suffixes)-1 {
+		value /= base
+		i++
+	}
+
+	if i == 0 {
+		return fmt.Sprintf("%d %s", int64(b), suffixes[0])
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
@@ -0,0 +1,47 @@
+package units
+
+import "testing"
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name string
+		got  int64
+		want int64
+	}{
+		{"KiB to bytes", FromKiB(16384).Bytes(), 16777216},
+		{"GiB to MiB", FromGiB(40).ToMiB(), 40960},
+		{"MiB to GiB exact", FromMiB(4096).CeilGiB(), 4},
+		{"MiB to GiB rounds up", FromMiB(4097).CeilGiB(), 5},
+		{"MiB to GiB below one", FromMiB(512).CeilGiB(), 1},
+		{"zero", FromMiB(0).CeilGiB(), 0},
+		{"bytes to nearest MiB", FromBytes(3 * int64(MiB) / 2).ToMiB(), 2},
+		{"bytes to nearest GiB", FromBytes(int64(GiB) + int64(MiB)).ToGiB(), 1},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestHuman(t *testing.T) {
+	tests := []struct {
+		size ByteSize
+		iec  string
+		si   string
+	}{
+		{512, "512 B", "512 B"},
+		{3 * GiB / 2, "1.5 GiB", "1.6 GB"},
+		{2 * TiB, "2.0 TiB", "2.2 TB"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.size.HumanIEC(); got != tt.iec {
+			t.Errorf("HumanIEC(%d) = %q, want %q", tt.size, got, tt.iec)
+		}
+		if got := tt.size.HumanSI(); got != tt.si {
+			t.Errorf("HumanSI(%d) = %q, want %q", tt.size, got, tt.si)
+		}
+	}
+}
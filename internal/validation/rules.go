@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Rule describes a validation check and how severe its issues are
+type Rule struct {
+	ID             string `json:"id"`
+	Format         string `json:"format"` // file format the rule checks
+	Description    string `json:"description"`
+	Severity       string `json:"severity"`        // default severity
+	StrictSeverity string `json:"strict_severity"` // severity under --strict
+}
+
+// Rules is the registry of validation rules
+var Rules = []Rule{
+	{ID: "terraform-module-version", Format: "terraform", Description: "Module sources pin a version constraint", Severity: SeverityWarning, StrictSeverity: SeverityError},
+	{ID: "terraform-hardcoded-ami", Format: "terraform", Description: "AMI IDs are passed in as variables", Severity: SeverityWarning, StrictSeverity: SeverityWarning},
+	{ID: "terraform-hardcoded-credentials", Format: "terraform", Description: "Passwords, secrets and tokens are not string literals", Severity: SeverityWarning, StrictSeverity: SeverityError},
+	{ID: "json-syntax", Format: "json", Description: "Content is a JSON object or array", Severity: SeverityError, StrictSeverity: SeverityError},
+	{ID: "json-escape", Format: "json", Description: "Backslashes are escaped", Severity: SeverityWarning, StrictSeverity: SeverityWarning},
+	{ID: "yaml-tabs", Format: "yaml", Description: "Indentation uses spaces, not tabs", Severity: SeverityError, StrictSeverity: SeverityError},
+	{ID: "pulumi-import", Format: "pulumi", Description: "Programs import the Pulumi SDK", Severity: SeverityWarning, StrictSeverity: SeverityError},
+	{ID: "ansible-structure", Format: "ansible", Description: "Playbooks declare hosts or tasks", Severity: SeverityWarning, StrictSeverity: SeverityError},
+	{ID: "secret-token", Format: "all", Description: "No API tokens or private keys in a known format", Severity: SeverityError, StrictSeverity: SeverityError},
+	{ID: "secret-env", Format: "all", Description: "Provider credential environment variables are not set to literals", Severity: SeverityError, StrictSeverity: SeverityError},
+	{ID: "secret-literal", Format: "all", Description: "Password, secret and token keys hold references or placeholders, not values", Severity: SeverityError, StrictSeverity: SeverityError},
+	{ID: "secret-entropy", Format: "all", Description: "No random-looking strings that may be keys", Severity: SeverityError, StrictSeverity: SeverityError},
+}
+
+// LookupRule returns the registered rule with the given ID
+func LookupRule(id string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// RuleOverride changes a rule's behaviour; nil and empty values keep the default
+type RuleOverride struct {
+	Enabled  *bool
+	Severity string
+}
+
+// RuleOverrides holds per-rule overrides by rule ID
+type RuleOverrides map[string]RuleOverride
+
+// Check reports unknown rule IDs and invalid severities
+func (o RuleOverrides) Check() error {
+	ids := make([]string, 0, len(o))
+	for id := range o {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if _, ok := LookupRule(id); !ok {
+			return fmt.Errorf("unknown validation rule: %s (see valhalla validate --list-rules)", id)
+		}
+		switch o[id].Severity {
+		case "", SeverityError, SeverityWarning, SeverityInfo:
+		default:
+			return fmt.Errorf("invalid severity for validation rule %s: %s (expected error, warning or info)", id, o[id].Severity)
+		}
+	}
+	return nil
+}
+
+// Merge returns the overrides with other's applied on top
+func (o RuleOverrides) Merge(other RuleOverrides) RuleOverrides {
+	merged := make(RuleOverrides, len(o)+len(other))
+	for id, override := range o {
+		merged[id] = override
+	}
+	for id, override := range other {
+		current := merged[id]
+		if override.Enabled != nil {
+			current.Enabled = override.Enabled
+		}
+		if override.Severity != "" {
+			current.Severity = override.Severity
+		}
+		merged[id] = current
+	}
+	return merged
+}
+
+// severity returns the severity issues of a rule are reported at, or false
+// when the rule is disabled
+func (opts ValidateOptions) severity(id string) (string, bool) {
+	override := opts.Rules[id]
+	if override.Enabled != nil && !*override.Enabled {
+		return "", false
+	}
+	if override.Severity != "" {
+		return override.Severity, true
+	}
+
+	rule, ok := LookupRule(id)
+	if !ok {
+		return SeverityWarning, true
+	}
+	if opts.Strict {
+		return rule.StrictSeverity, true
+	}
+	return rule.Severity, true
+}
+
+// report adds an issue for a rule unless the rule is disabled
+func (v *Validator) report(result *ValidationResult, opts ValidateOptions, rule string, line int, message string) {
+	severity, enabled := opts.severity(rule)
+	if !enabled {
+		return
+	}
+	result.Issues = append(result.Issues, &ValidationIssue{
+		Line:     line,
+		Message:  message,
+		Severity: severity,
+		Rule:     rule,
+	})
+}
+
+// credentialAttributes are attribute names whose values are secrets
+var credentialAttributes = []string{"password", "secret", "token", "access_key", "secret_key"}
+
+// isHardcodedCredential reports whether an HCL line assigns a literal string
+// to a credential attribute
+func isHardcodedCredential(line string) bool {
+	name, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return false
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	value = strings.TrimSpace(value)
+	// Empty strings and interpolations are not hardcoded secrets
+	if !strings.HasPrefix(value, `"`) || value == `""` || strings.Contains(value, "${") {
+		return false
+	}
+	for _, attribute := range credentialAttributes {
+		if strings.HasSuffix(name, attribute) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// tokenPatterns match credentials by their well-known format
+var tokenPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"Proxmox API token", regexp.MustCompile(`PVEAPIToken=[^\s"'!]+![^\s"'=]+=[0-9a-fA-F-]{36}`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |ENCRYPTED )?PRIVATE KEY-----`)},
+}
+
+// secretEnvNames are the environment variables providers and their
+// Terraform and CLI tooling read credentials from
+var secretEnvNames = map[string]bool{
+	"VSPHERE_PASSWORD":     true,
+	"GOVC_PASSWORD":        true,
+	"PROXMOX_PASSWORD":     true,
+	"PROXMOX_SECRET":       true,
+	"PROXMOX_VE_PASSWORD":  true,
+	"PROXMOX_VE_API_TOKEN": true,
+	"PM_PASS":              true,
+	"PM_API_TOKEN_SECRET":  true,
+	"NUTANIX_PASSWORD":     true,
+}
+
+// secretKeySuffixes are key names whose values are credentials
+var secretKeySuffixes = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey",
+	"access_key", "secret_key", "private_key",
+}
+
+// placeholderValues stand in for a credential rather than being one
+var placeholderValues = map[string]bool{
+	"change_me": true, "changeme": true, "replace_me": true, "todo": true,
+	"redacted": true, "true": true, "false": true, "null": true, "none": true,
+	"yes": true, "no": true, "~": true,
+}
+
+// assignmentPattern splits key = value, key: value and "key": "value" lines,
+// allowing an export or YAML list prefix
+var assignmentPattern = regexp.MustCompile(`^\s*(?:export\s+|-\s+)?["']?([A-Za-z0-9_.-]+)["']?\s*([:=])\s*(.*?)\s*,?\s*$`)
+
+// quotedPattern matches single or double quoted string literals
+var quotedPattern = regexp.MustCompile(`"([^"\\]*)"|'([^'\\]*)'`)
+
+// Strings shorter than minEntropyLength or less random than minEntropy bits
+// per character are not treated as secrets; hex digests and UUIDs stay
+// below the threshold
+const (
+	minEntropyLength = 24
+	minEntropy       = 4.2
+)
+
+// scanSecrets reports hardcoded credentials in content, at most one per
+// line, and returns the lines it reported
+func (v *Validator) scanSecrets(content string, result *ValidationResult, opts ValidateOptions) map[int]bool {
+	reported := make(map[int]bool)
+	for i, line := range strings.Split(content, "\n") {
+		rule, what, start, end, ok := findSecret(line)
+		if !ok {
+			continue
+		}
+		if _, enabled := opts.severity(rule); !enabled {
+			continue
+		}
+		snippet := strings.TrimSpace(line[:start] + redact(line[start:end]) + line[end:])
+		v.report(result, opts, rule, i+1, fmt.Sprintf("Hardcoded %s: %s", what, snippet))
+		reported[i+1] = true
+	}
+	return reported
+}
+
+// ScanSecrets reports hardcoded credentials in a file's content. Only
+// formats the validator understands are scanned, judged by path.
+func (v *Validator) ScanSecrets(path string, content []byte, opts ValidateOptions) []*ValidationIssue {
+	switch v.detectFormat(path) {
+	case "terraform", "json", "yaml":
+	default:
+		return nil
+	}
+	result := &ValidationResult{Issues: []*ValidationIssue{}}
+	v.scanSecrets(string(content), result, opts)
+	return result.Issues
+}
+
+// findSecret returns the rule a line breaks, what was found and the byte
+// range of the secret within the line
+func findSecret(line string) (rule, what string, start, end int, ok bool) {
+	for _, token := range tokenPatterns {
+		if loc := token.pattern.FindStringIndex(line); loc != nil {
+			return "secret-token", token.name, loc[0], loc[1], true
+		}
+	}
+
+	if match := assignmentPattern.FindStringSubmatchIndex(line); match != nil {
+		key := line[match[2]:match[3]]
+		separator := line[match[4]:match[5]]
+		if valueStart, valueEnd, literal := literalValue(line, match[6], match[7], separator); literal {
+			name := strings.TrimPrefix(key, "VALHALLA_")
+			if secretEnvNames[name] {
+				return "secret-env", name + " value", valueStart, valueEnd, true
+			}
+			if isSecretKey(key) {
+				return "secret-literal", key + " value", valueStart, valueEnd, true
+			}
+		}
+	}
+
+	for _, loc := range quotedPattern.FindAllStringSubmatchIndex(line, -1) {
+		valueStart, valueEnd := loc[2], loc[3]
+		if valueStart < 0 {
+			valueStart, valueEnd = loc[4], loc[5]
+		}
+		if isHighEntropy(line[valueStart:valueEnd]) {
+			return "secret-entropy", "high-entropy string", valueStart, valueEnd, true
+		}
+	}
+
+	return "", "", 0, 0, false
+}
+
+// literalValue returns the range of an assigned value that is a literal
+// credential rather than a reference or placeholder. With = only quoted
+// values are literals, since HCL and code assign references unquoted; with
+// : unquoted YAML scalars are literals too.
+func literalValue(line string, start, end int, separator string) (int, int, bool) {
+	value := line[start:end]
+	switch {
+	case len(value) >= 2 && (value[0] == '"' || value[0] == '\''):
+		closing := strings.IndexByte(value[1:], value[0])
+		if closing < 0 {
+			return 0, 0, false
+		}
+		start, end = start+1, start+1+closing
+	case separator == ":" && value != "" && !strings.ContainsAny(value[:1], "[{&*!|>"):
+		if comment := strings.Index(value, " #"); comment >= 0 {
+			end = start + comment
+		}
+	default:
+		return 0, 0, false
+	}
+
+	value = strings.TrimSpace(line[start:end])
+	if value == "" || isReference(value) {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// isReference reports whether a value refers to a credential kept elsewhere
+// or only stands in for one
+func isReference(value string) bool {
+	if strings.Contains(value, "${") || strings.Contains(value, "{{") || strings.Contains(value, "%{") {
+		return true
+	}
+	if strings.HasPrefix(value, "$") || strings.HasPrefix(value, "<") || strings.HasPrefix(value, "!vault") {
+		return true
+	}
+	if strings.Trim(value, "*x.") == "" {
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+	return placeholderValues[strings.ToLower(value)]
+}
+
+// isSecretKey reports whether a key names a credential
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, suffix := range secretKeySuffixes {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHighEntropy reports whether a string looks like a random key: long,
+// without spaces, mixing letters and digits and with high Shannon entropy
+func isHighEntropy(value string) bool {
+	if len(value) < minEntropyLength || strings.ContainsAny(value, " \t") {
+		return false
+	}
+	var letters, digits bool
+	counts := make(map[rune]int)
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			letters = true
+		case r >= '0' && r <= '9':
+			digits = true
+		case !strings.ContainsRune("+/=_-", r):
+			return false
+		}
+		counts[r]++
+	}
+	if !letters || !digits {
+		return false
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(value))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minEntropy
+}
+
+// redact keeps the first two characters of a secret so a finding can be
+// located without repeating it
+func redact(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return secret[:2] + "****"
+}
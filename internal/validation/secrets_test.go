@@ -0,0 +1,104 @@
+package validation
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"valhalla/internal/logger"
+)
+
+// Fake secrets, assembled so the source holds no literal token
+var (
+	fakeUUID    = "6f1c2a9e-" + "4b7d-4e21-9a3c-" + "5d8e0f1a2b3c"
+	fakeToken   = "PVEAPIToken=terraform@pve!ci=" + fakeUUID
+	fakeRandom  = "q7Xv2" + "LmP9rT4wZ8kN1bYc6Hs3Jd"
+	fakeGitHub  = "ghp_" + strings.Repeat("a1B2c3D4e5", 4)
+	fakeLiteral = "Sup3r" + "S3cret!"
+)
+
+func TestSecretsInEachFormat(t *testing.T) {
+	tests := []struct {
+		file    string
+		format  string
+		content string
+		rule    string
+		secret  string
+	}{
+		{"main.tf", "auto", "provider \"vsphere\" {\n  password = \"" + fakeLiteral + "\"\n}\n", "secret-literal", fakeLiteral},
+		{"terraform.tfvars", "auto", "pm_api_token = \"" + fakeToken + "\"\n", "secret-token", fakeToken},
+		{"values.yaml", "auto", "vsphere:\n  password: " + fakeLiteral + "\n", "secret-literal", fakeLiteral},
+		{"config.json", "auto", "{\n  \"VSPHERE_PASSWORD\": \"" + fakeLiteral + "\"\n}\n", "secret-env", fakeLiteral},
+		{"config.json", "auto", "{\n  \"github\": \"" + fakeGitHub + "\"\n}\n", "secret-token", fakeGitHub},
+		{"playbook.yml", "ansible", "- name: Deploy\n  hosts: all\n  vars:\n    signing: \"" + fakeRandom + "\"\n", "secret-entropy", fakeRandom},
+	}
+
+	validator := NewValidator(logger.NewWithOutput(io.Discard))
+	for _, tt := range tests {
+		t.Run(tt.file+" "+tt.rule, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := validator.ValidateFile(path, ValidateOptions{Format: tt.format})
+			if err != nil {
+				t.Fatalf("ValidateFile: %v", err)
+			}
+			if result.Valid {
+				t.Error("file with a secret is valid")
+			}
+
+			var found *ValidationIssue
+			for _, issue := range result.Issues {
+				if issue.Rule == tt.rule {
+					found = issue
+				}
+			}
+			if found == nil {
+				t.Fatalf("no %s issue in %+v", tt.rule, result.Issues)
+			}
+			if found.Severity != SeverityError {
+				t.Errorf("severity = %s, want error", found.Severity)
+			}
+			if strings.Contains(found.Message, tt.secret) {
+				t.Errorf("message repeats the secret: %s", found.Message)
+			}
+			if !strings.Contains(found.Message, tt.secret[:2]+"****") {
+				t.Errorf("message does not show the redacted secret: %s", found.Message)
+			}
+		})
+	}
+}
+
+func TestSecretReferencesAreNotFindings(t *testing.T) {
+	lines := []string{
+		`password = var.vsphere_password`,
+		`password = "${var.vsphere_password}"`,
+		`password: "{{ vault_vsphere_password }}"`,
+		`password: !vault |`,
+		`"password": "changeme"`,
+		`secret: ""`,
+		`uuid = "` + fakeUUID + `"`,
+		`name = "web-01.example.com production frontend"`,
+	}
+
+	validator := NewValidator(logger.NewWithOutput(io.Discard))
+	for _, line := range lines {
+		if issues := validator.ScanSecrets("main.tf", []byte(line), ValidateOptions{}); len(issues) > 0 {
+			t.Errorf("%s: got %s", line, issues[0].Message)
+		}
+	}
+}
+
+func TestSecretRulesCanBeDisabled(t *testing.T) {
+	off := false
+	opts := ValidateOptions{Rules: RuleOverrides{"secret-literal": {Enabled: &off}}}
+
+	validator := NewValidator(logger.NewWithOutput(io.Discard))
+	if issues := validator.ScanSecrets("main.tf", []byte(`password = "`+fakeLiteral+`"`), opts); len(issues) > 0 {
+		t.Errorf("disabled rule still reported %s", issues[0].Message)
+	}
+}
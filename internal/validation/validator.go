@@ -27,6 +27,9 @@ type ValidateOptions struct {
 	Recursive bool   `json:"recursive"`
 	Fix       bool   `json:"fix"`
 	Strict    bool   `json:"strict"`
+
+	// Rules disables rules or changes their severity, by rule ID
+	Rules RuleOverrides `json:"rules,omitempty"`
 }
 
 // ValidationResult represents the result of a validation operation
@@ -147,7 +150,7 @@ func (v *Validator) detectFormat(path string) string {
 	ext := strings.ToLower(filepath.Ext(path))
 	
 	switch ext {
-	case ".tf", ".hcl":
+	case ".tf", ".tfvars", ".hcl":
 		return "terraform"
 	case ".json":
 		return "json"
@@ -201,7 +204,7 @@ func (v *Validator) shouldValidateFile(path string, format string) bool {
 // onlyWarnings checks if all issues are warnings (not errors)
 func (v *Validator) onlyWarnings(issues []*ValidationIssue) bool {
 	for _, issue := range issues {
-		if issue.Severity == "error" {
+		if issue.Severity == SeverityError {
 			return false
 		}
 	}
@@ -210,6 +213,9 @@ func (v *Validator) onlyWarnings(issues []*ValidationIssue) bool {
 
 // Validation functions for specific formats
 func (v *Validator) validateTerraform(content string, result *ValidationResult, opts ValidateOptions) {
+	// Secrets are reported once, ahead of the weaker credential warning
+	secrets := v.scanSecrets(content, result, opts)
+
 	// Basic Terraform syntax validation
 	lines := strings.Split(content, "\n")
 	
@@ -221,22 +227,15 @@ func (v *Validator) validateTerraform(content string, result *ValidationResult,
 
 		// Check for common Terraform issues
 		if strings.Contains(line, "source = \"") && !strings.Contains(line, "version = ") {
-			result.Issues = append(result.Issues, &ValidationIssue{
-				Line:     i + 1,
-				Message:  "Module source should include version constraint",
-				Severity: "warning",
-				Rule:     "terraform-module-version",
-			})
+			v.report(result, opts, "terraform-module-version", i+1, "Module source should include version constraint")
 		}
 
 		// Check for hardcoded values
 		if strings.Contains(line, "ami-") && !strings.Contains(line, "var.") {
-			result.Issues = append(result.Issues, &ValidationIssue{
-				Line:     i + 1,
-				Message:  "Hardcoded AMI ID should be parameterized",
-				Severity: "warning",
-				Rule:     "terraform-hardcoded-ami",
-			})
+			v.report(result, opts, "terraform-hardcoded-ami", i+1, "Hardcoded AMI ID should be parameterized")
+		}
+		if isHardcodedCredential(line) && !secrets[i+1] {
+			v.report(result, opts, "terraform-hardcoded-credentials", i+1, "Credential should come from a variable, not a string literal")
 		}
 	}
 }
@@ -244,24 +243,15 @@ func (v *Validator) validateTerraform(content string, result *ValidationResult,
 func (v *Validator) validateJSON(content string, result *ValidationResult, opts ValidateOptions) {
 	// Basic JSON syntax validation
 	if !strings.HasPrefix(strings.TrimSpace(content), "{") && !strings.HasPrefix(strings.TrimSpace(content), "[") {
-		result.Issues = append(result.Issues, &ValidationIssue{
-			Line:     1,
-			Message:  "Invalid JSON format",
-			Severity: "error",
-			Rule:     "json-syntax",
-		})
+		v.report(result, opts, "json-syntax", 1, "Invalid JSON format")
 	}
+	v.scanSecrets(content, result, opts)
 
 	// Check for common JSON issues
 	lines := strings.Split(content, "\n")
 	for i, line := range lines {
 		if strings.Contains(line, "\\") && !strings.Contains(line, "\\\\") {
-			result.Issues = append(result.Issues, &ValidationIssue{
-				Line:     i + 1,
-				Message:  "Unescaped backslash in JSON",
-				Severity: "warning",
-				Rule:     "json-escape",
-			})
+			v.report(result, opts, "json-escape", i+1, "Unescaped backslash in JSON")
 		}
 	}
 }
@@ -272,36 +262,23 @@ func (v *Validator) validateYAML(content string, result *ValidationResult, opts
 	for i, line := range lines {
 		// Check for tab characters (YAML should use spaces)
 		if strings.Contains(line, "\t") {
-			result.Issues = append(result.Issues, &ValidationIssue{
-				Line:     i + 1,
-				Message:  "YAML should use spaces instead of tabs",
-				Severity: "error",
-				Rule:     "yaml-tabs",
-			})
+			v.report(result, opts, "yaml-tabs", i+1, "YAML should use spaces instead of tabs")
 		}
 	}
+	v.scanSecrets(content, result, opts)
 }
 
 func (v *Validator) validatePulumi(content string, result *ValidationResult, opts ValidateOptions) {
 	// Basic Pulumi validation
 	if !strings.Contains(content, "import pulumi") && !strings.Contains(content, "import * as pulumi") {
-		result.Issues = append(result.Issues, &ValidationIssue{
-			Line:     1,
-			Message:  "Pulumi program should import pulumi",
-			Severity: "warning",
-			Rule:     "pulumi-import",
-		})
+		v.report(result, opts, "pulumi-import", 1, "Pulumi program should import pulumi")
 	}
 }
 
 func (v *Validator) validateAnsible(content string, result *ValidationResult, opts ValidateOptions) {
 	// Basic Ansible playbook validation
 	if !strings.Contains(content, "hosts:") && !strings.Contains(content, "- name:") {
-		result.Issues = append(result.Issues, &ValidationIssue{
-			Line:     1,
-			Message:  "Ansible playbook should contain hosts or tasks",
-			Severity: "warning",
-			Rule:     "ansible-structure",
-		})
+		v.report(result, opts, "ansible-structure", 1, "Ansible playbook should contain hosts or tasks")
 	}
+	v.scanSecrets(content, result, opts)
 }
@@ -0,0 +1,68 @@
+// Package version compares the dotted product versions hypervisors report,
+// such as "7.0.3" for vCenter or "8.1-2" for Proxmox VE.
+//
+// A version that couldn't be read is never taken as old or new: AtLeast and
+// Below are both false for it. Gate version-specific behaviour with
+// whichever of the two describes the versions it applies to, so an unknown
+// version never triggers the gate.
+package version
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AtLeast reports whether version is at least min. It is false for an
+// unknown or unparsable version.
+func AtLeast(version, min string) bool {
+	cmp, ok := Compare(version, min)
+	return ok && cmp >= 0
+}
+
+// Below reports whether version is older than max. It is false for an
+// unknown or unparsable version.
+func Below(version, max string) bool {
+	cmp, ok := Compare(version, max)
+	return ok && cmp < 0
+}
+
+// Compare compares two dotted versions component by component, treating
+// missing components as zero, and returns -1, 0 or 1. ok is false if version
+// has no leading numeric component.
+func Compare(version, other string) (cmp int, ok bool) {
+	have := parts(version)
+	if have == nil {
+		return 0, false
+	}
+	want := parts(other)
+
+	for i := 0; i < len(have) || i < len(want); i++ {
+		a, b := 0, 0
+		if i < len(have) {
+			a = have[i]
+		}
+		if i < len(want) {
+			b = want[i]
+		}
+		if a != b {
+			if a < b {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+// parts parses the leading numeric components of a version string
+func parts(version string) []int {
+	var parts []int
+	for _, field := range strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
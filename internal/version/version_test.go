@@ -0,0 +1,32 @@
+package version
+
+import "testing"
+
+func TestGates(t *testing.T) {
+	tests := []struct {
+		version string
+		bound   string
+		atLeast bool
+		below   bool
+	}{
+		{"7.0.3", "7.0", true, false},
+		{"6.7.0", "7.0", false, true},
+		{"8.1-2", "8.1.1", true, false},
+		{"8.1", "8.1.1", false, true},
+		{"9.0.0", "9.0", true, false},
+		{"10.0", "9.0", true, false},
+		// Versions that couldn't be read never trigger a gate
+		{"", "6.0", false, false},
+		{"unknown", "6.0", false, false},
+		{"v8.0", "6.0", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := AtLeast(tt.version, tt.bound); got != tt.atLeast {
+			t.Errorf("AtLeast(%q, %q) = %v, want %v", tt.version, tt.bound, got, tt.atLeast)
+		}
+		if got := Below(tt.version, tt.bound); got != tt.below {
+			t.Errorf("Below(%q, %q) = %v, want %v", tt.version, tt.bound, got, tt.below)
+		}
+	}
+}
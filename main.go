@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -38,6 +39,13 @@ Discover and transform your VMware vCenter, Proxmox, and Nutanix infrastructure
 			if err := cfg.InitConfig(cfgFile); err != nil {
 				log.Fatal("Failed to initialize config", "error", err)
 			}
+
+			// Log to a rotated file instead of stdout when asked to
+			if cfg.LogFile != "" {
+				if err := log.SetFile(cfg.LogFile); err != nil {
+					log.Fatal("Failed to open log file", "file", cfg.LogFile, "error", err)
+				}
+			}
 		},
 	}
 
@@ -45,20 +53,41 @@ Discover and transform your VMware vCenter, Proxmox, and Nutanix infrastructure
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.valhalla.yaml)")
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug logging")
 	rootCmd.PersistentFlags().String("log-format", "text", "log format (text, json)")
+	rootCmd.PersistentFlags().String("log-file", "", "write logs to this file instead of stdout, rotated at 10MB keeping 3 old files")
 
 	// Bind flags to viper
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	viper.BindPFlag("log-format", rootCmd.PersistentFlags().Lookup("log-format"))
+	// Bound to the config file's key, so log_file and VALHALLA_LOG_FILE work too
+	viper.BindPFlag("log_file", rootCmd.PersistentFlags().Lookup("log-file"))
+
+	cmd.SetBuildInfo(version, commit)
 
 	// Add subcommands
 	rootCmd.AddCommand(cmd.NewDiscoverCmd(log, cfg))
 	rootCmd.AddCommand(cmd.NewGenerateCmd(log, cfg))
 	rootCmd.AddCommand(cmd.NewAuthCmd(log, cfg))
 	rootCmd.AddCommand(cmd.NewValidateCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewProvidersCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewImportCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewEnrichCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewConvertCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewPlanCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewConfigCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewSignCmd(log, cfg))
+	rootCmd.AddCommand(cmd.NewWatchCmd(log, cfg))
 
 	// Execute
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if err != nil {
 		log.Error("Command execution failed", "error", err)
+	}
+	log.Close()
+	if err != nil {
+		var exitErr *cmd.ExitCodeError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
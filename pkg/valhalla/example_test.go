@@ -0,0 +1,65 @@
+package valhalla_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"valhalla/pkg/valhalla"
+)
+
+func ExampleDiscover() {
+	infra, err := valhalla.Discover(context.Background(), valhalla.ProviderConfig{
+		Provider: "vmware",
+		VMware: valhalla.VMwareConfig{
+			Server:     "vcenter.example.com",
+			Username:   "administrator@vsphere.local",
+			Password:   os.Getenv("VSPHERE_PASSWORD"),
+			Datacenter: "DC1",
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, vm := range infra.VirtualMachines {
+		fmt.Println(vm.Name, vm.PowerState)
+	}
+}
+
+func ExampleGenerate() {
+	infra := &valhalla.Infrastructure{
+		Provider:   "vmware",
+		Server:     "vcenter.example.com",
+		Datacenter: "DC1",
+		VirtualMachines: []valhalla.VirtualMachine{{
+			ID:     "vm-101",
+			Name:   "web-01",
+			CPUs:   2,
+			Memory: 4096,
+			Disks:  []valhalla.Disk{{ID: "2000", Size: 40, Datastore: "ds1"}},
+			NetworkCards: []valhalla.NetworkCard{{
+				ID: "4000", Key: 4000, Type: "vmxnet3", Network: "VM Network",
+			}},
+		}},
+		Networks: []valhalla.Network{{ID: "network-1", Name: "VM Network"}},
+		Storage:  []valhalla.Storage{{ID: "datastore-11", Name: "ds1"}},
+	}
+
+	// Without an OutputDir the artifacts are only returned
+	artifacts, err := valhalla.Generate(context.Background(), infra, valhalla.FormatTerraform, valhalla.Options{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, artifact := range artifacts {
+		fmt.Println(artifact.Path, artifact.Type)
+	}
+	// Output:
+	// provider.tf provider
+	// variables.tf variables
+	// data.tf data
+	// virtual_machines.tf resources
+	// outputs.tf outputs
+}
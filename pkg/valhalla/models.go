@@ -0,0 +1,53 @@
+package valhalla
+
+import (
+	"valhalla/internal/config"
+	"valhalla/internal/generators"
+	"valhalla/internal/models"
+	"valhalla/internal/validation"
+)
+
+// Provider connection settings
+type (
+	VMwareConfig          = config.VMwareConfig
+	VMwareDiscoveryConfig = config.VMwareDiscoveryConfig
+	ProxmoxConfig         = config.ProxmoxConfig
+	NutanixConfig         = config.NutanixConfig
+)
+
+// Discovered infrastructure
+type (
+	Infrastructure     = models.Infrastructure
+	VirtualMachine     = models.VirtualMachine
+	Disk               = models.Disk
+	NetworkCard        = models.NetworkCard
+	VMTools            = models.VMTools
+	HardwareInfo       = models.HardwareInfo
+	VMConfig           = models.VMConfig
+	Network            = models.Network
+	Storage            = models.Storage
+	StorageContents    = models.StorageContents
+	ContentSummary     = models.ContentSummary
+	StorageItem        = models.StorageItem
+	ResourcePool       = models.ResourcePool
+	ResourceAllocation = models.ResourceAllocation
+	HAGroup            = models.HAGroup
+	HAResource         = models.HAResource
+	Template           = models.Template
+	Host               = models.Host
+	HostResource       = models.HostResource
+	Cluster            = models.Cluster
+	Datacenter         = models.Datacenter
+	Permission         = models.Permission
+	ContentLibrary     = models.ContentLibrary
+	ContentLibraryItem = models.ContentLibraryItem
+	Finding            = models.Finding
+)
+
+// Generation settings and results
+type (
+	Hook          = generators.Hook
+	CapacityCheck = generators.CapacityCheck
+	FileDiff      = generators.FileDiff
+	SecretRules   = validation.RuleOverrides
+)
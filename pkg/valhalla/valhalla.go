@@ -0,0 +1,307 @@
+// Package valhalla is the Go API for embedding Valhalla's infrastructure
+// discovery and IaC generation in other programs without the CLI.
+//
+// Discover a vSphere environment and write Terraform for it:
+//
+//	infra, err := valhalla.Discover(ctx, valhalla.ProviderConfig{
+//		Provider: "vmware",
+//		VMware: valhalla.VMwareConfig{
+//			Server:     "vcenter.example.com",
+//			Username:   "administrator@vsphere.local",
+//			Password:   password,
+//			Datacenter: "DC1",
+//		},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	artifacts, err := valhalla.Generate(ctx, infra, valhalla.FormatTerraform, valhalla.Options{
+//		OutputDir: "terraform",
+//	})
+//
+// # Compatibility
+//
+// This package follows semantic versioning, independently of the CLI, with
+// its version in APIVersion. Within a major version, exported identifiers are
+// not removed or renamed and function signatures do not change; new
+// functions, constants and struct fields may be added, so construct structs
+// with field names. The model and provider configuration types are aliases
+// of Valhalla's internal types and carry the same guarantee: fields may be
+// added but are not removed or retyped. Generated artifact contents are not
+// covered and may change in any release. Nothing under internal/ is covered.
+package valhalla
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"valhalla/internal/discovery"
+	"valhalla/internal/generators"
+	"valhalla/internal/logger"
+	"valhalla/internal/models"
+)
+
+// APIVersion is the semantic version of this package's API
+const APIVersion = "1.1.0"
+
+// Logger is Valhalla's structured logger
+type Logger = logger.Logger
+
+// NewLogger returns a logger writing to w; pass io.Discard to silence it
+func NewLogger(w io.Writer) *Logger {
+	return logger.NewWithOutput(w)
+}
+
+// ProviderConfig selects a provider to discover and holds its connection
+// settings. Only the settings of the selected provider are used.
+type ProviderConfig struct {
+	// Provider is "vmware" (or "vsphere"), "proxmox" or "nutanix"
+	Provider string
+
+	VMware  VMwareConfig
+	Proxmox ProxmoxConfig
+	Nutanix NutanixConfig
+
+	// Log receives discovery progress; nil discards it
+	Log *Logger
+}
+
+// Discover connects to the configured provider, discovers its
+// infrastructure and disconnects. Resources are sorted by name, as they are
+// for `valhalla discover`, but unlike the CLI the result is not enriched with
+// ownership, complexity scores or advisories.
+func Discover(ctx context.Context, cfg ProviderConfig) (*Infrastructure, error) {
+	log := logOrDiscard(cfg.Log)
+
+	// The engine only needs the CLI configuration to run several providers
+	engine := discovery.NewEngine(log, nil)
+
+	var infrastructures []*models.Infrastructure
+	var err error
+	switch strings.ToLower(cfg.Provider) {
+	case "vmware", "vsphere":
+		infrastructures, err = engine.DiscoverVMware(ctx, cfg.VMware)
+	case "proxmox":
+		infrastructures, err = engine.DiscoverProxmox(ctx, cfg.Proxmox)
+	case "nutanix":
+		infrastructures, err = engine.DiscoverNutanix(ctx, cfg.Nutanix)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", cfg.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(infrastructures) != 1 {
+		return nil, fmt.Errorf("%s discovery returned %d infrastructures, expected one", cfg.Provider, len(infrastructures))
+	}
+
+	return infrastructures[0], nil
+}
+
+// Format is an IaC output format
+type Format string
+
+// Supported output formats
+const (
+	FormatTerraform        Format = "terraform"
+	FormatPulumiPython     Format = "pulumi-python"
+	FormatPulumiTypeScript Format = "pulumi-typescript"
+	FormatPulumiGo         Format = "pulumi-go"
+	FormatPulumiCSharp     Format = "pulumi-csharp"
+	FormatAnsible          Format = "ansible"
+)
+
+// Formats returns every supported output format
+func Formats() []Format {
+	var formats []Format
+	for _, format := range generators.GetAvailableFormats() {
+		formats = append(formats, Format(format))
+	}
+	return formats
+}
+
+// Options controls IaC generation
+type Options struct {
+	// OutputDir is where artifacts are written; empty only returns them
+	OutputDir string
+
+	// MaxFieldSize is the annotation size in bytes above which a sidecar
+	// notes file is referenced instead; 0 uses the default of 4096
+	MaxFieldSize int
+
+	// PreserveMAC keeps discovered MAC addresses as static MACs
+	PreserveMAC bool
+
+	// SkipRDMVMs leaves out VMs with raw device mapping disks instead of
+	// generating them without those disks
+	SkipRDMVMs bool
+
+	// UpgradeHWVersion generates VMs on an older virtual hardware version at
+	// this version instead; 0 keeps the discovered version
+	UpgradeHWVersion int
+
+	// EmitPostProvision carries post_provision.* annotations into
+	// commented-out post-clone blocks and POST_PROVISION.md
+	EmitPostProvision bool
+
+	// AllowLinkedClones recreates linked clones of discovered templates as
+	// linked clones instead of full copies (Terraform)
+	AllowLinkedClones bool
+
+	// WithImports adds import blocks and an import.sh script that adopt the
+	// discovered VMs into Terraform state (Terraform)
+	WithImports bool
+
+	// IncludeAllDataSources looks up every discovered network, datastore,
+	// host and resource pool instead of only those the VMs use (Terraform)
+	IncludeAllDataSources bool
+
+	// CreateNetworks creates the discovered port groups with their VLAN and
+	// security policy instead of only looking them up (Terraform)
+	CreateNetworks bool
+
+	// AllowSecrets writes to OutputDir even when generated files appear to
+	// contain hardcoded credentials; otherwise Generate fails
+	AllowSecrets bool
+
+	// Stack names the Pulumi stack configuration file; empty uses "dev"
+	Stack string
+
+	// DryRun renders artifacts without writing them, even with an OutputDir
+	DryRun bool
+
+	// Validate checks the generated files' syntax before writing them
+	Validate bool
+
+	// Flatten merges every generated Terraform .tf file into a single
+	// main.tf, in generation order; other files, such as notes, are written
+	// unchanged. Generators other than Terraform ignore it.
+	Flatten bool
+
+	// IncludeSecrets writes the plaintext credentials in Secrets, keyed by
+	// generated variable name, into git-ignored files
+	IncludeSecrets bool
+	Secrets        map[string]string
+
+	// SecretRules tunes the rules of the secret scan run on generated files
+	SecretRules SecretRules
+
+	// Capacity holds target capacity checks, keyed by CapacityKey, to note
+	// in the generated code
+	Capacity map[string]*CapacityCheck
+
+	// Hooks run commands on the written files, such as formatters
+	Hooks []Hook
+
+	// Log receives generation progress; nil discards it
+	Log *Logger
+}
+
+// Artifact is one generated file
+type Artifact struct {
+	// Path is where the artifact was written, or its path relative to the
+	// output directory when Options.OutputDir is empty
+	Path string
+
+	Content []byte
+
+	// Type is the artifact's role, such as "main", "variables" or "config"
+	Type string
+
+	// Provider is the infrastructure provider the artifact describes
+	Provider string
+
+	// Resources names the resources defined in the artifact
+	Resources []string
+
+	// Metadata holds generator details, such as the "hooks" run on it
+	Metadata map[string]interface{}
+}
+
+// Generate renders infrastructure as IaC in the given format
+func Generate(ctx context.Context, infra *Infrastructure, format Format, opts Options) ([]Artifact, error) {
+	if infra == nil {
+		return nil, fmt.Errorf("no infrastructure to generate from")
+	}
+	return GenerateAll(ctx, []*Infrastructure{infra}, format, opts)
+}
+
+// GenerateAll renders several infrastructures, such as every provider in a
+// discovery file, as one set of IaC artifacts
+func GenerateAll(ctx context.Context, infrastructures []*Infrastructure, format Format, opts Options) ([]Artifact, error) {
+	if len(infrastructures) == 0 {
+		return nil, fmt.Errorf("no infrastructure to generate from")
+	}
+	// Generators don't block on I/O, so only honor cancellation up front
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	generator, err := generators.NewGenerator(string(format), logOrDiscard(opts.Log))
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := generator.Generate(infrastructures, generators.GenerateOptions{
+		OutputDir:             opts.OutputDir,
+		DryRun:                opts.DryRun || opts.OutputDir == "",
+		Validate:              opts.Validate,
+		Flatten:               opts.Flatten,
+		MaxFieldSize:          opts.MaxFieldSize,
+		PreserveMAC:           opts.PreserveMAC,
+		SkipRDMVMs:            opts.SkipRDMVMs,
+		UpgradeHWVersion:      opts.UpgradeHWVersion,
+		EmitPostProvision:     opts.EmitPostProvision,
+		AllowLinkedClones:     opts.AllowLinkedClones,
+		WithImports:           opts.WithImports,
+		IncludeAllDataSources: opts.IncludeAllDataSources,
+		CreateNetworks:        opts.CreateNetworks,
+		Stack:                 opts.Stack,
+		IncludeSecrets:        opts.IncludeSecrets,
+		Secrets:               opts.Secrets,
+		AllowSecrets:          opts.AllowSecrets,
+		SecretRules:           opts.SecretRules,
+		Capacity:              opts.Capacity,
+		Hooks:                 opts.Hooks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts := make([]Artifact, 0, len(results))
+	for _, result := range results {
+		artifacts = append(artifacts, Artifact{
+			Path:      result.Path,
+			Content:   result.Content,
+			Type:      result.Type,
+			Provider:  result.Provider,
+			Resources: result.Resources,
+			Metadata:  result.Metadata,
+		})
+	}
+	return artifacts, nil
+}
+
+// Diff compares artifacts with the files already in dir, such as the
+// output of an earlier run
+func Diff(artifacts []Artifact, dir string) ([]FileDiff, error) {
+	results := make([]*generators.GenerateResult, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		results = append(results, &generators.GenerateResult{
+			Path:    artifact.Path,
+			Content: artifact.Content,
+			Size:    len(artifact.Content),
+		})
+	}
+	return generators.DiffResults(results, dir)
+}
+
+// logOrDiscard returns log, or a logger that discards everything when nil
+func logOrDiscard(log *Logger) *Logger {
+	if log == nil {
+		return NewLogger(io.Discard)
+	}
+	return log
+}